@@ -0,0 +1,176 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"sort"
+	"time"
+)
+
+// InactivePeriod is a stretch of a boss encounter during which a player
+// emitted no events, e.g. while disconnected, AFK or dead.
+type InactivePeriod struct {
+	Encounter string    `json:"encounter"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+}
+
+// Duration returns the length of the inactive period.
+func (p InactivePeriod) Duration() time.Duration {
+	return p.End.Sub(p.Start)
+}
+
+// PlayerActivity summarizes how much of the boss encounters a player was
+// present for was spent doing something.
+type PlayerActivity struct {
+	// EncounterTime is the combined length of every encounter the player
+	// took part in.
+	EncounterTime time.Duration    `json:"encounter_time"`
+	Inactive      []InactivePeriod `json:"inactive"`
+}
+
+// InactiveTime returns the combined length of every inactive period.
+func (a PlayerActivity) InactiveTime() time.Duration {
+	var d time.Duration
+	for _, p := range a.Inactive {
+		d += p.Duration()
+	}
+	return d
+}
+
+// Percent returns the share of encounter time the player was active, from 0
+// to 100.
+func (a PlayerActivity) Percent() float64 {
+	if a.EncounterTime <= 0 {
+		return 0
+	}
+	return 100 * float64(a.EncounterTime-a.InactiveTime()) / float64(a.EncounterTime)
+}
+
+// ActivityReport maps player name to their activity.
+type ActivityReport map[string]*PlayerActivity
+
+// ActivityAnalyzer finds the periods during boss encounters where a player
+// emitted no events.
+type ActivityAnalyzer struct {
+	// Threshold is the shortest silence that counts as inactive.
+	Threshold time.Duration
+}
+
+// ActivityAnalyzerFunc is an option for NewActivityAnalyzer.
+type ActivityAnalyzerFunc func(*ActivityAnalyzer)
+
+// WithInactivityThreshold sets the shortest silence that counts as inactive.
+func WithInactivityThreshold(d time.Duration) ActivityAnalyzerFunc {
+	return func(a *ActivityAnalyzer) {
+		a.Threshold = d
+	}
+}
+
+// NewActivityAnalyzer initializes, allocates and returns a pointer to an
+// ActivityAnalyzer.
+func NewActivityAnalyzer(opts ...ActivityAnalyzerFunc) *ActivityAnalyzer {
+	a := &ActivityAnalyzer{
+		Threshold: time.Second * 5,
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+// activitySegment is a single boss attempt and the events seen from each
+// player during it.
+type activitySegment struct {
+	encounter  string
+	start, end time.Time
+	events     map[string][]time.Time
+}
+
+// Run splits the records into boss encounters and measures the gaps between
+// consecutive events emitted by each player, including the gaps from the
+// start of the encounter and until its end. Trash segments are ignored.
+func (a *ActivityAnalyzer) Run(data []*CombatLogRecord) ActivityReport {
+	out := ActivityReport{}
+	encounters := newEncounterTracker(defaultCombatGap, nil)
+	var seg *activitySegment
+
+	flush := func() {
+		if seg == nil {
+			return
+		}
+		for name, events := range seg.events {
+			a.measure(out, seg, name, events)
+		}
+		seg = nil
+	}
+
+	for i := range data {
+		row := *data[i]
+		encounter := encounters.observe(row)
+		if seg != nil && (encounter != seg.encounter || row.Timestamp.Sub(seg.end) > defaultCombatGap) {
+			flush()
+		}
+		if encounter == TrashEncounter {
+			continue
+		}
+		if seg == nil {
+			seg = &activitySegment{
+				encounter: encounter,
+				start:     row.Timestamp,
+				events:    map[string][]time.Time{},
+			}
+		}
+		seg.end = row.Timestamp
+		if isPlayerID(row.SourceID) {
+			seg.events[row.SourceName] = append(seg.events[row.SourceName], row.Timestamp)
+		}
+		if isPlayerID(row.TargetID) {
+			// a player who is only ever targeted was present but idle
+			if _, ok := seg.events[row.TargetName]; !ok {
+				seg.events[row.TargetName] = nil
+			}
+		}
+	}
+	flush()
+	for _, p := range out {
+		sort.Slice(p.Inactive, func(i, j int) bool {
+			return p.Inactive[i].Start.Before(p.Inactive[j].Start)
+		})
+	}
+	return out
+}
+
+func (a *ActivityAnalyzer) measure(out ActivityReport, seg *activitySegment, name string, events []time.Time) {
+	p, ok := out[name]
+	if !ok {
+		p = &PlayerActivity{}
+		out[name] = p
+	}
+	p.EncounterTime += seg.end.Sub(seg.start)
+	last := seg.start
+	for _, ts := range append(events, seg.end) {
+		if ts.Sub(last) >= a.Threshold {
+			p.Inactive = append(p.Inactive, InactivePeriod{
+				Encounter: seg.encounter,
+				Start:     last,
+				End:       ts,
+			})
+		}
+		last = ts
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActivityAnalyzerRun(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:01.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000007721EC,"Yogzar",0x511,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:02.000  SWING_DAMAGE,0x07000000007721EC,"Yogzar",0x511,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:04.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:08.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:12.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:09:30.000  SWING_DAMAGE,0xF130009093000102,"The Damned",0xa48,0x07000000007721EC,"Yogzar",0x511,100,0,1,0,0,0,nil,nil,nil`,
+	)
+	report := NewActivityAnalyzer().Run(data)
+	w := report["Winterinjuly"]
+	if w == nil || len(w.Inactive) != 0 || w.Percent() != 100 {
+		t.Errorf("expected Winterinjuly to be fully active, got %+v", w)
+	}
+	y := report["Yogzar"]
+	if y == nil || len(y.Inactive) != 1 {
+		t.Fatalf("expected one inactive period for Yogzar, got %+v", y)
+	}
+	if y.Inactive[0].Duration() != time.Second*10 || y.Inactive[0].Encounter != "Lord Marrowgar" {
+		t.Errorf("unexpected inactive period: %+v", y.Inactive[0])
+	}
+	if y.EncounterTime != time.Second*12 {
+		t.Errorf("expected the trash record to be excluded from encounter time, got %s", y.EncounterTime)
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NameAlias is a player shown under a numbered name, such as "Arthas (2)",
+// because a player with another GUID appeared earlier in the log under the
+// same name, for example after a character was deleted and its name taken.
+type NameAlias struct {
+	GUID  string `json:"guid"`
+	Name  string `json:"name"`
+	Alias string `json:"alias"`
+}
+
+// WithMergeDuplicateNames sets whether players that share a name are left
+// under that name, so reports keyed by name add them up. By default every
+// player GUID after the first to use a name is renamed, see NameAlias.
+func WithMergeDuplicateNames(merge bool) ParserFunc {
+	return func(p *Parser) {
+		p.MergeDuplicateNames = merge
+	}
+}
+
+// playerNames gives every player GUID in a log a name of its own.
+type playerNames struct {
+	shown map[string]string
+	// used is the number of GUIDs seen under each name.
+	used    map[string]int
+	aliases []NameAlias
+}
+
+func newPlayerNames() *playerNames {
+	return &playerNames{shown: map[string]string{}, used: map[string]int{}}
+}
+
+// name returns the name the player GUID is shown as. The first GUID seen
+// under a name keeps it and later GUIDs are numbered from 2. Names that are
+// numbered already, such as those of cached records, are kept and reported
+// as aliases again.
+func (n *playerNames) name(guid, name string) string {
+	if name == "" || !isPlayerID(guid) {
+		return name
+	}
+	if shown, ok := n.shown[guid]; ok {
+		return shown
+	}
+	shown := name
+	if base, num, ok := splitAlias(name); ok {
+		n.used[base] = max(n.used[base], num)
+		n.aliases = append(n.aliases, NameAlias{GUID: guid, Name: base, Alias: name})
+	} else if n.used[name]++; n.used[name] > 1 {
+		shown = fmt.Sprintf("%s (%d)", name, n.used[name])
+		n.aliases = append(n.aliases, NameAlias{GUID: guid, Name: name, Alias: shown})
+	}
+	n.shown[guid] = shown
+	return shown
+}
+
+// splitAlias splits a numbered name into the name and its number. Player
+// names cannot contain spaces, so a real name is never mistaken for one.
+func splitAlias(s string) (string, int, bool) {
+	base, rest, ok := strings.Cut(s, " (")
+	if !ok || !strings.HasSuffix(rest, ")") {
+		return s, 0, false
+	}
+	num, err := strconv.Atoi(strings.TrimSuffix(rest, ")"))
+	return base, num, err == nil && num > 1
+}
+
+// rename applies the player names to the record's source and target.
+func (n *playerNames) rename(c *CombatLogRecord) {
+	c.SourceName = n.name(c.SourceID, c.SourceName)
+	c.TargetName = n.name(c.TargetID, c.TargetName)
+}
+
+// disambiguate renames the record's players unless MergeDuplicateNames is
+// set, reporting new aliases in the ParseReport.
+func (p *Parser) disambiguate(c *CombatLogRecord) {
+	if p.MergeDuplicateNames {
+		return
+	}
+	if p.players == nil {
+		p.players = newPlayerNames()
+	}
+	before := len(p.players.aliases)
+	p.players.rename(c)
+	p.report.Aliases = append(p.report.Aliases, p.players.aliases[before:]...)
+}
+
+// disambiguateAll is disambiguate over records parsed as a whole.
+func (p *Parser) disambiguateAll(records []*CombatLogRecord) {
+	p.players = newPlayerNames()
+	for _, v := range records {
+		p.disambiguate(v)
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParserDisambiguatesDuplicateNames(t *testing.T) {
+	lines := strings.Join([]string{
+		`12/11 01:08:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:01.000  SPELL_HEAL,0x0700000000AAAAAA,"Winterinjuly",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,3000,0,0,nil`,
+		`12/11 01:08:02.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+	}, "\n")
+	p := New(WithReader(strings.NewReader(lines)), WithLogYear(2010), WithStrictMode(true))
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[1].SourceName != "Winterinjuly (2)" || data[1].TargetName != "Winterinjuly" || data[2].SourceName != "Winterinjuly" {
+		t.Errorf("expected the second GUID to be numbered, got %q -> %q", data[1].SourceName, data[1].TargetName)
+	}
+	want := NameAlias{GUID: "0x0700000000AAAAAA", Name: "Winterinjuly", Alias: "Winterinjuly (2)"}
+	if a := p.Report().Aliases; len(a) != 1 || a[0] != want {
+		t.Errorf("expected %+v in the report, got %+v", want, a)
+	}
+
+	// numbered names, e.g. from the cache, are kept and reported again
+	p.report = ParseReport{}
+	p.disambiguateAll(data)
+	if a := p.Report().Aliases; data[1].SourceName != "Winterinjuly (2)" || len(a) != 1 || a[0] != want {
+		t.Errorf("expected the alias to be kept, got %q and %+v", data[1].SourceName, p.Report().Aliases)
+	}
+
+	p = New(WithReader(strings.NewReader(lines)), WithLogYear(2010), WithMergeDuplicateNames(true))
+	data, err = p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[1].SourceName != "Winterinjuly" || len(p.Report().Aliases) != 0 {
+		t.Errorf("expected the names to be merged, got %q", data[1].SourceName)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// EncounterKey identifies an encounter across parses of the same log.
+type EncounterKey struct {
+	Name      string    `json:"name"`
+	Attempt   int       `json:"attempt,omitempty"`
+	StartTime time.Time `json:"start_time"`
+}
+
+// Key returns the EncounterKey of the encounter.
+func (e Encounter) Key() EncounterKey {
+	return EncounterKey{Name: e.Name, Attempt: e.Attempt, StartTime: e.StartTime}
+}
+
+// Matches reports whether the key identifies the encounter.
+func (k EncounterKey) Matches(e Encounter) bool {
+	return k.Name == e.Name && k.Attempt == e.Attempt && k.StartTime.Equal(e.StartTime)
+}
+
+// Annotation is a note a user attached to an encounter, such as the strategy
+// used on an attempt.
+type Annotation struct {
+	Encounter EncounterKey `json:"encounter"`
+	// Offset is how far into the encounter the note refers to, or zero for
+	// a note about the whole encounter.
+	Offset  time.Duration `json:"offset,omitempty"`
+	Author  string        `json:"author,omitempty"`
+	Note    string        `json:"note"`
+	Created time.Time     `json:"created"`
+}
+
+// Annotations is a collection of notes on the encounters of one or more
+// logs.
+type Annotations []Annotation
+
+// For returns the notes attached to the encounter, in the order they were
+// added.
+func (a Annotations) For(e Encounter) []Annotation {
+	var out []Annotation
+	for _, n := range a {
+		if n.Encounter.Matches(e) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// Apply sets the Annotations of every encounter to the notes attached to it,
+// so they are included when the encounters are reported.
+func (a Annotations) Apply(encounters []Encounter) {
+	for i := range encounters {
+		encounters[i].Annotations = a.For(encounters[i])
+	}
+}
+
+// ReadAnnotations reads notes written by WriteAnnotations.
+func ReadAnnotations(r io.Reader) (Annotations, error) {
+	var out Annotations
+	if err := json.NewDecoder(r).Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WriteAnnotations writes the notes to w as an indented JSON array, so the
+// file stays readable and diffable when kept next to the logs.
+func WriteAnnotations(w io.Writer, a Annotations) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if a == nil {
+		a = Annotations{}
+	}
+	return enc.Encode(a)
+}
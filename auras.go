@@ -0,0 +1,250 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"sort"
+	"time"
+)
+
+// AuraInstance is a single application of an aura by one source on one
+// target, from when it was applied until it was removed.
+type AuraInstance struct {
+	SpellID    uint64    `json:"spell_id"`
+	SpellName  string    `json:"spell_name"`
+	AuraType   AuraType  `json:"aura_type"`
+	SourceID   string    `json:"source_id"`
+	SourceName string    `json:"source_name"`
+	TargetID   string    `json:"target_id"`
+	TargetName string    `json:"target_name"`
+	Applied    time.Time `json:"applied"`
+	Removed    time.Time `json:"removed"`
+	Refreshes  int       `json:"refreshes"`
+	// Doses are the stack counts logged by SPELL_AURA_APPLIED_DOSE and
+	// SPELL_AURA_REMOVED_DOSE events, in order. Auras that never stacked have
+	// none.
+	Doses    []AuraDose `json:"doses,omitempty"`
+	MaxDoses uint64     `json:"max_doses,omitempty"`
+}
+
+// AuraDose is the number of stacks an aura had from a point in time.
+type AuraDose struct {
+	Time  time.Time `json:"time"`
+	Doses uint64    `json:"doses"`
+}
+
+// Duration returns how long the aura instance was active.
+func (a AuraInstance) Duration() time.Duration {
+	return a.Removed.Sub(a.Applied)
+}
+
+// DosesAt returns the number of stacks the aura had at t, or zero before its
+// first dose event.
+func (a AuraInstance) DosesAt(t time.Time) uint64 {
+	var n uint64
+	for _, d := range a.Doses {
+		if d.Time.After(t) {
+			break
+		}
+		n = d.Doses
+	}
+	return n
+}
+
+// maxDosesBetween returns the highest stack count the aura had between start
+// and end.
+func (a AuraInstance) maxDosesBetween(start, end time.Time) uint64 {
+	n := a.DosesAt(start)
+	for _, d := range a.Doses {
+		if d.Time.After(start) && d.Time.Before(end) {
+			n = max(n, d.Doses)
+		}
+	}
+	return n
+}
+
+// dose records the stack count of a dose event.
+func (a *AuraInstance) dose(row CombatLogRecord) {
+	if row.AuraSuffix.Doses == 0 {
+		return
+	}
+	a.Doses = append(a.Doses, AuraDose{Time: row.Timestamp, Doses: row.AuraSuffix.Doses})
+	a.MaxDoses = max(a.MaxDoses, row.AuraSuffix.Doses)
+}
+
+// auraKey identifies an aura instance by who applied which spell to whom.
+type auraKey struct {
+	sourceID string
+	targetID string
+	spellID  uint64
+}
+
+// auraSlot identifies an aura on a target regardless of its source.
+type auraSlot struct {
+	targetID string
+	spellID  uint64
+}
+
+// AuraTracker pairs SPELL_AURA_APPLIED, SPELL_AURA_REFRESH and
+// SPELL_AURA_REMOVED events into AuraInstances, keeping a separate instance
+// per source so that interleaved applications of a shared aura are
+// attributed to the player who applied them. The stack counts of the _DOSE
+// events in between are recorded on the instance.
+//
+// Auras that can only exist once on a target, such as Sunder Armor, show up
+// in the log as a refresh or removal from a source that never applied them.
+// In that case ownership of the existing instance is handed over: the
+// previous source's instance ends and a new one starts for the new source.
+type AuraTracker struct {
+	active map[auraKey]*AuraInstance
+	slots  map[auraSlot]map[string]*AuraInstance
+	done   []*AuraInstance
+}
+
+// NewAuraTracker initializes, allocates and returns a pointer to an
+// AuraTracker.
+func NewAuraTracker() *AuraTracker {
+	return &AuraTracker{
+		active: map[auraKey]*AuraInstance{},
+		slots:  map[auraSlot]map[string]*AuraInstance{},
+	}
+}
+
+// Observe advances the tracker with the given record.
+func (t *AuraTracker) Observe(row CombatLogRecord) {
+	if row.EventType == UnitDied {
+		t.closeTarget(row.TargetID, row.Timestamp)
+		return
+	}
+	if row.AuraSuffix == nil || row.SpellAndRangePrefix == nil {
+		return
+	}
+	key := auraKey{sourceID: row.SourceID, targetID: row.TargetID, spellID: row.SpellAndRangePrefix.SpellID}
+	slot := auraSlot{targetID: row.TargetID, spellID: row.SpellAndRangePrefix.SpellID}
+	switch row.EventType {
+	case SpellAuraApplied:
+		if inst, ok := t.active[key]; ok {
+			inst.Refreshes++
+			return
+		}
+		t.open(key, slot, row)
+	case SpellAuraRefresh, SpellAuraAppliedDose, SpellAuraRemovedDose:
+		inst, ok := t.active[key]
+		if !ok {
+			// another source's instance, if any, is taken over
+			t.closeSlot(slot, row.Timestamp)
+			inst = t.open(key, slot, row)
+		}
+		if row.EventType == SpellAuraRefresh {
+			if ok {
+				inst.Refreshes++
+			}
+			return
+		}
+		inst.dose(row)
+	case SpellAuraRemoved:
+		if _, ok := t.active[key]; ok {
+			t.close(key, slot, row.Timestamp)
+			return
+		}
+		t.closeSlot(slot, row.Timestamp)
+	}
+}
+
+func (t *AuraTracker) open(key auraKey, slot auraSlot, row CombatLogRecord) *AuraInstance {
+	inst := &AuraInstance{
+		SpellID:    row.SpellAndRangePrefix.SpellID,
+		SpellName:  row.SpellAndRangePrefix.SpellName,
+		AuraType:   row.AuraSuffix.AuraType,
+		SourceID:   row.SourceID,
+		SourceName: row.SourceName,
+		TargetID:   row.TargetID,
+		TargetName: row.TargetName,
+		Applied:    row.Timestamp,
+	}
+	t.active[key] = inst
+	if t.slots[slot] == nil {
+		t.slots[slot] = map[string]*AuraInstance{}
+	}
+	t.slots[slot][key.sourceID] = inst
+	return inst
+}
+
+func (t *AuraTracker) close(key auraKey, slot auraSlot, at time.Time) {
+	inst := t.active[key]
+	inst.Removed = at
+	t.done = append(t.done, inst)
+	delete(t.active, key)
+	delete(t.slots[slot], key.sourceID)
+	if len(t.slots[slot]) == 0 {
+		delete(t.slots, slot)
+	}
+}
+
+// closeSlot ends every source's instance of an aura on a target.
+func (t *AuraTracker) closeSlot(slot auraSlot, at time.Time) {
+	for source := range t.slots[slot] {
+		t.close(auraKey{sourceID: source, targetID: slot.targetID, spellID: slot.spellID}, slot, at)
+	}
+}
+
+// closeTarget ends every aura on a target, e.g. when it dies.
+func (t *AuraTracker) closeTarget(targetID string, at time.Time) {
+	for slot := range t.slots {
+		if slot.targetID == targetID {
+			t.closeSlot(slot, at)
+		}
+	}
+}
+
+// Instances ends any aura still active at the given time and returns every
+// instance observed, ordered by when it was applied.
+func (t *AuraTracker) Instances(at time.Time) []*AuraInstance {
+	for key := range t.active {
+		t.close(key, auraSlot{targetID: key.targetID, spellID: key.spellID}, at)
+	}
+	sort.SliceStable(t.done, func(i, j int) bool {
+		return t.done[i].Applied.Before(t.done[j].Applied)
+	})
+	return t.done
+}
+
+// TrackAuras returns every AuraInstance in the records. Auras that are never
+// removed end at the last record.
+func TrackAuras(data []*CombatLogRecord) []*AuraInstance {
+	t := NewAuraTracker()
+	for i := range data {
+		t.Observe(*data[i])
+	}
+	var end time.Time
+	if len(data) > 0 {
+		end = data[len(data)-1].Timestamp
+	}
+	return t.Instances(end)
+}
+
+// AuraContribution returns how long each source kept the named aura up on
+// the named target.
+func AuraContribution(instances []*AuraInstance, target, spell string) map[string]time.Duration {
+	out := map[string]time.Duration{}
+	for _, inst := range instances {
+		if inst.TargetName == target && inst.SpellName == spell {
+			out[inst.SourceName] += inst.Duration()
+		}
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrackAurasPerSource(t *testing.T) {
+	data := parseTestLines(t,
+		// two independent Frost Fevers on the same target
+		`12/11 00:13:20.000  SPELL_AURA_APPLIED,0x07000000009DF7A8,"Winterinjuly",0x514,0xF13000909300002B,"The Damned",0xa48,55095,"Frost Fever",0x10,DEBUFF`,
+		`12/11 00:13:22.000  SPELL_AURA_APPLIED,0x07000000007721EC,"Yogzar",0x511,0xF13000909300002B,"The Damned",0xa48,55095,"Frost Fever",0x10,DEBUFF`,
+		`12/11 00:13:25.000  SPELL_AURA_REMOVED,0x07000000009DF7A8,"Winterinjuly",0x514,0xF13000909300002B,"The Damned",0xa48,55095,"Frost Fever",0x10,DEBUFF`,
+		// a single Sunder Armor taken over by another warrior's refresh
+		`12/11 00:13:20.000  SPELL_AURA_APPLIED,0x07000000009DF7A8,"Winterinjuly",0x514,0xF13000909300002B,"The Damned",0xa48,7386,"Sunder Armor",0x1,DEBUFF`,
+		`12/11 00:13:24.000  SPELL_AURA_REFRESH,0x07000000007721EC,"Yogzar",0x511,0xF13000909300002B,"The Damned",0xa48,7386,"Sunder Armor",0x1,DEBUFF`,
+		`12/11 00:13:26.000  SPELL_AURA_REFRESH,0x07000000007721EC,"Yogzar",0x511,0xF13000909300002B,"The Damned",0xa48,7386,"Sunder Armor",0x1,DEBUFF`,
+		`12/11 00:13:30.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF13000909300002B,"The Damned",0xa48`,
+	)
+	instances := TrackAuras(data)
+	if len(instances) != 4 {
+		t.Fatalf("expected 4 aura instances, got %d", len(instances))
+	}
+	ff := AuraContribution(instances, "The Damned", "Frost Fever")
+	if ff["Winterinjuly"] != time.Second*5 || ff["Yogzar"] != time.Second*8 {
+		t.Errorf("unexpected Frost Fever contribution: %v", ff)
+	}
+	sunder := AuraContribution(instances, "The Damned", "Sunder Armor")
+	if sunder["Winterinjuly"] != time.Second*4 || sunder["Yogzar"] != time.Second*6 {
+		t.Errorf("unexpected Sunder Armor contribution: %v", sunder)
+	}
+	for _, inst := range instances {
+		if inst.SourceName == "Yogzar" && inst.SpellName == "Sunder Armor" && inst.Refreshes != 1 {
+			t.Errorf("expected the second refresh to be counted, got %d", inst.Refreshes)
+		}
+	}
+}
+
+func TestTrackAurasDoses(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 00:13:20.000  SPELL_AURA_APPLIED,0x07000000009DF7A8,"Winterinjuly",0x514,0xF13000909300002B,"The Damned",0xa48,7386,"Sunder Armor",0x1,DEBUFF`,
+		`12/11 00:13:21.000  SPELL_AURA_APPLIED_DOSE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF13000909300002B,"The Damned",0xa48,7386,"Sunder Armor",0x1,DEBUFF,2`,
+		`12/11 00:13:22.000  SPELL_AURA_APPLIED_DOSE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF13000909300002B,"The Damned",0xa48,7386,"Sunder Armor",0x1,DEBUFF,3`,
+		`12/11 00:13:25.000  SPELL_AURA_REMOVED_DOSE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF13000909300002B,"The Damned",0xa48,7386,"Sunder Armor",0x1,DEBUFF,2`,
+		`12/11 00:13:30.000  SPELL_AURA_REMOVED,0x07000000009DF7A8,"Winterinjuly",0x514,0xF13000909300002B,"The Damned",0xa48,7386,"Sunder Armor",0x1,DEBUFF`,
+	)
+	instances := TrackAuras(data)
+	if len(instances) != 1 {
+		t.Fatalf("expected a single aura instance, got %d", len(instances))
+	}
+	inst := instances[0]
+	if len(inst.Doses) != 3 || inst.MaxDoses != 3 {
+		t.Errorf("expected 3 dose changes peaking at 3, got %+v", inst.Doses)
+	}
+	for _, tc := range []struct {
+		at   time.Time
+		want uint64
+	}{
+		{data[0].Timestamp, 0},
+		{data[2].Timestamp, 3},
+		{data[3].Timestamp.Add(time.Second), 2},
+	} {
+		if got := inst.DosesAt(tc.at); got != tc.want {
+			t.Errorf("DosesAt(%s): expected %d, got %d", tc.at, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,526 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// BinaryFormatVersion is the version of the .fpb encoding written by
+// WriteBinary. It is bumped whenever the record layout changes.
+const BinaryFormatVersion = 7
+
+// maxBinaryPrealloc caps the records allocated up front by ReadBinary, since
+// the count in the header cannot be trusted before the records are read.
+const maxBinaryPrealloc = 1 << 16
+
+// maxBinaryString is the longest string ReadBinary accepts. Every string of a
+// record is a field of a single log line.
+const maxBinaryString = 1 << 16
+
+// binaryMagic starts every .fpb file.
+var binaryMagic = [4]byte{'F', 'P', 'B', 0}
+
+var (
+	// ErrNotBinaryLog is returned when the input does not start with the .fpb
+	// magic bytes.
+	ErrNotBinaryLog = errors.New("frostparse: not a frostparse binary file")
+	// ErrUnsupportedVersion is returned when the .fpb file was written with a
+	// different BinaryFormatVersion.
+	ErrUnsupportedVersion = errors.New("frostparse: unsupported binary format version")
+	// ErrCorruptBinary is returned when the .fpb file is truncated or holds
+	// data WriteBinary would not have written.
+	ErrCorruptBinary = errors.New("frostparse: corrupt binary file")
+)
+
+// presence bits for the prefix and suffix pointers of a CombatLogRecord.
+const (
+	hasSpellAndRangePrefix = 1 << iota
+	hasEnchantPrefix
+	hasEnvironmentalPrefix
+	hasDamageSuffix
+	hasAuraSuffix
+	hasEnergizeSuffix
+	hasMissSuffix
+	hasHealSuffix
+	hasInterruptSuffix
+	hasExtraAttacksSuffix
+	hasDispelOrStolenSuffix
+	hasLeechOrDrainSuffix
+	hasRawEvent
+)
+
+// WriteBinary encodes records into the compact .fpb format: a magic header
+// and version, followed by each record with repeated strings (GUIDs, names,
+// spell names) interned into a table as they are first seen.
+func WriteBinary(w io.Writer, records []*CombatLogRecord) error {
+	e := &binaryEncoder{
+		w:       bufio.NewWriter(w),
+		strings: map[string]uint64{},
+	}
+	e.w.Write(binaryMagic[:])
+	e.uvarint(BinaryFormatVersion)
+	e.uvarint(uint64(len(records)))
+	for _, r := range records {
+		e.record(r)
+	}
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+// ReadBinary decodes records written by WriteBinary. A truncated or corrupt
+// input returns an error wrapping ErrCorruptBinary.
+func ReadBinary(r io.Reader) ([]*CombatLogRecord, error) {
+	// decoding from memory avoids a call per byte, and the records take more
+	// memory than their encoding anyway
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(binaryMagic) {
+		if len(data) == 0 {
+			return nil, io.EOF
+		}
+		return nil, io.ErrUnexpectedEOF
+	}
+	if [4]byte(data[:4]) != binaryMagic {
+		return nil, ErrNotBinaryLog
+	}
+	d := &binaryDecoder{buf: data[len(binaryMagic):]}
+	if v := d.uvarint(); d.err == nil && v != BinaryFormatVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, v)
+	}
+	n := d.uvarint()
+	if d.err != nil {
+		return nil, corrupt(d.err)
+	}
+	out := make([]*CombatLogRecord, 0, min(n, maxBinaryPrealloc))
+	for i := uint64(0); i < n; i++ {
+		rec := d.record()
+		if d.err != nil {
+			return nil, corrupt(d.err)
+		}
+		out = append(out, rec)
+	}
+	return out, nil
+}
+
+// corrupt wraps a decoding error in ErrCorruptBinary, reporting running out
+// of input as truncation.
+func corrupt(err error) error {
+	if errors.Is(err, ErrCorruptBinary) {
+		return err
+	}
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("%w: %w", ErrCorruptBinary, err)
+}
+
+// WriteBinaryFile encodes records into the file at path.
+func WriteBinaryFile(path string, records []*CombatLogRecord) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteBinary(f, records); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadBinaryFile decodes the records in the .fpb file at path.
+func ReadBinaryFile(path string) ([]*CombatLogRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadBinary(f)
+}
+
+type binaryEncoder struct {
+	w       *bufio.Writer
+	buf     [binary.MaxVarintLen64]byte
+	strings map[string]uint64
+	err     error
+}
+
+func (e *binaryEncoder) uvarint(v uint64) {
+	if e.err != nil {
+		return
+	}
+	n := binary.PutUvarint(e.buf[:], v)
+	_, e.err = e.w.Write(e.buf[:n])
+}
+
+func (e *binaryEncoder) varint(v int64) {
+	if e.err != nil {
+		return
+	}
+	n := binary.PutVarint(e.buf[:], v)
+	_, e.err = e.w.Write(e.buf[:n])
+}
+
+func (e *binaryEncoder) bool(b bool) {
+	if b {
+		e.uvarint(1)
+		return
+	}
+	e.uvarint(0)
+}
+
+// string writes a reference to the string table, where 0 means the string
+// follows inline and is appended to the table.
+func (e *binaryEncoder) string(s string) {
+	if idx, ok := e.strings[s]; ok {
+		e.uvarint(idx + 1)
+		return
+	}
+	e.strings[s] = uint64(len(e.strings))
+	e.uvarint(0)
+	e.uvarint(uint64(len(s)))
+	if e.err == nil {
+		_, e.err = e.w.WriteString(s)
+	}
+}
+
+func (e *binaryEncoder) record(r *CombatLogRecord) {
+	e.varint(r.Timestamp.UnixNano())
+	e.varint(int64(r.adjustment()))
+	e.string(string(r.EventType))
+	e.string(r.SourceID)
+	e.string(r.SourceName)
+	e.uvarint(uint64(r.SourceFlags))
+	e.string(r.TargetID)
+	e.string(r.TargetName)
+	e.uvarint(uint64(r.TargetFlags))
+
+	var bits uint64
+	set := func(present bool, bit uint64) {
+		if present {
+			bits |= bit
+		}
+	}
+	set(r.SpellAndRangePrefix != nil, hasSpellAndRangePrefix)
+	set(r.EnchantPrefix != nil, hasEnchantPrefix)
+	set(r.EnvironmentalPrefix != nil, hasEnvironmentalPrefix)
+	set(r.DamageSuffix != nil, hasDamageSuffix)
+	set(r.AuraSuffix != nil, hasAuraSuffix)
+	set(r.EnergizeSuffix != nil, hasEnergizeSuffix)
+	set(r.MissSuffix != nil, hasMissSuffix)
+	set(r.HealSuffix != nil, hasHealSuffix)
+	set(r.InterruptSuffix != nil, hasInterruptSuffix)
+	set(r.ExtraAttacksSuffix != nil, hasExtraAttacksSuffix)
+	set(r.DispelOrStolenSuffix != nil, hasDispelOrStolenSuffix)
+	set(r.LeechOrDrainSuffix != nil, hasLeechOrDrainSuffix)
+	set(r.Raw != nil, hasRawEvent)
+	e.uvarint(bits)
+
+	if p := r.SpellAndRangePrefix; p != nil {
+		e.uvarint(p.SpellID)
+		e.string(p.SpellName)
+		e.varint(int64(p.SpellSchool))
+	}
+	if p := r.EnchantPrefix; p != nil {
+		e.string(p.SpellName)
+		e.uvarint(p.ItemID)
+		e.string(p.ItemName)
+	}
+	if p := r.EnvironmentalPrefix; p != nil {
+		e.string(string(p.EnvironmentalType))
+	}
+	if s := r.DamageSuffix; s != nil {
+		e.uvarint(s.Amount)
+		e.uvarint(s.Overkill)
+		e.varint(int64(s.SpellSchool))
+		e.uvarint(s.Resisted)
+		e.uvarint(s.Blocked)
+		e.uvarint(s.Absorbed)
+		e.bool(s.Critical)
+		e.bool(s.Glancing)
+		e.bool(s.Crushing)
+	}
+	if s := r.AuraSuffix; s != nil {
+		e.string(string(s.AuraType))
+		e.uvarint(s.Doses)
+	}
+	if s := r.EnergizeSuffix; s != nil {
+		e.varint(s.Amount)
+		e.varint(int64(s.PowerType))
+	}
+	if s := r.MissSuffix; s != nil {
+		e.string(string(s.MissType))
+		e.bool(s.IsOffHand)
+		e.uvarint(s.Amount)
+	}
+	if s := r.HealSuffix; s != nil {
+		e.uvarint(s.Amount)
+		e.uvarint(s.Overhealing)
+		e.uvarint(s.Absorbed)
+		e.bool(s.Critical)
+	}
+	if s := r.InterruptSuffix; s != nil {
+		e.uvarint(s.ExtraSpellID)
+		e.string(s.ExtraSpellName)
+		e.varint(int64(s.ExtraSpellSchool))
+	}
+	if s := r.ExtraAttacksSuffix; s != nil {
+		e.uvarint(s.Amount)
+	}
+	if s := r.DispelOrStolenSuffix; s != nil {
+		e.uvarint(s.ExtraSpellID)
+		e.string(s.ExtraSpellName)
+		e.varint(int64(s.ExtraSpellSchool))
+		e.string(string(s.AuraType))
+	}
+	if s := r.LeechOrDrainSuffix; s != nil {
+		e.uvarint(s.Amount)
+		e.varint(int64(s.PowerType))
+		e.uvarint(s.ExtraAmount)
+	}
+	if raw := r.Raw; raw != nil {
+		e.uvarint(uint64(len(raw.Fields)))
+		for _, f := range raw.Fields {
+			e.string(f)
+		}
+	}
+}
+
+// binarySlabSize is the largest number of records, or of one kind of prefix
+// or suffix, allocated at once while decoding.
+const binarySlabSize = 1024
+
+// slab hands out pointers into chunks of T, so that decoded records share a
+// few large allocations instead of one each. Chunks start small and double,
+// so that rare suffixes don't waste a full chunk.
+type slab[T any] struct {
+	buf  []T
+	size int
+}
+
+func (s *slab[T]) next() *T {
+	if len(s.buf) == 0 {
+		s.size = min(max(2*s.size, 16), binarySlabSize)
+		s.buf = make([]T, s.size)
+	}
+	v := &s.buf[0]
+	s.buf = s.buf[1:]
+	return v
+}
+
+type binaryDecoder struct {
+	buf     []byte
+	strings []string
+	err     error
+
+	records       slab[CombatLogRecord]
+	spells        slab[SpellAndRangePrefix]
+	damage        slab[DamageSuffix]
+	auras         slab[AuraSuffix]
+	energizes     slab[EnergizeSuffix]
+	misses        slab[MissSuffix]
+	heals         slab[HealSuffix]
+	interrupts    slab[InterruptSuffix]
+	extraAttacks  slab[ExtraAttacksSuffix]
+	dispels       slab[DispelOrStolenSuffix]
+	leechOrDrains slab[LeechOrDrainSuffix]
+}
+
+func (d *binaryDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	// most values, such as flags and string references, fit in a byte
+	if len(d.buf) > 0 && d.buf[0] < 0x80 {
+		v := d.buf[0]
+		d.buf = d.buf[1:]
+		return uint64(v)
+	}
+	v, n := binary.Uvarint(d.buf)
+	switch {
+	case n == 0:
+		d.err = io.ErrUnexpectedEOF
+		return 0
+	case n < 0:
+		d.err = fmt.Errorf("%w: varint overflows a 64-bit integer", ErrCorruptBinary)
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *binaryDecoder) varint() int64 {
+	ux := d.uvarint()
+	x := int64(ux >> 1)
+	if ux&1 != 0 {
+		x = ^x
+	}
+	return x
+}
+
+func (d *binaryDecoder) bool() bool {
+	return d.uvarint() == 1
+}
+
+func (d *binaryDecoder) string() string {
+	ref := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if ref > 0 {
+		if ref > uint64(len(d.strings)) {
+			d.err = fmt.Errorf("%w: invalid string reference %d", ErrCorruptBinary, ref)
+			return ""
+		}
+		return d.strings[ref-1]
+	}
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if n > maxBinaryString {
+		d.err = fmt.Errorf("%w: string of %d bytes", ErrCorruptBinary, n)
+		return ""
+	}
+	if n > uint64(len(d.buf)) {
+		d.err = io.ErrUnexpectedEOF
+		return ""
+	}
+	s := string(d.buf[:n])
+	d.buf = d.buf[n:]
+	d.strings = append(d.strings, s)
+	return s
+}
+
+func (d *binaryDecoder) record() *CombatLogRecord {
+	r := d.records.next()
+	r.Timestamp = time.Unix(0, d.varint()).UTC()
+	r.AdjustedTimestamp = r.Timestamp.Add(time.Duration(d.varint()))
+	r.EventType = EventType(d.string())
+	r.SourceID = d.string()
+	r.SourceName = d.string()
+	r.SourceFlags = UnitFlags(d.uvarint())
+	r.TargetID = d.string()
+	r.TargetName = d.string()
+	r.TargetFlags = UnitFlags(d.uvarint())
+
+	bits := d.uvarint()
+	if bits&hasSpellAndRangePrefix != 0 {
+		p := d.spells.next()
+		p.SpellID = d.uvarint()
+		p.SpellName = d.string()
+		p.SpellSchool = SpellSchool(d.varint())
+		r.SpellAndRangePrefix = p
+	}
+	if bits&hasEnchantPrefix != 0 {
+		r.EnchantPrefix = &EnchantPrefix{
+			SpellName: d.string(),
+			ItemID:    d.uvarint(),
+			ItemName:  d.string(),
+		}
+	}
+	if bits&hasEnvironmentalPrefix != 0 {
+		r.EnvironmentalPrefix = &EnvironmentalPrefix{
+			EnvironmentalType: EnvironmentalType(d.string()),
+		}
+	}
+	if bits&hasDamageSuffix != 0 {
+		s := d.damage.next()
+		s.Amount = d.uvarint()
+		s.Overkill = d.uvarint()
+		s.SpellSchool = SpellSchool(d.varint())
+		s.Resisted = d.uvarint()
+		s.Blocked = d.uvarint()
+		s.Absorbed = d.uvarint()
+		s.Critical = d.bool()
+		s.Glancing = d.bool()
+		s.Crushing = d.bool()
+		r.DamageSuffix = s
+	}
+	if bits&hasAuraSuffix != 0 {
+		s := d.auras.next()
+		s.AuraType = AuraType(d.string())
+		s.Doses = d.uvarint()
+		r.AuraSuffix = s
+	}
+	if bits&hasEnergizeSuffix != 0 {
+		s := d.energizes.next()
+		s.Amount = d.varint()
+		s.PowerType = PowerType(d.varint())
+		r.EnergizeSuffix = s
+	}
+	if bits&hasMissSuffix != 0 {
+		s := d.misses.next()
+		s.MissType = MissType(d.string())
+		s.IsOffHand = d.bool()
+		s.Amount = d.uvarint()
+		r.MissSuffix = s
+	}
+	if bits&hasHealSuffix != 0 {
+		s := d.heals.next()
+		s.Amount = d.uvarint()
+		s.Overhealing = d.uvarint()
+		s.Absorbed = d.uvarint()
+		s.Critical = d.bool()
+		r.HealSuffix = s
+	}
+	if bits&hasInterruptSuffix != 0 {
+		s := d.interrupts.next()
+		s.ExtraSpellID = d.uvarint()
+		s.ExtraSpellName = d.string()
+		s.ExtraSpellSchool = SpellSchool(d.varint())
+		r.InterruptSuffix = s
+	}
+	if bits&hasExtraAttacksSuffix != 0 {
+		s := d.extraAttacks.next()
+		s.Amount = d.uvarint()
+		r.ExtraAttacksSuffix = s
+	}
+	if bits&hasDispelOrStolenSuffix != 0 {
+		s := d.dispels.next()
+		s.ExtraSpellID = d.uvarint()
+		s.ExtraSpellName = d.string()
+		s.ExtraSpellSchool = SpellSchool(d.varint())
+		s.AuraType = AuraType(d.string())
+		r.DispelOrStolenSuffix = s
+	}
+	if bits&hasLeechOrDrainSuffix != 0 {
+		s := d.leechOrDrains.next()
+		s.Amount = d.uvarint()
+		s.PowerType = PowerType(d.varint())
+		s.ExtraAmount = d.uvarint()
+		r.LeechOrDrainSuffix = s
+	}
+	if bits&hasRawEvent != 0 {
+		n := d.uvarint()
+		r.Raw = &RawEvent{Fields: make([]string, 0, min(n, maxEventFields))}
+		for i := uint64(0); i < n && d.err == nil; i++ {
+			r.Raw.Fields = append(r.Raw.Fields, d.string())
+		}
+	}
+	return r
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	want, err := newTestParser().Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteBinary(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadBinary(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d records, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Fatalf("record %d does not round trip:\nwant %+v\ngot  %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestBinaryRoundTripRawEvent(t *testing.T) {
+	want := parseTestLines(t, `12/11 00:13:39.000  SPELL_ABSORBED,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,69146,"Coldflame",0x10,"Power Word: Shield",1200`)
+	var buf bytes.Buffer
+	if err := WriteBinary(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadBinary(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !reflect.DeepEqual(got[0], want[0]) {
+		t.Errorf("expected the raw event to round trip, got %+v", got)
+	}
+}
+
+func TestReadBinaryRejectsOtherVersions(t *testing.T) {
+	if _, err := ReadBinary(bytes.NewReader([]byte("12/11 00:13:06.105"))); !errors.Is(err, ErrNotBinaryLog) {
+		t.Errorf("expected ErrNotBinaryLog, got %v", err)
+	}
+	b := append(binaryMagic[:], BinaryFormatVersion+1, 0)
+	if _, err := ReadBinary(bytes.NewReader(b)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
+
+func TestReadBinaryTruncated(t *testing.T) {
+	records := parseTestLines(t,
+		`12/11 01:08:00.000  SPELL_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47610,"Frostfire Bolt",0x14,9000,0,16,0,0,0,nil,nil,nil`,
+		`12/11 01:08:01.000  SWING_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,3000,0,1,0,0,0,nil,nil,nil`,
+	)
+	var buf bytes.Buffer
+	if err := WriteBinary(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	for n := len(binaryMagic) + 1; n < len(data); n++ {
+		if _, err := ReadBinary(bytes.NewReader(data[:n])); !errors.Is(err, ErrCorruptBinary) {
+			t.Fatalf("expected ErrCorruptBinary reading %d of %d bytes, got %v", n, len(data), err)
+		}
+	}
+	// a record count and string length far beyond the data must not be
+	// allocated up front
+	huge := append(binaryMagic[:], BinaryFormatVersion, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f, 0, 0, 0xff, 0xff, 0xff, 0xff, 0x0f)
+	if _, err := ReadBinary(bytes.NewReader(huge)); !errors.Is(err, ErrCorruptBinary) {
+		t.Errorf("expected ErrCorruptBinary, got %v", err)
+	}
+}
+
+func FuzzReadBinary(f *testing.F) {
+	records, err := New(WithLogYear(2010), WithReader(strings.NewReader(
+		`12/11 00:13:39.000  SPELL_ABSORBED,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,69146,"Coldflame",0x10,"Power Word: Shield",1200`+"\n"+
+			`12/11 01:08:06.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,3000,0,0,nil`,
+	))).Parse()
+	if err != nil {
+		f.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteBinary(&buf, records); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(buf.Bytes())
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// any input must decode or fail, never panic
+		ReadBinary(bytes.NewReader(data))
+	})
+}
+
+// BenchmarkReadBinary and BenchmarkParse compare reloading the testdata from
+// the cache with reparsing it. Decoding is bound by allocating the records, so
+// it is about 3x faster than parsing rather than an order of magnitude.
+func BenchmarkReadBinary(b *testing.B) {
+	records, err := newTestParser().Parse()
+	if err != nil {
+		b.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteBinary(&buf, records); err != nil {
+		b.Fatal(err)
+	}
+	data := buf.Bytes()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadBinary(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	p := newTestParser()
+	for i := 0; i < b.N; i++ {
+		if _, err := p.Parse(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
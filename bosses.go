@@ -0,0 +1,212 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "strconv"
+
+// Boss describes a boss encounter and the units that take part in it.
+type Boss struct {
+	// Name is the encounter name, e.g. "Blood Prince Council".
+	Name string
+	// Units are the names of the units fighting in the encounter. When
+	// empty, the encounter has a single unit called Name.
+	Units []string
+	// NPCIDs are the creature IDs of the units, used to match units by GUID
+	// regardless of the client locale.
+	NPCIDs []uint64
+	// Kill lists the units that must all die for the encounter to be a
+	// kill. When empty, every unit must die.
+	Kill []string
+	// Zone is the raid the boss is in. NewBossRegistry defaults it to the
+	// name of the registry.
+	Zone string
+}
+
+// units returns the unit names of the boss.
+func (b *Boss) units() []string {
+	if len(b.Units) == 0 {
+		return []string{b.Name}
+	}
+	return b.Units
+}
+
+// killed reports whether every unit required for a kill is in dead.
+func (b *Boss) killed(dead map[string]bool) bool {
+	required := b.Kill
+	if len(required) == 0 {
+		required = b.units()
+	}
+	for _, u := range required {
+		if !dead[u] {
+			return false
+		}
+	}
+	return true
+}
+
+// BossRegistry is a set of bosses that units are matched against to detect
+// encounters, by NPC ID when the GUID carries one and by unit name otherwise.
+type BossRegistry struct {
+	Name   string
+	Bosses []*Boss
+
+	byName map[string]*Boss
+	byID   map[uint64]*Boss
+}
+
+// NewBossRegistry initializes, allocates and returns a pointer to a
+// BossRegistry of the given bosses.
+func NewBossRegistry(name string, bosses ...*Boss) *BossRegistry {
+	r := &BossRegistry{
+		Name:   name,
+		Bosses: bosses,
+		byName: map[string]*Boss{},
+		byID:   map[uint64]*Boss{},
+	}
+	for _, b := range bosses {
+		if b.Zone == "" {
+			b.Zone = name
+		}
+		for _, u := range b.units() {
+			r.byName[u] = b
+		}
+		for _, id := range b.NPCIDs {
+			r.byID[id] = b
+		}
+	}
+	return r
+}
+
+// MergeBossRegistries returns a registry holding the bosses of every given
+// registry.
+func MergeBossRegistries(name string, registries ...*BossRegistry) *BossRegistry {
+	var bosses []*Boss
+	for _, r := range registries {
+		bosses = append(bosses, r.Bosses...)
+	}
+	return NewBossRegistry(name, bosses...)
+}
+
+// Match returns the boss the unit with the given name and GUID belongs to.
+// Players never match, even if they share a name with a boss.
+func (r *BossRegistry) Match(name, guid string) (*Boss, bool) {
+	if isPlayerID(guid) {
+		return nil, false
+	}
+	if id := NPCID(guid); id != 0 {
+		if b, ok := r.byID[id]; ok {
+			return b, true
+		}
+	}
+	b, ok := r.byName[name]
+	return b, ok
+}
+
+// NPCID returns the creature ID encoded in an NPC or vehicle GUID, or 0 for
+// any other kind of unit.
+func NPCID(guid string) uint64 {
+	if len(guid) != 18 || !(isNPCID(guid) || isBossID(guid)) {
+		return 0
+	}
+	id, err := strconv.ParseUint(guid[6:12], 16, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// IcecrownCitadel is the registry of Icecrown Citadel bosses.
+var IcecrownCitadel = NewBossRegistry("Icecrown Citadel",
+	&Boss{Name: "Lord Marrowgar", NPCIDs: []uint64{36612}},
+	&Boss{Name: "Lady Deathwhisper", NPCIDs: []uint64{36855}},
+	&Boss{Name: "Gunship Battle", Units: []string{"The Skybreaker", "Orgrim's Hammer"}, NPCIDs: []uint64{37540, 37215}},
+	&Boss{Name: "Deathbringer Saurfang", NPCIDs: []uint64{37813}},
+	&Boss{Name: "Festergut", NPCIDs: []uint64{36626}},
+	&Boss{Name: "Rotface", NPCIDs: []uint64{36627}},
+	&Boss{Name: "Professor Putricide", NPCIDs: []uint64{36678}},
+	&Boss{
+		Name:   "Blood Prince Council",
+		Units:  []string{"Prince Valanar", "Prince Keleseth", "Prince Taldaram"},
+		NPCIDs: []uint64{37970, 37972, 37973},
+	},
+	&Boss{Name: "Blood-Queen Lana'thel", NPCIDs: []uint64{37955}},
+	&Boss{Name: "Valithria Dreamwalker", NPCIDs: []uint64{36789}},
+	&Boss{Name: "Sindragosa", NPCIDs: []uint64{36853}},
+	&Boss{Name: "The Lich King", NPCIDs: []uint64{36597}},
+)
+
+// Ulduar is the registry of Ulduar bosses.
+var Ulduar = NewBossRegistry("Ulduar",
+	&Boss{Name: "Flame Leviathan", NPCIDs: []uint64{33113}},
+	&Boss{Name: "Ignis the Furnace Master", NPCIDs: []uint64{33118}},
+	&Boss{Name: "Razorscale", NPCIDs: []uint64{33186}},
+	&Boss{Name: "XT-002 Deconstructor", NPCIDs: []uint64{33293}},
+	&Boss{
+		Name:   "Assembly of Iron",
+		Units:  []string{"Steelbreaker", "Runemaster Molgeim", "Stormcaller Brundir"},
+		NPCIDs: []uint64{32867, 32927, 32857},
+	},
+	&Boss{Name: "Kologarn", NPCIDs: []uint64{32930}},
+	&Boss{Name: "Auriaya", NPCIDs: []uint64{33515}},
+	&Boss{Name: "Hodir", NPCIDs: []uint64{32845}},
+	&Boss{Name: "Thorim", NPCIDs: []uint64{32865}},
+	&Boss{Name: "Freya", NPCIDs: []uint64{32906}},
+	&Boss{Name: "Mimiron", NPCIDs: []uint64{33350}},
+	&Boss{Name: "General Vezax", NPCIDs: []uint64{33271}},
+	&Boss{Name: "Yogg-Saron", NPCIDs: []uint64{33288}},
+	&Boss{Name: "Algalon the Observer", NPCIDs: []uint64{32871}},
+)
+
+// TrialOfTheCrusader is the registry of Trial of the Crusader bosses. The
+// Faction Champions are left out as their roster differs from raid to raid.
+var TrialOfTheCrusader = NewBossRegistry("Trial of the Crusader",
+	&Boss{
+		Name:   "Northrend Beasts",
+		Units:  []string{"Gormok the Impaler", "Acidmaw", "Dreadscale", "Icehowl"},
+		NPCIDs: []uint64{34796, 35144, 34799, 34797},
+		Kill:   []string{"Icehowl"},
+	},
+	&Boss{Name: "Lord Jaraxxus", NPCIDs: []uint64{34780}},
+	&Boss{
+		Name:   "Twin Val'kyr",
+		Units:  []string{"Fjola Lightbane", "Eydis Darkbane"},
+		NPCIDs: []uint64{34497, 34496},
+	},
+	&Boss{Name: "Anub'arak", NPCIDs: []uint64{34564}},
+)
+
+// RubySanctum is the registry of Ruby Sanctum bosses.
+var RubySanctum = NewBossRegistry("Ruby Sanctum",
+	&Boss{Name: "Baltharus the Warborn", NPCIDs: []uint64{39751}},
+	&Boss{Name: "Saviana Ragefire", NPCIDs: []uint64{39747}},
+	&Boss{Name: "General Zarithrian", NPCIDs: []uint64{39746}},
+	&Boss{Name: "Halion", NPCIDs: []uint64{39863, 40142}, Kill: []string{"Halion"}},
+)
+
+// DefaultBossRegistry is used when no BossRegistry is configured and holds
+// the bosses of every built-in registry.
+var DefaultBossRegistry = MergeBossRegistries("Wrath of the Lich King",
+	IcecrownCitadel, Ulduar, TrialOfTheCrusader, RubySanctum,
+)
+
+// bossRegistryOrDefault returns r, or DefaultBossRegistry when r is nil.
+func bossRegistryOrDefault(r *BossRegistry) *BossRegistry {
+	if r == nil {
+		return DefaultBossRegistry
+	}
+	return r
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "testing"
+
+func TestNPCID(t *testing.T) {
+	if id := NPCID("0xF130008F0400003D"); id != 36612 {
+		t.Errorf("expected Lord Marrowgar's NPC ID 36612, got %d", id)
+	}
+	if id := NPCID("0x07000000009DF7A8"); id != 0 {
+		t.Errorf("expected players to have no NPC ID, got %d", id)
+	}
+}
+
+func TestBossRegistryCouncilKill(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 02:00:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130009452000001,"Prince Valanar",0xa48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 02:00:05.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130009454000002,"Prince Keleseth",0xa48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 02:00:10.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130009452000001,"Prince Valanar",0xa48`,
+		`12/11 02:00:10.100  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130009454000002,"Prince Keleseth",0xa48`,
+		`12/11 02:00:10.200  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130009455000003,"Prince Taldaram",0xa48`,
+	)
+	encounters := NewEncounterSplitter(WithSplitterBossRegistry(IcecrownCitadel)).Split(data)
+	if len(encounters) != 1 {
+		t.Fatalf("expected the princes to form a single encounter, got %d", len(encounters))
+	}
+	e := encounters[0]
+	if e.Name != "Blood Prince Council" || e.Result != EncounterKill || len(e.Records) != 5 {
+		t.Errorf("expected a Blood Prince Council kill on the last prince death, got %s %s with %d records",
+			e.Name, e.Result, len(e.Records))
+	}
+	if encounters := NewEncounterSplitter(WithSplitterBossRegistry(Ulduar)).Split(data); len(encounters) != 0 {
+		t.Errorf("expected no Ulduar encounters, got %d", len(encounters))
+	}
+}
+
+func TestCollectorWithBossRegistry(t *testing.T) {
+	custom := NewBossRegistry("Naxxramas", &Boss{Name: "Patchwerk", NPCIDs: []uint64{16028}})
+	data := parseTestLines(t,
+		`12/11 02:00:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130003E9C000001,"Patchwerk",0xa48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 02:00:05.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130003E9C000001,"Patchwerk",0xa48`,
+	)
+	stats := NewCollector(WithBossRegistry(custom)).Run(data)
+	if len(stats.Encounters) != 1 || stats.Encounters[0].Result != EncounterKill {
+		t.Errorf("expected a Patchwerk kill, got %+v", stats.Encounters)
+	}
+	if _, ok := stats.EncounterOverlays["Patchwerk"]; !ok {
+		t.Error("expected a Patchwerk encounter overlay")
+	}
+}
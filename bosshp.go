@@ -0,0 +1,215 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "time"
+
+// Difficulty is a raid size and mode.
+type Difficulty int
+
+const (
+	// DifficultyUnknown lets analyzers infer the raid size from the number
+	// of players seen, assuming normal mode.
+	DifficultyUnknown Difficulty = iota
+	Normal10
+	Normal25
+	Heroic10
+	Heroic25
+)
+
+// String implementation of Difficulty.
+func (d Difficulty) String() string {
+	switch d {
+	case Normal10:
+		return "10 Normal"
+	case Normal25:
+		return "25 Normal"
+	case Heroic10:
+		return "10 Heroic"
+	case Heroic25:
+		return "25 Heroic"
+	default:
+		return "Unknown"
+	}
+}
+
+// BossHealth maps encounter name to the maximum health of the boss on each
+// difficulty. For encounters where the units share a health pool, such as
+// the Blood Prince Council, it is the health of the pool.
+type BossHealth map[string]map[Difficulty]uint64
+
+// MaxHP returns the maximum health of the boss on the given difficulty.
+func (h BossHealth) MaxHP(boss string, d Difficulty) (uint64, bool) {
+	hp, ok := h[boss][d]
+	return hp, ok && hp > 0
+}
+
+// IcecrownCitadelHealth holds approximate boss health values for Icecrown
+// Citadel as listed by public game databases. Encounters that are not won by
+// depleting a health pool, such as the Gunship Battle and Valithria
+// Dreamwalker, are left out.
+var IcecrownCitadelHealth = BossHealth{
+	"Lord Marrowgar":        {Normal10: 6970000, Normal25: 23700000, Heroic10: 8370000, Heroic25: 31400000},
+	"Lady Deathwhisper":     {Normal10: 3150000, Normal25: 11800000, Heroic10: 3780000, Heroic25: 14200000},
+	"Deathbringer Saurfang": {Normal10: 8785000, Normal25: 31860000, Heroic10: 12300000, Heroic25: 43930000},
+	"Festergut":             {Normal10: 9761500, Normal25: 40400000, Heroic10: 13700000, Heroic25: 50500000},
+	"Rotface":               {Normal10: 7320000, Normal25: 30300000, Heroic10: 10200000, Heroic25: 41800000},
+	"Professor Putricide":   {Normal10: 9760000, Normal25: 40400000, Heroic10: 13900000, Heroic25: 50600000},
+	"Blood Prince Council":  {Normal10: 4950000, Normal25: 19900000, Heroic10: 6930000, Heroic25: 27900000},
+	"Blood-Queen Lana'thel": {Normal10: 14200000, Normal25: 47800000, Heroic10: 20900000, Heroic25: 66700000},
+	"Sindragosa":            {Normal10: 11156000, Normal25: 38348000, Heroic10: 13950000, Heroic25: 46018000},
+	"The Lich King":         {Normal10: 17431250, Normal25: 61009376, Heroic10: 29290000, Heroic25: 103200000},
+}
+
+// HPSample is the estimated health of a boss at a point in an attempt.
+type HPSample struct {
+	Time    time.Time `json:"time"`
+	Percent float64   `json:"percent"`
+}
+
+// BossHP is the estimated health of a boss over the course of one attempt.
+type BossHP struct {
+	Encounter  Encounter  `json:"encounter"`
+	Difficulty Difficulty `json:"difficulty"`
+	MaxHP      uint64     `json:"max_hp"`
+	Samples    []HPSample `json:"samples"`
+	// FinalPercent is the health left when the attempt ended, 0 for kills.
+	FinalPercent float64 `json:"final_percent"`
+}
+
+// BossHPAnalyzer estimates boss health over each attempt by subtracting the
+// damage the raid dealt to the boss from its maximum health.
+type BossHPAnalyzer struct {
+	Health     BossHealth
+	Difficulty Difficulty
+	// Resolution is the interval between samples.
+	Resolution time.Duration
+	Splitter   *EncounterSplitter
+}
+
+// BossHPAnalyzerFunc is an option for NewBossHPAnalyzer.
+type BossHPAnalyzerFunc func(*BossHPAnalyzer)
+
+// WithBossHealth sets the boss health table used to estimate health.
+func WithBossHealth(h BossHealth) BossHPAnalyzerFunc {
+	return func(a *BossHPAnalyzer) {
+		a.Health = h
+	}
+}
+
+// WithDifficulty sets the difficulty of the attempts instead of inferring a
+// normal mode raid size from the number of players seen.
+func WithDifficulty(d Difficulty) BossHPAnalyzerFunc {
+	return func(a *BossHPAnalyzer) {
+		a.Difficulty = d
+	}
+}
+
+// WithHPResolution sets the interval between health samples.
+func WithHPResolution(d time.Duration) BossHPAnalyzerFunc {
+	return func(a *BossHPAnalyzer) {
+		a.Resolution = d
+	}
+}
+
+// WithHPSplitter sets the EncounterSplitter used to find attempts.
+func WithHPSplitter(s *EncounterSplitter) BossHPAnalyzerFunc {
+	return func(a *BossHPAnalyzer) {
+		a.Splitter = s
+	}
+}
+
+// NewBossHPAnalyzer initializes, allocates and returns a pointer to a
+// BossHPAnalyzer.
+func NewBossHPAnalyzer(opts ...BossHPAnalyzerFunc) *BossHPAnalyzer {
+	a := &BossHPAnalyzer{
+		Health:     IcecrownCitadelHealth,
+		Resolution: time.Second * 5,
+		Splitter:   NewEncounterSplitter(),
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+// Run estimates boss health for every attempt at a boss found in the health
+// table. Attempts at bosses without a known maximum health are skipped.
+func (a *BossHPAnalyzer) Run(data []*CombatLogRecord) []BossHP {
+	var out []BossHP
+	bosses := bossRegistryOrDefault(a.Splitter.Bosses)
+	for _, e := range a.Splitter.Split(data) {
+		d := a.Difficulty
+		if d == DifficultyUnknown {
+			d = inferDifficulty(e.Records)
+		}
+		maxHP, ok := a.Health.MaxHP(e.Name, d)
+		if !ok {
+			continue
+		}
+		hp := BossHP{
+			Difficulty: d,
+			MaxHP:      maxHP,
+			Samples:    []HPSample{{Time: e.StartTime, Percent: 100}},
+		}
+		var damage uint64
+		next := e.StartTime.Add(a.Resolution)
+		for _, rec := range e.Records {
+			for !rec.Timestamp.Before(next) {
+				hp.Samples = append(hp.Samples, HPSample{Time: next, Percent: hpPercent(damage, maxHP)})
+				next = next.Add(a.Resolution)
+			}
+			if !isDamageEvent(*rec) {
+				continue
+			}
+			if b, ok := bosses.Match(rec.TargetName, rec.TargetID); ok && b.Name == e.Name {
+				damage += recordAmount(*rec)
+			}
+		}
+		hp.FinalPercent = hpPercent(damage, maxHP)
+		if e.Result == EncounterKill {
+			hp.FinalPercent = 0
+		}
+		hp.Samples = append(hp.Samples, HPSample{Time: e.EndTime, Percent: hp.FinalPercent})
+		e.Records = nil
+		hp.Encounter = e
+		out = append(out, hp)
+	}
+	return out
+}
+
+func hpPercent(damage, maxHP uint64) float64 {
+	if damage >= maxHP {
+		return 0
+	}
+	return 100 * float64(maxHP-damage) / float64(maxHP)
+}
+
+// inferDifficulty guesses the normal mode raid size from the number of
+// players seen during an attempt.
+func inferDifficulty(records []*CombatLogRecord) Difficulty {
+	players := map[string]struct{}{}
+	for _, rec := range records {
+		if isPlayerID(rec.SourceID) {
+			players[rec.SourceID] = struct{}{}
+		}
+	}
+	if len(players) > 10 {
+		return Normal25
+	}
+	return Normal10
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBossHPAnalyzerRun(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,250,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:06.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,250,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:08.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,5000,0,1,0,0,0,nil,nil,nil`,
+	)
+	health := BossHealth{"Lord Marrowgar": {Normal10: 1000}}
+	report := NewBossHPAnalyzer(WithBossHealth(health)).Run(data)
+	if len(report) != 1 {
+		t.Fatalf("expected 1 attempt, got %d", len(report))
+	}
+	hp := report[0]
+	if hp.Difficulty != Normal10 || hp.FinalPercent != 50 {
+		t.Errorf("expected a 10 player wipe at 50%%, got %s at %.1f%%", hp.Difficulty, hp.FinalPercent)
+	}
+	want := []float64{100, 75, 50}
+	if len(hp.Samples) != len(want) {
+		t.Fatalf("expected %d samples, got %v", len(want), hp.Samples)
+	}
+	for i := range want {
+		if hp.Samples[i].Percent != want[i] {
+			t.Errorf("expected sample %d at %.0f%%, got %.1f%%", i, want[i], hp.Samples[i].Percent)
+		}
+	}
+	if !hp.Samples[1].Time.Equal(hp.Encounter.StartTime.Add(time.Second * 5)) {
+		t.Errorf("expected samples every 5 seconds, got %s", hp.Samples[1].Time)
+	}
+}
+
+func TestBossHPAnalyzerRunTestData(t *testing.T) {
+	data, err := newTestParser().Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hp := range NewBossHPAnalyzer().Run(data) {
+		fmt.Printf("%s #%d (%s): wiped at %.1f%%\n", hp.Encounter.Name, hp.Encounter.Attempt, hp.Difficulty, hp.FinalPercent)
+	}
+}
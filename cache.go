@@ -0,0 +1,276 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fingerprintSampleSize is the number of bytes hashed from the head and the
+// tail of a log file when computing its fingerprint.
+const fingerprintSampleSize = 64 * 1024
+
+// recordsArtifact is the file name parsed records are cached under.
+const recordsArtifact = "records"
+
+// reportArtifact is the file name the ParseReport of cached records is
+// stored under.
+const reportArtifact = "report"
+
+// CacheFunc is a function that accepts a pointer to a Cache struct to be used
+// in the options variadic function in the `NewCache` function.
+type CacheFunc func(*Cache)
+
+// Cache stores parsed records and their ParseReport on disk keyed by the
+// fingerprint of the log file they were produced from, so repeated runs over
+// an unmodified log can skip parsing.
+type Cache struct {
+	Dir string
+}
+
+// WithCacheDir sets the directory artifacts are stored in.
+func WithCacheDir(dir string) CacheFunc {
+	return func(c *Cache) {
+		c.Dir = dir
+	}
+}
+
+// NewCache initializes and allocates a Cache rooted in the user cache
+// directory (e.g. ~/.cache/frostparse) and applies any CacheFunc options.
+func NewCache(opts ...CacheFunc) *Cache {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	c := &Cache{
+		Dir: filepath.Join(dir, "frostparse"),
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// Fingerprint identifies the contents of a log file without reading all of
+// it: the size, modification time, and the first and last 64KiB are hashed
+// together. Any append or rewrite of the log changes the fingerprint.
+func Fingerprint(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	var meta [16]byte
+	binary.LittleEndian.PutUint64(meta[:8], uint64(info.Size()))
+	binary.LittleEndian.PutUint64(meta[8:], uint64(info.ModTime().UnixNano()))
+	h.Write(meta[:])
+	if _, err := io.CopyN(h, f, fingerprintSampleSize); err != nil && err != io.EOF {
+		return "", err
+	}
+	if info.Size() > fingerprintSampleSize {
+		tail := max(info.Size()-fingerprintSampleSize, fingerprintSampleSize)
+		if _, err := f.Seek(tail, io.SeekStart); err != nil {
+			return "", err
+		}
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *Cache) path(fingerprint, artifact string) string {
+	return filepath.Join(c.Dir, fingerprint, artifact+".gob")
+}
+
+func (c *Cache) recordsPath(fingerprint string) string {
+	return filepath.Join(c.Dir, fingerprint, recordsArtifact+".fpb")
+}
+
+// LoadRecords reads parsed records stored in the .fpb binary format. It
+// returns false without an error when no records are cached for the
+// fingerprint.
+func (c *Cache) LoadRecords(fingerprint string) ([]*CombatLogRecord, bool, error) {
+	out, err := ReadBinaryFile(c.recordsPath(fingerprint))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return out, true, nil
+}
+
+// StoreRecords writes parsed records in the .fpb binary format.
+func (c *Cache) StoreRecords(fingerprint string, records []*CombatLogRecord) error {
+	return c.writeAtomic(c.recordsPath(fingerprint), func(w io.Writer) error {
+		return WriteBinary(w, records)
+	})
+}
+
+// cachedReport is the ParseReport of the parse that produced cached
+// records. Aliases are left out as they are worked out again on every parse,
+// and quarantined lines keep only the message of their error.
+type cachedReport struct {
+	Lines       int
+	Parsed      int
+	Blank       int
+	Filtered    int
+	Quarantined []cachedParseError
+}
+
+type cachedParseError struct {
+	Line  int
+	Raw   string
+	Field int
+	Err   string
+}
+
+// loadReport reads the ParseReport stored next to cached records. It returns
+// false without an error when no report is cached for the fingerprint.
+func (c *Cache) loadReport(fingerprint string) (ParseReport, bool, error) {
+	f, err := os.Open(c.path(fingerprint, reportArtifact))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ParseReport{}, false, nil
+	}
+	if err != nil {
+		return ParseReport{}, false, err
+	}
+	defer f.Close()
+	var cr cachedReport
+	if err := gob.NewDecoder(f).Decode(&cr); err != nil {
+		return ParseReport{}, false, err
+	}
+	r := ParseReport{Lines: cr.Lines, Parsed: cr.Parsed, Blank: cr.Blank, Filtered: cr.Filtered}
+	for _, q := range cr.Quarantined {
+		r.Quarantined = append(r.Quarantined, &ParseError{Line: q.Line, Raw: q.Raw, Field: q.Field, Err: errors.New(q.Err)})
+	}
+	return r, true, nil
+}
+
+// storeReport writes the ParseReport of cached records.
+func (c *Cache) storeReport(fingerprint string, r ParseReport) error {
+	cr := cachedReport{Lines: r.Lines, Parsed: r.Parsed, Blank: r.Blank, Filtered: r.Filtered}
+	for _, q := range r.Quarantined {
+		cr.Quarantined = append(cr.Quarantined, cachedParseError{Line: q.Line, Raw: q.Raw, Field: q.Field, Err: q.Err.Error()})
+	}
+	return c.writeAtomic(c.path(fingerprint, reportArtifact), func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(cr)
+	})
+}
+
+func (c *Cache) writeAtomic(p string, write func(io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// Purge removes every artifact from the cache.
+func (c *Cache) Purge() error {
+	return os.RemoveAll(c.Dir)
+}
+
+// cacheKey returns the key the records of the log with the fingerprint are
+// cached under. The options that change which records a parse returns, and
+// how they are stamped and named, are part of the key, so a parse never loads
+// records shaped by other options. Options the key cannot describe, such as
+// name sanitizers, limits and unknown event handlers, bypass the cache.
+// Privacy is left out because the cache holds records before the privacy
+// settings are applied, so every parse applies its own.
+func (p *Parser) cacheKey(fingerprint string) (string, bool) {
+	if len(p.NameSanitizers) > 0 || p.UnknownEventHandler != nil || p.Limits != (Limits{}) {
+		return "", false
+	}
+	var opts []string
+	add := func(name string, v any) {
+		opts = append(opts, fmt.Sprintf("%s=%v", name, v))
+	}
+	if p.LogYear != 0 {
+		add("year", p.LogYear)
+	}
+	if !p.ReferenceTime.IsZero() {
+		add("reference", p.ReferenceTime.UnixNano())
+	}
+	if p.Location != nil {
+		add("location", p.Location)
+	}
+	if p.UTCTimestamps {
+		add("utc", true)
+	}
+	if p.Locale != nil {
+		if p.Locale.Name == "" {
+			return "", false
+		}
+		add("locale", p.Locale.Name)
+	}
+	if !p.From.IsZero() {
+		add("from", p.From.UnixNano())
+	}
+	if !p.To.IsZero() {
+		add("to", p.To.UnixNano())
+	}
+	if len(p.OnlyEncounters) > 0 {
+		add("encounters", p.OnlyEncounters)
+	}
+	// an empty include list is a filter that admits nothing, so it is keyed
+	// as "include=[]" rather than dropped like a missing filter
+	if p.IncludeEvents != nil {
+		add("include", p.IncludeEvents)
+	}
+	if len(p.ExcludeEvents) > 0 {
+		add("exclude", p.ExcludeEvents)
+	}
+	if p.MergeDuplicateNames {
+		add("merge", true)
+	}
+	if p.Strict {
+		add("strict", true)
+	}
+	if len(opts) == 0 {
+		return fingerprint, true
+	}
+	sum := sha256.Sum256([]byte(strings.Join(opts, "\n")))
+	return fingerprint + "-" + hex.EncodeToString(sum[:8]), true
+}
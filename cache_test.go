@@ -0,0 +1,255 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFingerprintChangesOnAppend(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "WoWCombatLog.txt")
+	if err := os.WriteFile(p, []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	a, err := Fingerprint(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte("a\nb\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := Fingerprint(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Error("expected fingerprint to change when the log changes")
+	}
+}
+
+func TestParserParseWithCache(t *testing.T) {
+	c := NewCache(WithCacheDir(t.TempDir()))
+	p := New(
+		WithLogFile("./testdata/test.txt"),
+		WithCache(c),
+	)
+	first, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp, err := Fingerprint(p.LogFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := c.LoadRecords(fp); err != nil || !ok {
+		t.Fatalf("expected records to be cached, ok=%v err=%v", ok, err)
+	}
+	second, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != len(second) {
+		t.Fatalf("expected %d cached records, got %d", len(first), len(second))
+	}
+	if second[0].EventType != first[0].EventType || !second[0].Timestamp.Equal(first[0].Timestamp) {
+		t.Errorf("cached record does not match parsed record: %+v", second[0])
+	}
+}
+
+func TestParserParseWithCorruptCache(t *testing.T) {
+	c := NewCache(WithCacheDir(t.TempDir()))
+	p := New(WithLogFile("./testdata/test.txt"), WithCache(c))
+	fp, err := Fingerprint(p.LogFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a cache file cut off mid write claiming far more records than it holds
+	path := c.recordsPath(fp)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	corrupt := append(binaryMagic[:], BinaryFormatVersion, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f)
+	if err := os.WriteFile(path, corrupt, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 43433 {
+		t.Errorf("expected the log to be reparsed, got %d records", len(data))
+	}
+}
+
+func TestParserParseWithCacheDispatches(t *testing.T) {
+	c := NewCache(WithCacheDir(t.TempDir()))
+	l := NewEventListener()
+	calls := 0
+	l.OnAny(func(CombatLogRecord) { calls++ })
+	p := New(WithLogFile("./testdata/test.txt"), WithCache(c), WithEventListener(l))
+	first, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != len(first) {
+		t.Fatalf("expected %d callbacks parsing, got %d", len(first), calls)
+	}
+	calls = 0
+	if _, err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != len(first) {
+		t.Errorf("expected %d callbacks loading from the cache, got %d", len(first), calls)
+	}
+}
+
+func TestParserParseWithCacheOptions(t *testing.T) {
+	c := NewCache(WithCacheDir(t.TempDir()))
+	all, err := New(WithLogFile("./testdata/test.txt"), WithCache(c)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered, err := New(WithLogFile("./testdata/test.txt"), WithCache(c), WithEventFilter([]EventType{SwingDamage})).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered) == 0 || len(filtered) >= len(all) {
+		t.Fatalf("expected the event filter to apply despite the cache, got %d of %d records", len(filtered), len(all))
+	}
+	sanitized, err := New(WithLogFile("./testdata/test.txt"), WithCache(c), WithNameSanitizer(strings.ToUpper)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, rec := range all {
+		if isPlayerID(rec.SourceID) {
+			if sanitized[i].SourceName != strings.ToUpper(rec.SourceName) {
+				t.Errorf("expected name sanitizers to bypass the cache, got %q", sanitized[i].SourceName)
+			}
+			break
+		}
+	}
+}
+
+func TestParserParseWithCacheReport(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "WoWCombatLog.txt")
+	log := compressionTestLog + "\n12/11 00:13:39.000  SWING_DAMAGE,broken\n"
+	if err := os.WriteFile(p, []byte(log), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := NewCache(WithCacheDir(t.TempDir()))
+	var reports []ParseReport
+	for i := 0; i < 2; i++ {
+		parser := New(WithLogFile(p), WithCache(c), WithExcludedEvents(SpellHeal))
+		if _, err := parser.Parse(); err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, parser.Report())
+	}
+	miss, hit := reports[0], reports[1]
+	if miss.Lines != 4 || miss.Parsed != 1 || miss.Blank != 1 || miss.Filtered != 1 || len(miss.Quarantined) != 1 {
+		t.Fatalf("unexpected report parsing the log: %+v", miss)
+	}
+	if hit.Lines != miss.Lines || hit.Parsed != miss.Parsed || hit.Blank != miss.Blank || hit.Filtered != miss.Filtered {
+		t.Errorf("expected the cached report %+v to match %+v", hit, miss)
+	}
+	if len(hit.Quarantined) != 1 || hit.Quarantined[0].Error() != miss.Quarantined[0].Error() {
+		t.Errorf("expected the quarantined line to be cached, got %v", hit.Quarantined)
+	}
+}
+
+func TestParserParseWithCacheEmptyEventFilter(t *testing.T) {
+	c := NewCache(WithCacheDir(t.TempDir()))
+	none, err := New(WithLogFile("./testdata/test.txt"), WithCache(c), WithEventFilter([]EventType{})).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(none) != 0 {
+		t.Fatalf("expected an empty event filter to admit no records, got %d", len(none))
+	}
+	all, err := New(WithLogFile("./testdata/test.txt"), WithCache(c)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 43433 {
+		t.Errorf("expected a plain parse after an empty filter to return all records, got %d", len(all))
+	}
+}
+
+func TestParserParseWithCachePrivacy(t *testing.T) {
+	c := NewCache(WithCacheDir(t.TempDir()))
+	count := func(records []*CombatLogRecord, name string) int {
+		n := 0
+		for _, rec := range records {
+			if rec.SourceName == name || rec.TargetName == name {
+				n++
+			}
+		}
+		return n
+	}
+	all, err := New(WithLogFile("./testdata/test.txt")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, excluded := range []string{"Yogzar", "Winterinjuly", ""} {
+		opts := []ParserFunc{WithLogFile("./testdata/test.txt"), WithCache(c)}
+		if excluded != "" {
+			opts = append(opts, WithPrivacy(NewPrivacy(WithExcludedPlayers(excluded))))
+		}
+		data, err := New(opts...).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if excluded == "" {
+			if len(data) != len(all) {
+				t.Errorf("expected a plain parse to return all %d records, got %d", len(all), len(data))
+			}
+			continue
+		}
+		if count(data, excluded) != 0 || len(data) != len(all)-count(all, excluded) {
+			t.Errorf("expected only %s to be excluded, got %d of %d records", excluded, len(data), len(all))
+		}
+	}
+}
+
+func TestParserParseWithCacheLocation(t *testing.T) {
+	c := NewCache(WithCacheDir(t.TempDir()))
+	loc := time.FixedZone("CET", 3600)
+	for _, opts := range [][]ParserFunc{
+		{WithLocation(loc)},
+		{WithLocation(loc), WithUTCTimestamps(true)},
+		{},
+	} {
+		opts = append(opts, WithLogFile("./testdata/test.txt"), WithCache(c))
+		parsed, err := New(opts...).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cached, err := New(opts...).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range parsed {
+			if cached[i].Timestamp != parsed[i].Timestamp || cached[i].AdjustedTimestamp != parsed[i].AdjustedTimestamp {
+				t.Fatalf("expected cached timestamps in %s, got %s", parsed[i].Timestamp.Location(), cached[i].Timestamp.Location())
+			}
+		}
+	}
+}
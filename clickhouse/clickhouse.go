@@ -0,0 +1,294 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clickhouse writes parsed combat log records into a wide ClickHouse
+// events table for analytics across many logs.
+//
+// Rows are inserted over the HTTP interface, by default on port 8123, as
+// JSONEachRow, or over the native TCP protocol when the sink's URL has a
+// clickhouse:// or tcp:// scheme, by default on port 9000, as blocks in the
+// Native format. The native client is uncompressed and does not support TLS.
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+	"github.com/bradleybonitatibus/frostparse/export"
+)
+
+const (
+	defaultURL   = "http://localhost:8123"
+	defaultTable = "events"
+)
+
+// schema is the events table, with one nullable column per field of
+// export.Event so each record is a single row. %s is the table name.
+const schema = `CREATE TABLE IF NOT EXISTS %s (
+	timestamp          DateTime64(3, 'UTC'),
+	event              LowCardinality(String),
+	encounter          LowCardinality(String),
+	attempt            UInt16,
+	trash              Bool,
+	encounter_start    Nullable(DateTime64(3, 'UTC')),
+	source_id          String,
+	source_name        String,
+	source_flags       UInt32,
+	target_id          String,
+	target_name        String,
+	target_flags       UInt32,
+	spell_id           Nullable(UInt64),
+	spell_name         Nullable(String),
+	spell_school       Nullable(UInt8),
+	item_id            Nullable(UInt64),
+	item_name          Nullable(String),
+	environmental_type LowCardinality(Nullable(String)),
+	amount             Nullable(Int64),
+	overkill           Nullable(UInt64),
+	school             Nullable(UInt8),
+	resisted           Nullable(UInt64),
+	blocked            Nullable(UInt64),
+	absorbed           Nullable(UInt64),
+	overhealing        Nullable(UInt64),
+	critical           Nullable(Bool),
+	glancing           Nullable(Bool),
+	crushing           Nullable(Bool),
+	miss_type          LowCardinality(Nullable(String)),
+	aura_type          LowCardinality(Nullable(String)),
+	doses              Nullable(UInt64),
+	power_type         Nullable(Int8),
+	extra_amount       Nullable(UInt64),
+	extra_spell_id     Nullable(UInt64),
+	extra_spell_name   Nullable(String),
+	extra_spell_school Nullable(UInt8)
+) ENGINE = MergeTree
+PARTITION BY (toYYYYMMDD(timestamp), encounter)
+ORDER BY (encounter, timestamp, source_id)`
+
+// row is an events table row: the flattened record and the boss attempt or
+// trash segment it belongs to.
+type row struct {
+	export.Event
+	Encounter      string     `json:"encounter"`
+	Attempt        int        `json:"attempt"`
+	Trash          bool       `json:"trash"`
+	EncounterStart *time.Time `json:"encounter_start,omitempty"`
+}
+
+// SinkFunc is a function that accepts a pointer to a Sink to be used in the
+// options variadic function in `NewSink`.
+type SinkFunc func(*Sink)
+
+// Sink writes records into a ClickHouse events table.
+type Sink struct {
+	// URL is the address of the ClickHouse HTTP interface or, with a
+	// clickhouse:// or tcp:// scheme, of the native interface.
+	URL      string
+	Database string
+	Table    string
+	// BatchSize is the number of rows sent per INSERT, or per block over
+	// the native interface.
+	BatchSize int
+	Username  string
+	Password  string
+	Client    *http.Client
+	Splitter  *frostparse.EncounterSplitter
+}
+
+// WithDatabase sets the database of the events table. The server's default
+// database is used otherwise.
+func WithDatabase(db string) SinkFunc {
+	return func(s *Sink) {
+		s.Database = db
+	}
+}
+
+// WithTable sets the name of the events table.
+func WithTable(table string) SinkFunc {
+	return func(s *Sink) {
+		s.Table = table
+	}
+}
+
+// WithBatchSize sets the number of rows sent per INSERT.
+func WithBatchSize(n int) SinkFunc {
+	return func(s *Sink) {
+		s.BatchSize = n
+	}
+}
+
+// WithCredentials sets the user and password requests authenticate as.
+func WithCredentials(user, password string) SinkFunc {
+	return func(s *Sink) {
+		s.Username = user
+		s.Password = password
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for queries.
+func WithHTTPClient(c *http.Client) SinkFunc {
+	return func(s *Sink) {
+		s.Client = c
+	}
+}
+
+// WithSinkSplitter sets the EncounterSplitter used to fill the encounter
+// columns.
+func WithSinkSplitter(sp *frostparse.EncounterSplitter) SinkFunc {
+	return func(s *Sink) {
+		s.Splitter = sp
+	}
+}
+
+// NewSink initializes and allocates a Sink writing to the ClickHouse
+// interface at u, http://localhost:8123 if empty, and applies any SinkFunc
+// options. A clickhouse://host:9000 URL selects the native interface.
+func NewSink(u string, opts ...SinkFunc) *Sink {
+	if u == "" {
+		u = defaultURL
+	}
+	s := &Sink{
+		URL:       u,
+		Table:     defaultTable,
+		BatchSize: 100000,
+		Client:    http.DefaultClient,
+		Splitter:  frostparse.NewEncounterSplitter(),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// table returns the quoted, database qualified name of the events table.
+func (s *Sink) table() string {
+	name := quoteIdent(s.Table)
+	if s.Database != "" {
+		name = quoteIdent(s.Database) + "." + name
+	}
+	return name
+}
+
+func quoteIdent(s string) string {
+	return "`" + strings.ReplaceAll(s, "`", "\\`") + "`"
+}
+
+// DDL returns the CREATE TABLE statement of the events table, which is
+// partitioned by day and encounter.
+func (s *Sink) DDL() string {
+	return fmt.Sprintf(schema, s.table())
+}
+
+// CreateTable creates the events table if it does not exist yet.
+func (s *Sink) CreateTable(ctx context.Context) error {
+	if s.isNative() {
+		return s.native(ctx, func(c *nativeConn) error {
+			return c.exec(s.DDL())
+		})
+	}
+	return s.exec(ctx, nil, strings.NewReader(s.DDL()))
+}
+
+// Write creates the events table and inserts the records in batches of
+// BatchSize rows, each tagged with the boss attempt or trash segment it
+// belongs to. ClickHouse inserts each batch atomically, but a failed Write
+// may leave earlier batches behind.
+func (s *Sink) Write(ctx context.Context, records []*frostparse.CombatLogRecord) error {
+	if s.isNative() {
+		return s.writeNative(ctx, records)
+	}
+	if err := s.CreateTable(ctx); err != nil {
+		return err
+	}
+	segments := s.segments(records)
+	size := max(s.BatchSize, 1)
+	query := url.Values{
+		"query":                            {fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", s.table())},
+		"date_time_input_format":           {"best_effort"},
+		"input_format_skip_unknown_fields": {"1"},
+	}
+	for start := 0; start < len(records); start += size {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		for _, rec := range records[start:min(start+size, len(records))] {
+			if err := enc.Encode(newRow(rec, segments)); err != nil {
+				return err
+			}
+		}
+		if err := s.exec(ctx, query, &buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// segments maps each record to the boss attempt or trash segment it belongs
+// to.
+func (s *Sink) segments(records []*frostparse.CombatLogRecord) map[*frostparse.CombatLogRecord]*frostparse.Encounter {
+	segments := map[*frostparse.CombatLogRecord]*frostparse.Encounter{}
+	all := s.Splitter.Segments(records)
+	for i := range all {
+		for _, rec := range all[i].Records {
+			segments[rec] = &all[i]
+		}
+	}
+	return segments
+}
+
+// newRow flattens a record into a row tagged with its segment.
+func newRow(rec *frostparse.CombatLogRecord, segments map[*frostparse.CombatLogRecord]*frostparse.Encounter) row {
+	r := row{Event: export.NewEvent(rec)}
+	if e, ok := segments[rec]; ok {
+		r.Encounter, r.Attempt, r.Trash = e.Name, e.Attempt, e.Trash
+		r.EncounterStart = &e.StartTime
+	}
+	return r
+}
+
+// exec POSTs a statement to the HTTP interface. The statement is either the
+// body or, for an INSERT, the query parameter followed by the data in body.
+func (s *Sink) exec(ctx context.Context, query url.Values, body io.Reader) error {
+	u := strings.TrimRight(s.URL, "/") + "/"
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, body)
+	if err != nil {
+		return err
+	}
+	if s.Username != "" {
+		req.Header.Set("X-ClickHouse-User", s.Username)
+		req.Header.Set("X-ClickHouse-Key", s.Password)
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("clickhouse: query failed with status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clickhouse
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+func testRecords(t *testing.T) []*frostparse.CombatLogRecord {
+	t.Helper()
+	data, err := frostparse.New(
+		frostparse.WithReader(strings.NewReader(strings.Join([]string{
+			`12/11 01:08:00.000  SPELL_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47610,"Frostfire Bolt",0x14,9000,0,16,0,0,0,nil,nil,nil`,
+			`12/11 01:08:01.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,250,0,1,0,0,0,nil,nil,nil`,
+			`12/11 01:08:02.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,3000,0,0,nil`,
+		}, "\n"))),
+		frostparse.WithStrictMode(true),
+	).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestSinkWrite(t *testing.T) {
+	var ddl []string
+	var inserts [][]map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-ClickHouse-User") != "raid" || r.Header.Get("X-ClickHouse-Key") != "secret" {
+			t.Errorf("unexpected credentials %q", r.Header)
+		}
+		q := r.URL.Query().Get("query")
+		if q == "" {
+			b, _ := io.ReadAll(r.Body)
+			ddl = append(ddl, string(b))
+			return
+		}
+		if q != "INSERT INTO `logs`.`events` FORMAT JSONEachRow" {
+			t.Errorf("unexpected query %q", q)
+		}
+		var rows []map[string]any
+		sc := bufio.NewScanner(r.Body)
+		for sc.Scan() {
+			var row map[string]any
+			if err := json.Unmarshal(sc.Bytes(), &row); err != nil {
+				t.Fatal(err)
+			}
+			rows = append(rows, row)
+		}
+		inserts = append(inserts, rows)
+	}))
+	defer srv.Close()
+
+	sink := NewSink(srv.URL, WithDatabase("logs"), WithBatchSize(2), WithCredentials("raid", "secret"))
+	if err := sink.Write(context.Background(), testRecords(t)); err != nil {
+		t.Fatal(err)
+	}
+	if len(ddl) != 1 || !strings.HasPrefix(ddl[0], "CREATE TABLE IF NOT EXISTS `logs`.`events`") {
+		t.Errorf("expected the table to be created, got %q", ddl)
+	}
+	if !strings.Contains(ddl[0], "PARTITION BY (toYYYYMMDD(timestamp), encounter)") {
+		t.Error("expected the table to be partitioned by day and encounter")
+	}
+	if len(inserts) != 2 || len(inserts[0]) != 2 || len(inserts[1]) != 1 {
+		t.Fatalf("expected batches of 2 and 1 rows, got %v", inserts)
+	}
+	swing := inserts[0][1]
+	if swing["event"] != "SWING_DAMAGE" || swing["amount"] != 250.0 || swing["spell_id"] != nil {
+		t.Errorf("unexpected swing row %v", swing)
+	}
+	if swing["encounter"] != "Lord Marrowgar" || swing["attempt"] != 1.0 || swing["encounter_start"] == nil {
+		t.Errorf("expected the swing to belong to the encounter, got %v", swing)
+	}
+}
+
+func TestSinkWriteError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Code: 60. DB::Exception: Unknown table", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	err := NewSink(srv.URL).Write(context.Background(), testRecords(t))
+	if err == nil || !strings.Contains(err.Error(), "status 404: Code: 60.") {
+		t.Errorf("expected the server error, got %v", err)
+	}
+}
@@ -0,0 +1,540 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clickhouse
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+const (
+	defaultNativePort = "9000"
+	nativeClientName  = "frostparse"
+	nativeClientMajor = 1
+	nativeClientMinor = 0
+	// nativeRevision is the protocol revision the client speaks. The server
+	// answers with its own, and the lower of the two decides which fields
+	// the packets carry.
+	nativeRevision = 54429
+
+	revisionServerTimezone    = 54058
+	revisionQuotaKey          = 54060
+	revisionServerDisplayName = 54372
+	revisionVersionPatch      = 54401
+	revisionWriteInfo         = 54420
+
+	maxNativeString = 1 << 30
+)
+
+// client packets
+const (
+	clientHello = 0
+	clientQuery = 1
+	clientData  = 2
+)
+
+// server packets
+const (
+	serverHello        = 0
+	serverData         = 1
+	serverException    = 2
+	serverProgress     = 3
+	serverEndOfStream  = 5
+	serverProfileInfo  = 6
+	serverTableColumns = 11
+)
+
+// queryStageComplete asks the server to run the query to completion.
+const queryStageComplete = 2
+
+// isNative reports whether the sink's URL addresses the native TCP interface.
+func (s *Sink) isNative() bool {
+	u, err := url.Parse(s.URL)
+	return err == nil && (u.Scheme == "clickhouse" || u.Scheme == "tcp")
+}
+
+// native connects to the native interface, runs f and closes the connection
+// when f returns or ctx is done.
+func (s *Sink) native(ctx context.Context, f func(*nativeConn) error) error {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return err
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), defaultNativePort)
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	c := &nativeConn{
+		enc: nativeEncoder{w: bufio.NewWriter(conn)},
+		dec: nativeDecoder{r: bufio.NewReader(conn)},
+	}
+	err = c.hello(s.Database, s.Username, s.Password)
+	if err == nil {
+		err = f(c)
+	}
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// writeNative creates the events table and inserts the records over a single
+// native connection, as one INSERT with a block of BatchSize rows at a time.
+func (s *Sink) writeNative(ctx context.Context, records []*frostparse.CombatLogRecord) error {
+	return s.native(ctx, func(c *nativeConn) error {
+		if err := c.exec(s.DDL()); err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		names := make([]string, len(nativeColumns))
+		for i, col := range nativeColumns {
+			names[i] = quoteIdent(col.name)
+		}
+		c.query(fmt.Sprintf("INSERT INTO %s (%s) VALUES", s.table(), strings.Join(names, ", ")))
+		if err := c.receive(true); err != nil {
+			return err
+		}
+		segments := s.segments(records)
+		size := max(s.BatchSize, 1)
+		rows := make([]row, 0, min(size, len(records)))
+		for start := 0; start < len(records); start += size {
+			rows = rows[:0]
+			for _, rec := range records[start:min(start+size, len(records))] {
+				rows = append(rows, newRow(rec, segments))
+			}
+			c.block(nativeColumns, rows)
+			if err := c.enc.flush(); err != nil {
+				return err
+			}
+		}
+		// an empty block ends the INSERT
+		c.block(nil, nil)
+		if err := c.enc.flush(); err != nil {
+			return err
+		}
+		return c.receive(false)
+	})
+}
+
+// nativeColumn is a column of the events table in the Native format.
+type nativeColumn struct {
+	name string
+	typ  string
+	// write encodes the column's values of rows.
+	write func(e *nativeEncoder, rows []row)
+}
+
+func column[T any](name, typ string, get func(*row) T, put func(*nativeEncoder, T)) nativeColumn {
+	return nativeColumn{name, typ, func(e *nativeEncoder, rows []row) {
+		for i := range rows {
+			put(e, get(&rows[i]))
+		}
+	}}
+}
+
+// nullable is a Nullable(typ) column, encoded as a null map followed by the
+// values, with the zero value in place of each null.
+func nullable[T any](name, typ string, get func(*row) *T, put func(*nativeEncoder, T)) nativeColumn {
+	return nativeColumn{name, "Nullable(" + typ + ")", func(e *nativeEncoder, rows []row) {
+		for i := range rows {
+			e.bool(get(&rows[i]) == nil)
+		}
+		var zero T
+		for i := range rows {
+			if v := get(&rows[i]); v != nil {
+				put(e, *v)
+			} else {
+				put(e, zero)
+			}
+		}
+	}}
+}
+
+func putSmallUint(e *nativeEncoder, v int) {
+	e.uint8(uint8(v))
+}
+
+func putSmallInt(e *nativeEncoder, v int) {
+	e.uint8(uint8(int8(v)))
+}
+
+// nativeColumns are the columns of schema in order. LowCardinality columns
+// are sent as their plain type, which the server converts on insert.
+var nativeColumns = []nativeColumn{
+	column("timestamp", "DateTime64(3, 'UTC')", func(r *row) time.Time { return r.Timestamp }, (*nativeEncoder).datetime64),
+	column("event", "String", func(r *row) string { return r.EventType }, (*nativeEncoder).string),
+	column("encounter", "String", func(r *row) string { return r.Encounter }, (*nativeEncoder).string),
+	column("attempt", "UInt16", func(r *row) int { return r.Attempt }, func(e *nativeEncoder, v int) { e.uint16(uint16(v)) }),
+	column("trash", "Bool", func(r *row) bool { return r.Trash }, (*nativeEncoder).bool),
+	nullable("encounter_start", "DateTime64(3, 'UTC')", func(r *row) *time.Time { return r.EncounterStart }, (*nativeEncoder).datetime64),
+	column("source_id", "String", func(r *row) string { return r.SourceID }, (*nativeEncoder).string),
+	column("source_name", "String", func(r *row) string { return r.SourceName }, (*nativeEncoder).string),
+	column("source_flags", "UInt32", func(r *row) uint32 { return r.SourceFlags }, (*nativeEncoder).uint32),
+	column("target_id", "String", func(r *row) string { return r.TargetID }, (*nativeEncoder).string),
+	column("target_name", "String", func(r *row) string { return r.TargetName }, (*nativeEncoder).string),
+	column("target_flags", "UInt32", func(r *row) uint32 { return r.TargetFlags }, (*nativeEncoder).uint32),
+	nullable("spell_id", "UInt64", func(r *row) *uint64 { return r.SpellID }, (*nativeEncoder).uint64),
+	nullable("spell_name", "String", func(r *row) *string { return r.SpellName }, (*nativeEncoder).string),
+	nullable("spell_school", "UInt8", func(r *row) *int { return r.SpellSchool }, putSmallUint),
+	nullable("item_id", "UInt64", func(r *row) *uint64 { return r.ItemID }, (*nativeEncoder).uint64),
+	nullable("item_name", "String", func(r *row) *string { return r.ItemName }, (*nativeEncoder).string),
+	nullable("environmental_type", "String", func(r *row) *string { return r.EnvironmentalType }, (*nativeEncoder).string),
+	nullable("amount", "Int64", func(r *row) *int64 { return r.Amount }, (*nativeEncoder).int64),
+	nullable("overkill", "UInt64", func(r *row) *uint64 { return r.Overkill }, (*nativeEncoder).uint64),
+	nullable("school", "UInt8", func(r *row) *int { return r.School }, putSmallUint),
+	nullable("resisted", "UInt64", func(r *row) *uint64 { return r.Resisted }, (*nativeEncoder).uint64),
+	nullable("blocked", "UInt64", func(r *row) *uint64 { return r.Blocked }, (*nativeEncoder).uint64),
+	nullable("absorbed", "UInt64", func(r *row) *uint64 { return r.Absorbed }, (*nativeEncoder).uint64),
+	nullable("overhealing", "UInt64", func(r *row) *uint64 { return r.Overhealing }, (*nativeEncoder).uint64),
+	nullable("critical", "Bool", func(r *row) *bool { return r.Critical }, (*nativeEncoder).bool),
+	nullable("glancing", "Bool", func(r *row) *bool { return r.Glancing }, (*nativeEncoder).bool),
+	nullable("crushing", "Bool", func(r *row) *bool { return r.Crushing }, (*nativeEncoder).bool),
+	nullable("miss_type", "String", func(r *row) *string { return r.MissType }, (*nativeEncoder).string),
+	nullable("aura_type", "String", func(r *row) *string { return r.AuraType }, (*nativeEncoder).string),
+	nullable("doses", "UInt64", func(r *row) *uint64 { return r.Doses }, (*nativeEncoder).uint64),
+	nullable("power_type", "Int8", func(r *row) *int { return r.PowerType }, putSmallInt),
+	nullable("extra_amount", "UInt64", func(r *row) *uint64 { return r.ExtraAmount }, (*nativeEncoder).uint64),
+	nullable("extra_spell_id", "UInt64", func(r *row) *uint64 { return r.ExtraSpellID }, (*nativeEncoder).uint64),
+	nullable("extra_spell_name", "String", func(r *row) *string { return r.ExtraSpellName }, (*nativeEncoder).string),
+	nullable("extra_spell_school", "UInt8", func(r *row) *int { return r.ExtraSpellSchool }, putSmallUint),
+}
+
+// nativeConn is a connection to the native interface that has completed the
+// handshake.
+type nativeConn struct {
+	enc      nativeEncoder
+	dec      nativeDecoder
+	revision uint64
+}
+
+// hello authenticates and negotiates the protocol revision.
+func (c *nativeConn) hello(database, user, password string) error {
+	if user == "" {
+		user = "default"
+	}
+	e := &c.enc
+	e.uvarint(clientHello)
+	e.string(nativeClientName)
+	e.uvarint(nativeClientMajor)
+	e.uvarint(nativeClientMinor)
+	e.uvarint(nativeRevision)
+	e.string(database)
+	e.string(user)
+	e.string(password)
+	if err := e.flush(); err != nil {
+		return err
+	}
+
+	d := &c.dec
+	switch p := d.uvarint(); {
+	case d.err != nil:
+		return d.err
+	case p == serverException:
+		return d.exception()
+	case p != serverHello:
+		return fmt.Errorf("clickhouse: unexpected packet %d in handshake", p)
+	}
+	d.string()  // server name
+	d.uvarint() // major version
+	d.uvarint() // minor version
+	c.revision = min(d.uvarint(), nativeRevision)
+	if c.revision >= revisionServerTimezone {
+		d.string()
+	}
+	if c.revision >= revisionServerDisplayName {
+		d.string()
+	}
+	if c.revision >= revisionVersionPatch {
+		d.uvarint()
+	}
+	return d.err
+}
+
+// exec runs a statement that returns no rows.
+func (c *nativeConn) exec(q string) error {
+	c.query(q)
+	return c.receive(false)
+}
+
+// query sends a query with no settings and no external tables.
+func (c *nativeConn) query(q string) {
+	hostname, _ := os.Hostname()
+	e := &c.enc
+	e.uvarint(clientQuery)
+	e.string("") // query id, assigned by the server
+	// client info of an initial query over TCP
+	e.uint8(1)
+	e.string("")          // initial user
+	e.string("")          // initial query id
+	e.string("0.0.0.0:0") // initial address
+	e.uint8(1)
+	e.string("") // os user
+	e.string(hostname)
+	e.string(nativeClientName)
+	e.uvarint(nativeClientMajor)
+	e.uvarint(nativeClientMinor)
+	e.uvarint(nativeRevision)
+	if c.revision >= revisionQuotaKey {
+		e.string("")
+	}
+	if c.revision >= revisionVersionPatch {
+		e.uvarint(0)
+	}
+	e.string("") // end of settings
+	e.uvarint(queryStageComplete)
+	e.uvarint(0) // uncompressed
+	e.string(q)
+	c.block(nil, nil)
+}
+
+// block sends a data block of the columns of rows.
+func (c *nativeConn) block(columns []nativeColumn, rows []row) {
+	e := &c.enc
+	e.uvarint(clientData)
+	e.string("") // temporary table name
+	// block info: not an overflow block, no bucket
+	e.uvarint(1)
+	e.uint8(0)
+	e.uvarint(2)
+	e.uint32(^uint32(0))
+	e.uvarint(0)
+	e.uvarint(uint64(len(columns)))
+	e.uvarint(uint64(len(rows)))
+	for _, col := range columns {
+		e.string(col.name)
+		e.string(col.typ)
+		col.write(e, rows)
+	}
+}
+
+// receive reads packets until the end of the query or, with header set,
+// until the sample block the server answers an INSERT with.
+func (c *nativeConn) receive(header bool) error {
+	if err := c.enc.flush(); err != nil {
+		return err
+	}
+	d := &c.dec
+	for {
+		p := d.uvarint()
+		if d.err != nil {
+			return d.err
+		}
+		switch p {
+		case serverData:
+			d.skipBlock()
+			if header {
+				return d.err
+			}
+		case serverException:
+			return d.exception()
+		case serverProgress:
+			d.uvarint() // rows
+			d.uvarint() // bytes
+			d.uvarint() // total rows
+			if c.revision >= revisionWriteInfo {
+				d.uvarint() // written rows
+				d.uvarint() // written bytes
+			}
+		case serverProfileInfo:
+			d.uvarint() // rows
+			d.uvarint() // blocks
+			d.uvarint() // bytes
+			d.uint8()   // applied limit
+			d.uvarint() // rows before limit
+			d.uint8()   // calculated rows before limit
+		case serverTableColumns:
+			d.string() // table name
+			d.string() // column descriptions
+		case serverEndOfStream:
+			if header {
+				return errors.New("clickhouse: query ended before the INSERT header")
+			}
+			return nil
+		default:
+			return fmt.Errorf("clickhouse: unexpected packet %d", p)
+		}
+	}
+}
+
+type nativeEncoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (e *nativeEncoder) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.Write(b)
+}
+
+func (e *nativeEncoder) flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	e.err = e.w.Flush()
+	return e.err
+}
+
+func (e *nativeEncoder) uvarint(v uint64) {
+	e.write(binary.AppendUvarint(e.buf[:0], v))
+}
+
+func (e *nativeEncoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.WriteString(s)
+}
+
+func (e *nativeEncoder) uint8(v uint8) {
+	e.write(append(e.buf[:0], v))
+}
+
+func (e *nativeEncoder) bool(b bool) {
+	if b {
+		e.uint8(1)
+		return
+	}
+	e.uint8(0)
+}
+
+func (e *nativeEncoder) uint16(v uint16) {
+	e.write(binary.LittleEndian.AppendUint16(e.buf[:0], v))
+}
+
+func (e *nativeEncoder) uint32(v uint32) {
+	e.write(binary.LittleEndian.AppendUint32(e.buf[:0], v))
+}
+
+func (e *nativeEncoder) uint64(v uint64) {
+	e.write(binary.LittleEndian.AppendUint64(e.buf[:0], v))
+}
+
+func (e *nativeEncoder) int64(v int64) {
+	e.uint64(uint64(v))
+}
+
+// datetime64 writes a DateTime64(3) as milliseconds since the epoch.
+func (e *nativeEncoder) datetime64(t time.Time) {
+	e.int64(t.UnixMilli())
+}
+
+type nativeDecoder struct {
+	r   *bufio.Reader
+	buf [8]byte
+	err error
+}
+
+func (d *nativeDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	var v uint64
+	v, d.err = binary.ReadUvarint(d.r)
+	return v
+}
+
+func (d *nativeDecoder) string() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if n > maxNativeString {
+		d.err = fmt.Errorf("clickhouse: string of %d bytes", n)
+		return ""
+	}
+	b := make([]byte, n)
+	_, d.err = io.ReadFull(d.r, b)
+	return string(b)
+}
+
+// fixed reads a little endian integer of n bytes.
+func (d *nativeDecoder) fixed(n int) uint64 {
+	if d.err != nil {
+		return 0
+	}
+	clear(d.buf[:])
+	if _, d.err = io.ReadFull(d.r, d.buf[:n]); d.err != nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(d.buf[:])
+}
+
+func (d *nativeDecoder) uint8() uint8 {
+	return uint8(d.fixed(1))
+}
+
+// skipBlock reads a data block, which the sink only receives without rows.
+func (d *nativeDecoder) skipBlock() {
+	d.string() // temporary table name
+	for field := d.uvarint(); field != 0 && d.err == nil; field = d.uvarint() {
+		switch field {
+		case 1:
+			d.uint8() // overflows
+		case 2:
+			d.fixed(4) // bucket
+		default:
+			d.err = fmt.Errorf("clickhouse: unknown block info field %d", field)
+		}
+	}
+	columns := d.uvarint()
+	if rows := d.uvarint(); rows > 0 && d.err == nil {
+		d.err = fmt.Errorf("clickhouse: unexpected block of %d rows", rows)
+	}
+	for i := uint64(0); i < columns && d.err == nil; i++ {
+		d.string() // name
+		d.string() // type
+	}
+}
+
+// exception reads an exception packet into an error.
+func (d *nativeDecoder) exception() error {
+	code := int32(d.fixed(4))
+	d.string() // name
+	msg := d.string()
+	if d.err != nil {
+		return d.err
+	}
+	return fmt.Errorf("clickhouse: query failed with code %d: %s", code, strings.TrimSpace(msg))
+}
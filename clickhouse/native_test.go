@@ -0,0 +1,263 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clickhouse
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeServer speaks enough of the native protocol to receive DDL and INSERT
+// blocks, decoding each block into columns of values.
+type fakeServer struct {
+	ln        net.Listener
+	exception string
+
+	mu      sync.Mutex
+	hello   []string
+	queries []string
+	blocks  []map[string][]any
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	srv := &fakeServer{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+	return srv
+}
+
+func (srv *fakeServer) url() string {
+	return "clickhouse://" + srv.ln.Addr().String()
+}
+
+func (srv *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	e := &nativeEncoder{w: bufio.NewWriter(conn)}
+	d := &nativeDecoder{r: bufio.NewReader(conn)}
+
+	d.uvarint() // hello
+	name := d.string()
+	d.uvarint()
+	d.uvarint()
+	d.uvarint() // revision
+	hello := []string{name, d.string(), d.string(), d.string()}
+	srv.mu.Lock()
+	srv.hello = hello
+	srv.mu.Unlock()
+	e.uvarint(serverHello)
+	e.string("ClickHouse")
+	e.uvarint(23)
+	e.uvarint(8)
+	e.uvarint(nativeRevision)
+	e.string("UTC")
+	e.string("fake")
+	e.uvarint(1)
+	e.flush()
+
+	for d.uvarint() == clientQuery && d.err == nil {
+		d.string()  // query id
+		d.uint8()   // query kind
+		d.string()  // initial user
+		d.string()  // initial query id
+		d.string()  // initial address
+		d.uint8()   // interface
+		d.string()  // os user
+		d.string()  // hostname
+		d.string()  // client name
+		d.uvarint() // major
+		d.uvarint() // minor
+		d.uvarint() // revision
+		d.string()  // quota key
+		d.uvarint() // patch
+		d.string()  // settings
+		d.uvarint() // stage
+		d.uvarint() // compression
+		q := d.string()
+		srv.readBlock(d) // external tables
+		srv.mu.Lock()
+		srv.queries = append(srv.queries, q)
+		srv.mu.Unlock()
+
+		switch {
+		case srv.exception != "":
+			e.uvarint(serverException)
+			e.uint32(60)
+			e.string("DB::Exception")
+			e.string(srv.exception)
+			e.string("")
+			e.uint8(0)
+		case strings.HasPrefix(q, "INSERT"):
+			e.uvarint(serverTableColumns)
+			e.string("")
+			e.string("columns format version: 1")
+			e.uvarint(serverData)
+			e.string("")
+			e.uvarint(0) // end of block info
+			e.uvarint(1)
+			e.uvarint(0)
+			e.string("event")
+			e.string("LowCardinality(String)")
+			e.flush()
+			for {
+				block := srv.readBlock(d)
+				if d.err != nil || block == nil {
+					break
+				}
+				srv.mu.Lock()
+				srv.blocks = append(srv.blocks, block)
+				srv.mu.Unlock()
+			}
+			e.uvarint(serverProgress)
+			for i := 0; i < 5; i++ {
+				e.uvarint(0)
+			}
+			e.uvarint(serverEndOfStream)
+		default:
+			e.uvarint(serverEndOfStream)
+		}
+		e.flush()
+	}
+}
+
+// readBlock reads a client data packet, returning nil for an empty block.
+func (srv *fakeServer) readBlock(d *nativeDecoder) map[string][]any {
+	d.uvarint() // data
+	d.string()
+	for field := d.uvarint(); field != 0 && d.err == nil; field = d.uvarint() {
+		if field == 1 {
+			d.uint8()
+		} else {
+			d.fixed(4)
+		}
+	}
+	columns, rows := d.uvarint(), int(d.uvarint())
+	if columns == 0 {
+		return nil
+	}
+	block := map[string][]any{}
+	for i := uint64(0); i < columns && d.err == nil; i++ {
+		name, typ := d.string(), d.string()
+		block[name] = decodeColumn(d, typ, rows)
+	}
+	return block
+}
+
+func decodeColumn(d *nativeDecoder, typ string, rows int) []any {
+	if inner, ok := strings.CutPrefix(typ, "Nullable("); ok {
+		nulls := make([]bool, rows)
+		for i := range nulls {
+			nulls[i] = d.uint8() == 1
+		}
+		values := decodeColumn(d, strings.TrimSuffix(inner, ")"), rows)
+		for i, null := range nulls {
+			if null {
+				values[i] = nil
+			}
+		}
+		return values
+	}
+	values := make([]any, rows)
+	for i := range values {
+		switch typ {
+		case "String":
+			values[i] = d.string()
+		case "UInt8", "Int8", "Bool":
+			values[i] = d.fixed(1)
+		case "UInt16":
+			values[i] = d.fixed(2)
+		case "UInt32":
+			values[i] = d.fixed(4)
+		case "UInt64", "Int64", "DateTime64(3, 'UTC')":
+			values[i] = d.fixed(8)
+		default:
+			d.err = fmt.Errorf("unsupported type %s", typ)
+		}
+	}
+	return values
+}
+
+func TestSinkWriteNative(t *testing.T) {
+	srv := newFakeServer(t)
+	records := testRecords(t)
+	sink := NewSink(srv.url(), WithDatabase("logs"), WithBatchSize(2), WithCredentials("raid", "secret"))
+	if err := sink.Write(context.Background(), records); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if strings.Join(srv.hello, " ") != "frostparse logs raid secret" {
+		t.Errorf("unexpected hello %q", srv.hello)
+	}
+	if len(srv.queries) != 2 || !strings.HasPrefix(srv.queries[0], "CREATE TABLE IF NOT EXISTS `logs`.`events`") {
+		t.Fatalf("expected the table to be created, got %q", srv.queries)
+	}
+	if !strings.HasPrefix(srv.queries[1], "INSERT INTO `logs`.`events` (`timestamp`, `event`,") {
+		t.Errorf("unexpected insert %q", srv.queries[1])
+	}
+	if len(srv.blocks) != 2 || len(srv.blocks[0]["event"]) != 2 || len(srv.blocks[1]["event"]) != 1 {
+		t.Fatalf("expected blocks of 2 and 1 rows, got %v", srv.blocks)
+	}
+	if len(srv.blocks[0]) != len(nativeColumns) {
+		t.Errorf("expected %d columns, got %d", len(nativeColumns), len(srv.blocks[0]))
+	}
+	swing := map[string]any{}
+	for name, values := range srv.blocks[0] {
+		swing[name] = values[1]
+	}
+	if swing["event"] != "SWING_DAMAGE" || swing["amount"] != uint64(250) || swing["spell_id"] != nil {
+		t.Errorf("unexpected swing row %v", swing)
+	}
+	if swing["timestamp"] != uint64(records[1].Timestamp.UnixMilli()) {
+		t.Errorf("expected the timestamp in milliseconds, got %v", swing["timestamp"])
+	}
+	if swing["encounter"] != "Lord Marrowgar" || swing["attempt"] != uint64(1) || swing["encounter_start"] == nil {
+		t.Errorf("expected the swing to belong to the encounter, got %v", swing)
+	}
+	heal := srv.blocks[1]
+	if heal["overhealing"][0] != uint64(0) || heal["critical"][0] != uint64(0) || heal["spell_name"][0] != "Riptide" {
+		t.Errorf("unexpected heal row %v", heal)
+	}
+}
+
+func TestSinkWriteNativeError(t *testing.T) {
+	srv := newFakeServer(t)
+	srv.exception = "DB::Exception: Unknown table"
+
+	err := NewSink(srv.url()).Write(context.Background(), testRecords(t))
+	if err == nil || !strings.Contains(err.Error(), "code 60: DB::Exception: Unknown table") {
+		t.Errorf("expected the server exception, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "time"
+
+// referenceSlack is how far past the reference time the first line of a log
+// may be and still be taken to be in the reference year, to allow for the
+// time zone of the machine that recorded the log.
+const referenceSlack = 24 * time.Hour
+
+// WithLogYear sets the year the first line of the log was written in,
+// instead of working it out from the reference time.
+func WithLogYear(year int) ParserFunc {
+	return func(p *Parser) {
+		p.LogYear = year
+	}
+}
+
+// WithReferenceTime sets the time the log is known to have been written by,
+// such as the modification time of the log file, which defaults to the time
+// parsing starts. Combat log lines carry no year, so the log is taken to
+// start in the year of the reference time, or the year before when its first
+// line would otherwise be after the reference time.
+func WithReferenceTime(t time.Time) ParserFunc {
+	return func(p *Parser) {
+		p.ReferenceTime = t
+	}
+}
+
+// WithLocation sets the time zone of the machine that recorded the log, which
+// timestamps are local to. Timestamps are in UTC by default.
+func WithLocation(loc *time.Location) ParserFunc {
+	return func(p *Parser) {
+		p.Location = loc
+	}
+}
+
+// WithUTCTimestamps converts timestamps read in the location set by
+// WithLocation to UTC, so logs recorded in different zones line up.
+func WithUTCTimestamps(utc bool) ParserFunc {
+	return func(p *Parser) {
+		p.UTCTimestamps = utc
+	}
+}
+
+// logClock assigns years to the timestamps of a log, which only carry the
+// month and day. The year advances whenever the month goes from December to
+// January, so a log running past New Year's Eve stays in order.
+type logClock struct {
+	year int
+	// loc is the location timestamps are parsed in, UTC when nil.
+	loc *time.Location
+	// utc converts timestamps to UTC once they are parsed.
+	utc bool
+	// ref is the reference time plus referenceSlack. It is zero when the
+	// year is known.
+	ref time.Time
+	// month is the month of the previous line, or zero before the first.
+	month time.Month
+	// fixed stops the year from advancing, for single lines.
+	fixed bool
+}
+
+// newClock returns the clock the lines of a parse are stamped with.
+func (p *Parser) newClock() *logClock {
+	c := &logClock{year: p.LogYear, loc: p.Location, utc: p.UTCTimestamps}
+	if p.LogYear != 0 {
+		return c
+	}
+	ref := p.ReferenceTime
+	if ref.IsZero() {
+		ref = time.Now()
+	}
+	if p.Location != nil {
+		ref = ref.In(p.Location)
+	} else {
+		// Without a location the reference is compared to timestamps by its
+		// local wall clock, which is also how they were recorded.
+		ref = time.Date(ref.Year(), ref.Month(), ref.Day(), ref.Hour(), ref.Minute(), ref.Second(), ref.Nanosecond(), time.UTC)
+	}
+	c.year = ref.Year()
+	c.ref = ref.Add(referenceSlack)
+	return c
+}
+
+// stamp parses the timestamp of the next line of the log.
+func (c *logClock) stamp(ts []byte) (time.Time, error) {
+	loc := c.loc
+	if loc == nil {
+		loc = time.UTC
+	}
+	t, err := parseTimestamp(ts, c.year, loc)
+	if err == nil && !c.fixed {
+		year := c.year
+		switch {
+		case c.month == 0 && !c.ref.IsZero() && t.After(c.ref):
+			c.year--
+		case c.month == time.December && t.Month() == time.January:
+			c.year++
+		}
+		c.month = t.Month()
+		if c.year != year {
+			t, err = parseTimestamp(ts, c.year, loc)
+		}
+	}
+	if err != nil || !c.utc {
+		return t, err
+	}
+	return t.UTC(), nil
+}
+
+// relocate puts the timestamps of records loaded from the Cache in the time
+// zone a parse stamps them in, because the .fpb format only keeps the instant.
+func (p *Parser) relocate(records []*CombatLogRecord) {
+	loc := p.Location
+	if loc == nil || p.UTCTimestamps {
+		loc = time.UTC
+	}
+	for _, r := range records {
+		r.Timestamp = r.Timestamp.In(loc)
+		if !r.AdjustedTimestamp.IsZero() {
+			r.AdjustedTimestamp = r.AdjustedTimestamp.In(loc)
+		}
+	}
+}
+
+// monotonicClock sets the AdjustedTimestamp of records in log order. A record
+// stamped at or before the previous one is moved to a nanosecond after it,
+// so adjusted timestamps never decrease and lines sharing a millisecond keep
+// their order. This holds for large jumps back too, such as the clock of the
+// machine being changed; Timestamp keeps the time the line was stamped with.
+type monotonicClock struct {
+	last time.Time
+}
+
+func (c *monotonicClock) adjust(r *CombatLogRecord) {
+	t := r.Timestamp
+	if !c.last.IsZero() && !t.After(c.last) {
+		t = c.last.Add(time.Nanosecond)
+	}
+	r.AdjustedTimestamp = t
+	c.last = t
+}
+
+// adjustment is how far the AdjustedTimestamp of r was moved from its
+// Timestamp, zero when it was never set.
+func (r *BaseCombatEvent) adjustment() time.Duration {
+	if r.AdjustedTimestamp.IsZero() {
+		return 0
+	}
+	return r.AdjustedTimestamp.Sub(r.Timestamp)
+}
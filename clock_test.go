@@ -0,0 +1,160 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeRolloverLog(t *testing.T) string {
+	t.Helper()
+	var lines []string
+	for _, ts := range []string{"12/31 23:59:58.000", "12/31 23:59:59.500", "1/1 00:00:00.250", "1/1 00:00:01.000"} {
+		for i := 0; i < 50; i++ {
+			lines = append(lines, ts+`  SWING_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130007E6B000063,"Frostbrood Whelp",0xa48,84,0,1,nil,nil,nil,nil,nil,nil`)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "WoWCombatLog.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParserYearRollover(t *testing.T) {
+	path := writeRolloverLog(t)
+	for _, n := range []int{1, 4} {
+		data, err := New(WithLogFile(path), WithLogYear(2010), WithParallelism(n)).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i < len(data); i++ {
+			if data[i].Timestamp.Before(data[i-1].Timestamp) {
+				t.Fatalf("parallelism %d: expected timestamps in order, got %v after %v", n, data[i].Timestamp, data[i-1].Timestamp)
+			}
+		}
+		if y := data[0].Timestamp.Year(); y != 2010 {
+			t.Errorf("parallelism %d: expected log to start in 2010, got %d", n, y)
+		}
+		if y := data[len(data)-1].Timestamp.Year(); y != 2011 {
+			t.Errorf("parallelism %d: expected log to end in 2011, got %d", n, y)
+		}
+	}
+}
+
+func TestParserReferenceTime(t *testing.T) {
+	path := writeRolloverLog(t)
+	cases := []struct {
+		ref  time.Time
+		want int
+	}{
+		{time.Date(2011, time.January, 1, 0, 5, 0, 0, time.UTC), 2010},
+		{time.Date(2010, time.December, 31, 23, 0, 0, 0, time.UTC), 2010},
+		{time.Date(2011, time.June, 1, 0, 0, 0, 0, time.UTC), 2010},
+		{time.Date(2011, time.December, 31, 23, 59, 59, 0, time.UTC), 2011},
+	}
+	for _, tc := range cases {
+		data, err := New(WithLogFile(path), WithReferenceTime(tc.ref)).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if y := data[0].Timestamp.Year(); y != tc.want {
+			t.Errorf("reference %v: expected log to start in %d, got %d", tc.ref, tc.want, y)
+		}
+	}
+}
+
+func TestParserLocation(t *testing.T) {
+	loc := time.FixedZone("CET", 3600)
+	line := `12/11 00:13:37.531  SWING_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130007E6B000063,"Frostbrood Whelp",0xa48,84,0,1,nil,nil,nil,nil,nil,nil`
+	want := time.Date(2010, time.December, 11, 0, 13, 37, 531*int(time.Millisecond), loc)
+	for _, utc := range []bool{false, true} {
+		data, err := New(
+			WithReader(strings.NewReader(line)),
+			WithLogYear(2010),
+			WithLocation(loc),
+			WithUTCTimestamps(utc),
+		).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := data[0].Timestamp
+		if !got.Equal(want) {
+			t.Errorf("utc %v: expected %s, got %s", utc, want, got)
+		}
+		if wantLoc := map[bool]*time.Location{false: loc, true: time.UTC}[utc]; got.Location() != wantLoc {
+			t.Errorf("utc %v: expected timestamp in %s, got %s", utc, wantLoc, got.Location())
+		}
+	}
+}
+
+func TestParserAdjustedTimestamps(t *testing.T) {
+	const event = `  SWING_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130007E6B000063,"Frostbrood Whelp",0xa48,84,0,1,nil,nil,nil,nil,nil,nil`
+	lines := []string{
+		"12/11 00:13:06.105" + event,
+		"12/11 00:13:06.105" + event,
+		"12/11 00:13:05.900" + event,
+		"12/11 00:13:06.300" + event,
+		"12/11 00:12:00.000" + event,
+	}
+	data, err := New(WithReader(strings.NewReader(strings.Join(lines, "\n"))), WithLogYear(2010)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := data[0].Timestamp
+	want := []time.Time{
+		raw,
+		raw.Add(time.Nanosecond),
+		raw.Add(2 * time.Nanosecond),
+		raw.Add(195 * time.Millisecond),
+		raw.Add(195*time.Millisecond + time.Nanosecond),
+	}
+	for i, w := range want {
+		if !data[i].AdjustedTimestamp.Equal(w) {
+			t.Errorf("line %d: expected adjusted timestamp %v, got %v", i, w, data[i].AdjustedTimestamp)
+		}
+	}
+	if !data[2].Timestamp.Equal(raw.Add(-205 * time.Millisecond)) {
+		t.Errorf("expected the raw timestamp to be kept, got %v", data[2].Timestamp)
+	}
+}
+
+func TestParserAdjustedTimestampsClockChange(t *testing.T) {
+	const event = `  SWING_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130007E6B000063,"Frostbrood Whelp",0xa48,84,0,1,nil,nil,nil,nil,nil,nil`
+	lines := []string{
+		"12/11 20:15:42.123" + event,
+		"12/11 20:15:30.000" + event,
+		"12/11 20:15:31.000" + event,
+	}
+	data, err := New(WithReader(strings.NewReader(strings.Join(lines, "\n"))), WithLogYear(2010)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := data[0].Timestamp
+	for i, w := range []time.Time{raw, raw.Add(time.Nanosecond), raw.Add(2 * time.Nanosecond)} {
+		if !data[i].AdjustedTimestamp.Equal(w) {
+			t.Errorf("line %d: expected adjusted timestamp %v after the clock went back, got %v", i, w, data[i].AdjustedTimestamp)
+		}
+	}
+	if !data[1].Timestamp.Equal(raw.Add(-12123 * time.Millisecond)) {
+		t.Errorf("expected the raw timestamp to be kept, got %v", data[1].Timestamp)
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// now is the clock annotations are stamped with, replaced in tests.
+var now = time.Now
+
+func runAnnotate(args []string, stdout, _ io.Writer) error {
+	fs := newFlagSet("annotate")
+	notes := fs.String("notes", "annotations.json", "file the notes are kept in")
+	name := fs.String("encounter", "", "boss or trash segment the note is about")
+	attempt := fs.Int("attempt", 1, "attempt number of the boss")
+	at := fs.Duration("at", 0, "how far into the encounter the note refers to")
+	author := fs.String("author", "", "who wrote the note")
+	note := fs.String("note", "", "the note")
+	var in logInput
+	in.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" || *note == "" {
+		return fmt.Errorf("%s: -encounter and -note are required", fs.Name())
+	}
+	data, _, err := in.parse(fs)
+	if err != nil {
+		return err
+	}
+	var target *frostparse.Encounter
+	segments := frostparse.NewEncounterSplitter().Segments(data)
+	for i, e := range segments {
+		if e.Name == *name && (e.Trash || e.Attempt == *attempt) {
+			target = &segments[i]
+			break
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("%s: no encounter %q attempt %d in the log", fs.Name(), *name, *attempt)
+	}
+	existing, err := readNotes(*notes)
+	if err != nil {
+		return err
+	}
+	existing = append(existing, frostparse.Annotation{
+		Encounter: target.Key(),
+		Offset:    *at,
+		Author:    *author,
+		Note:      *note,
+		Created:   now(),
+	})
+	f, err := os.Create(*notes)
+	if err != nil {
+		return err
+	}
+	if err := frostparse.WriteAnnotations(f, existing); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "annotated %s attempt %d in %s\n", target.Name, target.Attempt, *notes)
+	return nil
+}
+
+// readNotes reads the annotations kept in path, which may not exist yet.
+func readNotes(path string) (frostparse.Annotations, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return frostparse.ReadAnnotations(f)
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunAnnotate(t *testing.T) {
+	now = func() time.Time { return time.Date(2023, 12, 12, 0, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+	log := writeTestLog(t)
+	notes := filepath.Join(t.TempDir(), "notes.json")
+	var out bytes.Buffer
+	if err := run([]string{"annotate", "-notes", notes, "-encounter", "Lord Marrowgar", "-at", "5s", "-author", "Yogzar", "-note", "tried 3-heal here", log}, &out, &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"annotate", "-notes", notes, "-encounter", "Lord Marrowgar", "-attempt", "2", "-note", "nope", log}, &out, &out); err == nil {
+		t.Error("expected an error for a missing attempt")
+	}
+
+	out.Reset()
+	if err := run([]string{"encounters", "-notes", notes, log}, &out, &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || strings.TrimSpace(lines[2]) != "note: tried 3-heal here (at 5s) - Yogzar" {
+		t.Errorf("expected the note under the kill, got:\n%s", out.String())
+	}
+}
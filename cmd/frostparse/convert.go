@@ -0,0 +1,89 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bradleybonitatibus/frostparse"
+	"github.com/bradleybonitatibus/frostparse/export"
+)
+
+// runConvert converts between a text combat log and the formats records can
+// be stored in, picking the formats from the file extensions.
+func runConvert(args []string, stdout, _ io.Writer) error {
+	fs := newFlagSet("convert")
+	var in logInput
+	in.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: frostparse convert [flags] <log | in.fpb> <out.fpb | out.jsonl | out.csv>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if in.err != nil {
+		return in.err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("convert: expected an input and an output file, got %q", strings.Join(fs.Args(), " "))
+	}
+	src, dst := fs.Arg(0), fs.Arg(1)
+	var data []*frostparse.CombatLogRecord
+	var err error
+	if filepath.Ext(src) == ".fpb" {
+		data, err = frostparse.ReadBinaryFile(src)
+	} else {
+		data, err = frostparse.New(append(in.cfg.ParserOptions(), frostparse.WithLogFile(src))...).Parse()
+	}
+	if err != nil {
+		return err
+	}
+	if err := writeConverted(dst, data); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "wrote %d records to %s\n", len(data), dst)
+	return nil
+}
+
+// writeConverted writes the records to path in the format of its extension.
+func writeConverted(path string, data []*frostparse.CombatLogRecord) error {
+	var write func(io.Writer, []*frostparse.CombatLogRecord) error
+	switch ext := filepath.Ext(path); ext {
+	case ".fpb":
+		return frostparse.WriteBinaryFile(path, data)
+	case ".jsonl":
+		write = export.WriteJSONL
+	case ".csv":
+		write = export.WriteWideCSV
+	default:
+		return fmt.Errorf("convert: unknown output format %q, expected .fpb, .jsonl or .csv", ext)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
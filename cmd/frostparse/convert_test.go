@@ -0,0 +1,59 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+func TestRunConvert(t *testing.T) {
+	dir := t.TempDir()
+	fpb := filepath.Join(dir, "raid.fpb")
+	var out bytes.Buffer
+	if err := run([]string{"convert", writeTestLog(t), fpb}, &out, &out); err != nil {
+		t.Fatal(err)
+	}
+	records, err := frostparse.ReadBinaryFile(fpb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 || !strings.Contains(out.String(), "wrote 5 records") {
+		t.Fatalf("expected the log to be written as .fpb, got %d records and %q", len(records), out.String())
+	}
+
+	jsonl := filepath.Join(dir, "raid.jsonl")
+	if err := run([]string{"convert", fpb, jsonl}, &out, &out); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(jsonl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(b), "\n"); lines != len(records) {
+		t.Errorf("expected %d JSON lines from the .fpb file, got %d", len(records), lines)
+	}
+
+	if err := run([]string{"convert", fpb, filepath.Join(dir, "raid.txt")}, &out, &out); err == nil || !strings.Contains(err.Error(), "unknown output format") {
+		t.Errorf("expected an unknown output format error, got %v", err)
+	}
+}
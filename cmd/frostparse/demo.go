@@ -0,0 +1,507 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+	"github.com/bradleybonitatibus/frostparse/demo"
+)
+
+// demoSection is a report printed by the demo command.
+type demoSection struct {
+	name  string
+	title string
+	write func(w io.Writer, data []*frostparse.CombatLogRecord, top int) error
+}
+
+// demoSections are the reports of the demo command, in the order they are
+// printed.
+var demoSections = []demoSection{
+	{"encounters", "Encounters", writeDemoEncounters},
+	{"summary", "Damage and Healing Done", writeDemoSummary},
+	{"dps", "DPS and HPS", writeDemoDPS},
+	{"spells", "Damage and Healing by Spell", writeDemoSpells},
+	{"groups", "Raid Groups", writeDemoGroups},
+	{"grades", "Grades", writeDemoGrades},
+	{"deaths", "Deaths", writeDemoDeaths},
+	{"cooldowns", "Raid Cooldowns", writeDemoCooldowns},
+	{"healing", "Healing Spikes", writeDemoHealingSpikes},
+	{"mitigation", "Damage Prevented", writeDemoMitigation},
+	{"activity", "Activity", writeDemoActivity},
+	{"composition", "Damage Composition", writeDemoComposition},
+	{"efficiency", "Spell Efficiency", writeDemoEfficiency},
+	{"melee", "Melee Uptime", writeDemoMelee},
+	{"auras", "Aura Uptime", writeDemoAuras},
+	{"trinkets", "Cooldown Alignment", writeDemoTrinkets},
+	{"bosshp", "Boss Health", writeDemoBossHP},
+	{"progression", "Progression", writeDemoProgression},
+	{"positioning", "Boss Positioning", writeDemoPositioning},
+	{"parryhaste", "Parry Haste", writeDemoParryHaste},
+	{"dispels", "Undispelled Debuffs", writeDemoDispels},
+	{"heatmap", "Damage Taken by Mechanic", writeDemoHeatmap},
+	{"interactions", "Interactions", writeDemoInteractions},
+	{"latency", "Spell Latency", writeDemoLatency},
+}
+
+func runDemo(args []string, stdout, _ io.Writer) error {
+	fs := newFlagSet("demo")
+	top := fs.Int("top", 5, "number of rows to list per report, 0 for all")
+	only := fs.String("report", "", "comma separated reports to print instead of all of them: "+demoSectionNames())
+	extract := fs.String("extract", "", "write the sample log to this file, to try the other commands on it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("demo: unexpected arguments %q, the demo reads its own sample log", strings.Join(fs.Args(), " "))
+	}
+	if *extract != "" {
+		return extractDemoLog(*extract)
+	}
+	sections := demoSections
+	if *only != "" {
+		var err error
+		if sections, err = selectDemoSections(*only); err != nil {
+			return err
+		}
+	}
+	start := time.Now()
+	data, p, err := demo.Parse()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Sample log: %d lines, %d records parsed in %s\n",
+		p.Report().Lines, len(data), time.Since(start).Round(time.Millisecond))
+	for _, s := range sections {
+		fmt.Fprintf(stdout, "\n== %s ==\n", s.title)
+		if err := s.write(stdout, data, *top); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func demoSectionNames() string {
+	names := make([]string, len(demoSections))
+	for i, s := range demoSections {
+		names[i] = s.name
+	}
+	return strings.Join(names, ", ")
+}
+
+func selectDemoSections(list string) ([]demoSection, error) {
+	var out []demoSection
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, s := range demoSections {
+			if s.name == name {
+				out = append(out, s)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("demo: unknown report %q, expected one of %s", name, demoSectionNames())
+		}
+	}
+	return out, nil
+}
+
+// extractDemoLog writes the decompressed sample log to path.
+func extractDemoLog(path string) error {
+	zr, err := gzip.NewReader(demo.Open())
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, zr)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// demoRow is a line of a demo report, ranked by its value.
+type demoRow struct {
+	value float64
+	line  string
+}
+
+// writeDemoRows prints the rows with the largest values first, at most top
+// of them when top is positive.
+func writeDemoRows(w io.Writer, header string, rows []demoRow, top int) error {
+	if len(rows) == 0 {
+		_, err := fmt.Fprintln(w, "  (none in the sample log)")
+		return err
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].value != rows[j].value {
+			return rows[i].value > rows[j].value
+		}
+		return rows[i].line < rows[j].line
+	})
+	if top > 0 && len(rows) > top {
+		rows = rows[:top]
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  "+header)
+	for _, r := range rows {
+		fmt.Fprintln(tw, "  "+r.line)
+	}
+	return tw.Flush()
+}
+
+func writeDemoEncounters(w io.Writer, data []*frostparse.CombatLogRecord, _ int) error {
+	return writeEncounters(w, frostparse.NewEncounterSplitter().Segments(data))
+}
+
+func writeDemoSummary(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	stats := frostparse.NewCollector().Run(data)
+	if err := writeTotals(w, "Damage Done", stats.DamageBySource, top); err != nil {
+		return err
+	}
+	return writeTotals(w, "Healing Done", stats.HealingBySource, top)
+}
+
+func writeDemoDPS(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	var rows []demoRow
+	for _, enc := range frostparse.NewEncounterSplitter().Split(data) {
+		stats := frostparse.NewCollector().Run(enc.Records)
+		dps, hps := stats.DPS(enc.Duration()), stats.HPS(enc.Duration())
+		active := stats.ActiveDPS()
+		for player := range stats.ActiveTimeBySource {
+			rows = append(rows, demoRow{
+				value: dps[player],
+				line:  fmt.Sprintf("%s #%d\t%s\t%.0f\t%.0f\t%.0f", enc.Name, enc.Attempt, player, dps[player], active[player], hps[player]),
+			})
+		}
+	}
+	return writeDemoRows(w, "ENCOUNTER\tPLAYER\tDPS\tACTIVE DPS\tHPS", rows, top)
+}
+
+func writeDemoSpells(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	stats := frostparse.NewCollector().Run(data)
+	var rows []demoRow
+	for player, b := range stats.DamageBySourceAndSpell {
+		for spell, amount := range b.Spells {
+			rows = append(rows, demoRow{
+				value: float64(amount),
+				line:  fmt.Sprintf("%s\t%s\tdamage\t%d\t-", player, spell, amount),
+			})
+		}
+		for pet, spells := range b.Pets {
+			for spell, amount := range spells {
+				rows = append(rows, demoRow{
+					value: float64(amount),
+					line:  fmt.Sprintf("%s\t%s (%s)\tdamage\t%d\t-", player, spell, pet, amount),
+				})
+			}
+		}
+	}
+	for player, spells := range stats.HealingBySourceAndSpell {
+		for spell, h := range spells {
+			rows = append(rows, demoRow{
+				value: float64(h.Total()),
+				line:  fmt.Sprintf("%s\t%s\thealing\t%d\t%d", player, spell, h.Total(), h.Periodic),
+			})
+		}
+	}
+	return writeDemoRows(w, "PLAYER\tSPELL\tKIND\tAMOUNT\tPERIODIC", rows, top)
+}
+
+func writeDemoGroups(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	roster, err := demo.Roster()
+	if err != nil {
+		return err
+	}
+	stats := frostparse.NewCollector(frostparse.WithRoster(roster)).Run(data)
+	var rows []demoRow
+	for g := 1; g <= 8; g++ {
+		members := roster.Members(g)
+		if len(members) == 0 {
+			continue
+		}
+		sort.Strings(members)
+		rows = append(rows, demoRow{
+			value: float64(stats.DamageTakenByGroup[g]),
+			line:  fmt.Sprintf("%d\t%d\t%d\t%s", g, stats.DamageTakenByGroup[g], stats.HealingReceivedByGroup[g], strings.Join(members, ", ")),
+		})
+	}
+	return writeDemoRows(w, "GROUP\tDAMAGE TAKEN\tHEALING RECEIVED\tMEMBERS", rows, top)
+}
+
+func writeDemoGrades(w io.Writer, data []*frostparse.CombatLogRecord, _ int) error {
+	return writeGradeTable(w, frostparse.NewEncounterSplitter().Split(data), defaultGradeConfig())
+}
+
+func writeDemoDeaths(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	var rows []demoRow
+	for _, d := range frostparse.NewDeathAnalyzer().Run(data) {
+		blow := "-"
+		if k := d.KillingBlow; k != nil {
+			blow = fmt.Sprintf("%s %s %d", k.Source, k.Spell, k.Amount)
+		}
+		rows = append(rows, demoRow{
+			value: -float64(d.Timestamp.UnixNano()),
+			line:  fmt.Sprintf("%s\t%s\t%s\t%s", d.Timestamp.Format("15:04:05"), d.Player, d.Encounter, blow),
+		})
+	}
+	return writeDemoRows(w, "TIME\tPLAYER\tENCOUNTER\tKILLING BLOW", rows, top)
+}
+
+func writeDemoCooldowns(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	var rows []demoRow
+	for _, enc := range frostparse.NewRaidCooldownAnalyzer().Run(data) {
+		for _, u := range enc.Uses {
+			rows = append(rows, demoRow{
+				value: -float64(u.Time.UnixNano()),
+				line:  fmt.Sprintf("%s #%d\t%s\t%s\t%s\t%s", enc.Encounter, enc.Attempt, u.Offset.Round(time.Second), u.Player, u.Spell, u.Target),
+			})
+		}
+	}
+	return writeDemoRows(w, "ENCOUNTER\tOFFSET\tPLAYER\tSPELL\tTARGET", rows, top)
+}
+
+func writeDemoHealingSpikes(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	var rows []demoRow
+	for _, enc := range frostparse.NewHealingSpikeAnalyzer().Run(data) {
+		triage := map[string]frostparse.TriageResponse{}
+		for _, r := range enc.Triage {
+			triage[r.Healer] = r
+		}
+		for _, b := range enc.Bursts {
+			response := "-"
+			t, ok := triage[b.Healer]
+			if ok {
+				response = t.Mean.Round(time.Millisecond).String()
+			}
+			rows = append(rows, demoRow{
+				value: float64(b.Healing),
+				line:  fmt.Sprintf("%s #%d\t%s\t%d\t%d\t%d\t%s", enc.Encounter, enc.Attempt, b.Healer, b.Healing, b.Heals, t.Responses, response),
+			})
+		}
+	}
+	return writeDemoRows(w, "ENCOUNTER\tHEALER\tBEST 5S\tHEALS\tTRIAGED\tMEAN RESPONSE", rows, top)
+}
+
+func writeDemoMitigation(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	var rows []demoRow
+	for _, c := range frostparse.NewMitigationAnalyzer().Run(data).Leaderboard() {
+		rows = append(rows, demoRow{
+			value: float64(c.Prevented),
+			line:  fmt.Sprintf("%s\t%s\t%d", c.Caster, c.Aura, c.Prevented),
+		})
+	}
+	return writeDemoRows(w, "CASTER\tAURA\tPREVENTED", rows, top)
+}
+
+func writeDemoActivity(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	var rows []demoRow
+	for player, a := range frostparse.NewActivityAnalyzer().Run(data) {
+		rows = append(rows, demoRow{
+			value: a.InactiveTime().Seconds(),
+			line:  fmt.Sprintf("%s\t%.1f%%\t%s", player, a.Percent(), a.InactiveTime().Round(time.Second)),
+		})
+	}
+	return writeDemoRows(w, "PLAYER\tACTIVE\tINACTIVE", rows, top)
+}
+
+func writeDemoComposition(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	header := "PLAYER\tTOTAL"
+	for _, class := range frostparse.DamageClasses {
+		header += "\t" + strings.ToUpper(string(class))
+	}
+	var rows []demoRow
+	for player, c := range frostparse.NewCompositionAnalyzer().Run(data) {
+		line := fmt.Sprintf("%s\t%d", player, c.Total())
+		for _, class := range frostparse.DamageClasses {
+			line += fmt.Sprintf("\t%.0f%%", 100*c.Share(class))
+		}
+		rows = append(rows, demoRow{value: float64(c.Total()), line: line})
+	}
+	return writeDemoRows(w, header, rows, top)
+}
+
+func writeDemoEfficiency(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	var rows []demoRow
+	for encounter, players := range frostparse.NewEfficiencyAnalyzer().Run(data) {
+		for player, spells := range players {
+			for spell, s := range spells {
+				if s.Casts == 0 {
+					continue
+				}
+				rows = append(rows, demoRow{
+					value: float64(s.Damage),
+					line:  fmt.Sprintf("%s\t%s\t%s\t%d\t%d\t%.0f", encounter, player, spell, s.Casts, s.Damage, s.DamagePerCast()),
+				})
+			}
+		}
+	}
+	return writeDemoRows(w, "ENCOUNTER\tPLAYER\tSPELL\tCASTS\tDAMAGE\tPER CAST", rows, top)
+}
+
+func writeDemoMelee(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	var rows []demoRow
+	for encounter, players := range frostparse.NewSwingTimerAnalyzer().Run(data) {
+		for player, m := range players {
+			rows = append(rows, demoRow{
+				value: float64(m.Swings),
+				line:  fmt.Sprintf("%s\t%s\t%d\t%.1f%%", encounter, player, m.Swings, m.Percent()),
+			})
+		}
+	}
+	return writeDemoRows(w, "ENCOUNTER\tPLAYER\tSWINGS\tUPTIME", rows, top)
+}
+
+func writeDemoAuras(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	var rows []demoRow
+	for encounter, units := range frostparse.NewAuraUptimeAnalyzer().Run(data) {
+		for unit, auras := range units {
+			for aura, u := range auras {
+				rows = append(rows, demoRow{
+					value: u.Uptime.Seconds(),
+					line:  fmt.Sprintf("%s\t%s\t%s\t%s\t%.1f%%", encounter, unit, aura, u.Uptime.Round(time.Second), u.Percent()),
+				})
+			}
+		}
+	}
+	return writeDemoRows(w, "ENCOUNTER\tUNIT\tAURA\tUPTIME\tSHARE", rows, top)
+}
+
+func writeDemoTrinkets(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	var rows []demoRow
+	for player, c := range frostparse.NewCooldownAlignmentAnalyzer().Run(data) {
+		rows = append(rows, demoRow{
+			value: float64(len(c.Uses)),
+			line:  fmt.Sprintf("%s\t%d\t%d\t%d\t%d", player, len(c.Uses), c.Aligned, c.Wasted, c.Misaligned),
+		})
+	}
+	return writeDemoRows(w, "PLAYER\tUSES\tALIGNED\tWASTED\tMISALIGNED", rows, top)
+}
+
+func writeDemoBossHP(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	var rows []demoRow
+	for _, b := range frostparse.NewBossHPAnalyzer().Run(data) {
+		rows = append(rows, demoRow{
+			value: -float64(b.Encounter.StartTime.UnixNano()),
+			line:  fmt.Sprintf("%s #%d\t%s\t%d\t%.1f%%", b.Encounter.Name, b.Encounter.Attempt, b.Difficulty, b.MaxHP, b.FinalPercent),
+		})
+	}
+	return writeDemoRows(w, "ENCOUNTER\tDIFFICULTY\tMAX HP\tFINAL", rows, top)
+}
+
+func writeDemoProgression(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	var rows []demoRow
+	for _, p := range frostparse.ProgressionReport(frostparse.NewBossHPAnalyzer().Run(data)) {
+		killed := "-"
+		if p.Killed() {
+			killed = fmt.Sprintf("pull %d", p.PullsToKill)
+		}
+		rows = append(rows, demoRow{
+			value: -float64(p.FirstPull.UnixNano()),
+			line:  fmt.Sprintf("%s\t%s\t%d\t%.1f%%\t%s\t%s", p.Boss, p.Difficulty, p.Pulls, p.BestPercent, p.TimeSpent.Round(time.Second), killed),
+		})
+	}
+	return writeDemoRows(w, "BOSS\tDIFFICULTY\tPULLS\tBEST\tTIME SPENT\tKILLED", rows, top)
+}
+
+func writeDemoPositioning(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	var rows []demoRow
+	for _, p := range frostparse.NewPositioningAnalyzer().Run(data) {
+		rows = append(rows, demoRow{
+			value: p.IdleTime().Seconds(),
+			line:  fmt.Sprintf("%s #%d\t%s\t%s\t%.1f%%\t%s", p.Encounter, p.Attempt, p.Boss, strings.Join(p.Tanks, ", "), p.Uptime(), p.IdleTime().Round(time.Second)),
+		})
+	}
+	return writeDemoRows(w, "ENCOUNTER\tBOSS\tTANKS\tUPTIME\tIDLE", rows, top)
+}
+
+func writeDemoParryHaste(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	var rows []demoRow
+	for _, b := range frostparse.NewParryHasteAnalyzer().Run(data) {
+		rows = append(rows, demoRow{
+			value: float64(len(b.Incidents)),
+			line:  fmt.Sprintf("%s #%d\t%s\t%d\t%d\t%d", b.Encounter, b.Attempt, b.Boss, b.Swings, b.Parries, len(b.Incidents)),
+		})
+	}
+	return writeDemoRows(w, "ENCOUNTER\tBOSS\tSWINGS\tPARRIES\tHASTED", rows, top)
+}
+
+func writeDemoDispels(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	var rows []demoRow
+	for _, u := range frostparse.NewDispelAnalyzer().Run(data) {
+		rows = append(rows, demoRow{
+			value: u.Duration().Seconds(),
+			line:  fmt.Sprintf("%s\t%s\t%s\t%s\t%s", u.Encounter, u.Player, u.SpellName, u.Duration().Round(time.Second), strings.Join(u.Dispellers, ", ")),
+		})
+	}
+	return writeDemoRows(w, "ENCOUNTER\tPLAYER\tDEBUFF\tDURATION\tDISPELLERS", rows, top)
+}
+
+func writeDemoHeatmap(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	h := frostparse.NewHeatmapAnalyzer().Run(data)
+	var rows []demoRow
+	for i, player := range h.Players {
+		for j, mechanic := range h.Mechanics {
+			c := h.Cells[i][j]
+			if c.Hits == 0 {
+				continue
+			}
+			rows = append(rows, demoRow{
+				value: float64(c.Damage),
+				line:  fmt.Sprintf("%s\t%s\t%d\t%d", player, mechanic, c.Hits, c.Damage),
+			})
+		}
+	}
+	return writeDemoRows(w, "PLAYER\tMECHANIC\tHITS\tDAMAGE", rows, top)
+}
+
+func writeDemoInteractions(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	var rows []demoRow
+	for _, g := range frostparse.NewInteractionAnalyzer().Run(data) {
+		for _, e := range g.Edges {
+			rows = append(rows, demoRow{
+				value: float64(e.Amount),
+				line:  fmt.Sprintf("%s #%d\t%s\t%s\t%s\t%d", g.Encounter, g.Attempt, e.Source, e.Target, e.Kind, e.Amount),
+			})
+		}
+	}
+	return writeDemoRows(w, "ENCOUNTER\tSOURCE\tTARGET\tKIND\tAMOUNT", rows, top)
+}
+
+func writeDemoLatency(w io.Writer, data []*frostparse.CombatLogRecord, top int) error {
+	var rows []demoRow
+	for spell, l := range frostparse.NewLatencyAnalyzer().Run(data) {
+		rows = append(rows, demoRow{
+			value: float64(l.Samples),
+			line:  fmt.Sprintf("%s\t%d\t%s\t%s", spell, l.Samples, l.Median, l.Max),
+		})
+	}
+	return writeDemoRows(w, "SPELL\tSAMPLES\tMEDIAN\tMAX", rows, top)
+}
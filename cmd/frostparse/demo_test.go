@@ -0,0 +1,72 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunDemo(t *testing.T) {
+	var out bytes.Buffer
+	if err := run([]string{"demo"}, &out, &out); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, s := range demoSections {
+		if !strings.Contains(got, "== "+s.title+" ==") {
+			t.Errorf("expected the %s report, got:\n%s", s.name, got)
+		}
+	}
+	if !strings.Contains(got, "Lord Marrowgar #1 (wipe, 17s)") {
+		t.Errorf("expected the sample Lord Marrowgar wipe, got:\n%s", got)
+	}
+	if !strings.Contains(got, "Phokkwho, Raddyboy, Ragequitwar, Rzoe, Shevros") {
+		t.Errorf("expected the raid groups of the sample roster, got:\n%s", got)
+	}
+}
+
+func TestRunDemoReport(t *testing.T) {
+	var out bytes.Buffer
+	if err := run([]string{"demo", "-report", "bosshp", "-top", "1"}, &out, &out); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	if !strings.Contains(got, "== Boss Health ==") || strings.Contains(got, "== Encounters ==") {
+		t.Errorf("expected only the boss health report, got:\n%s", got)
+	}
+	if err := run([]string{"demo", "-report", "bogus"}, &out, &out); err == nil || !strings.Contains(err.Error(), `unknown report "bogus"`) {
+		t.Errorf("expected an unknown report error, got %v", err)
+	}
+}
+
+func TestRunDemoExtract(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "demo.txt")
+	var out bytes.Buffer
+	if err := run([]string{"demo", "-extract", path}, &out, &out); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := run([]string{"encounters", path}, &out, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Lord Marrowgar") {
+		t.Errorf("expected the extracted log to parse, got:\n%s", out.String())
+	}
+}
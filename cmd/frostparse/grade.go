@@ -0,0 +1,235 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// letterThreshold is the lowest score that earns a letter grade.
+type letterThreshold struct {
+	Letter string  `json:"letter"`
+	Min    float64 `json:"min"`
+}
+
+// gradeConfig holds the thresholds a grade card is computed with. Scores
+// start at Base, earn up to DPSWeight points by DPS percentile within the
+// attempt and InterruptBonus per interrupt, and lose DeathPenalty per death
+// and AvoidablePenalty per AvoidableUnit of damage taken from
+// AvoidableSpells. Scores are clamped to 0-100.
+type gradeConfig struct {
+	Base             float64           `json:"base"`
+	DPSWeight        float64           `json:"dps_weight"`
+	InterruptBonus   float64           `json:"interrupt_bonus"`
+	DeathPenalty     float64           `json:"death_penalty"`
+	AvoidablePenalty float64           `json:"avoidable_penalty"`
+	AvoidableUnit    uint64            `json:"avoidable_unit"`
+	AvoidableSpells  []string          `json:"avoidable_spells"`
+	Letters          []letterThreshold `json:"letters"`
+}
+
+func defaultGradeConfig() gradeConfig {
+	return gradeConfig{
+		Base:             40,
+		DPSWeight:        60,
+		InterruptBonus:   2,
+		DeathPenalty:     20,
+		AvoidablePenalty: 5,
+		AvoidableUnit:    10000,
+		AvoidableSpells: []string{
+			"Coldflame", "Death and Decay", "Slime Spray", "Malleable Goo",
+			"Choking Gas Explosion", "Defile", "Shadow Trap",
+		},
+		Letters: []letterThreshold{
+			{"A", 90}, {"B", 80}, {"C", 70}, {"D", 60}, {"F", 0},
+		},
+	}
+}
+
+// letter returns the letter grade of a score.
+func (c gradeConfig) letter(score float64) string {
+	letters := append([]letterThreshold(nil), c.Letters...)
+	sort.Slice(letters, func(i, j int) bool { return letters[i].Min > letters[j].Min })
+	for _, l := range letters {
+		if score >= l.Min {
+			return l.Letter
+		}
+	}
+	return "-"
+}
+
+// playerGrade is a single row of a grade card.
+type playerGrade struct {
+	Player        string
+	Score         float64
+	Letter        string
+	DPS           float64
+	DPSPercentile float64
+	Avoidable     uint64
+	Deaths        int
+	Interrupts    uint64
+}
+
+// gradeEncounter grades every player who acted during the attempt.
+func gradeEncounter(e frostparse.Encounter, cfg gradeConfig) []playerGrade {
+	stats := frostparse.NewCollector().Run(e.Records)
+	dps := stats.DPS(e.Duration())
+	avoidable := map[string]bool{}
+	for _, s := range cfg.AvoidableSpells {
+		avoidable[s] = true
+	}
+	grades := map[string]*playerGrade{}
+	for name := range stats.ActiveTimeBySource {
+		grades[name] = &playerGrade{
+			Player:     name,
+			DPS:        dps[name],
+			Interrupts: stats.InterruptsBySource[name],
+		}
+	}
+	for _, rec := range e.Records {
+		g, ok := grades[rec.TargetName]
+		if !ok || !strings.HasPrefix(rec.TargetID, "0x07") {
+			continue
+		}
+		switch {
+		case rec.EventType == frostparse.UnitDied:
+			g.Deaths++
+		case rec.DamageSuffix != nil && rec.SpellAndRangePrefix != nil && avoidable[rec.SpellAndRangePrefix.SpellName]:
+			g.Avoidable += rec.DamageSuffix.Amount
+		}
+	}
+	out := make([]playerGrade, 0, len(grades))
+	for _, g := range grades {
+		below := 0
+		for _, other := range grades {
+			if other.DPS < g.DPS {
+				below++
+			}
+		}
+		g.DPSPercentile = 100
+		if len(grades) > 1 {
+			g.DPSPercentile = 100 * float64(below) / float64(len(grades)-1)
+		}
+		score := cfg.Base + cfg.DPSWeight*g.DPSPercentile/100 + cfg.InterruptBonus*float64(g.Interrupts) -
+			cfg.DeathPenalty*float64(g.Deaths)
+		if cfg.AvoidableUnit > 0 {
+			score -= cfg.AvoidablePenalty * float64(g.Avoidable) / float64(cfg.AvoidableUnit)
+		}
+		g.Score = min(max(score, 0), 100)
+		g.Letter = cfg.letter(g.Score)
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Player < out[j].Player
+	})
+	return out
+}
+
+func runGrade(args []string, stdout, _ io.Writer) error {
+	fs := newFlagSet("grade")
+	configPath := fs.String("config", "", "JSON file with grade thresholds")
+	format := fs.String("format", "table", "output format: table or markdown")
+	var in logInput
+	in.register(fs)
+	cfg := defaultGradeConfig()
+	fs.Float64Var(&cfg.DPSWeight, "dps-weight", cfg.DPSWeight, "points awarded for the top DPS percentile")
+	fs.Float64Var(&cfg.DeathPenalty, "death-penalty", cfg.DeathPenalty, "points deducted per death")
+	fs.Float64Var(&cfg.InterruptBonus, "interrupt-bonus", cfg.InterruptBonus, "points awarded per interrupt")
+	fs.Float64Var(&cfg.AvoidablePenalty, "avoidable-penalty", cfg.AvoidablePenalty, "points deducted per avoidable-unit of avoidable damage taken")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *configPath != "" {
+		if err := loadGradeConfig(*configPath, &cfg); err != nil {
+			return err
+		}
+		// flags given explicitly win over the config file
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+	}
+	data, _, err := in.parse(fs)
+	if err != nil {
+		return err
+	}
+	encounters := frostparse.NewEncounterSplitter().Split(data)
+	switch *format {
+	case "table":
+		return writeGradeTable(stdout, encounters, cfg)
+	case "markdown", "md":
+		return writeGradeMarkdown(stdout, encounters, cfg)
+	default:
+		return fmt.Errorf("grade: unknown format %q", *format)
+	}
+}
+
+func loadGradeConfig(path string, cfg *gradeConfig) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return fmt.Errorf("grade: invalid config %s: %w", path, err)
+	}
+	return nil
+}
+
+func encounterTitle(e frostparse.Encounter) string {
+	return fmt.Sprintf("%s #%d (%s, %s)", e.Name, e.Attempt, e.Result, e.Duration().Round(time.Second))
+}
+
+func writeGradeTable(w io.Writer, encounters []frostparse.Encounter, cfg gradeConfig) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENCOUNTER\tPLAYER\tGRADE\tSCORE\tDPS\tDPS PCT\tAVOIDABLE\tDEATHS\tINTERRUPTS")
+	for _, e := range encounters {
+		for _, g := range gradeEncounter(e, cfg) {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%.0f\t%.0f\t%.0f\t%d\t%d\t%d\n",
+				encounterTitle(e), g.Player, g.Letter, g.Score, g.DPS, g.DPSPercentile, g.Avoidable, g.Deaths, g.Interrupts)
+		}
+	}
+	return tw.Flush()
+}
+
+func writeGradeMarkdown(w io.Writer, encounters []frostparse.Encounter, cfg gradeConfig) error {
+	for i, e := range encounters {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "## %s\n\n", encounterTitle(e))
+		fmt.Fprintln(w, "| Player | Grade | Score | DPS | DPS Pct | Avoidable | Deaths | Interrupts |")
+		fmt.Fprintln(w, "|---|---|---:|---:|---:|---:|---:|---:|")
+		for _, g := range gradeEncounter(e, cfg) {
+			if _, err := fmt.Fprintf(w, "| %s | %s | %.0f | %.0f | %.0f | %d | %d | %d |\n",
+				g.Player, g.Letter, g.Score, g.DPS, g.DPSPercentile, g.Avoidable, g.Deaths, g.Interrupts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const gradeTestLog = `12/11 01:08:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,1000,0,1,0,0,0,nil,nil,nil
+12/11 01:08:01.000  SWING_DAMAGE,0x07000000007721EC,"Yogzar",0x511,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil
+12/11 01:08:02.000  SPELL_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000007721EC,"Yogzar",0x511,69146,"Coldflame",0x10,20000,0,16,0,0,0,nil,nil,nil
+12/11 01:08:05.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0x07000000007721EC,"Yogzar",0x511
+12/11 01:08:10.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F0400003D,"Lord Marrowgar",0x10a48
+`
+
+func TestRunGrade(t *testing.T) {
+	var out bytes.Buffer
+	if err := run([]string{"grade", "-format", "markdown", writeTestLog(t)}, &out, &out); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	if !strings.Contains(got, "## Lord Marrowgar #1 (kill, 10s)") {
+		t.Errorf("expected a Lord Marrowgar kill heading, got:\n%s", got)
+	}
+	// Winterinjuly tops the meter: 40 + 60 points
+	if !strings.Contains(got, "| Winterinjuly | A | 100 |") {
+		t.Errorf("expected Winterinjuly to earn an A, got:\n%s", got)
+	}
+	// Yogzar is last, died once and stood in 20000 Coldflame: 40 - 20 - 10
+	if !strings.Contains(got, "| Yogzar | F | 10 | 10 | 0 | 20000 | 1 | 0 |") {
+		t.Errorf("expected Yogzar to fail, got:\n%s", got)
+	}
+}
+
+func TestRunUnknownCommand(t *testing.T) {
+	var out bytes.Buffer
+	if err := run([]string{"nope"}, &out, &out); err == nil {
+		t.Error("expected an unknown command to fail")
+	}
+}
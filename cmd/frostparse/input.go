@@ -0,0 +1,178 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/bradleybonitatibus/frostparse"
+	"github.com/bradleybonitatibus/frostparse/export"
+)
+
+// stdin is where "-" reads from, replaced in tests.
+var stdin io.Reader = os.Stdin
+
+// clipboardCommands are the commands tried in order to read the clipboard on
+// each platform.
+var clipboardCommands = map[string][][]string{
+	"darwin":  {{"pbpaste"}},
+	"windows": {{"powershell", "-NoProfile", "-Command", "Get-Clipboard"}},
+	"linux": {
+		{"wl-paste", "--no-newline"},
+		{"xclip", "-selection", "clipboard", "-o"},
+		{"xsel", "--clipboard", "--output"},
+	},
+}
+
+// errNoClipboard is returned when no clipboard command is available.
+var errNoClipboard = errors.New("no clipboard command found, install wl-paste, xclip or xsel")
+
+// readClipboard returns the text on the system clipboard.
+func readClipboard() ([]byte, error) {
+	for _, args := range clipboardCommands[runtime.GOOS] {
+		path, err := exec.LookPath(args[0])
+		if err != nil {
+			continue
+		}
+		return exec.Command(path, args[1:]...).Output()
+	}
+	return nil, errNoClipboard
+}
+
+// openLog opens the combat log a subcommand reads: the clipboard, standard
+// input for "-", or a file.
+func openLog(path string, clipboard bool) (io.ReadCloser, error) {
+	switch {
+	case clipboard:
+		b, err := readClipboard()
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(b)), nil
+	case path == "-":
+		return io.NopCloser(stdin), nil
+	default:
+		return os.Open(path)
+	}
+}
+
+// logInput registers the flags shared by subcommands that read a combat log
+// and parses the log they select. Every parser option can also be set with an
+// environment variable, see frostparse.Config.
+type logInput struct {
+	clipboard bool
+	cfg       frostparse.Config
+	// err is the error reading the environment, reported once the log is
+	// parsed so flags can still be registered.
+	err error
+}
+
+func (in *logInput) register(fs *flag.FlagSet) {
+	fs.BoolVar(&in.clipboard, "clipboard", false, "read the combat log from the clipboard")
+	in.cfg = frostparse.DefaultConfig()
+	in.err = in.cfg.LoadEnv(os.LookupEnv)
+	in.cfg.RegisterFlags(fs)
+}
+
+// parse reads the log named by the positional argument of fs, or the
+// clipboard, and returns the parser used so callers can inspect its report.
+// Several files are merged in timestamp order. The records are also written
+// to the configured sinks.
+func (in *logInput) parse(fs *flag.FlagSet) ([]*frostparse.CombatLogRecord, *frostparse.Parser, error) {
+	if in.err != nil {
+		return nil, nil, in.err
+	}
+	data, p, err := in.read(fs)
+	if err != nil {
+		return data, p, err
+	}
+	return data, p, writeSinks(in.cfg.Sinks, data)
+}
+
+func (in *logInput) read(fs *flag.FlagSet) ([]*frostparse.CombatLogRecord, *frostparse.Parser, error) {
+	opts := in.cfg.ParserOptions()
+	if !in.clipboard && fs.NArg() > 1 {
+		for _, path := range fs.Args() {
+			if path == "-" {
+				return nil, nil, fmt.Errorf("%s: cannot merge standard input with other logs", fs.Name())
+			}
+		}
+		p := frostparse.New(opts...)
+		data, err := p.ParseFiles(fs.Args()...)
+		return data, p, err
+	}
+	path := ""
+	if !in.clipboard {
+		var err error
+		if path, err = logPath(fs, in.cfg); err != nil {
+			return nil, nil, err
+		}
+		if path != "-" {
+			// files are parsed by the parser so -workers applies
+			p := frostparse.New(append(opts, frostparse.WithLogFile(path))...)
+			data, err := p.Parse()
+			return data, p, err
+		}
+	}
+	r, err := openLog(path, in.clipboard)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+	p := frostparse.New(append(opts, frostparse.WithReader(r))...)
+	data, err := p.Parse()
+	return data, p, err
+}
+
+// writeSinks writes the records to every sink, rotating the files they are
+// written to like a long running service would.
+func writeSinks(sinks []frostparse.SinkConfig, data []*frostparse.CombatLogRecord) error {
+	for _, sc := range sinks {
+		f, err := export.NewRotatingFile(sc.Path)
+		if err != nil {
+			return err
+		}
+		var sink interface {
+			Write(context.Context, []*frostparse.CombatLogRecord) error
+		}
+		switch sc.Format {
+		case "jsonl":
+			sink = export.NewJSONLSink(f)
+		case "csv":
+			sink = export.NewCSVSink(f)
+		default:
+			f.Close()
+			return fmt.Errorf("unknown sink format %q in %q, expected jsonl or csv", sc.Format, sc)
+		}
+		err = sink.Write(context.Background(), data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("sink %s: %w", sc, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Control keys understood by the line editor.
+const (
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyBackspace = 8
+	keyTab       = 9
+	keyLF        = 10
+	keyCR        = 13
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// errInterrupted is returned by editLine when the user presses Ctrl-C.
+var errInterrupted = errors.New("interrupted")
+
+// editLine reads a line from a terminal in raw mode, echoing what is typed
+// to w. Tab completes the last word with the candidates returned by
+// complete: a single candidate is filled in, and several are completed to
+// their common prefix or listed when there is none.
+func editLine(r *bufio.Reader, w io.Writer, prompt string, complete func(string) []string) (string, error) {
+	fmt.Fprint(w, prompt)
+	var line []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch c {
+		case keyCR, keyLF:
+			fmt.Fprint(w, "\r\n")
+			return string(line), nil
+		case keyCtrlC:
+			fmt.Fprint(w, "^C\r\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(line) == 0 {
+				return "", io.EOF
+			}
+		case keyBackspace, keyDelete:
+			if len(line) > 0 {
+				_, size := utf8.DecodeLastRune(line)
+				line = line[:len(line)-size]
+				fmt.Fprint(w, "\b \b")
+			}
+		case keyTab:
+			line = completeLine(w, prompt, line, complete)
+		case keyEscape:
+			// skip cursor keys and other CSI sequences
+			if b, err := r.ReadByte(); err == nil && b == '[' {
+				for {
+					b, err := r.ReadByte()
+					if err != nil || b >= 0x40 && b <= 0x7e {
+						break
+					}
+				}
+			}
+		default:
+			if c >= ' ' {
+				line = append(line, c)
+				w.Write([]byte{c})
+			}
+		}
+	}
+}
+
+// completeLine applies tab completion to line and returns the new line.
+func completeLine(w io.Writer, prompt string, line []byte, complete func(string) []string) []byte {
+	candidates := complete(string(line))
+	if len(candidates) == 0 {
+		return line
+	}
+	// the word being completed is the longest end of the line, starting at a
+	// word, that the candidates begin with, since names may hold spaces
+	word := ""
+	for i := 0; i < len(line); i++ {
+		if (i == 0 || line[i-1] == ' ') && line[i] != ' ' &&
+			strings.HasPrefix(strings.ToLower(candidates[0]), strings.ToLower(string(line[i:]))) {
+			word = string(line[i:])
+			break
+		}
+	}
+	fill := candidates[0]
+	if len(candidates) == 1 {
+		fill += " "
+	} else {
+		fill = commonPrefix(candidates)
+	}
+	if len(fill) <= len(word) && len(candidates) > 1 {
+		fmt.Fprintf(w, "\r\n%s\r\n%s%s", strings.Join(candidates, "  "), prompt, line)
+		return line
+	}
+	next := append(append([]byte{}, line[:len(line)-len(word)]...), fill...)
+	// redraw the line, since completion may change the case of what was
+	// typed
+	fmt.Fprintf(w, "\r\x1b[K%s%s", prompt, next)
+	return next
+}
+
+// commonPrefix returns the longest prefix shared by every candidate,
+// ignoring case, spelled as in the first one.
+func commonPrefix(candidates []string) string {
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		n := 0
+		for n < len(prefix) && n < len(c) && strings.EqualFold(prefix[n:n+1], c[n:n+1]) {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return prefix
+}
+
+// terminalReader reads lines with editLine from a terminal that is switched
+// to raw mode for each line.
+type terminalReader struct {
+	t        terminal
+	r        *bufio.Reader
+	out      io.Writer
+	complete func(string) []string
+}
+
+// terminal is a terminal that can be switched to raw mode.
+type terminal interface {
+	makeRaw() (restore func() error, err error)
+}
+
+func (t *terminalReader) readLine(prompt string) (string, error) {
+	restore, err := t.t.makeRaw()
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+	for {
+		line, err := editLine(t.r, t.out, prompt, t.complete)
+		if errors.Is(err, errInterrupted) {
+			continue
+		}
+		return line, err
+	}
+}
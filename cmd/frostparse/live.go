@@ -0,0 +1,81 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/bradleybonitatibus/frostparse"
+	"github.com/bradleybonitatibus/frostparse/live"
+)
+
+// liveContext is the context the live command tails the log with, replaced
+// in tests.
+var liveContext = func() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+func runLive(args []string, stdout, _ io.Writer) error {
+	fs := newFlagSet("live")
+	addr := fs.String("addr", "localhost:8080", "address to serve the WebSocket stream on, at /live")
+	cfg := frostparse.DefaultConfig()
+	envErr := cfg.LoadEnv(os.LookupEnv)
+	cfg.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if envErr != nil {
+		return envErr
+	}
+	path, err := logPath(fs, cfg)
+	if err != nil {
+		return err
+	}
+	if path == "-" {
+		return errors.New("live: cannot tail standard input, give the log file")
+	}
+	b := live.NewBroadcaster()
+	l := frostparse.NewEventListener()
+	l.OnAny(b.Broadcast)
+	p := frostparse.New(append(cfg.ParserOptions(), frostparse.WithLogFile(path), frostparse.WithEventListener(l))...)
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/live", b)
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	defer srv.Close()
+	defer b.Close()
+	fmt.Fprintf(stdout, "streaming %s to ws://%s/live\n", path, ln.Addr())
+
+	ctx, stop := liveContext()
+	defer stop()
+	if err := p.Tail(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command frostparse analyzes World of Warcraft 3.3.5a combat logs from the
+// command line.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// command is a frostparse subcommand.
+type command struct {
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) error
+}
+
+var commands = map[string]command{
+	"annotate":   {"attach a note to an encounter", runAnnotate},
+	"convert":    {"convert a combat log to .fpb, JSON Lines or CSV", runConvert},
+	"demo":       {"print every report for a bundled sample log", runDemo},
+	"grade":      {"print per-player letter grades for every encounter", runGrade},
+	"live":       {"stream records to WebSocket clients while tailing a log", runLive},
+	"parse":      {"parse a combat log and dump its records", runParse},
+	"repl":       {"explore a combat log at an interactive prompt", runRepl},
+	"summary":    {"print damage and healing done by source", runSummary},
+	"encounters": {"list boss attempts with their durations and results", runEncounters},
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "frostparse:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		usage(stderr)
+		return flag.ErrHelp
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		usage(stderr)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return cmd.run(args[1:], stdout, stderr)
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "usage: frostparse <command> [flags] [file | -]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-12s %s\n", name, commands[name].summary)
+	}
+}
+
+// newFlagSet returns a flag set for a subcommand that reports errors instead
+// of exiting.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("frostparse "+name, flag.ContinueOnError)
+	return fs
+}
+
+// logPath returns the single combat log path argument of a subcommand. A path
+// of "-" means standard input. Without an argument the configured log file,
+// or else the most recent log in the game's Logs directory, is used.
+func logPath(fs *flag.FlagSet, cfg frostparse.Config) (string, error) {
+	if fs.NArg() == 0 && cfg.LogFile != "" {
+		return cfg.LogFile, nil
+	}
+	if fs.NArg() == 0 {
+		var dirs []string
+		if cfg.LogDir != "" {
+			dirs = append(dirs, cfg.LogDir)
+		}
+		path, err := frostparse.DiscoverLogFile(dirs...)
+		if err != nil {
+			return "", fmt.Errorf("%s: no combat log file given: %w, set $FROSTPARSE_LOG_DIR to the game's Logs directory", fs.Name(), err)
+		}
+		return path, nil
+	}
+	if fs.NArg() != 1 {
+		return "", fmt.Errorf("%s: expected a single combat log file, got %q", fs.Name(), strings.Join(fs.Args(), " "))
+	}
+	return fs.Arg(0), nil
+}
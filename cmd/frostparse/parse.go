@@ -0,0 +1,100 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bradleybonitatibus/frostparse"
+	"github.com/bradleybonitatibus/frostparse/export"
+)
+
+func runParse(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("parse")
+	format := fs.String("format", "text", "output format: text, jsonl or csv")
+	var in logInput
+	in.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	data, p, err := in.parse(fs)
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "text":
+		for _, rec := range data {
+			if _, err := fmt.Fprintln(stdout, formatRecord(rec)); err != nil {
+				return err
+			}
+		}
+	case "jsonl":
+		if err := export.WriteJSONL(stdout, data); err != nil {
+			return err
+		}
+	case "csv":
+		if err := export.WriteWideCSV(stdout, data); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("parse: unknown format %q", *format)
+	}
+	report := p.Report()
+	fmt.Fprintf(stderr, "%d lines, %d records, %d skipped\n", report.Lines, report.Parsed, len(report.Quarantined))
+	for _, a := range report.Aliases {
+		fmt.Fprintf(stderr, "renamed %s (%s) to %s\n", a.Name, a.GUID, a.Alias)
+	}
+	return nil
+}
+
+// formatRecord renders a record as a single human readable line.
+func formatRecord(rec *frostparse.CombatLogRecord) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s  %-24s %s", rec.Timestamp.Format("01/02 15:04:05.000"), rec.EventType, unitName(rec.SourceName))
+	if rec.TargetName != "" {
+		fmt.Fprintf(&b, " -> %s", unitName(rec.TargetName))
+	}
+	if rec.SpellAndRangePrefix != nil {
+		fmt.Fprintf(&b, "  %s", rec.SpellAndRangePrefix.SpellName)
+	}
+	switch {
+	case rec.DamageSuffix != nil:
+		fmt.Fprintf(&b, "  %d", rec.DamageSuffix.Amount)
+		if rec.DamageSuffix.Critical {
+			b.WriteString(" (crit)")
+		}
+	case rec.HealSuffix != nil:
+		fmt.Fprintf(&b, "  +%d", rec.HealSuffix.Amount)
+		if rec.HealSuffix.Critical {
+			b.WriteString(" (crit)")
+		}
+	case rec.MissSuffix != nil:
+		fmt.Fprintf(&b, "  %s", rec.MissSuffix.MissType)
+	case rec.AuraSuffix != nil:
+		fmt.Fprintf(&b, "  %s", rec.AuraSuffix.AuraType)
+	}
+	return b.String()
+}
+
+func unitName(name string) string {
+	if name == "" || name == "nil" {
+		return "-"
+	}
+	return name
+}
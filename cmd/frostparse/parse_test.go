@@ -0,0 +1,134 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRunParseStdin(t *testing.T) {
+	saved := stdin
+	stdin = strings.NewReader(gradeTestLog)
+	defer func() { stdin = saved }()
+	var out, errOut bytes.Buffer
+	if err := run([]string{"parse", "-"}, &out, &errOut); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out.String(), "\n"); n != 5 {
+		t.Errorf("expected 5 records, got %d:\n%s", n, out.String())
+	}
+	if !strings.Contains(out.String(), "Lord Marrowgar -> Yogzar  Coldflame  20000") {
+		t.Errorf("expected the Coldflame hit to be dumped, got:\n%s", out.String())
+	}
+	if errOut.String() != "5 lines, 5 records, 0 skipped\n" {
+		t.Errorf("unexpected report: %q", errOut.String())
+	}
+}
+
+func TestRunParseBoss(t *testing.T) {
+	saved := stdin
+	defer func() { stdin = saved }()
+	for boss, want := range map[string]string{
+		"lord marrowgar":                 "5 lines, 5 records, 0 skipped\n",
+		"Deathbringer Saurfang, Rotface": "5 lines, 0 records, 0 skipped\n",
+	} {
+		stdin = strings.NewReader(gradeTestLog)
+		var out, errOut bytes.Buffer
+		if err := run([]string{"parse", "-boss", boss, "-"}, &out, &errOut); err != nil {
+			t.Fatal(err)
+		}
+		if errOut.String() != want {
+			t.Errorf("%s: expected %q, got %q", boss, want, errOut.String())
+		}
+	}
+}
+
+func TestRunParsePrivacy(t *testing.T) {
+	saved := stdin
+	stdin = strings.NewReader(gradeTestLog)
+	defer func() { stdin = saved }()
+	var out, errOut bytes.Buffer
+	if err := run([]string{"parse", "-exclude", "Winterinjuly", "-pseudonymize", "Yogzar", "-"}, &out, &errOut); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "Winterinjuly") || strings.Contains(out.String(), "Yogzar") {
+		t.Errorf("expected the players to be hidden, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "Lord Marrowgar -> Player-") {
+		t.Errorf("expected the pseudonym to be dumped, got:\n%s", out.String())
+	}
+	if errOut.String() != "5 lines, 4 records, 0 skipped\n" {
+		t.Errorf("unexpected report: %q", errOut.String())
+	}
+}
+
+func TestRunParseDiscoversLog(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "WoWCombatLog.txt"), []byte(gradeTestLog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FROSTPARSE_LOG_DIR", dir)
+	var out, errOut bytes.Buffer
+	if err := run([]string{"parse"}, &out, &errOut); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out.String(), "\n"); n != 5 {
+		t.Errorf("expected 5 records, got %d:\n%s", n, out.String())
+	}
+}
+
+func TestRunParseUnknownLocale(t *testing.T) {
+	var out bytes.Buffer
+	if err := run([]string{"parse", "-locale", "xxXX", "-"}, &out, &out); err == nil || !strings.Contains(err.Error(), "unknown locale") {
+		t.Errorf("expected an unknown locale error, got %v", err)
+	}
+}
+
+func TestRunParseClipboardUnavailable(t *testing.T) {
+	saved := clipboardCommands[runtime.GOOS]
+	clipboardCommands[runtime.GOOS] = [][]string{{"frostparse-no-such-clipboard"}}
+	defer func() { clipboardCommands[runtime.GOOS] = saved }()
+	var out bytes.Buffer
+	if err := run([]string{"parse", "-clipboard"}, &out, &out); !errors.Is(err, errNoClipboard) {
+		t.Errorf("expected errNoClipboard, got %v", err)
+	}
+}
+
+func TestRunParseSinkFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.jsonl")
+	t.Setenv("FROSTPARSE_SINKS", "jsonl:"+path)
+	saved := stdin
+	stdin = strings.NewReader(gradeTestLog)
+	defer func() { stdin = saved }()
+	var out, errOut bytes.Buffer
+	if err := run([]string{"parse", "-format", "jsonl", "-"}, &out, &errOut); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != out.String() {
+		t.Errorf("expected the sink to receive the dumped records, got:\n%s", b)
+	}
+}
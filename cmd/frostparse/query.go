@@ -0,0 +1,353 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// queryMeasures are what a query adds up, or records to list the matches.
+var queryMeasures = []string{"count", "damage", "healing", "overhealing", "records"}
+
+// queryKeys are what a query groups its measure by.
+var queryKeys = []string{"event", "source", "spell", "target"}
+
+// queryClauses are the keywords that start a clause of a query. The value of
+// by is the next word, and of the others every word up to the next keyword,
+// so names need no quoting.
+var queryClauses = []string{"attempt", "by", "event", "from", "limit", "spell", "to"}
+
+// queryUsage describes the query syntax.
+const queryUsage = "query <count|damage|healing|overhealing|records> [by source|target|spell|event] [from <unit>] [to <unit>] [spell <name>] [event <type>] [attempt <n>] [limit <n>]"
+
+// query is a parsed query command.
+type query struct {
+	measure string
+	by      string
+	from    string
+	to      string
+	spell   string
+	event   string
+	attempt int
+	limit   int
+}
+
+// parseQuery parses the arguments of the query command. Names are returned
+// as typed, to be resolved against the log.
+func parseQuery(arg string) (query, error) {
+	words := strings.Fields(arg)
+	if len(words) == 0 || !slices.Contains(queryMeasures, words[0]) {
+		return query{}, fmt.Errorf("usage: %s", queryUsage)
+	}
+	q := query{measure: words[0], by: "source", limit: 20}
+	clauses := map[string]string{}
+	for i := 1; i < len(words); {
+		keyword := words[i]
+		if !slices.Contains(queryClauses, keyword) {
+			return query{}, fmt.Errorf("unexpected %q, expected one of %s", keyword, strings.Join(queryClauses, ", "))
+		}
+		if _, ok := clauses[keyword]; ok {
+			return query{}, fmt.Errorf("%s is given twice", keyword)
+		}
+		j := i + 1
+		if keyword == "by" {
+			// the keys spell and event are keywords too
+			j = min(j+1, len(words))
+		}
+		for j < len(words) && !slices.Contains(queryClauses, words[j]) {
+			j++
+		}
+		if j == i+1 {
+			return query{}, fmt.Errorf("expected a value after %s", keyword)
+		}
+		clauses[keyword] = strings.Join(words[i+1:j], " ")
+		i = j
+	}
+	for keyword, v := range clauses {
+		var err error
+		switch keyword {
+		case "by":
+			if !slices.Contains(queryKeys, v) {
+				err = fmt.Errorf("cannot group by %q, expected one of %s", v, strings.Join(queryKeys, ", "))
+			}
+			q.by = v
+		case "from":
+			q.from = v
+		case "to":
+			q.to = v
+		case "spell":
+			q.spell = v
+		case "event":
+			q.event = v
+		case "attempt":
+			if q.attempt, err = strconv.Atoi(v); err != nil || q.attempt < 1 {
+				err = fmt.Errorf("expected an attempt number, got %q", v)
+			}
+		case "limit":
+			if q.limit, err = strconv.Atoi(v); err != nil || q.limit < 0 {
+				err = fmt.Errorf("expected a number of rows, got %q", v)
+			}
+		}
+		if err != nil {
+			return query{}, err
+		}
+	}
+	return q, nil
+}
+
+func (r *repl) query(arg string) error {
+	q, err := parseQuery(arg)
+	if err != nil {
+		return err
+	}
+	records, match, err := r.queryRecords(q)
+	if err != nil {
+		return err
+	}
+	if q.measure == "records" {
+		return r.writeRecords(records, match, q.limit)
+	}
+	values := map[string]uint64{}
+	for _, rec := range records {
+		if !match(rec) {
+			continue
+		}
+		v, ok := queryMeasure(q.measure, rec)
+		if !ok {
+			continue
+		}
+		values[queryKey(q.by, rec)] += v
+	}
+	if len(values) == 0 {
+		fmt.Fprintln(r.out, "no records match")
+		return nil
+	}
+	title := fmt.Sprintf("%s by %s", strings.ToUpper(q.measure[:1])+q.measure[1:], q.by)
+	return writeTotals(r.out, title, values, q.limit)
+}
+
+// queryRecords returns the records a query looks at, narrowed with the
+// session index where the query allows it, and the filter they must pass.
+func (r *repl) queryRecords(q query) ([]*frostparse.CombatLogRecord, func(*frostparse.CombatLogRecord) bool, error) {
+	var filters []func(*frostparse.CombatLogRecord) bool
+	var from, to, spell string
+	var err error
+	if q.from != "" {
+		if from, err = resolve("unit", q.from, r.unitNames()); err != nil {
+			return nil, nil, err
+		}
+		filters = append(filters, func(rec *frostparse.CombatLogRecord) bool { return rec.SourceName == from })
+	}
+	if q.to != "" {
+		if to, err = resolve("unit", q.to, r.unitNames()); err != nil {
+			return nil, nil, err
+		}
+		filters = append(filters, func(rec *frostparse.CombatLogRecord) bool { return rec.TargetName == to })
+	}
+	if q.spell != "" {
+		if spell, err = resolve("spell", q.spell, r.spellNames()); err != nil {
+			return nil, nil, err
+		}
+		filters = append(filters, func(rec *frostparse.CombatLogRecord) bool {
+			return rec.SpellAndRangePrefix != nil && rec.SpellAndRangePrefix.SpellName == spell
+		})
+	}
+	if q.event != "" {
+		name, err := resolve("event", strings.ToUpper(q.event), eventNames())
+		if err != nil {
+			return nil, nil, err
+		}
+		filters = append(filters, func(rec *frostparse.CombatLogRecord) bool { return string(rec.EventType) == name })
+	}
+	records := r.session.Records
+	if q.attempt > 0 {
+		encounters := r.session.Encounters()
+		if q.attempt > len(encounters) {
+			return nil, nil, fmt.Errorf("expected an attempt number between 1 and %d, see encounters", len(encounters))
+		}
+		records = encounters[q.attempt-1].Records
+		in := make(map[*frostparse.CombatLogRecord]bool, len(records))
+		for _, rec := range records {
+			in[rec] = true
+		}
+		filters = append(filters, func(rec *frostparse.CombatLogRecord) bool { return in[rec] })
+	}
+	idx := r.session.Index()
+	switch {
+	case from != "":
+		records = indexed(r.units()[from], idx.BySource)
+	case to != "":
+		records = indexed(r.units()[to], idx.ByTarget)
+	case spell != "":
+		records = indexed(r.spellIDs()[spell], idx.BySpell)
+	}
+	match := func(rec *frostparse.CombatLogRecord) bool {
+		for _, f := range filters {
+			if !f(rec) {
+				return false
+			}
+		}
+		return true
+	}
+	return records, match, nil
+}
+
+// indexed returns the records the index holds for every key, in log order.
+func indexed[K comparable](keys []K, lookup func(K) []*frostparse.CombatLogRecord) []*frostparse.CombatLogRecord {
+	if len(keys) == 1 {
+		return lookup(keys[0])
+	}
+	var out []*frostparse.CombatLogRecord
+	for _, k := range keys {
+		out = append(out, lookup(k)...)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Timestamp.Before(out[j].Timestamp)
+	})
+	return out
+}
+
+// queryMeasure returns the amount a record adds to a measure, and false if it
+// does not count towards it.
+func queryMeasure(measure string, rec *frostparse.CombatLogRecord) (uint64, bool) {
+	switch measure {
+	case "damage":
+		if rec.DamageSuffix != nil {
+			return rec.DamageSuffix.Amount, true
+		}
+	case "healing":
+		if rec.HealSuffix != nil {
+			return rec.HealSuffix.Amount, true
+		}
+	case "overhealing":
+		if rec.HealSuffix != nil {
+			return rec.HealSuffix.Overhealing, true
+		}
+	case "count":
+		return 1, true
+	}
+	return 0, false
+}
+
+// queryKey returns the group of a record.
+func queryKey(by string, rec *frostparse.CombatLogRecord) string {
+	switch by {
+	case "target":
+		return unitName(rec.TargetName)
+	case "spell":
+		if rec.SpellAndRangePrefix != nil {
+			return rec.SpellAndRangePrefix.SpellName
+		}
+		if rec.EventType == frostparse.SwingDamage || rec.EventType == frostparse.SwingMissed {
+			return "Melee"
+		}
+		return "-"
+	case "event":
+		return string(rec.EventType)
+	}
+	return unitName(rec.SourceName)
+}
+
+// writeRecords lists the matching records, at most limit of them when limit
+// is positive.
+func (r *repl) writeRecords(records []*frostparse.CombatLogRecord, match func(*frostparse.CombatLogRecord) bool, limit int) error {
+	tw := tabwriter.NewWriter(r.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tEVENT\tSOURCE\tTARGET\tSPELL\tAMOUNT")
+	n := 0
+	for _, rec := range records {
+		if !match(rec) {
+			continue
+		}
+		if limit > 0 && n == limit {
+			fmt.Fprintln(tw, "...")
+			break
+		}
+		n++
+		amount := "-"
+		for _, measure := range []string{"damage", "healing"} {
+			if v, ok := queryMeasure(measure, rec); ok {
+				amount = strconv.FormatUint(v, 10)
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", rec.Timestamp.Format("15:04:05.000"), rec.EventType,
+			unitName(rec.SourceName), unitName(rec.TargetName), queryKey("spell", rec), amount)
+	}
+	if n == 0 {
+		fmt.Fprintln(tw, "no records match")
+	}
+	return tw.Flush()
+}
+
+// completeQuery completes the last clause of a query: the measure, the
+// keyword of a clause, or its value.
+func (r *repl) completeQuery(arg string) (string, []string) {
+	words := strings.Fields(arg)
+	trailing := strings.HasSuffix(arg, " ")
+	if len(words) == 0 || (len(words) == 1 && !trailing) {
+		return strings.TrimSpace(arg), queryMeasures
+	}
+	last := ""
+	if !trailing {
+		last = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+	// the value typed so far runs from the last keyword to the end
+	k := len(words) - 1
+	for (k > 0 && !slices.Contains(queryClauses, words[k])) || (k > 1 && words[k-1] == "by") {
+		k--
+	}
+	var values []string
+	// by takes a single word, so once it is typed the next clause follows
+	if k > 0 && (words[k] != "by" || k == len(words)-1) {
+		switch words[k] {
+		case "by":
+			values = queryKeys
+		case "from", "to":
+			values = r.unitNames()
+		case "spell":
+			values = r.spellNames()
+		case "event":
+			values = eventNames()
+		}
+	}
+	value := strings.TrimLeft(strings.Join(append(words[k+1:], last), " "), " ")
+	if k > 0 && values != nil {
+		for _, v := range values {
+			if strings.HasPrefix(strings.ToLower(v), strings.ToLower(value)) {
+				return value, values
+			}
+		}
+	}
+	return last, queryClauses
+}
+
+// eventNames returns every event type the parser understands.
+func eventNames() []string {
+	out := make([]string, len(frostparse.EventTypes))
+	for i, e := range frostparse.EventTypes {
+		out[i] = string(e)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,418 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// replPrompt is printed before every command.
+const replPrompt = "frostparse> "
+
+func runRepl(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("repl")
+	var in logInput
+	in.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.Arg(0) == "-" {
+		return errors.New("repl: standard input is needed for the prompt, pass the log as a file")
+	}
+	data, p, err := in.parse(fs)
+	if err != nil {
+		return err
+	}
+	r := newRepl(frostparse.NewSession(data), stdout)
+	report := p.Report()
+	fmt.Fprintf(stdout, "%d records, %d players, %d boss attempts. Type help for commands.\n",
+		report.Parsed, len(r.session.Players()), len(r.session.Encounters()))
+	return r.loop(newLineReader(stdin, stdout, r.complete))
+}
+
+// lineReader reads commands from the user.
+type lineReader interface {
+	readLine(prompt string) (string, error)
+}
+
+// scannerReader reads lines without editing or completion, for input that
+// is not a terminal.
+type scannerReader struct {
+	s   *bufio.Scanner
+	out io.Writer
+}
+
+func (r *scannerReader) readLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	if !r.s.Scan() {
+		if err := r.s.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.s.Text(), nil
+}
+
+// replCommand is a command of the repl. complete returns the word of the
+// argument being completed and the candidates for it.
+type replCommand struct {
+	usage    string
+	help     string
+	run      func(r *repl, arg string) error
+	complete func(r *repl, arg string) (string, []string)
+}
+
+// completeNames completes the whole argument with the names returned by list.
+func completeNames(list func(*repl) []string) func(*repl, string) (string, []string) {
+	return func(r *repl, arg string) (string, []string) {
+		return arg, list(r)
+	}
+}
+
+var replCommands map[string]replCommand
+
+func init() {
+	// assigned in init because the help command refers to the map
+	replCommands = map[string]replCommand{
+		"help":       {"help", "list the commands", (*repl).help, nil},
+		"players":    {"players", "list the players in the log", (*repl).players, nil},
+		"spells":     {"spells [prefix]", "list the spells used in the log", (*repl).spells, nil},
+		"encounters": {"encounters", "list the boss attempts", (*repl).encounters, nil},
+		"summary":    {"summary [attempt]", "damage and healing done, for the log or one numbered boss attempt", (*repl).summary, nil},
+		"player":     {"player <name>", "damage and healing done by spell of a player", (*repl).player, completeNames((*repl).playerNames)},
+		"spell":      {"spell <name>", "damage and healing done with a spell by source", (*repl).spell, completeNames((*repl).spellNames)},
+		"query":      {queryUsage, "add up or list the records matching the filters", (*repl).query, (*repl).completeQuery},
+		"deaths":     {"deaths", "list player deaths with their killing blow", (*repl).deaths, nil},
+		"quit":       {"quit", "leave the repl", nil, nil},
+	}
+}
+
+// repl answers commands about a parsed log.
+type repl struct {
+	session *frostparse.Session
+	out     io.Writer
+	// spellList is every spell used in the log, sorted, and spellIDList the
+	// ids of each, computed on first use.
+	spellList   []string
+	spellIDList map[string][]uint64
+	// unitList is every unit named in the log, sorted, and unitGUIDs the
+	// GUIDs of each, computed on first use.
+	unitList  []string
+	unitGUIDs map[string][]string
+}
+
+func newRepl(s *frostparse.Session, out io.Writer) *repl {
+	return &repl{session: s, out: out}
+}
+
+// loop runs commands until quit or the end of the input.
+func (r *repl) loop(lr lineReader) error {
+	for {
+		line, err := lr.readLine(replPrompt)
+		if errors.Is(err, io.EOF) {
+			fmt.Fprintln(r.out)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if name == "" {
+			continue
+		}
+		if name == "quit" || name == "exit" {
+			return nil
+		}
+		cmd, ok := replCommands[name]
+		if !ok {
+			fmt.Fprintf(r.out, "unknown command %q, type help for commands\n", name)
+			continue
+		}
+		if err := cmd.run(r, strings.TrimSpace(arg)); err != nil {
+			fmt.Fprintln(r.out, err)
+		}
+	}
+}
+
+// complete returns the completions of the last word of line: command names
+// for the first word, and the candidates of the command after it.
+func (r *repl) complete(line string) []string {
+	name, arg, ok := strings.Cut(strings.TrimLeft(line, " "), " ")
+	var candidates []string
+	if !ok {
+		for n := range replCommands {
+			candidates = append(candidates, n)
+		}
+		arg = name
+	} else if cmd, found := replCommands[name]; found && cmd.complete != nil {
+		arg, candidates = cmd.complete(r, strings.TrimLeft(arg, " "))
+	}
+	var out []string
+	for _, c := range candidates {
+		if strings.HasPrefix(strings.ToLower(c), strings.ToLower(strings.TrimLeft(arg, " "))) {
+			out = append(out, c)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// resolve finds the candidate named by arg, case-insensitively, accepting
+// any unique prefix.
+func resolve(kind, arg string, candidates []string) (string, error) {
+	if arg == "" {
+		return "", fmt.Errorf("expected a %s name", kind)
+	}
+	var matches []string
+	for _, c := range candidates {
+		if strings.EqualFold(c, arg) {
+			return c, nil
+		}
+		if strings.HasPrefix(strings.ToLower(c), strings.ToLower(arg)) {
+			matches = append(matches, c)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no %s named %q", kind, arg)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("%q matches several %ss: %s", arg, kind, strings.Join(matches, ", "))
+}
+
+func (r *repl) help(string) error {
+	names := make([]string, 0, len(replCommands))
+	for name := range replCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(r.out, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "  %s\t%s\n", replCommands[name].usage, replCommands[name].help)
+	}
+	fmt.Fprintln(tw, "Names may be shortened to any unique prefix, and tab completes them.")
+	return tw.Flush()
+}
+
+func (r *repl) playerNames() []string {
+	return r.session.Players()
+}
+
+func (r *repl) spellNames() []string {
+	if r.spellList == nil {
+		r.spellIDList = map[string][]uint64{}
+		for _, rec := range r.session.Records {
+			if p := rec.SpellAndRangePrefix; p != nil && !slices.Contains(r.spellIDList[p.SpellName], p.SpellID) {
+				if r.spellIDList[p.SpellName] == nil {
+					r.spellList = append(r.spellList, p.SpellName)
+				}
+				r.spellIDList[p.SpellName] = append(r.spellIDList[p.SpellName], p.SpellID)
+			}
+		}
+		sort.Strings(r.spellList)
+	}
+	return r.spellList
+}
+
+// spellIDs returns the ids of every spell by name, since ranks of a spell
+// share its name.
+func (r *repl) spellIDs() map[string][]uint64 {
+	r.spellNames()
+	return r.spellIDList
+}
+
+func (r *repl) unitNames() []string {
+	if r.unitList == nil {
+		r.unitGUIDs = map[string][]string{}
+		add := func(name, guid string) {
+			if name == "" || name == "nil" || slices.Contains(r.unitGUIDs[name], guid) {
+				return
+			}
+			if r.unitGUIDs[name] == nil {
+				r.unitList = append(r.unitList, name)
+			}
+			r.unitGUIDs[name] = append(r.unitGUIDs[name], guid)
+		}
+		for _, rec := range r.session.Records {
+			add(rec.SourceName, rec.SourceID)
+			add(rec.TargetName, rec.TargetID)
+		}
+		sort.Strings(r.unitList)
+	}
+	return r.unitList
+}
+
+// units returns the GUIDs of every unit by name, since every add of an NPC
+// has a GUID of its own.
+func (r *repl) units() map[string][]string {
+	r.unitNames()
+	return r.unitGUIDs
+}
+
+func (r *repl) players(string) error {
+	for _, name := range r.session.Players() {
+		fmt.Fprintln(r.out, name)
+	}
+	return nil
+}
+
+func (r *repl) spells(prefix string) error {
+	for _, name := range r.spellNames() {
+		if strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix)) {
+			fmt.Fprintln(r.out, name)
+		}
+	}
+	return nil
+}
+
+func (r *repl) encounters(string) error {
+	tw := tabwriter.NewWriter(r.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tSTART\tENCOUNTER\tATTEMPT\tDURATION\tRESULT")
+	for i, e := range r.session.Encounters() {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%s\n",
+			i+1, e.StartTime.Format("01/02 15:04:05"), e.Name, e.Attempt, e.Duration().Round(time.Second), e.Result)
+	}
+	return tw.Flush()
+}
+
+// stats returns the summary of the numbered boss attempt, or of the whole log
+// when arg is empty.
+func (r *repl) stats(arg string) (*frostparse.SummaryStats, error) {
+	if arg == "" {
+		return r.session.Summary(), nil
+	}
+	encounters := r.session.Encounters()
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 || n > len(encounters) {
+		return nil, fmt.Errorf("expected an attempt number between 1 and %d, see encounters", len(encounters))
+	}
+	return r.session.Collector.Run(encounters[n-1].Records), nil
+}
+
+func (r *repl) summary(arg string) error {
+	stats, err := r.stats(arg)
+	if err != nil {
+		return err
+	}
+	if err := writeTotals(r.out, "Damage Done", stats.DamageBySource, 10); err != nil {
+		return err
+	}
+	fmt.Fprintln(r.out)
+	return writeTotals(r.out, "Healing Done", stats.HealingBySource, 10)
+}
+
+func (r *repl) player(arg string) error {
+	name, err := resolve("player", arg, r.session.Players())
+	if err != nil {
+		return err
+	}
+	stats := r.session.Summary()
+	damage := map[string]uint64{}
+	if b, ok := stats.DamageBySourceAndSpell[name]; ok {
+		for spell, v := range b.Spells {
+			damage[spell] += v
+		}
+		for pet, spells := range b.Pets {
+			for spell, v := range spells {
+				damage[pet+": "+spell] += v
+			}
+		}
+	}
+	healing := map[string]uint64{}
+	for spell, h := range stats.HealingBySourceAndSpell[name] {
+		healing[spell] = h.Total()
+	}
+	return r.writeDamageAndHealing(name, damage, healing)
+}
+
+func (r *repl) spell(arg string) error {
+	name, err := resolve("spell", arg, r.spellNames())
+	if err != nil {
+		return err
+	}
+	damage, healing := map[string]uint64{}, map[string]uint64{}
+	for _, rec := range r.session.Records {
+		if rec.SpellAndRangePrefix == nil || rec.SpellAndRangePrefix.SpellName != name {
+			continue
+		}
+		switch {
+		case rec.DamageSuffix != nil:
+			damage[rec.SourceName] += rec.DamageSuffix.Amount
+		case rec.HealSuffix != nil:
+			healing[rec.SourceName] += rec.HealSuffix.Amount
+		}
+	}
+	return r.writeDamageAndHealing(name, damage, healing)
+}
+
+// writeDamageAndHealing prints the damage and healing tables of a player or
+// spell, leaving out the empty one.
+func (r *repl) writeDamageAndHealing(name string, damage, healing map[string]uint64) error {
+	if len(damage) == 0 && len(healing) == 0 {
+		fmt.Fprintf(r.out, "%s did no damage or healing\n", name)
+		return nil
+	}
+	if len(damage) > 0 {
+		if err := writeTotals(r.out, name+" Damage Done", damage, 0); err != nil {
+			return err
+		}
+	}
+	if len(damage) > 0 && len(healing) > 0 {
+		fmt.Fprintln(r.out)
+	}
+	if len(healing) > 0 {
+		return writeTotals(r.out, name+" Healing Done", healing, 0)
+	}
+	return nil
+}
+
+func (r *repl) deaths(string) error {
+	tw := tabwriter.NewWriter(r.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tPLAYER\tENCOUNTER\tKILLING BLOW")
+	for _, d := range r.session.Deaths() {
+		blow := "-"
+		if k := d.KillingBlow; k != nil {
+			blow = fmt.Sprintf("%s %s %d", unitName(k.Source), k.Spell, k.Amount)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", d.Timestamp.Format("01/02 15:04:05"), d.Player, d.Encounter, blow)
+	}
+	return tw.Flush()
+}
+
+// newLineReader returns a line editor with tab completion when in is a
+// terminal that supports it, and a plain line reader otherwise.
+func newLineReader(in io.Reader, out io.Writer, complete func(string) []string) lineReader {
+	if f, ok := in.(*os.File); ok {
+		if t, err := newTerminalReader(f, out, complete); err == nil {
+			return t
+		}
+	}
+	return &scannerReader{s: bufio.NewScanner(in), out: out}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+func TestRunRepl(t *testing.T) {
+	path := writeTestLog(t)
+	saved := stdin
+	stdin = strings.NewReader("players\nplayer yog\nencounters\nsummary 1\nspell cold\ndeaths\nsummary 9\nbogus\nquit\nplayers\n")
+	defer func() { stdin = saved }()
+	var out bytes.Buffer
+	if err := run([]string{"repl", path}, &out, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"5 records, 2 players, 1 boss attempts",
+		"Winterinjuly\n",
+		"Yogzar Damage Done\n  Melee  100  100.0%",
+		"Lord Marrowgar  1",
+		"Coldflame Damage Done\n  Lord Marrowgar  20000",
+		"Yogzar  Lord Marrowgar  Lord Marrowgar Coldflame 20000",
+		"expected an attempt number between 1 and 1",
+		`unknown command "bogus"`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in the output:\n%s", want, out.String())
+		}
+	}
+	if strings.Count(out.String(), "Winterinjuly\n") != 1 {
+		t.Errorf("expected commands after quit to be ignored:\n%s", out.String())
+	}
+}
+
+func TestReplQuery(t *testing.T) {
+	data, err := frostparse.New(frostparse.WithReader(strings.NewReader(gradeTestLog))).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	r := newRepl(frostparse.NewSession(data), &out)
+	for arg, want := range map[string]string{
+		"damage by target from w":             "Damage by target\n  Lord Marrowgar  1000  100.0%",
+		"damage by spell to Yogzar attempt 1": "Damage by spell\n  Coldflame  20000  100.0%",
+		"count by event event unit_died":      "Count by event\n  UNIT_DIED  2  100.0%",
+		"damage from Yogzar spell Coldflame":  "no records match",
+		"records from Lord Marrowgar":         "SPELL_DAMAGE  Lord Marrowgar  Yogzar  Coldflame  20000",
+		"damage by bogus":                     `cannot group by "bogus"`,
+		"damage attempt 2":                    "expected an attempt number between 1 and 1",
+		"damage from":                         "expected a value after from",
+		"sum":                                 "usage: query",
+	} {
+		out.Reset()
+		if err := r.query(arg); err != nil {
+			fmt.Fprint(&out, err)
+		}
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("query %s: expected %q in the output:\n%s", arg, want, out.String())
+		}
+	}
+}
+
+func TestRunReplStdin(t *testing.T) {
+	if err := run([]string{"repl", "-"}, io.Discard, io.Discard); err == nil {
+		t.Error("expected the log to be required as a file")
+	}
+}
+
+func TestReplComplete(t *testing.T) {
+	data, err := frostparse.New(frostparse.WithReader(strings.NewReader(gradeTestLog))).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newRepl(frostparse.NewSession(data), io.Discard)
+	for line, want := range map[string][]string{
+		"pl":                         {"player", "players"},
+		"player w":                   {"Winterinjuly"},
+		"spell c":                    {"Coldflame"},
+		"summary 1":                  nil,
+		"query d":                    {"damage"},
+		"query damage ":              {"attempt", "by", "event", "from", "limit", "spell", "to"},
+		"query damage by s":          {"source", "spell"},
+		"query damage by spell f":    {"from"},
+		"query damage from lord m":   {"Lord Marrowgar"},
+		"query count to Yogzar ev":   {"event"},
+		"query count event unit_die": {"UNIT_DIED"},
+		"query records spell Coldf":  {"Coldflame"},
+	} {
+		if got := r.complete(line); !reflect.DeepEqual(got, want) {
+			t.Errorf("complete(%q) = %v, expected %v", line, got, want)
+		}
+	}
+	if _, err := resolve("player", "x", r.session.Players()); err == nil {
+		t.Error("expected no player to match")
+	}
+}
+
+func TestEditLine(t *testing.T) {
+	complete := func(line string) []string {
+		switch line {
+		case "pla":
+			return []string{"player", "players"}
+		case "player", "players":
+			return []string{"players"}
+		case "player w":
+			return []string{"Winterinjuly"}
+		case "query damage to lord m":
+			return []string{"Lord Marrowgar"}
+		}
+		return nil
+	}
+	for in, want := range map[string]string{
+		"ab\x7fc\r":                  "ac",
+		"pla\t\r":                    "player",
+		"player w\t\r":               "player Winterinjuly ",
+		"query damage to lord m\t\r": "query damage to Lord Marrowgar ",
+		"\x1b[Ax\r":                  "x",
+		"héllo\x7f\x7f\r":            "hél",
+	} {
+		got, err := editLine(bufio.NewReader(strings.NewReader(in)), io.Discard, "> ", complete)
+		if err != nil || got != want {
+			t.Errorf("editLine(%q) = %q, %v, expected %q", in, got, err, want)
+		}
+	}
+	if _, err := editLine(bufio.NewReader(strings.NewReader("\x04")), io.Discard, "> ", complete); !errors.Is(err, io.EOF) {
+		t.Errorf("expected Ctrl-D to end the input, got %v", err)
+	}
+	if _, err := editLine(bufio.NewReader(strings.NewReader("ab\x03")), io.Discard, "> ", complete); !errors.Is(err, errInterrupted) {
+		t.Errorf("expected Ctrl-C to interrupt the line, got %v", err)
+	}
+}
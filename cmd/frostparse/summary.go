@@ -0,0 +1,153 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+func runSummary(args []string, stdout, _ io.Writer) error {
+	fs := newFlagSet("summary")
+	top := fs.Int("top", 10, "number of sources to list per table, 0 for all")
+	var in logInput
+	in.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	data, _, err := in.parse(fs)
+	if err != nil {
+		return err
+	}
+	stats := frostparse.NewCollector(in.cfg.CollectorOptions()...).Run(data)
+	tables := []struct {
+		title  string
+		values map[string]uint64
+	}{
+		{"Damage Done", stats.DamageBySource},
+		{"Healing Done", stats.HealingBySource},
+		{"Damage Taken by Source", stats.DamageTakenBySource},
+		{"Damage Taken by Spell", stats.DamageTakenBySpell},
+	}
+	for i, t := range tables {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		if err := writeTotals(stdout, t.title, t.values, *top); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTotals prints a table of totals, largest first, with each row's share
+// of the total.
+func writeTotals(w io.Writer, title string, values map[string]uint64, top int) error {
+	names := make([]string, 0, len(values))
+	var total uint64
+	for name, v := range values {
+		names = append(names, name)
+		total += v
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if values[names[i]] != values[names[j]] {
+			return values[names[i]] > values[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if top > 0 && len(names) > top {
+		names = names[:top]
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, title)
+	for _, name := range names {
+		share := 0.0
+		if total > 0 {
+			share = 100 * float64(values[name]) / float64(total)
+		}
+		fmt.Fprintf(tw, "  %s\t%d\t%.1f%%\n", name, values[name], share)
+	}
+	return tw.Flush()
+}
+
+func runEncounters(args []string, stdout, _ io.Writer) error {
+	fs := newFlagSet("encounters")
+	trash := fs.Bool("trash", false, "include trash pulls between bosses")
+	notesPath := fs.String("notes", "", "file of notes written by annotate to list under each encounter")
+	var in logInput
+	in.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	data, _, err := in.parse(fs)
+	if err != nil {
+		return err
+	}
+	splitter := frostparse.NewEncounterSplitter()
+	var encounters []frostparse.Encounter
+	if *trash {
+		encounters = splitter.Segments(data)
+	} else {
+		encounters = splitter.Split(data)
+	}
+	if *notesPath != "" {
+		notes, err := readNotes(*notesPath)
+		if err != nil {
+			return err
+		}
+		notes.Apply(encounters)
+	}
+	return writeEncounters(stdout, encounters)
+}
+
+// writeEncounters prints a table of encounters with their notes.
+func writeEncounters(w io.Writer, encounters []frostparse.Encounter) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "START\tENCOUNTER\tATTEMPT\tDURATION\tRESULT")
+	for _, e := range encounters {
+		attempt, result := "-", "-"
+		if !e.Trash {
+			attempt, result = fmt.Sprint(e.Attempt), string(e.Result)
+		}
+		if e.Bugged() {
+			result += " (paused)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			e.StartTime.Format("01/02 15:04:05"), e.Name, attempt, e.Duration().Round(time.Second), result)
+		for _, n := range e.Annotations {
+			fmt.Fprintf(tw, "  %s\n", formatNote(n))
+		}
+	}
+	return tw.Flush()
+}
+
+// formatNote renders an annotation on a single line.
+func formatNote(n frostparse.Annotation) string {
+	s := "note: " + n.Note
+	if n.Offset > 0 {
+		s += fmt.Sprintf(" (at %s)", n.Offset)
+	}
+	if n.Author != "" {
+		s += " - " + n.Author
+	}
+	return s
+}
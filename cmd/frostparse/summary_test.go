@@ -0,0 +1,69 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestLog(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "WoWCombatLog.txt")
+	if err := os.WriteFile(path, []byte(gradeTestLog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunSummary(t *testing.T) {
+	var out bytes.Buffer
+	if err := run([]string{"summary", writeTestLog(t)}, &out, &out); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	if !strings.Contains(got, "Winterinjuly  1000  90.9%") {
+		t.Errorf("expected Winterinjuly to top damage done, got:\n%s", got)
+	}
+	if !strings.Contains(got, "Coldflame  20000  100.0%") {
+		t.Errorf("expected Coldflame in damage taken by spell, got:\n%s", got)
+	}
+}
+
+func TestRunEncounters(t *testing.T) {
+	var out bytes.Buffer
+	if err := run([]string{"encounters", writeTestLog(t)}, &out, &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], "Lord Marrowgar  1        10s       kill") {
+		t.Errorf("expected a single Lord Marrowgar kill, got:\n%s", out.String())
+	}
+}
+
+func TestRunEncountersMergesFiles(t *testing.T) {
+	var out bytes.Buffer
+	if err := run([]string{"encounters", writeTestLog(t), writeTestLog(t)}, &out, &out); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 {
+		t.Errorf("expected the duplicate logs to merge into a single kill, got:\n%s", out.String())
+	}
+}
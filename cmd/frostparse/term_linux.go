@@ -0,0 +1,75 @@
+//go:build linux
+
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ttyFile is a terminal on Linux, switched to raw mode with termios.
+type ttyFile struct {
+	f *os.File
+}
+
+func getTermios(fd uintptr) (*syscall.Termios, error) {
+	t := &syscall.Termios{}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCGETS, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return nil, errno
+	}
+	return t, nil
+}
+
+func setTermios(fd uintptr, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// makeRaw turns off line buffering, echo and signals, so the line editor
+// sees every key including Tab and Ctrl-C.
+func (t ttyFile) makeRaw() (func() error, error) {
+	fd := t.f.Fd()
+	saved, err := getTermios(fd)
+	if err != nil {
+		return nil, err
+	}
+	raw := *saved
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO | syscall.ISIG | syscall.IEXTEN
+	raw.Iflag &^= syscall.ICRNL | syscall.IXON
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := setTermios(fd, &raw); err != nil {
+		return nil, err
+	}
+	return func() error { return setTermios(fd, saved) }, nil
+}
+
+// newTerminalReader returns a line editor over f, or an error when f is not
+// a terminal.
+func newTerminalReader(f *os.File, out io.Writer, complete func(string) []string) (lineReader, error) {
+	if _, err := getTermios(f.Fd()); err != nil {
+		return nil, err
+	}
+	return &terminalReader{t: ttyFile{f}, r: bufio.NewReader(f), out: out, complete: complete}, nil
+}
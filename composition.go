@@ -0,0 +1,156 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+// DamageClass is where damage comes from, the way meters break a player's
+// damage down.
+type DamageClass string
+
+const (
+	// DamageDirect is melee swings, auto shots and hits of spells the
+	// player cast.
+	DamageDirect DamageClass = "direct"
+	// DamagePeriodic is damage over time ticks.
+	DamagePeriodic DamageClass = "periodic"
+	// DamagePet is everything done by the player's pets and guardians.
+	DamagePet DamageClass = "pet"
+	// DamageProc is damage of spells the player never cast, such as
+	// trinket, weapon enchant and talent procs, and damage shields.
+	DamageProc DamageClass = "proc"
+)
+
+// DamageClasses lists every DamageClass in display order.
+var DamageClasses = []DamageClass{DamageDirect, DamagePeriodic, DamagePet, DamageProc}
+
+// DefaultDirectSpells are spells whose damage is logged under a different
+// name than the spell the player cast, which would otherwise be mistaken for
+// procs.
+var DefaultDirectSpells = []string{
+	"Judgement of Blood",
+	"Judgement of Command",
+	"Judgement of Corruption",
+	"Judgement of Righteousness",
+	"Judgement of the Martyr",
+	"Judgement of Vengeance",
+	"Explosive Trap Effect",
+	"Immolation Trap Effect",
+	"Frost Trap Effect",
+}
+
+// DamageComposition is the damage a player did by DamageClass.
+type DamageComposition map[DamageClass]uint64
+
+// Total returns the damage of every class combined.
+func (c DamageComposition) Total() uint64 {
+	var total uint64
+	for _, v := range c {
+		total += v
+	}
+	return total
+}
+
+// Share returns the fraction of the total damage done by the class.
+func (c DamageComposition) Share(class DamageClass) float64 {
+	total := c.Total()
+	if total == 0 {
+		return 0
+	}
+	return float64(c[class]) / float64(total)
+}
+
+// CompositionReport is the DamageComposition of each player, by name.
+type CompositionReport map[string]DamageComposition
+
+// CompositionAnalyzerFunc is an option for NewCompositionAnalyzer.
+type CompositionAnalyzerFunc func(*CompositionAnalyzer)
+
+// CompositionAnalyzer breaks the damage players and their pets did to NPCs
+// down by DamageClass. A spell hit counts as direct when the player cast a
+// spell of that name anywhere in the records and as a proc otherwise.
+type CompositionAnalyzer struct {
+	// DirectSpells are always counted as direct damage. Defaults to
+	// DefaultDirectSpells.
+	DirectSpells []string
+}
+
+// WithDirectSpells sets the spells that are always counted as direct damage.
+func WithDirectSpells(spells ...string) CompositionAnalyzerFunc {
+	return func(a *CompositionAnalyzer) {
+		a.DirectSpells = spells
+	}
+}
+
+// NewCompositionAnalyzer initializes, allocates and returns a pointer to a
+// CompositionAnalyzer.
+func NewCompositionAnalyzer(opts ...CompositionAnalyzerFunc) *CompositionAnalyzer {
+	a := &CompositionAnalyzer{
+		DirectSpells: DefaultDirectSpells,
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+// Run computes the CompositionReport of the records.
+func (a *CompositionAnalyzer) Run(data []*CombatLogRecord) CompositionReport {
+	type cast struct{ player, spell string }
+	casts := map[cast]bool{}
+	for _, row := range data {
+		if (row.EventType == SpellCastSuccess || row.EventType == SpellCastStart) &&
+			isPlayerID(row.SourceID) && row.SpellAndRangePrefix != nil {
+			casts[cast{row.SourceName, row.SpellAndRangePrefix.SpellName}] = true
+		}
+	}
+
+	out := CompositionReport{}
+	add := func(player string, class DamageClass, amount uint64) {
+		if out[player] == nil {
+			out[player] = DamageComposition{}
+		}
+		out[player][class] += amount
+	}
+	pets := newPetTracker()
+	for _, row := range data {
+		pets.observe(*row)
+		if row.DamageSuffix == nil || row.EventType == DamageSplit ||
+			!(isNPCID(row.TargetID) || isBossID(row.TargetID)) {
+			continue
+		}
+		amount := row.DamageSuffix.Amount
+		if !isPlayerID(row.SourceID) {
+			if owner, ok := pets.owner(row.SourceID); ok {
+				add(owner, DamagePet, amount)
+			}
+			continue
+		}
+		switch {
+		case row.EventType == SpellPeriodicDamage:
+			add(row.SourceName, DamagePeriodic, amount)
+		case row.EventType == DamageShield:
+			add(row.SourceName, DamageProc, amount)
+		case row.SpellAndRangePrefix == nil,
+			row.EventType == RangeDamage && row.SpellAndRangePrefix.SpellName == "Auto Shot",
+			casts[cast{row.SourceName, row.SpellAndRangePrefix.SpellName}],
+			sliceContains(a.DirectSpells, row.SpellAndRangePrefix.SpellName):
+			add(row.SourceName, DamageDirect, amount)
+		default:
+			add(row.SourceName, DamageProc, amount)
+		}
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "testing"
+
+func TestCompositionAnalyzerRun(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:00.000  SPELL_CAST_SUCCESS,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47610,"Frostfire Bolt",0x14`,
+		`12/11 01:08:01.000  SPELL_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47610,"Frostfire Bolt",0x14,9000,0,16,0,0,0,nil,nil,nil`,
+		`12/11 01:08:01.000  SPELL_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,60488,"Shadow Bolt",0x20,1500,0,32,0,0,0,nil,nil,nil`,
+		`12/11 01:08:03.000  SPELL_PERIODIC_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47610,"Frostfire Bolt",0x14,700,0,16,0,0,0,nil,nil,nil`,
+		`12/11 01:08:03.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,300,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:04.000  DAMAGE_SHIELD,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,43046,"Molten Armor",0x4,200,0,4,0,0,0,nil,nil,nil`,
+		`12/11 01:08:04.000  SPELL_SUMMON,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130006C7D00A1B2,"Mirror Image",0xa28,55342,"Mirror Image",0x40`,
+		`12/11 01:08:05.000  SPELL_DAMAGE,0xF130006C7D00A1B2,"Mirror Image",0xa28,0xF130008F0400003D,"Lord Marrowgar",0x10a48,59638,"Frostbolt",0x10,800,0,16,0,0,0,nil,nil,nil`,
+		`12/11 01:08:06.000  SPELL_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0x07000000007721EC,"Yogzar",0x511,47610,"Frostfire Bolt",0x14,9000,0,16,0,0,0,nil,nil,nil`,
+	)
+	got := NewCompositionAnalyzer().Run(data)["Winterinjuly"]
+	want := DamageComposition{DamageDirect: 9300, DamagePeriodic: 700, DamagePet: 800, DamageProc: 1700}
+	for _, class := range DamageClasses {
+		if got[class] != want[class] {
+			t.Errorf("expected %d %s damage, got %d", want[class], class, got[class])
+		}
+	}
+	if got.Total() != 12500 {
+		t.Errorf("expected 12500 damage in total, got %d", got.Total())
+	}
+	if share := got.Share(DamagePeriodic); share != 700.0/12500 {
+		t.Errorf("unexpected periodic share %f", share)
+	}
+
+	got = NewCompositionAnalyzer(WithDirectSpells("Shadow Bolt")).Run(data)["Winterinjuly"]
+	if got[DamageDirect] != 10800 || got[DamageProc] != 200 {
+		t.Errorf("expected Shadow Bolt to count as direct, got %v", got)
+	}
+}
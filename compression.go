@@ -0,0 +1,119 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrUnsupportedCompression is returned when the input is compressed in a
+// format no Decompressor has been registered for.
+var ErrUnsupportedCompression = errors.New("unsupported compression")
+
+// Decompressor wraps a compressed stream in a reader of its decompressed
+// contents.
+type Decompressor func(io.Reader) (io.Reader, error)
+
+// compressionFormat is a compressed format recognised by its magic number.
+type compressionFormat struct {
+	name  string
+	magic []byte
+	open  Decompressor
+}
+
+var (
+	compressionMu sync.RWMutex
+	// compressionFormats are detected in order. zstd is recognised but has
+	// no Decompressor until one is registered, as the standard library has
+	// no zstd implementation.
+	compressionFormats = []compressionFormat{
+		{name: "gzip", magic: []byte{0x1f, 0x8b}, open: func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		}},
+		{name: "zstd", magic: []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	}
+	// maxMagicLength is the length of the longest registered magic, the
+	// number of bytes peeked to detect compression.
+	maxMagicLength = 4
+)
+
+// RegisterDecompressor registers a Decompressor for inputs starting with
+// magic, replacing any Decompressor registered under the same name. For
+// example, to read .zst archives with github.com/klauspost/compress/zstd:
+//
+//	frostparse.RegisterDecompressor("zstd", []byte{0x28, 0xb5, 0x2f, 0xfd}, func(r io.Reader) (io.Reader, error) {
+//		return zstd.NewReader(r)
+//	})
+func RegisterDecompressor(name string, magic []byte, d Decompressor) {
+	compressionMu.Lock()
+	defer compressionMu.Unlock()
+	f := compressionFormat{name: name, magic: bytes.Clone(magic), open: d}
+	maxMagicLength = max(maxMagicLength, len(magic))
+	for i := range compressionFormats {
+		if compressionFormats[i].name == name {
+			compressionFormats[i] = f
+			return
+		}
+	}
+	compressionFormats = append(compressionFormats, f)
+}
+
+// detectCompression returns the format the data starting with head is
+// compressed in.
+func detectCompression(head []byte) (compressionFormat, bool) {
+	compressionMu.RLock()
+	defer compressionMu.RUnlock()
+	for _, f := range compressionFormats {
+		if bytes.HasPrefix(head, f.magic) {
+			return f, true
+		}
+	}
+	return compressionFormat{}, false
+}
+
+// magicLength returns the number of bytes needed to detect every registered
+// compression format.
+func magicLength() int {
+	compressionMu.RLock()
+	defer compressionMu.RUnlock()
+	return maxMagicLength
+}
+
+// decompress returns a reader of the decompressed contents of r when r is
+// compressed in a registered format, and a reader of r itself otherwise.
+// Combat logs are plain text, so no log line can start with a magic number.
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(magicLength())
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	f, ok := detectCompression(head)
+	if !ok {
+		return br, nil
+	}
+	if f.open == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCompression, f.name)
+	}
+	return f.open(br)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const compressionTestLog = `12/11 00:13:06.105  SWING_DAMAGE,0xF1300094280000B2,"Argent Champion",0xa18,0xF130009093000102,"The Damned",0xa48,40828,0,1,0,0,0,1,nil,nil
+12/11 00:13:38.531  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x070000000062ADF1,"Phokkwho",0x514,61301,"Riptide",0x8,3000,0,100,1
+`
+
+func gzipBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParserParseGzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "WoWCombatLog.txt.gz")
+	if err := os.WriteFile(path, gzipBytes(t, compressionTestLog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := New(WithLogFile(path), WithStrictMode(true)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2 || data[1].HealSuffix.Amount != 3000 {
+		t.Errorf("expected 2 records from the gzipped log, got %d", len(data))
+	}
+	data, err = New(WithReader(bytes.NewReader(gzipBytes(t, compressionTestLog)))).Parse()
+	if err != nil || len(data) != 2 {
+		t.Errorf("expected 2 records from a gzipped reader, got %d (%v)", len(data), err)
+	}
+}
+
+func TestParserParseUnsupportedCompression(t *testing.T) {
+	zstd := append([]byte{0x28, 0xb5, 0x2f, 0xfd}, "frame"...)
+	_, err := New(WithReader(bytes.NewReader(zstd))).Parse()
+	if !errors.Is(err, ErrUnsupportedCompression) {
+		t.Errorf("expected ErrUnsupportedCompression, got %v", err)
+	}
+}
+
+func TestRegisterDecompressor(t *testing.T) {
+	magic := []byte("FPTESTMAGIC")
+	RegisterDecompressor("test", magic, func(r io.Reader) (io.Reader, error) {
+		if _, err := io.ReadFull(r, make([]byte, len(magic))); err != nil {
+			return nil, err
+		}
+		return r, nil
+	})
+	data, err := New(WithReader(strings.NewReader(string(magic) + compressionTestLog))).Parse()
+	if err != nil || len(data) != 2 {
+		t.Errorf("expected the registered decompressor to be used, got %d records (%v)", len(data), err)
+	}
+}
@@ -0,0 +1,543 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the options shared by programs built on frostparse, such as
+// the frostparse command. LoadConfig reads it with a single precedence
+// order: command line flags win over environment variables, which win over
+// the defaults of DefaultConfig. Parser options that take code or streams,
+// such as WithEventListener, WithReader, WithNameSanitizer,
+// WithUnknownEventHandler and WithProgress, have no counterpart, and neither
+// have WithReferenceTime and WithAutoDiscover, which LogYear and LogDir
+// replace.
+type Config struct {
+	// LogFile is the combat log to parse ($FROSTPARSE_LOG_FILE, -log-file).
+	LogFile string
+	// LogDir is searched for the newest combat log when LogFile is unset
+	// ($FROSTPARSE_LOG_DIR, -log-dir).
+	LogDir string
+	// Workers is the number of chunks a log file is parsed in concurrently
+	// ($FROSTPARSE_WORKERS, -workers).
+	Workers int
+	// DispatchWorkers is the number of workers EventListener callbacks run
+	// on ($FROSTPARSE_DISPATCH_WORKERS, -dispatch-workers).
+	DispatchWorkers int
+	// Strict stops at the first malformed line ($FROSTPARSE_STRICT,
+	// -strict). Otherwise up to MaxSkipped lines are skipped
+	// ($FROSTPARSE_MAX_SKIPPED, -max-skipped), without a limit when zero.
+	Strict     bool
+	MaxSkipped int
+	// Resolution is the width of the damage and healing over time buckets
+	// ($FROSTPARSE_RESOLUTION, -resolution).
+	Resolution time.Duration
+	// Location is the time zone of the machine that recorded the log
+	// ($FROSTPARSE_ZONE, -zone), and UTC converts timestamps to UTC
+	// afterwards ($FROSTPARSE_UTC, -utc).
+	Location *time.Location
+	UTC      bool
+	// LogYear is the year the log starts in, worked out from the file when
+	// zero ($FROSTPARSE_LOG_YEAR, -log-year).
+	LogYear int
+	// Locale is the client locale the log was recorded with
+	// ($FROSTPARSE_LOCALE, -locale).
+	Locale *Locale
+	// Bosses restricts records to these boss encounters ($FROSTPARSE_BOSSES,
+	// -boss).
+	Bosses []string
+	// Exclude and Pseudonymize hide players, see WithPrivacy
+	// ($FROSTPARSE_EXCLUDE, -exclude, $FROSTPARSE_PSEUDONYMIZE,
+	// -pseudonymize). PseudonymKey keys the pseudonyms and is only read from
+	// $FROSTPARSE_PSEUDONYM_KEY, so it does not show up in process listings.
+	Exclude      []string
+	Pseudonymize []string
+	PseudonymKey string
+	// MergeDuplicateNames leaves players that share a name under that name
+	// ($FROSTPARSE_MERGE_DUPLICATE_NAMES, -merge-duplicate-names).
+	MergeDuplicateNames bool
+	// From and To only keep lines logged in that time range, see
+	// WithTimeRange ($FROSTPARSE_FROM, -from, $FROSTPARSE_TO, -to).
+	From, To time.Time
+	// Events and ExcludeEvents filter records by event type, see
+	// WithEventFilter ($FROSTPARSE_EVENTS, -events, $FROSTPARSE_EXCLUDE_EVENTS,
+	// -exclude-events).
+	Events        []EventType
+	ExcludeEvents []EventType
+	// MaxBytes, MaxLines, MaxLineLength and MaxDuration bound a parse, see
+	// Limits ($FROSTPARSE_MAX_BYTES, -max-bytes, $FROSTPARSE_MAX_LINES,
+	// -max-lines, $FROSTPARSE_MAX_LINE_LENGTH, -max-line-length,
+	// $FROSTPARSE_MAX_DURATION, -max-duration). Zero means no limit.
+	MaxBytes      int64
+	MaxLines      int
+	MaxLineLength int
+	MaxDuration   time.Duration
+	// CacheDir caches parsed records in the directory, see WithCache
+	// ($FROSTPARSE_CACHE_DIR, -cache-dir).
+	CacheDir string
+	// PollInterval is how often a followed log is checked for new lines
+	// ($FROSTPARSE_POLL_INTERVAL, -poll-interval).
+	PollInterval time.Duration
+	// ProgressInterval is how often progress is reported
+	// ($FROSTPARSE_PROGRESS_INTERVAL, -progress-interval).
+	ProgressInterval time.Duration
+	// Sinks are extra outputs parsed records are written to
+	// ($FROSTPARSE_SINKS, -sink).
+	Sinks []SinkConfig
+}
+
+// SinkConfig names an output in the form "format:path", e.g.
+// "jsonl:/var/log/frostparse/records.jsonl". Which formats are available is
+// up to the program writing the records.
+type SinkConfig struct {
+	Format string
+	Path   string
+}
+
+// String returns the sink in the form "format:path".
+func (s SinkConfig) String() string {
+	return s.Format + ":" + s.Path
+}
+
+// parseSinkConfig parses a sink in the form "format:path".
+func parseSinkConfig(s string) (SinkConfig, error) {
+	format, path, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok || format == "" || path == "" {
+		return SinkConfig{}, fmt.Errorf("frostparse: sink %q is not of the form format:path", s)
+	}
+	return SinkConfig{Format: strings.ToLower(format), Path: path}, nil
+}
+
+// DefaultConfig returns the configuration used when neither an environment
+// variable nor a flag sets an option.
+func DefaultConfig() Config {
+	return Config{
+		Workers:    1,
+		Resolution: 30 * time.Second,
+	}
+}
+
+// configVar binds a Config field to its environment variable and, unless
+// flag is empty, its command line flag.
+type configVar struct {
+	env, flag, usage string
+	isBool           bool
+	set              func(c *Config, s string) error
+	get              func(c *Config) string
+}
+
+var configVars = []configVar{
+	{
+		env: "FROSTPARSE_LOG_FILE", flag: "log-file", usage: "combat log file to parse when none is given as an argument",
+		set: func(c *Config, s string) error { c.LogFile = s; return nil },
+		get: func(c *Config) string { return c.LogFile },
+	},
+	{
+		env: "FROSTPARSE_LOG_DIR", flag: "log-dir", usage: "directory searched for the newest combat log",
+		set: func(c *Config, s string) error { c.LogDir = s; return nil },
+		get: func(c *Config) string { return c.LogDir },
+	},
+	{
+		env: "FROSTPARSE_WORKERS", flag: "workers", usage: "number of chunks a log file is parsed in concurrently",
+		set: func(c *Config, s string) error { return setInt(&c.Workers, s) },
+		get: func(c *Config) string { return strconv.Itoa(c.Workers) },
+	},
+	{
+		env: "FROSTPARSE_DISPATCH_WORKERS", flag: "dispatch-workers", usage: "number of workers event callbacks run on",
+		set: func(c *Config, s string) error { return setInt(&c.DispatchWorkers, s) },
+		get: func(c *Config) string { return strconv.Itoa(c.DispatchWorkers) },
+	},
+	{
+		env: "FROSTPARSE_STRICT", flag: "strict", usage: "fail on the first malformed line", isBool: true,
+		set: func(c *Config, s string) error {
+			v, err := strconv.ParseBool(s)
+			c.Strict = v
+			return err
+		},
+		get: func(c *Config) string { return strconv.FormatBool(c.Strict) },
+	},
+	{
+		env: "FROSTPARSE_MAX_SKIPPED", flag: "max-skipped", usage: "fail once this many malformed lines were skipped, 0 for no limit",
+		set: func(c *Config, s string) error { return setInt(&c.MaxSkipped, s) },
+		get: func(c *Config) string { return strconv.Itoa(c.MaxSkipped) },
+	},
+	{
+		env: "FROSTPARSE_RESOLUTION", flag: "resolution", usage: "width of the damage and healing over time buckets",
+		set: func(c *Config, s string) error {
+			d, err := time.ParseDuration(s)
+			if err == nil && d <= 0 {
+				err = fmt.Errorf("resolution must be positive")
+			}
+			c.Resolution = d
+			return err
+		},
+		get: func(c *Config) string { return c.Resolution.String() },
+	},
+	{
+		env: "FROSTPARSE_ZONE", flag: "zone", usage: "time zone the log was recorded in, e.g. Europe/Berlin",
+		set: func(c *Config, s string) error {
+			loc, err := time.LoadLocation(s)
+			c.Location = loc
+			return err
+		},
+		get: func(c *Config) string {
+			if c.Location == nil {
+				return ""
+			}
+			return c.Location.String()
+		},
+	},
+	{
+		env: "FROSTPARSE_UTC", flag: "utc", usage: "convert timestamps to UTC", isBool: true,
+		set: func(c *Config, s string) error {
+			v, err := strconv.ParseBool(s)
+			c.UTC = v
+			return err
+		},
+		get: func(c *Config) string { return strconv.FormatBool(c.UTC) },
+	},
+	{
+		env: "FROSTPARSE_LOG_YEAR", flag: "log-year", usage: "year the log starts in",
+		set: func(c *Config, s string) error { return setInt(&c.LogYear, s) },
+		get: func(c *Config) string { return strconv.Itoa(c.LogYear) },
+	},
+	{
+		env: "FROSTPARSE_LOCALE", flag: "locale", usage: "client locale the log was recorded with, e.g. deDE",
+		set: func(c *Config, s string) error {
+			l, ok := LookupLocale(s)
+			if !ok {
+				return fmt.Errorf("unknown locale %q", s)
+			}
+			c.Locale = l
+			return nil
+		},
+		get: func(c *Config) string {
+			if c.Locale == nil {
+				return ""
+			}
+			return c.Locale.Name
+		},
+	},
+	{
+		env: "FROSTPARSE_BOSSES", flag: "boss", usage: "only keep attempts at these bosses, comma separated",
+		set: func(c *Config, s string) error { c.Bosses = splitConfigList(s); return nil },
+		get: func(c *Config) string { return strings.Join(c.Bosses, ",") },
+	},
+	{
+		env: "FROSTPARSE_EXCLUDE", flag: "exclude", usage: "leave out every event involving these players, comma separated",
+		set: func(c *Config, s string) error { c.Exclude = splitConfigList(s); return nil },
+		get: func(c *Config) string { return strings.Join(c.Exclude, ",") },
+	},
+	{
+		env: "FROSTPARSE_PSEUDONYMIZE", flag: "pseudonymize", usage: "replace the names of these players with pseudonyms keyed by $FROSTPARSE_PSEUDONYM_KEY, comma separated",
+		set: func(c *Config, s string) error { c.Pseudonymize = splitConfigList(s); return nil },
+		get: func(c *Config) string { return strings.Join(c.Pseudonymize, ",") },
+	},
+	{
+		env: "FROSTPARSE_PSEUDONYM_KEY",
+		set: func(c *Config, s string) error { c.PseudonymKey = s; return nil },
+		get: func(c *Config) string { return c.PseudonymKey },
+	},
+	{
+		env: "FROSTPARSE_MERGE_DUPLICATE_NAMES", flag: "merge-duplicate-names", usage: "do not number players that share a name", isBool: true,
+		set: func(c *Config, s string) error {
+			v, err := strconv.ParseBool(s)
+			c.MergeDuplicateNames = v
+			return err
+		},
+		get: func(c *Config) string { return strconv.FormatBool(c.MergeDuplicateNames) },
+	},
+	{
+		env: "FROSTPARSE_FROM", flag: "from", usage: "only parse lines logged at or after this RFC 3339 time",
+		set: func(c *Config, s string) error { return setTime(&c.From, s) },
+		get: func(c *Config) string { return formatConfigTime(c.From) },
+	},
+	{
+		env: "FROSTPARSE_TO", flag: "to", usage: "only parse lines logged before this RFC 3339 time",
+		set: func(c *Config, s string) error { return setTime(&c.To, s) },
+		get: func(c *Config) string { return formatConfigTime(c.To) },
+	},
+	{
+		env: "FROSTPARSE_EVENTS", flag: "events", usage: "only parse these event types, comma separated",
+		set: func(c *Config, s string) error { return setEventTypes(&c.Events, s) },
+		get: func(c *Config) string { return joinEventTypes(c.Events) },
+	},
+	{
+		env: "FROSTPARSE_EXCLUDE_EVENTS", flag: "exclude-events", usage: "skip these event types, comma separated",
+		set: func(c *Config, s string) error { return setEventTypes(&c.ExcludeEvents, s) },
+		get: func(c *Config) string { return joinEventTypes(c.ExcludeEvents) },
+	},
+	{
+		env: "FROSTPARSE_MAX_BYTES", flag: "max-bytes", usage: "fail on input larger than this many bytes, 0 for no limit",
+		set: func(c *Config, s string) error {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err == nil && n < 0 {
+				err = fmt.Errorf("%d is negative", n)
+			}
+			c.MaxBytes = n
+			return err
+		},
+		get: func(c *Config) string { return strconv.FormatInt(c.MaxBytes, 10) },
+	},
+	{
+		env: "FROSTPARSE_MAX_LINES", flag: "max-lines", usage: "fail on input of more than this many lines, 0 for no limit",
+		set: func(c *Config, s string) error { return setInt(&c.MaxLines, s) },
+		get: func(c *Config) string { return strconv.Itoa(c.MaxLines) },
+	},
+	{
+		env: "FROSTPARSE_MAX_LINE_LENGTH", flag: "max-line-length", usage: "fail on lines longer than this many bytes, 0 for the default of 64KiB",
+		set: func(c *Config, s string) error { return setInt(&c.MaxLineLength, s) },
+		get: func(c *Config) string { return strconv.Itoa(c.MaxLineLength) },
+	},
+	{
+		env: "FROSTPARSE_MAX_DURATION", flag: "max-duration", usage: "fail when parsing takes longer than this, 0 for no limit",
+		set: func(c *Config, s string) error { return setDuration(&c.MaxDuration, s) },
+		get: func(c *Config) string { return c.MaxDuration.String() },
+	},
+	{
+		env: "FROSTPARSE_CACHE_DIR", flag: "cache-dir", usage: "cache parsed records in this directory",
+		set: func(c *Config, s string) error { c.CacheDir = s; return nil },
+		get: func(c *Config) string { return c.CacheDir },
+	},
+	{
+		env: "FROSTPARSE_POLL_INTERVAL", flag: "poll-interval", usage: "how often a followed log is checked for new lines",
+		set: func(c *Config, s string) error { return setDuration(&c.PollInterval, s) },
+		get: func(c *Config) string { return c.PollInterval.String() },
+	},
+	{
+		env: "FROSTPARSE_PROGRESS_INTERVAL", flag: "progress-interval", usage: "how often parsing progress is reported",
+		set: func(c *Config, s string) error { return setDuration(&c.ProgressInterval, s) },
+		get: func(c *Config) string { return c.ProgressInterval.String() },
+	},
+	{
+		env: "FROSTPARSE_SINKS", flag: "sink", usage: "also write the records to these outputs, format:path, comma separated",
+		set: func(c *Config, s string) error {
+			c.Sinks = nil
+			for _, part := range splitConfigList(s) {
+				sink, err := parseSinkConfig(part)
+				if err != nil {
+					return err
+				}
+				c.Sinks = append(c.Sinks, sink)
+			}
+			return nil
+		},
+		get: func(c *Config) string {
+			parts := make([]string, len(c.Sinks))
+			for i, s := range c.Sinks {
+				parts[i] = s.String()
+			}
+			return strings.Join(parts, ",")
+		},
+	},
+}
+
+func setInt(dst *int, s string) error {
+	n, err := strconv.Atoi(s)
+	if err == nil && n < 0 {
+		err = fmt.Errorf("%d is negative", n)
+	}
+	*dst = n
+	return err
+}
+
+func setDuration(dst *time.Duration, s string) error {
+	d, err := time.ParseDuration(s)
+	if err == nil && d < 0 {
+		err = fmt.Errorf("%s is negative", d)
+	}
+	*dst = d
+	return err
+}
+
+func setTime(dst *time.Time, s string) error {
+	t, err := time.Parse(time.RFC3339, s)
+	*dst = t
+	return err
+}
+
+func formatConfigTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func setEventTypes(dst *[]EventType, s string) error {
+	*dst = nil
+	for _, part := range splitConfigList(s) {
+		var et EventType
+		if err := et.UnmarshalText([]byte(part)); err != nil {
+			return err
+		}
+		*dst = append(*dst, et)
+	}
+	return nil
+}
+
+func joinEventTypes(types []EventType) string {
+	parts := make([]string, len(types))
+	for i, et := range types {
+		parts[i] = string(et)
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitConfigList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// LoadConfig returns DefaultConfig overridden by the environment variables
+// that are set, then by the flags in args. The flags are registered on fs,
+// next to any flags the caller registered, and fs is parsed.
+func LoadConfig(fs *flag.FlagSet, args []string) (Config, error) {
+	c := DefaultConfig()
+	if err := c.LoadEnv(os.LookupEnv); err != nil {
+		return c, err
+	}
+	c.RegisterFlags(fs)
+	return c, fs.Parse(args)
+}
+
+// LoadEnv sets the options whose environment variables are set, looked up
+// with lookup such as os.LookupEnv. Empty variables are ignored.
+func (c *Config) LoadEnv(lookup func(string) (string, bool)) error {
+	for _, v := range configVars {
+		s, ok := lookup(v.env)
+		if !ok || s == "" {
+			continue
+		}
+		if err := v.set(c, s); err != nil {
+			return fmt.Errorf("frostparse: $%s: %w", v.env, err)
+		}
+	}
+	return nil
+}
+
+// RegisterFlags registers a flag on fs for every option that has one. The
+// flags default to the current values of c, so call it after LoadEnv for
+// flags to take precedence over the environment.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	for _, v := range configVars {
+		if v.flag != "" {
+			fs.Var(configFlag{c: c, v: v}, v.flag, v.usage+" ($"+v.env+")")
+		}
+	}
+}
+
+// configFlag is the flag.Value of a configVar.
+type configFlag struct {
+	c *Config
+	v configVar
+}
+
+func (f configFlag) String() string {
+	if f.c == nil {
+		return ""
+	}
+	return f.v.get(f.c)
+}
+
+func (f configFlag) Set(s string) error { return f.v.set(f.c, s) }
+
+func (f configFlag) IsBoolFlag() bool { return f.v.isBool }
+
+// ParserOptions returns the parser options selected by c.
+func (c Config) ParserOptions() []ParserFunc {
+	opts := []ParserFunc{
+		WithStrictMode(c.Strict),
+		WithParallelism(c.Workers),
+		WithUTCTimestamps(c.UTC),
+		WithMergeDuplicateNames(c.MergeDuplicateNames),
+	}
+	if c.LogFile != "" {
+		opts = append(opts, WithLogFile(c.LogFile))
+	}
+	if c.DispatchWorkers > 0 {
+		opts = append(opts, WithAsyncDispatch(c.DispatchWorkers))
+	}
+	if l := c.limits(); l != (Limits{}) {
+		opts = append(opts, WithLimits(l))
+	}
+	if c.Location != nil {
+		opts = append(opts, WithLocation(c.Location))
+	}
+	if c.LogYear != 0 {
+		opts = append(opts, WithLogYear(c.LogYear))
+	}
+	if c.Locale != nil {
+		opts = append(opts, WithLocale(c.Locale))
+	}
+	if len(c.Bosses) > 0 {
+		opts = append(opts, WithOnlyEncounters(c.Bosses...))
+	}
+	if !c.From.IsZero() || !c.To.IsZero() {
+		opts = append(opts, WithTimeRange(c.From, c.To))
+	}
+	if len(c.Events) > 0 {
+		opts = append(opts, WithEventFilter(c.Events))
+	}
+	if len(c.ExcludeEvents) > 0 {
+		opts = append(opts, WithExcludedEvents(c.ExcludeEvents...))
+	}
+	if c.CacheDir != "" {
+		opts = append(opts, WithCache(NewCache(WithCacheDir(c.CacheDir))))
+	}
+	if c.PollInterval > 0 {
+		opts = append(opts, WithPollInterval(c.PollInterval))
+	}
+	if c.ProgressInterval > 0 {
+		opts = append(opts, WithProgressInterval(c.ProgressInterval))
+	}
+	if len(c.Exclude) > 0 || len(c.Pseudonymize) > 0 {
+		opts = append(opts, WithPrivacy(NewPrivacy(
+			WithExcludedPlayers(c.Exclude...),
+			WithPseudonymizedPlayers(c.Pseudonymize...),
+			WithPseudonymKey(c.PseudonymKey),
+		)))
+	}
+	return opts
+}
+
+// limits returns the Limits selected by c.
+func (c Config) limits() Limits {
+	return Limits{
+		MaxBytes:      c.MaxBytes,
+		MaxLines:      c.MaxLines,
+		MaxLineLength: c.MaxLineLength,
+		MaxDuration:   c.MaxDuration,
+		MaxSkipped:    c.MaxSkipped,
+	}
+}
+
+// CollectorOptions returns the collector options selected by c.
+func (c Config) CollectorOptions() []CollectorFunc {
+	return []CollectorFunc{WithTimeResolution(c.Resolution)}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	t.Setenv("FROSTPARSE_WORKERS", "4")
+	t.Setenv("FROSTPARSE_RESOLUTION", "5s")
+	t.Setenv("FROSTPARSE_ZONE", "Europe/Berlin")
+	t.Setenv("FROSTPARSE_STRICT", "true")
+	t.Setenv("FROSTPARSE_SINKS", "jsonl:out.jsonl, csv:out.csv")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	c, err := LoadConfig(fs, []string{"-workers", "8", "-strict=false", "-boss", "Lord Marrowgar,Deathbringer Saurfang"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Workers != 8 || c.Strict {
+		t.Errorf("expected flags to win over the environment, got %d workers and strict %v", c.Workers, c.Strict)
+	}
+	if c.Resolution != 5*time.Second || c.Location == nil || c.Location.String() != "Europe/Berlin" {
+		t.Errorf("expected the environment to win over the defaults, got %v in %v", c.Resolution, c.Location)
+	}
+	if len(c.Sinks) != 2 || c.Sinks[1] != (SinkConfig{Format: "csv", Path: "out.csv"}) {
+		t.Errorf("unexpected sinks %v", c.Sinks)
+	}
+	if len(c.Bosses) != 2 || c.Bosses[1] != "Deathbringer Saurfang" {
+		t.Errorf("unexpected bosses %q", c.Bosses)
+	}
+	if c.DispatchWorkers != 0 || c.LogYear != 0 {
+		t.Errorf("expected unset options to keep their defaults, got %+v", c)
+	}
+}
+
+func TestConfigLoadEnvRejectsInvalidValues(t *testing.T) {
+	for env, value := range map[string]string{
+		"FROSTPARSE_WORKERS":    "many",
+		"FROSTPARSE_RESOLUTION": "-1s",
+		"FROSTPARSE_ZONE":       "Azeroth/Stormwind",
+		"FROSTPARSE_LOCALE":     "xxXX",
+		"FROSTPARSE_SINKS":      "out.jsonl",
+		"FROSTPARSE_FROM":       "yesterday",
+		"FROSTPARSE_EVENTS":     "SPELL_EXPLODE",
+		"FROSTPARSE_MAX_BYTES":  "-1",
+	} {
+		c := DefaultConfig()
+		err := c.LoadEnv(func(k string) (string, bool) {
+			return value, k == env
+		})
+		if err == nil {
+			t.Errorf("expected $%s=%s to fail", env, value)
+		}
+	}
+}
+
+func TestConfigParserOptions(t *testing.T) {
+	t.Setenv("FROSTPARSE_EVENTS", "spell_damage,SWING_DAMAGE")
+	t.Setenv("FROSTPARSE_MAX_LINES", "1000")
+	t.Setenv("FROSTPARSE_CACHE_DIR", t.TempDir())
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	c, err := LoadConfig(fs, []string{
+		"-from", "2023-12-11T01:00:00Z",
+		"-exclude-events", "SPELL_CAST_START",
+		"-max-duration", "1m",
+		"-poll-interval", "1s",
+		"-progress-interval", "2s",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := New(c.ParserOptions()...)
+	if !p.From.Equal(time.Date(2023, 12, 11, 1, 0, 0, 0, time.UTC)) || !p.To.IsZero() {
+		t.Errorf("unexpected time range %s to %s", p.From, p.To)
+	}
+	if len(p.IncludeEvents) != 2 || p.IncludeEvents[0] != SpellDamage || len(p.ExcludeEvents) != 1 || p.ExcludeEvents[0] != SpellCastStart {
+		t.Errorf("unexpected event filter %v, %v", p.IncludeEvents, p.ExcludeEvents)
+	}
+	if p.Limits != (Limits{MaxLines: 1000, MaxDuration: time.Minute}) {
+		t.Errorf("unexpected limits %+v", p.Limits)
+	}
+	if p.Cache == nil || p.Cache.Dir != c.CacheDir {
+		t.Errorf("expected a cache in %s, got %+v", c.CacheDir, p.Cache)
+	}
+	if p.PollInterval != time.Second || p.ProgressInterval != 2*time.Second {
+		t.Errorf("unexpected intervals %s and %s", p.PollInterval, p.ProgressInterval)
+	}
+}
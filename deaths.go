@@ -0,0 +1,194 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "time"
+
+// DeathEvent is a single hit or heal in a DeathRecap.
+type DeathEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	EventType EventType `json:"event_type"`
+	Source    string    `json:"source"`
+	Spell     string    `json:"spell"`
+	Amount    uint64    `json:"amount"`
+	Overkill  uint64    `json:"overkill,omitempty"`
+	Heal      bool      `json:"heal,omitempty"`
+}
+
+// DeathRecap describes what happened to a player in the moments before they
+// died.
+type DeathRecap struct {
+	Player    string    `json:"player"`
+	Timestamp time.Time `json:"timestamp"`
+	Encounter string    `json:"encounter"`
+	// Events are the hits and heals the player took during the window
+	// before dying, oldest first.
+	Events          []DeathEvent `json:"events"`
+	KillingBlow     *DeathEvent  `json:"killing_blow,omitempty"`
+	DamageTaken     uint64       `json:"damage_taken"`
+	HealingReceived uint64       `json:"healing_received"`
+}
+
+// DeathAnalyzer builds a DeathRecap for every player death.
+type DeathAnalyzer struct {
+	// Window is how far back before a death the recap reaches.
+	Window time.Duration
+}
+
+// DeathAnalyzerFunc is an option for NewDeathAnalyzer.
+type DeathAnalyzerFunc func(*DeathAnalyzer)
+
+// WithDeathWindow sets how far back before a death the recap reaches.
+func WithDeathWindow(d time.Duration) DeathAnalyzerFunc {
+	return func(a *DeathAnalyzer) {
+		a.Window = d
+	}
+}
+
+// NewDeathAnalyzer initializes, allocates and returns a pointer to a
+// DeathAnalyzer.
+func NewDeathAnalyzer(opts ...DeathAnalyzerFunc) *DeathAnalyzer {
+	a := &DeathAnalyzer{
+		Window: time.Second * 10,
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+// Run keeps a sliding window of damage taken and healing received per
+// player and turns it into a DeathRecap on every player UNIT_DIED. When a
+// PARTY_KILL names who killed the player, the killing blow is that unit's
+// last hit, or the PARTY_KILL itself when the hit is not in the window.
+// Otherwise it is the last hit that overkilled the player, or failing that
+// an instant kill or the last hit taken.
+func (a *DeathAnalyzer) Run(data []*CombatLogRecord) []DeathRecap {
+	var out []DeathRecap
+	encounters := newEncounterTracker(defaultCombatGap, nil)
+	windows := map[string][]DeathEvent{}
+	partyKills := map[string]CombatLogRecord{}
+
+	for i := range data {
+		row := *data[i]
+		encounter := encounters.observe(row)
+		if !isPlayerID(row.TargetID) {
+			continue
+		}
+		if row.EventType == PartyKill {
+			partyKills[row.TargetID] = row
+			continue
+		}
+		if row.EventType == UnitDied {
+			var killer *CombatLogRecord
+			if k, ok := partyKills[row.TargetID]; ok && row.Timestamp.Sub(k.Timestamp) <= a.Window {
+				killer = &k
+			}
+			out = append(out, a.recap(row, encounter, a.trim(windows[row.TargetID], row.Timestamp), killer))
+			delete(windows, row.TargetID)
+			delete(partyKills, row.TargetID)
+			continue
+		}
+		ev, ok := deathEvent(row)
+		if !ok {
+			continue
+		}
+		windows[row.TargetID] = append(a.trim(windows[row.TargetID], row.Timestamp), ev)
+	}
+	return out
+}
+
+// trim drops events that fell out of the window ending at now.
+func (a *DeathAnalyzer) trim(events []DeathEvent, now time.Time) []DeathEvent {
+	i := 0
+	for i < len(events) && now.Sub(events[i].Timestamp) > a.Window {
+		i++
+	}
+	return events[i:]
+}
+
+func (a *DeathAnalyzer) recap(row CombatLogRecord, encounter string, events []DeathEvent, killer *CombatLogRecord) DeathRecap {
+	r := DeathRecap{
+		Player:    row.TargetName,
+		Timestamp: row.Timestamp,
+		Encounter: encounter,
+		Events:    append([]DeathEvent(nil), events...),
+	}
+	var lastHit, instakill, overkill, killerHit *DeathEvent
+	for i := range r.Events {
+		ev := &r.Events[i]
+		if ev.Heal {
+			r.HealingReceived += ev.Amount
+			continue
+		}
+		r.DamageTaken += ev.Amount
+		lastHit = ev
+		if ev.EventType == SpellInstakill {
+			instakill = ev
+		}
+		if ev.Overkill > 0 {
+			overkill = ev
+		}
+		if killer != nil && ev.Source == killer.SourceName {
+			killerHit = ev
+		}
+	}
+	switch {
+	case killerHit != nil:
+		r.KillingBlow = killerHit
+	case killer != nil:
+		r.KillingBlow = &DeathEvent{
+			Timestamp: killer.Timestamp,
+			EventType: PartyKill,
+			Source:    killer.SourceName,
+		}
+	case overkill != nil:
+		r.KillingBlow = overkill
+	case instakill != nil:
+		r.KillingBlow = instakill
+	default:
+		r.KillingBlow = lastHit
+	}
+	return r
+}
+
+// deathEvent converts a hit or heal on a player into a DeathEvent.
+func deathEvent(row CombatLogRecord) (DeathEvent, bool) {
+	ev := DeathEvent{
+		Timestamp: row.Timestamp,
+		EventType: row.EventType,
+		Source:    row.SourceName,
+		Spell:     abilityName(row),
+		Amount:    recordAmount(row),
+	}
+	switch {
+	case isDamageEvent(row):
+		if row.DamageSuffix != nil {
+			ev.Overkill = row.DamageSuffix.Overkill
+		}
+	case isHealingEvent(row) && row.HealSuffix != nil:
+		ev.Heal = true
+	case row.EventType == EnvironmentalDamage && row.EnvironmentalPrefix != nil:
+		ev.Spell = string(row.EnvironmentalPrefix.EnvironmentalType)
+		if row.DamageSuffix != nil {
+			ev.Overkill = row.DamageSuffix.Overkill
+		}
+	default:
+		return DeathEvent{}, false
+	}
+	return ev, true
+}
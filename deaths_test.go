@@ -0,0 +1,84 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeathAnalyzerRun(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 00:14:00.000  SWING_DAMAGE,0xF13000909300008E,"The Damned",0xa48,0x070000000062ADF1,"Phokkwho",0x514,9999,0,1,0,0,0,nil,nil,nil`,
+		`12/11 00:14:12.000  SWING_DAMAGE,0xF13000909300008E,"The Damned",0xa48,0x070000000062ADF1,"Phokkwho",0x514,5897,0,1,0,0,0,nil,nil,nil`,
+		`12/11 00:14:13.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x070000000062ADF1,"Phokkwho",0x514,61301,"Riptide",0x8,3000,0,0,nil`,
+		`12/11 00:14:14.509  SWING_DAMAGE,0xF130009093000090,"The Damned",0xa48,0x070000000062ADF1,"Phokkwho",0x514,5765,4167,1,0,0,0,nil,nil,nil`,
+		`12/11 00:14:14.509  SWING_DAMAGE,0xF130009093000091,"The Damned",0xa48,0x070000000062ADF1,"Phokkwho",0x514,100,100,1,0,0,0,nil,nil,nil`,
+		`12/11 00:14:14.509  UNIT_DIED,0x0000000000000000,nil,0x80000000,0x070000000062ADF1,"Phokkwho",0x514`,
+	)
+	recaps := NewDeathAnalyzer().Run(data)
+	if len(recaps) != 1 {
+		t.Fatalf("expected 1 death recap, got %d", len(recaps))
+	}
+	r := recaps[0]
+	if r.Player != "Phokkwho" || r.Encounter != TrashEncounter || len(r.Events) != 4 {
+		t.Errorf("expected the hit from 14 seconds earlier to fall out of the window, got %+v", r)
+	}
+	if r.DamageTaken != 5897+5765+100 || r.HealingReceived != 3000 {
+		t.Errorf("unexpected totals: %d taken, %d received", r.DamageTaken, r.HealingReceived)
+	}
+	if r.KillingBlow == nil || r.KillingBlow.Amount != 100 {
+		t.Errorf("expected the last overkilling hit to be the killing blow, got %+v", r.KillingBlow)
+	}
+}
+
+func TestDeathAnalyzerRunPartyKill(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 00:14:12.000  SPELL_DAMAGE,0x07000000007721EC,"Yogzar",0x511,0x070000000062ADF1,"Phokkwho",0x514,49238,"Lightning Bolt",0x8,4000,0,8,0,0,0,nil,nil,nil`,
+		`12/11 00:14:14.000  SWING_DAMAGE,0xF130009093000090,"The Damned",0xa48,0x070000000062ADF1,"Phokkwho",0x514,5765,4167,1,0,0,0,nil,nil,nil`,
+		`12/11 00:14:14.509  PARTY_KILL,0x07000000007721EC,"Yogzar",0x511,0x070000000062ADF1,"Phokkwho",0x514`,
+		`12/11 00:14:14.509  UNIT_DIED,0x0000000000000000,nil,0x80000000,0x070000000062ADF1,"Phokkwho",0x514`,
+		`12/11 00:15:00.000  PARTY_KILL,0x07000000007721EC,"Yogzar",0x511,0x070000000062ADF1,"Phokkwho",0x514`,
+		`12/11 00:15:00.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0x070000000062ADF1,"Phokkwho",0x514`,
+	)
+	recaps := NewDeathAnalyzer().Run(data)
+	if len(recaps) != 2 {
+		t.Fatalf("expected 2 death recaps, got %d", len(recaps))
+	}
+	if kb := recaps[0].KillingBlow; kb == nil || kb.Source != "Yogzar" || kb.Spell != "Lightning Bolt" {
+		t.Errorf("expected the PARTY_KILL source's last hit to be the killing blow, got %+v", kb)
+	}
+	if kb := recaps[1].KillingBlow; kb == nil || kb.Source != "Yogzar" || kb.EventType != PartyKill {
+		t.Errorf("expected the PARTY_KILL to be the killing blow without a hit in the window, got %+v", kb)
+	}
+}
+
+func TestDeathAnalyzerRunTestData(t *testing.T) {
+	data, err := newTestParser().Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recaps := NewDeathAnalyzer(WithDeathWindow(time.Second * 5)).Run(data)
+	if len(recaps) == 0 {
+		t.Fatal("expected player deaths in the test log")
+	}
+	for _, r := range recaps {
+		if r.KillingBlow == nil {
+			t.Errorf("expected a killing blow for %s at %s", r.Player, r.Timestamp)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package demo embeds a sample 3.3.5a combat log, to try frostparse without a
+// log of your own. The log is an Icecrown Citadel raid night: trash leading up
+// to Lord Marrowgar and an attempt at him. An example roster assigns its raid
+// to groups.
+package demo
+
+import (
+	"bytes"
+	_ "embed"
+	"io"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+//go:embed raid.log.gz
+var raidLog []byte
+
+//go:embed roster.csv
+var roster []byte
+
+// Open returns a reader of the sample log. The log is gzip compressed, which
+// the parser detects and decompresses.
+func Open() io.Reader {
+	return bytes.NewReader(raidLog)
+}
+
+// Parse parses the sample log with the options, returning the parser so its
+// report can be inspected.
+func Parse(opts ...frostparse.ParserFunc) ([]*frostparse.CombatLogRecord, *frostparse.Parser, error) {
+	p := frostparse.New(append(opts, frostparse.WithReader(Open()))...)
+	data, err := p.Parse()
+	return data, p, err
+}
+
+// Roster returns the example raid groups of the sample log's players.
+func Roster() (frostparse.Roster, error) {
+	return frostparse.ReadRoster(bytes.NewReader(roster))
+}
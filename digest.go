@@ -0,0 +1,165 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"context"
+	"time"
+)
+
+// Digest is a compact summary of the raid's activity over one interval of
+// the log, for consumers that do not need every record.
+type Digest struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Damage    uint64    `json:"damage"`
+	Healing   uint64    `json:"healing"`
+	DPS       float64   `json:"dps"`
+	HPS       float64   `json:"hps"`
+	Deaths    []string  `json:"deaths"`
+	Encounter string    `json:"encounter"`
+}
+
+// DigesterFunc is an option for NewDigester.
+type DigesterFunc func(*Digester)
+
+// Digester folds a record stream into a Digest per interval of log time.
+type Digester struct {
+	// Interval is the length of each digest. Defaults to a minute.
+	Interval time.Duration
+	// Bosses is the registry bosses are matched against. Defaults to
+	// DefaultBossRegistry.
+	Bosses *BossRegistry
+
+	encounters *encounterTracker
+	pets       *petTracker
+	current    *Digest
+}
+
+// WithDigestInterval sets the length of each digest.
+func WithDigestInterval(d time.Duration) DigesterFunc {
+	return func(g *Digester) {
+		g.Interval = d
+	}
+}
+
+// WithDigestBossRegistry sets the registry bosses are matched against.
+func WithDigestBossRegistry(r *BossRegistry) DigesterFunc {
+	return func(g *Digester) {
+		g.Bosses = r
+	}
+}
+
+// NewDigester initializes, allocates and returns a pointer to a Digester.
+func NewDigester(opts ...DigesterFunc) *Digester {
+	g := &Digester{
+		Interval: time.Minute,
+	}
+	for _, o := range opts {
+		o(g)
+	}
+	g.encounters = newEncounterTracker(defaultCombatGap, g.Bosses)
+	g.pets = newPetTracker()
+	return g
+}
+
+// Observe adds a record to the current digest. Records must be observed in
+// log order; when a record falls past the end of the current interval, the
+// completed digest is returned and a new one is started.
+func (g *Digester) Observe(row CombatLogRecord) (Digest, bool) {
+	var done Digest
+	var ok bool
+	start := row.Timestamp.Truncate(g.Interval)
+	if g.current != nil && !start.Equal(g.current.Start) {
+		done, ok = g.Flush()
+	}
+	if g.current == nil {
+		g.current = &Digest{
+			Start:     start,
+			End:       start.Add(g.Interval),
+			Encounter: TrashEncounter,
+		}
+	}
+	g.add(row)
+	return done, ok
+}
+
+func (g *Digester) add(row CombatLogRecord) {
+	d := g.current
+	g.pets.observe(row)
+	if encounter := g.encounters.observe(row); encounter != TrashEncounter {
+		d.Encounter = encounter
+	}
+	_, pet := g.pets.owner(row.SourceID)
+	switch {
+	case isDamageEvent(row) && (isPlayerID(row.SourceID) || pet) && !isPlayerID(row.TargetID):
+		d.Damage += recordAmount(row)
+	case isHealingEvent(row) && isPlayerID(row.SourceID) && row.HealSuffix != nil:
+		d.Healing += row.HealSuffix.Amount
+	case row.EventType == UnitDied && isPlayerID(row.TargetID):
+		d.Deaths = append(d.Deaths, row.TargetName)
+	}
+}
+
+// Flush returns the digest in progress, if any, and resets the Digester so
+// the next record starts a new interval.
+func (g *Digester) Flush() (Digest, bool) {
+	if g.current == nil {
+		return Digest{}, false
+	}
+	d := *g.current
+	g.current = nil
+	d.DPS = float64(d.Damage) / g.Interval.Seconds()
+	d.HPS = float64(d.Healing) / g.Interval.Seconds()
+	return d, true
+}
+
+// Run consumes records from in, for example the channel returned by
+// Parser.Stream, and sends a Digest for every completed interval. The
+// returned channel is closed once in is closed, after the final partial
+// interval has been sent, or when ctx is cancelled.
+func (g *Digester) Run(ctx context.Context, in <-chan CombatLogRecord) <-chan Digest {
+	out := make(chan Digest)
+	go func() {
+		defer close(out)
+		send := func(d Digest) bool {
+			select {
+			case out <- d:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case row, ok := <-in:
+				if !ok {
+					if d, ok := g.Flush(); ok {
+						send(d)
+					}
+					return
+				}
+				if d, ok := g.Observe(row); ok && !send(d) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDigesterRun(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,6000,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:30.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,1200,0,0,nil`,
+		`12/11 01:08:59.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0x07000000007721EC,"Yogzar",0x511`,
+		`12/11 01:09:10.000  SWING_DAMAGE,0xF130009093000102,"The Damned",0xa48,0x07000000009DF7A8,"Winterinjuly",0x514,100,0,1,0,0,0,nil,nil,nil`,
+	)
+	in := make(chan CombatLogRecord)
+	go func() {
+		defer close(in)
+		for _, rec := range data {
+			in <- *rec
+		}
+	}()
+	var digests []Digest
+	for d := range NewDigester().Run(context.Background(), in) {
+		digests = append(digests, d)
+	}
+	if len(digests) != 2 {
+		t.Fatalf("expected 2 digests, got %d", len(digests))
+	}
+	first := digests[0]
+	if first.DPS != 100 || first.HPS != 20 || first.Encounter != "Lord Marrowgar" {
+		t.Errorf("unexpected first digest: %+v", first)
+	}
+	if len(first.Deaths) != 1 || first.Deaths[0] != "Yogzar" {
+		t.Errorf("expected Yogzar's death in the first digest, got %v", first.Deaths)
+	}
+	if digests[1].Damage != 0 || !digests[1].Start.Equal(first.End) {
+		t.Errorf("unexpected second digest: %+v", digests[1])
+	}
+}
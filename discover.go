@@ -0,0 +1,124 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// LogFilePattern matches the names of the files the game client writes
+// combat logs to.
+const LogFilePattern = "WoWCombatLog*.txt"
+
+// ErrLogNotFound is returned when auto discovery finds no combat log.
+var ErrLogNotFound = errors.New("frostparse: no combat log found")
+
+// installDirs are the folder names the game is commonly installed under.
+var installDirs = []string{
+	"World of Warcraft",
+	"World of Warcraft 3.3.5a",
+	"Wow",
+}
+
+// LogDirectories returns the directories DiscoverLogFile searches by default,
+// in order: $FROSTPARSE_LOG_DIR when it is set, then the Logs folder of the
+// usual install locations on the current platform. On Linux the default Wine
+// prefix is searched.
+func LogDirectories() []string {
+	return logDirectories(runtime.GOOS, os.Getenv)
+}
+
+func logDirectories(goos string, getenv func(string) string) []string {
+	var dirs []string
+	if dir := getenv("FROSTPARSE_LOG_DIR"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	var roots []string
+	home := getenv("HOME")
+	switch goos {
+	case "windows":
+		for _, v := range []string{"ProgramFiles(x86)", "ProgramFiles"} {
+			if root := getenv(v); root != "" {
+				roots = append(roots, root)
+			}
+		}
+		roots = append(roots, `C:\`, `C:\Games`)
+	case "darwin":
+		roots = append(roots, "/Applications")
+		if home != "" {
+			roots = append(roots, filepath.Join(home, "Applications"))
+		}
+	default:
+		if home != "" {
+			drive := filepath.Join(home, ".wine", "drive_c")
+			roots = append(roots, filepath.Join(drive, "Program Files (x86)"), filepath.Join(drive, "Program Files"))
+		}
+	}
+	for _, root := range roots {
+		for _, install := range installDirs {
+			dirs = append(dirs, filepath.Join(root, install, "Logs"))
+		}
+	}
+	return dirs
+}
+
+// DiscoverLogFile returns the most recently modified file matching
+// LogFilePattern in dirs, or in LogDirectories when no dirs are given.
+// Directories that do not exist are skipped. It returns ErrLogNotFound when
+// there is no combat log in any of them.
+func DiscoverLogFile(dirs ...string) (string, error) {
+	if len(dirs) == 0 {
+		dirs = LogDirectories()
+	}
+	var (
+		newest string
+		latest os.FileInfo
+	)
+	for _, dir := range dirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, LogFilePattern))
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			if latest == nil || info.ModTime().After(latest.ModTime()) {
+				newest, latest = path, info
+			}
+		}
+	}
+	if latest == nil {
+		return "", ErrLogNotFound
+	}
+	return newest, nil
+}
+
+// discoverLogFile sets LogFile to the discovered combat log when it is unset
+// and AutoDiscover is enabled.
+func (p *Parser) discoverLogFile() error {
+	if p.LogFile != "" || !p.AutoDiscover {
+		return nil
+	}
+	path, err := DiscoverLogFile()
+	if err != nil {
+		return err
+	}
+	p.LogFile = path
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeDiscoverLog(t *testing.T, dir, name string, mtime time.Time) string {
+	t.Helper()
+	src, err := os.ReadFile("./testdata/test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiscoverLogFile(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	writeDiscoverLog(t, dir, "WoWCombatLog.txt", now.Add(-time.Hour))
+	newest := writeDiscoverLog(t, dir, "WoWCombatLog-archived.txt", now)
+	writeDiscoverLog(t, dir, "notes.txt", now.Add(time.Hour))
+
+	got, err := DiscoverLogFile(filepath.Join(dir, "missing"), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != newest {
+		t.Errorf("expected %s, got %s", newest, got)
+	}
+	if _, err := DiscoverLogFile(t.TempDir()); !errors.Is(err, ErrLogNotFound) {
+		t.Errorf("expected ErrLogNotFound, got %v", err)
+	}
+}
+
+func TestLogDirectories(t *testing.T) {
+	env := map[string]string{
+		"FROSTPARSE_LOG_DIR": "/logs",
+		"HOME":               "/home/raider",
+	}
+	dirs := logDirectories("darwin", func(k string) string { return env[k] })
+	if dirs[0] != "/logs" {
+		t.Errorf("expected $FROSTPARSE_LOG_DIR first, got %v", dirs)
+	}
+	want := filepath.Join("/Applications", "World of Warcraft", "Logs")
+	if !sliceContains(dirs, want) {
+		t.Errorf("expected %s in %v", want, dirs)
+	}
+	want = filepath.Join("/home/raider", ".wine", "drive_c", "Program Files (x86)", "World of Warcraft", "Logs")
+	if dirs := logDirectories("linux", func(k string) string { return env[k] }); !sliceContains(dirs, want) {
+		t.Errorf("expected %s in %v", want, dirs)
+	}
+}
+
+func TestParserAutoDiscover(t *testing.T) {
+	dir := t.TempDir()
+	path := writeDiscoverLog(t, dir, "WoWCombatLog.txt", time.Now())
+	t.Setenv("FROSTPARSE_LOG_DIR", dir)
+	t.Setenv("FROSTPARSE_LOG_FILE", "")
+
+	p := New(WithAutoDiscover(true))
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.LogFile != path || len(data) == 0 {
+		t.Errorf("expected %s to be parsed, got %q with %d records", path, p.LogFile, len(data))
+	}
+}
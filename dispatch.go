@@ -0,0 +1,104 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// dispatchQueueSize is the number of records each async dispatch worker
+// buffers before parsing blocks on it.
+const dispatchQueueSize = 256
+
+// WithAsyncDispatch invokes EventListener callbacks on a pool of workers
+// instead of on the parsing goroutine, so slow callbacks do not stall
+// parsing until a worker's queue fills up. Records of the same event type are
+// always handled by the same worker, so each event type is delivered in log
+// order, but callbacks for different event types, including OnAny and
+// filtered callbacks, run concurrently and must be safe for concurrent use.
+// Parse, Stream and Tail wait for the queued callbacks before returning.
+// A value of zero or less dispatches synchronously, the default.
+func WithAsyncDispatch(workers int) ParserFunc {
+	return func(p *Parser) {
+		p.DispatchWorkers = workers
+	}
+}
+
+// asyncDispatcher fans records out to per-worker queues, sharded by event
+// type.
+type asyncDispatcher struct {
+	listener EventListener
+	queues   []chan CombatLogRecord
+	wg       sync.WaitGroup
+}
+
+func newAsyncDispatcher(l EventListener, workers int) *asyncDispatcher {
+	d := &asyncDispatcher{
+		listener: l,
+		queues:   make([]chan CombatLogRecord, workers),
+	}
+	d.wg.Add(workers)
+	for i := range d.queues {
+		q := make(chan CombatLogRecord, dispatchQueueSize)
+		d.queues[i] = q
+		go func() {
+			defer d.wg.Done()
+			for rec := range q {
+				d.listener.Dispatch(rec)
+			}
+		}()
+	}
+	return d
+}
+
+func (d *asyncDispatcher) dispatch(rec CombatLogRecord) {
+	h := fnv.New32a()
+	h.Write([]byte(rec.EventType))
+	d.queues[h.Sum32()%uint32(len(d.queues))] <- rec
+}
+
+// close waits for every queued record to be dispatched.
+func (d *asyncDispatcher) close() {
+	for _, q := range d.queues {
+		close(q)
+	}
+	d.wg.Wait()
+}
+
+// startDispatch starts the async dispatcher when DispatchWorkers is set and
+// returns the function that drains and stops it.
+func (p *Parser) startDispatch() func() {
+	if p.DispatchWorkers <= 0 {
+		return func() {}
+	}
+	p.dispatcher = newAsyncDispatcher(p.EventListener, p.DispatchWorkers)
+	return func() {
+		p.dispatcher.close()
+		p.dispatcher = nil
+	}
+}
+
+// dispatch hands the record to the EventListener, directly or through the
+// async dispatcher.
+func (p *Parser) dispatch(rec CombatLogRecord) {
+	if p.dispatcher != nil {
+		p.dispatcher.dispatch(rec)
+		return
+	}
+	p.EventListener.Dispatch(rec)
+}
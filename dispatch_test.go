@@ -0,0 +1,71 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParserAsyncDispatch(t *testing.T) {
+	l := NewEventListener()
+	var swings, spells []time.Time
+	l.AddEventListener(SwingDamage, func(r CombatLogRecord) {
+		swings = append(swings, r.Timestamp)
+	})
+	l.AddEventListener(SpellDamage, func(r CombatLogRecord) {
+		// a slow callback must not reorder or drop records
+		time.Sleep(time.Microsecond)
+		spells = append(spells, r.Timestamp)
+	})
+	var all atomic.Int64
+	l.OnAny(func(CombatLogRecord) {
+		all.Add(1)
+	})
+
+	p := New(WithLogFile("./testdata/test.txt"), WithEventListener(l), WithAsyncDispatch(4))
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := all.Load(); got != int64(len(data)) {
+		t.Errorf("expected %d callbacks, got %d", len(data), got)
+	}
+	var wantSwings, wantSpells int
+	for _, r := range data {
+		switch r.EventType {
+		case SwingDamage:
+			wantSwings++
+		case SpellDamage:
+			wantSpells++
+		}
+	}
+	if len(swings) != wantSwings || len(spells) != wantSpells {
+		t.Fatalf("expected %d swings and %d spells, got %d and %d", wantSwings, wantSpells, len(swings), len(spells))
+	}
+	for _, ts := range [][]time.Time{swings, spells} {
+		for i := 1; i < len(ts); i++ {
+			if ts[i].Before(ts[i-1]) {
+				t.Fatalf("records delivered out of order at %d: %s before %s", i, ts[i], ts[i-1])
+			}
+		}
+	}
+	if p.dispatcher != nil {
+		t.Error("expected the dispatcher to be stopped after Parse")
+	}
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"sort"
+	"time"
+)
+
+// DispelType is the kind of effect a dispel can remove.
+type DispelType string
+
+const (
+	DispelMagic   DispelType = "Magic"
+	DispelCurse   DispelType = "Curse"
+	DispelDisease DispelType = "Disease"
+	DispelPoison  DispelType = "Poison"
+)
+
+// dispelSpells maps the friendly dispels available to players to the kinds
+// of effects they remove.
+var dispelSpells = map[string][]DispelType{
+	"Dispel Magic":    {DispelMagic},
+	"Mass Dispel":     {DispelMagic},
+	"Devour Magic":    {DispelMagic},
+	"Cure Disease":    {DispelDisease},
+	"Abolish Disease": {DispelDisease},
+	"Cleanse":         {DispelMagic, DispelDisease, DispelPoison},
+	"Purify":          {DispelDisease, DispelPoison},
+	"Remove Curse":    {DispelCurse},
+	"Cure Poison":     {DispelPoison},
+	"Abolish Poison":  {DispelPoison},
+	"Cleanse Spirit":  {DispelCurse, DispelDisease, DispelPoison},
+	"Cure Toxins":     {DispelDisease, DispelPoison},
+}
+
+// UndispelledDebuff is a dispellable debuff on a player that ran its course
+// while someone in the raid could have removed it.
+type UndispelledDebuff struct {
+	Encounter string       `json:"encounter"`
+	Player    string       `json:"player"`
+	SpellID   uint64       `json:"spell_id"`
+	SpellName string       `json:"spell_name"`
+	Types     []DispelType `json:"types"`
+	Applied   time.Time    `json:"applied"`
+	Expired   time.Time    `json:"expired"`
+	// Dispellers are the players able to remove the debuff.
+	Dispellers []string `json:"dispellers"`
+}
+
+// Duration returns how long the debuff stayed on the player.
+func (u UndispelledDebuff) Duration() time.Duration {
+	return u.Expired.Sub(u.Applied)
+}
+
+// DispelAnalyzer finds dispellable debuffs that expired on players instead
+// of being dispelled.
+//
+// The 3.3.5a combat log does not say which kind of effect a debuff is, so
+// the kind is taken from DebuffTypes or learned from the log: a debuff that
+// was removed by Remove Curse somewhere in the log is a curse. Who can
+// dispel what is learned the same way, from the dispels each player cast.
+type DispelAnalyzer struct {
+	// Roster limits the dispellers to the players in the raid roster.
+	Roster Roster
+	// DebuffTypes maps debuff names to their kind, for debuffs that are
+	// never dispelled in the log being analyzed.
+	DebuffTypes map[string]DispelType
+}
+
+// DispelAnalyzerFunc is an option for NewDispelAnalyzer.
+type DispelAnalyzerFunc func(*DispelAnalyzer)
+
+// WithDispelRoster limits the dispellers to the players in the roster.
+func WithDispelRoster(r Roster) DispelAnalyzerFunc {
+	return func(a *DispelAnalyzer) {
+		a.Roster = r
+	}
+}
+
+// WithDebuffTypes sets the kind of debuffs that cannot be learned from the
+// log.
+func WithDebuffTypes(types map[string]DispelType) DispelAnalyzerFunc {
+	return func(a *DispelAnalyzer) {
+		a.DebuffTypes = types
+	}
+}
+
+// NewDispelAnalyzer initializes, allocates and returns a pointer to a
+// DispelAnalyzer.
+func NewDispelAnalyzer(opts ...DispelAnalyzerFunc) *DispelAnalyzer {
+	a := &DispelAnalyzer{
+		DebuffTypes: map[string]DispelType{},
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+// activeDebuff is a debuff on a player that has not been removed yet.
+type activeDebuff struct {
+	encounter string
+	spellName string
+	applied   time.Time
+}
+
+// Run reports every debuff on a player that was removed by running out
+// rather than by a dispel or the player's death, when it is of a kind one of
+// the dispellers can remove.
+func (a *DispelAnalyzer) Run(data []*CombatLogRecord) []UndispelledDebuff {
+	debuffTypes, dispellers := a.learn(data)
+	var out []UndispelledDebuff
+	encounters := newEncounterTracker(defaultCombatGap, nil)
+	active := map[auraSlot]*activeDebuff{}
+	for _, rec := range data {
+		encounter := encounters.observe(*rec)
+		switch {
+		case rec.EventType == UnitDied:
+			for slot := range active {
+				if slot.targetID == rec.TargetID {
+					delete(active, slot)
+				}
+			}
+		case rec.DispelOrStolenSuffix != nil && rec.EventType == SpellDispel:
+			delete(active, auraSlot{targetID: rec.TargetID, spellID: rec.DispelOrStolenSuffix.ExtraSpellID})
+		case rec.AuraSuffix != nil && rec.AuraSuffix.AuraType == DebuffAura &&
+			rec.SpellAndRangePrefix != nil && isPlayerID(rec.TargetID):
+			slot := auraSlot{targetID: rec.TargetID, spellID: rec.SpellAndRangePrefix.SpellID}
+			if rec.EventType != SpellAuraRemoved {
+				if _, ok := active[slot]; !ok {
+					active[slot] = &activeDebuff{
+						encounter: encounter,
+						spellName: rec.SpellAndRangePrefix.SpellName,
+						applied:   rec.Timestamp,
+					}
+				}
+				continue
+			}
+			d, ok := active[slot]
+			if !ok {
+				continue
+			}
+			delete(active, slot)
+			types := debuffTypes[d.spellName]
+			able := capable(dispellers, types)
+			if len(able) == 0 {
+				continue
+			}
+			out = append(out, UndispelledDebuff{
+				Encounter:  d.encounter,
+				Player:     rec.TargetName,
+				SpellID:    slot.spellID,
+				SpellName:  d.spellName,
+				Types:      types,
+				Applied:    d.applied,
+				Expired:    rec.Timestamp,
+				Dispellers: able,
+			})
+		}
+	}
+	return out
+}
+
+// learn returns the possible kinds of each debuff and the kinds each player
+// is able to dispel.
+func (a *DispelAnalyzer) learn(data []*CombatLogRecord) (map[string][]DispelType, map[string][]DispelType) {
+	debuffs := map[string][]DispelType{}
+	for name, t := range a.DebuffTypes {
+		debuffs[name] = []DispelType{t}
+	}
+	dispellers := map[string][]DispelType{}
+	for _, rec := range data {
+		if rec.SpellAndRangePrefix == nil || !isPlayerID(rec.SourceID) {
+			continue
+		}
+		types, ok := dispelSpells[rec.SpellAndRangePrefix.SpellName]
+		if !ok {
+			continue
+		}
+		if rec.EventType != SpellCastSuccess && rec.EventType != SpellDispel {
+			continue
+		}
+		if a.Roster == nil || a.Roster.Group(rec.SourceName) != 0 {
+			dispellers[rec.SourceName] = unionDispelTypes(dispellers[rec.SourceName], types)
+		}
+		if rec.EventType == SpellDispel && rec.DispelOrStolenSuffix != nil {
+			name := rec.DispelOrStolenSuffix.ExtraSpellName
+			if _, fixed := a.DebuffTypes[name]; fixed {
+				continue
+			}
+			if known, ok := debuffs[name]; ok {
+				debuffs[name] = intersectDispelTypes(known, types)
+			} else {
+				debuffs[name] = types
+			}
+		}
+	}
+	return debuffs, dispellers
+}
+
+// capable returns the sorted names of the dispellers able to remove any of
+// the given kinds.
+func capable(dispellers map[string][]DispelType, types []DispelType) []string {
+	var out []string
+	for name, can := range dispellers {
+		if len(intersectDispelTypes(can, types)) > 0 {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func unionDispelTypes(a, b []DispelType) []DispelType {
+	out := append([]DispelType{}, a...)
+	for _, t := range b {
+		if !sliceContains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func intersectDispelTypes(a, b []DispelType) []DispelType {
+	var out []DispelType
+	for _, t := range a {
+		if sliceContains(b, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDispelAnalyzerRun(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:00:00.000  SPELL_AURA_APPLIED,0xF130009402000010,"Cult Adherent",0xa48,0x07000000009DF7A8,"Winterinjuly",0x514,71237,"Curse of Torpor",0x20,DEBUFF`,
+		`12/11 01:00:02.000  SPELL_DISPEL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,475,"Remove Curse",0x40,71237,"Curse of Torpor",0x20,DEBUFF`,
+		`12/11 01:00:02.000  SPELL_AURA_REMOVED,0xF130009402000010,"Cult Adherent",0xa48,0x07000000009DF7A8,"Winterinjuly",0x514,71237,"Curse of Torpor",0x20,DEBUFF`,
+		`12/11 01:00:10.000  SPELL_AURA_APPLIED,0xF130009402000010,"Cult Adherent",0xa48,0x070000000062ADF1,"Phokkwho",0x514,71237,"Curse of Torpor",0x20,DEBUFF`,
+		`12/11 01:00:10.000  SPELL_AURA_APPLIED,0xF130009402000010,"Cult Adherent",0xa48,0x070000000062ADF1,"Phokkwho",0x514,55095,"Frost Fever",0x10,DEBUFF`,
+		`12/11 01:00:12.000  SPELL_AURA_APPLIED,0xF130009402000010,"Cult Adherent",0xa48,0x07000000009DF7A8,"Winterinjuly",0x514,71237,"Curse of Torpor",0x20,DEBUFF`,
+		`12/11 01:00:13.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0x07000000009DF7A8,"Winterinjuly",0x514`,
+		`12/11 01:00:25.000  SPELL_AURA_REMOVED,0xF130009402000010,"Cult Adherent",0xa48,0x070000000062ADF1,"Phokkwho",0x514,71237,"Curse of Torpor",0x20,DEBUFF`,
+		`12/11 01:00:25.000  SPELL_AURA_REMOVED,0xF130009402000010,"Cult Adherent",0xa48,0x070000000062ADF1,"Phokkwho",0x514,55095,"Frost Fever",0x10,DEBUFF`,
+	)
+	report := NewDispelAnalyzer().Run(data)
+	if len(report) != 1 {
+		t.Fatalf("expected 1 undispelled debuff, got %+v", report)
+	}
+	u := report[0]
+	if u.Player != "Phokkwho" || u.SpellName != "Curse of Torpor" || u.Duration() != time.Second*15 {
+		t.Errorf("unexpected undispelled debuff: %+v", u)
+	}
+	if len(u.Types) != 1 || u.Types[0] != DispelCurse || len(u.Dispellers) != 1 || u.Dispellers[0] != "Yogzar" {
+		t.Errorf("expected a curse Yogzar could remove, got %v by %v", u.Types, u.Dispellers)
+	}
+
+	if report := NewDispelAnalyzer(WithDispelRoster(Roster{"Phokkwho": 1})).Run(data); len(report) != 0 {
+		t.Errorf("expected no dispellers in the roster, got %+v", report)
+	}
+	withFever := NewDispelAnalyzer(WithDebuffTypes(map[string]DispelType{"Frost Fever": DispelDisease})).Run(data)
+	if len(withFever) != 1 {
+		t.Errorf("expected Frost Fever to need a disease dispeller, got %+v", withFever)
+	}
+}
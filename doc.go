@@ -16,4 +16,27 @@ limitations under the License.
 
 // Frostparse package is the library that provides a means to parse a combatlog
 // file into a collection of CombatLogRecord struct.
+//
+// # Compatibility
+//
+// Frostparse follows semantic versioning from v1.0.0: within v1 the exported
+// record types (CombatLogRecord and its prefixes and suffixes, Event and the
+// typed events), the Parser and its options, the EventListener, Session and
+// the analyzers only change in backwards compatible ways. Names that are
+// replaced, typically because they were misspelled, are kept as deprecated
+// aliases of the new name until the next major version. The same holds for
+// the JSON field names of records and reports, and for the subpackages.
+//
+// Some things may change in a minor version:
+//   - The numbers of analyzers that estimate, such as boss health, damage
+//     prevented and triage response, as their heuristics improve.
+//   - The spell, aura and boss tables, such as DefaultRaidCooldowns and
+//     IcecrownCitadel, which gain entries as they are found missing.
+//   - The .fpb format, whose version is BinaryFormatVersion. Older files are
+//     rejected with ErrUnsupportedVersion, and the Cache parses the log again.
+//   - The output of the frostparse command, which is meant to be read rather
+//     than parsed. Its -format jsonl and csv output follows the rules above.
+//
+// Lookup tables the parser and analyzers use internally, such as the spells
+// that reveal a pet's owner, are unexported so that they can change freely.
 package frostparse
@@ -0,0 +1,138 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "time"
+
+// SpellEfficiency combines the number of successful casts of a spell with the
+// damage it dealt and the time spent casting it.
+type SpellEfficiency struct {
+	Casts         uint64        `json:"casts"`
+	Damage        uint64        `json:"damage"`
+	ExecutionTime time.Duration `json:"execution_time"`
+}
+
+// DamagePerCast returns the average damage dealt per successful cast.
+func (s SpellEfficiency) DamagePerCast() float64 {
+	if s.Casts == 0 {
+		return 0
+	}
+	return float64(s.Damage) / float64(s.Casts)
+}
+
+// DamagePerExecutionTime returns the damage dealt per second spent casting.
+func (s SpellEfficiency) DamagePerExecutionTime() float64 {
+	if s.ExecutionTime <= 0 {
+		return 0
+	}
+	return float64(s.Damage) / s.ExecutionTime.Seconds()
+}
+
+// EfficiencyReport maps encounter name to player name to spell name.
+type EfficiencyReport map[string]map[string]map[string]*SpellEfficiency
+
+// EfficiencyAnalyzer computes per-spell cast efficiency for every player in
+// every encounter.
+type EfficiencyAnalyzer struct {
+	// GlobalCooldown is the execution time charged to instant casts, which
+	// have no SPELL_CAST_START to measure a cast time from.
+	GlobalCooldown time.Duration
+}
+
+// EfficiencyAnalyzerFunc is an option for NewEfficiencyAnalyzer.
+type EfficiencyAnalyzerFunc func(*EfficiencyAnalyzer)
+
+// WithGlobalCooldown sets the execution time charged to instant casts.
+func WithGlobalCooldown(d time.Duration) EfficiencyAnalyzerFunc {
+	return func(e *EfficiencyAnalyzer) {
+		e.GlobalCooldown = d
+	}
+}
+
+// NewEfficiencyAnalyzer initializes, allocates and returns a pointer to an
+// EfficiencyAnalyzer.
+func NewEfficiencyAnalyzer(opts ...EfficiencyAnalyzerFunc) *EfficiencyAnalyzer {
+	e := &EfficiencyAnalyzer{
+		GlobalCooldown: time.Millisecond * 1500,
+	}
+	for _, o := range opts {
+		o(e)
+	}
+	return e
+}
+
+// castKey identifies an in-progress cast by caster and spell.
+type castKey struct {
+	sourceID string
+	spellID  uint64
+}
+
+// Run walks the records in order, pairing SPELL_CAST_START with
+// SPELL_CAST_SUCCESS to measure cast times, and attributes player damage to
+// the spell that dealt it.
+func (e *EfficiencyAnalyzer) Run(data []*CombatLogRecord) EfficiencyReport {
+	out := EfficiencyReport{}
+	encounters := newEncounterTracker(defaultCombatGap, nil)
+	started := map[castKey]time.Time{}
+
+	get := func(encounter, player, spell string) *SpellEfficiency {
+		players, ok := out[encounter]
+		if !ok {
+			players = map[string]map[string]*SpellEfficiency{}
+			out[encounter] = players
+		}
+		spells, ok := players[player]
+		if !ok {
+			spells = map[string]*SpellEfficiency{}
+			players[player] = spells
+		}
+		s, ok := spells[spell]
+		if !ok {
+			s = &SpellEfficiency{}
+			spells[spell] = s
+		}
+		return s
+	}
+
+	for i := range data {
+		row := *data[i]
+		encounter := encounters.observe(row)
+		if !isPlayerID(row.SourceID) || row.SpellAndRangePrefix == nil {
+			continue
+		}
+		key := castKey{sourceID: row.SourceID, spellID: row.SpellAndRangePrefix.SpellID}
+		switch {
+		case row.EventType == SpellCastStart:
+			started[key] = row.Timestamp
+		case row.EventType == SpellCastFailed:
+			delete(started, key)
+		case row.EventType == SpellCastSuccess:
+			s := get(encounter, row.SourceName, row.SpellAndRangePrefix.SpellName)
+			s.Casts++
+			if start, ok := started[key]; ok {
+				s.ExecutionTime += row.Timestamp.Sub(start)
+				delete(started, key)
+			} else {
+				s.ExecutionTime += e.GlobalCooldown
+			}
+		case isDamageEvent(row) && row.DamageSuffix != nil && !isPlayerID(row.TargetID):
+			s := get(encounter, row.SourceName, row.SpellAndRangePrefix.SpellName)
+			s.Damage += row.DamageSuffix.Amount
+		}
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEfficiencyAnalyzerRun(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:13.000  SPELL_CAST_START,0x07000000009DF7A8,"Winterinjuly",0x514,0x0000000000000000,nil,0x80000000,47809,"Shadow Bolt",0x20`,
+		`12/11 01:08:15.500  SPELL_CAST_SUCCESS,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47809,"Shadow Bolt",0x20`,
+		`12/11 01:08:16.000  SPELL_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47809,"Shadow Bolt",0x20,5000,0,32,0,0,0,nil,nil,nil`,
+		`12/11 01:08:16.100  SPELL_CAST_SUCCESS,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47867,"Curse of Doom",0x20`,
+	)
+	report := NewEfficiencyAnalyzer().Run(data)
+	sb := report["Lord Marrowgar"]["Winterinjuly"]["Shadow Bolt"]
+	if sb == nil {
+		t.Fatal("expected Shadow Bolt efficiency in the Lord Marrowgar encounter")
+	}
+	if sb.Casts != 1 || sb.ExecutionTime != time.Millisecond*2500 {
+		t.Errorf("unexpected casts or execution time: %+v", *sb)
+	}
+	if sb.DamagePerCast() != 5000 || sb.DamagePerExecutionTime() != 2000 {
+		t.Errorf("unexpected efficiency: %f per cast, %f per second", sb.DamagePerCast(), sb.DamagePerExecutionTime())
+	}
+	cod := report["Lord Marrowgar"]["Winterinjuly"]["Curse of Doom"]
+	if cod == nil || cod.ExecutionTime != time.Millisecond*1500 {
+		t.Errorf("expected instant cast to be charged a global cooldown, got %+v", cod)
+	}
+}
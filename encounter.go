@@ -0,0 +1,323 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "time"
+
+// TrashEncounter is the encounter name used for records that happen outside
+// of any boss encounter.
+const TrashEncounter = "Trash"
+
+// defaultPauseThreshold is how long a boss can go uninvolved during an
+// attempt before the silence is flagged as a pause.
+const defaultPauseThreshold = time.Second * 15
+
+// defaultCombatGap is how long a boss can go without being involved in any
+// event before the encounter is considered over.
+const defaultCombatGap = time.Second * 30
+
+// EncounterResult is the outcome of a boss attempt.
+type EncounterResult string
+
+const (
+	// EncounterKill is an attempt that ended with the boss dying.
+	EncounterKill EncounterResult = "kill"
+	// EncounterWipe is an attempt that ended with every player dead or the
+	// raid no longer fighting the boss.
+	EncounterWipe EncounterResult = "wipe"
+)
+
+// Encounter is a single attempt at a boss, from the first hostile action
+// between the raid and the boss until the boss died or the raid stopped
+// fighting it. Name, Attempt, Result and Records are only set by
+// EncounterSplitter.
+//
+// EncounterSplitter.Segments also returns the trash pulls between bosses as
+// Encounters with Trash set, a heuristic name and the NPCs the raid did the
+// most damage to in Mobs.
+type Encounter struct {
+	Name      string          `json:"name,omitempty"`
+	Attempt   int             `json:"attempt,omitempty"`
+	Result    EncounterResult `json:"result,omitempty"`
+	Trash     bool            `json:"trash,omitempty"`
+	Mobs      []string        `json:"mobs,omitempty"`
+	StartTime time.Time       `json:"start_time"`
+	EndTime   time.Time       `json:"end_time"`
+	// Pauses are the silences during a boss attempt after which the fight
+	// carried on, typically a boss that evaded or reset mid-pull.
+	Pauses []EncounterPause `json:"pauses,omitempty"`
+	// Annotations are the notes attached to the encounter by
+	// Annotations.Apply.
+	Annotations []Annotation       `json:"annotations,omitempty"`
+	Records     []*CombatLogRecord `json:"-"`
+}
+
+// EncounterPause is a stretch of an attempt in which the boss was not
+// involved in any event.
+type EncounterPause struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Duration returns the length of the pause.
+func (p EncounterPause) Duration() time.Duration {
+	return p.End.Sub(p.Start)
+}
+
+// Duration returns the length of the encounter.
+func (e Encounter) Duration() time.Duration {
+	return e.EndTime.Sub(e.StartTime)
+}
+
+// ActiveDuration returns the length of the encounter without its pauses.
+func (e Encounter) ActiveDuration() time.Duration {
+	d := e.Duration()
+	for _, p := range e.Pauses {
+		d -= p.Duration()
+	}
+	return d
+}
+
+// Bugged reports whether the attempt paused and then resumed, which makes
+// its duration unreliable for kill time statistics.
+func (e Encounter) Bugged() bool {
+	return len(e.Pauses) > 0
+}
+
+// encounterTracker follows the record stream in order and reports which boss
+// encounter, if any, each record belongs to.
+type encounterTracker struct {
+	gap      time.Duration
+	bosses   *BossRegistry
+	current  *Boss
+	lastSeen time.Time
+	// attempt is the attempt number of the current encounter.
+	attempt  int
+	attempts map[string]int
+	// result is the outcome of the current or most recently closed
+	// encounter.
+	result EncounterResult
+	// alive tracks which players taking part in the current encounter are
+	// still alive, by GUID.
+	alive map[string]bool
+	// dead holds the boss units that died during the current encounter.
+	dead map[string]bool
+}
+
+func newEncounterTracker(gap time.Duration, bosses *BossRegistry) *encounterTracker {
+	return &encounterTracker{
+		gap:      gap,
+		bosses:   bossRegistryOrDefault(bosses),
+		attempts: map[string]int{},
+	}
+}
+
+// hostileBoss returns the boss when the record is a player and a boss acting
+// against each other, which is what starts a pull. Aura removals and other
+// bookkeeping events around a boss reset do not count, and neither do pets,
+// which the boss keeps fighting after the players have wiped.
+func (e *encounterTracker) hostileBoss(row CombatLogRecord) (*Boss, bool) {
+	switch {
+	case isDamageEvent(row), row.EventType == SwingMissed, row.EventType == SpellMissed,
+		row.EventType == RangeMissed, row.EventType == SpellCastSuccess, row.EventType == SpellAuraApplied:
+	default:
+		return nil, false
+	}
+	if isPlayerID(row.SourceID) {
+		return e.bosses.Match(row.TargetName, row.TargetID)
+	}
+	if isPlayerID(row.TargetID) {
+		return e.bosses.Match(row.SourceName, row.SourceID)
+	}
+	return nil, false
+}
+
+// involves reports whether the unit belongs to the current encounter.
+func (e *encounterTracker) involves(name, guid string) bool {
+	b, ok := e.bosses.Match(name, guid)
+	return ok && b == e.current
+}
+
+// observe advances the tracker with the given record and returns the name of
+// the encounter the record belongs to, or TrashEncounter.
+func (e *encounterTracker) observe(row CombatLogRecord) string {
+	if e.current != nil && row.Timestamp.Sub(e.lastSeen) > e.gap {
+		e.current = nil
+	}
+	if e.current == nil {
+		b, ok := e.hostileBoss(row)
+		if !ok {
+			return TrashEncounter
+		}
+		e.current = b
+		e.attempts[b.Name]++
+		e.attempt = e.attempts[b.Name]
+		e.result = EncounterWipe
+		e.alive = map[string]bool{}
+		e.dead = map[string]bool{}
+	}
+	name := e.current.Name
+	switch {
+	case row.EventType == UnitDied && e.involves(row.TargetName, row.TargetID):
+		e.lastSeen = row.Timestamp
+		e.dead[row.TargetName] = true
+		if e.current.killed(e.dead) {
+			// the boss death closes the encounter but still belongs to it
+			e.result = EncounterKill
+			e.current = nil
+		}
+		return name
+	case row.EventType == UnitDied && isPlayerID(row.TargetID):
+		e.alive[row.TargetID] = false
+		if e.wiped() {
+			e.current = nil
+			e.lastSeen = row.Timestamp
+			return name
+		}
+	case row.EventType == SpellResurrect && isPlayerID(row.TargetID):
+		e.alive[row.TargetID] = true
+	}
+	e.seePlayer(row.SourceID)
+	e.seePlayer(row.TargetID)
+	if e.involves(row.TargetName, row.TargetID) || e.involves(row.SourceName, row.SourceID) {
+		e.lastSeen = row.Timestamp
+	}
+	return name
+}
+
+// seePlayer marks a player as taking part in the current encounter the
+// first time they are seen.
+func (e *encounterTracker) seePlayer(guid string) {
+	if _, ok := e.alive[guid]; !ok && isPlayerID(guid) {
+		e.alive[guid] = true
+	}
+}
+
+// wiped reports whether every player seen during the current encounter is
+// dead.
+func (e *encounterTracker) wiped() bool {
+	for _, alive := range e.alive {
+		if alive {
+			return false
+		}
+	}
+	return len(e.alive) > 0
+}
+
+// EncounterSplitterFunc is an option for NewEncounterSplitter.
+type EncounterSplitterFunc func(*EncounterSplitter)
+
+// EncounterSplitter slices a parsed log into discrete boss attempts.
+type EncounterSplitter struct {
+	// CombatGap is how long a boss can go without being involved in any
+	// event before the attempt is considered over.
+	CombatGap time.Duration
+	// Bosses is the registry bosses are matched against. Defaults to
+	// DefaultBossRegistry.
+	Bosses *BossRegistry
+	// PauseThreshold is how long a boss can go uninvolved during an attempt
+	// before the silence is recorded as a pause. It should be shorter than
+	// CombatGap, which ends the attempt instead.
+	PauseThreshold time.Duration
+}
+
+// WithCombatGap sets how long a boss can go uninvolved before the attempt
+// is considered over.
+func WithCombatGap(d time.Duration) EncounterSplitterFunc {
+	return func(s *EncounterSplitter) {
+		s.CombatGap = d
+	}
+}
+
+// WithSplitterBossRegistry sets the registry bosses are matched against.
+func WithSplitterBossRegistry(r *BossRegistry) EncounterSplitterFunc {
+	return func(s *EncounterSplitter) {
+		s.Bosses = r
+	}
+}
+
+// WithPauseThreshold sets how long a boss can go uninvolved before the
+// silence is recorded as a pause.
+func WithPauseThreshold(d time.Duration) EncounterSplitterFunc {
+	return func(s *EncounterSplitter) {
+		s.PauseThreshold = d
+	}
+}
+
+// NewEncounterSplitter initializes, allocates and returns a pointer to an
+// EncounterSplitter.
+func NewEncounterSplitter(opts ...EncounterSplitterFunc) *EncounterSplitter {
+	s := &EncounterSplitter{
+		CombatGap:      defaultCombatGap,
+		PauseThreshold: defaultPauseThreshold,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// Split returns every boss attempt in the records in order. An attempt
+// starts with the first hostile action between the raid and a boss and ends
+// with the boss UNIT_DIED, a kill, or with the last player death or the last
+// event involving the boss before a combat gap, a wipe. Attempts are
+// numbered from 1 per boss, and Records holds every record logged during the
+// attempt, boss related or not. Silences longer than PauseThreshold but
+// shorter than CombatGap are recorded in Pauses.
+func (s *EncounterSplitter) Split(data []*CombatLogRecord) []Encounter {
+	var out []Encounter
+	tracker := newEncounterTracker(s.CombatGap, s.Bosses)
+	var cur *Encounter
+
+	finish := func() {
+		n := len(cur.Records)
+		for n > 0 && cur.Records[n-1].Timestamp.After(cur.EndTime) {
+			n--
+		}
+		cur.Records = cur.Records[:n:n]
+		out = append(out, *cur)
+		cur = nil
+	}
+
+	for i := range data {
+		last := tracker.lastSeen
+		name := tracker.observe(*data[i])
+		if cur != nil && (name != cur.Name || tracker.attempt != cur.Attempt) {
+			finish()
+		}
+		if cur != nil && s.PauseThreshold > 0 && tracker.lastSeen.Sub(last) > s.PauseThreshold {
+			cur.Pauses = append(cur.Pauses, EncounterPause{Start: last, End: tracker.lastSeen})
+		}
+		if name == TrashEncounter {
+			continue
+		}
+		if cur == nil {
+			cur = &Encounter{
+				Name:      name,
+				Attempt:   tracker.attempt,
+				StartTime: data[i].Timestamp,
+			}
+		}
+		cur.Records = append(cur.Records, data[i])
+		cur.EndTime = tracker.lastSeen
+		cur.Result = tracker.result
+	}
+	if cur != nil {
+		finish()
+	}
+	return out
+}
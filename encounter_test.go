@@ -0,0 +1,141 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestEncounterSplitterSplit(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:07:50.000  SPELL_AURA_APPLIED,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0xF130008F0400003D,"Lord Marrowgar",0x10a48,69146,"Coldflame",0x10,BUFF`,
+		`12/11 01:08:13.904  SPELL_CAST_SUCCESS,0x07000000008B0BE3,"Mostfa",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,53338,"Hunter's Mark",0x40`,
+		`12/11 01:08:14.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:20.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:21.000  SWING_DAMAGE,0xF130009093000102,"The Damned",0xa48,0x07000000007721EC,"Yogzar",0x511,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:09:30.000  SPELL_AURA_REMOVED,0x07000000008B0BE3,"Mostfa",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,53338,"Hunter's Mark",0x40,DEBUFF`,
+		`12/11 01:12:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:12:05.000  SWING_DAMAGE,0xF130009093000102,"The Damned",0xa48,0x07000000007721EC,"Yogzar",0x511,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:12:10.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F0400003D,"Lord Marrowgar",0x10a48`,
+		`12/11 01:12:11.000  SWING_DAMAGE,0xF130009093000102,"The Damned",0xa48,0x07000000007721EC,"Yogzar",0x511,100,0,1,0,0,0,nil,nil,nil`,
+	)
+	encounters := NewEncounterSplitter().Split(data)
+	if len(encounters) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(encounters))
+	}
+	wipe, kill := encounters[0], encounters[1]
+	if wipe.Name != "Lord Marrowgar" || wipe.Attempt != 1 || kill.Attempt != 2 {
+		t.Errorf("unexpected attempts: %s #%d, %s #%d", wipe.Name, wipe.Attempt, kill.Name, kill.Attempt)
+	}
+	if wipe.Result != EncounterWipe || kill.Result != EncounterKill {
+		t.Errorf("expected a wipe then a kill, got %s then %s", wipe.Result, kill.Result)
+	}
+	if !wipe.StartTime.Equal(data[1].Timestamp) || !wipe.EndTime.Equal(data[3].Timestamp) || len(wipe.Records) != 3 {
+		t.Errorf("expected the first attempt to span the pull until the last boss swing, got %s-%s with %d records",
+			wipe.StartTime, wipe.EndTime, len(wipe.Records))
+	}
+	if !kill.EndTime.Equal(data[8].Timestamp) || len(kill.Records) != 3 {
+		t.Errorf("expected the second attempt to end on the boss death, got %s with %d records", kill.EndTime, len(kill.Records))
+	}
+}
+
+func TestEncounterSplitterWipeOnPlayerDeaths(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:14.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:15.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000007721EC,"Yogzar",0x511,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:16.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0x07000000007721EC,"Yogzar",0x511`,
+		`12/11 01:08:18.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0x07000000009DF7A8,"Winterinjuly",0x514`,
+		`12/11 01:08:19.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0xF14000A1B2000001,"pettywap",0x1114,100,0,1,0,0,0,nil,nil,nil`,
+	)
+	encounters := NewEncounterSplitter().Split(data)
+	if len(encounters) != 1 {
+		t.Fatalf("expected 1 attempt, got %d", len(encounters))
+	}
+	e := encounters[0]
+	if e.Result != EncounterWipe || e.Duration() != time.Second*4 || len(e.Records) != 4 {
+		t.Errorf("expected a 4s wipe ending on the last player death, got %s after %s with %d records",
+			e.Result, e.Duration(), len(e.Records))
+	}
+}
+
+func TestEncounterSplitterFlagsPauses(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:05.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:15.000  SWING_DAMAGE,0xF130009093000102,"The Damned",0xa48,0x07000000007721EC,"Yogzar",0x511,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:25.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:30.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F0400003D,"Lord Marrowgar",0x10a48`,
+	)
+	encounters := NewEncounterSplitter().Split(data)
+	if len(encounters) != 1 {
+		t.Fatalf("expected the pause not to split the attempt, got %d attempts", len(encounters))
+	}
+	e := encounters[0]
+	if !e.Bugged() || len(e.Pauses) != 1 || e.Pauses[0].Duration() != time.Second*20 {
+		t.Fatalf("expected a 20s pause, got %v", e.Pauses)
+	}
+	if e.Duration() != time.Second*30 || e.ActiveDuration() != time.Second*10 {
+		t.Errorf("expected 10s of active time in 30s, got %s in %s", e.ActiveDuration(), e.Duration())
+	}
+	if encounters := NewEncounterSplitter(WithPauseThreshold(0)).Split(data); encounters[0].Bugged() {
+		t.Error("expected no pauses with the threshold disabled")
+	}
+}
+
+func TestEncounterSplitterSplitTestData(t *testing.T) {
+	data, err := newTestParser().Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encounters := NewEncounterSplitter(WithCombatGap(time.Minute)).Split(data)
+	for _, e := range encounters {
+		fmt.Println(e.Name, e.Attempt, e.StartTime, e.EndTime, len(e.Records))
+	}
+	if len(encounters) != 1 || encounters[0].Name != "Lord Marrowgar" {
+		t.Errorf("expected a single Lord Marrowgar attempt, got %d attempts", len(encounters))
+	}
+}
+
+func TestEncounterSplitterSegments(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:00:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130009093000102,"The Damned",0xa48,500,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:00:01.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF13000946C0000C9,"Ancient Skeletal Soldier",0xa48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:00:02.000  SWING_DAMAGE,0xF130009093000102,"The Damned",0xa48,0x07000000009DF7A8,"Winterinjuly",0x514,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:14.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:20.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F0400003D,"Lord Marrowgar",0x10a48`,
+		`12/11 01:10:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130009093000103,"The Damned",0xa48,500,0,1,0,0,0,nil,nil,nil`,
+	)
+	segments := NewEncounterSplitter().Segments(data)
+	if len(segments) != 3 {
+		t.Fatalf("expected trash, boss, trash segments, got %d", len(segments))
+	}
+	names := []string{
+		"Icecrown Citadel Trash before Lord Marrowgar",
+		"Lord Marrowgar",
+		"Icecrown Citadel Trash after Lord Marrowgar",
+	}
+	for i, name := range names {
+		if segments[i].Name != name {
+			t.Errorf("expected segment %d to be named %q, got %q", i, name, segments[i].Name)
+		}
+	}
+	if !segments[0].Trash || len(segments[0].Records) != 3 || segments[0].Mobs[0] != "The Damned" {
+		t.Errorf("unexpected first trash segment: %+v", segments[0])
+	}
+}
@@ -16,35 +16,226 @@ limitations under the License.
 
 package frostparse
 
+import "sync"
+
 // CombatLogRecordCallback is the callback function signature.
 type CombatLogRecordCallback func(CombatLogRecord)
 
+// EventListener dispatches parsed records to the callbacks registered for
+// their event type. Any number of callbacks can be registered per event type.
 type EventListener interface {
-	AddEventListener(event EventType, callback CombatLogRecordCallback)
+	// AddEventListener registers a callback for a given event type.
+	AddEventListener(event EventType, callback CombatLogRecordCallback) *Subscription
+	// OnAny registers a callback invoked for every event type.
+	OnAny(callback CombatLogRecordCallback) *Subscription
+	// AddFilteredListener registers a callback invoked for every record the
+	// predicate returns true for.
+	AddFilteredListener(pred func(CombatLogRecord) bool, callback CombatLogRecordCallback) *Subscription
+	// AddSpellListener registers a callback invoked for every record that
+	// references the spell ID.
+	AddSpellListener(spellID uint64, callback CombatLogRecordCallback) *Subscription
+	// Dispatch invokes every callback registered for the record's event type.
+	Dispatch(CombatLogRecord)
+	// Get returns a callback that invokes every callback registered for the
+	// event type, and whether any are registered.
+	//
+	// Deprecated: use Dispatch, which does not allocate a closure per call.
 	Get(EventType) (CombatLogRecordCallback, bool)
 }
 
-// listener stores eventtype and callbacks in a map.
+// Subscription is the handle returned when registering a callback.
+type Subscription struct {
+	l        *listener
+	event    EventType
+	wildcard bool
+	spell    uint64
+	bySpell  bool
+	id       uint64
+}
+
+// Unsubscribe removes the callback from its listener. It is safe to call more
+// than once, and from within a callback.
+func (s *Subscription) Unsubscribe() {
+	if s == nil || s.l == nil {
+		return
+	}
+	s.l.remove(s)
+}
+
+type callbackEntry struct {
+	id   uint64
+	pred func(CombatLogRecord) bool
+	cb   CombatLogRecordCallback
+}
+
+func (c callbackEntry) call(rec CombatLogRecord) {
+	if c.pred == nil || c.pred(rec) {
+		c.cb(rec)
+	}
+}
+
+// listener stores callbacks per event type. The callback slices are copied
+// on write, so Dispatch can iterate them without holding the lock while
+// callbacks subscribe or unsubscribe.
 type listener struct {
-	cbs map[EventType]CombatLogRecordCallback
+	mu       sync.RWMutex
+	nextID   uint64
+	cbs      map[EventType][]callbackEntry
+	spells   map[uint64][]callbackEntry
+	wildcard []callbackEntry
+}
+
+// AddEventListener registers a callback for a given event type. Callbacks for
+// the same event type are invoked in the order they were registered.
+func (e *listener) AddEventListener(event EventType, cb CombatLogRecordCallback) *Subscription {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.nextID++
+	e.cbs[event] = appendEntry(e.cbs[event], callbackEntry{id: e.nextID, cb: cb})
+	return &Subscription{l: e, event: event, id: e.nextID}
+}
+
+// OnAny registers a callback invoked for every record, after the callbacks
+// registered for its event type.
+func (e *listener) OnAny(cb CombatLogRecordCallback) *Subscription {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.nextID++
+	e.wildcard = appendEntry(e.wildcard, callbackEntry{id: e.nextID, cb: cb})
+	return &Subscription{l: e, wildcard: true, id: e.nextID}
 }
 
-// AddEventListener registers a callback for a given event type.
-func (e listener) AddEventListener(event EventType, cb CombatLogRecordCallback) {
-	e.cbs[event] = cb
+// AddFilteredListener registers a callback invoked for every record the
+// predicate returns true for. Filtered callbacks run alongside the wildcard
+// callbacks, in the order they were registered.
+func (e *listener) AddFilteredListener(pred func(CombatLogRecord) bool, cb CombatLogRecordCallback) *Subscription {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.nextID++
+	e.wildcard = appendEntry(e.wildcard, callbackEntry{id: e.nextID, pred: pred, cb: cb})
+	return &Subscription{l: e, wildcard: true, id: e.nextID}
 }
 
-// Get returns the callback and an `ok` to indicate if the key existed in
-// the event callback map.
-func (e listener) Get(event EventType) (CombatLogRecordCallback, bool) {
-	cb, ok := e.cbs[event]
-	return cb, ok
+// AddSpellListener registers a callback invoked for every record that
+// references the spell ID, as the spell of its prefix or as the interrupted,
+// dispelled, stolen or aura breaking spell of its suffix. Spell callbacks run
+// after the callbacks registered for the event type, in the order they were
+// registered.
+func (e *listener) AddSpellListener(spellID uint64, cb CombatLogRecordCallback) *Subscription {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.nextID++
+	e.spells[spellID] = appendEntry(e.spells[spellID], callbackEntry{id: e.nextID, cb: cb})
+	return &Subscription{l: e, spell: spellID, bySpell: true, id: e.nextID}
+}
+
+// Dispatch invokes the callbacks registered for the record's event type and
+// spells, followed by the wildcard and filtered callbacks.
+func (e *listener) Dispatch(rec CombatLogRecord) {
+	e.mu.RLock()
+	cbs, wild := e.cbs[rec.EventType], e.wildcard
+	var spell, extra []callbackEntry
+	if len(e.spells) > 0 {
+		id, extraID := recordSpellIDs(rec)
+		if id != 0 {
+			spell = e.spells[id]
+		}
+		if extraID != 0 && extraID != id {
+			extra = e.spells[extraID]
+		}
+	}
+	e.mu.RUnlock()
+	for _, c := range cbs {
+		c.call(rec)
+	}
+	for _, c := range spell {
+		c.call(rec)
+	}
+	for _, c := range extra {
+		c.call(rec)
+	}
+	for _, c := range wild {
+		c.call(rec)
+	}
+}
+
+// recordSpellIDs returns the spell ID of the record's prefix and the extra
+// spell ID of its suffix, 0 when absent.
+func recordSpellIDs(rec CombatLogRecord) (id, extra uint64) {
+	if rec.SpellAndRangePrefix != nil {
+		id = rec.SpellAndRangePrefix.SpellID
+	}
+	switch {
+	case rec.InterruptSuffix != nil:
+		extra = rec.InterruptSuffix.ExtraSpellID
+	case rec.DispelOrStolenSuffix != nil:
+		extra = rec.DispelOrStolenSuffix.ExtraSpellID
+	}
+	return id, extra
+}
+
+// Get returns a callback invoking every callback registered for the event
+// type, including wildcards, and an `ok` to indicate if there were any.
+func (e *listener) Get(event EventType) (CombatLogRecordCallback, bool) {
+	e.mu.RLock()
+	cbs, wild := e.cbs[event], e.wildcard
+	e.mu.RUnlock()
+	if len(cbs) == 0 && len(wild) == 0 {
+		return nil, false
+	}
+	return func(rec CombatLogRecord) {
+		for _, c := range cbs {
+			c.call(rec)
+		}
+		for _, c := range wild {
+			c.call(rec)
+		}
+	}, true
+}
+
+func (e *listener) remove(s *Subscription) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if s.wildcard {
+		e.wildcard = removeEntry(e.wildcard, s.id)
+		return
+	}
+	if s.bySpell {
+		if cbs := removeEntry(e.spells[s.spell], s.id); len(cbs) > 0 {
+			e.spells[s.spell] = cbs
+		} else {
+			delete(e.spells, s.spell)
+		}
+		return
+	}
+	if cbs := removeEntry(e.cbs[s.event], s.id); len(cbs) > 0 {
+		e.cbs[s.event] = cbs
+	} else {
+		delete(e.cbs, s.event)
+	}
+}
+
+func appendEntry(entries []callbackEntry, c callbackEntry) []callbackEntry {
+	out := make([]callbackEntry, len(entries), len(entries)+1)
+	copy(out, entries)
+	return append(out, c)
+}
+
+func removeEntry(entries []callbackEntry, id uint64) []callbackEntry {
+	out := make([]callbackEntry, 0, len(entries))
+	for _, c := range entries {
+		if c.id != id {
+			out = append(out, c)
+		}
+	}
+	return out
 }
 
-// NewEventListener initializes and allocates an EventLisener implementation
+// NewEventListener initializes and allocates an EventListener implementation
 // and returns it.
 func NewEventListener() EventListener {
 	return &listener{
-		cbs: map[EventType]CombatLogRecordCallback{},
+		cbs:    map[EventType][]callbackEntry{},
+		spells: map[uint64][]callbackEntry{},
 	}
 }
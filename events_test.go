@@ -0,0 +1,126 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "testing"
+
+func TestEventListenerMultipleAndWildcard(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 00:13:38.000  SWING_DAMAGE,0xF14000A1B2000001,"pettywap",0x1114,0xF130009093000102,"The Damned",0xa48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 00:13:39.000  SPELL_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130009093000102,"The Damned",0xa48,49050,"Aimed Shot",0x1,1000,0,1,0,0,0,1,nil,nil`,
+		`12/11 00:13:40.000  SWING_DAMAGE,0xF14000A1B2000001,"pettywap",0x1114,0xF130009093000102,"The Damned",0xa48,120,0,1,0,0,0,nil,nil,nil`,
+	)
+	l := NewEventListener()
+	var first, second, all int
+	sub := l.AddEventListener(SwingDamage, func(CombatLogRecord) { first++ })
+	l.AddEventListener(SwingDamage, func(CombatLogRecord) { second++ })
+	l.OnAny(func(CombatLogRecord) { all++ })
+
+	l.Dispatch(*data[0])
+	l.Dispatch(*data[1])
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+	l.Dispatch(*data[2])
+
+	if first != 1 {
+		t.Errorf("expected unsubscribed callback to run once, got %d", first)
+	}
+	if second != 2 {
+		t.Errorf("expected second callback to run twice, got %d", second)
+	}
+	if all != 3 {
+		t.Errorf("expected wildcard to run 3 times, got %d", all)
+	}
+	if _, ok := l.Get(SpellCastStart); !ok {
+		t.Error("expected wildcard callback for SPELL_CAST_START")
+	}
+}
+
+func TestEventListenerUnsubscribeInsideCallback(t *testing.T) {
+	rec := parseTestLines(t,
+		`12/11 00:13:38.000  SWING_DAMAGE,0xF14000A1B2000001,"pettywap",0x1114,0xF130009093000102,"The Damned",0xa48,100,0,1,0,0,0,nil,nil,nil`,
+	)[0]
+	l := NewEventListener()
+	calls := 0
+	var sub *Subscription
+	sub = l.OnAny(func(CombatLogRecord) {
+		calls++
+		sub.Unsubscribe()
+	})
+	l.Dispatch(*rec)
+	l.Dispatch(*rec)
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+	if _, ok := l.Get(SwingDamage); ok {
+		t.Error("expected no callbacks after unsubscribe")
+	}
+}
+
+func TestEventListenerFiltered(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 00:13:39.000  SPELL_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130009093000102,"The Damned",0xa48,49050,"Aimed Shot",0x1,1000,0,1,0,0,0,1,nil,nil`,
+		`12/11 00:13:40.000  SPELL_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,49050,"Aimed Shot",0x1,1200,0,1,0,0,0,1,nil,nil`,
+		`12/11 00:13:41.000  SWING_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+	)
+	l := NewEventListener()
+	var got []uint64
+	sub := l.AddFilteredListener(func(r CombatLogRecord) bool {
+		return r.EventType == SpellDamage && r.TargetName == "Lord Marrowgar"
+	}, func(r CombatLogRecord) {
+		got = append(got, r.DamageSuffix.Amount)
+	})
+	for _, r := range data {
+		l.Dispatch(*r)
+	}
+	if len(got) != 1 || got[0] != 1200 {
+		t.Errorf("expected only the Marrowgar spell hit, got %v", got)
+	}
+	sub.Unsubscribe()
+	l.Dispatch(*data[1])
+	if len(got) != 1 {
+		t.Errorf("expected no calls after unsubscribe, got %v", got)
+	}
+}
+
+func TestEventListenerSpell(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 00:13:39.000  SPELL_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130009093000102,"The Damned",0xa48,49050,"Aimed Shot",0x1,1000,0,1,0,0,0,1,nil,nil`,
+		`12/11 00:13:40.000  SPELL_CAST_START,0xF130009402000010,"Cult Adherent",0xa48,0x0000000000000000,nil,0x80000000,72007,"Frostbolt",0x10`,
+		`12/11 00:13:41.000  SPELL_INTERRUPT,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130009402000010,"Cult Adherent",0xa48,47528,"Mind Freeze",0x10,72007,"Frostbolt",16`,
+		`12/11 00:13:42.000  SWING_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+	)
+	l := NewEventListener()
+	var got []EventType
+	sub := l.AddSpellListener(72007, func(r CombatLogRecord) {
+		got = append(got, r.EventType)
+	})
+	l.AddSpellListener(0, func(r CombatLogRecord) {
+		t.Errorf("expected no spell 0 callback, got %s", r.EventType)
+	})
+	for _, r := range data {
+		l.Dispatch(*r)
+	}
+	if len(got) != 2 || got[0] != SpellCastStart || got[1] != SpellInterrupt {
+		t.Errorf("expected the Frostbolt cast and its interrupt, got %v", got)
+	}
+	sub.Unsubscribe()
+	l.Dispatch(*data[1])
+	if len(got) != 2 {
+		t.Errorf("expected no calls after unsubscribe, got %v", got)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse_test
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+func ExampleParser_Parse() {
+	p := frostparse.New(frostparse.WithLogFile("./testdata/test.txt"))
+	data, err := p.Parse()
+	if err != nil {
+		log.Fatal(err)
+	}
+	report := p.Report()
+	fmt.Println("records:", len(data))
+	fmt.Println("skipped:", len(report.Quarantined))
+	fmt.Println("first:", data[0].EventType, data[0].SourceName, "->", data[0].TargetName)
+	// Output:
+	// records: 43433
+	// skipped: 0
+	// first: SWING_DAMAGE Argent Champion -> The Damned
+}
+
+func ExampleCollector_Run() {
+	data, err := frostparse.New(frostparse.WithLogFile("./testdata/test.txt")).Parse()
+	if err != nil {
+		log.Fatal(err)
+	}
+	stats := frostparse.NewCollector().Run(data)
+	fmt.Println("Ragequitwar damage:", stats.DamageBySource["Ragequitwar"])
+	fmt.Println("Rzoe healing:", stats.HealingBySource["Rzoe"])
+	// Output:
+	// Ragequitwar damage: 3842788
+	// Rzoe healing: 2613371
+}
+
+func ExampleEventListener() {
+	listener := frostparse.NewEventListener()
+	deaths := 0
+	listener.AddEventListener(frostparse.UnitDied, func(frostparse.CombatLogRecord) {
+		deaths++
+	})
+	events := 0
+	sub := listener.OnAny(func(frostparse.CombatLogRecord) {
+		events++
+	})
+	defer sub.Unsubscribe()
+
+	p := frostparse.New(
+		frostparse.WithLogFile("./testdata/test.txt"),
+		frostparse.WithEventListener(listener),
+	)
+	if _, err := p.Parse(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("deaths:", deaths)
+	fmt.Println("events:", events)
+	// Output:
+	// deaths: 62
+	// events: 43433
+}
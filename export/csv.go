@@ -0,0 +1,305 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// csvTimeFormat keeps millisecond precision, which is all the log has.
+const csvTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// csvColumn is a single typed column of a CSV table.
+type csvColumn struct {
+	name  string
+	value func(*frostparse.CombatLogRecord) string
+}
+
+// csvTable is a CSV schema for the records a table accepts.
+type csvTable struct {
+	file    string
+	accepts func(*frostparse.CombatLogRecord) bool
+	columns []csvColumn
+}
+
+func formatUint(v uint64) string {
+	return strconv.FormatUint(v, 10)
+}
+
+var baseColumns = []csvColumn{
+	{"timestamp", func(r *frostparse.CombatLogRecord) string { return r.Timestamp.Format(csvTimeFormat) }},
+	{"event", func(r *frostparse.CombatLogRecord) string { return string(r.EventType) }},
+	{"source_id", func(r *frostparse.CombatLogRecord) string { return r.SourceID }},
+	{"source_name", func(r *frostparse.CombatLogRecord) string { return r.SourceName }},
+	{"target_id", func(r *frostparse.CombatLogRecord) string { return r.TargetID }},
+	{"target_name", func(r *frostparse.CombatLogRecord) string { return r.TargetName }},
+}
+
+// spellColumns are empty for swings and environmental damage.
+var spellColumns = []csvColumn{
+	{"spell_id", func(r *frostparse.CombatLogRecord) string {
+		if r.SpellAndRangePrefix == nil {
+			return ""
+		}
+		return formatUint(r.SpellAndRangePrefix.SpellID)
+	}},
+	{"spell_name", func(r *frostparse.CombatLogRecord) string {
+		if r.SpellAndRangePrefix == nil {
+			return ""
+		}
+		return r.SpellAndRangePrefix.SpellName
+	}},
+	{"spell_school", func(r *frostparse.CombatLogRecord) string {
+		if r.SpellAndRangePrefix == nil {
+			return ""
+		}
+		return strconv.Itoa(int(r.SpellAndRangePrefix.SpellSchool))
+	}},
+}
+
+func columns(groups ...[]csvColumn) []csvColumn {
+	var out []csvColumn
+	for _, g := range groups {
+		out = append(out, g...)
+	}
+	return out
+}
+
+var damageTable = csvTable{
+	file:    "damage.csv",
+	accepts: func(r *frostparse.CombatLogRecord) bool { return r.DamageSuffix != nil },
+	columns: columns(baseColumns, spellColumns, []csvColumn{
+		{"amount", func(r *frostparse.CombatLogRecord) string { return formatUint(r.DamageSuffix.Amount) }},
+		{"overkill", func(r *frostparse.CombatLogRecord) string { return formatUint(r.DamageSuffix.Overkill) }},
+		{"school", func(r *frostparse.CombatLogRecord) string { return strconv.Itoa(int(r.DamageSuffix.SpellSchool)) }},
+		{"resisted", func(r *frostparse.CombatLogRecord) string { return formatUint(r.DamageSuffix.Resisted) }},
+		{"blocked", func(r *frostparse.CombatLogRecord) string { return formatUint(r.DamageSuffix.Blocked) }},
+		{"absorbed", func(r *frostparse.CombatLogRecord) string { return formatUint(r.DamageSuffix.Absorbed) }},
+		{"critical", func(r *frostparse.CombatLogRecord) string { return strconv.FormatBool(r.DamageSuffix.Critical) }},
+		{"glancing", func(r *frostparse.CombatLogRecord) string { return strconv.FormatBool(r.DamageSuffix.Glancing) }},
+		{"crushing", func(r *frostparse.CombatLogRecord) string { return strconv.FormatBool(r.DamageSuffix.Crushing) }},
+	}),
+}
+
+var healingTable = csvTable{
+	file:    "healing.csv",
+	accepts: func(r *frostparse.CombatLogRecord) bool { return r.HealSuffix != nil },
+	columns: columns(baseColumns, spellColumns, []csvColumn{
+		{"amount", func(r *frostparse.CombatLogRecord) string { return formatUint(r.HealSuffix.Amount) }},
+		{"overhealing", func(r *frostparse.CombatLogRecord) string { return formatUint(r.HealSuffix.Overhealing) }},
+		{"absorbed", func(r *frostparse.CombatLogRecord) string { return formatUint(r.HealSuffix.Absorbed) }},
+		{"critical", func(r *frostparse.CombatLogRecord) string { return strconv.FormatBool(r.HealSuffix.Critical) }},
+	}),
+}
+
+var auraTable = csvTable{
+	file:    "auras.csv",
+	accepts: func(r *frostparse.CombatLogRecord) bool { return r.AuraSuffix != nil },
+	columns: columns(baseColumns, spellColumns, []csvColumn{
+		{"aura_type", func(r *frostparse.CombatLogRecord) string { return string(r.AuraSuffix.AuraType) }},
+		{"doses", func(r *frostparse.CombatLogRecord) string { return formatUint(r.AuraSuffix.Doses) }},
+	}),
+}
+
+// write writes the header and one row per accepted record.
+func (t csvTable) write(w io.Writer, records []*frostparse.CombatLogRecord) error {
+	cw := csv.NewWriter(w)
+	row := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		row[i] = c.name
+	}
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	for _, rec := range records {
+		if !t.accepts(rec) {
+			continue
+		}
+		for i, c := range t.columns {
+			row[i] = c.value(rec)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteDamageCSV writes every record with a damage suffix to w as CSV.
+func WriteDamageCSV(w io.Writer, records []*frostparse.CombatLogRecord) error {
+	return damageTable.write(w, records)
+}
+
+// WriteHealingCSV writes every record with a heal suffix to w as CSV.
+func WriteHealingCSV(w io.Writer, records []*frostparse.CombatLogRecord) error {
+	return healingTable.write(w, records)
+}
+
+// WriteAuraCSV writes every aura application, removal, refresh and dose
+// change to w as CSV.
+func WriteAuraCSV(w io.Writer, records []*frostparse.CombatLogRecord) error {
+	return auraTable.write(w, records)
+}
+
+// wideColumns has a column for every field of Event, in the same order and
+// with the same names as the JSON fields.
+var wideColumns = []struct {
+	name  string
+	value func(Event) string
+}{
+	{"timestamp", func(e Event) string { return e.Timestamp.Format(csvTimeFormat) }},
+	{"event", func(e Event) string { return e.EventType }},
+	{"source_id", func(e Event) string { return e.SourceID }},
+	{"source_name", func(e Event) string { return e.SourceName }},
+	{"source_flags", func(e Event) string { return formatUint(uint64(e.SourceFlags)) }},
+	{"target_id", func(e Event) string { return e.TargetID }},
+	{"target_name", func(e Event) string { return e.TargetName }},
+	{"target_flags", func(e Event) string { return formatUint(uint64(e.TargetFlags)) }},
+	{"spell_id", func(e Event) string { return cell(e.SpellID, formatUint) }},
+	{"spell_name", func(e Event) string { return cell(e.SpellName, identity) }},
+	{"spell_school", func(e Event) string { return cell(e.SpellSchool, strconv.Itoa) }},
+	{"item_id", func(e Event) string { return cell(e.ItemID, formatUint) }},
+	{"item_name", func(e Event) string { return cell(e.ItemName, identity) }},
+	{"environmental_type", func(e Event) string { return cell(e.EnvironmentalType, identity) }},
+	{"amount", func(e Event) string { return cell(e.Amount, formatInt) }},
+	{"overkill", func(e Event) string { return cell(e.Overkill, formatUint) }},
+	{"school", func(e Event) string { return cell(e.School, strconv.Itoa) }},
+	{"resisted", func(e Event) string { return cell(e.Resisted, formatUint) }},
+	{"blocked", func(e Event) string { return cell(e.Blocked, formatUint) }},
+	{"absorbed", func(e Event) string { return cell(e.Absorbed, formatUint) }},
+	{"overhealing", func(e Event) string { return cell(e.Overhealing, formatUint) }},
+	{"critical", func(e Event) string { return cell(e.Critical, strconv.FormatBool) }},
+	{"glancing", func(e Event) string { return cell(e.Glancing, strconv.FormatBool) }},
+	{"crushing", func(e Event) string { return cell(e.Crushing, strconv.FormatBool) }},
+	{"miss_type", func(e Event) string { return cell(e.MissType, identity) }},
+	{"aura_type", func(e Event) string { return cell(e.AuraType, identity) }},
+	{"doses", func(e Event) string { return cell(e.Doses, formatUint) }},
+	{"power_type", func(e Event) string { return cell(e.PowerType, strconv.Itoa) }},
+	{"extra_amount", func(e Event) string { return cell(e.ExtraAmount, formatUint) }},
+	{"extra_spell_id", func(e Event) string { return cell(e.ExtraSpellID, formatUint) }},
+	{"extra_spell_name", func(e Event) string { return cell(e.ExtraSpellName, identity) }},
+	{"extra_spell_school", func(e Event) string { return cell(e.ExtraSpellSchool, strconv.Itoa) }},
+}
+
+func cell[T any](v *T, format func(T) string) string {
+	if v == nil {
+		return ""
+	}
+	return format(*v)
+}
+
+func identity(s string) string {
+	return s
+}
+
+func formatInt(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
+
+// WriteWideCSV writes every record to w as a single denormalized CSV with a
+// column for every field of Event. Cells for fields the event does not have
+// are left empty.
+func WriteWideCSV(w io.Writer, records []*frostparse.CombatLogRecord) error {
+	cw := csv.NewWriter(w)
+	row := make([]string, len(wideColumns))
+	for i, c := range wideColumns {
+		row[i] = c.name
+	}
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	for _, rec := range records {
+		e := NewEvent(rec)
+		for i, c := range wideColumns {
+			row[i] = c.value(e)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// CSVExporterFunc is a function that accepts a pointer to a CSVExporter to be
+// used in the options variadic function in `NewCSVExporter`.
+type CSVExporterFunc func(*CSVExporter)
+
+// CSVExporter writes records into a directory of CSV files.
+type CSVExporter struct {
+	// Wide writes a single events.csv with every field instead of one file
+	// per event kind.
+	Wide bool
+}
+
+// WithWideCSV writes a single denormalized events.csv.
+func WithWideCSV(wide bool) CSVExporterFunc {
+	return func(c *CSVExporter) {
+		c.Wide = wide
+	}
+}
+
+// NewCSVExporter initializes and allocates a CSVExporter and applies any
+// CSVExporterFunc options.
+func NewCSVExporter(opts ...CSVExporterFunc) *CSVExporter {
+	c := &CSVExporter{}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// WriteDir writes damage.csv, healing.csv and auras.csv into dir, or a
+// single events.csv when Wide is set. The directory is created if needed and
+// existing files are overwritten.
+func (c *CSVExporter) WriteDir(dir string, records []*frostparse.CombatLogRecord) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if c.Wide {
+		return writeFile(filepath.Join(dir, "events.csv"), func(w io.Writer) error {
+			return WriteWideCSV(w, records)
+		})
+	}
+	for _, t := range []csvTable{damageTable, healingTable, auraTable} {
+		if err := writeFile(filepath.Join(dir, t.file), func(w io.Writer) error {
+			return t.write(w, records)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
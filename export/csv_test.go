@@ -0,0 +1,132 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+func TestCSVExporterWriteDir(t *testing.T) {
+	data := parseLines(t,
+		`12/11 00:13:06.105  SWING_DAMAGE,0xF1300094280000B2,"Argent Champion",0xa18,0xF130009093000102,"The Damned",0xa48,40828,0,1,0,0,0,1,nil,nil`,
+		`12/11 00:13:38.531  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x070000000062ADF1,"Phokkwho",0x514,61301,"Riptide",0x8,3000,0,100,1`,
+		`12/11 00:13:38.532  SPELL_AURA_APPLIED,0x07000000007721EC,"Yogzar",0x511,0x070000000062ADF1,"Phokkwho",0x514,61301,"Riptide",0x8,BUFF`,
+	)
+	dir := t.TempDir()
+	if err := NewCSVExporter().WriteDir(dir, data); err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string][2]string{
+		"damage.csv":  {"amount", "40828"},
+		"healing.csv": {"amount", "3000"},
+		"auras.csv":   {"aura_type", "BUFF"},
+	} {
+		rows := readCSV(t, filepath.Join(dir, file))
+		if len(rows) != 2 {
+			t.Fatalf("expected a header and 1 row in %s, got %v", file, rows)
+		}
+		if got := rows[1][column(rows[0], want[0])]; got != want[1] {
+			t.Errorf("expected %s %s in %s, got %q", want[0], want[1], file, got)
+		}
+	}
+	if rows := readCSV(t, filepath.Join(dir, "damage.csv")); rows[1][column(rows[0], "spell_id")] != "" {
+		t.Errorf("expected swings to have an empty spell_id, got %v", rows[1])
+	}
+}
+
+func TestWriteWideCSV(t *testing.T) {
+	data := parseLines(t,
+		`12/11 00:13:06.105  SWING_DAMAGE,0xF1300094280000B2,"Argent Champion",0xa18,0xF130009093000102,"The Damned",0xa48,40828,0,1,0,0,0,1,nil,nil`,
+		`12/11 00:13:38.531  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x070000000062ADF1,"Phokkwho",0x514,61301,"Riptide",0x8,3000,0,100,1`,
+	)
+	var buf bytes.Buffer
+	if err := WriteWideCSV(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %d", len(rows))
+	}
+	col := func(name string) int { return column(rows[0], name) }
+	if rows[1][col("amount")] != "40828" || rows[1][col("overhealing")] != "" {
+		t.Errorf("unexpected swing row: %v", rows[1])
+	}
+	if rows[2][col("spell_name")] != "Riptide" || rows[2][col("overhealing")] != "0" || rows[2][col("overkill")] != "" {
+		t.Errorf("unexpected heal row: %v", rows[2])
+	}
+}
+
+func TestWriteHeatmapCSV(t *testing.T) {
+	h := &frostparse.Heatmap{
+		Players:   []string{"Winterinjuly", "Yogzar"},
+		Mechanics: []string{"Coldflame", "Bone Slice"},
+		Cells: [][]frostparse.HeatmapCell{
+			{{Hits: 2, Damage: 8000}, {}},
+			{{}, {Hits: 1, Damage: 9000}},
+		},
+	}
+	var buf bytes.Buffer
+	if err := WriteHeatmapCSV(&buf, h); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 5 {
+		t.Fatalf("expected a header and 4 cells, got %d rows", len(rows))
+	}
+	if got := strings.Join(rows[1], ","); got != "Winterinjuly,Coldflame,2,8000" {
+		t.Errorf("unexpected first cell: %s", got)
+	}
+	if got := strings.Join(rows[4], ","); got != "Yogzar,Bone Slice,1,9000" {
+		t.Errorf("unexpected last cell: %s", got)
+	}
+}
+
+func readCSV(t *testing.T, path string) [][]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func column(header []string, name string) int {
+	for i, h := range header {
+		if h == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// dotColors are the edge colors of each kind of interaction.
+var dotColors = map[frostparse.InteractionKind]string{
+	frostparse.InteractionDamage:  "firebrick",
+	frostparse.InteractionHealing: "forestgreen",
+}
+
+// WriteDOT writes the interaction graph to w in the Graphviz DOT language.
+// Edges are colored by kind and carry their amount as the weight, so layout
+// engines pull units that interact heavily closer together.
+func WriteDOT(w io.Writer, g frostparse.InteractionGraph) error {
+	bw := bufio.NewWriter(w)
+	name := g.Encounter
+	if g.Attempt > 0 {
+		name = fmt.Sprintf("%s #%d", g.Encounter, g.Attempt)
+	}
+	fmt.Fprintf(bw, "digraph %s {\n", strconv.Quote(name))
+	for _, n := range g.Nodes {
+		fmt.Fprintf(bw, "\t%s [kind=%s];\n", strconv.Quote(n.Name), strconv.Quote(n.Kind))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "\t%s -> %s [kind=%s, weight=%d, hits=%d, color=%s];\n",
+			strconv.Quote(e.Source), strconv.Quote(e.Target), strconv.Quote(string(e.Kind)),
+			e.Amount, e.Hits, dotColors[e.Kind])
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Data        []graphMLData `xml:"data"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// graphMLKeys declare the attributes written by WriteGraphML. Gephi reads
+// the edge weight as the weight of the edge.
+var graphMLKeys = []graphMLKey{
+	{ID: "encounter", For: "graph", Name: "encounter", Type: "string"},
+	{ID: "attempt", For: "graph", Name: "attempt", Type: "int"},
+	{ID: "kind", For: "node", Name: "kind", Type: "string"},
+	{ID: "interaction", For: "edge", Name: "kind", Type: "string"},
+	{ID: "weight", For: "edge", Name: "weight", Type: "double"},
+	{ID: "hits", For: "edge", Name: "hits", Type: "int"},
+}
+
+// WriteGraphML writes the interaction graph to w as GraphML, with units as
+// nodes identified by name and the amount of each edge as its weight.
+func WriteGraphML(w io.Writer, g frostparse.InteractionGraph) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys:  graphMLKeys,
+		Graph: graphMLGraph{
+			ID:          "G",
+			EdgeDefault: "directed",
+			Data: []graphMLData{
+				{Key: "encounter", Value: g.Encounter},
+				{Key: "attempt", Value: strconv.Itoa(g.Attempt)},
+			},
+			Nodes: make([]graphMLNode, len(g.Nodes)),
+			Edges: make([]graphMLEdge, len(g.Edges)),
+		},
+	}
+	for i, n := range g.Nodes {
+		doc.Graph.Nodes[i] = graphMLNode{
+			ID:   n.Name,
+			Data: []graphMLData{{Key: "kind", Value: n.Kind}},
+		}
+	}
+	for i, e := range g.Edges {
+		doc.Graph.Edges[i] = graphMLEdge{
+			Source: e.Source,
+			Target: e.Target,
+			Data: []graphMLData{
+				{Key: "interaction", Value: string(e.Kind)},
+				{Key: "weight", Value: formatUint(e.Amount)},
+				{Key: "hits", Value: strconv.Itoa(e.Hits)},
+			},
+		}
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+var testGraph = frostparse.InteractionGraph{
+	Encounter: "Lord Marrowgar",
+	Attempt:   2,
+	Nodes: []frostparse.GraphNode{
+		{Name: "Lord Marrowgar", Kind: "boss"},
+		{Name: `Win"ter`, Kind: "player"},
+	},
+	Edges: []frostparse.GraphEdge{
+		{Source: "Lord Marrowgar", Target: `Win"ter`, Kind: frostparse.InteractionDamage, Amount: 4000, Hits: 3},
+	},
+}
+
+func TestWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteDOT(&buf, testGraph); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph "Lord Marrowgar #2" {
+	"Lord Marrowgar" [kind="boss"];
+	"Win\"ter" [kind="player"];
+	"Lord Marrowgar" -> "Win\"ter" [kind="damage", weight=4000, hits=3, color=firebrick];
+}
+`
+	if buf.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, buf.String())
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGraphML(&buf, testGraph); err != nil {
+		t.Fatal(err)
+	}
+	var doc graphML
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Graph.Nodes) != 2 || doc.Graph.Nodes[1].ID != `Win"ter` {
+		t.Errorf("unexpected nodes %+v", doc.Graph.Nodes)
+	}
+	if len(doc.Graph.Edges) != 1 || doc.Graph.Edges[0].Data[1] != (graphMLData{Key: "weight", Value: "4000"}) {
+		t.Errorf("unexpected edges %+v", doc.Graph.Edges)
+	}
+	if !strings.Contains(buf.String(), `<key id="weight" for="edge" attr.name="weight" attr.type="double"></key>`) {
+		t.Errorf("expected the weight key to be declared, got\n%s", buf.String())
+	}
+}
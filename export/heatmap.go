@@ -0,0 +1,46 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// WriteHeatmapCSV writes the heatmap to w as CSV with one player, mechanic,
+// hits, damage row per cell, the long format plotting tools build heatmaps
+// from. Cells the player was never hit in are written with zeros so every
+// player has a row for every mechanic.
+func WriteHeatmapCSV(w io.Writer, h *frostparse.Heatmap) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"player", "mechanic", "hits", "damage"}); err != nil {
+		return err
+	}
+	for i, player := range h.Players {
+		for j, mechanic := range h.Mechanics {
+			c := h.Cells[i][j]
+			if err := cw.Write([]string{player, mechanic, strconv.Itoa(c.Hits), formatUint(c.Damage)}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package export writes parsed combat log records in formats other tools can
+// load.
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// Event is the flattened form of a frostparse.CombatLogRecord. Fields that
+// come from a prefix or suffix the record does not have are nil and omitted
+// from JSON, while fields that are present keep their zero values, e.g. a
+// fully absorbed hit still has an amount of 0.
+type Event struct {
+	Timestamp   time.Time `json:"timestamp"`
+	EventType   string    `json:"event"`
+	SourceID    string    `json:"source_id"`
+	SourceName  string    `json:"source_name"`
+	SourceFlags uint32    `json:"source_flags"`
+	TargetID    string    `json:"target_id"`
+	TargetName  string    `json:"target_name"`
+	TargetFlags uint32    `json:"target_flags"`
+
+	SpellID           *uint64 `json:"spell_id,omitempty"`
+	SpellName         *string `json:"spell_name,omitempty"`
+	SpellSchool       *int    `json:"spell_school,omitempty"`
+	ItemID            *uint64 `json:"item_id,omitempty"`
+	ItemName          *string `json:"item_name,omitempty"`
+	EnvironmentalType *string `json:"environmental_type,omitempty"`
+
+	Amount           *int64  `json:"amount,omitempty"`
+	Overkill         *uint64 `json:"overkill,omitempty"`
+	School           *int    `json:"school,omitempty"`
+	Resisted         *uint64 `json:"resisted,omitempty"`
+	Blocked          *uint64 `json:"blocked,omitempty"`
+	Absorbed         *uint64 `json:"absorbed,omitempty"`
+	Overhealing      *uint64 `json:"overhealing,omitempty"`
+	Critical         *bool   `json:"critical,omitempty"`
+	Glancing         *bool   `json:"glancing,omitempty"`
+	Crushing         *bool   `json:"crushing,omitempty"`
+	MissType         *string `json:"miss_type,omitempty"`
+	AuraType         *string `json:"aura_type,omitempty"`
+	Doses            *uint64 `json:"doses,omitempty"`
+	PowerType        *int    `json:"power_type,omitempty"`
+	ExtraAmount      *uint64 `json:"extra_amount,omitempty"`
+	ExtraSpellID     *uint64 `json:"extra_spell_id,omitempty"`
+	ExtraSpellName   *string `json:"extra_spell_name,omitempty"`
+	ExtraSpellSchool *int    `json:"extra_spell_school,omitempty"`
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// NewEvent flattens a record into an Event.
+func NewEvent(rec *frostparse.CombatLogRecord) Event {
+	e := Event{
+		Timestamp:   rec.Timestamp,
+		EventType:   string(rec.EventType),
+		SourceID:    rec.SourceID,
+		SourceName:  rec.SourceName,
+		SourceFlags: uint32(rec.SourceFlags),
+		TargetID:    rec.TargetID,
+		TargetName:  rec.TargetName,
+		TargetFlags: uint32(rec.TargetFlags),
+	}
+	if p := rec.SpellAndRangePrefix; p != nil {
+		e.SpellID = ptr(p.SpellID)
+		e.SpellName = ptr(p.SpellName)
+		e.SpellSchool = ptr(int(p.SpellSchool))
+	}
+	if p := rec.EnchantPrefix; p != nil {
+		e.SpellName = ptr(p.SpellName)
+		e.ItemID = ptr(p.ItemID)
+		e.ItemName = ptr(p.ItemName)
+	}
+	if p := rec.EnvironmentalPrefix; p != nil {
+		e.EnvironmentalType = ptr(string(p.EnvironmentalType))
+	}
+	if s := rec.DamageSuffix; s != nil {
+		e.Amount = ptr(int64(s.Amount))
+		e.Overkill = ptr(s.Overkill)
+		e.School = ptr(int(s.SpellSchool))
+		e.Resisted = ptr(s.Resisted)
+		e.Blocked = ptr(s.Blocked)
+		e.Absorbed = ptr(s.Absorbed)
+		e.Critical = ptr(s.Critical)
+		e.Glancing = ptr(s.Glancing)
+		e.Crushing = ptr(s.Crushing)
+	}
+	if s := rec.HealSuffix; s != nil {
+		e.Amount = ptr(int64(s.Amount))
+		e.Overhealing = ptr(s.Overhealing)
+		e.Absorbed = ptr(s.Absorbed)
+		e.Critical = ptr(s.Critical)
+	}
+	if s := rec.EnergizeSuffix; s != nil {
+		e.Amount = ptr(s.Amount)
+		e.PowerType = ptr(int(s.PowerType))
+	}
+	if s := rec.ExtraAttacksSuffix; s != nil {
+		e.Amount = ptr(int64(s.Amount))
+	}
+	if s := rec.LeechOrDrainSuffix; s != nil {
+		e.Amount = ptr(int64(s.Amount))
+		e.PowerType = ptr(int(s.PowerType))
+		e.ExtraAmount = ptr(s.ExtraAmount)
+	}
+	if s := rec.MissSuffix; s != nil {
+		e.MissType = ptr(string(s.MissType))
+		if s.Amount > 0 {
+			e.Amount = ptr(int64(s.Amount))
+		}
+	}
+	if s := rec.AuraSuffix; s != nil {
+		e.AuraType = ptr(string(s.AuraType))
+		if s.Doses > 0 {
+			e.Doses = ptr(s.Doses)
+		}
+	}
+	if s := rec.InterruptSuffix; s != nil {
+		e.ExtraSpellID = ptr(s.ExtraSpellID)
+		e.ExtraSpellName = ptr(s.ExtraSpellName)
+		e.ExtraSpellSchool = ptr(int(s.ExtraSpellSchool))
+	}
+	if s := rec.DispelOrStolenSuffix; s != nil {
+		e.ExtraSpellID = ptr(s.ExtraSpellID)
+		e.ExtraSpellName = ptr(s.ExtraSpellName)
+		e.ExtraSpellSchool = ptr(int(s.ExtraSpellSchool))
+		if s.AuraType != "" {
+			e.AuraType = ptr(string(s.AuraType))
+		}
+	}
+	return e
+}
+
+// JSONLWriter writes records as JSON Lines, one flattened Event per line.
+type JSONLWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewJSONLWriter returns a JSONLWriter writing to w. Call Flush once done.
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	bw := bufio.NewWriter(w)
+	return &JSONLWriter{
+		w:   bw,
+		enc: json.NewEncoder(bw),
+	}
+}
+
+// Write writes a single record.
+func (j *JSONLWriter) Write(rec *frostparse.CombatLogRecord) error {
+	return j.enc.Encode(NewEvent(rec))
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (j *JSONLWriter) Flush() error {
+	return j.w.Flush()
+}
+
+// WriteJSONL writes every record to w as JSON Lines.
+func WriteJSONL(w io.Writer, records []*frostparse.CombatLogRecord) error {
+	j := NewJSONLWriter(w)
+	for _, rec := range records {
+		if err := j.Write(rec); err != nil {
+			return err
+		}
+	}
+	return j.Flush()
+}
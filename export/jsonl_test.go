@@ -0,0 +1,73 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+func parseLines(t *testing.T, lines ...string) []*frostparse.CombatLogRecord {
+	t.Helper()
+	data, err := frostparse.New(
+		frostparse.WithReader(strings.NewReader(strings.Join(lines, "\n"))),
+		frostparse.WithStrictMode(true),
+	).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestWriteJSONL(t *testing.T) {
+	data := parseLines(t,
+		`12/11 00:13:06.105  SWING_DAMAGE,0xF1300094280000B2,"Argent Champion",0xa18,0xF130009093000102,"The Damned",0xa48,0,0,1,0,0,4000,1,nil,nil`,
+		`12/11 00:13:38.531  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x070000000062ADF1,"Phokkwho",0x514,61301,"Riptide",0x8,3000,0,100,1`,
+	)
+	var buf bytes.Buffer
+	if err := WriteJSONL(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	var swing map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &swing); err != nil {
+		t.Fatal(err)
+	}
+	if swing["event"] != "SWING_DAMAGE" || swing["amount"] != float64(0) || swing["absorbed"] != float64(4000) {
+		t.Errorf("expected a fully absorbed swing to keep its zero amount, got %v", swing)
+	}
+	if _, ok := swing["spell_id"]; ok {
+		t.Error("expected swings to have no spell fields")
+	}
+	var heal map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &heal); err != nil {
+		t.Fatal(err)
+	}
+	if heal["spell_name"] != "Riptide" || heal["amount"] != float64(3000) || heal["critical"] != true {
+		t.Errorf("unexpected heal: %v", heal)
+	}
+	if _, ok := heal["miss_type"]; ok {
+		t.Error("expected nil suffixes to be omitted")
+	}
+}
@@ -0,0 +1,295 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedStamp is the layout of the start time in the names of rotated
+// files. It sorts chronologically and is a valid file name on every platform.
+const rotatedStamp = "2006-01-02T150405"
+
+// RotatingFileFunc is a function that accepts a pointer to a RotatingFile to
+// be used in the options variadic function in `NewRotatingFile`.
+type RotatingFileFunc func(*RotatingFile)
+
+// RotatingFile is an io.WriteCloser for long-running sinks that keeps disk
+// usage bounded. Once the file grows past MaxSize, or a write happens on a
+// later day than the file was started, it is renamed next to Path with its
+// start time, e.g. events-2010-12-11T201500.jsonl, optionally gzip
+// compressed, and a new file is started. Rotation only happens between
+// writes, so writers that write whole lines, such as JSONLSink and CSVSink,
+// never have a line split across files. It is safe for concurrent use.
+type RotatingFile struct {
+	// Path is the file being written.
+	Path string
+	// MaxSize is the size in bytes a file is rotated at. Zero disables size
+	// based rotation.
+	MaxSize int64
+	// Daily rotates the file on the first write of each day.
+	Daily bool
+	// Compress gzips rotated files.
+	Compress bool
+	// MaxBackups is the number of rotated files kept, oldest first removed.
+	// Zero keeps every rotated file.
+	MaxBackups int
+
+	mu      sync.Mutex
+	f       *os.File
+	size    int64
+	started time.Time
+	now     func() time.Time
+}
+
+// WithMaxSize sets the size in bytes a file is rotated at.
+func WithMaxSize(n int64) RotatingFileFunc {
+	return func(r *RotatingFile) {
+		r.MaxSize = n
+	}
+}
+
+// WithDailyRotation sets whether the file is rotated once a day.
+func WithDailyRotation(daily bool) RotatingFileFunc {
+	return func(r *RotatingFile) {
+		r.Daily = daily
+	}
+}
+
+// WithCompression sets whether rotated files are gzip compressed.
+func WithCompression(compress bool) RotatingFileFunc {
+	return func(r *RotatingFile) {
+		r.Compress = compress
+	}
+}
+
+// WithMaxBackups sets the number of rotated files kept.
+func WithMaxBackups(n int) RotatingFileFunc {
+	return func(r *RotatingFile) {
+		r.MaxBackups = n
+	}
+}
+
+// NewRotatingFile opens path for appending, creating it if needed, and
+// returns a RotatingFile writing to it. By default files are rotated daily
+// and at 100 MiB, compressed, and the last 10 rotated files are kept.
+func NewRotatingFile(path string, opts ...RotatingFileFunc) (*RotatingFile, error) {
+	r := &RotatingFile{
+		Path:       path,
+		MaxSize:    100 << 20,
+		Daily:      true,
+		Compress:   true,
+		MaxBackups: 10,
+		now:        time.Now,
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens Path for appending. An existing file keeps its modification
+// time as its start, so a file left over from yesterday is rotated on the
+// first write.
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f, r.size, r.started = f, info.Size(), r.now()
+	if r.size > 0 {
+		r.started = info.ModTime()
+	}
+	return nil
+}
+
+// Write writes p to the current file, rotating it first when it is due.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.rotateIfDue(); err != nil {
+		return 0, err
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file now, unless it is empty.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size == 0 {
+		return nil
+	}
+	return r.rotate()
+}
+
+// Close closes the current file.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+// fresh rotates the file when it is due and reports whether the next write
+// starts a new file, so writers can repeat a header in every file.
+func (r *RotatingFile) fresh() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.rotateIfDue(); err != nil {
+		return false, err
+	}
+	return r.size == 0, nil
+}
+
+func (r *RotatingFile) rotateIfDue() error {
+	if r.size == 0 {
+		return nil
+	}
+	due := r.MaxSize > 0 && r.size >= r.MaxSize
+	if r.Daily {
+		y1, m1, d1 := r.started.Date()
+		y2, m2, d2 := r.now().Date()
+		due = due || y1 != y2 || m1 != m2 || d1 != d2
+	}
+	if !due {
+		return nil
+	}
+	return r.rotate()
+}
+
+// rotate renames the current file, compresses it and removes the oldest
+// rotated files before starting a new file.
+func (r *RotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	name := r.backupName()
+	if err := os.Rename(r.Path, name); err != nil {
+		return err
+	}
+	if r.Compress {
+		if err := compressFile(name); err != nil {
+			return err
+		}
+	}
+	if err := r.prune(); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+// backupName returns an unused name for the current file, stamped with the
+// time it was started.
+func (r *RotatingFile) backupName() string {
+	ext := filepath.Ext(r.Path)
+	base := strings.TrimSuffix(r.Path, ext) + "-" + r.started.Format(rotatedStamp)
+	name := base + ext
+	for i := 1; exists(name) || exists(name+".gz"); i++ {
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	return name
+}
+
+// prune removes the oldest rotated files beyond MaxBackups.
+func (r *RotatingFile) prune() error {
+	if r.MaxBackups <= 0 {
+		return nil
+	}
+	ext := filepath.Ext(r.Path)
+	prefix := strings.TrimSuffix(r.Path, ext) + "-"
+	matches, err := filepath.Glob(prefix + "*" + ext + "*")
+	if err != nil {
+		return err
+	}
+	type backup struct {
+		path    string
+		started time.Time
+		seq     int
+	}
+	var backups []backup
+	for _, m := range matches {
+		rest := strings.TrimPrefix(m, prefix)
+		if len(rest) < len(rotatedStamp) {
+			continue
+		}
+		started, err := time.Parse(rotatedStamp, rest[:len(rotatedStamp)])
+		if err != nil {
+			continue
+		}
+		// files started in the same second are numbered -1, -2, ...
+		seq, _ := strconv.Atoi(strings.TrimPrefix(strings.SplitN(rest[len(rotatedStamp):], ".", 2)[0], "-"))
+		backups = append(backups, backup{m, started, seq})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].started.Equal(backups[j].started) {
+			return backups[i].started.Before(backups[j].started)
+		}
+		return backups[i].seq < backups[j].seq
+	})
+	for len(backups) > r.MaxBackups {
+		if err := os.Remove(backups[0].path); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// compressFile replaces path with a gzip compressed path.gz.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err := writeFile(path+".gz", func(w io.Writer) error {
+		zw := gzip.NewWriter(w)
+		if _, err := io.Copy(zw, src); err != nil {
+			return err
+		}
+		return zw.Close()
+	}); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readRotated returns the contents of every file in dir, decompressing
+// rotated files, keyed by file name.
+func readRotated(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]string{}
+	for _, e := range entries {
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasSuffix(e.Name(), ".gz") {
+			zr, err := gzip.NewReader(bytes.NewReader(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b, err = io.ReadAll(zr); err != nil {
+				t.Fatal(err)
+			}
+		}
+		out[e.Name()] = string(b)
+	}
+	return out
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewRotatingFile(filepath.Join(dir, "events.jsonl"), WithMaxSize(10), WithMaxBackups(2), WithDailyRotation(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"first line\n", "second line\n", "third line\n", "fourth line\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files := readRotated(t, dir)
+	if len(files) != 3 || files["events.jsonl"] != "fourth line\n" {
+		t.Fatalf("expected the current file and two backups, got %v", files)
+	}
+	var backups []string
+	for name, content := range files {
+		if name != "events.jsonl" {
+			if !strings.HasPrefix(name, "events-") || !strings.HasSuffix(name, ".jsonl.gz") {
+				t.Errorf("unexpected backup name %s", name)
+			}
+			backups = append(backups, content)
+		}
+	}
+	sort.Strings(backups)
+	if strings.Join(backups, "") != "second line\nthird line\n" {
+		t.Errorf("expected the oldest backup to be pruned, got %q", backups)
+	}
+}
+
+func TestRotatingFileDaily(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2010, 12, 11, 23, 59, 0, 0, time.Local)
+	f, err := NewRotatingFile(filepath.Join(dir, "events.jsonl"), WithCompression(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.now = func() time.Time { return now }
+	f.started = now
+	f.Write([]byte("before midnight\n"))
+	now = now.Add(2 * time.Minute)
+	f.Write([]byte("after midnight\n"))
+	f.Close()
+
+	files := readRotated(t, dir)
+	if files["events-2010-12-11T235900.jsonl"] != "before midnight\n" || files["events.jsonl"] != "after midnight\n" {
+		t.Errorf("expected a rotation at midnight, got %v", files)
+	}
+}
+
+func TestCSVSinkRotatingHeader(t *testing.T) {
+	data := parseLines(t,
+		`12/11 01:08:14.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:15.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,200,0,1,0,0,0,nil,nil,nil`,
+	)
+	dir := t.TempDir()
+	f, err := NewRotatingFile(filepath.Join(dir, "events.csv"), WithMaxSize(1), WithDailyRotation(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewCSVSink(f).Write(context.Background(), data); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	files := readRotated(t, dir)
+	if len(files) != 2 {
+		t.Fatalf("expected one rotation, got %v", files)
+	}
+	for name, content := range files {
+		rows, err := csv.NewReader(strings.NewReader(content)).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 || rows[0][0] != wideColumns[0].name {
+			t.Errorf("%s: expected a header and one row, got %v", name, rows)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// JSONLSink appends records to w as JSON Lines. Every line is written with a
+// single Write call, so a RotatingFile never splits one across files. It
+// satisfies service.Sink and is safe for concurrent use.
+type JSONLSink struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+// NewJSONLSink returns a JSONLSink writing to w.
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	return &JSONLSink{w: w}
+}
+
+// Write appends the records to w.
+func (s *JSONLSink) Write(ctx context.Context, records []*frostparse.CombatLogRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	enc := json.NewEncoder(&s.buf)
+	for _, rec := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.buf.Reset()
+		if err := enc.Encode(NewEvent(rec)); err != nil {
+			return err
+		}
+		if _, err := s.w.Write(s.buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CSVSink appends records to w as rows of the wide CSV written by
+// WriteWideCSV. The header is written before the first row, and again at the
+// top of every file when w is a RotatingFile. It satisfies service.Sink and
+// is safe for concurrent use.
+type CSVSink struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf bytes.Buffer
+	cw  *csv.Writer
+	row []string
+	// wroteHeader is set once a header has been written to a writer that is
+	// not a RotatingFile.
+	wroteHeader bool
+}
+
+// NewCSVSink returns a CSVSink writing to w.
+func NewCSVSink(w io.Writer) *CSVSink {
+	s := &CSVSink{
+		w:   w,
+		row: make([]string, len(wideColumns)),
+	}
+	s.cw = csv.NewWriter(&s.buf)
+	return s
+}
+
+// Write appends the records to w.
+func (s *CSVSink) Write(ctx context.Context, records []*frostparse.CombatLogRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rec := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.buf.Reset()
+		header, err := s.needsHeader()
+		if err != nil {
+			return err
+		}
+		if header {
+			for i, c := range wideColumns {
+				s.row[i] = c.name
+			}
+			s.cw.Write(s.row)
+		}
+		e := NewEvent(rec)
+		for i, c := range wideColumns {
+			s.row[i] = c.value(e)
+		}
+		s.cw.Write(s.row)
+		s.cw.Flush()
+		if err := s.cw.Error(); err != nil {
+			return err
+		}
+		if _, err := s.w.Write(s.buf.Bytes()); err != nil {
+			return err
+		}
+		s.wroteHeader = true
+	}
+	return nil
+}
+
+// needsHeader reports whether the next row starts a file.
+func (s *CSVSink) needsHeader() (bool, error) {
+	if f, ok := s.w.(*RotatingFile); ok {
+		return f.fresh()
+	}
+	return !s.wroteHeader, nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "time"
+
+// extraAttackWindow is how long granted extra attacks wait for the swings
+// that consume them.
+const extraAttackWindow = time.Second
+
+// pendingExtraAttacks are swings granted by a SPELL_EXTRA_ATTACKS event that
+// have not been seen yet.
+type pendingExtraAttacks struct {
+	spell     string
+	remaining uint64
+	at        time.Time
+}
+
+// extraAttackTracker attributes the swings granted by effects such as
+// Windfury, Sword Specialization and Hack and Slash to the effect that
+// granted them. The log writes SPELL_EXTRA_ATTACKS before the swing that
+// procced it, at the same timestamp, so swings at that timestamp are left
+// alone and the next swings of the source consume the extra attacks.
+type extraAttackTracker struct {
+	pending map[string]*pendingExtraAttacks
+}
+
+func newExtraAttackTracker() *extraAttackTracker {
+	return &extraAttackTracker{
+		pending: map[string]*pendingExtraAttacks{},
+	}
+}
+
+// clone returns a copy of the tracker that does not share pending swings.
+func (t *extraAttackTracker) clone() *extraAttackTracker {
+	out := newExtraAttackTracker()
+	for source, p := range t.pending {
+		v := *p
+		out.pending[source] = &v
+	}
+	return out
+}
+
+// observe returns the name of the effect that granted a swing, or false for
+// swings that were not extra attacks.
+func (t *extraAttackTracker) observe(row CombatLogRecord) (string, bool) {
+	switch row.EventType {
+	case SpellExtraAttacks:
+		if row.ExtraAttacksSuffix == nil || row.SpellAndRangePrefix == nil {
+			return "", false
+		}
+		t.pending[row.SourceID] = &pendingExtraAttacks{
+			spell:     row.SpellAndRangePrefix.SpellName,
+			remaining: row.ExtraAttacksSuffix.Amount,
+			at:        row.Timestamp,
+		}
+	case SwingDamage, SwingMissed:
+		p, ok := t.pending[row.SourceID]
+		if !ok || row.Timestamp.Equal(p.at) {
+			return "", false
+		}
+		if row.Timestamp.Sub(p.at) > extraAttackWindow {
+			delete(t.pending, row.SourceID)
+			return "", false
+		}
+		p.remaining--
+		if p.remaining == 0 {
+			delete(t.pending, row.SourceID)
+		}
+		return p.spell, true
+	}
+	return "", false
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// SummaryMetric names a per-key total of SummaryStats, using the same name
+// as its JSON field.
+type SummaryMetric string
+
+const (
+	MetricDamageBySource        SummaryMetric = "damage_by_source"
+	MetricHealingBySource       SummaryMetric = "healing_by_source"
+	MetricDamageTakenBySource   SummaryMetric = "damage_taken_by_source"
+	MetricDamageTakenBySpell    SummaryMetric = "damage_taken_by_spell"
+	MetricInterruptsBySource    SummaryMetric = "interrupts_by_source"
+	MetricDispelsBySource       SummaryMetric = "dispells_by_source"
+	MetricFailedDispelsBySource SummaryMetric = "failed_dispels_by_source"
+
+	// Deprecated: MetricDispellsBySource is misspelled, use
+	// MetricDispelsBySource.
+	MetricDispellsBySource = MetricDispelsBySource
+)
+
+// summaryMetrics returns the totals of s that deltas are computed for.
+func summaryMetrics(s *SummaryStats) map[SummaryMetric]map[string]uint64 {
+	return map[SummaryMetric]map[string]uint64{
+		MetricDamageBySource:        s.DamageBySource,
+		MetricHealingBySource:       s.HealingBySource,
+		MetricDamageTakenBySource:   s.DamageTakenBySource,
+		MetricDamageTakenBySpell:    s.DamageTakenBySpell,
+		MetricInterruptsBySource:    s.InterruptsBySource,
+		MetricDispelsBySource:       s.DispelsBySource,
+		MetricFailedDispelsBySource: s.FailedDispelsBySource,
+	}
+}
+
+// SummaryDelta is the change of one total over one interval, e.g. damage by
+// source for Winterinjuly went up by 12345.
+type SummaryDelta struct {
+	Time   time.Time     `json:"time"`
+	Metric SummaryMetric `json:"metric"`
+	Key    string        `json:"key"`
+	Change uint64        `json:"change"`
+}
+
+// SummaryFeed aggregates a record stream like Collector.Run, but instead of
+// a snapshot it reports what changed in each interval of log time, so live
+// clients only receive the totals that moved.
+type SummaryFeed struct {
+	interval   time.Duration
+	resolution time.Duration
+	stats      *SummaryStats
+	previous   map[SummaryMetric]map[string]uint64
+	start      time.Time
+	started    bool
+}
+
+// Feed returns a SummaryFeed with the Collector's options that reports the
+// changes over every interval of log time.
+func (c *Collector) Feed(interval time.Duration) *SummaryFeed {
+	return &SummaryFeed{
+		interval:   interval,
+		resolution: c.TimeResolution,
+		stats:      c.newStats(),
+		previous:   map[SummaryMetric]map[string]uint64{},
+	}
+}
+
+// Observe adds a record to the feed. Records must be observed in log order;
+// when a record falls past the end of the current interval, the changes
+// over the completed interval are returned.
+func (f *SummaryFeed) Observe(row CombatLogRecord) ([]SummaryDelta, bool) {
+	var done []SummaryDelta
+	var ok bool
+	start := row.Timestamp.Truncate(f.interval)
+	if f.started && !start.Equal(f.start) {
+		done, ok = f.Flush()
+	}
+	f.start, f.started = start, true
+	f.stats.handleEvent(row, f.resolution)
+	return done, ok
+}
+
+// Flush returns the changes since the last flush, ordered by metric and key,
+// and false when nothing changed.
+func (f *SummaryFeed) Flush() ([]SummaryDelta, bool) {
+	var out []SummaryDelta
+	for metric, totals := range summaryMetrics(f.stats) {
+		prev := f.previous[metric]
+		if prev == nil {
+			prev = map[string]uint64{}
+			f.previous[metric] = prev
+		}
+		for key, total := range totals {
+			if total != prev[key] {
+				out = append(out, SummaryDelta{Time: f.start, Metric: metric, Key: key, Change: total - prev[key]})
+				prev[key] = total
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Metric != out[j].Metric {
+			return out[i].Metric < out[j].Metric
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out, len(out) > 0
+}
+
+// Stats returns the totals accumulated so far. The encounter and activity
+// fields that Collector.Run fills in after the fact are left empty.
+func (f *SummaryFeed) Stats() *SummaryStats {
+	return f.stats
+}
+
+// Run consumes records from in, for example the channel returned by
+// Parser.Stream, and sends the changes over every completed interval. The
+// returned channel is closed once in is closed, after the changes of the
+// final interval have been sent, or when ctx is cancelled.
+func (f *SummaryFeed) Run(ctx context.Context, in <-chan CombatLogRecord) <-chan []SummaryDelta {
+	out := make(chan []SummaryDelta)
+	go func() {
+		defer close(out)
+		send := func(d []SummaryDelta) bool {
+			select {
+			case out <- d:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case row, ok := <-in:
+				if !ok {
+					if d, ok := f.Flush(); ok {
+						send(d)
+					}
+					return
+				}
+				if d, ok := f.Observe(row); ok && !send(d) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSummaryFeedRun(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,6000,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:00.500  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,345,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:01.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,1200,0,0,nil`,
+		`12/11 01:08:02.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+	)
+	in := make(chan CombatLogRecord)
+	go func() {
+		defer close(in)
+		for _, rec := range data {
+			in <- *rec
+		}
+	}()
+	feed := NewCollector().Feed(time.Second)
+	var batches [][]SummaryDelta
+	for d := range feed.Run(context.Background(), in) {
+		batches = append(batches, d)
+	}
+	if len(batches) != 3 {
+		t.Fatalf("expected 3 batches of deltas, got %d: %v", len(batches), batches)
+	}
+	want := []SummaryDelta{
+		{Metric: MetricDamageBySource, Key: "Winterinjuly", Change: 6345},
+		{Metric: MetricHealingBySource, Key: "Yogzar", Change: 1200},
+		{Metric: MetricDamageBySource, Key: "Winterinjuly", Change: 100},
+	}
+	for i, w := range want {
+		if len(batches[i]) != 1 {
+			t.Fatalf("expected a single delta in batch %d, got %v", i, batches[i])
+		}
+		got := batches[i][0]
+		if got.Metric != w.Metric || got.Key != w.Key || got.Change != w.Change {
+			t.Errorf("expected %+v in batch %d, got %+v", w, i, got)
+		}
+	}
+	if !batches[1][0].Time.Equal(data[2].Timestamp) {
+		t.Errorf("expected the second batch at %s, got %s", data[2].Timestamp, batches[1][0].Time)
+	}
+	if total := feed.Stats().DamageBySource["Winterinjuly"]; total != 6445 {
+		t.Errorf("expected the feed to keep the running totals, got %d", total)
+	}
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+)
+
+// ErrMissingField is returned when an event has fewer fields than its event
+// type requires, usually because the line was truncated.
+var ErrMissingField = errors.New("missing field")
+
+// maxEventFields is the number of fields the fieldReader keeps the position
+// of. The longest events, such as SPELL_DAMAGE, have 20.
+const maxEventFields = 32
+
+// maxInterned bounds the number of distinct strings an interner keeps, so a
+// log with an unusual number of distinct names cannot grow it without limit.
+const maxInterned = 1 << 16
+
+// interner deduplicates the strings read from the log. Unit names, GUIDs and
+// spell names repeat on almost every line, so each distinct value is only
+// allocated once per parse.
+type interner struct {
+	strs map[string]string
+}
+
+func newInterner() *interner {
+	return &interner{strs: map[string]string{}}
+}
+
+// intern returns b as a string, reusing a previous allocation of the same
+// value. A nil interner allocates every time.
+func (in *interner) intern(b []byte) string {
+	if in == nil {
+		return string(b)
+	}
+	if s, ok := in.strs[string(b)]; ok {
+		return s
+	}
+	s := string(b)
+	if len(in.strs) < maxInterned {
+		in.strs[s] = s
+	}
+	return s
+}
+
+// fieldReader reads typed values out of the comma separated fields of an
+// event. The line is tokenized once, in place, and strings are only
+// allocated for the fields that are read as strings. The first failure is
+// remembered along with the index of the field that caused it, and every
+// read after a failure returns the zero value, so the parse functions can
+// read all of their fields and check once.
+type fieldReader struct {
+	line  []byte
+	spans [maxEventFields][2]int
+	n     int
+	names *interner
+	field int
+	err   error
+}
+
+// tokenize records where each field of line starts and ends. Commas inside
+// quoted fields, which names may contain, do not end a field.
+func (f *fieldReader) tokenize(line []byte) {
+	f.line = line
+	f.n = 0
+	start := 0
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			f.add(start, i)
+			start = i + 1
+		}
+	}
+	f.add(start, len(line))
+}
+
+func (f *fieldReader) add(start, end int) {
+	if f.n < maxEventFields {
+		f.spans[f.n] = [2]int{start, end}
+	}
+	f.n++
+}
+
+func (f *fieldReader) len() int {
+	return f.n
+}
+
+func (f *fieldReader) fail(i int, err error) {
+	if f.err == nil {
+		f.field = i
+		f.err = err
+	}
+}
+
+// raw returns the field as it appears in the log.
+func (f *fieldReader) raw(i int) ([]byte, bool) {
+	if f.err != nil {
+		return nil, false
+	}
+	if i >= f.n || i >= maxEventFields {
+		f.fail(i, ErrMissingField)
+		return nil, false
+	}
+	return f.line[f.spans[i][0]:f.spans[i][1]], true
+}
+
+// id returns the field as a string without unquoting it, as used for GUIDs
+// and the event type.
+func (f *fieldReader) id(i int) string {
+	b, _ := f.raw(i)
+	return f.names.intern(b)
+}
+
+// str returns the unquoted string value of a field.
+func (f *fieldReader) str(i int) string {
+	b, _ := f.raw(i)
+	return f.names.intern(unquoteField(b))
+}
+
+// uint parses a decimal or 0x prefixed hexadecimal unsigned integer field.
+func (f *fieldReader) uint(i int) uint64 {
+	b, ok := f.raw(i)
+	if !ok {
+		return 0
+	}
+	v, err := strconv.ParseUint(string(b), 0, 64)
+	if err != nil {
+		f.fail(i, err)
+	}
+	return v
+}
+
+// int parses a signed decimal integer field.
+func (f *fieldReader) int(i int) int64 {
+	b, ok := f.raw(i)
+	if !ok {
+		return 0
+	}
+	v, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		f.fail(i, err)
+	}
+	return v
+}
+
+// uintOrNil parses an unsigned integer field that may be logged as nil.
+func (f *fieldReader) uintOrNil(i int) uint64 {
+	if b, ok := f.raw(i); ok && bytes.Contains(b, nilField) {
+		return 0
+	}
+	return f.uint(i)
+}
+
+// nilBool parses a boolean flag field, where nil and anything unparseable are
+// false.
+func (f *fieldReader) nilBool(i int) bool {
+	b, ok := f.raw(i)
+	if !ok {
+		return false
+	}
+	return parseNilBool(b)
+}
+
+// spellSchool parses a spell school field, which is logged in hexadecimal in
+// prefixes and in decimal in suffixes.
+func (f *fieldReader) spellSchool(i int) SpellSchool {
+	return SpellSchool(f.uint(i))
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+// UnitFlags is the bitmask logged as sourceFlags and targetFlags, describing
+// the affiliation, reaction, controller and type of a unit relative to the
+// player that recorded the log.
+type UnitFlags uint32
+
+// UnitReaction is the decoded reaction of a unit towards the logging player.
+type UnitReaction string
+
+// UnitController is the decoded controller of a unit.
+type UnitController string
+
+const (
+	UnitFlagAffiliationMine     UnitFlags = 0x00000001
+	UnitFlagAffiliationParty    UnitFlags = 0x00000002
+	UnitFlagAffiliationRaid     UnitFlags = 0x00000004
+	UnitFlagAffiliationOutsider UnitFlags = 0x00000008
+	UnitFlagAffiliationMask     UnitFlags = 0x0000000F
+	UnitFlagReactionFriendly    UnitFlags = 0x00000010
+	UnitFlagReactionNeutral     UnitFlags = 0x00000020
+	UnitFlagReactionHostile     UnitFlags = 0x00000040
+	UnitFlagReactionMask        UnitFlags = 0x000000F0
+	UnitFlagControlPlayer       UnitFlags = 0x00000100
+	UnitFlagControlNPC          UnitFlags = 0x00000200
+	UnitFlagControlMask         UnitFlags = 0x00000300
+	UnitFlagTypePlayer          UnitFlags = 0x00000400
+	UnitFlagTypeNPC             UnitFlags = 0x00000800
+	UnitFlagTypePet             UnitFlags = 0x00001000
+	UnitFlagTypeGuardian        UnitFlags = 0x00002000
+	UnitFlagTypeObject          UnitFlags = 0x00004000
+	UnitFlagTypeMask            UnitFlags = 0x0000FC00
+	UnitFlagTarget              UnitFlags = 0x00010000
+	UnitFlagFocus               UnitFlags = 0x00020000
+	UnitFlagMainTank            UnitFlags = 0x00040000
+	UnitFlagMainAssist          UnitFlags = 0x00080000
+	UnitFlagNone                UnitFlags = 0x80000000
+)
+
+const (
+	ReactionFriendly UnitReaction = "Friendly"
+	ReactionNeutral  UnitReaction = "Neutral"
+	ReactionHostile  UnitReaction = "Hostile"
+	ReactionUnknown  UnitReaction = "Unknown"
+)
+
+const (
+	ControllerPlayer  UnitController = "Player"
+	ControllerNPC     UnitController = "NPC"
+	ControllerUnknown UnitController = "Unknown"
+)
+
+// Has reports whether every bit of flag is set.
+func (f UnitFlags) Has(flag UnitFlags) bool {
+	return f&flag == flag
+}
+
+// IsPlayer reports whether the unit is a player character.
+func (f UnitFlags) IsPlayer() bool {
+	return f.Has(UnitFlagTypePlayer)
+}
+
+// IsPet reports whether the unit is a pet or a guardian.
+func (f UnitFlags) IsPet() bool {
+	return f&(UnitFlagTypePet|UnitFlagTypeGuardian) != 0
+}
+
+// IsNPC reports whether the unit is a non-player character.
+func (f UnitFlags) IsNPC() bool {
+	return f.Has(UnitFlagTypeNPC)
+}
+
+// IsHostile reports whether the unit is hostile to the logging player.
+func (f UnitFlags) IsHostile() bool {
+	return f.Has(UnitFlagReactionHostile)
+}
+
+// IsFriendly reports whether the unit is friendly to the logging player.
+func (f UnitFlags) IsFriendly() bool {
+	return f.Has(UnitFlagReactionFriendly)
+}
+
+// IsRaidMember reports whether the unit is in the logging player's party or
+// raid, or is the logging player.
+func (f UnitFlags) IsRaidMember() bool {
+	return f&(UnitFlagAffiliationMine|UnitFlagAffiliationParty|UnitFlagAffiliationRaid) != 0
+}
+
+// Reaction decodes the reaction bits.
+func (f UnitFlags) Reaction() UnitReaction {
+	switch {
+	case f.Has(UnitFlagReactionHostile):
+		return ReactionHostile
+	case f.Has(UnitFlagReactionNeutral):
+		return ReactionNeutral
+	case f.Has(UnitFlagReactionFriendly):
+		return ReactionFriendly
+	default:
+		return ReactionUnknown
+	}
+}
+
+// Controller decodes the controller bits.
+func (f UnitFlags) Controller() UnitController {
+	switch {
+	case f.Has(UnitFlagControlPlayer):
+		return ControllerPlayer
+	case f.Has(UnitFlagControlNPC):
+		return ControllerNPC
+	default:
+		return ControllerUnknown
+	}
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "testing"
+
+func TestParseRowUnitFlags(t *testing.T) {
+	v := mustParseRow(t, `12/11 01:08:13.904  SPELL_CAST_SUCCESS,0x07000000008B0BE3,"Mostfa",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,53338,"Hunter's Mark",0x40`)
+	if !v.SourceFlags.IsPlayer() || !v.SourceFlags.IsFriendly() || v.SourceFlags.Controller() != ControllerPlayer {
+		t.Errorf("expected a friendly player controlled source, got %#x", uint32(v.SourceFlags))
+	}
+	if !v.TargetFlags.IsNPC() || !v.TargetFlags.IsHostile() || v.TargetFlags.Reaction() != ReactionHostile {
+		t.Errorf("expected a hostile NPC target, got %#x", uint32(v.TargetFlags))
+	}
+	if !v.TargetFlags.Has(UnitFlagTarget) || v.TargetFlags.Controller() != ControllerNPC {
+		t.Errorf("expected the target to be the logger's NPC controlled target, got %#x", uint32(v.TargetFlags))
+	}
+}
+
+func TestUnitFlagsIsPet(t *testing.T) {
+	if !UnitFlags(0x1114).IsPet() {
+		t.Error("expected 0x1114 to be a pet")
+	}
+	if UnitFlags(0x514).IsPet() || !UnitFlags(0x514).IsRaidMember() {
+		t.Error("expected 0x514 to be a raid member player")
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparsetest
+
+import (
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// EncounterFunc is an option for Encounter.
+type EncounterFunc func(*EncounterSpec)
+
+// EncounterSpec describes the boss attempt Encounter generates.
+type EncounterSpec struct {
+	Boss    Unit
+	Players []Unit
+	Start   time.Time
+	// Duration is the time from the first swing to the boss or the last
+	// player dying.
+	Duration time.Duration
+	// Kill ends the attempt with the boss dying, otherwise every player
+	// dies.
+	Kill bool
+	// Damage is what every player swings the boss for each second.
+	Damage uint64
+	// BossDamage is what the boss swings the first player for each second.
+	BossDamage uint64
+}
+
+// WithBoss sets the boss of the encounter.
+func WithBoss(u Unit) EncounterFunc {
+	return func(s *EncounterSpec) {
+		s.Boss = u
+	}
+}
+
+// WithPlayers sets the raid fighting the boss. The first player tanks it.
+func WithPlayers(players ...Unit) EncounterFunc {
+	return func(s *EncounterSpec) {
+		s.Players = players
+	}
+}
+
+// WithStart sets when the encounter starts.
+func WithStart(t time.Time) EncounterFunc {
+	return func(s *EncounterSpec) {
+		s.Start = t
+	}
+}
+
+// WithDuration sets how long the encounter lasts.
+func WithDuration(d time.Duration) EncounterFunc {
+	return func(s *EncounterSpec) {
+		s.Duration = d
+	}
+}
+
+// WithWipe ends the encounter with every player dead instead of a kill.
+func WithWipe() EncounterFunc {
+	return func(s *EncounterSpec) {
+		s.Kill = false
+	}
+}
+
+// WithDamage sets the damage every player and the boss deal each second.
+func WithDamage(player, boss uint64) EncounterFunc {
+	return func(s *EncounterSpec) {
+		s.Damage = player
+		s.BossDamage = boss
+	}
+}
+
+// Encounter returns the records of a boss attempt: every second each player
+// swings the boss and the boss swings the first player, and the attempt ends
+// with the boss's or every player's death. By default two players kill Lord
+// Marrowgar at Epoch in 30 seconds.
+func Encounter(opts ...EncounterFunc) []*frostparse.CombatLogRecord {
+	s := &EncounterSpec{
+		Boss:       Boss("Lord Marrowgar"),
+		Players:    []Unit{Player("Yogzar"), Player("Winterinjuly")},
+		Start:      Epoch,
+		Duration:   30 * time.Second,
+		Kill:       true,
+		Damage:     5000,
+		BossDamage: 10000,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	var out []*frostparse.CombatLogRecord
+	for d := time.Duration(0); d < s.Duration; d += time.Second {
+		at := s.Start.Add(d)
+		for _, p := range s.Players {
+			out = append(out, SwingDamage(at, p, s.Boss, s.Damage))
+		}
+		if len(s.Players) > 0 {
+			out = append(out, SwingDamage(at, s.Boss, s.Players[0], s.BossDamage))
+		}
+	}
+	end := s.Start.Add(s.Duration)
+	if s.Kill {
+		return append(out, UnitDied(end, s.Boss))
+	}
+	for _, p := range s.Players {
+		out = append(out, UnitDied(end, p))
+	}
+	return out
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package frostparsetest builds combat log records for unit tests of code
+// that consumes frostparse, so analyzers and listeners can be tested without
+// writing raw log lines.
+package frostparsetest
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// Epoch is the time At measures from, the night Icecrown Citadel opened.
+var Epoch = time.Date(2010, 12, 11, 20, 0, 0, 0, time.UTC)
+
+// At returns the time d after Epoch.
+func At(d time.Duration) time.Time {
+	return Epoch.Add(d)
+}
+
+const (
+	playerFlags = frostparse.UnitFlagTypePlayer | frostparse.UnitFlagControlPlayer | frostparse.UnitFlagReactionFriendly | frostparse.UnitFlagAffiliationRaid
+	npcFlags    = frostparse.UnitFlagTypeNPC | frostparse.UnitFlagControlNPC | frostparse.UnitFlagReactionHostile | frostparse.UnitFlagAffiliationOutsider
+)
+
+// Unit is the source or target of a record.
+type Unit struct {
+	ID    string
+	Name  string
+	Flags frostparse.UnitFlags
+}
+
+// Player returns a raid member. The GUID is derived from the name, so the
+// same name always gets the same GUID.
+func Player(name string) Unit {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return Unit{
+		ID:    fmt.Sprintf("0x07%014X", h.Sum64()&0xFFFFFFFFFFFFFF),
+		Name:  name,
+		Flags: playerFlags,
+	}
+}
+
+// NPC returns a hostile NPC with the given NPC ID.
+func NPC(name string, npcID uint64) Unit {
+	return Unit{
+		ID:    fmt.Sprintf("0xF130%06X%06X", npcID, 1),
+		Name:  name,
+		Flags: npcFlags,
+	}
+}
+
+// Boss returns the named boss of frostparse.DefaultBossRegistry, with the
+// boss's NPC ID in its GUID when the registry knows it.
+func Boss(name string) Unit {
+	var npcID uint64
+	if b, ok := frostparse.DefaultBossRegistry.Match(name, ""); ok && len(b.NPCIDs) > 0 {
+		npcID = b.NPCIDs[0]
+	}
+	return NPC(name, npcID)
+}
+
+// Spell is the spell of a record.
+type Spell struct {
+	ID     uint64
+	Name   string
+	School frostparse.SpellSchool
+}
+
+// RecordFunc is an option for the record builders.
+type RecordFunc func(*frostparse.CombatLogRecord)
+
+// Critical marks a damage or heal record as a critical hit.
+func Critical() RecordFunc {
+	return func(r *frostparse.CombatLogRecord) {
+		if r.DamageSuffix != nil {
+			r.DamageSuffix.Critical = true
+		}
+		if r.HealSuffix != nil {
+			r.HealSuffix.Critical = true
+		}
+	}
+}
+
+// Overkill sets the overkill of a damage record.
+func Overkill(n uint64) RecordFunc {
+	return func(r *frostparse.CombatLogRecord) {
+		if r.DamageSuffix != nil {
+			r.DamageSuffix.Overkill = n
+		}
+	}
+}
+
+// Overhealing sets the overhealing of a heal record.
+func Overhealing(n uint64) RecordFunc {
+	return func(r *frostparse.CombatLogRecord) {
+		if r.HealSuffix != nil {
+			r.HealSuffix.Overhealing = n
+		}
+	}
+}
+
+// Absorbed sets the amount absorbed of a damage or heal record.
+func Absorbed(n uint64) RecordFunc {
+	return func(r *frostparse.CombatLogRecord) {
+		if r.DamageSuffix != nil {
+			r.DamageSuffix.Absorbed = n
+		}
+		if r.HealSuffix != nil {
+			r.HealSuffix.Absorbed = n
+		}
+	}
+}
+
+// Record returns a record of the event type between source and target,
+// without a prefix or suffix.
+func Record(at time.Time, event frostparse.EventType, source, target Unit, opts ...RecordFunc) *frostparse.CombatLogRecord {
+	r := &frostparse.CombatLogRecord{
+		BaseCombatEvent: frostparse.BaseCombatEvent{
+			Timestamp:         at,
+			AdjustedTimestamp: at,
+			EventType:         event,
+			SourceID:          source.ID,
+			SourceName:        source.Name,
+			SourceFlags:       source.Flags,
+			TargetID:          target.ID,
+			TargetName:        target.Name,
+			TargetFlags:       target.Flags,
+		},
+	}
+	return apply(r, opts)
+}
+
+func spellPrefix(s Spell) *frostparse.SpellAndRangePrefix {
+	return &frostparse.SpellAndRangePrefix{
+		SpellID:     s.ID,
+		SpellName:   s.Name,
+		SpellSchool: s.School,
+	}
+}
+
+// apply applies the options to r and returns it.
+func apply(r *frostparse.CombatLogRecord, opts []RecordFunc) *frostparse.CombatLogRecord {
+	for _, o := range opts {
+		o(r)
+	}
+	return r
+}
+
+// SwingDamage returns a SWING_DAMAGE record.
+func SwingDamage(at time.Time, source, target Unit, amount uint64, opts ...RecordFunc) *frostparse.CombatLogRecord {
+	r := Record(at, frostparse.SwingDamage, source, target)
+	r.DamageSuffix = &frostparse.DamageSuffix{Amount: amount, SpellSchool: frostparse.Physical}
+	return apply(r, opts)
+}
+
+// SpellDamage returns a SPELL_DAMAGE record.
+func SpellDamage(at time.Time, source, target Unit, spell Spell, amount uint64, opts ...RecordFunc) *frostparse.CombatLogRecord {
+	r := Record(at, frostparse.SpellDamage, source, target)
+	r.SpellAndRangePrefix = spellPrefix(spell)
+	r.DamageSuffix = &frostparse.DamageSuffix{Amount: amount, SpellSchool: spell.School}
+	return apply(r, opts)
+}
+
+// SpellHeal returns a SPELL_HEAL record.
+func SpellHeal(at time.Time, source, target Unit, spell Spell, amount uint64, opts ...RecordFunc) *frostparse.CombatLogRecord {
+	r := Record(at, frostparse.SpellHeal, source, target)
+	r.SpellAndRangePrefix = spellPrefix(spell)
+	r.HealSuffix = &frostparse.HealSuffix{Amount: amount}
+	return apply(r, opts)
+}
+
+// CastSuccess returns a SPELL_CAST_SUCCESS record.
+func CastSuccess(at time.Time, source, target Unit, spell Spell) *frostparse.CombatLogRecord {
+	r := Record(at, frostparse.SpellCastSuccess, source, target)
+	r.SpellAndRangePrefix = spellPrefix(spell)
+	return r
+}
+
+// AuraApplied returns a SPELL_AURA_APPLIED record.
+func AuraApplied(at time.Time, source, target Unit, spell Spell, aura frostparse.AuraType) *frostparse.CombatLogRecord {
+	return auraRecord(at, frostparse.SpellAuraApplied, source, target, spell, aura)
+}
+
+// AuraRemoved returns a SPELL_AURA_REMOVED record.
+func AuraRemoved(at time.Time, source, target Unit, spell Spell, aura frostparse.AuraType) *frostparse.CombatLogRecord {
+	return auraRecord(at, frostparse.SpellAuraRemoved, source, target, spell, aura)
+}
+
+func auraRecord(at time.Time, event frostparse.EventType, source, target Unit, spell Spell, aura frostparse.AuraType) *frostparse.CombatLogRecord {
+	r := Record(at, event, source, target)
+	r.SpellAndRangePrefix = spellPrefix(spell)
+	r.AuraSuffix = &frostparse.AuraSuffix{AuraType: aura}
+	return r
+}
+
+// UnitDied returns the UNIT_DIED record of a unit.
+func UnitDied(at time.Time, unit Unit) *frostparse.CombatLogRecord {
+	return Record(at, frostparse.UnitDied, Unit{ID: "0x0000000000000000", Flags: frostparse.UnitFlagNone}, unit)
+}
+
+// Collect runs a Collector with one second buckets over the records, for
+// tests that need SummaryStats.
+func Collect(records []*frostparse.CombatLogRecord, opts ...frostparse.CollectorFunc) *frostparse.SummaryStats {
+	opts = append([]frostparse.CollectorFunc{frostparse.WithTimeResolution(time.Second)}, opts...)
+	return frostparse.NewCollector(opts...).Run(records)
+}
+
+// Recorder is an EventListener that keeps every record dispatched to it, in
+// order, and still invokes the callbacks registered on it. It is safe for
+// concurrent use.
+type Recorder struct {
+	frostparse.EventListener
+
+	mu      sync.Mutex
+	records []frostparse.CombatLogRecord
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{EventListener: frostparse.NewEventListener()}
+}
+
+// Dispatch records rec and invokes the callbacks registered for it.
+func (r *Recorder) Dispatch(rec frostparse.CombatLogRecord) {
+	r.mu.Lock()
+	r.records = append(r.records, rec)
+	r.mu.Unlock()
+	r.EventListener.Dispatch(rec)
+}
+
+// Records returns the records dispatched so far.
+func (r *Recorder) Records() []frostparse.CombatLogRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]frostparse.CombatLogRecord(nil), r.records...)
+}
+
+// Count returns how many records of the event type were dispatched.
+func (r *Recorder) Count(event frostparse.EventType) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, rec := range r.records {
+		if rec.EventType == event {
+			n++
+		}
+	}
+	return n
+}
+
+// Replay dispatches the records to listener in order, as a parse would.
+func Replay(listener frostparse.EventListener, records []*frostparse.CombatLogRecord) {
+	for _, rec := range records {
+		listener.Dispatch(*rec)
+	}
+}
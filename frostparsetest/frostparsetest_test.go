@@ -0,0 +1,96 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparsetest
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+func TestUnits(t *testing.T) {
+	if Player("Yogzar") != Player("Yogzar") || Player("Yogzar").ID == Player("Winterinjuly").ID {
+		t.Error("expected stable, distinct player GUIDs")
+	}
+	boss := Boss("Lord Marrowgar")
+	if frostparse.NPCID(boss.ID) != 36612 {
+		t.Errorf("expected Lord Marrowgar's NPC ID in %s", boss.ID)
+	}
+	if !Player("Yogzar").Flags.IsPlayer() || boss.Flags.IsPlayer() {
+		t.Error("unexpected unit flags")
+	}
+}
+
+func TestEncounter(t *testing.T) {
+	kill := Encounter(WithDuration(10 * time.Second))
+	encounters := frostparse.NewEncounterSplitter().Split(kill)
+	if len(encounters) != 1 || encounters[0].Name != "Lord Marrowgar" || encounters[0].Result != frostparse.EncounterKill {
+		t.Fatalf("expected a Lord Marrowgar kill, got %+v", encounters)
+	}
+	stats := Collect(kill)
+	if got := stats.DamageBySource["Yogzar"]; got != 50000 {
+		t.Errorf("expected 50000 damage, got %d", got)
+	}
+	if got := stats.DamageTakenBySource["Lord Marrowgar"]; got != 100000 {
+		t.Errorf("expected 100000 damage taken, got %d", got)
+	}
+
+	wipe := Encounter(WithBoss(Boss("Lady Deathwhisper")), WithPlayers(Player("Yogzar")), WithWipe())
+	encounters = frostparse.NewEncounterSplitter().Split(wipe)
+	if len(encounters) != 1 || encounters[0].Result != frostparse.EncounterWipe {
+		t.Fatalf("expected a Lady Deathwhisper wipe, got %+v", encounters)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	yogzar, boss := Player("Yogzar"), Boss("Lord Marrowgar")
+	r := NewRecorder()
+	heals := 0
+	r.AddEventListener(frostparse.SpellHeal, func(frostparse.CombatLogRecord) { heals++ })
+	Replay(r, []*frostparse.CombatLogRecord{
+		SpellDamage(At(0), yogzar, boss, Spell{ID: 49909, Name: "Icy Touch", School: frostparse.Frost}, 3000, Critical()),
+		SpellHeal(At(time.Second), yogzar, yogzar, Spell{ID: 48071, Name: "Flash Heal", School: frostparse.Holy}, 4000, Overhealing(1000)),
+	})
+	records := r.Records()
+	if len(records) != 2 || !records[0].DamageSuffix.Critical || records[1].HealSuffix.Overhealing != 1000 {
+		t.Errorf("unexpected records: %+v", records)
+	}
+	if heals != 1 || r.Count(frostparse.SpellDamage) != 1 {
+		t.Errorf("expected the callbacks to be invoked, got %d heals", heals)
+	}
+}
+
+func TestGenerateLog(t *testing.T) {
+	var a, b bytes.Buffer
+	if err := GenerateLog(&a, 2000, 1); err != nil {
+		t.Fatal(err)
+	}
+	GenerateLog(&b, 2000, 1)
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Error("expected the same seed to generate the same log")
+	}
+	p := frostparse.New(frostparse.WithReader(&a), frostparse.WithStrictMode(true))
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2000 {
+		t.Errorf("expected 2000 records, got %d", len(data))
+	}
+}
@@ -0,0 +1,362 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"sort"
+	"time"
+)
+
+// Heal is a single SPELL_HEAL or SPELL_PERIODIC_HEAL.
+type Heal struct {
+	Time   time.Time `json:"time"`
+	Healer string    `json:"healer"`
+	Target string    `json:"target"`
+	Spell  string    `json:"spell"`
+	// Amount is the heal as logged, including overhealing.
+	Amount      uint64 `json:"amount"`
+	Overhealing uint64 `json:"overhealing,omitempty"`
+	Critical    bool   `json:"critical,omitempty"`
+	Periodic    bool   `json:"periodic,omitempty"`
+}
+
+// Effective returns the healing that restored health.
+func (h Heal) Effective() uint64 {
+	if h.Overhealing >= h.Amount {
+		return 0
+	}
+	return h.Amount - h.Overhealing
+}
+
+// HealingBurst is the window of a healer's most effective healing.
+type HealingBurst struct {
+	Healer string `json:"healer"`
+	// Start and End are the times of the first and last heal in the window.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Healing is the effective healing done in the window.
+	Healing uint64 `json:"healing"`
+	Heals   int    `json:"heals"`
+}
+
+// TriageResponse is how quickly a healer reacted to players dropping low.
+type TriageResponse struct {
+	Healer string `json:"healer"`
+	// Responses is the number of times the healer landed the first heal on a
+	// player below the triage threshold.
+	Responses int           `json:"responses"`
+	Mean      time.Duration `json:"mean"`
+	Max       time.Duration `json:"max"`
+}
+
+// EncounterHealingSpikes is the largest heals, the healing bursts and the
+// triage responses of one boss attempt.
+type EncounterHealingSpikes struct {
+	Encounter string          `json:"encounter"`
+	Attempt   int             `json:"attempt,omitempty"`
+	Result    EncounterResult `json:"result,omitempty"`
+	StartTime time.Time       `json:"start_time"`
+	// Largest are the biggest heals by amount, largest first.
+	Largest []Heal `json:"largest"`
+	// Bursts has the best window of every healer, sorted by healing, highest
+	// first.
+	Bursts []HealingBurst `json:"bursts"`
+	// Triage has the response of every healer who landed a first heal on a
+	// low player, fastest first.
+	Triage []TriageResponse `json:"triage"`
+}
+
+// HealingSpikeAnalyzerFunc is an option for NewHealingSpikeAnalyzer.
+type HealingSpikeAnalyzerFunc func(*HealingSpikeAnalyzer)
+
+// HealingSpikeAnalyzer finds the largest single heals, the burst healing
+// windows and the triage response times of every healer in each boss attempt.
+//
+// 3.3.5a combat logs do not record unit health, so triage is approximated.
+// The health a player is missing is the damage they took less the healing
+// they received, reset by overhealing and death, and their maximum health is
+// the most they were ever missing in the log. A player is low once they miss
+// more than 1 - Threshold of it, and the healer of the next heal they receive
+// is credited with the time since.
+type HealingSpikeAnalyzer struct {
+	// Window is the length of a burst window.
+	Window time.Duration
+	// Top is the number of largest heals kept per attempt.
+	Top int
+	// Threshold is the fraction of estimated maximum health below which a
+	// player needs triage.
+	Threshold float64
+	// Splitter finds the boss attempts. Defaults to an EncounterSplitter with
+	// default settings.
+	Splitter *EncounterSplitter
+}
+
+// WithBurstWindow sets the length of a burst window.
+func WithBurstWindow(d time.Duration) HealingSpikeAnalyzerFunc {
+	return func(a *HealingSpikeAnalyzer) {
+		a.Window = d
+	}
+}
+
+// WithLargestHeals sets the number of largest heals kept per attempt.
+func WithLargestHeals(n int) HealingSpikeAnalyzerFunc {
+	return func(a *HealingSpikeAnalyzer) {
+		a.Top = n
+	}
+}
+
+// WithTriageThreshold sets the fraction of estimated maximum health below
+// which a player needs triage.
+func WithTriageThreshold(f float64) HealingSpikeAnalyzerFunc {
+	return func(a *HealingSpikeAnalyzer) {
+		a.Threshold = f
+	}
+}
+
+// WithHealingSpikeSplitter sets the EncounterSplitter used to find boss
+// attempts.
+func WithHealingSpikeSplitter(s *EncounterSplitter) HealingSpikeAnalyzerFunc {
+	return func(a *HealingSpikeAnalyzer) {
+		a.Splitter = s
+	}
+}
+
+// NewHealingSpikeAnalyzer initializes, allocates and returns a pointer to a
+// HealingSpikeAnalyzer. Burst windows are 5 seconds long, the 10 largest
+// heals are kept and players below 30% health need triage by default.
+func NewHealingSpikeAnalyzer(opts ...HealingSpikeAnalyzerFunc) *HealingSpikeAnalyzer {
+	a := &HealingSpikeAnalyzer{
+		Window:    time.Second * 5,
+		Top:       10,
+		Threshold: 0.3,
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	if a.Splitter == nil {
+		a.Splitter = NewEncounterSplitter()
+	}
+	return a
+}
+
+// Run returns the healing spikes of every boss attempt, in the order of the
+// attempts. Heals by pets and totems are credited to their owner.
+func (a *HealingSpikeAnalyzer) Run(data []*CombatLogRecord) []EncounterHealingSpikes {
+	pets := newPetTracker()
+	maxHealth := estimateMaxHealth(data)
+	out := []EncounterHealingSpikes{}
+	for _, enc := range a.Splitter.Split(data) {
+		var heals []Heal
+		byHealer := map[string][]Heal{}
+		triage := newTriageTracker(maxHealth, a.Threshold)
+		for _, rec := range enc.Records {
+			pets.observe(*rec)
+			if rec.HealSuffix == nil || (rec.EventType != SpellHeal && rec.EventType != SpellPeriodicHeal) {
+				triage.observe(*rec, "")
+				continue
+			}
+			healer := rec.SourceName
+			if owner, ok := pets.owner(rec.SourceID); ok {
+				healer = owner
+			} else if !isPlayerID(rec.SourceID) {
+				triage.observe(*rec, "")
+				continue
+			}
+			triage.observe(*rec, healer)
+			h := Heal{
+				Time:        rec.Timestamp,
+				Healer:      healer,
+				Target:      rec.TargetName,
+				Spell:       abilityName(*rec),
+				Amount:      rec.HealSuffix.Amount,
+				Overhealing: rec.HealSuffix.Overhealing,
+				Critical:    rec.HealSuffix.Critical,
+				Periodic:    rec.EventType == SpellPeriodicHeal,
+			}
+			heals = append(heals, h)
+			byHealer[healer] = append(byHealer[healer], h)
+		}
+		es := EncounterHealingSpikes{
+			Encounter: enc.Name,
+			Attempt:   enc.Attempt,
+			Result:    enc.Result,
+			StartTime: enc.StartTime,
+			Largest:   a.largest(heals),
+			Bursts:    make([]HealingBurst, 0, len(byHealer)),
+			Triage:    triage.responses(),
+		}
+		for healer, hs := range byHealer {
+			if b, ok := a.burst(healer, hs); ok {
+				es.Bursts = append(es.Bursts, b)
+			}
+		}
+		sort.Slice(es.Bursts, func(i, j int) bool {
+			if es.Bursts[i].Healing != es.Bursts[j].Healing {
+				return es.Bursts[i].Healing > es.Bursts[j].Healing
+			}
+			return es.Bursts[i].Healer < es.Bursts[j].Healer
+		})
+		out = append(out, es)
+	}
+	return out
+}
+
+// largest returns the Top biggest heals, largest first.
+func (a *HealingSpikeAnalyzer) largest(heals []Heal) []Heal {
+	out := append([]Heal{}, heals...)
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Amount > out[j].Amount
+	})
+	if a.Top > 0 && len(out) > a.Top {
+		out = out[:a.Top]
+	}
+	return out
+}
+
+// burst returns the window of the healer's heals, in log order, with the
+// most effective healing.
+func (a *HealingSpikeAnalyzer) burst(healer string, heals []Heal) (HealingBurst, bool) {
+	var best HealingBurst
+	var sum uint64
+	start := 0
+	for end, h := range heals {
+		sum += h.Effective()
+		for start < end && h.Time.Sub(heals[start].Time) >= a.Window {
+			sum -= heals[start].Effective()
+			start++
+		}
+		if sum > best.Healing {
+			best = HealingBurst{
+				Healer:  healer,
+				Start:   heals[start].Time,
+				End:     h.Time,
+				Healing: sum,
+				Heals:   end - start + 1,
+			}
+		}
+	}
+	return best, best.Healing > 0
+}
+
+// missingHealth tracks the health players are missing from the damage they
+// take and the healing they receive.
+type missingHealth map[string]uint64
+
+// observe applies a record and returns the player it changed, if any.
+func (m missingHealth) observe(rec CombatLogRecord) (string, bool) {
+	if !isPlayerID(rec.TargetID) {
+		return "", false
+	}
+	switch {
+	case rec.EventType == UnitDied:
+		delete(m, rec.TargetID)
+	case rec.DamageSuffix != nil && isDamageEvent(rec):
+		d := rec.DamageSuffix
+		if d.Overkill < d.Amount {
+			m[rec.TargetID] += d.Amount - d.Overkill
+		}
+	case rec.HealSuffix != nil && isHealingEvent(rec):
+		h := rec.HealSuffix
+		if h.Overhealing > 0 || h.Amount >= m[rec.TargetID] {
+			delete(m, rec.TargetID)
+		} else {
+			m[rec.TargetID] -= h.Amount
+		}
+	default:
+		return "", false
+	}
+	return rec.TargetID, true
+}
+
+// estimateMaxHealth returns the most health every player was missing at once,
+// which is their maximum health if they ever died or were nearly killed.
+func estimateMaxHealth(data []*CombatLogRecord) map[string]uint64 {
+	missing := missingHealth{}
+	out := map[string]uint64{}
+	for _, rec := range data {
+		if id, ok := missing.observe(*rec); ok && missing[id] > out[id] {
+			out[id] = missing[id]
+		}
+	}
+	return out
+}
+
+// triageTracker times how long low players waited for a heal.
+type triageTracker struct {
+	maxHealth map[string]uint64
+	threshold float64
+	missing   missingHealth
+	// low is when each player currently waiting for a heal dropped low.
+	low   map[string]time.Time
+	waits map[string][]time.Duration
+}
+
+func newTriageTracker(maxHealth map[string]uint64, threshold float64) *triageTracker {
+	return &triageTracker{
+		maxHealth: maxHealth,
+		threshold: threshold,
+		missing:   missingHealth{},
+		low:       map[string]time.Time{},
+		waits:     map[string][]time.Duration{},
+	}
+}
+
+// observe applies a record. healer is set for heals by players and their pets.
+func (t *triageTracker) observe(rec CombatLogRecord, healer string) {
+	if since, ok := t.low[rec.TargetID]; ok && rec.HealSuffix != nil {
+		delete(t.low, rec.TargetID)
+		if healer != "" {
+			t.waits[healer] = append(t.waits[healer], rec.Timestamp.Sub(since))
+		}
+	}
+	id, ok := t.missing.observe(rec)
+	if !ok {
+		return
+	}
+	if rec.EventType == UnitDied {
+		delete(t.low, id)
+		return
+	}
+	max := t.maxHealth[id]
+	if _, ok := t.low[id]; !ok && max > 0 && float64(t.missing[id]) > float64(max)*(1-t.threshold) {
+		t.low[id] = rec.Timestamp
+	}
+}
+
+// responses returns the triage response of every healer, fastest first.
+func (t *triageTracker) responses() []TriageResponse {
+	out := make([]TriageResponse, 0, len(t.waits))
+	for healer, waits := range t.waits {
+		r := TriageResponse{Healer: healer, Responses: len(waits)}
+		var sum time.Duration
+		for _, w := range waits {
+			sum += w
+			if w > r.Max {
+				r.Max = w
+			}
+		}
+		r.Mean = sum / time.Duration(len(waits))
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Mean != out[j].Mean {
+			return out[i].Mean < out[j].Mean
+		}
+		return out[i].Healer < out[j].Healer
+	})
+	return out
+}
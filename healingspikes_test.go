@@ -0,0 +1,103 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHealingSpikeAnalyzerRun(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:01.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,3000,0,0,nil`,
+		`12/11 01:08:02.000  SPELL_PERIODIC_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,1000,400,0,nil`,
+		`12/11 01:08:04.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,49273,"Healing Wave",0x8,9000,0,0,1`,
+		`12/11 01:08:20.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,49273,"Healing Wave",0x8,5000,0,0,nil`,
+		`12/11 01:08:25.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:30.000  SPELL_HEAL,0x0700000000A1B2C3,"Pieshop",0x512,0x07000000009DF7A8,"Winterinjuly",0x514,48071,"Flash Heal",0x2,4000,4000,0,nil`,
+		`12/11 01:08:40.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:41.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F0400003D,"Lord Marrowgar",0x10a48`,
+	)
+	spikes := NewHealingSpikeAnalyzer(WithLargestHeals(2)).Run(data)
+	if len(spikes) != 1 || spikes[0].Encounter != "Lord Marrowgar" {
+		t.Fatalf("expected the Marrowgar attempt, got %+v", spikes)
+	}
+	largest := spikes[0].Largest
+	if len(largest) != 2 || largest[0].Amount != 9000 || !largest[0].Critical || largest[1].Amount != 5000 {
+		t.Fatalf("expected the two largest heals, got %+v", largest)
+	}
+	bursts := spikes[0].Bursts
+	if len(bursts) != 1 {
+		t.Fatalf("expected only Yogzar to have effective healing, got %+v", bursts)
+	}
+	b := bursts[0]
+	if b.Healer != "Yogzar" || b.Healing != 12600 || b.Heals != 3 || b.End.Sub(b.Start) != 3*time.Second {
+		t.Errorf("expected a 12600 burst over the first three heals, got %+v", b)
+	}
+}
+
+func TestHealingSpikeAnalyzerWindow(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:01.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,3000,0,0,nil`,
+		`12/11 01:08:02.000  SPELL_PERIODIC_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,1000,400,0,nil`,
+		`12/11 01:08:04.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,49273,"Healing Wave",0x8,9000,0,0,1`,
+		`12/11 01:08:05.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:06.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F0400003D,"Lord Marrowgar",0x10a48`,
+	)
+	spikes := NewHealingSpikeAnalyzer(WithBurstWindow(2 * time.Second)).Run(data)
+	if len(spikes) != 1 || len(spikes[0].Bursts) != 1 {
+		t.Fatalf("expected one burst, got %+v", spikes)
+	}
+	if b := spikes[0].Bursts[0]; b.Healing != 9000 || b.Heals != 1 {
+		t.Errorf("expected the window to hold the Healing Wave alone, got %+v", b)
+	}
+	if heal := spikes[0].Largest[2]; !heal.Periodic || heal.Effective() != 600 {
+		t.Errorf("expected the periodic tick to heal for 600, got %+v", heal)
+	}
+}
+
+func TestHealingSpikeAnalyzerTriage(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:00.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,10500,500,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:00.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0x07000000009DF7A8,"Winterinjuly",0x514`,
+		`12/11 01:08:10.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,5000,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:11.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,3000,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:12.500  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,49273,"Healing Wave",0x8,4000,0,0,nil`,
+		`12/11 01:08:13.000  SPELL_HEAL,0x0700000000A1B2C3,"Pieshop",0x512,0x07000000009DF7A8,"Winterinjuly",0x514,48071,"Flash Heal",0x2,4000,0,0,nil`,
+		`12/11 01:08:20.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,8000,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:23.000  SPELL_HEAL,0x0700000000A1B2C3,"Pieshop",0x512,0x07000000009DF7A8,"Winterinjuly",0x514,48071,"Flash Heal",0x2,4000,0,0,nil`,
+		`12/11 01:08:24.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F0400003D,"Lord Marrowgar",0x10a48`,
+	)
+	// the first attempt wipes, and its killing blow sets the maximum health
+	// used in the second
+	spikes := NewHealingSpikeAnalyzer().Run(data)
+	if len(spikes) != 2 {
+		t.Fatalf("expected two attempts, got %+v", spikes)
+	}
+	triage := spikes[1].Triage
+	if len(triage) != 2 {
+		t.Fatalf("expected a response from both healers, got %+v", triage)
+	}
+	if r := triage[0]; r.Healer != "Yogzar" || r.Responses != 1 || r.Mean != 1500*time.Millisecond {
+		t.Errorf("expected Yogzar to respond in 1.5s, got %+v", r)
+	}
+	if r := triage[1]; r.Healer != "Pieshop" || r.Responses != 1 || r.Max != 3*time.Second {
+		t.Errorf("expected Pieshop to respond in 3s, got %+v", r)
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "sort"
+
+// Mechanic is a boss ability tracked by the HeatmapAnalyzer. Damage is
+// matched by SpellIDs when set, otherwise by spell name.
+type Mechanic struct {
+	Name     string   `json:"name"`
+	SpellIDs []uint64 `json:"spell_ids,omitempty"`
+}
+
+// HeatmapCell is the damage a player took from one mechanic.
+type HeatmapCell struct {
+	Hits   int    `json:"hits"`
+	Damage uint64 `json:"damage"`
+}
+
+// Heatmap is a players × mechanics matrix of damage taken. Cells is indexed
+// by the position of the player in Players, then of the mechanic in
+// Mechanics.
+type Heatmap struct {
+	Players   []string        `json:"players"`
+	Mechanics []string        `json:"mechanics"`
+	Cells     [][]HeatmapCell `json:"cells"`
+}
+
+// Cell returns the cell of the named player and mechanic.
+func (h *Heatmap) Cell(player, mechanic string) (HeatmapCell, bool) {
+	i := sort.SearchStrings(h.Players, player)
+	if i == len(h.Players) || h.Players[i] != player {
+		return HeatmapCell{}, false
+	}
+	for j, m := range h.Mechanics {
+		if m == mechanic {
+			return h.Cells[i][j], true
+		}
+	}
+	return HeatmapCell{}, false
+}
+
+// HeatmapAnalyzerFunc is an option for NewHeatmapAnalyzer.
+type HeatmapAnalyzerFunc func(*HeatmapAnalyzer)
+
+// HeatmapAnalyzer counts the hits and damage players take from each
+// mechanic.
+type HeatmapAnalyzer struct {
+	// Mechanics are the columns of the heatmap, in order. When empty, every
+	// ability an NPC damaged a player with becomes a mechanic, ordered by
+	// total damage.
+	Mechanics []Mechanic
+}
+
+// WithMechanics sets the mechanics tracked by the heatmap.
+func WithMechanics(m ...Mechanic) HeatmapAnalyzerFunc {
+	return func(a *HeatmapAnalyzer) {
+		a.Mechanics = m
+	}
+}
+
+// NewHeatmapAnalyzer initializes, allocates and returns a pointer to a
+// HeatmapAnalyzer.
+func NewHeatmapAnalyzer(opts ...HeatmapAnalyzerFunc) *HeatmapAnalyzer {
+	a := &HeatmapAnalyzer{}
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+// Run builds the heatmap of the damage NPCs dealt to players. Every player
+// hit by at least one mechanic gets a row, sorted by name.
+func (a *HeatmapAnalyzer) Run(data []*CombatLogRecord) *Heatmap {
+	mechanics := a.Mechanics
+	if len(mechanics) == 0 {
+		mechanics = discoverMechanics(data)
+	}
+	byID := map[uint64]int{}
+	byName := map[string]int{}
+	for i, m := range mechanics {
+		if len(m.SpellIDs) == 0 {
+			byName[m.Name] = i
+		}
+		for _, id := range m.SpellIDs {
+			byID[id] = i
+		}
+	}
+
+	cells := map[string][]HeatmapCell{}
+	for _, row := range data {
+		if !isMechanicHit(*row) {
+			continue
+		}
+		col, ok := -1, false
+		if row.SpellAndRangePrefix != nil {
+			col, ok = byID[row.SpellAndRangePrefix.SpellID]
+		}
+		if !ok {
+			col, ok = byName[abilityName(*row)]
+		}
+		if !ok {
+			continue
+		}
+		r, seen := cells[row.TargetName]
+		if !seen {
+			r = make([]HeatmapCell, len(mechanics))
+			cells[row.TargetName] = r
+		}
+		r[col].Hits++
+		r[col].Damage += row.DamageSuffix.Amount
+	}
+
+	h := &Heatmap{
+		Players:   make([]string, 0, len(cells)),
+		Mechanics: make([]string, len(mechanics)),
+	}
+	for i, m := range mechanics {
+		h.Mechanics[i] = m.Name
+	}
+	for name := range cells {
+		h.Players = append(h.Players, name)
+	}
+	sort.Strings(h.Players)
+	h.Cells = make([][]HeatmapCell, len(h.Players))
+	for i, name := range h.Players {
+		h.Cells[i] = cells[name]
+	}
+	return h
+}
+
+// isMechanicHit reports whether the record is damage an NPC dealt to a
+// player.
+func isMechanicHit(row CombatLogRecord) bool {
+	return row.DamageSuffix != nil && isDamageEvent(row) &&
+		(isNPCID(row.SourceID) || isBossID(row.SourceID)) && isPlayerID(row.TargetID)
+}
+
+// discoverMechanics returns every ability NPCs damaged players with, most
+// damaging first.
+func discoverMechanics(data []*CombatLogRecord) []Mechanic {
+	damage := map[string]uint64{}
+	for _, row := range data {
+		if isMechanicHit(*row) {
+			damage[abilityName(*row)] += row.DamageSuffix.Amount
+		}
+	}
+	out := make([]Mechanic, 0, len(damage))
+	for name := range damage {
+		out = append(out, Mechanic{Name: name})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if damage[out[i].Name] != damage[out[j].Name] {
+			return damage[out[i].Name] > damage[out[j].Name]
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "testing"
+
+func TestHeatmapAnalyzerRun(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:20.000  SPELL_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,69146,"Coldflame",0x10,5000,0,16,0,0,0,nil,nil,nil`,
+		`12/11 01:08:21.000  SPELL_PERIODIC_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,69146,"Coldflame",0x10,3000,0,16,0,0,0,nil,nil,nil`,
+		`12/11 01:08:22.000  SPELL_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000007721EC,"Yogzar",0x511,69055,"Bone Slice",0x1,9000,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:23.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000007721EC,"Yogzar",0x511,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:24.000  SPELL_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47610,"Frostfire Bolt",0x14,9000,0,16,0,0,0,nil,nil,nil`,
+	)
+
+	h := NewHeatmapAnalyzer(WithMechanics(
+		Mechanic{Name: "Coldflame", SpellIDs: []uint64{69146, 70823}},
+		Mechanic{Name: "Bone Slice"},
+	)).Run(data)
+	if len(h.Players) != 2 || h.Players[0] != "Winterinjuly" || h.Players[1] != "Yogzar" {
+		t.Fatalf("unexpected players: %v", h.Players)
+	}
+	if c, _ := h.Cell("Winterinjuly", "Coldflame"); c.Hits != 2 || c.Damage != 8000 {
+		t.Errorf("unexpected Coldflame cell: %+v", c)
+	}
+	if c, _ := h.Cell("Yogzar", "Bone Slice"); c.Hits != 1 || c.Damage != 9000 {
+		t.Errorf("unexpected Bone Slice cell: %+v", c)
+	}
+	if c, ok := h.Cell("Yogzar", "Coldflame"); !ok || c.Hits != 0 {
+		t.Errorf("expected an empty Coldflame cell for Yogzar, got %+v", c)
+	}
+
+	h = NewHeatmapAnalyzer().Run(data)
+	if len(h.Mechanics) != 3 || h.Mechanics[0] != "Bone Slice" || h.Mechanics[2] != "Melee" {
+		t.Errorf("expected discovered mechanics ordered by damage, got %v", h.Mechanics)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+// Index holds inverted indexes over a slice of parsed records so that lookups
+// by unit or spell do not need a linear scan. The returned slices share the
+// records with the indexed slice, preserve log order, and must not be
+// modified.
+type Index struct {
+	bySource map[string][]*CombatLogRecord
+	byTarget map[string][]*CombatLogRecord
+	bySpell  map[uint64][]*CombatLogRecord
+}
+
+// NewIndex builds an Index over records.
+func NewIndex(records []*CombatLogRecord) *Index {
+	idx := &Index{
+		bySource: map[string][]*CombatLogRecord{},
+		byTarget: map[string][]*CombatLogRecord{},
+		bySpell:  map[uint64][]*CombatLogRecord{},
+	}
+	for _, r := range records {
+		idx.bySource[r.SourceID] = append(idx.bySource[r.SourceID], r)
+		idx.byTarget[r.TargetID] = append(idx.byTarget[r.TargetID], r)
+		spellID, extraSpellID := spellReferences(*r)
+		if spellID != 0 {
+			idx.bySpell[spellID] = append(idx.bySpell[spellID], r)
+		}
+		if extraSpellID != 0 && extraSpellID != spellID {
+			idx.bySpell[extraSpellID] = append(idx.bySpell[extraSpellID], r)
+		}
+	}
+	return idx
+}
+
+// BySource returns the records whose source has the given GUID.
+func (i *Index) BySource(guid string) []*CombatLogRecord {
+	return i.bySource[guid]
+}
+
+// ByTarget returns the records whose target has the given GUID.
+func (i *Index) ByTarget(guid string) []*CombatLogRecord {
+	return i.byTarget[guid]
+}
+
+// BySpell returns the records that reference the spell, either as the spell
+// of the event or as the interrupted, dispelled or stolen spell.
+func (i *Index) BySpell(id uint64) []*CombatLogRecord {
+	return i.bySpell[id]
+}
+
+// Sources returns the GUIDs of every unit that sourced an event.
+func (i *Index) Sources() []string {
+	out := make([]string, 0, len(i.bySource))
+	for guid := range i.bySource {
+		out = append(out, guid)
+	}
+	return out
+}
+
+// Targets returns the GUIDs of every unit that was the target of an event.
+func (i *Index) Targets() []string {
+	out := make([]string, 0, len(i.byTarget))
+	for guid := range i.byTarget {
+		out = append(out, guid)
+	}
+	return out
+}
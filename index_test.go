@@ -0,0 +1,55 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "testing"
+
+func TestIndex(t *testing.T) {
+	data, err := newTestParser().Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := NewIndex(data)
+
+	const marrowgar = "0xF130008F0400003D"
+	var wantTarget, wantKick int
+	for _, r := range data {
+		if r.TargetID == marrowgar {
+			wantTarget++
+		}
+		if r.InterruptSuffix != nil && r.InterruptSuffix.ExtraSpellID == 71029 {
+			wantKick++
+		}
+	}
+	if got := len(idx.ByTarget(marrowgar)); got != wantTarget {
+		t.Errorf("expected %d records targeting Marrowgar, got %d", wantTarget, got)
+	}
+	for _, r := range idx.BySource("0x07000000007721EC") {
+		if r.SourceName != "Yogzar" {
+			t.Fatalf("unexpected source in index: %s", r.SourceName)
+		}
+	}
+	interrupted := 0
+	for _, r := range idx.BySpell(71029) {
+		if r.EventType == SpellInterrupt {
+			interrupted++
+		}
+	}
+	if interrupted != wantKick {
+		t.Errorf("expected %d interrupts of Glacial Blast, got %d", wantKick, interrupted)
+	}
+}
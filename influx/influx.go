@@ -0,0 +1,193 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package influx writes damage and healing over time into InfluxDB as time
+// series points tagged with source, spell and encounter, so Grafana
+// dashboards can chart raid performance live or after the fact. It uses the
+// InfluxDB 2 write API, which InfluxDB 1.8 and later also serve.
+package influx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// SinkFunc is a function that accepts a pointer to a Sink to be used in the
+// options variadic function in `NewSink`.
+type SinkFunc func(*Sink)
+
+// Sink writes SeriesPoints into an InfluxDB bucket.
+type Sink struct {
+	// URL is the address of the InfluxDB server.
+	URL    string
+	Org    string
+	Bucket string
+	// Token is sent as the API token. For InfluxDB 1.8 use
+	// "username:password".
+	Token string
+	// Interval is the bucket width Write aggregates records into.
+	Interval time.Duration
+	// BatchSize is the number of points sent per request.
+	BatchSize int
+	Client    *http.Client
+}
+
+// WithToken sets the API token requests authenticate with.
+func WithToken(token string) SinkFunc {
+	return func(s *Sink) {
+		s.Token = token
+	}
+}
+
+// WithInterval sets the bucket width Write aggregates records into.
+func WithInterval(d time.Duration) SinkFunc {
+	return func(s *Sink) {
+		s.Interval = d
+	}
+}
+
+// WithBatchSize sets the number of points sent per request.
+func WithBatchSize(n int) SinkFunc {
+	return func(s *Sink) {
+		s.BatchSize = n
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for writes.
+func WithHTTPClient(c *http.Client) SinkFunc {
+	return func(s *Sink) {
+		s.Client = c
+	}
+}
+
+// NewSink initializes and allocates a Sink writing to the bucket of the
+// organization on the InfluxDB server at u and applies any SinkFunc
+// options.
+func NewSink(u, org, bucket string, opts ...SinkFunc) *Sink {
+	s := &Sink{
+		URL:       u,
+		Org:       org,
+		Bucket:    bucket,
+		Interval:  5 * time.Second,
+		BatchSize: 5000,
+		Client:    http.DefaultClient,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// Write aggregates the records into points of Interval width and writes
+// them. Points with the same time and tags replace each other, so records
+// of the same interval must be passed to a single Write; when tailing a log,
+// feed a frostparse.SeriesAggregator and pass its points to WritePoints
+// instead.
+func (s *Sink) Write(ctx context.Context, records []*frostparse.CombatLogRecord) error {
+	a := frostparse.NewSeriesAggregator(frostparse.WithSeriesInterval(s.Interval))
+	return s.WritePoints(ctx, a.Run(records))
+}
+
+// WritePoints writes the points in batches of BatchSize.
+func (s *Sink) WritePoints(ctx context.Context, points []frostparse.SeriesPoint) error {
+	size := max(s.BatchSize, 1)
+	for start := 0; start < len(points); start += size {
+		var buf bytes.Buffer
+		if err := WriteLineProtocol(&buf, points[start:min(start+size, len(points))]); err != nil {
+			return err
+		}
+		if err := s.post(ctx, &buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Sink) post(ctx context.Context, body io.Reader) error {
+	q := url.Values{
+		"org":       {s.Org},
+		"bucket":    {s.Bucket},
+		"precision": {"ms"},
+	}
+	u := strings.TrimRight(s.URL, "/") + "/api/v2/write?" + q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Token "+s.Token)
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("influx: write failed with status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// WriteLineProtocol writes the points in InfluxDB line protocol with
+// millisecond timestamps. The measurement is the point's kind, and tags with
+// an empty value are left out.
+func WriteLineProtocol(w io.Writer, points []frostparse.SeriesPoint) error {
+	var b []byte
+	for _, p := range points {
+		b = append(b[:0], measurementEscaper.Replace(string(p.Kind))...)
+		for _, tag := range [...][2]string{{"encounter", p.Encounter}, {"source", p.Source}, {"spell", p.Spell}} {
+			if tag[1] != "" {
+				b = append(b, ',')
+				b = append(b, tag[0]...)
+				b = append(b, '=')
+				b = append(b, tagEscaper.Replace(tag[1])...)
+			}
+		}
+		b = append(b, " amount="...)
+		b = strconv.AppendUint(b, p.Amount, 10)
+		b = append(b, "i,hits="...)
+		b = strconv.AppendInt(b, int64(p.Hits), 10)
+		b = append(b, 'i')
+		if p.Kind == frostparse.SeriesHealing {
+			b = append(b, ",overhealing="...)
+			b = strconv.AppendUint(b, p.Overhealing, 10)
+			b = append(b, 'i')
+		}
+		b = append(b, ' ')
+		b = strconv.AppendInt(b, p.Time.UnixMilli(), 10)
+		b = append(b, '\n')
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
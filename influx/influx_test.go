@@ -0,0 +1,88 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package influx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+func TestSinkWrite(t *testing.T) {
+	data, err := frostparse.New(
+		frostparse.WithReader(strings.NewReader(strings.Join([]string{
+			`12/11 01:08:00.000  SPELL_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47610,"Frostfire Bolt",0x14,9000,0,16,0,0,0,nil,nil,nil`,
+			`12/11 01:08:01.000  SPELL_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47610,"Frostfire Bolt",0x14,8000,0,16,0,0,0,nil,nil,nil`,
+			`12/11 01:08:02.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,3000,500,0,nil`,
+		}, "\n"))),
+		frostparse.WithLogYear(2010),
+		frostparse.WithStrictMode(true),
+	).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/write" || r.URL.Query().Get("bucket") != "raids" || r.URL.Query().Get("precision") != "ms" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if r.Header.Get("Authorization") != "Token tok" {
+			t.Errorf("unexpected authorization header %q", r.Header.Get("Authorization"))
+		}
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sink := NewSink(srv.URL, "guild", "raids", WithToken("tok"), WithBatchSize(1))
+	if err := sink.Write(context.Background(), data); err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("expected 2 batches, got %q", bodies)
+	}
+	ts := data[0].Timestamp.UnixMilli()
+	want := []string{
+		"damage,encounter=Lord\\ Marrowgar,source=Winterinjuly,spell=Frostfire\\ Bolt amount=17000i,hits=2i " + strconv.FormatInt(ts, 10) + "\n",
+		"healing,encounter=Lord\\ Marrowgar,source=Yogzar,spell=Riptide amount=3000i,hits=1i,overhealing=500i " + strconv.FormatInt(ts, 10) + "\n",
+	}
+	for i := range want {
+		if bodies[i] != want[i] {
+			t.Errorf("expected %q, got %q", want[i], bodies[i])
+		}
+	}
+}
+
+func TestSinkWriteError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"code":"not found","message":"bucket \"raids\" not found"}`, http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	points := []frostparse.SeriesPoint{{Kind: frostparse.SeriesDamage, Source: "Winterinjuly", Amount: 1, Hits: 1}}
+	err := NewSink(srv.URL, "guild", "raids").WritePoints(context.Background(), points)
+	if err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("expected the server error, got %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "sort"
+
+// InteractionKind is what flows along an edge of an InteractionGraph.
+type InteractionKind string
+
+const (
+	// InteractionDamage is damage done by the source to the target.
+	InteractionDamage InteractionKind = "damage"
+	// InteractionHealing is effective healing done by the source to the
+	// target, excluding overhealing.
+	InteractionHealing InteractionKind = "healing"
+)
+
+// GraphNode is a unit in an InteractionGraph. Units are keyed by name, so
+// every add of the same name is a single node. Kind is one of "player",
+// "pet", "npc" or "boss".
+type GraphNode struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// GraphEdge is the damage or healing one unit did to another.
+type GraphEdge struct {
+	Source string          `json:"source"`
+	Target string          `json:"target"`
+	Kind   InteractionKind `json:"kind"`
+	Amount uint64          `json:"amount"`
+	Hits   int             `json:"hits"`
+}
+
+// InteractionGraph is the network of damage and healing between the units
+// of one encounter. Nodes are sorted by name and edges by source, target and
+// kind.
+type InteractionGraph struct {
+	Encounter string      `json:"encounter"`
+	Attempt   int         `json:"attempt"`
+	Nodes     []GraphNode `json:"nodes"`
+	Edges     []GraphEdge `json:"edges"`
+}
+
+// InteractionAnalyzerFunc is an option for NewInteractionAnalyzer.
+type InteractionAnalyzerFunc func(*InteractionAnalyzer)
+
+// InteractionAnalyzer builds the interaction graph of each boss attempt.
+type InteractionAnalyzer struct {
+	// Splitter slices the log into encounters.
+	Splitter *EncounterSplitter
+	// MinAmount drops edges with a smaller total amount, to keep the graph of
+	// a large raid readable.
+	MinAmount uint64
+}
+
+// WithInteractionSplitter sets the splitter the log is sliced into
+// encounters with.
+func WithInteractionSplitter(s *EncounterSplitter) InteractionAnalyzerFunc {
+	return func(a *InteractionAnalyzer) {
+		a.Splitter = s
+	}
+}
+
+// WithMinEdgeAmount drops edges that total less than amount.
+func WithMinEdgeAmount(amount uint64) InteractionAnalyzerFunc {
+	return func(a *InteractionAnalyzer) {
+		a.MinAmount = amount
+	}
+}
+
+// NewInteractionAnalyzer initializes, allocates and returns a pointer to an
+// InteractionAnalyzer.
+func NewInteractionAnalyzer(opts ...InteractionAnalyzerFunc) *InteractionAnalyzer {
+	a := &InteractionAnalyzer{
+		Splitter: NewEncounterSplitter(),
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+// Run returns the interaction graph of every boss attempt in the records.
+// Damage and healing done by pets whose owner is known is credited to the
+// owner.
+func (a *InteractionAnalyzer) Run(data []*CombatLogRecord) []InteractionGraph {
+	pets := newPetTracker()
+	bosses := bossRegistryOrDefault(a.Splitter.Bosses)
+	encounters := a.Splitter.Split(data)
+	out := make([]InteractionGraph, 0, len(encounters))
+	next := 0
+	for _, e := range encounters {
+		// the records of an encounter are a run of data, and pets summoned
+		// before the pull are tracked from the records leading up to it
+		for len(e.Records) > 0 && next < len(data) && data[next] != e.Records[0] {
+			pets.observe(*data[next])
+			next++
+		}
+		next += len(e.Records)
+		g := a.graph(e.Records, pets, bosses)
+		g.Encounter = e.Name
+		g.Attempt = e.Attempt
+		out = append(out, g)
+	}
+	return out
+}
+
+type edgeKey struct {
+	source, target string
+	kind           InteractionKind
+}
+
+// graph builds the interaction graph of the records of one encounter.
+func (a *InteractionAnalyzer) graph(data []*CombatLogRecord, pets *petTracker, bosses *BossRegistry) InteractionGraph {
+	nodes := map[string]string{}
+	edges := map[edgeKey]*GraphEdge{}
+	unit := func(guid, name string) string {
+		kind := unitKind(guid)
+		if owner, ok := pets.owner(guid); ok {
+			name, kind = owner, "player"
+		} else if _, ok := bosses.Match(name, guid); ok {
+			kind = "boss"
+		}
+		if _, ok := nodes[name]; !ok {
+			nodes[name] = kind
+		}
+		return name
+	}
+	for _, row := range data {
+		pets.observe(*row)
+		var kind InteractionKind
+		var amount uint64
+		switch {
+		case row.DamageSuffix != nil && isDamageEvent(*row):
+			kind, amount = InteractionDamage, row.DamageSuffix.Amount
+		case row.HealSuffix != nil && isHealingEvent(*row) && row.HealSuffix.Amount > row.HealSuffix.Overhealing:
+			kind, amount = InteractionHealing, row.HealSuffix.Amount-row.HealSuffix.Overhealing
+		default:
+			continue
+		}
+		if amount == 0 || row.SourceName == "" || row.TargetName == "" {
+			continue
+		}
+		k := edgeKey{unit(row.SourceID, row.SourceName), unit(row.TargetID, row.TargetName), kind}
+		e, ok := edges[k]
+		if !ok {
+			e = &GraphEdge{Source: k.source, Target: k.target, Kind: kind}
+			edges[k] = e
+		}
+		e.Amount += amount
+		e.Hits++
+	}
+
+	g := InteractionGraph{Edges: make([]GraphEdge, 0, len(edges))}
+	linked := map[string]bool{}
+	for _, e := range edges {
+		if e.Amount < a.MinAmount {
+			continue
+		}
+		g.Edges = append(g.Edges, *e)
+		linked[e.Source] = true
+		linked[e.Target] = true
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		x, y := g.Edges[i], g.Edges[j]
+		if x.Source != y.Source {
+			return x.Source < y.Source
+		}
+		if x.Target != y.Target {
+			return x.Target < y.Target
+		}
+		return x.Kind < y.Kind
+	})
+	g.Nodes = make([]GraphNode, 0, len(linked))
+	for name := range linked {
+		g.Nodes = append(g.Nodes, GraphNode{Name: name, Kind: nodes[name]})
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].Name < g.Nodes[j].Name
+	})
+	return g
+}
+
+// unitKind classifies a GUID as a player, pet, boss or other NPC. Most
+// bosses have NPC GUIDs and are only recognized by the boss registry.
+func unitKind(guid string) string {
+	switch {
+	case isPlayerID(guid):
+		return "player"
+	case isPetID(guid):
+		return "pet"
+	case isBossID(guid):
+		return "boss"
+	}
+	return "npc"
+}
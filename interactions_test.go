@@ -0,0 +1,59 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInteractionAnalyzerRun(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:07:00.000  SPELL_SUMMON,0x07000000009DF7A8,"Winterinjuly",0x514,0xF140000000000001,"Army of the Dead",0xa28,42651,"Army of the Dead",0x1`,
+		`12/11 01:08:14.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:15.000  SWING_DAMAGE,0xF140000000000001,"Army of the Dead",0xa28,0xF130008F0400003D,"Lord Marrowgar",0x10a48,50,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:16.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,4000,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:17.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,3000,1000,0,nil`,
+		`12/11 01:08:18.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,500,500,0,nil`,
+		`12/11 01:08:19.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000007721EC,"Yogzar",0x511,10,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:20.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F0400003D,"Lord Marrowgar",0x10a48`,
+	)
+	graphs := NewInteractionAnalyzer(WithMinEdgeAmount(20)).Run(data)
+	if len(graphs) != 1 {
+		t.Fatalf("expected 1 graph, got %d", len(graphs))
+	}
+	g := graphs[0]
+	if g.Encounter != "Lord Marrowgar" || g.Attempt != 1 {
+		t.Errorf("unexpected encounter %s #%d", g.Encounter, g.Attempt)
+	}
+	wantNodes := []GraphNode{
+		{Name: "Lord Marrowgar", Kind: "boss"},
+		{Name: "Winterinjuly", Kind: "player"},
+		{Name: "Yogzar", Kind: "player"},
+	}
+	if !reflect.DeepEqual(g.Nodes, wantNodes) {
+		t.Errorf("expected nodes %v, got %v", wantNodes, g.Nodes)
+	}
+	wantEdges := []GraphEdge{
+		{Source: "Lord Marrowgar", Target: "Winterinjuly", Kind: InteractionDamage, Amount: 4000, Hits: 1},
+		{Source: "Winterinjuly", Target: "Lord Marrowgar", Kind: InteractionDamage, Amount: 150, Hits: 2},
+		{Source: "Yogzar", Target: "Winterinjuly", Kind: InteractionHealing, Amount: 2000, Hits: 1},
+	}
+	if !reflect.DeepEqual(g.Edges, wantEdges) {
+		t.Errorf("expected edges %v, got %v", wantEdges, g.Edges)
+	}
+}
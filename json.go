@@ -0,0 +1,267 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// recordJSON is the flattened JSON form of a CombatLogRecord. Fields of a
+// prefix or suffix the record does not have are nil and omitted. Every
+// suffix writes at least one field no other suffix shares, so UnmarshalJSON
+// can tell them apart: overkill for damage, overhealing for heals,
+// extra_amount for leeches and drains, power_type for energizes, miss_type
+// for misses, extra_spell_id for interrupts, and extra_spell_id with
+// aura_type for dispels and steals.
+type recordJSON struct {
+	Timestamp         time.Time  `json:"timestamp"`
+	AdjustedTimestamp *time.Time `json:"adjusted_timestamp,omitempty"`
+	EventType         string     `json:"event_type"`
+	SourceID          string     `json:"source_id"`
+	SourceName        string     `json:"source_name"`
+	SourceFlags       UnitFlags  `json:"source_flags"`
+	TargetID          string     `json:"target_id"`
+	TargetName        string     `json:"target_name"`
+	TargetFlags       UnitFlags  `json:"target_flags"`
+
+	SpellID           *uint64            `json:"spell_id,omitempty"`
+	SpellName         *string            `json:"spell_name,omitempty"`
+	SpellSchool       *SpellSchool       `json:"spell_school,omitempty"`
+	ItemID            *uint64            `json:"item_id,omitempty"`
+	ItemName          *string            `json:"item_name,omitempty"`
+	EnvironmentalType *EnvironmentalType `json:"environmental_type,omitempty"`
+
+	Amount           *int64       `json:"amount,omitempty"`
+	Overkill         *uint64      `json:"overkill,omitempty"`
+	School           *SpellSchool `json:"school,omitempty"`
+	Resisted         *uint64      `json:"resisted,omitempty"`
+	Blocked          *uint64      `json:"blocked,omitempty"`
+	Absorbed         *uint64      `json:"absorbed,omitempty"`
+	Overhealing      *uint64      `json:"overhealing,omitempty"`
+	Critical         *bool        `json:"critical,omitempty"`
+	Glancing         *bool        `json:"glancing,omitempty"`
+	Crushing         *bool        `json:"crushing,omitempty"`
+	MissType         *MissType    `json:"miss_type,omitempty"`
+	OffHand          *bool        `json:"off_hand,omitempty"`
+	AuraType         *AuraType    `json:"aura_type,omitempty"`
+	Doses            *uint64      `json:"doses,omitempty"`
+	PowerType        *PowerType   `json:"power_type,omitempty"`
+	ExtraAmount      *uint64      `json:"extra_amount,omitempty"`
+	ExtraSpellID     *uint64      `json:"extra_spell_id,omitempty"`
+	ExtraSpellName   *string      `json:"extra_spell_name,omitempty"`
+	ExtraSpellSchool *SpellSchool `json:"extra_spell_school,omitempty"`
+
+	Raw *[]string `json:"raw,omitempty"`
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// MarshalJSON implements json.Marshaler. The record is written as a single
+// flat object with the event type as a string, leaving out the fields of
+// prefixes and suffixes the event does not have instead of writing them as
+// null.
+func (r CombatLogRecord) MarshalJSON() ([]byte, error) {
+	j := recordJSON{
+		Timestamp:   r.Timestamp,
+		EventType:   string(r.EventType),
+		SourceID:    r.SourceID,
+		SourceName:  r.SourceName,
+		SourceFlags: r.SourceFlags,
+		TargetID:    r.TargetID,
+		TargetName:  r.TargetName,
+		TargetFlags: r.TargetFlags,
+	}
+	if !r.AdjustedTimestamp.IsZero() && !r.AdjustedTimestamp.Equal(r.Timestamp) {
+		j.AdjustedTimestamp = ptr(r.AdjustedTimestamp)
+	}
+	if p := r.SpellAndRangePrefix; p != nil {
+		j.SpellID = ptr(p.SpellID)
+		j.SpellName = ptr(p.SpellName)
+		j.SpellSchool = ptr(p.SpellSchool)
+	}
+	if p := r.EnchantPrefix; p != nil {
+		j.SpellName = ptr(p.SpellName)
+		j.ItemID = ptr(p.ItemID)
+		j.ItemName = ptr(p.ItemName)
+	}
+	if p := r.EnvironmentalPrefix; p != nil {
+		j.EnvironmentalType = ptr(p.EnvironmentalType)
+	}
+	if s := r.DamageSuffix; s != nil {
+		j.Amount = ptr(int64(s.Amount))
+		j.Overkill = ptr(s.Overkill)
+		j.School = ptr(s.SpellSchool)
+		j.Resisted = ptr(s.Resisted)
+		j.Blocked = ptr(s.Blocked)
+		j.Absorbed = ptr(s.Absorbed)
+		j.Critical = ptr(s.Critical)
+		j.Glancing = ptr(s.Glancing)
+		j.Crushing = ptr(s.Crushing)
+	}
+	if s := r.HealSuffix; s != nil {
+		j.Amount = ptr(int64(s.Amount))
+		j.Overhealing = ptr(s.Overhealing)
+		j.Absorbed = ptr(s.Absorbed)
+		j.Critical = ptr(s.Critical)
+	}
+	if s := r.EnergizeSuffix; s != nil {
+		j.Amount = ptr(s.Amount)
+		j.PowerType = ptr(s.PowerType)
+	}
+	if s := r.ExtraAttacksSuffix; s != nil {
+		j.Amount = ptr(int64(s.Amount))
+	}
+	if s := r.LeechOrDrainSuffix; s != nil {
+		j.Amount = ptr(int64(s.Amount))
+		j.PowerType = ptr(s.PowerType)
+		j.ExtraAmount = ptr(s.ExtraAmount)
+	}
+	if s := r.MissSuffix; s != nil {
+		j.MissType = ptr(s.MissType)
+		if s.IsOffHand {
+			j.OffHand = ptr(true)
+		}
+		if s.Amount > 0 {
+			j.Amount = ptr(int64(s.Amount))
+		}
+	}
+	if s := r.AuraSuffix; s != nil {
+		j.AuraType = ptr(s.AuraType)
+		if s.Doses > 0 {
+			j.Doses = ptr(s.Doses)
+		}
+	}
+	if s := r.InterruptSuffix; s != nil {
+		j.ExtraSpellID = ptr(s.ExtraSpellID)
+		j.ExtraSpellName = ptr(s.ExtraSpellName)
+		j.ExtraSpellSchool = ptr(s.ExtraSpellSchool)
+	}
+	if s := r.DispelOrStolenSuffix; s != nil {
+		j.ExtraSpellID = ptr(s.ExtraSpellID)
+		j.ExtraSpellName = ptr(s.ExtraSpellName)
+		j.ExtraSpellSchool = ptr(s.ExtraSpellSchool)
+		j.AuraType = ptr(s.AuraType)
+	}
+	if r.Raw != nil {
+		j.Raw = ptr(r.Raw.Fields)
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, reading the object written by
+// MarshalJSON back into a record with the same prefix and suffix pointers
+// set.
+func (r *CombatLogRecord) UnmarshalJSON(b []byte) error {
+	var j recordJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	out := CombatLogRecord{
+		BaseCombatEvent: BaseCombatEvent{
+			Timestamp:         j.Timestamp,
+			AdjustedTimestamp: j.Timestamp,
+			EventType:         EventType(j.EventType),
+			SourceID:          j.SourceID,
+			SourceName:        j.SourceName,
+			SourceFlags:       j.SourceFlags,
+			TargetID:          j.TargetID,
+			TargetName:        j.TargetName,
+			TargetFlags:       j.TargetFlags,
+		},
+	}
+	if j.AdjustedTimestamp != nil {
+		out.AdjustedTimestamp = *j.AdjustedTimestamp
+	}
+	switch {
+	case j.ItemID != nil:
+		out.EnchantPrefix = &EnchantPrefix{
+			SpellName: deref(j.SpellName),
+			ItemID:    *j.ItemID,
+			ItemName:  deref(j.ItemName),
+		}
+	case j.SpellID != nil:
+		out.SpellAndRangePrefix = &SpellAndRangePrefix{
+			SpellID:     *j.SpellID,
+			SpellName:   deref(j.SpellName),
+			SpellSchool: deref(j.SpellSchool),
+		}
+	}
+	if j.EnvironmentalType != nil {
+		out.EnvironmentalPrefix = &EnvironmentalPrefix{EnvironmentalType: *j.EnvironmentalType}
+	}
+	amount := deref(j.Amount)
+	switch {
+	case j.Overkill != nil:
+		out.DamageSuffix = &DamageSuffix{
+			Amount:      uint64(amount),
+			Overkill:    *j.Overkill,
+			SpellSchool: deref(j.School),
+			Resisted:    deref(j.Resisted),
+			Blocked:     deref(j.Blocked),
+			Absorbed:    deref(j.Absorbed),
+			Critical:    deref(j.Critical),
+			Glancing:    deref(j.Glancing),
+			Crushing:    deref(j.Crushing),
+		}
+	case j.Overhealing != nil:
+		out.HealSuffix = &HealSuffix{
+			Amount:      uint64(amount),
+			Overhealing: *j.Overhealing,
+			Absorbed:    deref(j.Absorbed),
+			Critical:    deref(j.Critical),
+		}
+	case j.ExtraAmount != nil:
+		out.LeechOrDrainSuffix = &LeechOrDrainSuffix{
+			Amount:      uint64(amount),
+			PowerType:   deref(j.PowerType),
+			ExtraAmount: *j.ExtraAmount,
+		}
+	case j.PowerType != nil:
+		out.EnergizeSuffix = &EnergizeSuffix{Amount: amount, PowerType: *j.PowerType}
+	case j.MissType != nil:
+		out.MissSuffix = &MissSuffix{
+			MissType:  *j.MissType,
+			IsOffHand: deref(j.OffHand),
+			Amount:    uint64(amount),
+		}
+	case j.ExtraSpellID != nil && j.AuraType != nil:
+		out.DispelOrStolenSuffix = &DispelOrStolenSuffix{
+			ExtraSpellID:     *j.ExtraSpellID,
+			ExtraSpellName:   deref(j.ExtraSpellName),
+			ExtraSpellSchool: deref(j.ExtraSpellSchool),
+			AuraType:         *j.AuraType,
+		}
+	case j.ExtraSpellID != nil:
+		out.InterruptSuffix = &InterruptSuffix{
+			ExtraSpellID:     *j.ExtraSpellID,
+			ExtraSpellName:   deref(j.ExtraSpellName),
+			ExtraSpellSchool: deref(j.ExtraSpellSchool),
+		}
+	case j.AuraType != nil:
+		out.AuraSuffix = &AuraSuffix{AuraType: *j.AuraType, Doses: deref(j.Doses)}
+	case j.Amount != nil:
+		out.ExtraAttacksSuffix = &ExtraAttacksSuffix{Amount: uint64(amount)}
+	}
+	if j.Raw != nil {
+		out.Raw = &RawEvent{Fields: *j.Raw}
+	}
+	*r = out
+	return nil
+}
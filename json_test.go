@@ -0,0 +1,73 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCombatLogRecordJSONRoundTrip(t *testing.T) {
+	want, err := newTestParser().Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = append(want, parseTestLines(t,
+		`12/11 00:13:39.000  SPELL_ABSORBED,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,69146,"Coldflame",0x10,"Power Word: Shield",1200`,
+		`12/11 00:13:40.000  SPELL_DISPEL,0x07000000009DF7A8,"Winterinjuly",0x514,0x070000000047DAB8,"Raddyboy",0x514,988,"Dispel Magic",0x2,69065,"Impaled",0x1,DEBUFF`,
+		`12/11 00:13:41.000  SPELL_INTERRUPT,0x070000000047DAB8,"Raddyboy",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47528,"Mind Freeze",0x10,69075,"Bone Storm",0x1`,
+		`12/11 00:13:42.000  SWING_MISSED,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x070000000047DAB8,"Raddyboy",0x514,ABSORB,1500`,
+	)...)
+	for i, rec := range want {
+		b, err := json.Marshal(rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(b), "null") {
+			t.Fatalf("record %d: expected no null fields, got %s", i, b)
+		}
+		var got CombatLogRecord
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(&got, rec) {
+			t.Fatalf("record %d does not round trip through %s:\nwant %+v\ngot  %+v", i, b, rec, got)
+		}
+	}
+}
+
+func TestCombatLogRecordMarshalJSON(t *testing.T) {
+	rec := mustParseRow(t, `12/11 00:13:38.500  SPELL_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130009093000102,"The Damned",0xa48,49909,"Icy Touch",0x10,5306,0,16,0,0,0,1,nil,nil`)
+	b, err := json.Marshal(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["event_type"] != "SPELL_DAMAGE" || m["spell_name"] != "Icy Touch" || m["school"] != "Frost" || m["critical"] != true {
+		t.Errorf("unexpected flattened record %s", b)
+	}
+	for _, key := range []string{"overhealing", "miss_type", "aura_type", "extra_spell_id", "raw"} {
+		if _, ok := m[key]; ok {
+			t.Errorf("expected %s to be omitted, got %s", key, b)
+		}
+	}
+}
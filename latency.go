@@ -0,0 +1,176 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"sort"
+	"time"
+)
+
+// LatencySpike is a cast that took noticeably longer than usual to land.
+type LatencySpike struct {
+	Time   time.Time     `json:"time"`
+	Source string        `json:"source"`
+	Delay  time.Duration `json:"delay"`
+}
+
+// SpellLatency summarizes the delay between the successful cast of a spell
+// and its first hit or miss. For projectiles it is the travel time plus any
+// server latency; for instant spells it should be close to zero.
+type SpellLatency struct {
+	SpellID uint64        `json:"spell_id"`
+	Samples int           `json:"samples"`
+	Min     time.Duration `json:"min"`
+	Max     time.Duration `json:"max"`
+	Mean    time.Duration `json:"mean"`
+	Median  time.Duration `json:"median"`
+	// Batched counts hits that landed at the same timestamp as an earlier
+	// hit from the same caster, a sign of spell batching or "munching".
+	Batched int            `json:"batched"`
+	Spikes  []LatencySpike `json:"spikes"`
+
+	delays []time.Duration
+}
+
+// LatencyReport maps spell name to the latency of that spell.
+type LatencyReport map[string]*SpellLatency
+
+// LatencyAnalyzer pairs SPELL_CAST_SUCCESS events with the SPELL_DAMAGE or
+// SPELL_MISSED events they caused to measure cast-to-land delays.
+type LatencyAnalyzer struct {
+	// MaxDelay is how long a cast waits for its hit before it is assumed to
+	// have never landed.
+	MaxDelay time.Duration
+	// SpikeFactor flags casts that took longer than this multiple of the
+	// median delay of the spell.
+	SpikeFactor float64
+}
+
+// LatencyAnalyzerFunc is an option for NewLatencyAnalyzer.
+type LatencyAnalyzerFunc func(*LatencyAnalyzer)
+
+// WithMaxLatency sets how long a cast waits for its hit.
+func WithMaxLatency(d time.Duration) LatencyAnalyzerFunc {
+	return func(a *LatencyAnalyzer) {
+		a.MaxDelay = d
+	}
+}
+
+// WithSpikeFactor sets the multiple of the median delay above which a cast
+// is reported as a spike.
+func WithSpikeFactor(f float64) LatencyAnalyzerFunc {
+	return func(a *LatencyAnalyzer) {
+		a.SpikeFactor = f
+	}
+}
+
+// NewLatencyAnalyzer initializes, allocates and returns a pointer to a
+// LatencyAnalyzer.
+func NewLatencyAnalyzer(opts ...LatencyAnalyzerFunc) *LatencyAnalyzer {
+	a := &LatencyAnalyzer{
+		MaxDelay:    time.Second * 5,
+		SpikeFactor: 2,
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+// pendingCast is a cast waiting for its hit.
+type pendingCast struct {
+	at     time.Time
+	source string
+}
+
+// Run measures the delays of every spell cast by players or their pets.
+// Casts in flight are matched to hits first in, first out, so several
+// projectiles of the same spell may be airborne at once. Spells that hit
+// several targets are measured on the first hit only.
+func (a *LatencyAnalyzer) Run(data []*CombatLogRecord) LatencyReport {
+	out := LatencyReport{}
+	pending := map[castKey][]pendingCast{}
+	lastHit := map[castKey]time.Time{}
+	for _, rec := range data {
+		if rec.SpellAndRangePrefix == nil || !(isPlayerID(rec.SourceID) || isPetID(rec.SourceID)) {
+			continue
+		}
+		key := castKey{sourceID: rec.SourceID, spellID: rec.SpellAndRangePrefix.SpellID}
+		switch rec.EventType {
+		case SpellCastSuccess:
+			pending[key] = append(pending[key], pendingCast{at: rec.Timestamp, source: rec.SourceName})
+		case SpellDamage, SpellMissed:
+			queue := pending[key]
+			for len(queue) > 0 && rec.Timestamp.Sub(queue[0].at) > a.MaxDelay {
+				queue = queue[1:]
+			}
+			if len(queue) == 0 {
+				delete(pending, key)
+				continue
+			}
+			cast := queue[0]
+			pending[key] = queue[1:]
+			s, ok := out[rec.SpellAndRangePrefix.SpellName]
+			if !ok {
+				s = &SpellLatency{SpellID: rec.SpellAndRangePrefix.SpellID}
+				out[rec.SpellAndRangePrefix.SpellName] = s
+			}
+			if last, ok := lastHit[key]; ok && last.Equal(rec.Timestamp) {
+				s.Batched++
+			}
+			lastHit[key] = rec.Timestamp
+			s.delays = append(s.delays, rec.Timestamp.Sub(cast.at))
+			s.Spikes = append(s.Spikes, LatencySpike{Time: cast.at, Source: cast.source, Delay: rec.Timestamp.Sub(cast.at)})
+		}
+	}
+	for _, s := range out {
+		s.summarize(a.SpikeFactor)
+	}
+	return out
+}
+
+// summarize computes the statistics of the collected delays and keeps only
+// the spikes above factor times the median.
+func (s *SpellLatency) summarize(factor float64) {
+	s.Samples = len(s.delays)
+	if s.Samples == 0 {
+		return
+	}
+	sorted := make([]time.Duration, len(s.delays))
+	copy(sorted, s.delays)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	s.Min = sorted[0]
+	s.Max = sorted[len(sorted)-1]
+	s.Mean = total / time.Duration(len(sorted))
+	s.Median = sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		s.Median = (sorted[len(sorted)/2-1] + s.Median) / 2
+	}
+	threshold := time.Duration(float64(s.Median) * factor)
+	spikes := s.Spikes[:0]
+	for _, spike := range s.Spikes {
+		if s.Median > 0 && spike.Delay > threshold {
+			spikes = append(spikes, spike)
+		}
+	}
+	s.Spikes = spikes
+	s.delays = nil
+}
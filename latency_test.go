@@ -0,0 +1,73 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLatencyAnalyzerRun(t *testing.T) {
+	cast := func(ts string) string {
+		return ts + `  SPELL_CAST_SUCCESS,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47610,"Frostfire Bolt",0x14`
+	}
+	hit := func(ts string) string {
+		return ts + `  SPELL_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47610,"Frostfire Bolt",0x14,9000,0,16,0,0,0,nil,nil,nil`
+	}
+	data := parseTestLines(t,
+		cast("12/11 01:08:00.000"),
+		hit("12/11 01:08:00.500"),
+		cast("12/11 01:08:02.000"),
+		cast("12/11 01:08:02.200"),
+		hit("12/11 01:08:02.600"),
+		hit("12/11 01:08:02.600"),
+		cast("12/11 01:08:04.000"),
+		hit("12/11 01:08:05.500"),
+		cast("12/11 01:08:10.000"),
+		hit("12/11 01:08:20.000"),
+	)
+	report := NewLatencyAnalyzer().Run(data)
+	s, ok := report["Frostfire Bolt"]
+	if !ok {
+		t.Fatal("expected Frostfire Bolt latency")
+	}
+	if s.Samples != 4 {
+		t.Errorf("expected the cast that never landed to be dropped, got %d samples", s.Samples)
+	}
+	if s.Min != time.Millisecond*400 || s.Max != time.Millisecond*1500 || s.Median != time.Millisecond*550 {
+		t.Errorf("unexpected latency: min %s, median %s, max %s", s.Min, s.Median, s.Max)
+	}
+	if s.Batched != 1 {
+		t.Errorf("expected 1 batched hit, got %d", s.Batched)
+	}
+	if len(s.Spikes) != 1 || s.Spikes[0].Delay != time.Millisecond*1500 {
+		t.Errorf("expected the 1.5s cast to be a spike, got %v", s.Spikes)
+	}
+}
+
+func TestLatencyAnalyzerRunTestData(t *testing.T) {
+	data, err := newTestParser().Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for spell, s := range NewLatencyAnalyzer().Run(data) {
+		if s.Median > 0 {
+			fmt.Printf("%s: median %s over %d casts, %d spikes\n", spell, s.Median, s.Samples, len(s.Spikes))
+		}
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrLimitExceeded is wrapped by every *LimitError.
+var ErrLimitExceeded = errors.New("frostparse: limit exceeded")
+
+// Limit names reported in a LimitError.
+const (
+	LimitBytes      = "bytes"
+	LimitLines      = "lines"
+	LimitLineLength = "line length"
+	LimitDuration   = "duration"
+	LimitSkipped    = "skipped lines"
+)
+
+// LimitError is returned when parsing input exceeds one of the configured
+// Limits.
+type LimitError struct {
+	Limit string
+	Max   int64
+}
+
+// Error implements the error interface.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("frostparse: %s limit of %d exceeded", e.Limit, e.Max)
+}
+
+// Unwrap returns ErrLimitExceeded so that callers can match any limit with
+// errors.Is.
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// Limits bound the resources a single parse may use, for services that parse
+// untrusted uploads. A zero value for any field means no limit.
+type Limits struct {
+	// MaxBytes is the maximum size of the input. For compressed input it
+	// bounds both the compressed and the decompressed size.
+	MaxBytes int64
+	// MaxLines is the maximum number of lines, which also bounds the number
+	// of records allocated.
+	MaxLines int
+	// MaxLineLength is the maximum length of a single line, which bounds the
+	// scanner buffer. Lines longer than 64KiB are rejected when unset.
+	MaxLineLength int
+	// MaxDuration is the maximum wall clock time spent parsing.
+	MaxDuration time.Duration
+	// MaxSkipped is the maximum number of malformed lines retained in the
+	// ParseReport in lenient mode.
+	MaxSkipped int
+}
+
+// WithLimits sets the resource limits applied to every parse.
+func WithLimits(l Limits) ParserFunc {
+	return func(p *Parser) {
+		p.Limits = l
+	}
+}
+
+// WithMaxSkipped sets Limits.MaxSkipped, leaving the other limits as they
+// are.
+func WithMaxSkipped(n int) ParserFunc {
+	return func(p *Parser) {
+		p.Limits.MaxSkipped = n
+	}
+}
+
+// durationCheckInterval is how many lines are scanned between checks of the
+// MaxDuration limit, to keep time.Since off the hot path.
+const durationCheckInterval = 1024
+
+// limitReader fails with a LimitError instead of silently truncating once
+// more than max bytes have been read.
+type limitReader struct {
+	r    io.Reader
+	read int64
+	max  int64
+}
+
+func (l *limitReader) Read(b []byte) (int, error) {
+	n, err := l.r.Read(b)
+	l.read += int64(n)
+	if l.read > l.max {
+		return n, &LimitError{Limit: LimitBytes, Max: l.max}
+	}
+	return n, err
+}
+
+// reader wraps r so that reads fail past MaxBytes.
+func (l Limits) reader(r io.Reader) io.Reader {
+	if l.MaxBytes <= 0 {
+		return r
+	}
+	return &limitReader{r: r, max: l.MaxBytes}
+}
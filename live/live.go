@@ -0,0 +1,402 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package live pushes combat log records to WebSocket clients as they are
+// parsed, so browser overlays and meters can follow a raid in real time.
+//
+// A Broadcaster is both the http.Handler clients connect to and the callback
+// records are fed to, typically by a Parser tailing the log:
+//
+//	b := live.NewBroadcaster()
+//	l := frostparse.NewEventListener()
+//	l.OnAny(b.Broadcast)
+//	p := frostparse.New(frostparse.WithLogFile(path), frostparse.WithEventListener(l))
+//	go p.Tail(ctx)
+//	http.Handle("/live", b)
+//
+// Every record is sent as a text message holding its JSON form. Clients
+// choose the records they receive with query parameters when connecting,
+// and can change their choice by sending a Filter as a JSON text message.
+package live
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// Filter selects the records sent to a client. A record must match every
+// non-empty field, and any of the values of a field.
+type Filter struct {
+	Events []frostparse.EventType `json:"events,omitempty"`
+	// Sources and Targets match units by name or GUID.
+	Sources []string `json:"sources,omitempty"`
+	Targets []string `json:"targets,omitempty"`
+	// Units matches records with the unit as either source or target.
+	Units  []string `json:"units,omitempty"`
+	Spells []uint64 `json:"spells,omitempty"`
+}
+
+// FilterFromQuery reads a Filter from the comma separated query parameters
+// events, sources, targets, units and spells, e.g.
+// ?events=SPELL_DAMAGE,SWING_DAMAGE&sources=Winterinjuly.
+func FilterFromQuery(q url.Values) (Filter, error) {
+	var f Filter
+	for _, e := range splitQuery(q, "events") {
+		f.Events = append(f.Events, frostparse.EventType(strings.ToUpper(e)))
+	}
+	f.Sources = splitQuery(q, "sources")
+	f.Targets = splitQuery(q, "targets")
+	f.Units = splitQuery(q, "units")
+	for _, s := range splitQuery(q, "spells") {
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return Filter{}, fmt.Errorf("live: invalid spell ID %q", s)
+		}
+		f.Spells = append(f.Spells, id)
+	}
+	return f, nil
+}
+
+func splitQuery(q url.Values, key string) []string {
+	var out []string
+	for _, v := range q[key] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// Match reports whether the record passes the filter.
+func (f Filter) Match(rec frostparse.CombatLogRecord) bool {
+	if len(f.Events) > 0 && !contains(f.Events, rec.EventType) {
+		return false
+	}
+	if len(f.Sources) > 0 && !matchUnit(f.Sources, rec.SourceID, rec.SourceName) {
+		return false
+	}
+	if len(f.Targets) > 0 && !matchUnit(f.Targets, rec.TargetID, rec.TargetName) {
+		return false
+	}
+	if len(f.Units) > 0 && !matchUnit(f.Units, rec.SourceID, rec.SourceName) && !matchUnit(f.Units, rec.TargetID, rec.TargetName) {
+		return false
+	}
+	if len(f.Spells) > 0 && (rec.SpellAndRangePrefix == nil || !contains(f.Spells, rec.SpellAndRangePrefix.SpellID)) {
+		return false
+	}
+	return true
+}
+
+func contains[T comparable](s []T, v T) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func matchUnit(units []string, guid, name string) bool {
+	return contains(units, name) || contains(units, guid)
+}
+
+// BroadcasterFunc is an option for NewBroadcaster.
+type BroadcasterFunc func(*Broadcaster)
+
+// Broadcaster sends records to the WebSocket clients connected to it. It is
+// safe for concurrent use.
+type Broadcaster struct {
+	// Buffer is the number of messages queued for each client. Records for a
+	// client whose queue is full are dropped rather than holding up the
+	// parser.
+	Buffer int
+	// Origins are the Origin headers clients may connect from. Empty allows
+	// any origin, including overlays opened from a file.
+	Origins []string
+	// WriteTimeout is how long a write to a client may take before the
+	// client is disconnected.
+	WriteTimeout time.Duration
+
+	mu      sync.Mutex
+	clients map[*client]struct{}
+	closed  bool
+	dropped atomic.Uint64
+}
+
+// WithBuffer sets the number of messages queued for each client.
+func WithBuffer(n int) BroadcasterFunc {
+	return func(b *Broadcaster) {
+		b.Buffer = n
+	}
+}
+
+// WithAllowedOrigins restricts the origins clients may connect from.
+func WithAllowedOrigins(origins ...string) BroadcasterFunc {
+	return func(b *Broadcaster) {
+		b.Origins = origins
+	}
+}
+
+// WithWriteTimeout sets how long a write to a client may take.
+func WithWriteTimeout(d time.Duration) BroadcasterFunc {
+	return func(b *Broadcaster) {
+		b.WriteTimeout = d
+	}
+}
+
+// NewBroadcaster initializes and allocates a Broadcaster and applies any
+// BroadcasterFunc options. Each client gets a queue of 256 messages and
+// writes time out after 10 seconds by default.
+func NewBroadcaster(opts ...BroadcasterFunc) *Broadcaster {
+	b := &Broadcaster{
+		Buffer:       256,
+		WriteTimeout: time.Second * 10,
+		clients:      map[*client]struct{}{},
+	}
+	for _, o := range opts {
+		o(b)
+	}
+	return b
+}
+
+// client is a connected WebSocket client.
+type client struct {
+	conn    net.Conn
+	rw      *bufio.ReadWriter
+	filter  atomic.Pointer[Filter]
+	send    chan []byte
+	control chan frame
+	quit    chan struct{}
+	stop    sync.Once
+}
+
+func (c *client) close() {
+	c.stop.Do(func() { close(c.quit) })
+}
+
+// Broadcast sends the record to every client whose filter it matches. It
+// never blocks, so it can be registered directly as a callback with
+// EventListener.OnAny.
+func (b *Broadcaster) Broadcast(rec frostparse.CombatLogRecord) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var msg []byte
+	for c := range b.clients {
+		if !c.filter.Load().Match(rec) {
+			continue
+		}
+		if msg == nil {
+			var err error
+			if msg, err = json.Marshal(rec); err != nil {
+				return
+			}
+		}
+		select {
+		case c.send <- msg:
+		default:
+			b.dropped.Add(1)
+		}
+	}
+}
+
+// Clients returns the number of connected clients.
+func (b *Broadcaster) Clients() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.clients)
+}
+
+// Dropped returns the number of messages dropped because a client's queue
+// was full.
+func (b *Broadcaster) Dropped() uint64 {
+	return b.dropped.Load()
+}
+
+// Close disconnects every client and refuses new ones.
+func (b *Broadcaster) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for c := range b.clients {
+		c.close()
+	}
+	return nil
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection and streams
+// records to it until the client disconnects or the Broadcaster is closed.
+// The initial filter is read from the query parameters, see
+// FilterFromQuery.
+func (b *Broadcaster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, err := checkHandshake(r)
+	if err != nil {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if origin := r.Header.Get("Origin"); len(b.Origins) > 0 && !contains(b.Origins, origin) {
+		http.Error(w, fmt.Sprintf("live: origin %q not allowed", origin), http.StatusForbidden)
+		return
+	}
+	filter, err := FilterFromQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "live: connection cannot be upgraded", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+	if err := rw.Flush(); err != nil {
+		return
+	}
+	c := &client{
+		conn:    conn,
+		rw:      rw,
+		send:    make(chan []byte, max(b.Buffer, 1)),
+		control: make(chan frame, 4),
+		quit:    make(chan struct{}),
+	}
+	c.filter.Store(&filter)
+	if !b.add(c) {
+		b.writeFrame(c, closeFrame(closeGoingAway, "shutting down"))
+		return
+	}
+	defer b.remove(c)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.write(c)
+	}()
+	b.read(c)
+	c.close()
+	<-done
+}
+
+func (b *Broadcaster) add(c *client) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return false
+	}
+	b.clients[c] = struct{}{}
+	return true
+}
+
+func (b *Broadcaster) remove(c *client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.clients, c)
+}
+
+// read handles the messages of a client until it disconnects. Text messages
+// replace the client's filter.
+func (b *Broadcaster) read(c *client) {
+	reply := func(f frame) {
+		select {
+		case c.control <- f:
+		default:
+		}
+	}
+	for {
+		msg, err := readMessage(c.rw.Reader, reply)
+		var perr *protocolError
+		if errors.As(err, &perr) {
+			reply(closeFrame(perr.code, perr.reason))
+		}
+		if err != nil {
+			return
+		}
+		var f Filter
+		if err := json.Unmarshal(msg, &f); err != nil {
+			b.queue(c, errorMessage(fmt.Errorf("live: invalid filter: %w", err)))
+			continue
+		}
+		c.filter.Store(&f)
+	}
+}
+
+// queue sends a message to the client unless its queue is full.
+func (b *Broadcaster) queue(c *client, msg []byte) {
+	select {
+	case c.send <- msg:
+	default:
+		b.dropped.Add(1)
+	}
+}
+
+func errorMessage(err error) []byte {
+	msg, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return msg
+}
+
+// write sends the queued messages and control frames to the client until it
+// is closed, then says goodbye with a close frame.
+func (b *Broadcaster) write(c *client) {
+	for {
+		select {
+		case msg := <-c.send:
+			if b.writeFrame(c, frame{fin: true, op: opText, payload: msg}) != nil {
+				c.conn.Close()
+				return
+			}
+		case f := <-c.control:
+			if b.writeFrame(c, f) != nil || f.op == opClose {
+				c.conn.Close()
+				return
+			}
+		case <-c.quit:
+			// answer a close the client sent before falling back to ours
+			f := closeFrame(closeGoingAway, "shutting down")
+			select {
+			case f = <-c.control:
+			default:
+			}
+			b.writeFrame(c, f)
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+func (b *Broadcaster) writeFrame(c *client, f frame) error {
+	if b.WriteTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(b.WriteTimeout))
+	}
+	return writeFrame(c.rw.Writer, f, nil)
+}
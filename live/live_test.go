@@ -0,0 +1,172 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package live
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+	"github.com/bradleybonitatibus/frostparse/frostparsetest"
+)
+
+var testMask = []byte{1, 2, 3, 4}
+
+// dial opens a WebSocket connection to the test server.
+func dial(t *testing.T, srv *httptest.Server, query string) (net.Conn, *bufio.ReadWriter) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/live?"+query, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	resp, err := http.ReadResponse(rw.Reader, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected handshake response %d %v", resp.StatusCode, resp.Header)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return conn, rw
+}
+
+// waitClients waits until the broadcaster has n clients.
+func waitClients(t *testing.T, b *Broadcaster, n int) {
+	t.Helper()
+	for i := 0; b.Clients() != n; i++ {
+		if i == 500 {
+			t.Fatalf("expected %d clients, got %d", n, b.Clients())
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func readRecord(t *testing.T, rw *bufio.ReadWriter) frostparse.CombatLogRecord {
+	t.Helper()
+	f, _, err := readFrame(rw.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec frostparse.CombatLogRecord
+	if f.op != opText || json.Unmarshal(f.payload, &rec) != nil {
+		t.Fatalf("expected a record, got opcode %d %q", f.op, f.payload)
+	}
+	return rec
+}
+
+func TestBroadcaster(t *testing.T) {
+	b := NewBroadcaster()
+	srv := httptest.NewServer(b)
+	defer srv.Close()
+	_, rw := dial(t, srv, "events=swing_damage&sources=Winterinjuly")
+	waitClients(t, b, 1)
+
+	boss := frostparsetest.Boss("Lord Marrowgar")
+	mage, rogue := frostparsetest.Player("Winterinjuly"), frostparsetest.Player("Phokkwho")
+	bolt := frostparsetest.Spell{ID: 47610, Name: "Frostfire Bolt", School: frostparse.Frost | frostparse.Fire}
+	b.Broadcast(*frostparsetest.SpellDamage(frostparsetest.At(0), mage, boss, bolt, 9000))
+	b.Broadcast(*frostparsetest.SwingDamage(frostparsetest.At(time.Second), rogue, boss, 3000))
+	b.Broadcast(*frostparsetest.SwingDamage(frostparsetest.At(2*time.Second), mage, boss, 1000))
+	if rec := readRecord(t, rw); rec.EventType != frostparse.SwingDamage || rec.SourceName != "Winterinjuly" {
+		t.Errorf("expected only Winterinjuly's swing, got %+v", rec)
+	}
+
+	// a ping answered means the filter sent before it was applied
+	writeFrame(rw.Writer, frame{fin: true, op: opText, payload: []byte(`{"spells":[47610]}`)}, testMask)
+	writeFrame(rw.Writer, frame{fin: true, op: opPing, payload: []byte("hi")}, testMask)
+	if f, _, err := readFrame(rw.Reader); err != nil || f.op != opPong || string(f.payload) != "hi" {
+		t.Fatalf("expected a pong, got %+v %v", f, err)
+	}
+	b.Broadcast(*frostparsetest.SwingDamage(frostparsetest.At(3*time.Second), mage, boss, 1000))
+	b.Broadcast(*frostparsetest.SpellDamage(frostparsetest.At(4*time.Second), mage, boss, bolt, 9000))
+	if rec := readRecord(t, rw); rec.SpellAndRangePrefix == nil || rec.SpellAndRangePrefix.SpellName != "Frostfire Bolt" {
+		t.Errorf("expected the Frostfire Bolt after changing the filter, got %+v", rec)
+	}
+
+	b.Close()
+	f, _, err := readFrame(rw.Reader)
+	if err != nil || f.op != opClose || binary.BigEndian.Uint16(f.payload) != closeGoingAway {
+		t.Fatalf("expected a going away close frame, got %+v %v", f, err)
+	}
+	waitClients(t, b, 0)
+}
+
+func TestBroadcasterClientClose(t *testing.T) {
+	b := NewBroadcaster()
+	srv := httptest.NewServer(b)
+	defer srv.Close()
+	_, rw := dial(t, srv, "")
+	waitClients(t, b, 1)
+	writeFrame(rw.Writer, closeFrame(closeNormal, "bye"), testMask)
+	f, _, err := readFrame(rw.Reader)
+	if err != nil || f.op != opClose || binary.BigEndian.Uint16(f.payload) != closeNormal {
+		t.Fatalf("expected the close to be echoed, got %+v %v", f, err)
+	}
+	waitClients(t, b, 0)
+}
+
+func TestBroadcasterRejects(t *testing.T) {
+	b := NewBroadcaster(WithAllowedOrigins("https://overlay.example"))
+	w := httptest.NewRecorder()
+	b.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/live", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected a plain request to be rejected, got %d", w.Code)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/live", nil)
+	r.Header.Set("Connection", "keep-alive, Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	r.Header.Set("Sec-WebSocket-Version", "13")
+	r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	r.Header.Set("Origin", "https://evil.example")
+	w = httptest.NewRecorder()
+	b.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected an unknown origin to be rejected, got %d", w.Code)
+	}
+}
+
+func TestFilterFromQuery(t *testing.T) {
+	f, err := FilterFromQuery(url.Values{"events": {"spell_damage,SWING_DAMAGE"}, "units": {"Yogzar"}, "spells": {"61301"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Events) != 2 || f.Events[0] != frostparse.SpellDamage || f.Units[0] != "Yogzar" || f.Spells[0] != 61301 {
+		t.Errorf("unexpected filter %+v", f)
+	}
+	if _, err := FilterFromQuery(url.Values{"spells": {"riptide"}}); err == nil {
+		t.Error("expected an invalid spell ID to fail")
+	}
+}
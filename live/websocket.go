@@ -0,0 +1,282 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package live
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// The subset of RFC 6455 needed to push messages to browsers: the opening
+// handshake, unfragmented text frames from the server, and the text and
+// control frames a client sends.
+
+// websocketGUID is appended to the client's key to compute the accept key.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxMessageSize is the largest message accepted from a client. Clients only
+// send filters, so anything larger is a misbehaving peer.
+const maxMessageSize = 64 << 10
+
+type opcode byte
+
+const (
+	opContinuation opcode = 0x0
+	opText         opcode = 0x1
+	opBinary       opcode = 0x2
+	opClose        opcode = 0x8
+	opPing         opcode = 0x9
+	opPong         opcode = 0xA
+)
+
+// Close status codes sent to clients.
+const (
+	closeNormal    = 1000
+	closeGoingAway = 1001
+	closeProtocol  = 1002
+	closeTooBig    = 1009
+)
+
+// frame is a single WebSocket frame.
+type frame struct {
+	fin     bool
+	op      opcode
+	payload []byte
+}
+
+func (f frame) control() bool {
+	return f.op&0x8 != 0
+}
+
+// closeFrame returns a close frame with the status code and reason.
+func closeFrame(code uint16, reason string) frame {
+	p := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(p, code)
+	return frame{fin: true, op: opClose, payload: append(p, reason...)}
+}
+
+// errNotWebSocket is returned for requests that are not a WebSocket opening
+// handshake.
+var errNotWebSocket = errors.New("live: not a websocket handshake")
+
+// acceptKey returns the Sec-WebSocket-Accept value for the client's key.
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContains reports whether the comma separated header has the token.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkHandshake validates a WebSocket opening handshake and returns the
+// client's key.
+func checkHandshake(r *http.Request) (string, error) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		return "", errNotWebSocket
+	}
+	if v := r.Header.Get("Sec-WebSocket-Version"); v != "13" {
+		return "", fmt.Errorf("live: unsupported websocket version %q", v)
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 16 {
+		return "", fmt.Errorf("live: invalid websocket key %q", key)
+	}
+	return key, nil
+}
+
+// writeFrame writes a frame to w, masked with mask when it is not nil.
+// Servers never mask; clients always do.
+func writeFrame(w *bufio.Writer, f frame, mask []byte) error {
+	b0 := byte(f.op)
+	if f.fin {
+		b0 |= 0x80
+	}
+	var maskBit byte
+	if mask != nil {
+		maskBit = 0x80
+	}
+	header := []byte{b0, 0}
+	switch n := len(f.payload); {
+	case n < 126:
+		header[1] = maskBit | byte(n)
+	case n <= 0xFFFF:
+		header[1] = maskBit | 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = maskBit | 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	payload := f.payload
+	if mask != nil {
+		w.Write(mask)
+		payload = append([]byte(nil), payload...)
+		maskBytes(payload, mask)
+	}
+	if _, err := w.Write(payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// readFrame reads a frame from r, unmasking its payload. Frames longer than
+// maxMessageSize are rejected.
+func readFrame(r *bufio.Reader) (frame, bool, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return frame{}, false, err
+	}
+	f := frame{fin: head[0]&0x80 != 0, op: opcode(head[0] & 0x0F)}
+	if head[0]&0x70 != 0 {
+		return f, false, errProtocol("reserved bits set")
+	}
+	masked := head[1]&0x80 != 0
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return f, masked, err
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return f, masked, err
+		}
+		n = binary.BigEndian.Uint64(b[:])
+	}
+	if f.control() && (n > 125 || !f.fin) {
+		return f, masked, errProtocol("invalid control frame")
+	}
+	if n > maxMessageSize {
+		return f, masked, errTooBig
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return f, masked, err
+		}
+	}
+	f.payload = make([]byte, n)
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return f, masked, err
+	}
+	if masked {
+		maskBytes(f.payload, mask[:])
+	}
+	return f, masked, nil
+}
+
+func maskBytes(b, mask []byte) {
+	for i := range b {
+		b[i] ^= mask[i%4]
+	}
+}
+
+// protocolError is a violation of the protocol by the client, answered with
+// a close frame.
+type protocolError struct {
+	code   uint16
+	reason string
+}
+
+func (e *protocolError) Error() string {
+	return "live: websocket protocol error: " + e.reason
+}
+
+func errProtocol(reason string) error {
+	return &protocolError{code: closeProtocol, reason: reason}
+}
+
+var errTooBig = &protocolError{code: closeTooBig, reason: "message too big"}
+
+// readMessage reads the next text message from r, answering pings with
+// pongs through reply. It returns io.EOF once the client closes the
+// connection, after queueing the close frame echoing its status.
+func readMessage(r *bufio.Reader, reply func(frame)) ([]byte, error) {
+	var msg []byte
+	var op opcode
+	for {
+		f, masked, err := readFrame(r)
+		if err != nil {
+			return nil, err
+		}
+		if !masked {
+			return nil, errProtocol("unmasked client frame")
+		}
+		switch f.op {
+		case opPing:
+			reply(frame{fin: true, op: opPong, payload: f.payload})
+			continue
+		case opPong:
+			continue
+		case opClose:
+			code := uint16(closeNormal)
+			if len(f.payload) >= 2 {
+				code = binary.BigEndian.Uint16(f.payload)
+			}
+			reply(closeFrame(code, ""))
+			return nil, io.EOF
+		case opContinuation:
+			if op == 0 {
+				return nil, errProtocol("unexpected continuation frame")
+			}
+		case opText, opBinary:
+			if op != 0 {
+				return nil, errProtocol("expected a continuation frame")
+			}
+			op = f.op
+		default:
+			return nil, errProtocol(fmt.Sprintf("unknown opcode %d", f.op))
+		}
+		if len(msg)+len(f.payload) > maxMessageSize {
+			return nil, errTooBig
+		}
+		msg = append(msg, f.payload...)
+		if !f.fin {
+			continue
+		}
+		if op == opBinary {
+			// only text messages carry filters
+			msg, op = nil, 0
+			continue
+		}
+		return msg, nil
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "strings"
+
+// Locale translates the strings a non-English client writes to the combat
+// log into the canonical English values the rest of the package matches
+// against. The 3.3.5a client writes miss and aura types as English tokens in
+// every language, so the built-in locales only translate unit names, while
+// MissTypes and AuraTypes are there for logs that went through a translating
+// server or converter.
+type Locale struct {
+	// Name is the client locale, e.g. "deDE".
+	Name string
+	// MissTypes maps localized miss types to canonical ones such as DodgeMiss.
+	MissTypes map[string]MissType
+	// AuraTypes maps localized aura types to BuffAura and DebuffAura.
+	AuraTypes map[string]AuraType
+	// Units maps localized NPC names to English ones, so kills are detected
+	// and reported by their English names. Bosses are matched by NPC ID
+	// regardless of the locale.
+	Units map[string]string
+}
+
+// MissType returns the canonical miss type of a localized one.
+func (l *Locale) MissType(s MissType) MissType {
+	if v, ok := l.MissTypes[string(s)]; ok {
+		return v
+	}
+	return s
+}
+
+// AuraType returns the canonical aura type of a localized one.
+func (l *Locale) AuraType(s AuraType) AuraType {
+	if v, ok := l.AuraTypes[string(s)]; ok {
+		return v
+	}
+	return s
+}
+
+// UnitName returns the English name of a localized NPC name.
+func (l *Locale) UnitName(s string) string {
+	if v, ok := l.Units[s]; ok {
+		return v
+	}
+	return s
+}
+
+// localize rewrites the localized fields of a record to canonical values.
+// Player names are left alone, even if a player is named after an NPC.
+func (l *Locale) localize(c *CombatLogRecord) {
+	if !isPlayerID(c.SourceID) {
+		c.SourceName = l.UnitName(c.SourceName)
+	}
+	if !isPlayerID(c.TargetID) {
+		c.TargetName = l.UnitName(c.TargetName)
+	}
+	if c.MissSuffix != nil {
+		c.MissSuffix.MissType = l.MissType(c.MissSuffix.MissType)
+	}
+	if c.AuraSuffix != nil {
+		c.AuraSuffix.AuraType = l.AuraType(c.AuraSuffix.AuraType)
+	}
+	if c.DispelOrStolenSuffix != nil {
+		c.DispelOrStolenSuffix.AuraType = l.AuraType(c.DispelOrStolenSuffix.AuraType)
+	}
+}
+
+// WithLocale translates the records of a log recorded by a non-English
+// client with the given locale.
+func WithLocale(l *Locale) ParserFunc {
+	return func(p *Parser) {
+		p.Locale = l
+	}
+}
+
+// LocaleDeDE translates German logs.
+var LocaleDeDE = &Locale{
+	Name: "deDE",
+	Units: map[string]string{
+		"Lord Mark'gar":          "Lord Marrowgar",
+		"Lady Todeswisper":       "Lady Deathwhisper",
+		"Todesbringer Saurfang":  "Deathbringer Saurfang",
+		"Fauldarm":               "Festergut",
+		"Modermiene":             "Rotface",
+		"Professor Seuchenmord":  "Professor Putricide",
+		"Prinz Valanar":          "Prince Valanar",
+		"Prinz Keleseth":         "Prince Keleseth",
+		"Prinz Taldaram":         "Prince Taldaram",
+		"Blutkönigin Lana'thel":  "Blood-Queen Lana'thel",
+		"Valithria Traumwandler": "Valithria Dreamwalker",
+		"Der Lichkönig":          "The Lich King",
+	},
+}
+
+// LocaleFrFR translates French logs.
+var LocaleFrFR = &Locale{
+	Name: "frFR",
+	Units: map[string]string{
+		"Seigneur Gargamoelle":    "Lord Marrowgar",
+		"Dame Murmemort":          "Lady Deathwhisper",
+		"Porte-mort Saurcroc":     "Deathbringer Saurfang",
+		"Pulentraille":            "Festergut",
+		"Trognepus":               "Rotface",
+		"Professeur Putricide":    "Professor Putricide",
+		"Reine de sang Lana'thel": "Blood-Queen Lana'thel",
+		"Valithria Marcherêve":    "Valithria Dreamwalker",
+		"Le roi-liche":            "The Lich King",
+	},
+}
+
+// LocaleRuRU translates Russian logs.
+var LocaleRuRU = &Locale{
+	Name: "ruRU",
+	Units: map[string]string{
+		"Лорд Ребрад":                 "Lord Marrowgar",
+		"Леди Смертный Шепот":         "Lady Deathwhisper",
+		"Саурфанг Смертоносный":       "Deathbringer Saurfang",
+		"Тухлопуз":                    "Festergut",
+		"Гниломорд":                   "Rotface",
+		"Профессор Мерзоцид":          "Professor Putricide",
+		"Принц Валанар":               "Prince Valanar",
+		"Принц Келесет":               "Prince Keleseth",
+		"Принц Талдарам":              "Prince Taldaram",
+		"Кровавая королева Лана'тель": "Blood-Queen Lana'thel",
+		"Валитрия Сноходица":          "Valithria Dreamwalker",
+		"Синдрагоса":                  "Sindragosa",
+		"Король-лич":                  "The Lich King",
+	},
+}
+
+// Locales are the built-in locales, by name.
+var Locales = map[string]*Locale{
+	LocaleDeDE.Name: LocaleDeDE,
+	LocaleFrFR.Name: LocaleFrFR,
+	LocaleRuRU.Name: LocaleRuRU,
+}
+
+// LookupLocale returns the built-in locale with the given name, ignoring
+// case. "enUS" and "enGB" return nil, as English logs need no translation.
+func LookupLocale(name string) (*Locale, bool) {
+	for k, l := range Locales {
+		if strings.EqualFold(k, name) {
+			return l, true
+		}
+	}
+	if strings.EqualFold(name, "enUS") || strings.EqualFold(name, "enGB") {
+		return nil, true
+	}
+	return nil, false
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParserLocale(t *testing.T) {
+	lines := []string{
+		`12/11 01:08:14.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Mark'gar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:15.000  SWING_MISSED,0xF130008F0400003D,"Lord Mark'gar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,AUSGEWICHEN`,
+		`12/11 01:08:16.000  SPELL_AURA_APPLIED,0xF130008F0400003D,"Lord Mark'gar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,69146,"Eisflamme",0x10,SCHWÄCHUNG`,
+		`12/11 01:08:17.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F0400003D,"Lord Mark'gar",0x10a48`,
+	}
+	l := &Locale{
+		Name:      "deDE",
+		MissTypes: map[string]MissType{"AUSGEWICHEN": DodgeMiss},
+		AuraTypes: map[string]AuraType{"SCHWÄCHUNG": DebuffAura},
+		Units:     LocaleDeDE.Units,
+	}
+	data, err := New(WithReader(strings.NewReader(strings.Join(lines, "\n"))), WithLocale(l)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[0].TargetName != "Lord Marrowgar" || data[1].SourceName != "Lord Marrowgar" {
+		t.Errorf("expected the boss name to be translated, got %q and %q", data[0].TargetName, data[1].SourceName)
+	}
+	if data[1].MissSuffix.MissType != "DODGE" {
+		t.Errorf("expected DODGE, got %q", data[1].MissSuffix.MissType)
+	}
+	if data[2].AuraSuffix.AuraType != DebuffAura {
+		t.Errorf("expected DEBUFF, got %q", data[2].AuraSuffix.AuraType)
+	}
+	encounters := NewEncounterSplitter().Split(data)
+	if len(encounters) != 1 || encounters[0].Result != EncounterKill {
+		t.Errorf("expected a kill of the translated boss, got %+v", encounters)
+	}
+}
+
+func TestLookupLocale(t *testing.T) {
+	if l, ok := LookupLocale("dede"); !ok || l != LocaleDeDE {
+		t.Errorf("expected deDE, got %v, %v", l, ok)
+	}
+	if l, ok := LookupLocale("enUS"); !ok || l != nil {
+		t.Errorf("expected no translation for enUS, got %v, %v", l, ok)
+	}
+	if _, ok := LookupLocale("xxXX"); ok {
+		t.Error("expected unknown locale to be rejected")
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ParseFiles parses several combat log files, such as the logs of two
+// raiders in the same raid or one raid night split over two files, and
+// merges the records in timestamp order. Every file is parsed with the
+// parser's options, and the Report covers all of them.
+//
+// Events logged by more than one file are kept once: at every timestamp, a
+// record appears as many times as it did in the file that logged it most
+// often, so repeated identical events within one file survive the merge.
+// EventListener callbacks are invoked as each file is parsed, so they do
+// see the duplicates, and players sharing a name under that name.
+func (p *Parser) ParseFiles(paths ...string) ([]*CombatLogRecord, error) {
+	files := make([][]*CombatLogRecord, 0, len(paths))
+	var report ParseReport
+	for _, path := range paths {
+		fp := *p
+		fp.LogFile = path
+		fp.Reader = nil
+		// names are disambiguated over the merged records, so that every
+		// file numbers the same players
+		fp.MergeDuplicateNames = true
+		data, err := fp.Parse()
+		r := fp.Report()
+		report.Lines += r.Lines
+		report.Parsed += r.Parsed
+		report.Blank += r.Blank
+		report.Filtered += r.Filtered
+		report.Quarantined = append(report.Quarantined, r.Quarantined...)
+		if err != nil {
+			p.report = report
+			return []*CombatLogRecord{}, fmt.Errorf("%s: %w", path, err)
+		}
+		files = append(files, data)
+	}
+	out := mergeRecords(files)
+	report.Duplicates = report.Parsed - len(out)
+	p.report = report
+	if !p.MergeDuplicateNames {
+		p.disambiguateAll(out)
+	}
+	return out, nil
+}
+
+// mergeRecords merges the records of several files in timestamp order,
+// keeping records of the same file in their original order. Adjusted
+// timestamps are set again for the merged order.
+func mergeRecords(files [][]*CombatLogRecord) []*CombatLogRecord {
+	type tagged struct {
+		rec  *CombatLogRecord
+		file int
+	}
+	var all []tagged
+	for i, data := range files {
+		for _, rec := range data {
+			all = append(all, tagged{rec: rec, file: i})
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].rec.Timestamp.Before(all[j].rec.Timestamp)
+	})
+
+	out := make([]*CombatLogRecord, 0, len(all))
+	for start := 0; start < len(all); {
+		end := start + 1
+		for end < len(all) && all[end].rec.Timestamp.Equal(all[start].rec.Timestamp) {
+			end++
+		}
+		// kept counts the copies of each record already merged at this
+		// timestamp, and seen counts the copies of each record found in each
+		// file so far
+		kept := map[string]int{}
+		seen := map[string]map[int]int{}
+		for _, t := range all[start:end] {
+			key := recordKey(t.rec)
+			if seen[key] == nil {
+				seen[key] = map[int]int{}
+			}
+			seen[key][t.file]++
+			if seen[key][t.file] > kept[key] {
+				kept[key]++
+				out = append(out, t.rec)
+			}
+		}
+		start = end
+	}
+	var clock monotonicClock
+	for _, rec := range out {
+		clock.adjust(rec)
+	}
+	return out
+}
+
+// recordKey returns a string that is equal for records parsed from
+// identical lines.
+func recordKey(rec *CombatLogRecord) string {
+	var b strings.Builder
+	// the adjusted timestamp depends on the lines around it in each file
+	base := rec.BaseCombatEvent
+	base.AdjustedTimestamp = time.Time{}
+	fmt.Fprintf(&b, "%+v", base)
+	for _, v := range []any{
+		rec.SpellAndRangePrefix, rec.EnchantPrefix, rec.EnvironmentalPrefix,
+		rec.DamageSuffix, rec.AuraSuffix, rec.EnergizeSuffix, rec.MissSuffix, rec.HealSuffix,
+		rec.InterruptSuffix, rec.ExtraAttacksSuffix, rec.DispelOrStolenSuffix, rec.LeechOrDrainSuffix,
+		rec.Raw,
+	} {
+		// %+v of a non-nil struct pointer prints &{...}, not the address
+		fmt.Fprintf(&b, "|%+v", v)
+	}
+	return b.String()
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParserParseFiles(t *testing.T) {
+	swing := func(ts, amount string) string {
+		return ts + `  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,` + amount + `,0,1,0,0,0,nil,nil,nil`
+	}
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	write := func(path string, lines ...string) {
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(first,
+		swing("12/11 01:08:00.000", "1"),
+		swing("12/11 01:08:02.000", "2"),
+		swing("12/11 01:08:02.000", "2"),
+		swing("12/11 01:08:04.000", "4"),
+	)
+	write(second,
+		swing("12/11 01:08:02.000", "2"),
+		swing("12/11 01:08:03.000", "3"),
+		swing("12/11 01:08:04.000", "4"),
+		swing("12/11 01:08:05.000", "5"),
+	)
+	p := New()
+	data, err := p.ParseFiles(first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []uint64
+	for _, rec := range data {
+		got = append(got, rec.DamageSuffix.Amount)
+	}
+	want := []uint64{1, 2, 2, 3, 4, 5}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+	if r := p.Report(); r.Parsed != 8 || r.Duplicates != 2 {
+		t.Errorf("expected 8 records parsed and 2 duplicates, got %+v", r)
+	}
+	if _, err := p.ParseFiles(first, filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("expected a missing file to fail")
+	}
+}
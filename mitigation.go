@@ -0,0 +1,262 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// MitigationAura is a damage reduction aura tracked by the
+// MitigationAnalyzer, matched by the name of the aura on its targets.
+type MitigationAura struct {
+	Name string `json:"name"`
+	// Reduction is the share of damage the aura removes, such as 0.2 for
+	// 20% less damage taken.
+	Reduction float64 `json:"reduction"`
+	// Schools limits the reduction to damage of these schools. Zero
+	// reduces damage of every school.
+	Schools SpellSchool `json:"schools,omitempty"`
+}
+
+// DefaultMitigationAuras are the damage reduction auras of 3.3.5a other
+// players can be credited for. Aura Mastery is left out, as what it
+// prevents depends on the aura it empowers and the raid's resistances.
+var DefaultMitigationAuras = []MitigationAura{
+	// Divine Sacrifice with the Divine Guardian talent
+	{Name: "Divine Guardian", Reduction: 0.2},
+	{Name: "Anti-Magic Zone", Reduction: 0.75, Schools: Magic},
+	{Name: "Pain Suppression", Reduction: 0.4},
+}
+
+// MitigationCredit is the damage a player prevented with one aura.
+type MitigationCredit struct {
+	Caster string `json:"caster"`
+	Aura   string `json:"aura"`
+	// Applications counts the targets the aura was applied to.
+	Applications int `json:"applications"`
+	// DamageTaken is the damage the targets took while covered by the aura.
+	DamageTaken uint64 `json:"damage_taken"`
+	// Prevented is the estimated damage the aura removed.
+	Prevented uint64 `json:"prevented"`
+}
+
+// EncounterMitigation is the damage prevented by mitigation auras during one
+// boss attempt.
+type EncounterMitigation struct {
+	Encounter string          `json:"encounter"`
+	Attempt   int             `json:"attempt,omitempty"`
+	Result    EncounterResult `json:"result,omitempty"`
+	StartTime time.Time       `json:"start_time"`
+	// Credits are sorted by damage prevented, highest first.
+	Credits []MitigationCredit `json:"credits"`
+}
+
+// MitigationReport is the damage prevented in every boss attempt.
+type MitigationReport []EncounterMitigation
+
+// Leaderboard adds up the credits of every attempt by caster and aura,
+// sorted by damage prevented, highest first.
+func (r MitigationReport) Leaderboard() []MitigationCredit {
+	type key struct{ caster, aura string }
+	totals := map[key]*MitigationCredit{}
+	out := []MitigationCredit{}
+	for _, e := range r {
+		for _, c := range e.Credits {
+			k := key{c.Caster, c.Aura}
+			t, ok := totals[k]
+			if !ok {
+				t = &MitigationCredit{Caster: c.Caster, Aura: c.Aura}
+				totals[k] = t
+			}
+			t.Applications += c.Applications
+			t.DamageTaken += c.DamageTaken
+			t.Prevented += c.Prevented
+		}
+	}
+	for _, t := range totals {
+		out = append(out, *t)
+	}
+	sortCredits(out)
+	return out
+}
+
+func sortCredits(c []MitigationCredit) {
+	sort.Slice(c, func(i, j int) bool {
+		if c[i].Prevented != c[j].Prevented {
+			return c[i].Prevented > c[j].Prevented
+		}
+		if c[i].Caster != c[j].Caster {
+			return c[i].Caster < c[j].Caster
+		}
+		return c[i].Aura < c[j].Aura
+	})
+}
+
+// MitigationAnalyzerFunc is an option for NewMitigationAnalyzer.
+type MitigationAnalyzerFunc func(*MitigationAnalyzer)
+
+// MitigationAnalyzer estimates the damage prevented by damage reduction
+// auras and credits it to the players who cast them.
+type MitigationAnalyzer struct {
+	// Auras are the tracked auras. Defaults to DefaultMitigationAuras.
+	Auras []MitigationAura
+	// Splitter finds the boss attempts. Defaults to an EncounterSplitter with
+	// default settings.
+	Splitter *EncounterSplitter
+}
+
+// WithMitigationAuras sets the tracked auras.
+func WithMitigationAuras(auras ...MitigationAura) MitigationAnalyzerFunc {
+	return func(a *MitigationAnalyzer) {
+		a.Auras = auras
+	}
+}
+
+// WithMitigationSplitter sets the EncounterSplitter used to find boss
+// attempts.
+func WithMitigationSplitter(s *EncounterSplitter) MitigationAnalyzerFunc {
+	return func(a *MitigationAnalyzer) {
+		a.Splitter = s
+	}
+}
+
+// NewMitigationAnalyzer initializes, allocates and returns a pointer to a
+// MitigationAnalyzer.
+func NewMitigationAnalyzer(opts ...MitigationAnalyzerFunc) *MitigationAnalyzer {
+	a := &MitigationAnalyzer{
+		Auras: DefaultMitigationAuras,
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	if a.Splitter == nil {
+		a.Splitter = NewEncounterSplitter()
+	}
+	return a
+}
+
+// mitigationKey identifies the credit of a caster for an aura.
+type mitigationKey struct {
+	caster string
+	aura   string
+}
+
+// activeMitigation is a tracked aura on a player.
+type activeMitigation struct {
+	aura MitigationAura
+	key  mitigationKey
+}
+
+// Run estimates the damage prevented in every boss attempt. A player hit
+// for damage D, counting what was absorbed, while covered by auras that
+// each reduce damage by r would have taken D / ∏(1 - r); the difference is
+// shared between the auras in proportion to their reduction. Auras cast by
+// a pet or a summon such as Anti-Magic Zone are credited to its owner.
+func (a *MitigationAnalyzer) Run(data []*CombatLogRecord) MitigationReport {
+	auras := map[string]MitigationAura{}
+	for _, m := range a.Auras {
+		if m.Reduction > 0 && m.Reduction < 1 {
+			auras[m.Name] = m
+		}
+	}
+	pets := newPetTracker()
+	out := MitigationReport{}
+	for _, enc := range a.Splitter.Split(data) {
+		credits := map[mitigationKey]*MitigationCredit{}
+		prevented := map[mitigationKey]float64{}
+		credit := func(k mitigationKey) *MitigationCredit {
+			c, ok := credits[k]
+			if !ok {
+				c = &MitigationCredit{Caster: k.caster, Aura: k.aura}
+				credits[k] = c
+			}
+			return c
+		}
+		// active are the tracked auras on each player, by target GUID
+		active := map[string]map[string]activeMitigation{}
+		for _, rec := range enc.Records {
+			pets.observe(*rec)
+			if rec.DamageSuffix != nil && isPlayerID(rec.TargetID) {
+				a.mitigate(rec, active[rec.TargetID], credit, prevented)
+				continue
+			}
+			if rec.SpellAndRangePrefix == nil || !isPlayerID(rec.TargetID) {
+				continue
+			}
+			m, ok := auras[rec.SpellAndRangePrefix.SpellName]
+			if !ok {
+				continue
+			}
+			switch rec.EventType {
+			case SpellAuraApplied, SpellAuraRefresh:
+				caster := rec.SourceName
+				if owner, ok := pets.owner(rec.SourceID); ok {
+					caster = owner
+				}
+				if active[rec.TargetID] == nil {
+					active[rec.TargetID] = map[string]activeMitigation{}
+				}
+				k := mitigationKey{caster: caster, aura: m.Name}
+				active[rec.TargetID][m.Name] = activeMitigation{aura: m, key: k}
+				if rec.EventType == SpellAuraApplied {
+					credit(k).Applications++
+				}
+			case SpellAuraRemoved:
+				delete(active[rec.TargetID], m.Name)
+			}
+		}
+		em := EncounterMitigation{
+			Encounter: enc.Name,
+			Attempt:   enc.Attempt,
+			Result:    enc.Result,
+			StartTime: enc.StartTime,
+			Credits:   make([]MitigationCredit, 0, len(credits)),
+		}
+		for k, c := range credits {
+			c.Prevented = uint64(math.Round(prevented[k]))
+			em.Credits = append(em.Credits, *c)
+		}
+		sortCredits(em.Credits)
+		out = append(out, em)
+	}
+	return out
+}
+
+// mitigate credits the auras covering the target of a damage record.
+func (a *MitigationAnalyzer) mitigate(rec *CombatLogRecord, on map[string]activeMitigation, credit func(mitigationKey) *MitigationCredit, prevented map[mitigationKey]float64) {
+	var covering []activeMitigation
+	taken, total := 1.0, 0.0
+	for _, m := range on {
+		if m.aura.Schools != 0 && rec.DamageSuffix.SpellSchool&m.aura.Schools == 0 {
+			continue
+		}
+		covering = append(covering, m)
+		taken *= 1 - m.aura.Reduction
+		total += m.aura.Reduction
+	}
+	if len(covering) == 0 {
+		return
+	}
+	damage := rec.DamageSuffix.Amount + rec.DamageSuffix.Absorbed
+	saved := float64(damage)/taken - float64(damage)
+	for _, m := range covering {
+		credit(m.key).DamageTaken += damage
+		prevented[m.key] += saved * m.aura.Reduction / total
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "testing"
+
+func TestMitigationAnalyzerRun(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:05.000  SPELL_AURA_APPLIED,0x0700000000A1B2C3,"Pieshop",0x512,0x07000000009DF7A8,"Winterinjuly",0x514,33206,"Pain Suppression",0x2,BUFF`,
+		`12/11 01:08:06.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,5000,0,1,0,0,1000,nil,nil,nil`,
+		`12/11 01:08:07.000  SPELL_AURA_REMOVED,0x0700000000A1B2C3,"Pieshop",0x512,0x07000000009DF7A8,"Winterinjuly",0x514,33206,"Pain Suppression",0x2,BUFF`,
+		`12/11 01:08:10.000  SPELL_SUMMON,0x07000000001A2B3C,"Deathknell",0x514,0xF13000823E000ABC,"Anti-Magic Zone",0xa28,51052,"Anti-Magic Zone",0x20`,
+		`12/11 01:08:10.000  SPELL_AURA_APPLIED,0xF13000823E000ABC,"Anti-Magic Zone",0xa28,0x07000000009DF7A8,"Winterinjuly",0x514,50461,"Anti-Magic Zone",0x20,BUFF`,
+		`12/11 01:08:11.000  SPELL_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,69146,"Coldflame",0x10,1000,0,16,0,0,0,nil,nil,nil`,
+		`12/11 01:08:12.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,4000,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:20.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,4000,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:56.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F0400003D,"Lord Marrowgar",0x10a48`,
+	)
+	report := NewMitigationAnalyzer().Run(data)
+	if len(report) != 1 || report[0].Encounter != "Lord Marrowgar" {
+		t.Fatalf("expected the Marrowgar attempt, got %+v", report)
+	}
+	credits := report[0].Credits
+	if len(credits) != 2 {
+		t.Fatalf("expected 2 credits, got %+v", credits)
+	}
+	// 6000 damage including the absorb under 40% reduction
+	if ps := credits[0]; ps.Caster != "Pieshop" || ps.Aura != "Pain Suppression" || ps.Prevented != 4000 || ps.DamageTaken != 6000 || ps.Applications != 1 {
+		t.Errorf("unexpected Pain Suppression credit %+v", ps)
+	}
+	// only the frost damage is reduced by Anti-Magic Zone
+	if amz := credits[1]; amz.Caster != "Deathknell" || amz.Prevented != 3000 || amz.DamageTaken != 1000 {
+		t.Errorf("expected Anti-Magic Zone credited to its summoner, got %+v", amz)
+	}
+	board := append(report, report...).Leaderboard()
+	if len(board) != 2 || board[0].Prevented != 8000 || board[1].Applications != 2 {
+		t.Errorf("unexpected leaderboard %+v", board)
+	}
+}
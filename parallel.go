@@ -0,0 +1,254 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// WithParallelism parses plain text log files in n chunks on separate
+// goroutines. The file is split into byte ranges aligned on newlines and the
+// chunks are stitched back together in log order, so the records, the
+// ParseReport and the order EventListener callbacks are invoked in match a
+// sequential parse. Compressed files, Reader input, Stream, Tail and parses
+// bounded by MaxLines, MaxDuration, MaxSkipped or OnlyEncounters are parsed
+// sequentially.
+// A value of one or less parses sequentially, the default.
+func WithParallelism(n int) ParserFunc {
+	return func(p *Parser) {
+		p.Parallelism = n
+	}
+}
+
+// parallel reports whether a plain text log file may be parsed in chunks.
+// The limits and encounter scope it excludes depend on the lines that came
+// before, which a chunk does not know about.
+func (p *Parser) parallel() bool {
+	return p.Parallelism > 1 && p.Limits.MaxLines <= 0 &&
+		p.Limits.MaxDuration <= 0 && p.Limits.MaxSkipped <= 0 &&
+		len(p.OnlyEncounters) == 0
+}
+
+// chunk holds the outcome of parsing one byte range of the log file, with
+// line numbers relative to the start of the range.
+type chunk struct {
+	records []*CombatLogRecord
+	// unknown holds the lines of records with an unknown event type, for
+	// the UnknownEventHandler.
+	unknown     map[*CombatLogRecord]string
+	lines       int
+	blank       int
+	filtered    int
+	quarantined []*ParseError
+	err         error
+}
+
+// parseParallel parses the first size bytes of f in chunks and stitches them
+// together, sanitizing and dispatching records in log order.
+func (p *Parser) parseParallel(f *os.File, size int64) ([]*CombatLogRecord, error) {
+	p.report = ParseReport{}
+	p.monotonic = monotonicClock{}
+	p.players = newPlayerNames()
+	bounds, err := chunkBounds(f, size, p.Parallelism)
+	if err != nil {
+		return []*CombatLogRecord{}, err
+	}
+	clocks := p.chunkClocks(f, bounds)
+	pr := p.newProgress(f)
+	chunks := make([]chunk, len(bounds)-1)
+	var wg sync.WaitGroup
+	wg.Add(len(chunks))
+	for i := range chunks {
+		go func(i int) {
+			defer wg.Done()
+			r := io.NewSectionReader(f, bounds[i], bounds[i+1]-bounds[i])
+			chunks[i] = p.parseChunk(pr.reader(r), clocks[i], pr)
+		}(i)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	pr.wait(done)
+
+	defer p.startDispatch()()
+	total := 0
+	for _, c := range chunks {
+		total += len(c.records)
+	}
+	out := make([]*CombatLogRecord, 0, total)
+	for _, c := range chunks {
+		parsed := 0
+		for _, v := range c.records {
+			p.sanitizeNames(v)
+			if !p.Privacy.apply(v) {
+				continue
+			}
+			p.disambiguate(v)
+			if line, ok := c.unknown[v]; ok {
+				p.UnknownEventHandler(line)
+			}
+			p.monotonic.adjust(v)
+			p.dispatch(*v)
+			out = append(out, v)
+			parsed++
+		}
+		for _, perr := range c.quarantined {
+			perr.Line += p.report.Lines
+		}
+		p.report.Lines += c.lines
+		p.report.Blank += c.blank
+		p.report.Filtered += c.filtered + len(c.records) - parsed
+		p.report.Parsed += parsed
+		if p.Strict && len(c.quarantined) > 0 {
+			return out, c.quarantined[0]
+		}
+		p.report.Quarantined = append(p.report.Quarantined, c.quarantined...)
+		if c.err != nil {
+			return out, c.err
+		}
+	}
+	pr.done(p.report.Lines)
+	return out, nil
+}
+
+// chunkClocks returns the clock each chunk between bounds is stamped with,
+// starting in the year of the first timestamp of the chunk as it would be
+// stamped when parsing the log from the beginning.
+func (p *Parser) chunkClocks(r io.ReaderAt, bounds []int64) []*logClock {
+	clock := p.newClock()
+	clocks := make([]*logClock, len(bounds)-1)
+	for i := range clocks {
+		br := bufio.NewReader(io.NewSectionReader(r, bounds[i], bounds[i+1]-bounds[i]))
+		for n := 0; n < chunkClockLines; n++ {
+			line, err := br.ReadSlice('\n')
+			if _, _, serr := splitLine(clock, line); serr == nil || err != nil {
+				break
+			}
+		}
+		// The year only advances again for a December to January jump within
+		// the chunk, so the chunk starts as if the previous line was in the
+		// same month.
+		c := *clock
+		c.ref = time.Time{}
+		clocks[i] = &c
+	}
+	return clocks
+}
+
+// chunkClockLines is how many lines at the start of a chunk are searched for
+// a timestamp to place the chunk in the log.
+const chunkClockLines = 16
+
+// parseChunk parses every line read from r with its own interner, so chunks
+// can be parsed concurrently. In strict mode it stops at the first malformed
+// line.
+func (p *Parser) parseChunk(r io.Reader, clock *logClock, pr *progress) chunk {
+	c := chunk{}
+	names := newInterner()
+	s := bufio.NewScanner(r)
+	if p.Limits.MaxLineLength > 0 {
+		s.Buffer(make([]byte, 0, min(p.Limits.MaxLineLength, bufio.MaxScanTokenSize)), p.Limits.MaxLineLength)
+	}
+	defer func() {
+		pr.addLines(c.lines % progressCheckInterval)
+	}()
+	for s.Scan() {
+		c.lines++
+		if c.lines%progressCheckInterval == 0 {
+			pr.addLines(progressCheckInterval)
+		}
+		line := s.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			c.blank++
+			continue
+		}
+		v, ok, err := p.parseScoped(clock, line, names)
+		if err != nil {
+			perr := lineError(err, c.lines, line)
+			c.quarantined = append(c.quarantined, perr)
+			if p.Strict {
+				return c
+			}
+			continue
+		}
+		if !ok {
+			c.filtered++
+			continue
+		}
+		c.records = append(c.records, &v)
+		if v.Raw != nil && p.UnknownEventHandler != nil {
+			if c.unknown == nil {
+				c.unknown = map[*CombatLogRecord]string{}
+			}
+			c.unknown[&v] = string(line)
+		}
+	}
+	c.err = s.Err()
+	if errors.Is(c.err, bufio.ErrTooLong) && p.Limits.MaxLineLength > 0 {
+		c.err = &LimitError{Limit: LimitLineLength, Max: int64(p.Limits.MaxLineLength)}
+	}
+	return c
+}
+
+// chunkBounds splits the first size bytes of r into at most n ranges that
+// each start at the beginning of a line, returning the offsets between them
+// including 0 and size.
+func chunkBounds(r io.ReaderAt, size int64, n int) ([]int64, error) {
+	bounds := []int64{0}
+	buf := make([]byte, 4096)
+	for i := 1; i < n; i++ {
+		off := max(size*int64(i)/int64(n), bounds[len(bounds)-1])
+		next, err := nextLine(r, off, size, buf)
+		if err != nil {
+			return nil, err
+		}
+		if next >= size {
+			break
+		}
+		if next > bounds[len(bounds)-1] {
+			bounds = append(bounds, next)
+		}
+	}
+	return append(bounds, size), nil
+}
+
+// nextLine returns the offset just past the first newline at or after off,
+// or size when there is none.
+func nextLine(r io.ReaderAt, off, size int64, buf []byte) (int64, error) {
+	for off < size {
+		n, err := r.ReadAt(buf[:min(int64(len(buf)), size-off)], off)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return off + int64(i) + 1, nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		if n == 0 {
+			break
+		}
+		off += int64(n)
+	}
+	return size, nil
+}
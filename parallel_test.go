@@ -0,0 +1,117 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParserParallelism(t *testing.T) {
+	want, err := newTestParser().Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []*CombatLogRecord
+	l := NewEventListener()
+	l.OnAny(func(r CombatLogRecord) {
+		order = append(order, &r)
+	})
+	p := New(WithLogFile("./testdata/test.txt"), WithEventListener(l), WithParallelism(8))
+	got, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected parallel parse to match sequential parse of %d records, got %d", len(want), len(got))
+	}
+	if !reflect.DeepEqual(order, want) {
+		t.Error("expected callbacks to be invoked in log order")
+	}
+	if p.Report().Lines != len(want) || p.Report().Parsed != len(want) {
+		t.Errorf("unexpected report %+v", p.Report())
+	}
+}
+
+func TestParserParallelismReport(t *testing.T) {
+	good := `12/11 00:13:37.531  SWING_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130007E6B000063,"Frostbrood Whelp",0xa48,84,0,1,nil,nil,nil,nil,nil,nil`
+	lines := []string{good, good, "", good, "garbage", good, good, "garbage", good}
+	path := filepath.Join(t.TempDir(), "WoWCombatLog.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	seq := New(WithLogFile(path))
+	want, err := seq.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := New(WithLogFile(path), WithParallelism(4))
+	got, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d records, got %d", len(want), len(got))
+	}
+	if !reflect.DeepEqual(p.Report(), seq.Report()) {
+		t.Errorf("expected report %+v, got %+v", seq.Report(), p.Report())
+	}
+
+	p = New(WithLogFile(path), WithParallelism(4), WithStrictMode(true))
+	got, err = p.Parse()
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Line != 5 {
+		t.Fatalf("expected a ParseError on line 5, got %v", err)
+	}
+	if len(got) != 3 {
+		t.Errorf("expected the 3 records before the malformed line, got %d", len(got))
+	}
+}
+
+func TestChunkBounds(t *testing.T) {
+	data := "aaaa\nbb\ncccccc\nd\n"
+	r := strings.NewReader(data)
+	bounds, err := chunkBounds(r, int64(len(data)), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range bounds[1 : len(bounds)-1] {
+		if data[b-1] != '\n' {
+			t.Errorf("expected bound %d to start a line", b)
+		}
+	}
+	if bounds[0] != 0 || bounds[len(bounds)-1] != int64(len(data)) {
+		t.Errorf("expected bounds to cover the input, got %v", bounds)
+	}
+	if bounds, _ := chunkBounds(strings.NewReader("one line"), 8, 4); len(bounds) != 2 {
+		t.Errorf("expected a single chunk, got %v", bounds)
+	}
+}
+
+func BenchmarkParseParallel(b *testing.B) {
+	p := New(WithLogFile("./testdata/test.txt"), WithParallelism(4))
+	for i := 0; i < b.N; i++ {
+		if _, err := p.Parse(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"sort"
+	"time"
+)
+
+// ParryHaste is a boss swing that came early because a player's attack was
+// parried, which shortens the remaining time on the boss's swing timer.
+type ParryHaste struct {
+	Time   time.Time `json:"time"`
+	Player string    `json:"player"`
+	// Interval is the time between the boss swings either side of the
+	// parry.
+	Interval time.Duration `json:"interval"`
+}
+
+// BossSwings describes the melee swings of one boss unit during one attempt.
+type BossSwings struct {
+	Encounter string `json:"encounter"`
+	Attempt   int    `json:"attempt"`
+	Boss      string `json:"boss"`
+	Swings    int    `json:"swings"`
+	// MedianInterval is the typical time between swings, the boss's swing
+	// speed when nothing hastes it.
+	MedianInterval time.Duration `json:"median_interval"`
+	// Parries counts player attacks the boss parried.
+	Parries   int          `json:"parries"`
+	Incidents []ParryHaste `json:"incidents"`
+}
+
+// IncidentsByPlayer counts the parry-haste incidents caused by each player.
+func (b BossSwings) IncidentsByPlayer() map[string]int {
+	out := map[string]int{}
+	for _, inc := range b.Incidents {
+		out[inc.Player]++
+	}
+	return out
+}
+
+// ParryHasteAnalyzer measures boss swing timers and detects swings hasted by
+// players attacking the boss from the front.
+type ParryHasteAnalyzer struct {
+	// Tolerance is how much shorter than the median interval a swing after
+	// a parry must be to count as parry-hasted, as a fraction.
+	Tolerance float64
+	Splitter  *EncounterSplitter
+}
+
+// ParryHasteAnalyzerFunc is an option for NewParryHasteAnalyzer.
+type ParryHasteAnalyzerFunc func(*ParryHasteAnalyzer)
+
+// WithParryHasteTolerance sets how much shorter than usual a swing after a
+// parry must be to count as parry-hasted.
+func WithParryHasteTolerance(f float64) ParryHasteAnalyzerFunc {
+	return func(a *ParryHasteAnalyzer) {
+		a.Tolerance = f
+	}
+}
+
+// WithParryHasteSplitter sets the EncounterSplitter used to find attempts.
+func WithParryHasteSplitter(s *EncounterSplitter) ParryHasteAnalyzerFunc {
+	return func(a *ParryHasteAnalyzer) {
+		a.Splitter = s
+	}
+}
+
+// NewParryHasteAnalyzer initializes, allocates and returns a pointer to a
+// ParryHasteAnalyzer.
+func NewParryHasteAnalyzer(opts ...ParryHasteAnalyzerFunc) *ParryHasteAnalyzer {
+	a := &ParryHasteAnalyzer{
+		Tolerance: 0.2,
+		Splitter:  NewEncounterSplitter(),
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+// bossMelee collects the swings of a boss unit and the parries against it.
+type bossMelee struct {
+	name    string
+	swings  []time.Time
+	parries []*CombatLogRecord
+}
+
+// Run finds every boss unit that swung during an attempt. A parry is
+// blamed for a swing when the swing came sooner after the previous one than
+// the median interval minus the tolerance. Several parries within the same
+// interval count as one incident, blamed on the first.
+func (a *ParryHasteAnalyzer) Run(data []*CombatLogRecord) []BossSwings {
+	var out []BossSwings
+	bosses := bossRegistryOrDefault(a.Splitter.Bosses)
+	for _, e := range a.Splitter.Split(data) {
+		units := map[string]*bossMelee{}
+		var order []string
+		unit := func(guid, name string) *bossMelee {
+			m, ok := units[guid]
+			if !ok {
+				m = &bossMelee{name: name}
+				units[guid] = m
+				order = append(order, guid)
+			}
+			return m
+		}
+		for _, rec := range e.Records {
+			if rec.EventType == SwingDamage || rec.EventType == SwingMissed {
+				if _, ok := bosses.Match(rec.SourceName, rec.SourceID); ok {
+					m := unit(rec.SourceID, rec.SourceName)
+					m.swings = append(m.swings, rec.Timestamp)
+					continue
+				}
+			}
+			if rec.MissSuffix != nil && rec.MissSuffix.MissType == ParryMiss && isPlayerID(rec.SourceID) {
+				if _, ok := bosses.Match(rec.TargetName, rec.TargetID); ok {
+					m := unit(rec.TargetID, rec.TargetName)
+					m.parries = append(m.parries, rec)
+				}
+			}
+		}
+		for _, guid := range order {
+			m := units[guid]
+			if len(m.swings) == 0 {
+				continue
+			}
+			out = append(out, m.report(e, a.Tolerance))
+		}
+	}
+	return out
+}
+
+func (m *bossMelee) report(e Encounter, tolerance float64) BossSwings {
+	s := BossSwings{
+		Encounter: e.Name,
+		Attempt:   e.Attempt,
+		Boss:      m.name,
+		Swings:    len(m.swings),
+		Parries:   len(m.parries),
+	}
+	if len(m.swings) < 2 {
+		return s
+	}
+	intervals := make([]time.Duration, 0, len(m.swings)-1)
+	for i := 1; i < len(m.swings); i++ {
+		intervals = append(intervals, m.swings[i].Sub(m.swings[i-1]))
+	}
+	sorted := make([]time.Duration, len(intervals))
+	copy(sorted, intervals)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	s.MedianInterval = sorted[len(sorted)/2]
+	threshold := time.Duration(float64(s.MedianInterval) * (1 - tolerance))
+
+	p := 0
+	for i := 1; i < len(m.swings); i++ {
+		prev, next := m.swings[i-1], m.swings[i]
+		for p < len(m.parries) && !m.parries[p].Timestamp.After(prev) {
+			p++
+		}
+		if p == len(m.parries) || !m.parries[p].Timestamp.Before(next) {
+			continue
+		}
+		if intervals[i-1] < threshold {
+			s.Incidents = append(s.Incidents, ParryHaste{
+				Time:     m.parries[p].Timestamp,
+				Player:   m.parries[p].SourceName,
+				Interval: intervals[i-1],
+			})
+		}
+	}
+	return s
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParryHasteAnalyzerRun(t *testing.T) {
+	swing := func(ts string) string {
+		return ts + `  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,5000,0,1,0,0,0,nil,nil,nil`
+	}
+	parry := func(ts string) string {
+		return ts + `  SWING_MISSED,0x07000000007721EC,"Yogzar",0x511,0xF130008F0400003D,"Lord Marrowgar",0x10a48,PARRY`
+	}
+	data := parseTestLines(t,
+		swing("12/11 01:08:00.000"),
+		swing("12/11 01:08:02.000"),
+		swing("12/11 01:08:04.000"),
+		swing("12/11 01:08:06.000"),
+		parry("12/11 01:08:06.500"),
+		parry("12/11 01:08:06.600"),
+		swing("12/11 01:08:07.300"),
+		swing("12/11 01:08:09.300"),
+		parry("12/11 01:08:10.000"),
+		swing("12/11 01:08:11.300"),
+	)
+	report := NewParryHasteAnalyzer().Run(data)
+	if len(report) != 1 {
+		t.Fatalf("expected 1 boss, got %d", len(report))
+	}
+	b := report[0]
+	if b.Boss != "Lord Marrowgar" || b.Swings != 7 || b.Parries != 3 {
+		t.Errorf("unexpected swings: %+v", b)
+	}
+	if b.MedianInterval != time.Second*2 {
+		t.Errorf("expected a 2s swing timer, got %s", b.MedianInterval)
+	}
+	if len(b.Incidents) != 1 || b.Incidents[0].Interval != time.Millisecond*1300 {
+		t.Fatalf("expected 1 parry-haste incident, got %v", b.Incidents)
+	}
+	if n := b.IncidentsByPlayer()["Yogzar"]; n != 1 {
+		t.Errorf("expected Yogzar to cause 1 incident, got %d", n)
+	}
+}
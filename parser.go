@@ -18,12 +18,17 @@ package frostparse
 
 import (
 	"bufio"
-	"fmt"
+	"bytes"
+	"context"
+	"errors"
+	"io"
 	"os"
-	"strings"
 	"time"
 )
 
+// streamBufferSize is the capacity of the channel returned by Parser.Stream.
+const streamBufferSize = 1024
+
 // ParserFunc is a function that accepts a pointer to a Parser struct
 // to be used in the options variadic function in the `New` function.
 type ParserFunc func(*Parser)
@@ -32,7 +37,70 @@ type ParserFunc func(*Parser)
 // into the CombatLogRecord struct.
 type Parser struct {
 	LogFile       string
+	Reader        io.Reader
 	EventListener EventListener
+	Cache         *Cache
+	// NameSanitizers are applied, in order, to the source and target name
+	// of every record.
+	NameSanitizers []NameSanitizer
+	// Strict makes parsing stop at the first malformed line and return its
+	// *ParseError. Otherwise malformed lines are skipped and reported by
+	// Skipped.
+	Strict bool
+	Limits Limits
+	// Progress is called periodically while parsing, see WithProgress.
+	Progress ProgressFunc
+	// ProgressInterval is how often Progress is called, 100ms when unset.
+	ProgressInterval time.Duration
+	// PollInterval is how often Tail checks the log file for new lines.
+	PollInterval time.Duration
+	// DispatchWorkers is the number of workers EventListener callbacks are
+	// invoked on. Callbacks run on the parsing goroutine when it is zero.
+	DispatchWorkers int
+	// Parallelism is the number of chunks a plain text log file is split
+	// into and parsed concurrently. Files are parsed sequentially when it is
+	// one or less.
+	Parallelism int
+	// AutoDiscover finds the combat log when LogFile is unset, see
+	// WithAutoDiscover.
+	AutoDiscover bool
+
+	// From and To bound the time of the lines that are parsed, see
+	// WithTimeRange.
+	From, To time.Time
+	// OnlyEncounters restricts records to the named boss encounters, see
+	// WithOnlyEncounters.
+	OnlyEncounters []string
+	// LogYear is the year the log starts in, see WithLogYear.
+	LogYear int
+	// ReferenceTime is when the log was written by, see WithReferenceTime.
+	ReferenceTime time.Time
+	// Location is the time zone timestamps are local to, see WithLocation.
+	Location *time.Location
+	// UTCTimestamps converts timestamps to UTC, see WithUTCTimestamps.
+	UTCTimestamps bool
+	// Locale translates logs of non-English clients, see WithLocale.
+	Locale *Locale
+	// Privacy hides players from the parsed records, see WithPrivacy.
+	Privacy *Privacy
+	// MergeDuplicateNames leaves players that share a name under that name,
+	// see WithMergeDuplicateNames.
+	MergeDuplicateNames bool
+	// UnknownEventHandler is called with lines of unknown event types, see
+	// WithUnknownEventHandler.
+	UnknownEventHandler func(line string)
+	// IncludeEvents and ExcludeEvents select the event types that are
+	// parsed, see WithEventFilter and WithExcludedEvents.
+	IncludeEvents []EventType
+	ExcludeEvents []EventType
+
+	report     ParseReport
+	dispatcher *asyncDispatcher
+	names      *interner
+	clock      *logClock
+	monotonic  monotonicClock
+	encounters *encounterTracker
+	players    *playerNames
 }
 
 // WithLogFile is a ParserFunc that sets the parsers log file.
@@ -42,6 +110,15 @@ func WithLogFile(path string) ParserFunc {
 	}
 }
 
+// WithAutoDiscover sets whether the parser looks for the most recent combat
+// log in the game's Logs directory, see DiscoverLogFile, when no log file or
+// reader is set.
+func WithAutoDiscover(enabled bool) ParserFunc {
+	return func(p *Parser) {
+		p.AutoDiscover = enabled
+	}
+}
+
 // WithEventListener sets the parsers EventListener.
 func WithEventListener(listener EventListener) ParserFunc {
 	return func(p *Parser) {
@@ -49,12 +126,50 @@ func WithEventListener(listener EventListener) ParserFunc {
 	}
 }
 
+// WithReader sets an io.Reader the parser reads the combat log from instead of
+// opening LogFile.
+func WithReader(r io.Reader) ParserFunc {
+	return func(p *Parser) {
+		p.Reader = r
+	}
+}
+
+// WithUnknownEventHandler sets a function called, in log order, with every
+// line whose event type the parser does not understand. Such lines are still
+// returned as records with their fields kept in Raw.
+func WithUnknownEventHandler(fn func(line string)) ParserFunc {
+	return func(p *Parser) {
+		p.UnknownEventHandler = fn
+	}
+}
+
+// WithStrictMode sets whether a malformed line aborts the parse with a
+// *ParseError (strict) or is skipped and recorded in the ParseReport (lenient,
+// the default).
+func WithStrictMode(strict bool) ParserFunc {
+	return func(p *Parser) {
+		p.Strict = strict
+	}
+}
+
+// WithCache sets a Cache that parsed records are loaded from and stored in,
+// keyed by the fingerprint of the log file and the parser options that shape
+// the records. Records loaded from the cache are dispatched to the
+// EventListener like parsed ones. Parsers with name sanitizers, limits or an
+// unknown event handler always parse the file.
+func WithCache(c *Cache) ParserFunc {
+	return func(p *Parser) {
+		p.Cache = c
+	}
+}
+
 // New initializes and allocates a parser and applies any ParserFunc options
 // and returns a pointer to the Parser.
 func New(opts ...ParserFunc) *Parser {
 	p := &Parser{
 		LogFile:       os.Getenv("FROSTPARSE_LOG_FILE"),
 		EventListener: NewEventListener(),
+		PollInterval:  defaultPollInterval,
 	}
 
 	for _, opt := range opts {
@@ -67,6 +182,96 @@ func New(opts ...ParserFunc) *Parser {
 // Parse opens the combat log file and returns a slice of pointers to CombatLogRecords
 // and an error if an error occurs during any part of the parsing.
 func (p *Parser) Parse() ([]*CombatLogRecord, error) {
+	if p.Reader != nil {
+		return p.ParseReader(p.Reader)
+	}
+	if err := p.discoverLogFile(); err != nil {
+		return []*CombatLogRecord{}, err
+	}
+	if p.Cache == nil {
+		return p.parseFile()
+	}
+	fp, err := Fingerprint(p.LogFile)
+	if err != nil {
+		return []*CombatLogRecord{}, err
+	}
+	key, cacheable := p.cacheKey(fp)
+	if !cacheable {
+		return p.parseFile()
+	}
+	// records cached by an older BinaryFormatVersion, or without their
+	// report, fail to load and are simply reparsed
+	if out, ok, err := p.Cache.LoadRecords(key); err == nil && ok {
+		if report, ok, err := p.Cache.loadReport(key); err == nil && ok {
+			p.report = report
+			p.relocate(out)
+			return p.deliver(out), nil
+		}
+	}
+	if p.Privacy == nil {
+		out, err := p.parseFile()
+		if err != nil {
+			return out, err
+		}
+		// a failure to populate the cache should not fail the parse
+		p.storeCache(key, out)
+		return out, nil
+	}
+	// the cache holds the records of every player, so the privacy settings
+	// are applied after storing them, as they are on a cache hit
+	privacy, listener := p.Privacy, p.EventListener
+	p.Privacy, p.EventListener = nil, NewEventListener()
+	out, err := p.parseFile()
+	p.Privacy, p.EventListener = privacy, listener
+	if err != nil {
+		return out, err
+	}
+	p.storeCache(key, out)
+	return p.deliver(out), nil
+}
+
+// storeCache writes freshly parsed records and the ParseReport of the parse
+// to the Cache under key, the records first so that a report is never
+// stored without them.
+func (p *Parser) storeCache(key string, out []*CombatLogRecord) {
+	if err := p.Cache.StoreRecords(key, out); err != nil {
+		return
+	}
+	_ = p.Cache.storeReport(key, p.report)
+}
+
+// deliver applies the privacy settings and player names to records that
+// were not scanned with them, updates the ParseReport and dispatches the
+// records to the EventListener.
+func (p *Parser) deliver(out []*CombatLogRecord) []*CombatLogRecord {
+	n := len(out)
+	out = p.applyPrivacy(out)
+	p.report.Parsed -= n - len(out)
+	p.report.Filtered += n - len(out)
+	p.report.Aliases = nil
+	p.disambiguateAll(out)
+	stop := p.startDispatch()
+	for _, rec := range out {
+		p.dispatch(*rec)
+	}
+	stop()
+	return out
+}
+
+// ParseReader parses the combat log read from r until EOF and returns a slice
+// of pointers to CombatLogRecords. The Cache is not consulted because a reader
+// has no fingerprint.
+func (p *Parser) ParseReader(r io.Reader) ([]*CombatLogRecord, error) {
+	out := []*CombatLogRecord{}
+	err := p.scan(r, func(v CombatLogRecord) error {
+		out = append(out, &v)
+		return nil
+	})
+	return out, err
+}
+
+// parseFile opens and parses the combat log file.
+func (p *Parser) parseFile() ([]*CombatLogRecord, error) {
 	empty := []*CombatLogRecord{}
 	f, err := os.Open(p.LogFile)
 	defer func() {
@@ -76,50 +281,262 @@ func (p *Parser) Parse() ([]*CombatLogRecord, error) {
 	if err != nil {
 		return empty, err
 	}
-	rows, err := rowsInFile(f)
-	if err != nil {
+	if p.Limits.MaxBytes > 0 {
+		info, err := f.Stat()
+		if err != nil {
+			return empty, err
+		}
+		if info.Size() > p.Limits.MaxBytes {
+			return empty, &LimitError{Limit: LimitBytes, Max: p.Limits.MaxBytes}
+		}
+	}
+	head := make([]byte, magicLength())
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
 		return empty, err
 	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return empty, err
+	}
+	// counting newlines only gives the number of rows of a plain text log
+	rows := 0
+	_, compressed := detectCompression(head[:n])
+	if !compressed && p.parallel() {
+		info, err := f.Stat()
+		if err != nil {
+			return empty, err
+		}
+		return p.parseParallel(f, info.Size())
+	}
+	if !compressed {
+		if rows, err = rowsInFile(f); err != nil {
+			return empty, err
+		}
+	}
+	if p.Limits.MaxLines > 0 {
+		rows = min(rows, p.Limits.MaxLines)
+	}
 	// pre-allocate based on the number of rows identified in the combat log file
 	// to limit number of allocations during parsing
-	out := make([]*CombatLogRecord, rows)
+	out := make([]*CombatLogRecord, 0, rows)
 	// after rowsInFile is called, we need to seek back to beginning of file.
 	_, err = f.Seek(0, 0)
 	if err != nil {
 		return empty, err
 	}
+	err = p.scan(f, func(v CombatLogRecord) error {
+		out = append(out, &v)
+		return nil
+	})
+	return out, err
+}
+
+// Stream opens the combat log file, or uses the configured Reader, and parses
+// it on a separate goroutine, sending each CombatLogRecord on the returned
+// channel as soon as it is parsed. Both channels are closed once the input has
+// been consumed, ctx is cancelled, or an error occurs; at most one error is
+// sent on the error channel.
+func (p *Parser) Stream(ctx context.Context) (<-chan CombatLogRecord, <-chan error) {
+	out := make(chan CombatLogRecord, streamBufferSize)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(out)
+		defer close(errc)
+		r := p.Reader
+		if r == nil {
+			if err := p.discoverLogFile(); err != nil {
+				errc <- err
+				return
+			}
+			f, err := os.Open(p.LogFile)
+			if err != nil {
+				errc <- err
+				return
+			}
+			defer f.Close()
+			r = f
+		}
+		err := p.scan(r, func(v CombatLogRecord) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			select {
+			case out <- v:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			errc <- err
+		}
+	}()
+	return out, errc
+}
+
+// scan reads r line by line, parses each row, invokes the EventListener
+// callback for the row's event type and hands the record to fn. Scanning stops
+// at the first error returned by fn, or at the first malformed line in strict
+// mode.
+func (p *Parser) scan(r io.Reader, fn func(CombatLogRecord) error) error {
+	p.reset()
 	start := time.Now()
-	s := bufio.NewScanner(f)
-	i := 0
+	defer p.startDispatch()()
+	pr := p.newProgress(r)
+	r, err := decompress(pr.reader(r))
+	if err != nil {
+		return err
+	}
+	s := bufio.NewScanner(p.Limits.reader(r))
+	if p.Limits.MaxLineLength > 0 {
+		s.Buffer(make([]byte, 0, min(p.Limits.MaxLineLength, bufio.MaxScanTokenSize)), p.Limits.MaxLineLength)
+	}
 	for s.Scan() {
-		v := parseRow(start, s.Text())
-		out[i] = &v
-		if cb, ok := p.EventListener.Get(v.EventType); ok {
-			cb(v)
+		p.report.Lines++
+		if p.Limits.MaxLines > 0 && p.report.Lines > p.Limits.MaxLines {
+			return &LimitError{Limit: LimitLines, Max: int64(p.Limits.MaxLines)}
+		}
+		if p.Limits.MaxDuration > 0 && p.report.Lines%durationCheckInterval == 0 &&
+			time.Since(start) > p.Limits.MaxDuration {
+			return &LimitError{Limit: LimitDuration, Max: int64(p.Limits.MaxDuration)}
+		}
+		if p.report.Lines%progressCheckInterval == 0 {
+			pr.tick(p.report.Lines)
+		}
+		if err := p.scanLine(s.Bytes(), fn); err != nil {
+			return err
+		}
+	}
+	if errors.Is(s.Err(), bufio.ErrTooLong) && p.Limits.MaxLineLength > 0 {
+		return &LimitError{Limit: LimitLineLength, Max: int64(p.Limits.MaxLineLength)}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	pr.done(p.report.Lines)
+	return nil
+}
+
+// reset clears the state a previous parse left on the Parser, so the lines
+// of the next one are stamped, scoped and named afresh.
+func (p *Parser) reset() {
+	p.report = ParseReport{}
+	p.names = newInterner()
+	p.clock = p.newClock()
+	p.monotonic = monotonicClock{}
+	p.encounters = nil
+	p.players = newPlayerNames()
+}
+
+// scanLine parses a single line, records the outcome in the ParseReport,
+// invokes the EventListener callback and hands the record to fn. The line is
+// only read during the call, so the scanner's buffer can be passed directly.
+func (p *Parser) scanLine(line []byte, fn func(CombatLogRecord) error) error {
+	if len(bytes.TrimSpace(line)) == 0 {
+		p.report.Blank++
+		return nil
+	}
+	if p.names == nil {
+		p.names = newInterner()
+	}
+	if p.clock == nil {
+		p.clock = p.newClock()
+	}
+	v, ok, err := p.parseScoped(p.clock, line, p.names)
+	if err != nil {
+		perr := lineError(err, p.report.Lines, line)
+		if p.Strict {
+			return perr
+		}
+		if p.Limits.MaxSkipped > 0 && len(p.report.Quarantined) >= p.Limits.MaxSkipped {
+			return &LimitError{Limit: LimitSkipped, Max: int64(p.Limits.MaxSkipped)}
 		}
-		i++
+		p.report.Quarantined = append(p.report.Quarantined, perr)
+		return nil
 	}
-	return out, nil
+	if !ok || !p.inEncounters(v) {
+		p.report.Filtered++
+		return nil
+	}
+	p.sanitizeNames(&v)
+	if !p.Privacy.apply(&v) {
+		p.report.Filtered++
+		return nil
+	}
+	p.disambiguate(&v)
+	p.report.Parsed++
+	if v.Raw != nil && p.UnknownEventHandler != nil {
+		p.UnknownEventHandler(string(line))
+	}
+	p.monotonic.adjust(&v)
+	p.dispatch(v)
+	return fn(v)
 }
 
+// minEventFields is the number of comma separated fields shared by every
+// event: the event type followed by the source and target GUID, name and
+// flags.
+const minEventFields = 7
+
+// timestampSeparator separates the timestamp from the event fields.
+var timestampSeparator = []byte("  ")
+
 // parseRow parses the string data from the combat log and stores it in a
-// CombatLogRecord struct and returns it.
-func parseRow(startTime time.Time, data string) CombatLogRecord {
-	s := strings.Split(data, "  ")
-	s[0] = fmt.Sprintf("%d/%s", startTime.Year(), s[0])
-	t := mustParseTimestamp(s[0])
-	eventParts := strings.Split(s[1], ",")
-	eventType := EventType(eventParts[0])
+// CombatLogRecord struct and returns it. The returned error is a *ParseError
+// with the Field set to the index of the offending event field, or -1 when the
+// line itself is malformed; Line and Raw are left for the caller to fill in.
+func parseRow(startTime time.Time, data string) (CombatLogRecord, error) {
+	return parseLine(&logClock{year: startTime.Year(), fixed: true}, []byte(data), nil)
+}
+
+// parseLine is parseRow over the bytes of a line, stamped by clock. Strings
+// are interned in names when it is not nil, and never reference data, so the
+// caller may reuse it.
+func parseLine(clock *logClock, data []byte, names *interner) (CombatLogRecord, error) {
+	t, event, err := splitLine(clock, data)
+	if err != nil {
+		return CombatLogRecord{}, err
+	}
+	return parseEvent(t, event, names)
+}
+
+// splitLine parses the timestamp of a line and returns it with the event
+// fields that follow it, so lines can be skipped by time before they are
+// tokenized.
+func splitLine(clock *logClock, data []byte) (time.Time, []byte, error) {
+	ts, event, ok := bytes.Cut(data, timestampSeparator)
+	if !ok || len(ts) == 0 {
+		return time.Time{}, nil, &ParseError{Field: -1, Err: ErrMissingTimestamp}
+	}
+	t, err := clock.stamp(ts)
+	if err != nil {
+		return time.Time{}, nil, &ParseError{Field: -1, Err: err}
+	}
+	return t, event, nil
+}
+
+// parseEvent parses the comma separated event fields of a line logged at t.
+func parseEvent(t time.Time, event []byte, names *interner) (CombatLogRecord, error) {
+	f := &fieldReader{names: names}
+	f.tokenize(event)
+	if f.len() < minEventFields {
+		return CombatLogRecord{}, &ParseError{Field: f.len(), Err: ErrMissingField}
+	}
+	eventType := EventType(f.id(0))
 	be := BaseCombatEvent{
-		Timestamp:  t,
-		EventType:  eventType,
-		SourceID:   eventParts[1],
-		SourceName: removeQuoteString(eventParts[2]),
-		TargetID:   eventParts[4],
-		TargetName: removeQuoteString(eventParts[5]),
+		Timestamp:         t,
+		AdjustedTimestamp: t,
+		EventType:         eventType,
+		SourceID:          f.id(1),
+		SourceName:        f.str(2),
+		SourceFlags:       UnitFlags(f.uint(3)),
+		TargetID:          f.id(4),
+		TargetName:        f.str(5),
+		TargetFlags:       UnitFlags(f.uint(6)),
 	}
 	prefix := Prefix{}
 	suffix := Suffix{}
+	var raw *RawEvent
 	switch eventType {
 	case UnitDied:
 		// can ignore
@@ -130,196 +547,251 @@ func parseRow(startTime time.Time, data string) CombatLogRecord {
 	case PartyKill:
 		// can ignore
 		break
+	case UnitDestroyed, UnitDissipates:
+		// totems, guardians and spirits going away carry no more than
+		// UNIT_DIED
+		break
+	case SpellDurabilityDamage, SpellDurabilityDamageAll:
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
 	case SwingDamage:
-		suffix.DamageSuffix = parseDamageSuffix(eventParts, 7)
+		suffix.DamageSuffix = parseDamageSuffix(f, 7)
 	case SpellDamage:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.DamageSuffix = parseDamageSuffix(eventParts, 10)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.DamageSuffix = parseDamageSuffix(f, 10)
 	case SpellPeriodicDamage:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.DamageSuffix = parseDamageSuffix(eventParts, 10)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.DamageSuffix = parseDamageSuffix(f, 10)
 	case DamageShield:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.DamageSuffix = parseDamageSuffix(eventParts, 10)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.DamageSuffix = parseDamageSuffix(f, 10)
 	case DamageSplit:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.DamageSuffix = parseDamageSuffix(eventParts, 10)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.DamageSuffix = parseDamageSuffix(f, 10)
 	case SpellDrain:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.LeechOrDrainSuffix = parseLeachOrDrainSuffix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.LeechOrDrainSuffix = parseLeechOrDrainSuffix(f)
 	case EnvironmentalDamage:
-		prefix.EnvironmentalPrefix = parseEnvironmentalPrefix(eventParts)
-		suffix.DamageSuffix = parseDamageSuffix(eventParts, 8)
+		prefix.EnvironmentalPrefix = parseEnvironmentalPrefix(f)
+		suffix.DamageSuffix = parseDamageSuffix(f, 8)
 	case RangeMissed:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.MissSuffix = parseMissSuffix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.MissSuffix = parseMissSuffix(f, 10)
 	case SpellAuraApplied:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.AuraSuffix = parseAuraSuffix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.AuraSuffix = parseAuraSuffix(f)
 	case SpellHeal:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.HealSuffix = parseHealSuffix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.HealSuffix = parseHealSuffix(f)
 	case SpellAuraRemoved:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.AuraSuffix = parseAuraSuffix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.AuraSuffix = parseAuraSuffix(f)
 	case SpellCastStart:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
 	case SpellCastFailed:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
 	case SpellAuraRefresh:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.AuraSuffix = parseAuraSuffix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.AuraSuffix = parseAuraSuffix(f)
 	case SpellEnergize:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.EnergizeSuffix = parseEnergizeSuffix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.EnergizeSuffix = parseEnergizeSuffix(f)
 	case SwingMissed:
-		suffix.MissSuffix = parseMissSuffix(eventParts)
+		suffix.MissSuffix = parseMissSuffix(f, 7)
 	case SpellAuraAppliedDose:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.AuraSuffix = parseAuraSuffix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.AuraSuffix = parseAuraDoseSuffix(f)
 	case SpellPeriodicEnergize:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.EnergizeSuffix = parseEnergizeSuffix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.EnergizeSuffix = parseEnergizeSuffix(f)
 	case SpellPeriodicHeal:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.HealSuffix = parseHealSuffix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.HealSuffix = parseHealSuffix(f)
 	case SpellInterrupt:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.InterruptSuffix = parseInterruptSuffix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.InterruptSuffix = parseInterruptSuffix(f)
 	case SpellMissed:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.MissSuffix = parseMissSuffix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.MissSuffix = parseMissSuffix(f, 10)
 	case SpellCreate:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
 	case RangeDamage:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.DamageSuffix = parseDamageSuffix(eventParts, 10)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.DamageSuffix = parseDamageSuffix(f, 10)
 	case SpellExtraAttacks:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.ExtraAttacksSuffix = parseExtraAttackSuffix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.ExtraAttacksSuffix = parseExtraAttackSuffix(f)
 	case SpellPeriodicMissed:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.MissSuffix = parseMissSuffix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.MissSuffix = parseMissSuffix(f, 10)
 	case SpellAuraRemovedDose:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.AuraSuffix = parseAuraDoseSuffix(f)
 	case EnchantApplied:
-		prefix.EnchantPrefix = parseEnchantPrefix(eventParts)
+		prefix.EnchantPrefix = parseEnchantPrefix(f)
 	case EnchantRemoved:
-		prefix.EnchantPrefix = parseEnchantPrefix(eventParts)
+		prefix.EnchantPrefix = parseEnchantPrefix(f)
 	case SpellResurrect:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-	case SpellDispell:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.DispelOrStolenSuffix = parseDispellOrStolenSuffix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+	case SpellDispel:
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.DispelOrStolenSuffix = parseDispelOrStolenSuffix(f)
+	case SpellDispelFailed:
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.DispelOrStolenSuffix = parseDispelOrStolenSuffix(f)
+	case SpellStolen:
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.DispelOrStolenSuffix = parseDispelOrStolenSuffix(f)
+	case SpellAuraBroken:
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.AuraSuffix = parseAuraSuffix(f)
+	case SpellAuraBrokenSpell:
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.DispelOrStolenSuffix = parseDispelOrStolenSuffix(f)
 	case DamageShieldMissed:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.MissSuffix = parseMissSuffix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.MissSuffix = parseMissSuffix(f, 10)
 	case SpellPeriodicLeech:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
-		suffix.LeechOrDrainSuffix = parseLeachOrDrainSuffix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
+		suffix.LeechOrDrainSuffix = parseLeechOrDrainSuffix(f)
 	case SpellSummon:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
 	case SpellCastSuccess:
-		prefix.SpellAndRangePrefix = parseSpellPrefix(eventParts)
+		prefix.SpellAndRangePrefix = parseSpellAndRangePrefix(f)
 	default:
-		fmt.Println("unknown eventType: ", eventType)
+		raw = &RawEvent{Fields: make([]string, 0, f.len()-minEventFields)}
+		for i := minEventFields; i < min(f.len(), maxEventFields); i++ {
+			raw.Fields = append(raw.Fields, f.id(i))
+		}
 	}
 
+	if f.err != nil {
+		return CombatLogRecord{}, &ParseError{Field: f.field, Err: f.err}
+	}
 	return CombatLogRecord{
 		BaseCombatEvent: be,
 		Prefix:          prefix,
 		Suffix:          suffix,
-	}
+		Raw:             raw,
+	}, nil
 }
 
-func parseSpellPrefix(eventParts []string) *SpellAndRangePrefix {
+func parseSpellAndRangePrefix(f *fieldReader) *SpellAndRangePrefix {
 	return &SpellAndRangePrefix{
-		SpellID:     mustParseUint(eventParts[7]),
-		SpellName:   removeQuoteString(eventParts[8]),
-		SpellSchool: mustParseSpellSchool(eventParts[9]),
+		SpellID:     f.uint(7),
+		SpellName:   f.str(8),
+		SpellSchool: f.spellSchool(9),
 	}
 }
 
-func parseDamageSuffix(eventParts []string, initialOffset int) *DamageSuffix {
+func parseDamageSuffix(f *fieldReader, initialOffset int) *DamageSuffix {
 	return &DamageSuffix{
-		Amount:      mustParseUint(eventParts[initialOffset]),
-		Overkill:    mustParseUint(eventParts[initialOffset+1]),
-		SpellSchool: SpellSchool(mustParseInt(eventParts[initialOffset+2])),
-		Resisted:    mustParseIntOrNil(eventParts[initialOffset+3]),
-		Blocked:     mustParseIntOrNil(eventParts[initialOffset+4]),
-		Absorbed:    mustParseIntOrNil(eventParts[initialOffset+5]),
-		Critical:    parseNilBool(eventParts[initialOffset+6]),
+		Amount:      f.uint(initialOffset),
+		Overkill:    f.uint(initialOffset + 1),
+		SpellSchool: SpellSchool(f.int(initialOffset + 2)),
+		Resisted:    f.uintOrNil(initialOffset + 3),
+		Blocked:     f.uintOrNil(initialOffset + 4),
+		Absorbed:    f.uintOrNil(initialOffset + 5),
+		Critical:    f.nilBool(initialOffset + 6),
+		Glancing:    f.nilBool(initialOffset + 7),
+		Crushing:    f.nilBool(initialOffset + 8),
 	}
 }
 
-func parseAuraSuffix(eventParts []string) *AuraSuffix {
+func parseAuraSuffix(f *fieldReader) *AuraSuffix {
 	return &AuraSuffix{
-		AuraType: AuraType(removeQuoteString(eventParts[10])),
+		AuraType: AuraType(f.str(10)),
 	}
 }
 
-func parseEnergizeSuffix(eventParts []string) *EnergizeSuffix {
+// parseAuraDoseSuffix reads the aura type and, when the line has it, the
+// number of stacks after the dose event.
+func parseAuraDoseSuffix(f *fieldReader) *AuraSuffix {
+	s := parseAuraSuffix(f)
+	if f.len() > 11 {
+		s.Doses = f.uint(11)
+	}
+	return s
+}
+
+func parseEnergizeSuffix(f *fieldReader) *EnergizeSuffix {
 	return &EnergizeSuffix{
-		Amount:    mustParseInt(eventParts[10]),
-		PowerType: PowerType(mustParseUint(eventParts[11])),
+		Amount:    f.int(10),
+		PowerType: PowerType(f.int(11)),
 	}
 }
 
-func parseMissSuffix(eventParts []string) *MissSuffix {
-	return &MissSuffix{
-		MissType: eventParts[7],
+// parseMissSuffix reads the miss type at offset and what follows it: the
+// amount of a partial miss in 3.3.5a logs, or the off-hand flag and then the
+// amount in logs of later clients.
+func parseMissSuffix(f *fieldReader, offset int) *MissSuffix {
+	s := &MissSuffix{
+		MissType: MissType(f.str(offset)),
 	}
+	switch f.len() - offset - 1 {
+	case 0:
+	case 1:
+		s.Amount = f.uintOrNil(offset + 1)
+	default:
+		s.IsOffHand = f.nilBool(offset + 1)
+		s.Amount = f.uintOrNil(offset + 2)
+	}
+	return s
 }
 
-func parseHealSuffix(eventParts []string) *HealSuffix {
+func parseHealSuffix(f *fieldReader) *HealSuffix {
 	return &HealSuffix{
-		Amount:      mustParseUint(eventParts[10]),
-		Overhealing: mustParseUint(eventParts[11]),
-		Absorbed:    mustParseUint(eventParts[12]),
-		Critical:    parseNilBool(eventParts[13]),
+		Amount:      f.uint(10),
+		Overhealing: f.uint(11),
+		Absorbed:    f.uint(12),
+		Critical:    f.nilBool(13),
 	}
 }
 
-func parseInterruptSuffix(eventParts []string) *InterruptSuffix {
+func parseInterruptSuffix(f *fieldReader) *InterruptSuffix {
 	return &InterruptSuffix{
-		ExtraSpellID:     mustParseUint(eventParts[10]),
-		ExtraSpellName:   removeQuoteString(eventParts[11]),
-		ExtraSpellSchool: SpellSchool(mustParseUint(eventParts[12])),
+		ExtraSpellID:     f.uint(10),
+		ExtraSpellName:   f.str(11),
+		ExtraSpellSchool: f.spellSchool(12),
 	}
 }
 
-func parseExtraAttackSuffix(eventParts []string) *ExtraAttacksSuffix {
+func parseExtraAttackSuffix(f *fieldReader) *ExtraAttacksSuffix {
 	return &ExtraAttacksSuffix{
-		Amount: mustParseUint(eventParts[10]),
+		Amount: f.uint(10),
 	}
 }
 
-func parseEnchantPrefix(eventParts []string) *EnchantPrefix {
+func parseEnchantPrefix(f *fieldReader) *EnchantPrefix {
 	return &EnchantPrefix{
-		SpellName: removeQuoteString(eventParts[7]),
-		ItemID:    mustParseUint(eventParts[8]),
-		ItemName:  removeQuoteString(eventParts[9]),
+		SpellName: f.str(7),
+		ItemID:    f.uint(8),
+		ItemName:  f.str(9),
 	}
 }
 
-func parseDispellOrStolenSuffix(eventParts []string) *DispelOrStolenSuffix {
-	return &DispelOrStolenSuffix{
-		ExtraSpellID:     mustParseUint(eventParts[10]),
-		ExtraSpellName:   removeQuoteString(eventParts[11]),
-		ExtraSpellSchool: mustParseSpellSchool(eventParts[12]),
+func parseDispelOrStolenSuffix(f *fieldReader) *DispelOrStolenSuffix {
+	suffix := &DispelOrStolenSuffix{
+		ExtraSpellID:     f.uint(10),
+		ExtraSpellName:   f.str(11),
+		ExtraSpellSchool: f.spellSchool(12),
+	}
+	// SPELL_DISPEL_FAILED does not carry an aura type
+	if f.len() > 13 {
+		suffix.AuraType = AuraType(f.str(13))
 	}
+	return suffix
 }
 
-func parseLeachOrDrainSuffix(eventParts []string) *LeechOrDrainSuffix {
+func parseLeechOrDrainSuffix(f *fieldReader) *LeechOrDrainSuffix {
 	return &LeechOrDrainSuffix{
-		Amount:      mustParseUint(eventParts[10]),
-		PowerType:   PowerType(mustParseUint(eventParts[11])),
-		ExtraAmount: mustParseUint(eventParts[12]),
+		Amount:      f.uint(10),
+		PowerType:   PowerType(f.int(11)),
+		ExtraAmount: f.uint(12),
 	}
 }
 
-func parseEnvironmentalPrefix(eventParts []string) *EnvironmentalPrefix {
+func parseEnvironmentalPrefix(f *fieldReader) *EnvironmentalPrefix {
 	return &EnvironmentalPrefix{
-		EnvironmentalType: EnvironmentalType(eventParts[7]),
+		EnvironmentalType: EnvironmentalType(f.str(7)),
 	}
 }
@@ -17,11 +17,26 @@ limitations under the License.
 package frostparse
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
 
+func mustParseRow(t *testing.T, line string) CombatLogRecord {
+	t.Helper()
+	v, err := parseRow(time.Now(), line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
 func TestNew(t *testing.T) {
 	p := New()
 	if p.LogFile != "" {
@@ -71,3 +86,360 @@ func TestParserWithEventListener(t *testing.T) {
 	fmt.Printf("found %d spelldamage events\n", spellDamageCount)
 	fmt.Printf("found %d swingdamage events\n", swingCount)
 }
+
+func TestParseRowDispelFailedAndStolen(t *testing.T) {
+	failed := mustParseRow(t, `12/11 00:22:24.866  SPELL_DISPEL_FAILED,0x0700000000821F6B,"Manorothh",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,4987,"Cleanse",0x2,69065,"Impaled",1`)
+	if failed.DispelOrStolenSuffix == nil || failed.DispelOrStolenSuffix.ExtraSpellName != "Impaled" {
+		t.Errorf("expected dispel failed suffix, got %+v", failed.Suffix)
+	}
+	stolen := mustParseRow(t, `12/11 00:22:24.866  SPELL_STOLEN,0x07000000000C1CFE,"Shevros",0x514,0xF130008F74000068,"Servant of the Throne",0xa48,30449,"Spellsteal",0x40,71029,"Frost Shield",16,BUFF`)
+	if stolen.DispelOrStolenSuffix == nil || stolen.DispelOrStolenSuffix.AuraType != BuffAura {
+		t.Errorf("expected stolen buff suffix, got %+v", stolen.Suffix)
+	}
+}
+
+func TestParseRowGlancingAndCrushing(t *testing.T) {
+	glancing := mustParseRow(t, `12/11 01:08:14.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,3120,0,1,0,0,0,nil,1,nil`)
+	if s := glancing.DamageSuffix; s.Critical || !s.Glancing || s.Crushing {
+		t.Errorf("expected a glancing blow, got %+v", s)
+	}
+	crushing := mustParseRow(t, `12/11 01:08:15.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000007721EC,"Yogzar",0x511,21500,0,1,0,0,0,nil,nil,1`)
+	if s := crushing.DamageSuffix; s.Critical || s.Glancing || !s.Crushing {
+		t.Errorf("expected a crushing blow, got %+v", s)
+	}
+}
+
+func TestParseRowMissSuffix(t *testing.T) {
+	tests := []struct {
+		line string
+		want MissSuffix
+	}{
+		{`12/11 00:13:06.441  SWING_MISSED,0xF130009093000102,"The Damned",0xa48,0xF13000946C0000C9,"Ebon Champion",0xa28,MISS`, MissSuffix{MissType: PlainMiss}},
+		{`12/11 00:19:27.233  SWING_MISSED,0xF13000909300008F,"The Damned",0x10a48,0x070000000062ADF1,"Phokkwho",0x514,ABSORB,2568`, MissSuffix{MissType: AbsorbMiss, Amount: 2568}},
+		{`12/11 00:13:12.703  SPELL_MISSED,0xF130009093000102,"The Damned",0xa48,0xF13000946C0000C9,"Ebon Champion",0xa28,70961,"Shattered Bones",0x1,EVADE`, MissSuffix{MissType: EvadeMiss}},
+		{`12/11 00:20:48.034  SPELL_MISSED,0xF130009094000095,"Ancient Skeletal Soldier",0x10a48,0x0700000000821F6B,"Manorothh",0x40514,70964,"Shield Bash",0x1,BLOCK,1737`, MissSuffix{MissType: BlockMiss, Amount: 1737}},
+		{`12/11 01:08:22.279  SPELL_PERIODIC_MISSED,0xF130008F40000132,"Coldflame",0xa48,0x070000000002C1B0,"Ashl",0x514,70823,"Coldflame",0x10,ABSORB,4656`, MissSuffix{MissType: AbsorbMiss, Amount: 4656}},
+		{`12/11 00:13:06.441  SWING_MISSED,0x0700000000821F6B,"Manorothh",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,PARRY,1`, MissSuffix{MissType: ParryMiss, Amount: 1}},
+		{`12/11 00:13:06.441  SWING_MISSED,0x0700000000821F6B,"Manorothh",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,ABSORB,1,812`, MissSuffix{MissType: AbsorbMiss, IsOffHand: true, Amount: 812}},
+	}
+	for _, tc := range tests {
+		rec := mustParseRow(t, tc.line)
+		if rec.MissSuffix == nil || *rec.MissSuffix != tc.want {
+			t.Errorf("%s: expected %+v, got %+v", tc.line, tc.want, rec.MissSuffix)
+		}
+	}
+}
+
+func TestParseRowAuraBroken(t *testing.T) {
+	broken := mustParseRow(t, `12/11 00:30:01.120  SPELL_AURA_BROKEN,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130009402000010,"Cult Adherent",0xa48,12826,"Polymorph",0x40,DEBUFF`)
+	if broken.AuraSuffix == nil || broken.AuraSuffix.AuraType != DebuffAura || broken.SpellAndRangePrefix.SpellName != "Polymorph" {
+		t.Errorf("expected broken polymorph, got %+v", broken)
+	}
+	bySpell := mustParseRow(t, `12/11 00:30:01.120  SPELL_AURA_BROKEN_SPELL,0x07000000000C1CFE,"Shevros",0x514,0xF130009402000010,"Cult Adherent",0xa48,12826,"Polymorph",0x40,49238,"Lightning Bolt",8,DEBUFF`)
+	if s := bySpell.DispelOrStolenSuffix; s == nil || s.ExtraSpellName != "Lightning Bolt" || s.ExtraSpellSchool != 8 || s.AuraType != DebuffAura {
+		t.Errorf("expected polymorph broken by Lightning Bolt, got %+v", bySpell.Suffix)
+	}
+	if e, ok := bySpell.Typed().(AuraBrokenEvent); !ok || e.Breaker.ExtraSpellID != 49238 || e.Aura.AuraType != DebuffAura {
+		t.Errorf("expected an AuraBrokenEvent, got %+v", bySpell.Typed())
+	}
+}
+
+func TestParseRowDestroyedAndDurability(t *testing.T) {
+	destroyed := mustParseRow(t, `12/11 00:31:00.000  UNIT_DESTROYED,0x0000000000000000,nil,0x80000000,0xF130005E4D000123,"Mana Tide Totem",0x2111`)
+	if destroyed.EventType != UnitDestroyed || destroyed.TargetName != "Mana Tide Totem" {
+		t.Errorf("unexpected destroyed record %+v", destroyed)
+	}
+	if _, ok := destroyed.Typed().(UnitDestroyedEvent); !ok {
+		t.Errorf("expected a UnitDestroyedEvent, got %T", destroyed.Typed())
+	}
+	pets := newPetTracker()
+	pets.observe(mustParseRow(t, `12/11 00:30:48.000  SPELL_SUMMON,0x07000000007721EC,"Yogzar",0x511,0xF130005E4D000123,"Mana Tide Totem",0xa28,16190,"Mana Tide Totem",0x8`))
+	if owner, ok := pets.owner("0xF130005E4D000123"); !ok || owner != "Yogzar" {
+		t.Fatalf("expected the totem to be owned by Yogzar, got %q", owner)
+	}
+	pets.observe(destroyed)
+	if _, ok := pets.owner("0xF130005E4D000123"); ok {
+		t.Error("expected the destroyed totem to be forgotten")
+	}
+	durability := mustParseRow(t, `12/11 00:31:01.000  SPELL_DURABILITY_DAMAGE_ALL,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,57806,"Durability Damage",0x1`)
+	if durability.SpellAndRangePrefix == nil || durability.SpellAndRangePrefix.SpellID != 57806 {
+		t.Errorf("expected the durability damage spell, got %+v", durability.Prefix)
+	}
+}
+
+func TestParserUnknownEventHandler(t *testing.T) {
+	unknown := `12/11 00:13:39.000  SPELL_ABSORBED,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,69146,"Coldflame",0x10,"Power Word: Shield",1200`
+	path := filepath.Join(t.TempDir(), "WoWCombatLog.txt")
+	content := strings.Join([]string{
+		`12/11 00:13:38.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		unknown,
+	}, "\n")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{1, 2} {
+		var lines []string
+		data, err := New(WithLogFile(path), WithParallelism(n), WithUnknownEventHandler(func(line string) {
+			lines = append(lines, line)
+		})).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != 1 || lines[0] != unknown {
+			t.Errorf("parallelism %d: expected the unknown line, got %q", n, lines)
+		}
+		raw := data[1].Raw
+		want := []string{"69146", `"Coldflame"`, "0x10", `"Power Word: Shield"`, "1200"}
+		if data[0].Raw != nil || raw == nil || !reflect.DeepEqual(raw.Fields, want) {
+			t.Errorf("parallelism %d: expected the raw fields %q, got %+v", n, want, raw)
+		}
+	}
+}
+
+func TestParserStream(t *testing.T) {
+	p := newTestParser()
+	want, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, errc := p.Stream(context.Background())
+	n := 0
+	for v := range records {
+		if v.EventType != want[n].EventType {
+			t.Fatalf("record %d: expected %s, got %s", n, want[n].EventType, v.EventType)
+		}
+		n++
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if n != len(want) {
+		t.Errorf("expected %d streamed records, got %d", len(want), n)
+	}
+}
+
+func TestParserStreamCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	records, errc := newTestParser().Stream(ctx)
+	<-records
+	cancel()
+	for range records {
+	}
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestParserQuarantinesMalformedLines(t *testing.T) {
+	lines := strings.Join([]string{
+		`12/11 00:13:06.105  SWING_DAMAGE,0xF1300094280000B2,"Argent Champion",0xa18,0xF130009093000102,"The Damned",0xa48,40828,0,1,0,0,0,1,nil,nil`,
+		``,
+		`[Addon] hello from a chat frame`,
+		`12/11 00:13:06.330  SPELL_DAMAGE,0xF1300094280000B4,"Argent Champion",0xa18,0xF13000909300002B,"The Damned",0xa48,53625`,
+		`12/11 00:13:06.441  SWING_MISSED,0xF130009093000102,"The Damned",0xa48,0xF13000946C0000C9,"Ebon Champion",0xa28,MISS`,
+		`12/11 00:13:07.361  SPELL_PERIODIC_DAMAGE,0xF13000946C0000C9,"Ebon Champion",0xa28,0xF1300`,
+	}, "\n")
+	path := filepath.Join(t.TempDir(), "WoWCombatLog.txt")
+	if err := os.WriteFile(path, []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := New(WithLogFile(path))
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2 {
+		t.Fatalf("expected 2 parsed records, got %d", len(data))
+	}
+	report := p.Report()
+	if report.Lines != 6 || report.Parsed != 2 || report.Blank != 1 || len(report.Quarantined) != 3 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if report.Quarantined[0].Line != 3 {
+		t.Errorf("expected the addon line to be quarantined first, got %+v", report.Quarantined[0])
+	}
+}
+
+func TestParserParseReader(t *testing.T) {
+	f, err := os.Open("./testdata/test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	want, err := newTestParser().Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := New(WithReader(f)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected %d records from reader, got %d", len(want), len(got))
+	}
+}
+
+func TestParserNameSanitizers(t *testing.T) {
+	line := "12/11 00:13:06.105  SWING_DAMAGE,0x07000000007721EC,\"Yogzar-Lordaeron\",0x511,0x07000000009DF7A8,\"Winter \\\"injuly\\\"\x07\",0x514,40828,0,1,0,0,0,1,nil,nil\n" +
+		"12/11 00:13:07.105  SWING_DAMAGE,0xF130008FF7000052,\"Blood-Queen Lana'thel\",0x10a48,0x07000000007721EC,\"Yogzar-Lordaeron\",0x511,9000,0,1,0,0,0,nil,nil,nil"
+	p := New(
+		WithReader(strings.NewReader(line)),
+		WithNameSanitizer(TrimServerSuffix, StripControlChars),
+	)
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[0].SourceName != "Yogzar" {
+		t.Errorf("expected server suffix to be trimmed, got %q", data[0].SourceName)
+	}
+	if data[0].TargetName != `Winter "injuly"` {
+		t.Errorf("expected escaped quotes to be kept and control chars stripped, got %q", data[0].TargetName)
+	}
+	if data[1].SourceName != "Blood-Queen Lana'thel" || data[1].TargetName != "Yogzar" {
+		t.Errorf("expected only player names to be sanitized, got %q and %q", data[1].SourceName, data[1].TargetName)
+	}
+}
+
+func TestParserStrictMode(t *testing.T) {
+	lines := "12/11 00:13:06.441  SWING_MISSED,0xF130009093000102,\"The Damned\",0xa48,0xF13000946C0000C9,\"Ebon Champion\",0xa28,MISS\n" +
+		"12/11 00:13:07.361  SPELL_PERIODIC_DAMAGE,0xF13000946C0000C9,\"Ebon Champion\",0xa28,0xF130009093000102,\"The Damned\",0xa48,67932,\"Frost Fever\",0x10,abc,0,16,0,0,0,nil,nil,nil\n"
+	_, err := New(WithReader(strings.NewReader(lines)), WithStrictMode(true)).Parse()
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected a *ParseError, got %v", err)
+	}
+	if perr.Line != 2 || perr.Field != 10 {
+		t.Errorf("expected line 2 field 10, got line %d field %d", perr.Line, perr.Field)
+	}
+
+	p := New(WithReader(strings.NewReader(lines)))
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 1 || len(p.Skipped()) != 1 || p.Skipped()[0].Line != 2 {
+		t.Errorf("expected lenient mode to skip line 2, got %d records and %v", len(data), p.Skipped())
+	}
+}
+
+func TestLineErrorWrapsPlainErrors(t *testing.T) {
+	plain := errors.New("locale hook failed")
+	perr := lineError(plain, 3, []byte("raw line"))
+	if perr.Line != 3 || perr.Raw != "raw line" || perr.Field != -1 || !errors.Is(perr, plain) {
+		t.Errorf("expected the plain error wrapped as a malformed line, got %+v", perr)
+	}
+	field := &ParseError{Field: 10, Err: plain}
+	if got := lineError(fmt.Errorf("scope: %w", field), 4, nil); got != field || got.Line != 4 {
+		t.Errorf("expected the wrapped *ParseError to be reused, got %+v", got)
+	}
+}
+
+func TestParseRowHexSpellSchool(t *testing.T) {
+	v := mustParseRow(t, `12/11 00:13:07.361  SPELL_PERIODIC_DAMAGE,0xF13000946C0000C9,"Ebon Champion",0xa28,0xF130009093000102,"The Damned",0xa48,67932,"Frost Fever",0x10,2120,0,16,0,0,0,nil,nil,nil`)
+	if v.SpellAndRangePrefix.SpellSchool != Frost {
+		t.Errorf("expected Frost spell school, got %s", v.SpellAndRangePrefix.SpellSchool)
+	}
+}
+
+func TestParserLimits(t *testing.T) {
+	line := `12/11 00:13:06.105  SWING_DAMAGE,0xF1300094280000B2,"Argent Champion",0xa18,0xF130009093000102,"The Damned",0xa48,40828,0,1,0,0,0,1,nil,nil`
+	lines := strings.Repeat(line+"\n", 10)
+	cases := []struct {
+		limits Limits
+		input  string
+		limit  string
+	}{
+		{Limits{MaxBytes: 100}, lines, LimitBytes},
+		{Limits{MaxLines: 5}, lines, LimitLines},
+		{Limits{MaxLineLength: 64}, lines, LimitLineLength},
+		{Limits{MaxSkipped: 2}, strings.Repeat("garbage\n", 3), LimitSkipped},
+	}
+	for _, c := range cases {
+		_, err := New(WithReader(strings.NewReader(c.input)), WithLimits(c.limits)).Parse()
+		var lerr *LimitError
+		if !errors.As(err, &lerr) || !errors.Is(err, ErrLimitExceeded) {
+			t.Fatalf("expected a *LimitError for %s, got %v", c.limit, err)
+		}
+		if lerr.Limit != c.limit {
+			t.Errorf("expected the %s limit to be exceeded, got %s", c.limit, lerr.Limit)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "WoWCombatLog.txt")
+	if err := os.WriteFile(path, []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(WithLogFile(path), WithLimits(Limits{MaxBytes: 100})).Parse(); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected oversized file to be rejected, got %v", err)
+	}
+	data, err := New(WithLogFile(path), WithLimits(Limits{MaxLines: 10, MaxBytes: 1 << 20})).Parse()
+	if err != nil || len(data) != 10 {
+		t.Errorf("expected input within limits to parse, got %d records and %v", len(data), err)
+	}
+}
+
+func TestParseRowQuotedComma(t *testing.T) {
+	row := mustParseRow(t, `12/11 00:13:21.795  ENCHANT_APPLIED,0x07000000007721EC,"Yogzar",0x511,0x07000000007721EC,"Yogzar",0x511,"Earthliving 6",46017,"Val'anyr, Hammer of Ancient Kings"`)
+	if row.EnchantPrefix.ItemName != "Val'anyr, Hammer of Ancient Kings" {
+		t.Errorf("expected the comma to stay in the item name, got %q", row.EnchantPrefix.ItemName)
+	}
+}
+
+func TestParseRowQuotedNames(t *testing.T) {
+	for _, tc := range []struct {
+		field, want string
+	}{
+		{`"Kor'kron Sergeant, Elite"`, "Kor'kron Sergeant, Elite"},
+		{`"Say \"hi\", friend"`, `Say "hi", friend`},
+		{`"Back\\slash"`, `Back\slash`},
+		{`"Trailing\\"`, `Trailing\`},
+		{`nil`, "nil"},
+	} {
+		row := mustParseRow(t, `12/11 01:08:00.000  SPELL_DAMAGE,0xF130008F0400003D,`+tc.field+`,0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,69146,"Coldflame, Rank 2",0x10,9000,0,16,0,0,0,nil,nil,nil`)
+		if row.SourceName != tc.want {
+			t.Errorf("expected %s to read as %q, got %q", tc.field, tc.want, row.SourceName)
+		}
+		// the fields after the name must keep their offsets
+		if row.TargetName != "Winterinjuly" || row.SpellAndRangePrefix.SpellName != "Coldflame, Rank 2" || row.DamageSuffix.Amount != 9000 {
+			t.Errorf("%s: fields after the name are misaligned: %+v", tc.field, row)
+		}
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want time.Time
+		ok   bool
+	}{
+		{"12/11 00:13:06.105", time.Date(2023, 12, 11, 0, 13, 6, 105e6, time.UTC), true},
+		{"1/5 9:07:00.000", time.Date(2023, 1, 5, 9, 7, 0, 0, time.UTC), true},
+		{"2/30 00:00:00.000", time.Time{}, false},
+		{"13/1 00:00:00.000", time.Time{}, false},
+		{"12/11 00:13:06.10", time.Time{}, false},
+		{"12/11 00:13:06.105x", time.Time{}, false},
+		{"12/11 00:3:06.105", time.Time{}, false},
+	} {
+		got, err := parseTimestamp([]byte(tc.in), 2023, time.UTC)
+		if (err == nil) != tc.ok || !got.Equal(tc.want) {
+			t.Errorf("parseTimestamp(%q) = %s, %v", tc.in, got, err)
+		}
+		layout, err := time.Parse("2006/1/_2 15:04:05.000", "2023/"+tc.in)
+		if (err == nil) != tc.ok || !layout.Equal(got) {
+			t.Errorf("parseTimestamp(%q) disagrees with time.Parse: %s, %v", tc.in, layout, err)
+		}
+	}
+}
+
+func BenchmarkParseRow(b *testing.B) {
+	line := []byte(`12/11 00:13:39.000  SPELL_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130009093000102,"The Damned",0xa48,49050,"Aimed Shot",0x1,1000,0,1,0,0,0,1,nil,nil`)
+	clock := &logClock{year: time.Now().Year(), fixed: true}
+	names := newInterner()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := parseLine(clock, line, names); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
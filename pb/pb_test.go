@@ -0,0 +1,124 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pb
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+func parseTestLog(t *testing.T) []*frostparse.CombatLogRecord {
+	t.Helper()
+	data, err := frostparse.New(frostparse.WithLogFile("../testdata/test.txt")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestRecordRoundTrip(t *testing.T) {
+	want := parseTestLog(t)
+	var buf bytes.Buffer
+	if err := WriteRecords(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadRecords(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d records, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Fatalf("record %d does not round trip:\nwant %+v\ngot  %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestMarshalRecordWireFormat(t *testing.T) {
+	rec := &frostparse.CombatLogRecord{}
+	rec.Timestamp = time.Unix(0, 0) // encoded as 0, and so left out
+	rec.EventType = frostparse.SwingDamage
+	rec.DamageSuffix = &frostparse.DamageSuffix{Amount: 300, Critical: true}
+	want := []byte{
+		0x1a, 12, 'S', 'W', 'I', 'N', 'G', '_', 'D', 'A', 'M', 'A', 'G', 'E', // event_type
+		0x6a, 5, 0x08, 0xac, 0x02, 0x38, 0x01, // damage {amount: 300, critical: true}
+	}
+	if got := MarshalRecord(rec); !bytes.Equal(got, want) {
+		t.Errorf("unexpected encoding\nwant % x\ngot  % x", want, got)
+	}
+}
+
+func TestUnmarshalRecordSkipsUnknownFields(t *testing.T) {
+	var e encoder
+	e.string(3, "UNIT_DIED")
+	e.string(99, "added in a later version")
+	e.double(100, 1.5)
+	rec, err := UnmarshalRecord(e.b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.EventType != frostparse.UnitDied {
+		t.Errorf("expected UNIT_DIED, got %q", rec.EventType)
+	}
+	if _, err := UnmarshalRecord(e.b[:len(e.b)-1]); !errors.Is(err, ErrTruncated) {
+		t.Errorf("expected a truncated message to fail, got %v", err)
+	}
+}
+
+func TestSummaryRoundTrip(t *testing.T) {
+	want := frostparse.NewCollector().Run(parseTestLog(t))
+	b := MarshalSummary(want)
+	got, err := UnmarshalSummary(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(MarshalSummary(got), b) {
+		t.Error("expected the decoded summary to encode to the same bytes")
+	}
+	for _, c := range []struct {
+		name      string
+		want, got any
+	}{
+		{"DamageDoneOverTime", want.DamageDoneOverTime, got.DamageDoneOverTime},
+		{"DamageBySource", want.DamageBySource, got.DamageBySource},
+		{"DamageBySourceAndSpell", want.DamageBySourceAndSpell, got.DamageBySourceAndSpell},
+		{"HealingBySourceAndSpell", want.HealingBySourceAndSpell, got.HealingBySourceAndSpell},
+		{"ActivityBySource", want.ActivityBySource, got.ActivityBySource},
+		{"ActiveTimeBySource", want.ActiveTimeBySource, got.ActiveTimeBySource},
+	} {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s does not round trip:\nwant %v\ngot  %v", c.name, c.want, c.got)
+		}
+	}
+	if len(got.Encounters) != len(want.Encounters) {
+		t.Fatalf("expected %d encounters, got %d", len(want.Encounters), len(got.Encounters))
+	}
+	for i, enc := range want.Encounters {
+		enc.Records = nil
+		enc.Annotations = nil
+		if !reflect.DeepEqual(got.Encounters[i], enc) {
+			t.Errorf("encounter %d does not round trip:\nwant %+v\ngot  %+v", i, enc, got.Encounters[i])
+		}
+	}
+}
@@ -0,0 +1,413 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// MarshalRecord encodes rec as a CombatLogRecord message.
+func MarshalRecord(rec *frostparse.CombatLogRecord) []byte {
+	var e encoder
+	encodeRecord(&e, rec)
+	return e.b
+}
+
+func encodeRecord(e *encoder, r *frostparse.CombatLogRecord) {
+	e.int(1, r.Timestamp.UnixNano())
+	if !r.AdjustedTimestamp.IsZero() {
+		e.int(2, r.AdjustedTimestamp.UnixNano())
+	}
+	e.string(3, string(r.EventType))
+	e.string(4, r.SourceID)
+	e.string(5, r.SourceName)
+	e.uint(6, uint64(r.SourceFlags))
+	e.string(7, r.TargetID)
+	e.string(8, r.TargetName)
+	e.uint(9, uint64(r.TargetFlags))
+	if p := r.SpellAndRangePrefix; p != nil {
+		e.message(10, func(e *encoder) {
+			e.uint(1, p.SpellID)
+			e.string(2, p.SpellName)
+			e.uint(3, uint64(p.SpellSchool))
+		})
+	}
+	if p := r.EnchantPrefix; p != nil {
+		e.message(11, func(e *encoder) {
+			e.string(1, p.SpellName)
+			e.uint(2, p.ItemID)
+			e.string(3, p.ItemName)
+		})
+	}
+	if p := r.EnvironmentalPrefix; p != nil {
+		e.message(12, func(e *encoder) {
+			e.string(1, string(p.EnvironmentalType))
+		})
+	}
+	if s := r.DamageSuffix; s != nil {
+		e.message(13, func(e *encoder) {
+			e.uint(1, s.Amount)
+			e.uint(2, s.Overkill)
+			e.uint(3, uint64(s.SpellSchool))
+			e.uint(4, s.Resisted)
+			e.uint(5, s.Blocked)
+			e.uint(6, s.Absorbed)
+			e.bool(7, s.Critical)
+			e.bool(8, s.Glancing)
+			e.bool(9, s.Crushing)
+		})
+	}
+	if s := r.AuraSuffix; s != nil {
+		e.message(14, func(e *encoder) {
+			e.string(1, string(s.AuraType))
+			e.uint(2, s.Doses)
+		})
+	}
+	if s := r.EnergizeSuffix; s != nil {
+		e.message(15, func(e *encoder) {
+			e.int(1, s.Amount)
+			e.int(2, int64(s.PowerType))
+		})
+	}
+	if s := r.MissSuffix; s != nil {
+		e.message(16, func(e *encoder) {
+			e.string(1, string(s.MissType))
+			e.bool(2, s.IsOffHand)
+			e.uint(3, s.Amount)
+		})
+	}
+	if s := r.HealSuffix; s != nil {
+		e.message(17, func(e *encoder) {
+			e.uint(1, s.Amount)
+			e.uint(2, s.Overhealing)
+			e.uint(3, s.Absorbed)
+			e.bool(4, s.Critical)
+		})
+	}
+	if s := r.InterruptSuffix; s != nil {
+		e.message(18, func(e *encoder) {
+			e.uint(1, s.ExtraSpellID)
+			e.string(2, s.ExtraSpellName)
+			e.uint(3, uint64(s.ExtraSpellSchool))
+		})
+	}
+	if s := r.ExtraAttacksSuffix; s != nil {
+		e.message(19, func(e *encoder) {
+			e.uint(1, s.Amount)
+		})
+	}
+	if s := r.DispelOrStolenSuffix; s != nil {
+		e.message(20, func(e *encoder) {
+			e.uint(1, s.ExtraSpellID)
+			e.string(2, s.ExtraSpellName)
+			e.uint(3, uint64(s.ExtraSpellSchool))
+			e.string(4, string(s.AuraType))
+		})
+	}
+	if s := r.LeechOrDrainSuffix; s != nil {
+		e.message(21, func(e *encoder) {
+			e.uint(1, s.Amount)
+			e.int(2, int64(s.PowerType))
+			e.uint(3, s.ExtraAmount)
+		})
+	}
+	if raw := r.Raw; raw != nil {
+		e.message(22, func(e *encoder) {
+			for _, f := range raw.Fields {
+				e.bytes(1, f)
+			}
+		})
+	}
+}
+
+// UnmarshalRecord decodes a CombatLogRecord message. Timestamps are in UTC.
+func UnmarshalRecord(b []byte) (*frostparse.CombatLogRecord, error) {
+	r := &frostparse.CombatLogRecord{}
+	adjusted := false
+	err := fields(b, func(f field) error {
+		switch f.num {
+		case 1:
+			r.Timestamp = unixNano(f.int())
+		case 2:
+			r.AdjustedTimestamp = unixNano(f.int())
+			adjusted = true
+		case 3:
+			r.EventType = frostparse.EventType(f.string())
+		case 4:
+			r.SourceID = f.string()
+		case 5:
+			r.SourceName = f.string()
+		case 6:
+			r.SourceFlags = frostparse.UnitFlags(f.u)
+		case 7:
+			r.TargetID = f.string()
+		case 8:
+			r.TargetName = f.string()
+		case 9:
+			r.TargetFlags = frostparse.UnitFlags(f.u)
+		case 10:
+			p := &frostparse.SpellAndRangePrefix{}
+			r.SpellAndRangePrefix = p
+			return fields(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					p.SpellID = f.u
+				case 2:
+					p.SpellName = f.string()
+				case 3:
+					p.SpellSchool = frostparse.SpellSchool(f.u)
+				}
+				return nil
+			})
+		case 11:
+			p := &frostparse.EnchantPrefix{}
+			r.EnchantPrefix = p
+			return fields(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					p.SpellName = f.string()
+				case 2:
+					p.ItemID = f.u
+				case 3:
+					p.ItemName = f.string()
+				}
+				return nil
+			})
+		case 12:
+			p := &frostparse.EnvironmentalPrefix{}
+			r.EnvironmentalPrefix = p
+			return fields(f.b, func(f field) error {
+				if f.num == 1 {
+					p.EnvironmentalType = frostparse.EnvironmentalType(f.string())
+				}
+				return nil
+			})
+		case 13:
+			s := &frostparse.DamageSuffix{}
+			r.DamageSuffix = s
+			return fields(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					s.Amount = f.u
+				case 2:
+					s.Overkill = f.u
+				case 3:
+					s.SpellSchool = frostparse.SpellSchool(f.u)
+				case 4:
+					s.Resisted = f.u
+				case 5:
+					s.Blocked = f.u
+				case 6:
+					s.Absorbed = f.u
+				case 7:
+					s.Critical = f.bool()
+				case 8:
+					s.Glancing = f.bool()
+				case 9:
+					s.Crushing = f.bool()
+				}
+				return nil
+			})
+		case 14:
+			s := &frostparse.AuraSuffix{}
+			r.AuraSuffix = s
+			return fields(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					s.AuraType = frostparse.AuraType(f.string())
+				case 2:
+					s.Doses = f.u
+				}
+				return nil
+			})
+		case 15:
+			s := &frostparse.EnergizeSuffix{}
+			r.EnergizeSuffix = s
+			return fields(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					s.Amount = f.int()
+				case 2:
+					s.PowerType = frostparse.PowerType(int32(f.u))
+				}
+				return nil
+			})
+		case 16:
+			s := &frostparse.MissSuffix{}
+			r.MissSuffix = s
+			return fields(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					s.MissType = frostparse.MissType(f.string())
+				case 2:
+					s.IsOffHand = f.bool()
+				case 3:
+					s.Amount = f.u
+				}
+				return nil
+			})
+		case 17:
+			s := &frostparse.HealSuffix{}
+			r.HealSuffix = s
+			return fields(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					s.Amount = f.u
+				case 2:
+					s.Overhealing = f.u
+				case 3:
+					s.Absorbed = f.u
+				case 4:
+					s.Critical = f.bool()
+				}
+				return nil
+			})
+		case 18:
+			s := &frostparse.InterruptSuffix{}
+			r.InterruptSuffix = s
+			return fields(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					s.ExtraSpellID = f.u
+				case 2:
+					s.ExtraSpellName = f.string()
+				case 3:
+					s.ExtraSpellSchool = frostparse.SpellSchool(f.u)
+				}
+				return nil
+			})
+		case 19:
+			s := &frostparse.ExtraAttacksSuffix{}
+			r.ExtraAttacksSuffix = s
+			return fields(f.b, func(f field) error {
+				if f.num == 1 {
+					s.Amount = f.u
+				}
+				return nil
+			})
+		case 20:
+			s := &frostparse.DispelOrStolenSuffix{}
+			r.DispelOrStolenSuffix = s
+			return fields(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					s.ExtraSpellID = f.u
+				case 2:
+					s.ExtraSpellName = f.string()
+				case 3:
+					s.ExtraSpellSchool = frostparse.SpellSchool(f.u)
+				case 4:
+					s.AuraType = frostparse.AuraType(f.string())
+				}
+				return nil
+			})
+		case 21:
+			s := &frostparse.LeechOrDrainSuffix{}
+			r.LeechOrDrainSuffix = s
+			return fields(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					s.Amount = f.u
+				case 2:
+					s.PowerType = frostparse.PowerType(int32(f.u))
+				case 3:
+					s.ExtraAmount = f.u
+				}
+				return nil
+			})
+		case 22:
+			raw := &frostparse.RawEvent{Fields: []string{}}
+			r.Raw = raw
+			return fields(f.b, func(f field) error {
+				if f.num == 1 {
+					raw.Fields = append(raw.Fields, f.string())
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !adjusted {
+		r.AdjustedTimestamp = r.Timestamp
+	}
+	return r, nil
+}
+
+func unixNano(n int64) time.Time {
+	return time.Unix(0, n).UTC()
+}
+
+// WriteRecords writes every record to w as a CombatLogRecord message
+// prefixed with its varint encoded length, the framing read by
+// parseDelimitedFrom in the protobuf libraries.
+func WriteRecords(w io.Writer, records []*frostparse.CombatLogRecord) error {
+	bw := bufio.NewWriter(w)
+	var e encoder
+	for _, rec := range records {
+		e.b = e.b[:0]
+		encodeRecord(&e, rec)
+		var size [binary.MaxVarintLen64]byte
+		if _, err := bw.Write(size[:binary.PutUvarint(size[:], uint64(len(e.b)))]); err != nil {
+			return err
+		}
+		if _, err := bw.Write(e.b); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadRecords reads the length delimited records written by WriteRecords
+// until EOF.
+func ReadRecords(r io.Reader) ([]*frostparse.CombatLogRecord, error) {
+	br := bufio.NewReader(r)
+	out := []*frostparse.CombatLogRecord{}
+	var buf []byte
+	for {
+		size, err := binary.ReadUvarint(br)
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return out, err
+		}
+		if uint64(cap(buf)) < size {
+			buf = make([]byte, size)
+		}
+		buf = buf[:size]
+		if _, err := io.ReadFull(br, buf); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = ErrTruncated
+			}
+			return out, err
+		}
+		rec, err := UnmarshalRecord(buf)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, rec)
+	}
+}
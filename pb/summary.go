@@ -0,0 +1,306 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pb
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// MarshalSummary encodes s as a SummaryStats message. Maps are written in
+// key order, so equal summaries encode to the same bytes.
+func MarshalSummary(s *frostparse.SummaryStats) []byte {
+	var e encoder
+	buckets := func(num int, m map[time.Time]uint64) {
+		times := make([]time.Time, 0, len(m))
+		for t := range m {
+			times = append(times, t)
+		}
+		sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+		for _, t := range times {
+			e.message(num, func(e *encoder) {
+				e.int(1, t.UnixNano())
+				e.uint(2, m[t])
+			})
+		}
+	}
+	totals := func(num int, m map[string]uint64) {
+		mapEntries(&e, num, m, stringKey, uintValue)
+	}
+	buckets(1, s.DamageDoneOverTime)
+	buckets(2, s.HealingDoneOverTime)
+	buckets(3, s.DamageTakenOverTime)
+	mapEntries(&e, 4, s.EncounterOverlays, stringKey, func(e *encoder, enc frostparse.Encounter) {
+		e.message(2, func(e *encoder) { encodeEncounter(e, enc) })
+	})
+	totals(5, s.DamageBySource)
+	totals(6, s.HealingBySource)
+	totals(7, s.DamageTakenBySource)
+	totals(8, s.DamageTakenBySpell)
+	totals(9, s.InterruptsBySource)
+	totals(10, s.DispelsBySource)
+	totals(11, s.FailedDispelsBySource)
+	mapEntries(&e, 12, s.DamageBySourceAndSpell, stringKey, func(e *encoder, b *frostparse.SpellBreakdown) {
+		e.message(2, func(e *encoder) {
+			mapEntries(e, 1, b.Spells, stringKey, uintValue)
+			mapEntries(e, 2, b.Pets, stringKey, func(e *encoder, spells map[string]uint64) {
+				e.message(2, func(e *encoder) {
+					mapEntries(e, 1, spells, stringKey, uintValue)
+				})
+			})
+		})
+	})
+	mapEntries(&e, 13, s.HealingBySourceAndSpell, stringKey, func(e *encoder, spells map[string]*frostparse.SpellHealing) {
+		e.message(2, func(e *encoder) {
+			mapEntries(e, 1, spells, stringKey, func(e *encoder, h *frostparse.SpellHealing) {
+				e.message(2, func(e *encoder) {
+					e.uint(1, h.Direct)
+					e.uint(2, h.Periodic)
+					e.uint(3, h.Absorbed)
+					e.uint(4, h.Overhealing)
+				})
+			})
+		})
+	})
+	mapEntries(&e, 14, s.ActivityBySource, stringKey, func(e *encoder, v float64) { e.double(2, v) })
+	for _, enc := range s.Encounters {
+		e.message(15, func(e *encoder) { encodeEncounter(e, enc) })
+	}
+	mapEntries(&e, 16, s.DamageTakenByGroup, intKey, uintValue)
+	mapEntries(&e, 17, s.HealingReceivedByGroup, intKey, uintValue)
+	mapEntries(&e, 18, s.ActiveTimeBySource, stringKey, func(e *encoder, d time.Duration) { e.int(2, int64(d)) })
+	return e.b
+}
+
+func stringKey(e *encoder, k string) { e.string(1, k) }
+func intKey(e *encoder, k int)       { e.int(1, int64(k)) }
+func uintValue(e *encoder, v uint64) { e.uint(2, v) }
+
+func encodeEncounter(e *encoder, enc frostparse.Encounter) {
+	e.string(1, enc.Name)
+	e.int(2, int64(enc.Attempt))
+	e.string(3, string(enc.Result))
+	e.bool(4, enc.Trash)
+	for _, mob := range enc.Mobs {
+		e.bytes(5, mob)
+	}
+	e.int(6, enc.StartTime.UnixNano())
+	e.int(7, enc.EndTime.UnixNano())
+	for _, p := range enc.Pauses {
+		e.message(8, func(e *encoder) {
+			e.int(1, p.Start.UnixNano())
+			e.int(2, p.End.UnixNano())
+		})
+	}
+}
+
+func decodeEncounter(b []byte) (frostparse.Encounter, error) {
+	var enc frostparse.Encounter
+	err := fields(b, func(f field) error {
+		switch f.num {
+		case 1:
+			enc.Name = f.string()
+		case 2:
+			enc.Attempt = int(int32(f.u))
+		case 3:
+			enc.Result = frostparse.EncounterResult(f.string())
+		case 4:
+			enc.Trash = f.bool()
+		case 5:
+			enc.Mobs = append(enc.Mobs, f.string())
+		case 6:
+			enc.StartTime = unixNano(f.int())
+		case 7:
+			enc.EndTime = unixNano(f.int())
+		case 8:
+			var p frostparse.EncounterPause
+			err := fields(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					p.Start = unixNano(f.int())
+				case 2:
+					p.End = unixNano(f.int())
+				}
+				return nil
+			})
+			enc.Pauses = append(enc.Pauses, p)
+			return err
+		}
+		return nil
+	})
+	return enc, err
+}
+
+// UnmarshalSummary decodes a SummaryStats message. Every map of the result is
+// allocated, as it is for summaries returned by a Collector, and times are in
+// UTC.
+func UnmarshalSummary(b []byte) (*frostparse.SummaryStats, error) {
+	s := &frostparse.SummaryStats{
+		DamageDoneOverTime:      map[time.Time]uint64{},
+		HealingDoneOverTime:     map[time.Time]uint64{},
+		DamageTakenOverTime:     map[time.Time]uint64{},
+		EncounterOverlays:       map[string]frostparse.Encounter{},
+		DamageBySource:          map[string]uint64{},
+		HealingBySource:         map[string]uint64{},
+		DamageTakenBySource:     map[string]uint64{},
+		DamageTakenBySpell:      map[string]uint64{},
+		InterruptsBySource:      map[string]uint64{},
+		DispelsBySource:         map[string]uint64{},
+		FailedDispelsBySource:   map[string]uint64{},
+		DamageBySourceAndSpell:  map[string]*frostparse.SpellBreakdown{},
+		HealingBySourceAndSpell: map[string]map[string]*frostparse.SpellHealing{},
+		ActivityBySource:        map[string]float64{},
+		DamageTakenByGroup:      map[int]uint64{},
+		HealingReceivedByGroup:  map[int]uint64{},
+		ActiveTimeBySource:      map[string]time.Duration{},
+	}
+	s.HealingpDoneOverTime = s.HealingDoneOverTime
+	s.DispellsBySource = s.DispelsBySource
+	totals := map[int]map[string]uint64{
+		5:  s.DamageBySource,
+		6:  s.HealingBySource,
+		7:  s.DamageTakenBySource,
+		8:  s.DamageTakenBySpell,
+		9:  s.InterruptsBySource,
+		10: s.DispelsBySource,
+		11: s.FailedDispelsBySource,
+	}
+	overTime := map[int]map[time.Time]uint64{
+		1: s.DamageDoneOverTime,
+		2: s.HealingDoneOverTime,
+		3: s.DamageTakenOverTime,
+	}
+	err := fields(b, func(f field) error {
+		if m, ok := overTime[f.num]; ok {
+			var t time.Time
+			var amount uint64
+			err := fields(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					t = unixNano(f.int())
+				case 2:
+					amount = f.u
+				}
+				return nil
+			})
+			m[t] = amount
+			return err
+		}
+		if f.num == 15 {
+			enc, err := decodeEncounter(f.b)
+			s.Encounters = append(s.Encounters, enc)
+			return err
+		}
+		if f.num < 4 || f.num > 18 {
+			return nil
+		}
+		// every other field is a map
+		key, value, err := mapEntry(f.b)
+		if err != nil {
+			return err
+		}
+		if m, ok := totals[f.num]; ok {
+			m[key.string()] = value.u
+			return nil
+		}
+		switch f.num {
+		case 4:
+			enc, err := decodeEncounter(value.b)
+			s.EncounterOverlays[key.string()] = enc
+			return err
+		case 12:
+			b, err := decodeSpellBreakdown(value.b)
+			s.DamageBySourceAndSpell[key.string()] = b
+			return err
+		case 13:
+			spells := map[string]*frostparse.SpellHealing{}
+			s.HealingBySourceAndSpell[key.string()] = spells
+			return fields(value.b, func(f field) error {
+				if f.num != 1 {
+					return nil
+				}
+				key, value, err := mapEntry(f.b)
+				if err != nil {
+					return err
+				}
+				h := &frostparse.SpellHealing{}
+				spells[key.string()] = h
+				return fields(value.b, func(f field) error {
+					switch f.num {
+					case 1:
+						h.Direct = f.u
+					case 2:
+						h.Periodic = f.u
+					case 3:
+						h.Absorbed = f.u
+					case 4:
+						h.Overhealing = f.u
+					}
+					return nil
+				})
+			})
+		case 14:
+			s.ActivityBySource[key.string()] = value.double()
+		case 16:
+			s.DamageTakenByGroup[int(int32(key.u))] = value.u
+		case 17:
+			s.HealingReceivedByGroup[int(int32(key.u))] = value.u
+		case 18:
+			s.ActiveTimeBySource[key.string()] = time.Duration(value.int())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func decodeSpellBreakdown(b []byte) (*frostparse.SpellBreakdown, error) {
+	out := &frostparse.SpellBreakdown{Spells: map[string]uint64{}}
+	err := fields(b, func(f field) error {
+		if f.num != 1 && f.num != 2 {
+			return nil
+		}
+		key, value, err := mapEntry(f.b)
+		if err != nil {
+			return err
+		}
+		switch f.num {
+		case 1:
+			out.Spells[key.string()] = value.u
+		case 2:
+			spells := map[string]uint64{}
+			if out.Pets == nil {
+				out.Pets = map[string]map[string]uint64{}
+			}
+			out.Pets[key.string()] = spells
+			return fields(value.b, func(f field) error {
+				if f.num != 1 {
+					return nil
+				}
+				key, value, err := mapEntry(f.b)
+				spells[key.string()] = value.u
+				return err
+			})
+		}
+		return nil
+	})
+	return out, err
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pb encodes parsed records and summaries in the protobuf wire
+// format described by frostparse.proto, so they can be shipped over gRPC or
+// Kafka and read by services in other languages with generated code. The
+// encoding is written by hand to keep frostparse free of dependencies.
+package pb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// ErrTruncated is returned when a message ends in the middle of a field.
+var ErrTruncated = errors.New("pb: truncated message")
+
+// encoder appends fields to a message. Scalar fields with their zero value
+// are left out as proto3 does, while message fields are always written so
+// their presence is kept.
+type encoder struct {
+	b []byte
+}
+
+func (e *encoder) tag(num, typ int) {
+	e.b = binary.AppendUvarint(e.b, uint64(num)<<3|uint64(typ))
+}
+
+func (e *encoder) uint(num int, v uint64) {
+	if v != 0 {
+		e.tag(num, wireVarint)
+		e.b = binary.AppendUvarint(e.b, v)
+	}
+}
+
+// int writes an int32 or int64 field, which protobuf encodes as the two's
+// complement of the value.
+func (e *encoder) int(num int, v int64) {
+	e.uint(num, uint64(v))
+}
+
+func (e *encoder) bool(num int, v bool) {
+	if v {
+		e.uint(num, 1)
+	}
+}
+
+func (e *encoder) double(num int, v float64) {
+	if v != 0 {
+		e.tag(num, wireFixed64)
+		e.b = binary.LittleEndian.AppendUint64(e.b, math.Float64bits(v))
+	}
+}
+
+func (e *encoder) string(num int, s string) {
+	if s != "" {
+		e.bytes(num, s)
+	}
+}
+
+// bytes writes a length delimited field even when it is empty, as repeated
+// strings must be.
+func (e *encoder) bytes(num int, s string) {
+	e.tag(num, wireBytes)
+	e.b = binary.AppendUvarint(e.b, uint64(len(s)))
+	e.b = append(e.b, s...)
+}
+
+func (e *encoder) message(num int, fn func(*encoder)) {
+	var m encoder
+	fn(&m)
+	e.tag(num, wireBytes)
+	e.b = binary.AppendUvarint(e.b, uint64(len(m.b)))
+	e.b = append(e.b, m.b...)
+}
+
+// mapEntries writes a map as repeated entry messages in key order, so equal
+// maps always encode to the same bytes.
+func mapEntries[K string | int, V any](e *encoder, num int, m map[K]V, key func(*encoder, K), value func(*encoder, V)) {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for _, k := range keys {
+		e.message(num, func(entry *encoder) {
+			key(entry, k)
+			value(entry, m[k])
+		})
+	}
+}
+
+// field is a single field read from a message.
+type field struct {
+	num int
+	typ int
+	// u is the value of varint and fixed fields.
+	u uint64
+	// b is the value of length delimited fields.
+	b []byte
+}
+
+func (f field) int() int64      { return int64(f.u) }
+func (f field) bool() bool      { return f.u != 0 }
+func (f field) string() string  { return string(f.b) }
+func (f field) double() float64 { return math.Float64frombits(f.u) }
+
+// fields calls fn with every field of the message in b. Fields fn does not
+// know are skipped by it, so newer messages can still be read.
+func fields(b []byte, fn func(field) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return ErrTruncated
+		}
+		b = b[n:]
+		f := field{num: int(key >> 3), typ: int(key & 7)}
+		switch f.typ {
+		case wireVarint:
+			if f.u, n = binary.Uvarint(b); n <= 0 {
+				return ErrTruncated
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return ErrTruncated
+			}
+			f.u, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return ErrTruncated
+			}
+			f.u, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < size {
+				return ErrTruncated
+			}
+			f.b, b = b[n:n+int(size)], b[n+int(size):]
+		default:
+			return fmt.Errorf("pb: unsupported wire type %d in field %d", f.typ, f.num)
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapEntry reads the key and value fields of a map entry.
+func mapEntry(b []byte) (key, value field, err error) {
+	err = fields(b, func(f field) error {
+		switch f.num {
+		case 1:
+			key = f
+		case 2:
+			value = f
+		}
+		return nil
+	})
+	return key, value, err
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "maps"
+
+// petOwnerSpells are spells that are only ever cast by a player on their own
+// pet, so seeing one of them links the pet GUID to its owner. The 3.3.5a
+// combat log has no owner field, so this is the only signal available for
+// permanent pets (hunter, warlock, DK ghoul) that are not summoned mid-log.
+var petOwnerSpells []string = []string{
+	"Go for the Throat",
+	"Fel Synergy",
+	"Mend Pet",
+	"Dark Pact",
+	"Life Tap",
+	"Soul Link",
+	"Demonic Empowerment",
+	"Kill Command",
+	"Feed Pet",
+	"Ghoul Frenzy",
+}
+
+// petTracker maps pet and guardian GUIDs to the name of the player that owns
+// them.
+type petTracker struct {
+	owners map[string]string
+}
+
+func newPetTracker() *petTracker {
+	return &petTracker{
+		owners: map[string]string{},
+	}
+}
+
+// clone returns a copy of the tracker with its own ownership map.
+func (t *petTracker) clone() *petTracker {
+	return &petTracker{
+		owners: maps.Clone(t.owners),
+	}
+}
+
+// observe records pet ownership from SPELL_SUMMON events and from owner-only
+// pet spells cast by a player, and forgets summons once UNIT_DESTROYED or
+// UNIT_DISSIPATES removes them.
+func (p *petTracker) observe(row CombatLogRecord) {
+	if row.EventType == UnitDestroyed || row.EventType == UnitDissipates {
+		delete(p.owners, row.TargetID)
+		return
+	}
+	if !isPlayerID(row.SourceID) || isPlayerID(row.TargetID) {
+		return
+	}
+	if row.EventType == SpellSummon {
+		p.owners[row.TargetID] = row.SourceName
+		return
+	}
+	if row.SpellAndRangePrefix != nil && isPetID(row.TargetID) &&
+		sliceContains(petOwnerSpells, row.SpellAndRangePrefix.SpellName) {
+		p.owners[row.TargetID] = row.SourceName
+	}
+}
+
+// owner returns the owning player's name for a pet or guardian GUID.
+func (p *petTracker) owner(guid string) (string, bool) {
+	name, ok := p.owners[guid]
+	return name, ok
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"sort"
+	"time"
+)
+
+// IdleWindow is a stretch of an attempt in which a boss did not swing at
+// any of its tanks, because it was moving, being repositioned or had no
+// tank in range.
+type IdleWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Offset is how far into the attempt the window started.
+	Offset time.Duration `json:"offset"`
+}
+
+// Duration returns the length of the window.
+func (w IdleWindow) Duration() time.Duration {
+	return w.End.Sub(w.Start)
+}
+
+// BossPositioning describes how steadily one boss unit was tanked during one
+// attempt, as a proxy for the quality of its positioning.
+type BossPositioning struct {
+	Encounter string          `json:"encounter"`
+	Attempt   int             `json:"attempt"`
+	Result    EncounterResult `json:"result"`
+	Boss      string          `json:"boss"`
+	Duration  time.Duration   `json:"duration"`
+	// Tanks are the players the boss swung at at least MinTankSwings times.
+	Tanks  []string `json:"tanks"`
+	Swings int      `json:"swings"`
+	// SwingInterval is the median time between swings at the tanks.
+	SwingInterval time.Duration `json:"swing_interval"`
+	Windows       []IdleWindow  `json:"windows"`
+}
+
+// IdleTime returns the combined length of every idle window.
+func (b BossPositioning) IdleTime() time.Duration {
+	var d time.Duration
+	for _, w := range b.Windows {
+		d += w.Duration()
+	}
+	return d
+}
+
+// Uptime returns the share of the attempt the boss was swinging at a tank,
+// from 0 to 100.
+func (b BossPositioning) Uptime() float64 {
+	if b.Duration <= 0 {
+		return 0
+	}
+	return 100 * float64(b.Duration-b.IdleTime()) / float64(b.Duration)
+}
+
+// PositioningAnalyzer finds the windows in which a boss dealt no melee to
+// its tanks. Bosses that never melee, or stop meleeing during a phase, have
+// idle windows by design, so compare attempts at the same boss rather than
+// different bosses.
+type PositioningAnalyzer struct {
+	Splitter *EncounterSplitter
+	// MinTankSwings is how many boss swings a player needs to take in an
+	// attempt to count as a tank, so players briefly pulling aggro are
+	// left out.
+	MinTankSwings int
+	// Tolerance is how many swing intervals may pass without a swing at a
+	// tank before the silence counts as an idle window, to allow for swing
+	// timer resets and spell casts.
+	Tolerance float64
+}
+
+// PositioningAnalyzerFunc is an option for NewPositioningAnalyzer.
+type PositioningAnalyzerFunc func(*PositioningAnalyzer)
+
+// WithPositioningSplitter sets the EncounterSplitter used to find attempts.
+func WithPositioningSplitter(s *EncounterSplitter) PositioningAnalyzerFunc {
+	return func(a *PositioningAnalyzer) {
+		a.Splitter = s
+	}
+}
+
+// WithMinTankSwings sets how many boss swings make a tank.
+func WithMinTankSwings(n int) PositioningAnalyzerFunc {
+	return func(a *PositioningAnalyzer) {
+		a.MinTankSwings = n
+	}
+}
+
+// WithIdleTolerance sets how many swing intervals without a swing at a tank
+// count as an idle window.
+func WithIdleTolerance(f float64) PositioningAnalyzerFunc {
+	return func(a *PositioningAnalyzer) {
+		a.Tolerance = f
+	}
+}
+
+// NewPositioningAnalyzer initializes, allocates and returns a pointer to a
+// PositioningAnalyzer.
+func NewPositioningAnalyzer(opts ...PositioningAnalyzerFunc) *PositioningAnalyzer {
+	a := &PositioningAnalyzer{
+		Splitter:      NewEncounterSplitter(),
+		MinTankSwings: 3,
+		Tolerance:     2,
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+// bossTargets collects the melee swings of a boss unit by target.
+type bossTargets struct {
+	name    string
+	targets map[string][]time.Time
+	order   []string
+}
+
+// Run reports every boss unit that swung at a tank during an attempt. Both
+// landed and avoided swings count, as a dodged or parried swing still means
+// the tank was in range. A silence longer than Tolerance median intervals
+// is idle from when the next swing was due until it landed, and the time
+// before the first and after the last swing of an attempt counts too.
+func (a *PositioningAnalyzer) Run(data []*CombatLogRecord) []BossPositioning {
+	var out []BossPositioning
+	bosses := bossRegistryOrDefault(a.Splitter.Bosses)
+	for _, e := range a.Splitter.Split(data) {
+		units := map[string]*bossTargets{}
+		var order []string
+		for _, rec := range e.Records {
+			if rec.EventType != SwingDamage && rec.EventType != SwingMissed || !isPlayerID(rec.TargetID) {
+				continue
+			}
+			if _, ok := bosses.Match(rec.SourceName, rec.SourceID); !ok {
+				continue
+			}
+			b, ok := units[rec.SourceID]
+			if !ok {
+				b = &bossTargets{name: rec.SourceName, targets: map[string][]time.Time{}}
+				units[rec.SourceID] = b
+				order = append(order, rec.SourceID)
+			}
+			if _, ok := b.targets[rec.TargetName]; !ok {
+				b.order = append(b.order, rec.TargetName)
+			}
+			b.targets[rec.TargetName] = append(b.targets[rec.TargetName], rec.Timestamp)
+		}
+		for _, guid := range order {
+			if p, ok := a.report(e, units[guid]); ok {
+				out = append(out, p)
+			}
+		}
+	}
+	return out
+}
+
+// report finds the idle windows of a boss unit, or reports false if it swung
+// at its tanks fewer than twice.
+func (a *PositioningAnalyzer) report(e Encounter, b *bossTargets) (BossPositioning, bool) {
+	p := BossPositioning{
+		Encounter: e.Name,
+		Attempt:   e.Attempt,
+		Result:    e.Result,
+		Boss:      b.name,
+		Duration:  e.Duration(),
+	}
+	var swings []time.Time
+	for _, name := range b.order {
+		if len(b.targets[name]) < a.MinTankSwings {
+			continue
+		}
+		p.Tanks = append(p.Tanks, name)
+		swings = append(swings, b.targets[name]...)
+	}
+	if len(swings) < 2 {
+		return p, false
+	}
+	sort.Slice(swings, func(i, j int) bool { return swings[i].Before(swings[j]) })
+	p.Swings = len(swings)
+
+	intervals := make([]time.Duration, 0, len(swings)-1)
+	for i := 1; i < len(swings); i++ {
+		intervals = append(intervals, swings[i].Sub(swings[i-1]))
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i] < intervals[j] })
+	p.SwingInterval = intervals[len(intervals)/2]
+	allowed := time.Duration(a.Tolerance * float64(p.SwingInterval))
+	// idle records the silence between two swings as idle from when the
+	// next swing was due
+	idle := func(last, next, due time.Time) {
+		if next.Sub(last) > allowed && next.After(due) {
+			p.Windows = append(p.Windows, IdleWindow{Start: due, End: next, Offset: due.Sub(e.StartTime)})
+		}
+	}
+	idle(e.StartTime, swings[0], e.StartTime)
+	for i := 1; i < len(swings); i++ {
+		idle(swings[i-1], swings[i], swings[i-1].Add(p.SwingInterval))
+	}
+	last := swings[len(swings)-1]
+	idle(last, e.EndTime, last.Add(p.SwingInterval))
+	return p, true
+}
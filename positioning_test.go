@@ -0,0 +1,63 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPositioningAnalyzerRun(t *testing.T) {
+	swing := func(ts, target string) string {
+		return ts + `  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,` + target + `,0x514,5000,0,1,0,0,0,nil,nil,nil`
+	}
+	tank := `0x07000000009DF7A8,"Winterinjuly"`
+	data := parseTestLines(t,
+		swing("12/11 01:08:00.000", tank),
+		swing("12/11 01:08:02.000", tank),
+		swing("12/11 01:08:04.000", tank),
+		`12/11 01:08:06.000  SWING_MISSED,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,PARRY`,
+		swing("12/11 01:08:08.000", tank),
+		swing("12/11 01:08:10.000", tank),
+		swing("12/11 01:08:15.000", `0x07000000007721EC,"Yogzar"`),
+		swing("12/11 01:08:20.000", tank),
+		swing("12/11 01:08:22.000", tank),
+		swing("12/11 01:08:24.000", tank),
+		swing("12/11 01:08:26.000", tank),
+		`12/11 01:08:27.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F0400003D,"Lord Marrowgar",0x10a48`,
+	)
+	report := NewPositioningAnalyzer().Run(data)
+	if len(report) != 1 {
+		t.Fatalf("expected 1 boss, got %d", len(report))
+	}
+	p := report[0]
+	if p.Boss != "Lord Marrowgar" || p.Result != EncounterKill || p.Swings != 10 {
+		t.Errorf("unexpected positioning: %+v", p)
+	}
+	if len(p.Tanks) != 1 || p.Tanks[0] != "Winterinjuly" {
+		t.Errorf("expected Winterinjuly to be the only tank, got %v", p.Tanks)
+	}
+	if p.SwingInterval != 2*time.Second {
+		t.Errorf("expected a 2s swing interval, got %s", p.SwingInterval)
+	}
+	if len(p.Windows) != 1 || p.Windows[0].Offset != 12*time.Second || p.Windows[0].Duration() != 8*time.Second {
+		t.Fatalf("expected one 8s idle window 12s in, got %+v", p.Windows)
+	}
+	if got := p.Uptime(); got < 70 || got > 71 {
+		t.Errorf("expected about 70.4%% uptime, got %.1f", got)
+	}
+}
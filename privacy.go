@@ -0,0 +1,155 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Privacy hides players who did not agree to their logs being published.
+// Excluded players disappear from the parsed records altogether, while
+// pseudonymized players keep their records under a stable pseudonym, so
+// reports still add up. Players are matched by name, ignoring case, after
+// the NameSanitizers have run.
+type Privacy struct {
+	// Excluded are the players whose records are skipped, both as source
+	// and as target.
+	Excluded []string
+	// Pseudonymized are the players whose names and GUIDs are replaced.
+	Pseudonymized []string
+	// Key seeds the pseudonyms. Without a secret key a pseudonym can be
+	// reversed by hashing candidate names.
+	Key string
+
+	excluded      map[string]bool
+	pseudonymized map[string]bool
+}
+
+// PrivacyFunc is an option for NewPrivacy.
+type PrivacyFunc func(*Privacy)
+
+// WithExcludedPlayers skips every record involving the named players.
+func WithExcludedPlayers(names ...string) PrivacyFunc {
+	return func(p *Privacy) {
+		p.Excluded = append(p.Excluded, names...)
+	}
+}
+
+// WithPseudonymizedPlayers replaces the names and GUIDs of the named players.
+func WithPseudonymizedPlayers(names ...string) PrivacyFunc {
+	return func(p *Privacy) {
+		p.Pseudonymized = append(p.Pseudonymized, names...)
+	}
+}
+
+// WithPseudonymKey sets the secret key pseudonyms are derived with.
+func WithPseudonymKey(key string) PrivacyFunc {
+	return func(p *Privacy) {
+		p.Key = key
+	}
+}
+
+// NewPrivacy initializes, allocates and returns a pointer to a Privacy.
+func NewPrivacy(opts ...PrivacyFunc) *Privacy {
+	p := &Privacy{}
+	for _, o := range opts {
+		o(p)
+	}
+	p.index()
+	return p
+}
+
+// index builds the lookup tables of the configured players.
+func (p *Privacy) index() {
+	p.excluded = map[string]bool{}
+	for _, name := range p.Excluded {
+		p.excluded[strings.ToLower(name)] = true
+	}
+	p.pseudonymized = map[string]bool{}
+	for _, name := range p.Pseudonymized {
+		p.pseudonymized[strings.ToLower(name)] = true
+	}
+}
+
+// WithPrivacy hides the players configured in p from every parsed record,
+// including records loaded from the Cache, so analyzers, exports and sinks
+// never see them.
+func WithPrivacy(p *Privacy) ParserFunc {
+	return func(parser *Parser) {
+		// a Privacy may be built without NewPrivacy
+		p.index()
+		parser.Privacy = p
+	}
+}
+
+// Pseudonym returns the name a pseudonymized player is shown as, such as
+// "Player-3f9a21c0". The same name and key always give the same pseudonym.
+func (p *Privacy) Pseudonym(name string) string {
+	return "Player-" + p.digest(name)[:8]
+}
+
+// pseudonymGUID returns the player GUID a pseudonymized player is shown as.
+func (p *Privacy) pseudonymGUID(name string) string {
+	return "0x070000" + strings.ToUpper(p.digest(name)[8:18])
+}
+
+func (p *Privacy) digest(name string) string {
+	mac := hmac.New(sha256.New, []byte(p.Key))
+	mac.Write([]byte(strings.ToLower(name)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// apply hides the configured players in the record, reporting false when
+// the record involves an excluded player and must be skipped.
+func (p *Privacy) apply(c *CombatLogRecord) bool {
+	if p == nil {
+		return true
+	}
+	// cached records carry numbered names, see NameAlias
+	source, _, _ := splitAlias(c.SourceName)
+	target, _, _ := splitAlias(c.TargetName)
+	if p.excluded[strings.ToLower(source)] || p.excluded[strings.ToLower(target)] {
+		return false
+	}
+	if p.pseudonymized[strings.ToLower(source)] {
+		c.SourceID = p.pseudonymGUID(source)
+		c.SourceName = p.Pseudonym(source)
+	}
+	if p.pseudonymized[strings.ToLower(target)] {
+		c.TargetID = p.pseudonymGUID(target)
+		c.TargetName = p.Pseudonym(target)
+	}
+	return true
+}
+
+// applyPrivacy hides the configured players in records, dropping the
+// records of excluded players in place.
+func (p *Parser) applyPrivacy(records []*CombatLogRecord) []*CombatLogRecord {
+	if p.Privacy == nil {
+		return records
+	}
+	out := records[:0]
+	for _, v := range records {
+		if p.Privacy.apply(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
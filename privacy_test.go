@@ -0,0 +1,122 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var privacyTestLog = strings.Join([]string{
+	`12/11 00:13:38.531  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x070000000062ADF1,"Phokkwho",0x514,61301,"Riptide",0x8,3000,0,100,1`,
+	`12/11 00:13:39.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130009093000102,"The Damned",0xa48,100,0,1,0,0,0,nil,nil,nil`,
+	`12/11 00:13:40.000  SWING_DAMAGE,0xF130009093000102,"The Damned",0xa48,0x070000000062ADF1,"Phokkwho",0x514,100,0,1,0,0,0,nil,nil,nil`,
+}, "\n")
+
+func TestParserPrivacy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "WoWCombatLog.txt")
+	if err := os.WriteFile(path, []byte(privacyTestLog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	privacy := NewPrivacy(
+		WithExcludedPlayers("winterinjuly"),
+		WithPseudonymizedPlayers("Phokkwho"),
+		WithPseudonymKey("secret"),
+	)
+	alias := privacy.Pseudonym("Phokkwho")
+	if alias == "Phokkwho" || alias != privacy.Pseudonym("PHOKKWHO") || alias == NewPrivacy().Pseudonym("Phokkwho") {
+		t.Errorf("expected a stable keyed pseudonym, got %q", alias)
+	}
+	for _, n := range []int{1, 2} {
+		var seen []string
+		l := NewEventListener()
+		l.OnAny(func(r CombatLogRecord) {
+			seen = append(seen, r.SourceName, r.TargetName)
+		})
+		p := New(WithLogFile(path), WithParallelism(n), WithPrivacy(privacy), WithEventListener(l))
+		data, err := p.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != 2 || p.Report().Filtered != 1 || p.Report().Parsed != 2 {
+			t.Fatalf("parallelism %d: expected the excluded player's record to be skipped, got %d records and %+v", n, len(data), p.Report())
+		}
+		if data[0].TargetName != alias || data[1].TargetName != alias || data[0].TargetID == "0x070000000062ADF1" || !isPlayerID(data[0].TargetID) {
+			t.Errorf("parallelism %d: expected the pseudonym, got %s %s", n, data[0].TargetName, data[0].TargetID)
+		}
+		for _, name := range seen {
+			if name == "Phokkwho" || name == "Winterinjuly" {
+				t.Errorf("parallelism %d: listener saw %s", n, name)
+			}
+		}
+	}
+}
+
+func TestParserPrivacyCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "WoWCombatLog.txt")
+	if err := os.WriteFile(path, []byte(privacyTestLog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cache := NewCache(WithCacheDir(t.TempDir()))
+	if _, err := New(WithLogFile(path), WithCache(cache)).Parse(); err != nil {
+		t.Fatal(err)
+	}
+	p := New(WithLogFile(path), WithCache(cache), WithPrivacy(&Privacy{Excluded: []string{"Phokkwho"}}))
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 1 || data[0].SourceName != "Winterinjuly" {
+		t.Errorf("expected cached records to be filtered too, got %d records", len(data))
+	}
+}
+
+func TestParserPrivacyCacheThenPlain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "WoWCombatLog.txt")
+	if err := os.WriteFile(path, []byte(privacyTestLog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cache := NewCache(WithCacheDir(t.TempDir()))
+	privacy := NewPrivacy(WithExcludedPlayers("Winterinjuly"), WithPseudonymizedPlayers("Phokkwho"))
+	var seen []string
+	l := NewEventListener()
+	l.OnAny(func(r CombatLogRecord) {
+		seen = append(seen, r.SourceName, r.TargetName)
+	})
+	p := New(WithLogFile(path), WithCache(cache), WithPrivacy(privacy), WithEventListener(l))
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2 || p.Report().Filtered != 1 || p.Report().Parsed != 2 || data[0].TargetName != privacy.Pseudonym("Phokkwho") {
+		t.Fatalf("expected the private parse to hide players, got %d records and %+v", len(data), p.Report())
+	}
+	for _, name := range seen {
+		if name == "Phokkwho" || name == "Winterinjuly" {
+			t.Errorf("listener saw %s", name)
+		}
+	}
+	data, err = New(WithLogFile(path), WithCache(cache)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 3 || data[0].TargetName != "Phokkwho" || data[1].SourceName != "Winterinjuly" {
+		t.Errorf("expected a plain parse to return every player, got %d records", len(data))
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// defaultProgressInterval is how often the ProgressFunc is called unless
+// WithProgressInterval is used.
+const defaultProgressInterval = 100 * time.Millisecond
+
+// ProgressFunc is called periodically while parsing with the number of bytes
+// read so far, the total size of the input, or -1 when it is not known, and
+// the number of lines scanned. For compressed input both byte counts are of
+// the compressed data.
+type ProgressFunc func(bytesRead, totalBytes int64, lines int)
+
+// WithProgress sets a ProgressFunc that Parse, ParseReader and Stream call at
+// most once per ProgressInterval on the parsing goroutine, and once more when
+// the input has been consumed. Time is only checked every thousand or so
+// lines, so it does not slow the parse down.
+func WithProgress(fn ProgressFunc) ParserFunc {
+	return func(p *Parser) {
+		p.Progress = fn
+	}
+}
+
+// WithProgressInterval sets how often the ProgressFunc is called.
+func WithProgressInterval(d time.Duration) ParserFunc {
+	return func(p *Parser) {
+		p.ProgressInterval = d
+	}
+}
+
+// progress tracks the bytes read from the input and throttles calls to the
+// ProgressFunc.
+type progress struct {
+	fn       ProgressFunc
+	interval time.Duration
+	total    int64
+	read     atomic.Int64
+	lines    atomic.Int64
+	last     time.Time
+}
+
+// newProgress returns nil when no ProgressFunc is set, which every method
+// accepts.
+func (p *Parser) newProgress(r io.Reader) *progress {
+	if p.Progress == nil {
+		return nil
+	}
+	interval := p.ProgressInterval
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+	return &progress{
+		fn:       p.Progress,
+		interval: interval,
+		total:    inputSize(r),
+		last:     time.Now(),
+	}
+}
+
+// reader counts the bytes read from r.
+func (pr *progress) reader(r io.Reader) io.Reader {
+	if pr == nil {
+		return r
+	}
+	return &countingReader{r: r, n: &pr.read}
+}
+
+// tick calls the ProgressFunc when the interval has passed since the last
+// call. It is meant to be called every progressCheckInterval lines.
+func (pr *progress) tick(lines int) {
+	if pr == nil {
+		return
+	}
+	if now := time.Now(); now.Sub(pr.last) >= pr.interval {
+		pr.last = now
+		pr.fn(pr.read.Load(), pr.total, lines)
+	}
+}
+
+// addLines counts lines scanned on another goroutine, for wait to report.
+func (pr *progress) addLines(n int) {
+	if pr != nil {
+		pr.lines.Add(int64(n))
+	}
+}
+
+// wait blocks until done is closed, calling the ProgressFunc every interval
+// with the lines counted by addLines.
+func (pr *progress) wait(done <-chan struct{}) {
+	if pr == nil {
+		<-done
+		return
+	}
+	t := time.NewTicker(pr.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-t.C:
+			pr.fn(pr.read.Load(), pr.total, int(pr.lines.Load()))
+		}
+	}
+}
+
+// done calls the ProgressFunc once the input has been consumed.
+func (pr *progress) done(lines int) {
+	if pr == nil {
+		return
+	}
+	pr.fn(pr.read.Load(), pr.total, lines)
+}
+
+// progressCheckInterval is how many lines are scanned between checks of the
+// progress interval, to keep time.Now off the hot path.
+const progressCheckInterval = 1024
+
+// countingReader adds the number of bytes read from r to n, which may be
+// shared by readers on several goroutines.
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// inputSize returns the size of files and in-memory readers, or -1.
+func inputSize(r io.Reader) int64 {
+	switch r := r.(type) {
+	case interface{ Stat() (os.FileInfo, error) }:
+		if info, err := r.Stat(); err == nil && info.Mode().IsRegular() {
+			return info.Size()
+		}
+	case interface{ Size() int64 }:
+		return r.Size()
+	}
+	return -1
+}
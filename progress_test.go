@@ -0,0 +1,86 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type progressCall struct {
+	read, total int64
+	lines       int
+}
+
+func recordProgress(calls *[]progressCall) ParserFunc {
+	var mu sync.Mutex
+	return WithProgress(func(read, total int64, lines int) {
+		mu.Lock()
+		defer mu.Unlock()
+		*calls = append(*calls, progressCall{read, total, lines})
+	})
+}
+
+func TestParserProgress(t *testing.T) {
+	info, err := os.Stat("./testdata/test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{0, 4} {
+		var calls []progressCall
+		p := New(WithLogFile("./testdata/test.txt"), recordProgress(&calls),
+			WithProgressInterval(time.Nanosecond), WithParallelism(n))
+		if _, err := p.Parse(); err != nil {
+			t.Fatal(err)
+		}
+		if len(calls) < 2 {
+			t.Fatalf("parallelism %d: expected periodic progress, got %v", n, calls)
+		}
+		last := calls[len(calls)-1]
+		if last.read != info.Size() || last.total != info.Size() || last.lines != p.Report().Lines {
+			t.Errorf("parallelism %d: expected final progress of the whole file, got %+v", n, last)
+		}
+		for i := 1; i < len(calls); i++ {
+			if calls[i].read < calls[i-1].read || calls[i].lines < calls[i-1].lines {
+				t.Errorf("parallelism %d: expected progress to never go backwards, got %+v after %+v", n, calls[i], calls[i-1])
+			}
+		}
+	}
+}
+
+func TestParserProgressUnknownSize(t *testing.T) {
+	line := `12/11 00:13:37.531  UNIT_DIED,0x0000000000000000,nil,0x80000000,0x070000000047DAB8,"Raddyboy",0x514` + "\n"
+	var calls []progressCall
+	p := New(recordProgress(&calls))
+	if _, err := p.ParseReader(strings.NewReader(line)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ParseReader(io.MultiReader(strings.NewReader(line))); err != nil {
+		t.Fatal(err)
+	}
+	want := []progressCall{
+		{int64(len(line)), int64(len(line)), 1},
+		{int64(len(line)), -1, 1},
+	}
+	if len(calls) != len(want) || calls[0] != want[0] || calls[1] != want[1] {
+		t.Errorf("expected %v, got %v", want, calls)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"sort"
+	"time"
+)
+
+// Progression tracks the attempts at one boss on one difficulty across any
+// number of raid nights.
+type Progression struct {
+	Boss       string     `json:"boss"`
+	Difficulty Difficulty `json:"difficulty"`
+	Pulls      int        `json:"pulls"`
+	// PullsToKill is the number of pulls up to and including the first kill,
+	// 0 while the boss has not been killed.
+	PullsToKill int `json:"pulls_to_kill"`
+	// BestPercent is the lowest health the boss was left on, 0 once killed.
+	BestPercent float64       `json:"best_percent"`
+	TimeSpent   time.Duration `json:"time_spent"`
+	FirstPull   time.Time     `json:"first_pull"`
+	FirstKill   time.Time     `json:"first_kill"`
+	// Curve is the health the boss was left on at the end of every pull, in
+	// the order the pulls happened.
+	Curve []float64 `json:"curve"`
+}
+
+// Killed reports whether the boss has been killed.
+func (p Progression) Killed() bool {
+	return p.PullsToKill > 0
+}
+
+// ProgressionReport groups estimated boss health from BossHPAnalyzer runs
+// over one or more logs by boss and difficulty, ordered by the first pull.
+// The attempts may be passed in any order.
+func ProgressionReport(attempts []BossHP) []Progression {
+	sorted := make([]BossHP, len(attempts))
+	copy(sorted, attempts)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Encounter.StartTime.Before(sorted[j].Encounter.StartTime)
+	})
+	type key struct {
+		boss       string
+		difficulty Difficulty
+	}
+	index := map[key]int{}
+	var out []Progression
+	for _, hp := range sorted {
+		k := key{boss: hp.Encounter.Name, difficulty: hp.Difficulty}
+		i, ok := index[k]
+		if !ok {
+			i = len(out)
+			index[k] = i
+			out = append(out, Progression{
+				Boss:        k.boss,
+				Difficulty:  k.difficulty,
+				BestPercent: 100,
+				FirstPull:   hp.Encounter.StartTime,
+			})
+		}
+		p := &out[i]
+		p.Pulls++
+		p.TimeSpent += hp.Encounter.Duration()
+		p.Curve = append(p.Curve, hp.FinalPercent)
+		p.BestPercent = min(p.BestPercent, hp.FinalPercent)
+		if hp.Encounter.Result == EncounterKill && !p.Killed() {
+			p.PullsToKill = p.Pulls
+			p.FirstKill = hp.Encounter.EndTime
+		}
+	}
+	return out
+}
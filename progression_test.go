@@ -0,0 +1,64 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressionReport(t *testing.T) {
+	night := time.Date(2023, 12, 11, 20, 0, 0, 0, time.UTC)
+	attempt := func(boss string, start time.Duration, result EncounterResult, final float64) BossHP {
+		return BossHP{
+			Difficulty: Heroic25,
+			Encounter: Encounter{
+				Name:      boss,
+				Result:    result,
+				StartTime: night.Add(start),
+				EndTime:   night.Add(start + time.Minute*5),
+			},
+			FinalPercent: final,
+		}
+	}
+	report := ProgressionReport([]BossHP{
+		attempt("The Lich King", time.Hour*24*7, EncounterKill, 0),
+		attempt("The Lich King", 0, EncounterWipe, 40),
+		attempt("Sindragosa", -time.Hour, EncounterKill, 0),
+		attempt("The Lich King", time.Hour, EncounterWipe, 12.5),
+		attempt("The Lich King", time.Hour*24*7+time.Hour, EncounterKill, 0),
+	})
+	if len(report) != 2 || report[0].Boss != "Sindragosa" {
+		t.Fatalf("expected Sindragosa then The Lich King, got %+v", report)
+	}
+	lk := report[1]
+	if lk.Pulls != 4 || lk.PullsToKill != 3 || !lk.Killed() {
+		t.Errorf("expected a kill on pull 3 of 4, got %d of %d", lk.PullsToKill, lk.Pulls)
+	}
+	if lk.TimeSpent != time.Minute*20 {
+		t.Errorf("expected 20 minutes spent, got %s", lk.TimeSpent)
+	}
+	want := []float64{40, 12.5, 0, 0}
+	for i := range want {
+		if lk.Curve[i] != want[i] {
+			t.Errorf("expected pull %d to end at %.1f%%, got %.1f%%", i+1, want[i], lk.Curve[i])
+		}
+	}
+	if !lk.FirstKill.Equal(night.Add(time.Hour*24*7 + time.Minute*5)) {
+		t.Errorf("unexpected first kill time %s", lk.FirstKill)
+	}
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "time"
+
+// CooldownCategory groups raid cooldowns by how they help the raid.
+type CooldownCategory string
+
+const (
+	// LustCooldown is Bloodlust or Heroism.
+	LustCooldown CooldownCategory = "lust"
+	// RaidDefensive reduces the damage taken by the whole raid or a group.
+	RaidDefensive CooldownCategory = "raid_defensive"
+	// PersonalDefensive reduces the damage taken by the player using it,
+	// typically a tank.
+	PersonalDefensive CooldownCategory = "personal_defensive"
+	// ExternalCooldown is cast by one player on another.
+	ExternalCooldown CooldownCategory = "external"
+	// HealingCooldown is a raid-wide burst of healing or mana.
+	HealingCooldown CooldownCategory = "healing"
+)
+
+// RaidCooldown is a cooldown tracked by the RaidCooldownAnalyzer, matched by
+// the name of the spell its SPELL_CAST_SUCCESS logs.
+type RaidCooldown struct {
+	Name     string           `json:"name"`
+	Category CooldownCategory `json:"category"`
+	// Duration is how long the effect lasts.
+	Duration time.Duration `json:"duration"`
+	// Cooldown is how long until it can be used again.
+	Cooldown time.Duration `json:"cooldown"`
+}
+
+// DefaultRaidCooldowns are the raid cooldowns of 3.3.5a.
+var DefaultRaidCooldowns = []RaidCooldown{
+	{Name: "Bloodlust", Category: LustCooldown, Duration: 40 * time.Second, Cooldown: 5 * time.Minute},
+	{Name: "Heroism", Category: LustCooldown, Duration: 40 * time.Second, Cooldown: 5 * time.Minute},
+	{Name: "Divine Sacrifice", Category: RaidDefensive, Duration: 10 * time.Second, Cooldown: 2 * time.Minute},
+	{Name: "Aura Mastery", Category: RaidDefensive, Duration: 6 * time.Second, Cooldown: 2 * time.Minute},
+	{Name: "Anti-Magic Zone", Category: RaidDefensive, Duration: 10 * time.Second, Cooldown: 2 * time.Minute},
+	{Name: "Shield Wall", Category: PersonalDefensive, Duration: 12 * time.Second, Cooldown: 5 * time.Minute},
+	{Name: "Last Stand", Category: PersonalDefensive, Duration: 20 * time.Second, Cooldown: 3 * time.Minute},
+	{Name: "Icebound Fortitude", Category: PersonalDefensive, Duration: 12 * time.Second, Cooldown: 2 * time.Minute},
+	{Name: "Vampiric Blood", Category: PersonalDefensive, Duration: 10 * time.Second, Cooldown: time.Minute},
+	{Name: "Survival Instincts", Category: PersonalDefensive, Duration: 20 * time.Second, Cooldown: 3 * time.Minute},
+	{Name: "Barkskin", Category: PersonalDefensive, Duration: 12 * time.Second, Cooldown: time.Minute},
+	{Name: "Divine Protection", Category: PersonalDefensive, Duration: 12 * time.Second, Cooldown: 3 * time.Minute},
+	{Name: "Pain Suppression", Category: ExternalCooldown, Duration: 8 * time.Second, Cooldown: 3 * time.Minute},
+	{Name: "Guardian Spirit", Category: ExternalCooldown, Duration: 10 * time.Second, Cooldown: 3 * time.Minute},
+	{Name: "Hand of Sacrifice", Category: ExternalCooldown, Duration: 12 * time.Second, Cooldown: 2 * time.Minute},
+	{Name: "Hand of Protection", Category: ExternalCooldown, Duration: 10 * time.Second, Cooldown: 5 * time.Minute},
+	{Name: "Lay on Hands", Category: ExternalCooldown, Cooldown: 20 * time.Minute},
+	{Name: "Innervate", Category: ExternalCooldown, Duration: 10 * time.Second, Cooldown: 3 * time.Minute},
+	{Name: "Power Infusion", Category: ExternalCooldown, Duration: 15 * time.Second, Cooldown: 2 * time.Minute},
+	{Name: "Tricks of the Trade", Category: ExternalCooldown, Duration: 6 * time.Second, Cooldown: 30 * time.Second},
+	{Name: "Divine Hymn", Category: HealingCooldown, Duration: 8 * time.Second, Cooldown: 8 * time.Minute},
+	{Name: "Tranquility", Category: HealingCooldown, Duration: 8 * time.Second, Cooldown: 8 * time.Minute},
+	{Name: "Hymn of Hope", Category: HealingCooldown, Duration: 8 * time.Second, Cooldown: 6 * time.Minute},
+	{Name: "Mana Tide Totem", Category: HealingCooldown, Duration: 12 * time.Second, Cooldown: 5 * time.Minute},
+}
+
+// RaidCooldownUse is a single use of a raid cooldown during an encounter.
+type RaidCooldownUse struct {
+	Time time.Time `json:"time"`
+	// Offset is how far into the encounter the cooldown was used.
+	Offset   time.Duration    `json:"offset"`
+	Player   string           `json:"player"`
+	Spell    string           `json:"spell"`
+	SpellID  uint64           `json:"spell_id"`
+	Category CooldownCategory `json:"category"`
+	// Target is the player an external was cast on, and empty for
+	// cooldowns without a target.
+	Target string `json:"target,omitempty"`
+	// End is when the effect ran out: when its aura was removed from the
+	// target or the player, or after its Duration when that is not logged.
+	End time.Time `json:"end"`
+}
+
+// EncounterCooldowns is the cooldown timeline of a single encounter.
+type EncounterCooldowns struct {
+	Encounter string          `json:"encounter"`
+	Attempt   int             `json:"attempt,omitempty"`
+	Result    EncounterResult `json:"result,omitempty"`
+	StartTime time.Time       `json:"start_time"`
+	EndTime   time.Time       `json:"end_time"`
+	// Uses are every raid cooldown used during the encounter, in order.
+	Uses []RaidCooldownUse `json:"uses"`
+}
+
+// ByPlayer returns the uses of the encounter grouped by the player who used
+// them.
+func (e EncounterCooldowns) ByPlayer() map[string][]RaidCooldownUse {
+	out := map[string][]RaidCooldownUse{}
+	for _, u := range e.Uses {
+		out[u.Player] = append(out[u.Player], u)
+	}
+	return out
+}
+
+// RaidCooldownAnalyzerFunc is an option for NewRaidCooldownAnalyzer.
+type RaidCooldownAnalyzerFunc func(*RaidCooldownAnalyzer)
+
+// RaidCooldownAnalyzer builds a timeline of the defensives, lust, externals
+// and healing cooldowns the raid used in each boss attempt, so a planned
+// cooldown rotation can be checked against what happened.
+type RaidCooldownAnalyzer struct {
+	// Cooldowns are the tracked cooldowns. Defaults to
+	// DefaultRaidCooldowns.
+	Cooldowns []RaidCooldown
+	// Splitter finds the boss attempts. Defaults to an EncounterSplitter with
+	// default settings.
+	Splitter *EncounterSplitter
+}
+
+// WithRaidCooldowns sets the tracked cooldowns.
+func WithRaidCooldowns(c ...RaidCooldown) RaidCooldownAnalyzerFunc {
+	return func(a *RaidCooldownAnalyzer) {
+		a.Cooldowns = c
+	}
+}
+
+// WithRaidCooldownSplitter sets the EncounterSplitter used to find boss
+// attempts.
+func WithRaidCooldownSplitter(s *EncounterSplitter) RaidCooldownAnalyzerFunc {
+	return func(a *RaidCooldownAnalyzer) {
+		a.Splitter = s
+	}
+}
+
+// NewRaidCooldownAnalyzer initializes, allocates and returns a pointer to a
+// RaidCooldownAnalyzer.
+func NewRaidCooldownAnalyzer(opts ...RaidCooldownAnalyzerFunc) *RaidCooldownAnalyzer {
+	a := &RaidCooldownAnalyzer{
+		Cooldowns: DefaultRaidCooldowns,
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	if a.Splitter == nil {
+		a.Splitter = NewEncounterSplitter()
+	}
+	return a
+}
+
+// Run returns the cooldown timeline of every boss attempt in the data, in
+// the order of the attempts. Uses are found from the SPELL_CAST_SUCCESS of a
+// tracked cooldown by a player.
+func (a *RaidCooldownAnalyzer) Run(data []*CombatLogRecord) []EncounterCooldowns {
+	cooldowns := map[string]RaidCooldown{}
+	for _, c := range a.Cooldowns {
+		cooldowns[c.Name] = c
+	}
+	out := []EncounterCooldowns{}
+	for _, enc := range a.Splitter.Split(data) {
+		ec := EncounterCooldowns{
+			Encounter: enc.Name,
+			Attempt:   enc.Attempt,
+			Result:    enc.Result,
+			StartTime: enc.StartTime,
+			EndTime:   enc.EndTime,
+			Uses:      []RaidCooldownUse{},
+		}
+		// open are the uses whose aura has not been removed yet, keyed by
+		// the unit the aura is on and the spell
+		open := map[auraSlot]int{}
+		for _, rec := range enc.Records {
+			if rec.SpellAndRangePrefix == nil {
+				continue
+			}
+			c, ok := cooldowns[rec.SpellAndRangePrefix.SpellName]
+			if !ok {
+				continue
+			}
+			switch rec.EventType {
+			case SpellCastSuccess:
+				if !isPlayerID(rec.SourceID) {
+					continue
+				}
+				u := RaidCooldownUse{
+					Time:     rec.Timestamp,
+					Offset:   rec.Timestamp.Sub(enc.StartTime),
+					Player:   rec.SourceName,
+					Spell:    c.Name,
+					SpellID:  rec.SpellAndRangePrefix.SpellID,
+					Category: c.Category,
+					End:      rec.Timestamp.Add(c.Duration),
+				}
+				onID := rec.SourceID
+				if isPlayerID(rec.TargetID) && rec.TargetID != rec.SourceID {
+					u.Target = rec.TargetName
+					onID = rec.TargetID
+				}
+				open[auraSlot{targetID: onID, spellID: u.SpellID}] = len(ec.Uses)
+				ec.Uses = append(ec.Uses, u)
+			case SpellAuraRemoved:
+				slot := auraSlot{targetID: rec.TargetID, spellID: rec.SpellAndRangePrefix.SpellID}
+				if i, ok := open[slot]; ok {
+					ec.Uses[i].End = rec.Timestamp
+					delete(open, slot)
+				}
+			}
+		}
+		out = append(out, ec)
+	}
+	return out
+}
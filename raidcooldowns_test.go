@@ -0,0 +1,58 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRaidCooldownAnalyzerRun(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:05.000  SPELL_CAST_SUCCESS,0x07000000007721EC,"Yogzar",0x511,0x0000000000000000,nil,0x80000000,2825,"Bloodlust",0x8`,
+		`12/11 01:08:05.000  SPELL_AURA_APPLIED,0x07000000007721EC,"Yogzar",0x511,0x07000000007721EC,"Yogzar",0x511,2825,"Bloodlust",0x8,BUFF`,
+		`12/11 01:08:12.000  SPELL_CAST_SUCCESS,0x0700000000A1B2C3,"Pieshop",0x512,0x07000000009DF7A8,"Winterinjuly",0x514,33206,"Pain Suppression",0x2`,
+		`12/11 01:08:12.000  SPELL_AURA_APPLIED,0x0700000000A1B2C3,"Pieshop",0x512,0x07000000009DF7A8,"Winterinjuly",0x514,33206,"Pain Suppression",0x2,BUFF`,
+		`12/11 01:08:14.500  SPELL_AURA_REMOVED,0x0700000000A1B2C3,"Pieshop",0x512,0x07000000009DF7A8,"Winterinjuly",0x514,33206,"Pain Suppression",0x2,BUFF`,
+		`12/11 01:08:20.000  SPELL_CAST_SUCCESS,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x0000000000000000,nil,0x80000000,69076,"Bone Storm",0x1`,
+		`12/11 01:08:30.000  SPELL_CAST_SUCCESS,0x07000000009DF7A8,"Winterinjuly",0x514,0x0000000000000000,nil,0x80000000,871,"Shield Wall",0x1`,
+		`12/11 01:08:40.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:56.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F0400003D,"Lord Marrowgar",0x10a48`,
+	)
+	timeline := NewRaidCooldownAnalyzer().Run(data)
+	if len(timeline) != 1 || timeline[0].Encounter != "Lord Marrowgar" {
+		t.Fatalf("expected the Marrowgar attempt, got %+v", timeline)
+	}
+	uses := timeline[0].Uses
+	if len(uses) != 3 {
+		t.Fatalf("expected 3 cooldown uses, got %+v", uses)
+	}
+	if lust := uses[0]; lust.Category != LustCooldown || lust.Player != "Yogzar" || lust.Offset != 5*time.Second || lust.Target != "" {
+		t.Errorf("unexpected lust use %+v", lust)
+	}
+	ps := uses[1]
+	if ps.Category != ExternalCooldown || ps.Target != "Winterinjuly" || ps.End.Sub(ps.Time) != 2500*time.Millisecond {
+		t.Errorf("expected Pain Suppression on Winterinjuly to end when its aura was removed, got %+v", ps)
+	}
+	if wall := uses[2]; wall.Category != PersonalDefensive || wall.End.Sub(wall.Time) != 12*time.Second {
+		t.Errorf("expected Shield Wall to last its duration, got %+v", wall)
+	}
+	if got := timeline[0].ByPlayer()["Winterinjuly"]; len(got) != 1 || got[0].Spell != "Shield Wall" {
+		t.Errorf("expected Winterinjuly's own cooldowns only, got %+v", got)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter thins out the records passed to a callback, so a listener
+// feeding a UI during Tail is not flooded during AoE-heavy moments. Records
+// over the limit are dropped, not delayed. Callbacks wrapped by the same
+// RateLimiter share its budget, and it is safe for concurrent use with
+// WithAsyncDispatch.
+type RateLimiter struct {
+	// PerSecond is the number of records passed on per second of wall
+	// clock time, with bursts of up to PerSecond records. Zero or less
+	// means no limit.
+	PerSecond int
+	// SampleEvery passes on one in every SampleEvery records. One or less
+	// passes on every record.
+	SampleEvery int
+	// Events are the event types that are limited. Records of other types
+	// are always passed on. When empty, every record is limited.
+	Events []EventType
+
+	mu      sync.Mutex
+	tokens  float64
+	last    time.Time
+	seen    uint64
+	dropped uint64
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+// RateLimiterFunc is a function that accepts a pointer to a RateLimiter to
+// be used in the options variadic function in `NewRateLimiter`.
+type RateLimiterFunc func(*RateLimiter)
+
+// WithRatePerSecond sets the number of records passed on per second.
+func WithRatePerSecond(n int) RateLimiterFunc {
+	return func(l *RateLimiter) {
+		l.PerSecond = n
+	}
+}
+
+// WithSampleEvery passes on one in every n records.
+func WithSampleEvery(n int) RateLimiterFunc {
+	return func(l *RateLimiter) {
+		l.SampleEvery = n
+	}
+}
+
+// WithLimitedEvents restricts the limit to the event types, such as
+// SpellDamage and SpellPeriodicHeal, letting rare events like UnitDied
+// through.
+func WithLimitedEvents(events ...EventType) RateLimiterFunc {
+	return func(l *RateLimiter) {
+		l.Events = events
+	}
+}
+
+// NewRateLimiter initializes, allocates and returns a pointer to a
+// RateLimiter. Without options it passes on every record.
+func NewRateLimiter(opts ...RateLimiterFunc) *RateLimiter {
+	l := &RateLimiter{}
+	for _, o := range opts {
+		o(l)
+	}
+	return l
+}
+
+// Wrap returns a callback that invokes cb for the records Allow accepts.
+func (l *RateLimiter) Wrap(cb CombatLogRecordCallback) CombatLogRecordCallback {
+	return func(rec CombatLogRecord) {
+		if l.Allow(rec) {
+			cb(rec)
+		}
+	}
+}
+
+// Allow reports whether the record should be passed on, and counts it as
+// dropped if not. Sampling is applied before the rate limit, so sampled
+// out records do not use up the budget.
+func (l *RateLimiter) Allow(rec CombatLogRecord) bool {
+	if len(l.Events) > 0 && !sliceContains(l.Events, rec.EventType) {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seen++
+	if l.SampleEvery > 1 && (l.seen-1)%uint64(l.SampleEvery) != 0 {
+		l.dropped++
+		return false
+	}
+	if l.PerSecond <= 0 {
+		return true
+	}
+	now := time.Now()
+	if l.now != nil {
+		now = l.now()
+	}
+	if l.last.IsZero() {
+		l.tokens = float64(l.PerSecond)
+	} else {
+		l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*float64(l.PerSecond), float64(l.PerSecond))
+	}
+	l.last = now
+	if l.tokens < 1 {
+		l.dropped++
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Dropped returns the number of records dropped so far.
+func (l *RateLimiter) Dropped() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.dropped
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 00:13:39.000  SPELL_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130009093000102,"The Damned",0xa48,49050,"Aimed Shot",0x1,1000,0,1,0,0,0,1,nil,nil`,
+		`12/11 00:13:40.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130009093000102,"The Damned",0xa48`,
+	)
+	hit, died := *data[0], *data[1]
+	now := time.Date(2010, 12, 11, 0, 13, 39, 0, time.UTC)
+	l := NewRateLimiter(WithRatePerSecond(2), WithLimitedEvents(SpellDamage))
+	l.now = func() time.Time { return now }
+
+	listener := NewEventListener()
+	calls := 0
+	listener.OnAny(l.Wrap(func(CombatLogRecord) { calls++ }))
+	for i := 0; i < 5; i++ {
+		listener.Dispatch(hit)
+	}
+	listener.Dispatch(died)
+	if calls != 3 || l.Dropped() != 3 {
+		t.Errorf("expected a burst of 2 hits and the death, got %d calls and %d dropped", calls, l.Dropped())
+	}
+	now = now.Add(500 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		listener.Dispatch(hit)
+	}
+	if calls != 4 {
+		t.Errorf("expected 1 hit after half a second, got %d calls", calls-3)
+	}
+
+	s := NewRateLimiter(WithSampleEvery(3))
+	passed := 0
+	for i := 0; i < 7; i++ {
+		if s.Allow(hit) {
+			passed++
+		}
+	}
+	if passed != 3 || s.Dropped() != 4 {
+		t.Errorf("expected 1 in 3 of 7 records sampled, got %d", passed)
+	}
+}
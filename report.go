@@ -0,0 +1,104 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrMissingTimestamp is returned for lines that do not start with a combat
+// log timestamp, such as addon output.
+var ErrMissingTimestamp = errors.New("missing timestamp")
+
+// ParseError describes a line of the combat log that could not be parsed into
+// a CombatLogRecord, such as addon output or a line truncated by a client
+// crash.
+type ParseError struct {
+	// Line is the 1-based line number in the input.
+	Line int
+	// Raw is the line as it appears in the input.
+	Raw string
+	// Field is the index of the comma separated event field that could not
+	// be parsed, or -1 when the timestamp or line structure is malformed.
+	Field int
+	Err   error
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	if e.Field < 0 {
+		return fmt.Sprintf("frostparse: line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("frostparse: line %d: field %d: %v", e.Line, e.Field, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// lineError returns err as a *ParseError for the line, wrapping errors that
+// are not already one as a malformed line.
+func lineError(err error, line int, raw []byte) *ParseError {
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		perr = &ParseError{Field: -1, Err: err}
+	}
+	perr.Line = line
+	perr.Raw = string(raw)
+	return perr
+}
+
+// MarshalJSON implements json.Marshaler so that ParseReports can be serialized.
+func (e *ParseError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Line  int    `json:"line"`
+		Raw   string `json:"raw"`
+		Field int    `json:"field"`
+		Error string `json:"error"`
+	}{e.Line, e.Raw, e.Field, e.Err.Error()})
+}
+
+// ParseReport summarizes the lines seen during the most recent parse.
+type ParseReport struct {
+	Lines  int `json:"lines"`
+	Parsed int `json:"parsed"`
+	Blank  int `json:"blank"`
+	// Filtered is the number of lines left out by WithTimeRange,
+	// WithOnlyEncounters, WithEventFilter or WithExcludedEvents.
+	Filtered int `json:"filtered,omitempty"`
+	// Duplicates is the number of records dropped by ParseFiles because
+	// another file logged the same event.
+	Duplicates int `json:"duplicates,omitempty"`
+	// Aliases lists the players renamed because another player in the log
+	// had the same name, see WithMergeDuplicateNames.
+	Aliases     []NameAlias   `json:"aliases,omitempty"`
+	Quarantined []*ParseError `json:"quarantined"`
+}
+
+// Report returns the ParseReport of the most recent Parse or Stream call. When
+// streaming, it is only complete once the record channel has been closed.
+func (p *Parser) Report() ParseReport {
+	return p.report
+}
+
+// Skipped returns the lines skipped by the most recent parse in lenient mode.
+func (p *Parser) Skipped() []*ParseError {
+	return p.report.Quarantined
+}
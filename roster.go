@@ -0,0 +1,72 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Roster maps player names to the raid group (1 through 8) they are assigned
+// to.
+type Roster map[string]int
+
+// Group returns the raid group of the named player, or 0 if they are not on
+// the roster.
+func (r Roster) Group(name string) int {
+	return r[name]
+}
+
+// Members returns the names of the players assigned to a raid group.
+func (r Roster) Members(group int) []string {
+	var out []string
+	for name, g := range r {
+		if g == group {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// ReadRoster reads a roster from CSV with one "name,group" record per line.
+// Blank lines and lines starting with "#" are ignored.
+func ReadRoster(r io.Reader) (Roster, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 2
+	cr.TrimLeadingSpace = true
+	out := Roster{}
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		g, err := strconv.Atoi(strings.TrimSpace(rec[1]))
+		if err != nil || g < 1 || g > 8 {
+			line, _ := cr.FieldPos(1)
+			return nil, fmt.Errorf("frostparse: invalid raid group %q on line %d", rec[1], line)
+		}
+		out[strings.TrimSpace(rec[0])] = g
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NameSanitizer transforms a player name after it has been unquoted.
+type NameSanitizer func(string) string
+
+// WithNameSanitizer sets the sanitizers applied, in order, to the source and
+// target name of every parsed record when the unit is a player. NPC names are
+// left alone, as many contain a hyphen, like "Blood-Queen Lana'thel".
+func WithNameSanitizer(fns ...NameSanitizer) ParserFunc {
+	return func(p *Parser) {
+		p.NameSanitizers = append(p.NameSanitizers, fns...)
+	}
+}
+
+// TrimServerSuffix removes the "-Realm" decoration that cross-realm players
+// carry in their names.
+func TrimServerSuffix(name string) string {
+	if i := strings.IndexByte(name, '-'); i > 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// StripControlChars removes non-printable characters, such as the UI escape
+// sequences some addons inject into names.
+func StripControlChars(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+}
+
+// sanitizeNames applies the configured NameSanitizers to the player names of
+// a record.
+func (p *Parser) sanitizeNames(c *CombatLogRecord) {
+	source, target := isPlayerID(c.SourceID), isPlayerID(c.TargetID)
+	for _, fn := range p.NameSanitizers {
+		if source {
+			c.SourceName = fn(c.SourceName)
+		}
+		if target {
+			c.TargetName = fn(c.TargetName)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"bytes"
+	"strings"
+	"time"
+)
+
+// WithTimeRange only parses lines logged at or after from and before to,
+// comparing the timestamp at the start of each line before the rest of the
+// line is tokenized. A zero from or to leaves that end of the range open.
+// Lines outside the range are counted as Filtered in the ParseReport and are
+// not passed to the EventListener.
+func WithTimeRange(from, to time.Time) ParserFunc {
+	return func(p *Parser) {
+		p.From = from
+		p.To = to
+	}
+}
+
+// WithOnlyEncounters only keeps the records of attempts at the named bosses,
+// matched case-insensitively against the names in DefaultBossRegistry, such
+// as "Lord Marrowgar". Encounters are followed as the log is parsed, so a
+// wipe keeps the records up to the combat gap that ends it, where
+// EncounterSplitter.Split stops at the last event involving the boss.
+// Records outside the encounters are counted as Filtered in the ParseReport
+// and are not passed to the EventListener.
+func WithOnlyEncounters(names ...string) ParserFunc {
+	return func(p *Parser) {
+		p.OnlyEncounters = names
+	}
+}
+
+// WithEventFilter only parses lines of the included event types, which are
+// recognised from the first field of the line so the rest of it is never
+// tokenized. A nil include parses every event type. Lines of other types are
+// counted as Filtered in the ParseReport and are not passed to the
+// EventListener.
+func WithEventFilter(include []EventType) ParserFunc {
+	return func(p *Parser) {
+		p.IncludeEvents = include
+	}
+}
+
+// WithExcludedEvents skips lines of the excluded event types, such as
+// SPELL_CAST_START spam, the same way WithEventFilter skips the event types it
+// does not include.
+func WithExcludedEvents(exclude ...EventType) ParserFunc {
+	return func(p *Parser) {
+		p.ExcludeEvents = exclude
+	}
+}
+
+// parseScoped parses a line, returning false for lines outside the time
+// range or of a filtered event type without tokenizing them.
+func (p *Parser) parseScoped(clock *logClock, line []byte, names *interner) (CombatLogRecord, bool, error) {
+	t, event, err := splitLine(clock, line)
+	if err != nil {
+		return CombatLogRecord{}, false, err
+	}
+	if (!p.From.IsZero() && t.Before(p.From)) || (!p.To.IsZero() && !t.Before(p.To)) {
+		return CombatLogRecord{}, false, nil
+	}
+	if !p.includesEvent(event) {
+		return CombatLogRecord{}, false, nil
+	}
+	v, err := parseEvent(t, event, names)
+	if err == nil && p.Locale != nil {
+		p.Locale.localize(&v)
+	}
+	return v, err == nil, err
+}
+
+// includesEvent reports whether the event type at the start of the event
+// fields passes IncludeEvents and ExcludeEvents.
+func (p *Parser) includesEvent(event []byte) bool {
+	if p.IncludeEvents == nil && len(p.ExcludeEvents) == 0 {
+		return true
+	}
+	name, _, _ := bytes.Cut(event, []byte{','})
+	if p.IncludeEvents != nil && !containsEventType(p.IncludeEvents, name) {
+		return false
+	}
+	return !containsEventType(p.ExcludeEvents, name)
+}
+
+func containsEventType(types []EventType, name []byte) bool {
+	for _, t := range types {
+		if string(t) == string(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// inEncounters reports whether the record belongs to one of the
+// OnlyEncounters. Every parsed record must be passed in log order.
+func (p *Parser) inEncounters(v CombatLogRecord) bool {
+	if len(p.OnlyEncounters) == 0 {
+		return true
+	}
+	if p.encounters == nil {
+		p.encounters = newEncounterTracker(defaultCombatGap, nil)
+	}
+	name := p.encounters.observe(v)
+	for _, want := range p.OnlyEncounters {
+		if strings.EqualFold(name, want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParserTimeRange(t *testing.T) {
+	all, err := newTestParser().Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, to := all[100].Timestamp, all[2000].Timestamp
+	want := 0
+	for _, r := range all {
+		if !r.Timestamp.Before(from) && r.Timestamp.Before(to) {
+			want++
+		}
+	}
+	for _, n := range []int{0, 4} {
+		dispatched := 0
+		l := NewEventListener()
+		l.OnAny(func(CombatLogRecord) { dispatched++ })
+		p := New(WithLogFile("./testdata/test.txt"), WithEventListener(l), WithTimeRange(from, to), WithParallelism(n))
+		got, err := p.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != want || dispatched != want {
+			t.Errorf("parallelism %d: expected %d records in range, got %d and %d callbacks", n, want, len(got), dispatched)
+		}
+		for _, r := range got {
+			if r.Timestamp.Before(from) || !r.Timestamp.Before(to) {
+				t.Fatalf("parallelism %d: record at %s is outside the range", n, r.Timestamp)
+			}
+		}
+		if r := p.Report(); r.Filtered != len(all)-want || r.Parsed != want {
+			t.Errorf("parallelism %d: unexpected report %+v", n, r)
+		}
+	}
+}
+
+func TestParserOnlyEncounters(t *testing.T) {
+	all, err := newTestParser().Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encounters := NewEncounterSplitter().Split(all)
+	if len(encounters) == 0 {
+		t.Fatal("expected encounters in the test log")
+	}
+	name := encounters[0].Name
+	var want []Encounter
+	for _, e := range encounters {
+		if e.Name == name {
+			want = append(want, e)
+		}
+	}
+
+	p := New(WithLogFile("./testdata/test.txt"), WithOnlyEncounters(strings.ToUpper(name)))
+	got, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	split := NewEncounterSplitter().Split(got)
+	if len(split) != len(want) {
+		t.Fatalf("expected %d attempts at %s, got %d", len(want), name, len(split))
+	}
+	for i, e := range split {
+		if e.Name != name || len(e.Records) != len(want[i].Records) {
+			t.Errorf("expected attempt %d of %s with %d records, got %s with %d", want[i].Attempt, name, len(want[i].Records), e.Name, len(e.Records))
+		}
+	}
+	if p.Report().Filtered+p.Report().Parsed != len(all) {
+		t.Errorf("unexpected report %+v", p.Report())
+	}
+}
+
+func TestParserEventFilter(t *testing.T) {
+	all, err := newTestParser().Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[EventType]int{}
+	for _, r := range all {
+		counts[r.EventType]++
+	}
+
+	include := []EventType{SwingDamage, SpellDamage}
+	p := New(WithLogFile("./testdata/test.txt"), WithEventFilter(include))
+	got, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := counts[SwingDamage] + counts[SpellDamage]; len(got) != want {
+		t.Errorf("expected %d damage records, got %d", want, len(got))
+	}
+	for _, r := range got {
+		if r.EventType != SwingDamage && r.EventType != SpellDamage {
+			t.Fatalf("unexpected %s record", r.EventType)
+		}
+	}
+	if p.Report().Filtered != len(all)-len(got) {
+		t.Errorf("unexpected report %+v", p.Report())
+	}
+
+	p = New(WithLogFile("./testdata/test.txt"), WithExcludedEvents(SpellCastStart, SpellCastSuccess))
+	if got, err = p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	if want := len(all) - counts[SpellCastStart] - counts[SpellCastSuccess]; len(got) != want {
+		t.Errorf("expected %d records without casts, got %d", want, len(got))
+	}
+}
+
+func BenchmarkParseEventFilter(b *testing.B) {
+	p := New(WithLogFile("./testdata/test.txt"), WithEventFilter([]EventType{SwingDamage, SpellDamage}))
+	for i := 0; i < b.N; i++ {
+		if _, err := p.Parse(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
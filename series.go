@@ -0,0 +1,181 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"time"
+)
+
+// SeriesKind is what a SeriesPoint measures.
+type SeriesKind string
+
+const (
+	// SeriesDamage is damage done by a player or their pets to an NPC.
+	SeriesDamage SeriesKind = "damage"
+	// SeriesHealing is healing done by a player.
+	SeriesHealing SeriesKind = "healing"
+)
+
+// SeriesPoint is the damage or healing one source did with one spell during
+// one interval of log time, for time-series databases.
+type SeriesPoint struct {
+	Time      time.Time  `json:"time"`
+	Kind      SeriesKind `json:"kind"`
+	Source    string     `json:"source"`
+	Spell     string     `json:"spell"`
+	Encounter string     `json:"encounter"`
+	Amount    uint64     `json:"amount"`
+	// Overhealing is only set for SeriesHealing.
+	Overhealing uint64 `json:"overhealing,omitempty"`
+	Hits        int    `json:"hits"`
+}
+
+// seriesKey identifies the SeriesPoint a record is added to.
+type seriesKey struct {
+	kind      SeriesKind
+	source    string
+	spell     string
+	encounter string
+}
+
+// SeriesAggregatorFunc is an option for NewSeriesAggregator.
+type SeriesAggregatorFunc func(*SeriesAggregator)
+
+// SeriesAggregator folds a record stream into a SeriesPoint per source,
+// spell and encounter for every interval of log time. Pet damage is added
+// to the owner's points.
+type SeriesAggregator struct {
+	// Interval is the length of each bucket. Defaults to 5 seconds.
+	Interval time.Duration
+	// Bosses is the registry bosses are matched against. Defaults to
+	// DefaultBossRegistry.
+	Bosses *BossRegistry
+
+	encounters *encounterTracker
+	pets       *petTracker
+	start      time.Time
+	points     map[seriesKey]*SeriesPoint
+	order      []seriesKey
+}
+
+// WithSeriesInterval sets the length of each bucket.
+func WithSeriesInterval(d time.Duration) SeriesAggregatorFunc {
+	return func(a *SeriesAggregator) {
+		a.Interval = d
+	}
+}
+
+// WithSeriesBossRegistry sets the registry bosses are matched against.
+func WithSeriesBossRegistry(r *BossRegistry) SeriesAggregatorFunc {
+	return func(a *SeriesAggregator) {
+		a.Bosses = r
+	}
+}
+
+// NewSeriesAggregator initializes, allocates and returns a pointer to a
+// SeriesAggregator.
+func NewSeriesAggregator(opts ...SeriesAggregatorFunc) *SeriesAggregator {
+	a := &SeriesAggregator{
+		Interval: 5 * time.Second,
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	a.encounters = newEncounterTracker(defaultCombatGap, a.Bosses)
+	a.pets = newPetTracker()
+	a.points = map[seriesKey]*SeriesPoint{}
+	return a
+}
+
+// Observe adds a record to the current interval. Records must be observed in
+// log order; when a record falls past the end of the current interval, the
+// points of the completed interval are returned.
+func (a *SeriesAggregator) Observe(row CombatLogRecord) ([]SeriesPoint, bool) {
+	var done []SeriesPoint
+	var ok bool
+	start := row.Timestamp.Truncate(a.Interval)
+	if len(a.order) > 0 && !start.Equal(a.start) {
+		done, ok = a.Flush()
+	}
+	a.start = start
+	a.add(row)
+	return done, ok
+}
+
+func (a *SeriesAggregator) add(row CombatLogRecord) {
+	a.pets.observe(row)
+	encounter := a.encounters.observe(row)
+	key := seriesKey{source: row.SourceName, spell: abilityName(row), encounter: encounter}
+	switch {
+	case isDamageEvent(row) && !isPlayerID(row.TargetID) && row.DamageSuffix != nil:
+		key.kind = SeriesDamage
+		if owner, ok := a.pets.owner(row.SourceID); ok {
+			key.source = owner
+		} else if !isPlayerID(row.SourceID) {
+			return
+		}
+	case isHealingEvent(row) && isPlayerID(row.SourceID) && row.HealSuffix != nil:
+		key.kind = SeriesHealing
+	default:
+		return
+	}
+	p, ok := a.points[key]
+	if !ok {
+		p = &SeriesPoint{
+			Time:      a.start,
+			Kind:      key.kind,
+			Source:    key.source,
+			Spell:     key.spell,
+			Encounter: key.encounter,
+		}
+		a.points[key] = p
+		a.order = append(a.order, key)
+	}
+	p.Hits++
+	p.Amount += recordAmount(row)
+	if row.HealSuffix != nil {
+		p.Overhealing += row.HealSuffix.Overhealing
+	}
+}
+
+// Flush returns the points of the interval in progress, if any, in the order
+// they were first seen, and starts a new interval.
+func (a *SeriesAggregator) Flush() ([]SeriesPoint, bool) {
+	if len(a.order) == 0 {
+		return nil, false
+	}
+	out := make([]SeriesPoint, len(a.order))
+	for i, k := range a.order {
+		out[i] = *a.points[k]
+	}
+	a.points = map[seriesKey]*SeriesPoint{}
+	a.order = nil
+	return out, true
+}
+
+// Run observes every record and returns the points of every interval,
+// including the last partial one.
+func (a *SeriesAggregator) Run(data []*CombatLogRecord) []SeriesPoint {
+	var out []SeriesPoint
+	for _, row := range data {
+		if points, ok := a.Observe(*row); ok {
+			out = append(out, points...)
+		}
+	}
+	points, _ := a.Flush()
+	return append(out, points...)
+}
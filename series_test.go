@@ -0,0 +1,47 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeriesAggregatorRun(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:00.000  SPELL_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47610,"Frostfire Bolt",0x14,9000,0,16,0,0,0,nil,nil,nil`,
+		`12/11 01:08:02.000  SPELL_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47610,"Frostfire Bolt",0x14,8000,0,16,0,0,0,nil,nil,nil`,
+		`12/11 01:08:03.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,1200,300,0,nil`,
+		`12/11 01:08:04.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,5000,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:06.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,250,0,1,0,0,0,nil,nil,nil`,
+	)
+	points := NewSeriesAggregator().Run(data)
+	if len(points) != 3 {
+		t.Fatalf("expected 3 points, got %+v", points)
+	}
+	bolt, riptide, melee := points[0], points[1], points[2]
+	if bolt.Kind != SeriesDamage || bolt.Source != "Winterinjuly" || bolt.Spell != "Frostfire Bolt" ||
+		bolt.Encounter != "Lord Marrowgar" || bolt.Amount != 17000 || bolt.Hits != 2 {
+		t.Errorf("unexpected Frostfire Bolt point: %+v", bolt)
+	}
+	if riptide.Kind != SeriesHealing || riptide.Amount != 1200 || riptide.Overhealing != 300 {
+		t.Errorf("unexpected Riptide point: %+v", riptide)
+	}
+	if melee.Spell != "Melee" || !melee.Time.Equal(bolt.Time.Add(5*time.Second)) {
+		t.Errorf("expected melee in the next interval, got %+v", melee)
+	}
+}
@@ -0,0 +1,327 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package server exposes parsed combat logs over a small REST API, so a
+// guild web service can be stood up without writing the plumbing:
+//
+//	POST /logs                     parse the uploaded log
+//	GET  /logs                     list the uploaded logs
+//	GET  /encounters               list the boss attempts of every log
+//	GET  /encounters/{id}/damage   damage done per player in an attempt
+//	GET  /players/{name}/timeline  every event a player sourced or received
+//
+// Logs are kept in memory for the life of the Server.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// LogInfo describes an uploaded log.
+type LogInfo struct {
+	ID       int       `json:"id"`
+	Name     string    `json:"name,omitempty"`
+	Uploaded time.Time `json:"uploaded"`
+	Lines    int       `json:"lines"`
+	Records  int       `json:"records"`
+	Skipped  int       `json:"skipped"`
+	// Encounters are the boss attempts found in the log.
+	Encounters []EncounterInfo `json:"encounters"`
+}
+
+// EncounterInfo is a boss attempt with the id it is requested by.
+type EncounterInfo struct {
+	ID    int `json:"id"`
+	LogID int `json:"log_id"`
+	frostparse.Encounter
+	// Duration is the length of the attempt in seconds.
+	Duration float64 `json:"duration"`
+}
+
+// PlayerDamage is the damage a player did during an attempt.
+type PlayerDamage struct {
+	Name   string  `json:"name"`
+	Damage uint64  `json:"damage"`
+	DPS    float64 `json:"dps"`
+}
+
+// EncounterDamage is the response of GET /encounters/{id}/damage, with the
+// players sorted by damage done.
+type EncounterDamage struct {
+	Encounter EncounterInfo  `json:"encounter"`
+	Players   []PlayerDamage `json:"players"`
+}
+
+// ServerFunc is a function that accepts a pointer to a Server to be used in
+// the options variadic function in `NewServer`.
+type ServerFunc func(*Server)
+
+// Server is an http.Handler serving the REST API. It is safe for concurrent
+// use.
+type Server struct {
+	// ParserOptions are applied to every uploaded log.
+	ParserOptions []frostparse.ParserFunc
+	// MaxUploadSize is the largest log accepted, in bytes.
+	MaxUploadSize int64
+
+	mu         sync.RWMutex
+	logs       []*storedLog
+	encounters []*storedEncounter
+}
+
+// storedLog is an uploaded log and its parsed records.
+type storedLog struct {
+	info    LogInfo
+	session *frostparse.Session
+}
+
+// storedEncounter is a boss attempt of an uploaded log.
+type storedEncounter struct {
+	info EncounterInfo
+	log  *storedLog
+}
+
+// WithParserOptions sets the options every uploaded log is parsed with,
+// such as frostparse.WithLogYear or frostparse.WithPrivacy.
+func WithParserOptions(opts ...frostparse.ParserFunc) ServerFunc {
+	return func(s *Server) {
+		s.ParserOptions = append(s.ParserOptions, opts...)
+	}
+}
+
+// WithMaxUploadSize sets the largest log accepted, in bytes.
+func WithMaxUploadSize(n int64) ServerFunc {
+	return func(s *Server) {
+		s.MaxUploadSize = n
+	}
+}
+
+// NewServer initializes and allocates a Server and applies any ServerFunc
+// options. Uploads are limited to 512 MiB by default.
+func NewServer(opts ...ServerFunc) *Server {
+	s := &Server{
+		MaxUploadSize: 512 << 20,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// ServeHTTP routes a request to its endpoint.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "logs":
+		switch r.Method {
+		case http.MethodPost:
+			s.upload(w, r)
+		case http.MethodGet:
+			s.listLogs(w)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodPost)
+		}
+	case len(parts) == 1 && parts[0] == "encounters":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		s.listEncounters(w)
+	case len(parts) == 3 && parts[0] == "encounters" && parts[2] == "damage":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		s.damage(w, parts[1])
+	case len(parts) == 3 && parts[0] == "players" && parts[2] == "timeline":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		s.timeline(w, r, parts[1])
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such endpoint %s", r.URL.Path))
+	}
+}
+
+// upload parses the request body as a combat log, which may be gzipped, and
+// stores it under the name in the "name" query parameter.
+func (s *Server) upload(w http.ResponseWriter, r *http.Request) {
+	body := http.MaxBytesReader(w, r.Body, s.MaxUploadSize)
+	opts := append(append([]frostparse.ParserFunc{}, s.ParserOptions...), frostparse.WithReader(body))
+	session, err := frostparse.New(opts...).Session()
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	encounters := session.Encounters()
+
+	s.mu.Lock()
+	l := &storedLog{
+		info: LogInfo{
+			ID:         len(s.logs) + 1,
+			Name:       r.URL.Query().Get("name"),
+			Uploaded:   time.Now().UTC(),
+			Lines:      session.Report.Lines,
+			Records:    session.Report.Parsed,
+			Skipped:    len(session.Report.Quarantined),
+			Encounters: make([]EncounterInfo, 0, len(encounters)),
+		},
+		session: session,
+	}
+	s.logs = append(s.logs, l)
+	for _, e := range encounters {
+		enc := &storedEncounter{
+			info: EncounterInfo{
+				ID:        len(s.encounters) + 1,
+				LogID:     l.info.ID,
+				Encounter: e,
+				Duration:  e.Duration().Seconds(),
+			},
+			log: l,
+		}
+		s.encounters = append(s.encounters, enc)
+		l.info.Encounters = append(l.info.Encounters, enc.info)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, l.info)
+}
+
+func (s *Server) listLogs(w http.ResponseWriter) {
+	s.mu.RLock()
+	out := make([]LogInfo, len(s.logs))
+	for i, l := range s.logs {
+		out[i] = l.info
+	}
+	s.mu.RUnlock()
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) listEncounters(w http.ResponseWriter) {
+	s.mu.RLock()
+	out := make([]EncounterInfo, len(s.encounters))
+	for i, e := range s.encounters {
+		out[i] = e.info
+	}
+	s.mu.RUnlock()
+	writeJSON(w, http.StatusOK, out)
+}
+
+// encounter returns the boss attempt with the id.
+func (s *Server) encounter(id string) (*storedEncounter, error) {
+	n, err := strconv.Atoi(id)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err != nil || n < 1 || n > len(s.encounters) {
+		return nil, fmt.Errorf("no encounter with id %q", id)
+	}
+	return s.encounters[n-1], nil
+}
+
+func (s *Server) damage(w http.ResponseWriter, id string) {
+	e, err := s.encounter(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	stats := e.log.session.Collector.Run(e.info.Records)
+	dps := stats.DPS(e.info.Encounter.Duration())
+	out := EncounterDamage{
+		Encounter: e.info,
+		Players:   make([]PlayerDamage, 0, len(stats.DamageBySource)),
+	}
+	for name, amount := range stats.DamageBySource {
+		out.Players = append(out.Players, PlayerDamage{Name: name, Damage: amount, DPS: dps[name]})
+	}
+	sort.Slice(out.Players, func(i, j int) bool {
+		a, b := out.Players[i], out.Players[j]
+		if a.Damage != b.Damage {
+			return a.Damage > b.Damage
+		}
+		return a.Name < b.Name
+	})
+	writeJSON(w, http.StatusOK, out)
+}
+
+// timeline returns the player's events from every log, or from a single
+// attempt when the "encounter" query parameter holds its id.
+func (s *Server) timeline(w http.ResponseWriter, r *http.Request, name string) {
+	type source struct {
+		session *frostparse.Session
+		records []*frostparse.CombatLogRecord
+	}
+	var sources []source
+	if id := r.URL.Query().Get("encounter"); id != "" {
+		e, err := s.encounter(id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		sources = append(sources, source{e.log.session, e.info.Records})
+	} else {
+		s.mu.RLock()
+		for _, l := range s.logs {
+			sources = append(sources, source{l.session, l.session.Records})
+		}
+		s.mu.RUnlock()
+	}
+	out := []frostparse.TimelineEntry{}
+	found := false
+	for _, src := range sources {
+		players := src.session.Players()
+		if i := sort.SearchStrings(players, name); i == len(players) || players[i] != name {
+			continue
+		}
+		found = true
+		out = append(out, frostparse.PlayerTimeline(src.records, name)...)
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no player named %q", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
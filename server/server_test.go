@@ -0,0 +1,99 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+const testLog = `12/11 01:08:00.000  SPELL_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47610,"Frostfire Bolt",0x14,9000,0,16,0,0,0,nil,nil,nil
+12/11 01:08:05.000  SWING_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,3000,0,1,0,0,0,nil,nil,nil
+12/11 01:08:06.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,3000,0,0,nil
+12/11 01:08:10.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F0400003D,"Lord Marrowgar",0x10a48
+`
+
+func do(t *testing.T, srv *Server, method, target, body string, v any) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+	if v != nil {
+		if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s: %v", method, target, err)
+		}
+	}
+	return w.Code
+}
+
+func TestServer(t *testing.T) {
+	srv := NewServer(WithParserOptions(frostparse.WithLogYear(2010)))
+
+	var log LogInfo
+	if code := do(t, srv, http.MethodPost, "/logs?name=icc", testLog, &log); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if log.ID != 1 || log.Name != "icc" || log.Records != 4 || len(log.Encounters) != 1 {
+		t.Errorf("unexpected upload response %+v", log)
+	}
+
+	var encounters []EncounterInfo
+	do(t, srv, http.MethodGet, "/encounters", "", &encounters)
+	if len(encounters) != 1 || encounters[0].Name != "Lord Marrowgar" || encounters[0].Result != frostparse.EncounterKill {
+		t.Fatalf("unexpected encounters %+v", encounters)
+	}
+
+	var damage EncounterDamage
+	if code := do(t, srv, http.MethodGet, "/encounters/1/damage", "", &damage); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(damage.Players) != 2 || damage.Players[0].Name != "Winterinjuly" || damage.Players[0].Damage != 9000 || damage.Players[0].DPS != 900 {
+		t.Errorf("unexpected damage %+v", damage.Players)
+	}
+
+	var timeline []frostparse.TimelineEntry
+	do(t, srv, http.MethodGet, "/players/Winterinjuly/timeline?encounter=1", "", &timeline)
+	if len(timeline) != 2 || timeline[1].Direction != frostparse.TimelineIncoming || timeline[1].Spell != "Riptide" {
+		t.Errorf("unexpected timeline %+v", timeline)
+	}
+
+	for _, tc := range []struct {
+		method, target string
+		code           int
+	}{
+		{http.MethodGet, "/encounters/2/damage", http.StatusNotFound},
+		{http.MethodGet, "/players/Nobody/timeline", http.StatusNotFound},
+		{http.MethodDelete, "/logs", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/bosses", http.StatusNotFound},
+	} {
+		var body map[string]string
+		if code := do(t, srv, tc.method, tc.target, "", &body); code != tc.code || body["error"] == "" {
+			t.Errorf("%s %s: expected %d with an error, got %d %v", tc.method, tc.target, tc.code, code, body)
+		}
+	}
+}
+
+func TestServerUploadTooLarge(t *testing.T) {
+	srv := NewServer(WithMaxUploadSize(16))
+	if code := do(t, srv, http.MethodPost, "/logs", testLog, nil); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d", code)
+	}
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package service runs ingestion jobs for servers and batch pipelines: a job
+// parses a combat log, hands the records to a Sink such as the one in the
+// sqlite package, and reports the outcome to any configured Notifiers.
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// Sink stores the records of a parsed combat log.
+type Sink interface {
+	Write(ctx context.Context, records []*frostparse.CombatLogRecord) error
+}
+
+// CheckpointSink is a Sink that keeps track of the logs it has written, such
+// as the one in the sqlite package. Jobs writing to it are idempotent: a log
+// that was already written is skipped, and one that was interrupted by a
+// crash or a failed write resumes from its last checkpoint.
+type CheckpointSink interface {
+	Sink
+	Ingested(ctx context.Context, fingerprint string) (bool, error)
+	WriteLog(ctx context.Context, fingerprint, name string, records []*frostparse.CombatLogRecord) error
+}
+
+// Notifier is told about every finished job, whether it succeeded or not.
+type Notifier interface {
+	Notify(ctx context.Context, res *Result) error
+}
+
+// Status is the outcome of a job.
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	// StatusSkipped is the status of a job for a log a CheckpointSink has
+	// already written.
+	StatusSkipped Status = "skipped"
+)
+
+// Result describes a finished job and is the payload sent to Notifiers.
+type Result struct {
+	LogFile     string    `json:"log_file"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	Status      Status    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	// Attempts is the number of times the records were written.
+	Attempts int `json:"attempts"`
+	Lines    int `json:"lines"`
+	Records  int `json:"records"`
+	Skipped  int `json:"skipped"`
+	// Encounters are the boss attempts found in the log.
+	Encounters []frostparse.Encounter `json:"encounters"`
+}
+
+// Duration returns how long the job ran for.
+func (r *Result) Duration() time.Duration {
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// RunnerFunc is a function that accepts a pointer to a Runner to be used in
+// the options variadic function in `NewRunner`.
+type RunnerFunc func(*Runner)
+
+// Runner runs ingestion jobs into a single Sink.
+type Runner struct {
+	Sink          Sink
+	ParserOptions []frostparse.ParserFunc
+	Splitter      *frostparse.EncounterSplitter
+	Notifiers     []Notifier
+	// Retries is how many more times a failed write to a CheckpointSink is
+	// attempted, waiting RetryBackoff times the attempt number in between.
+	Retries      int
+	RetryBackoff time.Duration
+}
+
+// WithParserOptions sets the options every job's Parser is created with.
+func WithParserOptions(opts ...frostparse.ParserFunc) RunnerFunc {
+	return func(r *Runner) {
+		r.ParserOptions = opts
+	}
+}
+
+// WithRunnerSplitter sets the EncounterSplitter used to find the encounters
+// reported in a Result.
+func WithRunnerSplitter(sp *frostparse.EncounterSplitter) RunnerFunc {
+	return func(r *Runner) {
+		r.Splitter = sp
+	}
+}
+
+// WithNotifiers adds Notifiers that are told about every finished job.
+func WithNotifiers(n ...Notifier) RunnerFunc {
+	return func(r *Runner) {
+		r.Notifiers = append(r.Notifiers, n...)
+	}
+}
+
+// WithRetries retries failed writes to a CheckpointSink up to n times, which
+// resume where the failed write stopped. Writes to other Sinks are never
+// retried, since they could write the same records twice.
+func WithRetries(n int, backoff time.Duration) RunnerFunc {
+	return func(r *Runner) {
+		r.Retries = n
+		r.RetryBackoff = backoff
+	}
+}
+
+// NewRunner initializes and allocates a Runner and applies any RunnerFunc
+// options and returns a pointer to the Runner.
+func NewRunner(sink Sink, opts ...RunnerFunc) *Runner {
+	r := &Runner{
+		Sink:     sink,
+		Splitter: frostparse.NewEncounterSplitter(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// now is the clock jobs are timed with, replaced in tests.
+var now = time.Now
+
+// Run parses the combat log at path and writes its records to the Sink. When
+// the Sink is a CheckpointSink the job is keyed by the log's Fingerprint and
+// skipped if the log was already written. The Notifiers are called once the
+// job has finished, even when it failed or ctx was cancelled, and any error
+// they return is joined to the job's error.
+func (r *Runner) Run(ctx context.Context, path string) (*Result, error) {
+	res := &Result{LogFile: path, StartedAt: now()}
+	err := r.run(ctx, path, res)
+	res.FinishedAt = now()
+	switch {
+	case err != nil:
+		res.Status = StatusFailed
+		res.Error = err.Error()
+	case res.Status == "":
+		res.Status = StatusSucceeded
+	}
+	ctx = context.WithoutCancel(ctx)
+	for _, n := range r.Notifiers {
+		if nerr := n.Notify(ctx, res); nerr != nil {
+			err = errors.Join(err, nerr)
+		}
+	}
+	return res, err
+}
+
+func (r *Runner) run(ctx context.Context, path string, res *Result) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	cs, checkpointed := r.Sink.(CheckpointSink)
+	if checkpointed {
+		fp, err := Fingerprint(path)
+		if err != nil {
+			return err
+		}
+		res.Fingerprint = fp
+		done, err := cs.Ingested(ctx, fp)
+		if err != nil {
+			return err
+		}
+		if done {
+			res.Status = StatusSkipped
+			return nil
+		}
+	}
+	opts := append(append([]frostparse.ParserFunc{}, r.ParserOptions...), frostparse.WithLogFile(path))
+	p := frostparse.New(opts...)
+	data, err := p.Parse()
+	report := p.Report()
+	res.Lines = report.Lines
+	res.Records = report.Parsed
+	res.Skipped = len(report.Quarantined)
+	if err != nil {
+		return err
+	}
+	res.Encounters = r.Splitter.Split(data)
+	if !checkpointed {
+		res.Attempts = 1
+		return r.Sink.Write(ctx, data)
+	}
+	for {
+		res.Attempts++
+		err := cs.WriteLog(ctx, res.Fingerprint, path, data)
+		if err == nil || res.Attempts > r.Retries {
+			return err
+		}
+		t := time.NewTimer(r.RetryBackoff * time.Duration(res.Attempts))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-t.C:
+		}
+	}
+}
+
+// Fingerprint identifies a log by the SHA-256 of its contents. Unlike
+// frostparse.Fingerprint it reads the whole file and ignores the
+// modification time, so the same log uploaded twice has the same
+// fingerprint.
+func Fingerprint(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
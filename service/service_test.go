@@ -0,0 +1,217 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+	"github.com/bradleybonitatibus/frostparse/sqlite"
+)
+
+var _ CheckpointSink = (*sqlite.Sink)(nil)
+
+type memorySink struct {
+	records []*frostparse.CombatLogRecord
+	err     error
+}
+
+func (s *memorySink) Write(_ context.Context, records []*frostparse.CombatLogRecord) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.records = append(s.records, records...)
+	return nil
+}
+
+// checkpointSink is a CheckpointSink whose first fails writes fail.
+type checkpointSink struct {
+	memorySink
+	fails    int
+	ingested map[string]bool
+}
+
+func (s *checkpointSink) Ingested(_ context.Context, fingerprint string) (bool, error) {
+	return s.ingested[fingerprint], nil
+}
+
+func (s *checkpointSink) WriteLog(ctx context.Context, fingerprint, _ string, records []*frostparse.CombatLogRecord) error {
+	if s.fails > 0 {
+		s.fails--
+		return errors.New("database is locked")
+	}
+	if s.ingested == nil {
+		s.ingested = map[string]bool{}
+	}
+	s.ingested[fingerprint] = true
+	return s.Write(ctx, records)
+}
+
+type notifierFunc func(context.Context, *Result) error
+
+func (f notifierFunc) Notify(ctx context.Context, res *Result) error { return f(ctx, res) }
+
+func TestRunnerRun(t *testing.T) {
+	start := time.Date(2023, 12, 11, 1, 0, 0, 0, time.UTC)
+	clock := start
+	now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	defer func() { now = time.Now }()
+
+	var notified []*Result
+	sink := &memorySink{}
+	r := NewRunner(sink, WithNotifiers(notifierFunc(func(_ context.Context, res *Result) error {
+		notified = append(notified, res)
+		return nil
+	})))
+	res, err := r.Run(context.Background(), "../testdata/test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != StatusSucceeded || res.Error != "" {
+		t.Errorf("expected success, got %s %q", res.Status, res.Error)
+	}
+	if res.Records != 43433 || len(sink.records) != res.Records {
+		t.Errorf("expected 43433 records written, got %d and %d", res.Records, len(sink.records))
+	}
+	if len(res.Encounters) == 0 {
+		t.Error("expected encounters in the result")
+	}
+	if res.Duration() != time.Second {
+		t.Errorf("expected the job to take 1s, got %s", res.Duration())
+	}
+	if len(notified) != 1 || notified[0] != res {
+		t.Errorf("expected a single notification with the result, got %v", notified)
+	}
+
+	sink.err = errors.New("disk full")
+	notified = nil
+	res, err = r.Run(context.Background(), "../testdata/test.txt")
+	if !errors.Is(err, sink.err) {
+		t.Fatalf("expected the sink error, got %v", err)
+	}
+	if res.Status != StatusFailed || res.Error != "disk full" || len(notified) != 1 {
+		t.Errorf("expected a failed result to be notified, got %+v", res)
+	}
+}
+
+func TestRunnerRunCancelledStillNotifies(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var notified error
+	r := NewRunner(&memorySink{}, WithNotifiers(notifierFunc(func(ctx context.Context, res *Result) error {
+		notified = ctx.Err()
+		return nil
+	})))
+	res, err := r.Run(ctx, "../testdata/test.txt")
+	if !errors.Is(err, context.Canceled) || res.Status != StatusFailed {
+		t.Fatalf("expected a cancelled job, got %v", err)
+	}
+	if notified != nil {
+		t.Errorf("expected notifiers to run without the cancelled context, got %v", notified)
+	}
+}
+
+func TestRunnerRunIdempotent(t *testing.T) {
+	sink := &checkpointSink{fails: 1}
+	r := NewRunner(sink, WithRetries(2, 0))
+	res, err := r.Run(context.Background(), "../testdata/test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != StatusSucceeded || res.Attempts != 2 || res.Fingerprint == "" {
+		t.Errorf("expected success on the second attempt, got %+v", res)
+	}
+
+	// the same log uploaded again under another name and modification time
+	b, err := os.ReadFile("../testdata/test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "upload.txt")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	again, err := r.Run(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Status != StatusSkipped || again.Fingerprint != res.Fingerprint || again.Records != 0 {
+		t.Errorf("expected the upload to be skipped, got %+v", again)
+	}
+	if len(sink.records) != res.Records {
+		t.Errorf("expected the log written once, got %d records", len(sink.records))
+	}
+
+	sink = &checkpointSink{fails: 3}
+	res, err = NewRunner(sink, WithRetries(1, 0)).Run(context.Background(), path)
+	if err == nil || res.Attempts != 2 || res.Status != StatusFailed {
+		t.Errorf("expected the job to fail after 2 attempts, got %+v", res)
+	}
+}
+
+func TestWebhookNotify(t *testing.T) {
+	var body []byte
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+	}))
+	defer srv.Close()
+
+	res := &Result{LogFile: "WoWCombatLog.txt", Status: StatusFailed, Error: "boom", Records: 3}
+	if err := NewWebhook(srv.URL, WithWebhookSecret("hunter2")).Notify(context.Background(), res); err != nil {
+		t.Fatal(err)
+	}
+	var got Result
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != StatusFailed || got.Error != "boom" || got.Records != 3 {
+		t.Errorf("unexpected payload %s", body)
+	}
+	if signature != "sha256="+Sign("hunter2", body) {
+		t.Errorf("unexpected signature %q", signature)
+	}
+}
+
+func TestWebhookNotifyStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	err := NewWebhook(srv.URL).Notify(context.Background(), &Result{})
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("expected a status error, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// SignatureHeader carries the hex encoded HMAC-SHA256 of a webhook body,
+// prefixed with "sha256=", when the Webhook has a Secret.
+const SignatureHeader = "X-Frostparse-Signature"
+
+// WebhookFunc is a function that accepts a pointer to a Webhook to be used
+// in the options variadic function in `NewWebhook`.
+type WebhookFunc func(*Webhook)
+
+// Webhook is a Notifier that POSTs every Result as JSON to a URL.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+	// Secret signs the body in the SignatureHeader so the receiver can
+	// verify that the notification came from this service.
+	Secret string
+}
+
+// WithWebhookClient sets the HTTP client notifications are sent with.
+func WithWebhookClient(c *http.Client) WebhookFunc {
+	return func(w *Webhook) {
+		w.Client = c
+	}
+}
+
+// WithWebhookSecret sets the secret notifications are signed with.
+func WithWebhookSecret(secret string) WebhookFunc {
+	return func(w *Webhook) {
+		w.Secret = secret
+	}
+}
+
+// NewWebhook initializes and allocates a Webhook and applies any WebhookFunc
+// options and returns a pointer to the Webhook.
+func NewWebhook(url string, opts ...WebhookFunc) *Webhook {
+	w := &Webhook{
+		URL:    url,
+		Client: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Notify implements Notifier. Any response status other than 2xx is an
+// error.
+func (w *Webhook) Notify(ctx context.Context, res *Result) error {
+	body, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(w.Secret, body))
+	}
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("service: webhook failed with status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body keyed by secret, for
+// receivers verifying the SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SessionFunc is an option for NewSession.
+type SessionFunc func(*Session)
+
+// Session wraps a parsed log and computes its index, encounters, players and
+// summaries on first use, caching each result for later calls. It is safe for
+// concurrent use; the records must not be modified once the session exists.
+type Session struct {
+	Records []*CombatLogRecord
+	// Report is the ParseReport of the parse that produced Records, when the
+	// session was created by Parser.Session.
+	Report ParseReport
+	// Splitter slices the log into encounters. Defaults to an
+	// EncounterSplitter with default settings.
+	Splitter *EncounterSplitter
+	// Collector computes the summaries. Defaults to NewCollector().
+	Collector *Collector
+	// CheckpointInterval is how far apart in log time SummaryAt keeps
+	// snapshots of the running summary. Defaults to one minute; zero or less
+	// disables checkpoints, so every SummaryAt call replays the log.
+	CheckpointInterval time.Duration
+
+	index      lazy[*Index]
+	encounters lazy[[]Encounter]
+	segments   lazy[[]Encounter]
+	players    lazy[[]string]
+	summary    lazy[*SummaryStats]
+	bySegment  lazy[[]*SummaryStats]
+	deaths     lazy[[]DeathRecap]
+
+	checkpoints lazy[[]summaryCheckpoint]
+}
+
+// summaryCheckpoint is the running summary after the first n records.
+type summaryCheckpoint struct {
+	n     int
+	stats *SummaryStats
+}
+
+// WithSessionSplitter sets the EncounterSplitter used by the session.
+func WithSessionSplitter(s *EncounterSplitter) SessionFunc {
+	return func(ss *Session) {
+		ss.Splitter = s
+	}
+}
+
+// WithSessionCollector sets the Collector used to compute summaries.
+func WithSessionCollector(c *Collector) SessionFunc {
+	return func(ss *Session) {
+		ss.Collector = c
+	}
+}
+
+// WithCheckpointInterval sets how far apart in log time SummaryAt keeps
+// snapshots of the running summary.
+func WithCheckpointInterval(d time.Duration) SessionFunc {
+	return func(ss *Session) {
+		ss.CheckpointInterval = d
+	}
+}
+
+// NewSession initializes, allocates and returns a pointer to a Session over
+// the records.
+func NewSession(records []*CombatLogRecord, opts ...SessionFunc) *Session {
+	s := &Session{
+		Records:            records,
+		CheckpointInterval: time.Minute,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	if s.Splitter == nil {
+		s.Splitter = NewEncounterSplitter()
+	}
+	if s.Collector == nil {
+		s.Collector = NewCollector()
+	}
+	return s
+}
+
+// Session parses the combat log and returns a Session over the records.
+func (p *Parser) Session(opts ...SessionFunc) (*Session, error) {
+	data, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+	s := NewSession(data, opts...)
+	s.Report = p.Report()
+	return s, nil
+}
+
+// Index returns the source, target and spell index of the records.
+func (s *Session) Index() *Index {
+	return s.index.get(func() *Index {
+		return NewIndex(s.Records)
+	})
+}
+
+// Encounters returns every boss attempt in the log.
+func (s *Session) Encounters() []Encounter {
+	return s.encounters.get(func() []Encounter {
+		return s.Splitter.Split(s.Records)
+	})
+}
+
+// Segments returns the boss attempts and the trash segments between them.
+func (s *Session) Segments() []Encounter {
+	return s.segments.get(func() []Encounter {
+		return s.Splitter.Segments(s.Records)
+	})
+}
+
+// Players returns the sorted names of every player that appears in the log.
+func (s *Session) Players() []string {
+	return s.players.get(func() []string {
+		seen := map[string]struct{}{}
+		for _, r := range s.Records {
+			if isPlayerID(r.SourceID) {
+				seen[r.SourceName] = struct{}{}
+			}
+			if isPlayerID(r.TargetID) {
+				seen[r.TargetName] = struct{}{}
+			}
+		}
+		out := make([]string, 0, len(seen))
+		for name := range seen {
+			out = append(out, name)
+		}
+		sort.Strings(out)
+		return out
+	})
+}
+
+// Summary returns the summary of the whole log.
+func (s *Session) Summary() *SummaryStats {
+	return s.summary.get(func() *SummaryStats {
+		return s.Collector.Run(s.Records)
+	})
+}
+
+// SummaryAt returns the summary of the records up to and including time t, as
+// meters would have shown it at that moment. The first call snapshots the
+// running summary every CheckpointInterval, and each call then replays only
+// the records since the closest snapshot, so scrubbing through a fight stays
+// cheap. The records must be in log order.
+func (s *Session) SummaryAt(t time.Time) *SummaryStats {
+	n := sort.Search(len(s.Records), func(i int) bool {
+		return s.Records[i].Timestamp.After(t)
+	})
+	checkpoints := s.checkpoints.get(s.buildCheckpoints)
+	i := sort.Search(len(checkpoints), func(i int) bool {
+		return checkpoints[i].n > n
+	}) - 1
+	stats, start := s.Collector.newStats(), 0
+	if i >= 0 {
+		stats, start = checkpoints[i].stats.clone(), checkpoints[i].n
+	}
+	for _, r := range s.Records[start:n] {
+		stats.handleEvent(*r, s.Collector.TimeResolution)
+	}
+	s.Collector.finish(stats, s.Records[:n])
+	return stats
+}
+
+// buildCheckpoints runs the collector over the records once, keeping a copy
+// of the running summary at the first record of every CheckpointInterval.
+func (s *Session) buildCheckpoints() []summaryCheckpoint {
+	if s.CheckpointInterval <= 0 || len(s.Records) == 0 {
+		return nil
+	}
+	var out []summaryCheckpoint
+	stats := s.Collector.newStats()
+	next := s.Records[0].Timestamp.Add(s.CheckpointInterval)
+	for i, r := range s.Records {
+		if !r.Timestamp.Before(next) {
+			out = append(out, summaryCheckpoint{n: i, stats: stats.clone()})
+			next = r.Timestamp.Add(s.CheckpointInterval)
+		}
+		stats.handleEvent(*r, s.Collector.TimeResolution)
+	}
+	return out
+}
+
+// SegmentSummaries returns the summary of each segment, in the same order as
+// Segments.
+func (s *Session) SegmentSummaries() []*SummaryStats {
+	return s.bySegment.get(func() []*SummaryStats {
+		segments := s.Segments()
+		out := make([]*SummaryStats, len(segments))
+		for i := range segments {
+			out[i] = s.Collector.Run(segments[i].Records)
+		}
+		return out
+	})
+}
+
+// Deaths returns a DeathRecap for every player death in the log.
+func (s *Session) Deaths() []DeathRecap {
+	return s.deaths.get(func() []DeathRecap {
+		return NewDeathAnalyzer().Run(s.Records)
+	})
+}
+
+// lazy computes a value once and caches it.
+type lazy[T any] struct {
+	once sync.Once
+	v    T
+}
+
+func (l *lazy[T]) get(fn func() T) T {
+	l.once.Do(func() {
+		l.v = fn()
+	})
+	return l.v
+}
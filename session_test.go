@@ -0,0 +1,102 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSessionCachesResults(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:14.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:20.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000007721EC,"Yogzar",0x511,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:25.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F0400003D,"Lord Marrowgar",0x10a48`,
+	)
+	s := NewSession(data)
+
+	players := s.Players()
+	if len(players) != 2 || players[0] != "Winterinjuly" || players[1] != "Yogzar" {
+		t.Errorf("unexpected players: %v", players)
+	}
+	encounters := s.Encounters()
+	if len(encounters) != 1 || encounters[0].Result != EncounterKill {
+		t.Fatalf("expected one kill, got %+v", encounters)
+	}
+	if &s.Encounters()[0] != &encounters[0] {
+		t.Error("expected encounters to be cached")
+	}
+	if s.Summary() != s.Summary() {
+		t.Error("expected summary to be cached")
+	}
+	if got := s.Summary().DamageBySource["Winterinjuly"]; got != 100 {
+		t.Errorf("expected 100 damage, got %d", got)
+	}
+	if len(s.SegmentSummaries()) != len(s.Segments()) {
+		t.Error("expected one summary per segment")
+	}
+	if len(s.Index().BySource("0x07000000009DF7A8")) != 1 {
+		t.Error("expected one record indexed for Winterinjuly")
+	}
+}
+
+func TestParserSession(t *testing.T) {
+	s, err := newTestParser().Session()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Report.Parsed != len(s.Records) || len(s.Records) == 0 {
+		t.Errorf("expected report to match %d records, got %+v", len(s.Records), s.Report.Parsed)
+	}
+}
+
+func TestSessionSummaryAt(t *testing.T) {
+	var lines []string
+	for i := 0; i < 12; i++ {
+		lines = append(lines,
+			fmt.Sprintf(`12/11 01:%02d:14.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,%d,0,1,0,0,0,nil,nil,nil`, 8+i/2, 100+i),
+			fmt.Sprintf(`12/11 01:%02d:44.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,48071,"Flash Heal",0x2,%d,0,0,nil`, 8+i/2, 50+i),
+		)
+	}
+	data := parseTestLines(t, lines...)
+	s := NewSession(data, WithCheckpointInterval(2*time.Minute))
+
+	for _, at := range []time.Time{
+		data[0].Timestamp.Add(-time.Second),
+		data[0].Timestamp,
+		data[7].Timestamp.Add(time.Second),
+		data[15].Timestamp,
+		data[len(data)-1].Timestamp.Add(time.Hour),
+	} {
+		n := 0
+		for n < len(data) && !data[n].Timestamp.After(at) {
+			n++
+		}
+		want := s.Collector.Run(data[:n])
+		for i := 0; i < 2; i++ {
+			if got := s.SummaryAt(at); !reflect.DeepEqual(got, want) {
+				t.Errorf("SummaryAt(%s) call %d: damage %v healing %v, expected %v %v",
+					at, i, got.DamageBySource, got.HealingBySource, want.DamageBySource, want.HealingBySource)
+			}
+		}
+	}
+	if len(s.checkpoints.v) == 0 {
+		t.Error("expected checkpoints to be built")
+	}
+}
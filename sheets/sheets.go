@@ -0,0 +1,313 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sheets publishes per-encounter summaries from frostparse into a
+// Google Sheet, authenticating with a service account key.
+package sheets
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+const (
+	defaultBaseURL  = "https://sheets.googleapis.com"
+	defaultTokenURI = "https://oauth2.googleapis.com/token"
+	sheetsScope     = "https://www.googleapis.com/auth/spreadsheets"
+)
+
+// Credentials holds the fields of a Google service account JSON key that are
+// needed to obtain an access token.
+type Credentials struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// LoadCredentials reads a service account JSON key file.
+func LoadCredentials(path string) (*Credentials, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Credentials{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+	if c.TokenURI == "" {
+		c.TokenURI = defaultTokenURI
+	}
+	return c, nil
+}
+
+// PublisherFunc is a function that accepts a pointer to a Publisher to be
+// used in the options variadic function in `NewPublisher`.
+type PublisherFunc func(*Publisher)
+
+// Publisher appends rows to a single spreadsheet.
+type Publisher struct {
+	SpreadsheetID string
+	Range         string
+	BaseURL       string
+	Client        *http.Client
+	// Splitter finds the boss attempts published by PublishEncounters.
+	Splitter *frostparse.EncounterSplitter
+
+	creds  *Credentials
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// WithRange sets the A1 notation range rows are appended to. Defaults to
+// "Sheet1".
+func WithRange(r string) PublisherFunc {
+	return func(p *Publisher) {
+		p.Range = r
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for token and API requests.
+func WithHTTPClient(c *http.Client) PublisherFunc {
+	return func(p *Publisher) {
+		p.Client = c
+	}
+}
+
+// WithBaseURL overrides the Sheets API base URL.
+func WithBaseURL(u string) PublisherFunc {
+	return func(p *Publisher) {
+		p.BaseURL = u
+	}
+}
+
+// WithSplitter sets the EncounterSplitter boss attempts are found with.
+func WithSplitter(s *frostparse.EncounterSplitter) PublisherFunc {
+	return func(p *Publisher) {
+		p.Splitter = s
+	}
+}
+
+// NewPublisher initializes and allocates a Publisher and applies any
+// PublisherFunc options and returns a pointer to the Publisher.
+func NewPublisher(spreadsheetID string, creds *Credentials, opts ...PublisherFunc) *Publisher {
+	p := &Publisher{
+		SpreadsheetID: spreadsheetID,
+		Range:         "Sheet1",
+		BaseURL:       defaultBaseURL,
+		Client:        http.DefaultClient,
+		creds:         creds,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.Splitter == nil {
+		p.Splitter = frostparse.NewEncounterSplitter()
+	}
+	return p
+}
+
+// EncounterHeader is the header row matching the rows produced by
+// EncounterRows.
+var EncounterHeader = []any{
+	"Encounter", "Attempt", "Result", "Start", "End", "Duration (s)", "Damage Done", "Healing Done", "Damage Taken",
+}
+
+// EncounterRows converts boss attempts, as returned by
+// EncounterSplitter.Split, into sheet rows, one per attempt in the order
+// given. Raid totals are summarized from the records of each attempt.
+func EncounterRows(attempts []frostparse.Encounter) [][]any {
+	rows := make([][]any, 0, len(attempts))
+	for _, e := range attempts {
+		stats := frostparse.NewCollector().Run(e.Records)
+		rows = append(rows, []any{
+			e.Name,
+			e.Attempt,
+			string(e.Result),
+			e.StartTime.Format(time.DateTime),
+			e.EndTime.Format(time.DateTime),
+			e.Duration().Seconds(),
+			sum(stats.DamageBySource),
+			sum(stats.HealingBySource),
+			sum(stats.DamageTakenBySource),
+		})
+	}
+	return rows
+}
+
+func sum(totals map[string]uint64) uint64 {
+	var total uint64
+	for _, v := range totals {
+		total += v
+	}
+	return total
+}
+
+// PublishEncounters appends one row per boss attempt in data.
+func (p *Publisher) PublishEncounters(ctx context.Context, data []*frostparse.CombatLogRecord) error {
+	return p.AppendRows(ctx, EncounterRows(p.Splitter.Split(data)))
+}
+
+// AppendRows appends rows after the last row of the configured range.
+func (p *Publisher) AppendRows(ctx context.Context, rows [][]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	token, err := p.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]any{"values": rows})
+	if err != nil {
+		return err
+	}
+	u := fmt.Sprintf(
+		"%s/v4/spreadsheets/%s/values/%s:append?valueInputOption=USER_ENTERED&insertDataOption=INSERT_ROWS",
+		strings.TrimRight(p.BaseURL, "/"), url.PathEscape(p.SpreadsheetID), url.PathEscape(p.Range),
+	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("sheets: append failed with status %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+// accessToken returns a cached OAuth access token, exchanging a freshly
+// signed JWT assertion when the cached token is missing or about to expire.
+func (p *Publisher) accessToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" && time.Now().Before(p.expiry.Add(-time.Minute)) {
+		return p.token, nil
+	}
+	if p.creds == nil {
+		return "", errors.New("sheets: no credentials")
+	}
+	assertion, err := p.signAssertion(time.Now())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenURI(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("sheets: token exchange failed with status %d: %s", resp.StatusCode, msg)
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", err
+	}
+	p.token = tok.AccessToken
+	p.expiry = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return p.token, nil
+}
+
+func (p *Publisher) tokenURI() string {
+	if p.creds.TokenURI != "" {
+		return p.creds.TokenURI
+	}
+	return defaultTokenURI
+}
+
+// signAssertion builds the RS256 signed JWT used in the jwt-bearer grant.
+func (p *Publisher) signAssertion(now time.Time) (string, error) {
+	key, err := parsePrivateKey(p.creds.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iss":   p.creds.ClientEmail,
+		"scope": sheetsScope,
+		"aud":   p.tokenURI(),
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+func parsePrivateKey(s string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("sheets: private key is not PEM encoded")
+	}
+	if k, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rk, ok := k.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("sheets: private key is not an RSA key")
+		}
+		return rk, nil
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+func TestPublisherPublishEncounters(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	var appended [][]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			t.Errorf("unexpected grant type %q", r.FormValue("grant_type"))
+		}
+		if strings.Count(r.FormValue("assertion"), ".") != 2 {
+			t.Error("expected a signed JWT assertion")
+		}
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "tok", "expires_in": 3600})
+	})
+	mux.HandleFunc("/v4/spreadsheets/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			t.Errorf("unexpected authorization header %q", r.Header.Get("Authorization"))
+		}
+		var body struct {
+			Values [][]any `json:"values"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		appended = append(appended, body.Values...)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	creds := &Credentials{
+		ClientEmail: "frostparse@example.iam.gserviceaccount.com",
+		PrivateKey:  string(pemKey),
+		TokenURI:    srv.URL + "/token",
+	}
+	p := NewPublisher("sheet-id", creds, WithBaseURL(srv.URL))
+
+	log := strings.Join([]string{
+		`12/11 01:08:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:05.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,3000,0,0,nil`,
+		`12/11 01:08:10.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,400,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:10:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,50,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:10:30.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F0400003D,"Lord Marrowgar",0x10a48`,
+	}, "\n")
+	data, err := frostparse.New(frostparse.WithReader(strings.NewReader(log)), frostparse.WithLogYear(2023)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PublishEncounters(context.Background(), data); err != nil {
+		t.Fatal(err)
+	}
+	if len(appended) != 2 {
+		t.Fatalf("expected a row per attempt, got %v", appended)
+	}
+	wipe, kill := appended[0], appended[1]
+	if wipe[0] != "Lord Marrowgar" || wipe[1].(float64) != 1 || wipe[2] != "wipe" || wipe[5].(float64) != 10 {
+		t.Errorf("unexpected wipe row %v", wipe)
+	}
+	if wipe[6].(float64) != 100 || wipe[7].(float64) != 3000 || wipe[8].(float64) != 400 {
+		t.Errorf("expected the wipe's totals, got %v", wipe)
+	}
+	if kill[1].(float64) != 2 || kill[2] != "kill" || kill[3] != "2023-12-11 01:10:00" || kill[6].(float64) != 50 {
+		t.Errorf("unexpected kill row %v", kill)
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlite
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrSchemaTooNew is returned by Migrate when the database was migrated by a
+// newer frostparse release than this one.
+var ErrSchemaTooNew = errors.New("sqlite: database schema is newer than this release")
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migration is one embedded migrations/NNNN_name.sql file.
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// migrations are applied in version order, and the applied ones are
+// recorded in the schema_version table. Released migrations must never be
+// edited; add a new file instead.
+var migrations = loadMigrations(migrationFiles)
+
+const (
+	createSchemaVersion = `CREATE TABLE IF NOT EXISTS schema_version (
+	version INTEGER PRIMARY KEY,
+	name    TEXT NOT NULL,
+	applied TEXT NOT NULL
+)`
+	selectSchemaVersion = `SELECT COALESCE(MAX(version), 0) FROM schema_version`
+	insertSchemaVersion = `INSERT INTO schema_version (version, name, applied) VALUES (?, ?, ?)`
+)
+
+// loadMigrations reads the migration files, which must be numbered from 1
+// without gaps.
+func loadMigrations(fsys fs.FS) []migration {
+	names, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		panic(err)
+	}
+	out := make([]migration, 0, len(names))
+	for i, name := range names {
+		base := strings.TrimSuffix(path.Base(name), ".sql")
+		num, label, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if err != nil || version != i+1 {
+			panic(fmt.Sprintf("sqlite: migration %s is out of sequence", name))
+		}
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			panic(err)
+		}
+		out = append(out, migration{version: version, name: label, sql: string(b)})
+	}
+	return out
+}
+
+// SchemaVersion returns the number of migrations applied to the database.
+func (s *Sink) SchemaVersion(ctx context.Context) (int, error) {
+	if _, err := s.db.ExecContext(ctx, createSchemaVersion); err != nil {
+		return 0, err
+	}
+	var version int
+	if err := s.db.QueryRowContext(ctx, selectSchemaVersion).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Migrate brings the schema up to date, applying each pending migration in
+// its own transaction. It is safe to call on every start, and fails with
+// ErrSchemaTooNew rather than touch a database a newer release migrated.
+func (s *Sink) Migrate(ctx context.Context) error {
+	version, err := s.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("%w: version %d, expected at most %d", ErrSchemaTooNew, version, len(migrations))
+	}
+	for _, m := range migrations[version:] {
+		if err := s.apply(ctx, m); err != nil {
+			return fmt.Errorf("sqlite: migration %d: %w", m.version, err)
+		}
+	}
+	return nil
+}
+
+// apply runs a migration and records it in one transaction.
+func (s *Sink) apply(ctx context.Context, m migration) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, insertSchemaVersion, m.version, m.name, time.Now().UTC().Format(timeFormat)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
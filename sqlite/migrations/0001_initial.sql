@@ -0,0 +1,58 @@
+-- Copyright 2023 Bradley Bonitatibus.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+CREATE TABLE units (
+	id    TEXT PRIMARY KEY,
+	name  TEXT NOT NULL,
+	flags INTEGER NOT NULL
+);
+CREATE TABLE spells (
+	id     INTEGER PRIMARY KEY,
+	name   TEXT NOT NULL,
+	school INTEGER NOT NULL
+);
+CREATE TABLE encounters (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	name       TEXT NOT NULL,
+	attempt    INTEGER NOT NULL,
+	result     TEXT NOT NULL,
+	trash      INTEGER NOT NULL,
+	start_time TEXT NOT NULL,
+	end_time   TEXT NOT NULL
+);
+CREATE TABLE events (
+	id             INTEGER PRIMARY KEY AUTOINCREMENT,
+	timestamp      TEXT NOT NULL,
+	event          TEXT NOT NULL,
+	encounter_id   INTEGER REFERENCES encounters(id),
+	source_id      TEXT REFERENCES units(id),
+	target_id      TEXT REFERENCES units(id),
+	spell_id       INTEGER REFERENCES spells(id),
+	amount         INTEGER,
+	overkill       INTEGER,
+	school         INTEGER,
+	resisted       INTEGER,
+	blocked        INTEGER,
+	absorbed       INTEGER,
+	overhealing    INTEGER,
+	critical       INTEGER,
+	miss_type      TEXT,
+	aura_type      TEXT,
+	power_type     INTEGER,
+	extra_amount   INTEGER,
+	extra_spell_id INTEGER REFERENCES spells(id)
+);
+CREATE INDEX events_encounter ON events(encounter_id);
+CREATE INDEX events_source ON events(source_id);
+CREATE INDEX events_spell ON events(spell_id);
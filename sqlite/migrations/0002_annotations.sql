@@ -0,0 +1,25 @@
+-- Copyright 2023 Bradley Bonitatibus.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+CREATE TABLE annotations (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	encounter  TEXT NOT NULL,
+	attempt    INTEGER NOT NULL,
+	start_time TEXT NOT NULL,
+	offset_ms  INTEGER NOT NULL,
+	author     TEXT NOT NULL,
+	note       TEXT NOT NULL,
+	created    TEXT NOT NULL
+);
+CREATE INDEX annotations_encounter ON annotations(encounter, attempt, start_time);
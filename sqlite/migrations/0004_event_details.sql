@@ -0,0 +1,24 @@
+-- Copyright 2023 Bradley Bonitatibus.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+-- Unit flags change during a log, e.g. when a player joins the raid, so they
+-- are kept per event next to the flags units were first seen with. Miss
+-- amounts are stored in amount, like damage and heal amounts.
+ALTER TABLE events ADD COLUMN source_flags INTEGER;
+ALTER TABLE events ADD COLUMN target_flags INTEGER;
+ALTER TABLE events ADD COLUMN glancing INTEGER;
+ALTER TABLE events ADD COLUMN crushing INTEGER;
+ALTER TABLE events ADD COLUMN doses INTEGER;
+ALTER TABLE events ADD COLUMN off_hand INTEGER;
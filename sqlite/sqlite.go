@@ -0,0 +1,420 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sqlite writes parsed combat log records into a SQLite database so
+// logs can be queried with SQL. It only depends on database/sql: open the
+// database with the SQLite driver of your choice and hand it to NewSink.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+	"github.com/bradleybonitatibus/frostparse/export"
+)
+
+// timeFormat is understood by the SQLite date and time functions. Every
+// stored time is converted to UTC first so times written under different
+// parser locations compare equal.
+const timeFormat = "2006-01-02 15:04:05.000"
+
+const (
+	insertUnit      = `INSERT OR IGNORE INTO units (id, name, flags) VALUES (?, ?, ?)`
+	insertSpell     = `INSERT OR IGNORE INTO spells (id, name, school) VALUES (?, ?, ?)`
+	insertEncounter = `INSERT INTO encounters (name, attempt, result, trash, start_time, end_time, log) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	insertEvent     = `INSERT INTO events (timestamp, event, encounter_id, source_id, target_id, spell_id,
+	amount, overkill, school, resisted, blocked, absorbed, overhealing, critical,
+	miss_type, aura_type, power_type, extra_amount, extra_spell_id,
+	source_flags, target_flags, glancing, crushing, doses, off_hand)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	insertAnnotation    = `INSERT INTO annotations (encounter, attempt, start_time, offset_ms, author, note, created) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	selectAnnotations   = `SELECT encounter, attempt, start_time, offset_ms, author, note, created FROM annotations ORDER BY id`
+	insertLog           = `INSERT INTO logs (fingerprint, name, records, written) VALUES (?, ?, ?, 0)`
+	selectLog           = `SELECT records, written, finished FROM logs WHERE fingerprint = ?`
+	selectLogEncounters = `SELECT id FROM encounters WHERE log = ? ORDER BY id`
+	updateLogWritten    = `UPDATE logs SET written = ? WHERE fingerprint = ?`
+	updateLogFinished   = `UPDATE logs SET finished = ? WHERE fingerprint = ?`
+)
+
+// SinkFunc is a function that accepts a pointer to a Sink to be used in the
+// options variadic function in `NewSink`.
+type SinkFunc func(*Sink)
+
+// Sink writes records into a SQLite database.
+type Sink struct {
+	// BatchSize is the number of records inserted per transaction.
+	BatchSize int
+	Splitter  *frostparse.EncounterSplitter
+
+	db *sql.DB
+}
+
+// WithBatchSize sets the number of records inserted per transaction.
+func WithBatchSize(n int) SinkFunc {
+	return func(s *Sink) {
+		s.BatchSize = n
+	}
+}
+
+// WithSinkSplitter sets the EncounterSplitter used to fill the encounters
+// table.
+func WithSinkSplitter(sp *frostparse.EncounterSplitter) SinkFunc {
+	return func(s *Sink) {
+		s.Splitter = sp
+	}
+}
+
+// NewSink initializes and allocates a Sink writing to db and applies any
+// SinkFunc options.
+func NewSink(db *sql.DB, opts ...SinkFunc) *Sink {
+	s := &Sink{
+		BatchSize: 5000,
+		Splitter:  frostparse.NewEncounterSplitter(),
+		db:        db,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// Write migrates the schema and inserts the records, their units, spells and
+// encounters. Each batch of records is inserted in its own transaction, so
+// a failed Write may leave earlier batches behind; use WriteLog to be able to
+// resume it.
+func (s *Sink) Write(ctx context.Context, records []*frostparse.CombatLogRecord) error {
+	if err := s.Migrate(ctx); err != nil {
+		return err
+	}
+	encounters, err := s.writeEncounters(ctx, records, logState{})
+	if err != nil {
+		return err
+	}
+	return s.writeBatches(ctx, records, encounters, logState{})
+}
+
+// logState is the row of the logs table a WriteLog call checkpoints its
+// progress in. A zero logState means the write is not tracked.
+type logState struct {
+	fingerprint string
+	name        string
+	records     int
+	written     int
+	finished    bool
+}
+
+// Ingested migrates the schema and reports whether WriteLog has written
+// every record of the log with the fingerprint.
+func (s *Sink) Ingested(ctx context.Context, fingerprint string) (bool, error) {
+	if err := s.Migrate(ctx); err != nil {
+		return false, err
+	}
+	l, ok, err := s.log(ctx, fingerprint)
+	return ok && l.finished, err
+}
+
+// WriteLog is Write for the records of a log identified by fingerprint,
+// which is checkpointed in the logs table with every batch. Writing a log
+// again is a no-op once it has finished, and continues after the last
+// committed batch when an earlier WriteLog failed part way, so a log is
+// never counted twice. The records and Splitter must be the same as in the
+// interrupted call.
+func (s *Sink) WriteLog(ctx context.Context, fingerprint, name string, records []*frostparse.CombatLogRecord) error {
+	if err := s.Migrate(ctx); err != nil {
+		return err
+	}
+	l, ok, err := s.log(ctx, fingerprint)
+	if err != nil {
+		return err
+	}
+	var encounters map[*frostparse.CombatLogRecord]int64
+	switch {
+	case ok && l.finished:
+		return nil
+	case ok:
+		if l.records != len(records) {
+			return fmt.Errorf("sqlite: log %s was started with %d records, got %d", fingerprint, l.records, len(records))
+		}
+		encounters, err = s.logEncounters(ctx, l, records)
+	default:
+		l = logState{fingerprint: fingerprint, name: name, records: len(records)}
+		encounters, err = s.writeEncounters(ctx, records, l)
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.writeBatches(ctx, records, encounters, l); err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, updateLogFinished, time.Now().UTC().Format(timeFormat), fingerprint)
+	return err
+}
+
+// log returns the logs row of the fingerprint, if there is one.
+func (s *Sink) log(ctx context.Context, fingerprint string) (logState, bool, error) {
+	l := logState{fingerprint: fingerprint}
+	var finished sql.NullString
+	err := s.db.QueryRowContext(ctx, selectLog, fingerprint).Scan(&l.records, &l.written, &finished)
+	if errors.Is(err, sql.ErrNoRows) {
+		return l, false, nil
+	}
+	l.finished = finished.Valid
+	return l, err == nil, err
+}
+
+// logEncounters returns the encounter id of each record from the encounters
+// an interrupted WriteLog inserted, which were inserted in segment order.
+func (s *Sink) logEncounters(ctx context.Context, l logState, records []*frostparse.CombatLogRecord) (map[*frostparse.CombatLogRecord]int64, error) {
+	rows, err := s.db.QueryContext(ctx, selectLogEncounters, l.fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	segments := s.Splitter.Segments(records)
+	if len(segments) != len(ids) {
+		return nil, fmt.Errorf("sqlite: log %s was started with %d encounters, got %d", l.fingerprint, len(ids), len(segments))
+	}
+	out := map[*frostparse.CombatLogRecord]int64{}
+	for i, e := range segments {
+		for _, rec := range e.Records {
+			out[rec] = ids[i]
+		}
+	}
+	return out, nil
+}
+
+// writeEncounters inserts every boss attempt and trash segment and returns
+// the encounter id of each record that belongs to one. The logs row of a
+// tracked write is inserted in the same transaction.
+func (s *Sink) writeEncounters(ctx context.Context, records []*frostparse.CombatLogRecord, l logState) (map[*frostparse.CombatLogRecord]int64, error) {
+	out := map[*frostparse.CombatLogRecord]int64{}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var log sql.NullString
+	if l.fingerprint != "" {
+		if _, err := tx.ExecContext(ctx, insertLog, l.fingerprint, l.name, l.records); err != nil {
+			return nil, err
+		}
+		log = sql.NullString{String: l.fingerprint, Valid: true}
+	}
+	stmt, err := tx.PrepareContext(ctx, insertEncounter)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	for _, e := range s.Splitter.Segments(records) {
+		res, err := stmt.ExecContext(ctx,
+			e.Name, e.Attempt, string(e.Result), e.Trash,
+			e.StartTime.UTC().Format(timeFormat), e.EndTime.UTC().Format(timeFormat), log,
+		)
+		if err != nil {
+			return nil, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range e.Records {
+			out[rec] = id
+		}
+	}
+	return out, tx.Commit()
+}
+
+// writeBatches inserts the records after the ones a tracked write already
+// committed, one transaction per batch.
+func (s *Sink) writeBatches(ctx context.Context, records []*frostparse.CombatLogRecord, encounters map[*frostparse.CombatLogRecord]int64, l logState) error {
+	size := max(s.BatchSize, 1)
+	for start := l.written; start < len(records); start += size {
+		end := min(start+size, len(records))
+		l.written = end
+		if err := s.writeBatch(ctx, records[start:end], encounters, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBatch inserts records in a single transaction, which also moves the
+// checkpoint of a tracked write past them.
+func (s *Sink) writeBatch(ctx context.Context, records []*frostparse.CombatLogRecord, encounters map[*frostparse.CombatLogRecord]int64, l logState) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var stmts [3]*sql.Stmt
+	for i, q := range []string{insertUnit, insertSpell, insertEvent} {
+		if stmts[i], err = tx.PrepareContext(ctx, q); err != nil {
+			return err
+		}
+		defer stmts[i].Close()
+	}
+	units, spells, events := stmts[0], stmts[1], stmts[2]
+	for _, rec := range records {
+		e := export.NewEvent(rec)
+		source, target := nullString(e.SourceID), nullString(e.TargetID)
+		if source.Valid {
+			if _, err := units.ExecContext(ctx, e.SourceID, e.SourceName, e.SourceFlags); err != nil {
+				return err
+			}
+		}
+		if target.Valid {
+			if _, err := units.ExecContext(ctx, e.TargetID, e.TargetName, e.TargetFlags); err != nil {
+				return err
+			}
+		}
+		if e.SpellID != nil {
+			if _, err := spells.ExecContext(ctx, *e.SpellID, *e.SpellName, *e.SpellSchool); err != nil {
+				return err
+			}
+		}
+		if e.ExtraSpellID != nil {
+			if _, err := spells.ExecContext(ctx, *e.ExtraSpellID, *e.ExtraSpellName, *e.ExtraSpellSchool); err != nil {
+				return err
+			}
+		}
+		var encounter sql.NullInt64
+		if id, ok := encounters[rec]; ok {
+			encounter = sql.NullInt64{Int64: id, Valid: true}
+		}
+		var offHand *bool
+		if s := rec.MissSuffix; s != nil {
+			offHand = &s.IsOffHand
+		}
+		if _, err := events.ExecContext(ctx,
+			e.Timestamp.UTC().Format(timeFormat), e.EventType, encounter, source, target,
+			value(e.SpellID), value(e.Amount), value(e.Overkill), value(e.School),
+			value(e.Resisted), value(e.Blocked), value(e.Absorbed), value(e.Overhealing),
+			value(e.Critical), value(e.MissType), value(e.AuraType), value(e.PowerType),
+			value(e.ExtraAmount), value(e.ExtraSpellID),
+			int64(e.SourceFlags), int64(e.TargetFlags), value(e.Glancing), value(e.Crushing),
+			value(e.Doses), value(offHand),
+		); err != nil {
+			return err
+		}
+	}
+	if l.fingerprint != "" {
+		if _, err := tx.ExecContext(ctx, updateLogWritten, l.written, l.fingerprint); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// nullString stores the "nil" unit the log writes for events without a
+// source or target as NULL.
+func nullString(guid string) sql.NullString {
+	if guid == "" || guid == "0x0000000000000000" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: guid, Valid: true}
+}
+
+// value converts an optional Event field into a value database/sql accepts,
+// with nil stored as NULL.
+func value[T any](v *T) any {
+	if v == nil {
+		return nil
+	}
+	switch v := any(*v).(type) {
+	case uint64:
+		return int64(v)
+	case int:
+		return int64(v)
+	default:
+		return v
+	}
+}
+
+// Annotate migrates the schema and stores the notes. Annotations are keyed
+// by encounter name, attempt and start time rather than by encounter id, so
+// they survive the log being written again.
+func (s *Sink) Annotate(ctx context.Context, notes ...frostparse.Annotation) error {
+	if err := s.Migrate(ctx); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, insertAnnotation)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, n := range notes {
+		if _, err := stmt.ExecContext(ctx,
+			n.Encounter.Name, n.Encounter.Attempt, n.Encounter.StartTime.UTC().Format(timeFormat),
+			n.Offset.Milliseconds(), n.Author, n.Note, n.Created.UTC().Format(timeFormat),
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Annotations migrates the schema and returns every stored note in the order
+// they were added.
+func (s *Sink) Annotations(ctx context.Context) (frostparse.Annotations, error) {
+	if err := s.Migrate(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, selectAnnotations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out frostparse.Annotations
+	for rows.Next() {
+		var (
+			n              frostparse.Annotation
+			start, created string
+			offset         int64
+		)
+		if err := rows.Scan(&n.Encounter.Name, &n.Encounter.Attempt, &start, &offset, &n.Author, &n.Note, &created); err != nil {
+			return nil, err
+		}
+		if n.Encounter.StartTime, err = time.Parse(timeFormat, start); err != nil {
+			return nil, err
+		}
+		if n.Created, err = time.Parse(timeFormat, created); err != nil {
+			return nil, err
+		}
+		n.Offset = time.Duration(offset) * time.Millisecond
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
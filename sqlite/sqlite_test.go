@@ -0,0 +1,392 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+)
+
+// recordingDriver is a database/sql driver that records the statements it
+// is asked to execute, standing in for a SQLite driver.
+type recordingDriver struct {
+	mu      sync.Mutex
+	version int64
+	execs   []recordedExec
+	commits int
+	lastID  int64
+	// txStart is where the open transaction's statements start, so that
+	// a rollback can discard them.
+	txStart int
+	// failEvents fails the event insert after that many have succeeded.
+	failEvents int
+}
+
+type recordedExec struct {
+	query string
+	args  []driver.Value
+	id    int64
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) { return &recordingConn{d}, nil }
+
+func (d *recordingDriver) count(prefix string) int {
+	n := 0
+	for _, e := range d.execs {
+		if strings.HasPrefix(e.query, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{d: c.d, query: query}, nil
+}
+func (c *recordingConn) Close() error { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.txStart = len(c.d.execs)
+	return c, nil
+}
+func (c *recordingConn) Rollback() error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.execs = c.d.execs[:c.d.txStart]
+	return nil
+}
+func (c *recordingConn) Commit() error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.commits++
+	return nil
+}
+
+type recordedResult int64
+
+func (r recordedResult) LastInsertId() (int64, error) { return int64(r), nil }
+func (r recordedResult) RowsAffected() (int64, error) { return 1, nil }
+
+type recordingStmt struct {
+	d     *recordingDriver
+	query string
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if strings.HasPrefix(s.query, "INSERT INTO events") && s.d.failEvents > 0 {
+		if s.d.count("INSERT INTO events") == s.d.failEvents {
+			return nil, errors.New("disk I/O error")
+		}
+	}
+	s.d.lastID++
+	s.d.execs = append(s.d.execs, recordedExec{query: s.query, args: args, id: s.d.lastID})
+	if strings.HasPrefix(s.query, "INSERT INTO schema_version") {
+		s.d.version = max(s.d.version, args[0].(int64))
+	}
+	return recordedResult(s.d.lastID), nil
+}
+
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	switch {
+	case strings.HasPrefix(s.query, "SELECT encounter"):
+		// the inserted annotations, in insertion order
+		rows := &tableRows{columns: []string{"encounter", "attempt", "start_time", "offset_ms", "author", "note", "created"}}
+		for _, e := range s.d.execs {
+			if strings.HasPrefix(e.query, "INSERT INTO annotations") {
+				rows.rows = append(rows.rows, e.args)
+			}
+		}
+		return rows, nil
+	case strings.HasPrefix(s.query, "SELECT records"):
+		// the logs row, replaying the updates to it
+		rows := &tableRows{columns: []string{"records", "written", "finished"}}
+		for _, e := range s.d.execs {
+			switch {
+			case strings.HasPrefix(e.query, "INSERT INTO logs") && e.args[0] == args[0]:
+				rows.rows = [][]driver.Value{{e.args[2], int64(0), nil}}
+			case strings.HasPrefix(e.query, "UPDATE logs SET written") && e.args[1] == args[0]:
+				rows.rows[0][1] = e.args[0]
+			case strings.HasPrefix(e.query, "UPDATE logs SET finished") && e.args[1] == args[0]:
+				rows.rows[0][2] = e.args[0]
+			}
+		}
+		return rows, nil
+	case strings.HasPrefix(s.query, "SELECT id FROM encounters"):
+		rows := &tableRows{columns: []string{"id"}}
+		for _, e := range s.d.execs {
+			if strings.HasPrefix(e.query, "INSERT INTO encounters") && e.args[6] == args[0] {
+				rows.rows = append(rows.rows, []driver.Value{e.id})
+			}
+		}
+		return rows, nil
+	}
+	return &versionRows{version: s.d.version}, nil
+}
+
+// tableRows answers a SELECT with previously inserted values.
+type tableRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *tableRows) Columns() []string {
+	return r.columns
+}
+func (r *tableRows) Close() error { return nil }
+func (r *tableRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// versionRows answers the schema_version query.
+type versionRows struct {
+	version int64
+	done    bool
+}
+
+func (r *versionRows) Columns() []string { return []string{"version"} }
+func (r *versionRows) Close() error      { return nil }
+func (r *versionRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.version
+	return nil
+}
+
+func openRecording(t *testing.T) (*sql.DB, *recordingDriver) {
+	t.Helper()
+	d := &recordingDriver{}
+	return sql.OpenDB(connector{d}), d
+}
+
+type connector struct{ d *recordingDriver }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return c.d.Open("") }
+func (c connector) Driver() driver.Driver                        { return c.d }
+
+func testRecords(t *testing.T) []*frostparse.CombatLogRecord {
+	t.Helper()
+	data, err := frostparse.New(
+		frostparse.WithReader(strings.NewReader(strings.Join([]string{
+			`12/11 01:08:00.000  SPELL_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47610,"Frostfire Bolt",0x14,9000,0,16,0,0,0,nil,nil,nil`,
+			`12/11 01:08:01.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,250,0,1,0,0,0,nil,nil,nil`,
+			`12/11 01:08:02.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,3000,0,0,nil`,
+		}, "\n"))),
+		frostparse.WithStrictMode(true),
+	).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestSinkWrite(t *testing.T) {
+	data := testRecords(t)
+	db, d := openRecording(t)
+	defer db.Close()
+	sink := NewSink(db, WithBatchSize(2))
+	if err := sink.Write(context.Background(), data); err != nil {
+		t.Fatal(err)
+	}
+	if d.version != int64(len(migrations)) {
+		t.Errorf("expected the schema at version %d, got %d", len(migrations), d.version)
+	}
+	if n := d.count("INSERT INTO events"); n != 3 {
+		t.Errorf("expected 3 events inserted, got %d", n)
+	}
+	if n := d.count("INSERT INTO encounters"); n != 1 {
+		t.Errorf("expected 1 encounter inserted, got %d", n)
+	}
+	// the migrations, the encounters and two batches of events
+	if want := len(migrations) + 3; d.commits != want {
+		t.Errorf("expected %d transactions, got %d", want, d.commits)
+	}
+	for _, e := range d.execs {
+		if strings.HasPrefix(e.query, "INSERT INTO events") && e.args[1] == "SWING_DAMAGE" {
+			if e.args[5] != nil || e.args[6] != int64(250) {
+				t.Errorf("expected a swing without a spell, got %v", e.args)
+			}
+			if e.args[2] == nil {
+				t.Error("expected the swing to belong to the encounter")
+			}
+			if e.args[19] != int64(0x514) || e.args[21] != false || e.args[23] != nil || e.args[24] != nil {
+				t.Errorf("expected the swing's flags and hit details, got %v", e.args[19:])
+			}
+		}
+	}
+
+	if err := sink.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if d.count("INSERT INTO schema_version") != len(migrations) {
+		t.Error("expected migrations to run once")
+	}
+}
+
+func TestSinkMigrateUpgrade(t *testing.T) {
+	db, d := openRecording(t)
+	defer db.Close()
+	sink := NewSink(db)
+	ctx := context.Background()
+
+	// a database the first two migrations were applied to
+	d.version = 2
+	if err := sink.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := sink.SchemaVersion(ctx); err != nil || v != len(migrations) {
+		t.Fatalf("expected schema version %d, got %d %v", len(migrations), v, err)
+	}
+	var applied []string
+	for _, e := range d.execs {
+		if strings.Contains(e.query, "CREATE TABLE") && !strings.Contains(e.query, "schema_version") {
+			applied = append(applied, e.query)
+		}
+	}
+	if len(applied) != 1 || !strings.Contains(applied[0], "CREATE TABLE logs") {
+		t.Errorf("expected only the logs migration to run, got %d migrations", len(applied))
+	}
+
+	d.version = int64(len(migrations)) + 1
+	if err := sink.Migrate(ctx); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
+func TestSinkWriteLog(t *testing.T) {
+	data := testRecords(t)
+	db, d := openRecording(t)
+	defer db.Close()
+	sink := NewSink(db, WithBatchSize(1))
+	ctx := context.Background()
+
+	// crash while inserting the third record
+	d.failEvents = 2
+	if err := sink.WriteLog(ctx, "abc", "WoWCombatLog.txt", data); err == nil {
+		t.Fatal("expected the write to fail")
+	}
+	if done, err := sink.Ingested(ctx, "abc"); err != nil || done {
+		t.Fatalf("expected the log to be unfinished, got %v %v", done, err)
+	}
+	if n := d.count("INSERT INTO events"); n != 2 {
+		t.Fatalf("expected the 2 committed events to remain, got %d", n)
+	}
+
+	d.failEvents = 0
+	for i := 0; i < 2; i++ {
+		if err := sink.WriteLog(ctx, "abc", "WoWCombatLog.txt", data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if done, err := sink.Ingested(ctx, "abc"); err != nil || !done {
+		t.Errorf("expected the log to be finished, got %v %v", done, err)
+	}
+	if n := d.count("INSERT INTO events"); n != 3 {
+		t.Errorf("expected every event inserted once, got %d", n)
+	}
+	if n := d.count("INSERT INTO encounters"); n != 1 {
+		t.Errorf("expected the encounter to be reused when resuming, got %d", n)
+	}
+	for _, e := range d.execs {
+		if strings.HasPrefix(e.query, "INSERT INTO events") && e.args[4] == "0xF130008F0400003D" && e.args[2] == nil {
+			t.Errorf("expected every hit on the boss to belong to the encounter, got %v", e.args)
+		}
+	}
+}
+
+func TestSinkAnnotations(t *testing.T) {
+	db, _ := openRecording(t)
+	defer db.Close()
+	sink := NewSink(db)
+	start := time.Date(2023, 12, 11, 1, 8, 0, 0, time.UTC)
+	note := frostparse.Annotation{
+		Encounter: frostparse.EncounterKey{Name: "Lord Marrowgar", Attempt: 2, StartTime: start},
+		Offset:    time.Second * 90,
+		Author:    "Winterinjuly",
+		Note:      "tried 3-heal here",
+		Created:   start.Add(time.Hour),
+	}
+	if err := sink.Annotate(context.Background(), note); err != nil {
+		t.Fatal(err)
+	}
+	got, err := sink.Annotations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != note {
+		t.Errorf("expected the note back, got %+v", got)
+	}
+	if n := got.For(frostparse.Encounter{Name: "Lord Marrowgar", Attempt: 2, StartTime: start}); len(n) != 1 {
+		t.Errorf("expected the note to match its encounter, got %v", n)
+	}
+}
+
+// TestMigrationsSQLite runs the migrations and compiles the sink's
+// statements with the sqlite3 command line shell, which the recording driver
+// cannot stand in for.
+func TestMigrationsSQLite(t *testing.T) {
+	bin, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("sqlite3 is not installed")
+	}
+	var script strings.Builder
+	script.WriteString(".bail on\n")
+	script.WriteString(createSchemaVersion + ";\n")
+	for _, m := range migrations {
+		script.WriteString(m.sql + "\n")
+	}
+	for _, q := range []string{
+		insertUnit, insertSpell, insertEncounter, insertEvent, insertAnnotation, selectAnnotations,
+		insertLog, selectLog, selectLogEncounters, updateLogWritten, updateLogFinished,
+		selectSchemaVersion, insertSchemaVersion,
+	} {
+		// EXPLAIN compiles the statement, checking its tables and columns,
+		// without running it
+		script.WriteString("EXPLAIN " + q + ";\n")
+	}
+	cmd := exec.Command(bin, ":memory:")
+	cmd.Stdin = strings.NewReader(script.String())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("sqlite3 rejected the schema: %v\n%s", err, out)
+	}
+}
@@ -16,19 +16,16 @@ limitations under the License.
 
 package frostparse
 
-import "time"
-
-
-type Encounter struct {
-	StartTime time.Time `json:"start_time"`
-	EndTime   time.Time `json:"end_time"`
-}
+import (
+	"maps"
+	"time"
+)
 
 // SummaryStats is responsible for listening to the parser.CombatLogRecord stream
 // and aggregating the events into well-known raid metrics.
 type SummaryStats struct {
 	DamageDoneOverTime   map[time.Time]uint64  `json:"damage_done"`
-	HealingpDoneOverTime map[time.Time]uint64  `json:"healing_done"`
+	HealingDoneOverTime  map[time.Time]uint64  `json:"healing_done"`
 	DamageTakenOverTime  map[time.Time]uint64  `json:"damage_taken"`
 	EncounterOverlays    map[string]Encounter `json:"encounter_overlays"`
 	DamageBySource       map[string]uint64     `json:"damage_by_source"`
@@ -36,21 +33,107 @@ type SummaryStats struct {
 	DamageTakenBySource  map[string]uint64     `json:"damage_taken_by_source"`
 	DamageTakenBySpell   map[string]uint64     `json:"damage_taken_by_spell"`
 	InterruptsBySource   map[string]uint64     `json:"interrupts_by_source"`
-	DispellsBySource     map[string]uint64     `json:"dispells_by_source"`
+	DispelsBySource      map[string]uint64     `json:"dispells_by_source"`
+
+	FailedDispelsBySource   map[string]uint64                   `json:"failed_dispels_by_source"`
+	DamageBySourceAndSpell  map[string]*SpellBreakdown          `json:"damage_by_source_and_spell"`
+	HealingBySourceAndSpell map[string]map[string]*SpellHealing `json:"healing_by_source_and_spell"`
+	ActivityBySource        map[string]float64                  `json:"activity_by_source"`
+	Encounters              []Encounter                         `json:"encounters"`
+	DamageTakenByGroup      map[int]uint64                      `json:"damage_taken_by_group"`
+	HealingReceivedByGroup  map[int]uint64                      `json:"healing_received_by_group"`
+	ActiveTimeBySource      map[string]time.Duration            `json:"active_time_by_source"`
+
+	// HealingpDoneOverTime is the same map as HealingDoneOverTime.
+	//
+	// Deprecated: HealingpDoneOverTime is misspelled, use HealingDoneOverTime.
+	HealingpDoneOverTime map[time.Time]uint64 `json:"-"`
+	// DispellsBySource is the same map as DispelsBySource.
+	//
+	// Deprecated: DispellsBySource is misspelled, use DispelsBySource.
+	DispellsBySource map[string]uint64 `json:"-"`
+
+	pets         *petTracker
+	extraAttacks *extraAttackTracker
+	roster       Roster
+	bosses       *BossRegistry
+	// firstSeen is when each player was first seen as a source, used to
+	// compute ActiveTimeBySource.
+	firstSeen map[string]time.Time
+}
+
+// SpellBreakdown is a per-player breakdown of damage by spell name. Abilities
+// used by the player's pets and guardians are grouped under Pets, keyed by
+// pet name and then spell name, the same way in-game meters display them.
+type SpellBreakdown struct {
+	Spells map[string]uint64            `json:"spells"`
+	Pets   map[string]map[string]uint64 `json:"pets,omitempty"`
+}
+
+// addSpellDamage accumulates damage for an ability into the owner's
+// SpellBreakdown. An empty pet name credits the owner directly.
+func (c *SummaryStats) addSpellDamage(owner, pet, spell string, amount uint64) {
+	b, ok := c.DamageBySourceAndSpell[owner]
+	if !ok {
+		b = &SpellBreakdown{
+			Spells: map[string]uint64{},
+		}
+		c.DamageBySourceAndSpell[owner] = b
+	}
+	if pet == "" {
+		b.Spells[spell] += amount
+		return
+	}
+	if b.Pets == nil {
+		b.Pets = map[string]map[string]uint64{}
+	}
+	if b.Pets[pet] == nil {
+		b.Pets[pet] = map[string]uint64{}
+	}
+	b.Pets[pet][spell] += amount
 }
 
 type Collector struct {
 	TimeResolution time.Duration
+	Roster         Roster
+	Bosses         *BossRegistry
 }
 
+// CollectorFunc is an option for NewCollector.
 type CollectorFunc func(*Collector)
 
-func WithTimeresolution(res time.Duration) CollectorFunc {
+// WithTimeResolution sets the width of the buckets damage and healing over
+// time are aggregated into.
+func WithTimeResolution(res time.Duration) CollectorFunc {
 	return func(c *Collector) {
 		c.TimeResolution=res
 	}
 }
 
+// WithTimeresolution sets the width of the buckets damage and healing over
+// time are aggregated into.
+//
+// Deprecated: WithTimeresolution is misspelled, use WithTimeResolution.
+func WithTimeresolution(res time.Duration) CollectorFunc {
+	return WithTimeResolution(res)
+}
+
+// WithRoster sets the raid roster used to aggregate damage taken and healing
+// received per raid group.
+func WithRoster(r Roster) CollectorFunc {
+	return func(c *Collector) {
+		c.Roster = r
+	}
+}
+
+// WithBossRegistry sets the registry bosses are matched against when
+// detecting encounters. Defaults to DefaultBossRegistry.
+func WithBossRegistry(r *BossRegistry) CollectorFunc {
+	return func(c *Collector) {
+		c.Bosses = r
+	}
+}
+
 // NewCollector initializes, allocates and returns a pointer to a Collector struct.
 func NewCollector(opts ...CollectorFunc) *Collector {
 	t := &Collector{
@@ -65,36 +148,210 @@ func NewCollector(opts ...CollectorFunc) *Collector {
 // Run consumes the input channel of parser.CombatLogRecord and processes
 // each event in the event handler.
 func (c *Collector) Run(data []*CombatLogRecord) *SummaryStats {
-	s := &SummaryStats{
-		DamageDoneOverTime:   map[time.Time]uint64{},
-		HealingpDoneOverTime: map[time.Time]uint64{},
-		DamageTakenOverTime:  map[time.Time]uint64{},
-		DamageBySource:       map[string]uint64{},
-		HealingBySource:      map[string]uint64{},
-		DamageTakenBySource:  map[string]uint64{},
-		DamageTakenBySpell:   map[string]uint64{},
-		InterruptsBySource:   map[string]uint64{},
-		DispellsBySource:     map[string]uint64{},
-		EncounterOverlays:    map[string]Encounter{},
-	}
+	s := c.newStats()
 	for i := range data {
 		s.handleEvent(*data[i], c.TimeResolution)
 	}
+	c.finish(s, data)
+	return s
+}
+
+// finish fills in the encounters and activity of s, which are computed from
+// the whole slice of records rather than event by event.
+func (c *Collector) finish(s *SummaryStats, data []*CombatLogRecord) {
+	for _, e := range NewEncounterSplitter(WithSplitterBossRegistry(c.Bosses)).Split(data) {
+		e.Records = nil
+		s.Encounters = append(s.Encounters, e)
+	}
+	for name, a := range NewActivityAnalyzer().Run(data) {
+		s.ActivityBySource[name] = a.Percent()
+	}
+}
+
+// newStats allocates empty SummaryStats configured by the Collector.
+func (c *Collector) newStats() *SummaryStats {
+	s := &SummaryStats{
+		DamageDoneOverTime:      map[time.Time]uint64{},
+		HealingDoneOverTime:     map[time.Time]uint64{},
+		DamageTakenOverTime:     map[time.Time]uint64{},
+		DamageBySource:          map[string]uint64{},
+		HealingBySource:         map[string]uint64{},
+		DamageTakenBySource:     map[string]uint64{},
+		DamageTakenBySpell:      map[string]uint64{},
+		InterruptsBySource:      map[string]uint64{},
+		DispelsBySource:         map[string]uint64{},
+		EncounterOverlays:       map[string]Encounter{},
+		FailedDispelsBySource:   map[string]uint64{},
+		DamageBySourceAndSpell:  map[string]*SpellBreakdown{},
+		HealingBySourceAndSpell: map[string]map[string]*SpellHealing{},
+		ActivityBySource:        map[string]float64{},
+		DamageTakenByGroup:      map[int]uint64{},
+		HealingReceivedByGroup:  map[int]uint64{},
+		ActiveTimeBySource:      map[string]time.Duration{},
+
+		pets:         newPetTracker(),
+		extraAttacks: newExtraAttackTracker(),
+		roster:       c.Roster,
+		bosses:       bossRegistryOrDefault(c.Bosses),
+
+		firstSeen: map[string]time.Time{},
+	}
+	s.HealingpDoneOverTime = s.HealingDoneOverTime
+	s.DispellsBySource = s.DispelsBySource
 	return s
 }
 
+// clone returns a deep copy of the stats, including the pet and extra attack
+// state, so that handling more events does not modify c.
+func (c *SummaryStats) clone() *SummaryStats {
+	out := *c
+	out.DamageDoneOverTime = maps.Clone(c.DamageDoneOverTime)
+	out.HealingDoneOverTime = maps.Clone(c.HealingDoneOverTime)
+	out.DamageTakenOverTime = maps.Clone(c.DamageTakenOverTime)
+	out.EncounterOverlays = maps.Clone(c.EncounterOverlays)
+	out.DamageBySource = maps.Clone(c.DamageBySource)
+	out.HealingBySource = maps.Clone(c.HealingBySource)
+	out.DamageTakenBySource = maps.Clone(c.DamageTakenBySource)
+	out.DamageTakenBySpell = maps.Clone(c.DamageTakenBySpell)
+	out.InterruptsBySource = maps.Clone(c.InterruptsBySource)
+	out.DispelsBySource = maps.Clone(c.DispelsBySource)
+	out.FailedDispelsBySource = maps.Clone(c.FailedDispelsBySource)
+	out.ActivityBySource = maps.Clone(c.ActivityBySource)
+	out.Encounters = append([]Encounter(nil), c.Encounters...)
+	out.DamageTakenByGroup = maps.Clone(c.DamageTakenByGroup)
+	out.HealingReceivedByGroup = maps.Clone(c.HealingReceivedByGroup)
+	out.ActiveTimeBySource = maps.Clone(c.ActiveTimeBySource)
+	out.HealingpDoneOverTime = out.HealingDoneOverTime
+	out.DispellsBySource = out.DispelsBySource
+
+	out.DamageBySourceAndSpell = make(map[string]*SpellBreakdown, len(c.DamageBySourceAndSpell))
+	for source, b := range c.DamageBySourceAndSpell {
+		cp := &SpellBreakdown{Spells: maps.Clone(b.Spells)}
+		if b.Pets != nil {
+			cp.Pets = make(map[string]map[string]uint64, len(b.Pets))
+			for pet, spells := range b.Pets {
+				cp.Pets[pet] = maps.Clone(spells)
+			}
+		}
+		out.DamageBySourceAndSpell[source] = cp
+	}
+	out.HealingBySourceAndSpell = make(map[string]map[string]*SpellHealing, len(c.HealingBySourceAndSpell))
+	for source, spells := range c.HealingBySourceAndSpell {
+		cp := make(map[string]*SpellHealing, len(spells))
+		for name, h := range spells {
+			v := *h
+			cp[name] = &v
+		}
+		out.HealingBySourceAndSpell[source] = cp
+	}
+
+	out.pets = c.pets.clone()
+	out.extraAttacks = c.extraAttacks.clone()
+	out.firstSeen = maps.Clone(c.firstSeen)
+	return &out
+}
+
+// DPS returns the damage per second of each source over the duration d,
+// typically the Duration of the encounter the stats were collected from.
+func (c *SummaryStats) DPS(d time.Duration) map[string]float64 {
+	return perSecond(c.DamageBySource, func(string) time.Duration { return d })
+}
+
+// HPS returns the healing per second of each source over the duration d.
+func (c *SummaryStats) HPS(d time.Duration) map[string]float64 {
+	return perSecond(c.HealingBySource, func(string) time.Duration { return d })
+}
+
+// ActiveDPS returns the damage per second of each source over its own active
+// time, the time between its first and last event.
+func (c *SummaryStats) ActiveDPS() map[string]float64 {
+	return perSecond(c.DamageBySource, c.activeTime)
+}
+
+// ActiveHPS returns the healing per second of each source over its own
+// active time.
+func (c *SummaryStats) ActiveHPS() map[string]float64 {
+	return perSecond(c.HealingBySource, c.activeTime)
+}
+
+func (c *SummaryStats) activeTime(source string) time.Duration {
+	return c.ActiveTimeBySource[source]
+}
+
+// perSecond divides each total by the duration returned for its source,
+// skipping sources without a positive duration.
+func perSecond(totals map[string]uint64, duration func(string) time.Duration) map[string]float64 {
+	out := make(map[string]float64, len(totals))
+	for source, total := range totals {
+		if d := duration(source); d > 0 {
+			out[source] = float64(total) / d.Seconds()
+		}
+	}
+	return out
+}
+
+// SpellHealing splits the healing done by a single spell into its direct and
+// periodic (HoT) components. Absorbed and Overhealing are tracked separately
+// and are included in the Direct and Periodic amounts as reported by the log.
+type SpellHealing struct {
+	Direct      uint64 `json:"direct"`
+	Periodic    uint64 `json:"periodic"`
+	Absorbed    uint64 `json:"absorbed"`
+	Overhealing uint64 `json:"overhealing"`
+}
+
+// Total returns the combined direct and periodic healing.
+func (h SpellHealing) Total() uint64 {
+	return h.Direct + h.Periodic
+}
+
+// addSpellHealing accumulates a heal event into HealingBySourceAndSpell.
+func (c *SummaryStats) addSpellHealing(row CombatLogRecord) {
+	spells, ok := c.HealingBySourceAndSpell[row.SourceName]
+	if !ok {
+		spells = map[string]*SpellHealing{}
+		c.HealingBySourceAndSpell[row.SourceName] = spells
+	}
+	name := abilityName(row)
+	h, ok := spells[name]
+	if !ok {
+		h = &SpellHealing{}
+		spells[name] = h
+	}
+	if row.EventType == SpellPeriodicHeal {
+		h.Periodic += row.HealSuffix.Amount
+	} else {
+		h.Direct += row.HealSuffix.Amount
+	}
+	h.Absorbed += row.HealSuffix.Absorbed
+	h.Overhealing += row.HealSuffix.Overhealing
+}
+
 // handleEvent is responsible for aggregating the event based on event type
 // and source-> target directionality.
 func (c *SummaryStats) handleEvent(row CombatLogRecord, resolution time.Duration) {
+	c.pets.observe(row)
+	ability := abilityName(row)
+	if effect, ok := c.extraAttacks.observe(row); ok {
+		ability = effect
+	}
+	if isPlayerID(row.SourceID) {
+		first, ok := c.firstSeen[row.SourceName]
+		if !ok {
+			first = row.Timestamp
+			c.firstSeen[row.SourceName] = first
+		}
+		c.ActiveTimeBySource[row.SourceName] = row.Timestamp.Sub(first)
+	}
 	if isDamageEvent(row) {
+		// SPELL_EXTRA_ATTACKS carries the number of swings granted, not
+		// damage, so it is counted as 0 and the swings are credited instead.
 		var amount uint64 = 0
-		if row.ExtraAttacksSuffix != nil {
-			amount = row.ExtraAttacksSuffix.Amount
-		} else if row.DamageSuffix != nil {
+		if row.DamageSuffix != nil {
 			amount = row.DamageSuffix.Amount
 		}
-		if isBossName(row.TargetName) {
-			encounter, ok := c.EncounterOverlays[row.TargetName]
+		if boss, ok := c.bosses.Match(row.TargetName, row.TargetID); ok {
+			encounter, ok := c.EncounterOverlays[boss.Name]
 			now := row.Timestamp.Truncate(resolution)
 			if !ok {
 				encounter = Encounter{
@@ -104,35 +361,55 @@ func (c *SummaryStats) handleEvent(row CombatLogRecord, resolution time.Duration
 			} else {
 				encounter.EndTime = now
 			}
-			c.EncounterOverlays[row.TargetName] = encounter
+			c.EncounterOverlays[boss.Name] = encounter
 		}
 		if (isBossID(row.SourceID) || isNPCID(row.SourceID)) && isPlayerID(row.TargetID) {
 			// NPC -> player, accumulate damage taken
 			c.DamageTakenBySource[row.SourceName] += amount
 			c.DamageTakenOverTime[row.Timestamp.Truncate(resolution)] += amount
+			if g := c.roster.Group(row.TargetName); g != 0 {
+				c.DamageTakenByGroup[g] += amount
+			}
 			if row.SpellAndRangePrefix != nil {
 				c.DamageTakenBySpell[row.SpellAndRangePrefix.SpellName] += amount
 			}
 			return
 		}
-		if isPlayerID(row.SourceID) && isNPCID(row.TargetID) || isBossID(row.TargetID) {
+		if isPlayerID(row.SourceID) && (isNPCID(row.TargetID) || isBossID(row.TargetID)) {
 			// player -> npc, accumulate damage done
 			c.DamageBySource[row.SourceName] += amount
 			c.DamageDoneOverTime[row.Timestamp.Truncate(resolution)] += amount
+			c.addSpellDamage(row.SourceName, "", ability, amount)
 			return
 		}
+		if owner, ok := c.pets.owner(row.SourceID); ok && (isNPCID(row.TargetID) || isBossID(row.TargetID)) {
+			// pet/guardian -> npc, fold under the owner
+			c.addSpellDamage(owner, row.SourceName, ability, amount)
+		}
 		return
 	}
 	if isHealingEvent(row) {
-		if isPlayerID(row.SourceID) {
+		if isPlayerID(row.SourceID) && row.HealSuffix != nil {
 			c.HealingBySource[row.SourceName] += row.HealSuffix.Amount
-			c.HealingpDoneOverTime[row.Timestamp.Truncate(resolution)] += row.HealSuffix.Amount
+			c.HealingDoneOverTime[row.Timestamp.Truncate(resolution)] += row.HealSuffix.Amount
+			c.addSpellHealing(row)
+		}
+		if isPlayerID(row.TargetID) && row.HealSuffix != nil {
+			if g := c.roster.Group(row.TargetName); g != 0 {
+				c.HealingReceivedByGroup[g] += row.HealSuffix.Amount
+			}
 		}
 		return
 	}
 	if isOverlayEvent(row) {
-		c.DispellsBySource[row.SourceName] += 1
-		c.InterruptsBySource[row.SourceName] += 1
+		switch row.EventType {
+		case SpellDispel, SpellStolen:
+			c.DispelsBySource[row.SourceName] += 1
+		case SpellDispelFailed:
+			c.FailedDispelsBySource[row.SourceName] += 1
+		case SpellInterrupt:
+			c.InterruptsBySource[row.SourceName] += 1
+		}
 		return
 	}
 }
@@ -18,10 +18,11 @@ package frostparse
 
 import (
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 )
 
-
 func newTestParser() *Parser {
 	return New(
 		WithLogFile("./testdata/test.txt"),
@@ -41,3 +42,152 @@ func TestCollectorRun(t *testing.T) {
 	fmt.Println("DamageTakenBySource: ", stats.DamageTakenBySource)
 	fmt.Println("DamageTakenBySpell: ", stats.DamageTakenBySpell)
 }
+
+func parseTestLines(t *testing.T, lines ...string) []*CombatLogRecord {
+	t.Helper()
+	out := make([]*CombatLogRecord, len(lines))
+	for i := range lines {
+		v := mustParseRow(t, lines[i])
+		out[i] = &v
+	}
+	return out
+}
+
+func TestCollectorRunFoldsPetDamage(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 00:13:37.531  SPELL_ENERGIZE,0x070000000047DAB8,"Raddyboy",0x514,0xF14000A1B2000001,"pettywap",0x1114,34953,"Go for the Throat",0x1,25,2`,
+		`12/11 00:13:38.000  SWING_DAMAGE,0xF14000A1B2000001,"pettywap",0x1114,0xF130009093000102,"The Damned",0xa48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 00:13:38.500  SPELL_DAMAGE,0xF14000A1B2000001,"pettywap",0x1114,0xF130009093000102,"The Damned",0xa48,52476,"Claw",0x1,250,0,1,0,0,0,nil,nil,nil`,
+		`12/11 00:13:39.000  SPELL_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130009093000102,"The Damned",0xa48,49050,"Aimed Shot",0x1,1000,0,1,0,0,0,1,nil,nil`,
+	)
+	stats := NewCollector().Run(data)
+	b, ok := stats.DamageBySourceAndSpell["Raddyboy"]
+	if !ok {
+		t.Fatal("expected breakdown for Raddyboy")
+	}
+	if b.Spells["Aimed Shot"] != 1000 {
+		t.Errorf("expected 1000 Aimed Shot damage, got %d", b.Spells["Aimed Shot"])
+	}
+	if b.Pets["pettywap"]["Melee"] != 100 || b.Pets["pettywap"]["Claw"] != 250 {
+		t.Errorf("expected pet abilities folded under owner, got %v", b.Pets)
+	}
+	if _, ok := stats.DamageBySourceAndSpell["pettywap"]; ok {
+		t.Error("pet should not have its own breakdown")
+	}
+}
+
+func TestCollectorRunFoldsPetDamageOnBoss(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 00:13:37.531  SPELL_ENERGIZE,0x070000000047DAB8,"Raddyboy",0x514,0xF14000A1B2000001,"pettywap",0x1114,34953,"Go for the Throat",0x1,25,2`,
+		`12/11 00:13:38.000  SWING_DAMAGE,0xF14000A1B2000001,"pettywap",0x1114,0xF150008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 00:13:39.000  SWING_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF150008F0400003D,"Lord Marrowgar",0x10a48,1000,0,1,0,0,0,nil,nil,nil`,
+	)
+	stats := NewCollector().Run(data)
+	if _, ok := stats.DamageBySource["pettywap"]; ok {
+		t.Errorf("expected the pet not to be credited as a player, got %v", stats.DamageBySource)
+	}
+	if _, ok := stats.DamageBySourceAndSpell["pettywap"]; ok {
+		t.Error("pet should not have its own breakdown")
+	}
+	if b := stats.DamageBySourceAndSpell["Raddyboy"]; b == nil || b.Pets["pettywap"]["Melee"] != 100 || b.Spells["Melee"] != 1000 {
+		t.Errorf("expected the pet's boss damage folded under its owner, got %+v", b)
+	}
+}
+
+func TestCollectorRunAttributesExtraAttacks(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 00:16:48.965  SPELL_EXTRA_ATTACKS,0x070000000062ADF1,"Phokkwho",0x514,0x070000000062ADF1,"Phokkwho",0x514,66923,"Hack and Slash",0x1,1`,
+		`12/11 00:16:48.965  SWING_DAMAGE,0x070000000062ADF1,"Phokkwho",0x514,0xF130009093000104,"The Damned",0xa48,1999,0,1,0,0,0,nil,nil,nil`,
+		`12/11 00:16:49.005  SWING_DAMAGE,0x070000000062ADF1,"Phokkwho",0x514,0xF130009093000104,"The Damned",0xa48,960,0,1,0,0,0,nil,nil,nil`,
+		`12/11 00:16:50.500  SWING_DAMAGE,0x070000000062ADF1,"Phokkwho",0x514,0xF130009093000104,"The Damned",0xa48,1000,0,1,0,0,0,nil,nil,nil`,
+	)
+	stats := NewCollector().Run(data)
+	spells := stats.DamageBySourceAndSpell["Phokkwho"].Spells
+	if spells["Hack and Slash"] != 960 || spells["Melee"] != 2999 {
+		t.Errorf("expected the extra swing to be credited to Hack and Slash, got %v", spells)
+	}
+	if stats.DamageBySource["Phokkwho"] != 3959 {
+		t.Errorf("expected the extra attack count not to be counted as damage, got %d", stats.DamageBySource["Phokkwho"])
+	}
+}
+
+func TestCollectorRunSplitsHealing(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 00:13:37.531  SPELL_PERIODIC_HEAL,0x07000000007721EC,"Yogzar",0x511,0x070000000062ADF1,"Phokkwho",0x514,61301,"Riptide",0x8,1417,200,0,nil`,
+		`12/11 00:13:38.531  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x070000000062ADF1,"Phokkwho",0x514,61301,"Riptide",0x8,3000,0,100,1`,
+	)
+	stats := NewCollector().Run(data)
+	h := stats.HealingBySourceAndSpell["Yogzar"]["Riptide"]
+	if h == nil {
+		t.Fatal("expected Riptide healing for Yogzar")
+	}
+	if h.Direct != 3000 || h.Periodic != 1417 || h.Absorbed != 100 || h.Overhealing != 200 {
+		t.Errorf("unexpected healing split: %+v", *h)
+	}
+	if h.Total() != stats.HealingBySource["Yogzar"] {
+		t.Errorf("expected spell total %d to match source total %d", h.Total(), stats.HealingBySource["Yogzar"])
+	}
+}
+
+func TestCollectorRunDispelReport(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 00:22:24.866  SPELL_DISPEL,0x0700000000821F6B,"Manorothh",0x40514,0x0700000000821F6B,"Manorothh",0x40514,4987,"Cleanse",0x2,70964,"Shield Bash",1,BUFF`,
+		`12/11 00:22:25.866  SPELL_STOLEN,0x07000000000C1CFE,"Shevros",0x514,0xF130008F74000068,"Servant of the Throne",0xa48,30449,"Spellsteal",0x40,71029,"Frost Shield",16,BUFF`,
+		`12/11 00:22:26.866  SPELL_DISPEL_FAILED,0x0700000000821F6B,"Manorothh",0x40514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,4987,"Cleanse",0x2,69065,"Impaled",1`,
+		`12/11 00:22:27.866  SPELL_INTERRUPT,0x07000000008F2080,"Hominy",0x514,0xF130008F74000068,"Servant of the Throne",0xa48,1766,"Kick",0x1,71029,"Glacial Blast",16`,
+	)
+	stats := NewCollector().Run(data)
+	if stats.DispelsBySource["Manorothh"] != 1 || stats.DispelsBySource["Shevros"] != 1 {
+		t.Errorf("unexpected dispels: %v", stats.DispelsBySource)
+	}
+	if stats.FailedDispelsBySource["Manorothh"] != 1 {
+		t.Errorf("unexpected failed dispels: %v", stats.FailedDispelsBySource)
+	}
+	if stats.InterruptsBySource["Hominy"] != 1 || len(stats.InterruptsBySource) != 1 {
+		t.Errorf("unexpected interrupts: %v", stats.InterruptsBySource)
+	}
+	if stats.DispellsBySource["Manorothh"] != 1 {
+		t.Errorf("expected the deprecated field to share the dispel totals, got %v", stats.DispellsBySource)
+	}
+}
+
+func TestCollectorRunGroupsByRoster(t *testing.T) {
+	roster, err := ReadRoster(strings.NewReader("# name,group\nYogzar,1\nPhokkwho, 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := parseTestLines(t,
+		`12/11 00:13:36.000  SWING_DAMAGE,0xF130009093000102,"The Damned",0xa48,0x07000000007721EC,"Yogzar",0x511,500,0,1,0,0,0,nil,nil,nil`,
+		`12/11 00:13:37.000  SWING_DAMAGE,0xF130009093000102,"The Damned",0xa48,0x070000000062ADF1,"Phokkwho",0x514,300,0,1,0,0,0,nil,nil,nil`,
+		`12/11 00:13:38.531  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x070000000062ADF1,"Phokkwho",0x514,61301,"Riptide",0x8,3000,0,100,1`,
+	)
+	stats := NewCollector(WithRoster(roster)).Run(data)
+	if stats.DamageTakenByGroup[1] != 500 || stats.DamageTakenByGroup[2] != 300 {
+		t.Errorf("unexpected damage taken by group: %v", stats.DamageTakenByGroup)
+	}
+	if stats.HealingReceivedByGroup[2] != 3000 {
+		t.Errorf("unexpected healing received by group: %v", stats.HealingReceivedByGroup)
+	}
+	if _, err := ReadRoster(strings.NewReader("Yogzar,9\n")); err == nil {
+		t.Error("expected an out of range group to fail")
+	}
+}
+
+func TestSummaryStatsDPS(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,1000,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:10.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,1000,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:15.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,3000,0,0,nil`,
+		`12/11 01:08:20.000  SPELL_HEAL,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,61301,"Riptide",0x8,3000,0,0,nil`,
+	)
+	stats := NewCollector().Run(data)
+	if dps := stats.DPS(time.Second * 20); dps["Winterinjuly"] != 100 {
+		t.Errorf("expected 100 DPS over the encounter, got %v", dps)
+	}
+	if dps := stats.ActiveDPS(); dps["Winterinjuly"] != 200 {
+		t.Errorf("expected 200 DPS over active time, got %v", dps)
+	}
+	if hps := stats.ActiveHPS(); hps["Yogzar"] != 1200 {
+		t.Errorf("expected 1200 HPS over active time, got %v", hps)
+	}
+}
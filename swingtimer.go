@@ -0,0 +1,203 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"sort"
+	"time"
+)
+
+// SwingGap is a stretch of an encounter in which a melee player missed
+// swings they would have made, e.g. while running out of Malleable Goo or
+// moving between targets.
+type SwingGap struct {
+	Encounter string    `json:"encounter"`
+	Attempt   int       `json:"attempt"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+}
+
+// Duration returns the length of the gap.
+func (g SwingGap) Duration() time.Duration {
+	return g.End.Sub(g.Start)
+}
+
+// MeleeUptime is the reconstructed swing timeline of a melee player.
+type MeleeUptime struct {
+	// Duration is the combined length of the attempts the player swung in.
+	Duration time.Duration `json:"duration"`
+	Swings   int           `json:"swings"`
+	// SwingInterval is the estimated time between two swings, which for dual
+	// wielders is the time between main and off hand swings.
+	SwingInterval time.Duration `json:"swing_interval"`
+	Gaps          []SwingGap    `json:"gaps"`
+}
+
+// Downtime returns the combined length of every gap.
+func (m MeleeUptime) Downtime() time.Duration {
+	var d time.Duration
+	for _, g := range m.Gaps {
+		d += g.Duration()
+	}
+	return d
+}
+
+// Percent returns the share of the encounter time the player was swinging,
+// from 0 to 100.
+func (m MeleeUptime) Percent() float64 {
+	if m.Duration <= 0 {
+		return 0
+	}
+	return 100 * float64(m.Duration-m.Downtime()) / float64(m.Duration)
+}
+
+// MeleeUptimeReport maps encounter name to player name. Attempts at the same
+// encounter are combined.
+type MeleeUptimeReport map[string]map[string]*MeleeUptime
+
+// SwingTimerAnalyzer reconstructs the swing timeline of melee players from
+// the intervals between their SWING_DAMAGE and SWING_MISSED events, to
+// estimate melee uptime and the time lost to target switching and
+// movement.
+type SwingTimerAnalyzer struct {
+	Splitter *EncounterSplitter
+	// MinSwings is how many swings a player needs in an attempt to count as
+	// a melee player, so casters meleeing a little are left out.
+	MinSwings int
+	// Tolerance is how many swing intervals may pass without a swing before
+	// the silence counts as downtime, to allow for parry haste, haste
+	// procs and swing timer resets.
+	Tolerance float64
+}
+
+// SwingTimerAnalyzerFunc is an option for NewSwingTimerAnalyzer.
+type SwingTimerAnalyzerFunc func(*SwingTimerAnalyzer)
+
+// WithSwingTimerSplitter sets the EncounterSplitter used to find encounters.
+func WithSwingTimerSplitter(s *EncounterSplitter) SwingTimerAnalyzerFunc {
+	return func(a *SwingTimerAnalyzer) {
+		a.Splitter = s
+	}
+}
+
+// WithMinSwings sets how many swings make a melee player.
+func WithMinSwings(n int) SwingTimerAnalyzerFunc {
+	return func(a *SwingTimerAnalyzer) {
+		a.MinSwings = n
+	}
+}
+
+// WithSwingTolerance sets how many swing intervals without a swing count as
+// downtime.
+func WithSwingTolerance(f float64) SwingTimerAnalyzerFunc {
+	return func(a *SwingTimerAnalyzer) {
+		a.Tolerance = f
+	}
+}
+
+// NewSwingTimerAnalyzer initializes, allocates and returns a pointer to a
+// SwingTimerAnalyzer.
+func NewSwingTimerAnalyzer(opts ...SwingTimerAnalyzerFunc) *SwingTimerAnalyzer {
+	a := &SwingTimerAnalyzer{
+		Splitter:  NewEncounterSplitter(),
+		MinSwings: 10,
+		Tolerance: 1.5,
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+// Run measures the melee uptime of every player who swung at least
+// MinSwings times in a boss attempt. The swing interval is estimated per
+// attempt from the intervals between swings, and a silence longer than
+// Tolerance intervals is downtime from when the next swing was due until it
+// landed. The time before the first and after the last swing of an attempt
+// counts too.
+func (a *SwingTimerAnalyzer) Run(data []*CombatLogRecord) MeleeUptimeReport {
+	out := MeleeUptimeReport{}
+	for _, e := range a.Splitter.Split(data) {
+		swings := map[string][]time.Time{}
+		for _, row := range e.Records {
+			if isMeleeSwing(*row) {
+				swings[row.SourceName] = append(swings[row.SourceName], row.Timestamp)
+			}
+		}
+		for name, ts := range swings {
+			if len(ts) < max(a.MinSwings, 2) {
+				continue
+			}
+			players, ok := out[e.Name]
+			if !ok {
+				players = map[string]*MeleeUptime{}
+				out[e.Name] = players
+			}
+			m, ok := players[name]
+			if !ok {
+				m = &MeleeUptime{}
+				players[name] = m
+			}
+			a.measure(m, e, ts)
+		}
+	}
+	return out
+}
+
+// isMeleeSwing reports whether the record is a player's melee swing at a
+// unit that is not a player.
+func isMeleeSwing(row CombatLogRecord) bool {
+	return (row.EventType == SwingDamage || row.EventType == SwingMissed) &&
+		isPlayerID(row.SourceID) && !isPlayerID(row.TargetID)
+}
+
+// measure adds the swings of one attempt to the player's timeline.
+func (a *SwingTimerAnalyzer) measure(m *MeleeUptime, e Encounter, swings []time.Time) {
+	interval := swingInterval(swings)
+	allowed := time.Duration(a.Tolerance * float64(interval))
+	// gap records the silence between two swings as downtime from when the
+	// next swing was due
+	gap := func(last, next, due time.Time) {
+		if next.Sub(last) > allowed && next.After(due) {
+			m.Gaps = append(m.Gaps, SwingGap{Encounter: e.Name, Attempt: e.Attempt, Start: due, End: next})
+		}
+	}
+	gap(e.StartTime, swings[0], e.StartTime)
+	for i := 1; i < len(swings); i++ {
+		gap(swings[i-1], swings[i], swings[i-1].Add(interval))
+	}
+	last := swings[len(swings)-1]
+	gap(last, e.EndTime, last.Add(interval))
+	// attempts are combined, so the interval is the swing weighted average
+	m.SwingInterval = (m.SwingInterval*time.Duration(m.Swings) + interval*time.Duration(len(swings))) /
+		time.Duration(m.Swings+len(swings))
+	m.Swings += len(swings)
+	m.Duration += e.Duration()
+}
+
+// swingInterval estimates the time between swings as the 75th percentile of
+// the intervals between consecutive swings. The high percentile skips over
+// the short intervals of dual wielders whose hands swing close together,
+// while the silences that are downtime are rare enough to sit above it.
+func swingInterval(swings []time.Time) time.Duration {
+	intervals := make([]time.Duration, 0, len(swings)-1)
+	for i := 1; i < len(swings); i++ {
+		intervals = append(intervals, swings[i].Sub(swings[i-1]))
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i] < intervals[j] })
+	return intervals[len(intervals)*3/4]
+}
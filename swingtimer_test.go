@@ -0,0 +1,55 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSwingTimerAnalyzerRun(t *testing.T) {
+	var lines []string
+	swing := func(sec int, source, guid string) {
+		lines = append(lines, fmt.Sprintf(`12/11 01:08:%02d.000  SWING_DAMAGE,%s,"%s",0x514,0xF130008F46000001,"Professor Putricide",0x10a48,100,0,1,0,0,0,nil,nil,nil`, sec, guid, source))
+	}
+	for sec := 0; sec <= 50; sec += 2 {
+		if sec > 20 && sec < 32 {
+			continue
+		}
+		swing(sec, "Winterinjuly", "0x07000000009DF7A8")
+		if sec < 6 {
+			swing(sec, "Yogzar", "0x07000000007721EC")
+		}
+	}
+	lines = append(lines, `12/11 01:08:51.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F46000001,"Professor Putricide",0x10a48`)
+	report := NewSwingTimerAnalyzer().Run(parseTestLines(t, lines...))
+	players := report["Professor Putricide"]
+	if len(players) != 1 {
+		t.Fatalf("expected only the melee player, got %v", players)
+	}
+	m := players["Winterinjuly"]
+	if m.SwingInterval != 2*time.Second || m.Swings != 21 || m.Duration != 51*time.Second {
+		t.Errorf("unexpected timeline %+v", m)
+	}
+	if len(m.Gaps) != 1 || m.Gaps[0].Duration() != 10*time.Second || m.Gaps[0].Attempt != 1 {
+		t.Fatalf("expected a single 10s gap, got %+v", m.Gaps)
+	}
+	if got, want := m.Percent(), 100*41/51.0; got != want {
+		t.Errorf("expected %.2f%% uptime, got %.2f%%", want, got)
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// defaultPollInterval is how often Tail checks the log file for new lines.
+// The game writes the combat log in buffered chunks, so polling much faster
+// gains little.
+const defaultPollInterval = time.Millisecond * 250
+
+// tailReadSize is the size of the buffer used to read appended data.
+const tailReadSize = 64 * 1024
+
+// WithPollInterval sets how often Tail checks the log file for new lines.
+func WithPollInterval(d time.Duration) ParserFunc {
+	return func(p *Parser) {
+		p.PollInterval = d
+	}
+}
+
+// Tail follows LogFile as the game appends to it and invokes the
+// EventListener callback for every new record, until ctx is cancelled or
+// parsing fails. Records already in the file when Tail starts are skipped;
+// use Parse for those. A file that does not exist yet is waited for.
+//
+// When the file is truncated, e.g. because it was cleared between sessions,
+// or replaced by a new file, Tail starts over from the beginning of the new
+// contents. A line without its newline yet is held back until the rest of it
+// is written.
+//
+// Tail returns ctx.Err() once ctx is cancelled.
+func (p *Parser) Tail(ctx context.Context) error {
+	if err := p.discoverLogFile(); err != nil {
+		return err
+	}
+	p.reset()
+	defer p.startDispatch()()
+	t := &tailer{path: p.LogFile, buf: make([]byte, tailReadSize)}
+	defer t.close()
+	if err := t.open(true); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	interval := p.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := t.poll(func(line string) error {
+			p.report.Lines++
+			return p.scanLine([]byte(line), func(CombatLogRecord) error { return nil })
+		}, p.Limits.MaxLineLength)
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// tailer reads the lines appended to a file, following truncation and
+// replacement of the file.
+type tailer struct {
+	path    string
+	f       *os.File
+	offset  int64
+	partial []byte
+	buf     []byte
+}
+
+// open opens the file, positioned at its end when atEnd is set.
+func (t *tailer) open(atEnd bool) error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	t.f, t.offset, t.partial = f, 0, t.partial[:0]
+	if atEnd {
+		if t.offset, err = f.Seek(0, io.SeekEnd); err != nil {
+			t.close()
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *tailer) close() {
+	if t.f != nil {
+		t.f.Close()
+		t.f = nil
+	}
+}
+
+// poll hands every complete line appended since the last poll to fn.
+func (t *tailer) poll(fn func(string) error, maxLineLength int) error {
+	if t.f == nil {
+		// the file did not exist or was replaced, so everything in it is new
+		if err := t.open(false); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+	}
+	if err := t.checkTruncated(); err != nil {
+		return err
+	}
+	for {
+		n, err := t.f.Read(t.buf)
+		t.offset += int64(n)
+		t.partial = append(t.partial, t.buf[:n]...)
+		if lerr := t.lines(fn, maxLineLength); lerr != nil {
+			return lerr
+		}
+		if errors.Is(err, io.EOF) || n == 0 {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return t.checkReplaced()
+}
+
+// lines hands the complete lines in partial to fn and keeps the rest.
+func (t *tailer) lines(fn func(string) error, maxLineLength int) error {
+	rest := t.partial
+	for {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			break
+		}
+		line := bytes.TrimSuffix(rest[:i], []byte{'\r'})
+		rest = rest[i+1:]
+		if err := fn(string(line)); err != nil {
+			return err
+		}
+	}
+	if maxLineLength > 0 && len(rest) > maxLineLength {
+		return &LimitError{Limit: LimitLineLength, Max: int64(maxLineLength)}
+	}
+	t.partial = append(t.partial[:0], rest...)
+	return nil
+}
+
+// checkTruncated rewinds to the start of the file when it shrank below what
+// was already read.
+func (t *tailer) checkTruncated() error {
+	info, err := t.f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() >= t.offset {
+		return nil
+	}
+	if _, err := t.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	t.offset, t.partial = 0, t.partial[:0]
+	return nil
+}
+
+// checkReplaced closes the file once the path points at a different file,
+// so the next poll opens the new one. A missing path is not a replacement:
+// the game may not have recreated the file yet.
+func (t *tailer) checkReplaced() error {
+	info, err := os.Stat(t.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	current, err := t.f.Stat()
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(info, current) {
+		t.close()
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParserTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "WoWCombatLog.txt")
+	line := func(amount string) string {
+		return fmt.Sprintf("12/11 00:13:06.105  SWING_DAMAGE,0xF1300094280000B2,\"Argent Champion\",0xa18,0xF130009093000102,\"The Damned\",0xa48,%s,0,1,0,0,0,nil,nil,nil\n", amount)
+	}
+	if err := os.WriteFile(path, []byte(line("1")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := make(chan uint64, 8)
+	l := NewEventListener()
+	l.AddEventListener(SwingDamage, func(r CombatLogRecord) {
+		got <- r.DamageSuffix.Amount
+	})
+	p := New(WithLogFile(path), WithEventListener(l), WithPollInterval(time.Millisecond*5))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Tail(ctx) }()
+
+	expect := func(want uint64) {
+		t.Helper()
+		select {
+		case amount := <-got:
+			if amount != want {
+				t.Fatalf("expected a swing for %d, got %d", want, amount)
+			}
+		case <-time.After(time.Second * 2):
+			t.Fatalf("timed out waiting for a swing for %d", want)
+		}
+	}
+	appendLog := func(s string) {
+		t.Helper()
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// give Tail a moment to open the file and skip the existing line
+	time.Sleep(time.Millisecond * 50)
+	second := line("2")
+	appendLog(second[:20])
+	time.Sleep(time.Millisecond * 20)
+	appendLog(second[20:])
+	expect(2)
+
+	if err := os.WriteFile(path, []byte(line("3")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expect(3)
+
+	replacement := path + ".new"
+	if err := os.WriteFile(replacement, []byte(line("40")+line("5")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(replacement, path); err != nil {
+		t.Fatal(err)
+	}
+	expect(40)
+	expect(5)
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected Tail to stop with context.Canceled, got %v", err)
+	}
+	select {
+	case amount := <-got:
+		t.Errorf("expected the existing line to be skipped, got a swing for %d", amount)
+	default:
+	}
+}
+
+func TestParserTailAfterParse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "WoWCombatLog.txt")
+	first := "12/11 00:13:06.105  SWING_DAMAGE,0x07000000009DF7A8,\"Arthas\",0x514,0xF130009093000102,\"The Damned\",0xa48,1,0,1,0,0,0,nil,nil,nil\n"
+	if err := os.WriteFile(path, []byte(first), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := make(chan CombatLogRecord, 1)
+	l := NewEventListener()
+	p := New(WithLogFile(path), WithEventListener(l), WithPollInterval(time.Millisecond*5))
+	if _, err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	l.AddEventListener(SwingDamage, func(r CombatLogRecord) {
+		got <- r
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Tail(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	time.Sleep(time.Millisecond * 50)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// another player under the same name, logged a second before the last
+	// parsed line
+	f.WriteString("12/11 00:13:05.105  SWING_DAMAGE,0x0700000000A1B2C3,\"Arthas\",0x514,0xF130009093000102,\"The Damned\",0xa48,2,0,1,0,0,0,nil,nil,nil\n")
+	f.Close()
+	select {
+	case r := <-got:
+		if r.SourceName != "Arthas" {
+			t.Errorf("expected names numbered afresh, got %q", r.SourceName)
+		}
+		if !r.AdjustedTimestamp.Equal(r.Timestamp) {
+			t.Errorf("expected timestamps adjusted afresh, got %s for %s", r.AdjustedTimestamp, r.Timestamp)
+		}
+	case <-time.After(time.Second * 2):
+		t.Fatal("timed out waiting for the tailed swing")
+	}
+}
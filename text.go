@@ -0,0 +1,135 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// normalizeName lowercases s and drops spaces, underscores and dashes so that
+// "Runic Power", "runic_power" and "RUNIC-POWER" compare equal.
+func normalizeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '_', '-':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(s)))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It accepts a school
+// name such as "Shadowfrost", a combination of names joined with "|" or ","
+// such as "Shadow|Frost", or the numeric bitmask in decimal or hex.
+func (s *SpellSchool) UnmarshalText(text []byte) error {
+	str := strings.TrimSpace(string(text))
+	if n, err := strconv.ParseUint(str, 0, 8); err == nil {
+		*s = SpellSchool(n)
+		return nil
+	}
+	var out SpellSchool
+	for _, part := range strings.FieldsFunc(str, func(r rune) bool { return r == '|' || r == ',' }) {
+		school, ok := spellSchoolByName(part)
+		if !ok {
+			return fmt.Errorf("frostparse: unknown spell school %q", part)
+		}
+		out |= school
+	}
+	if out == 0 {
+		return fmt.Errorf("frostparse: empty spell school %q", str)
+	}
+	*s = out
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler. Schools are written as
+// their String form so that UnmarshalText reads them back unchanged; values
+// with bits outside the known schools fall back to the decimal bitmask.
+func (s SpellSchool) MarshalText() ([]byte, error) {
+	if s == 0 || s&^Fel != 0 {
+		return []byte(strconv.Itoa(int(s))), nil
+	}
+	return []byte(s.String()), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting either a number or
+// any string accepted by UnmarshalText.
+func (s *SpellSchool) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONText(b, s.UnmarshalText)
+}
+
+func spellSchoolByName(name string) (SpellSchool, bool) {
+	name = normalizeName(name)
+	for i := SpellSchool(1); i <= Fel; i++ {
+		if str := i.name(); str != "" && normalizeName(str) == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It accepts a power type
+// name such as "Runic Power" or its numeric value.
+func (pt *PowerType) UnmarshalText(text []byte) error {
+	str := strings.TrimSpace(string(text))
+	if n, err := strconv.Atoi(str); err == nil {
+		*pt = PowerType(n)
+		return nil
+	}
+	name := normalizeName(str)
+	for i := PowerType(-2); i <= 7; i++ {
+		if normalizeName(i.String()) == name {
+			*pt = i
+			return nil
+		}
+	}
+	return fmt.Errorf("frostparse: unknown power type %q", str)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting either a number or
+// any string accepted by UnmarshalText.
+func (pt *PowerType) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONText(b, pt.UnmarshalText)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It accepts any of the
+// EventTypes regardless of case, e.g. "spell_damage".
+func (e *EventType) UnmarshalText(text []byte) error {
+	str := strings.ToUpper(strings.TrimSpace(string(text)))
+	for _, et := range EventTypes {
+		if string(et) == str {
+			*e = et
+			return nil
+		}
+	}
+	return fmt.Errorf("frostparse: unknown event type %q", string(text))
+}
+
+// unmarshalJSONText passes a JSON string or bare number to fn.
+func unmarshalJSONText(b []byte, fn func([]byte) error) error {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		return fn([]byte(s))
+	}
+	return fn(b)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUnmarshalTextNames(t *testing.T) {
+	var cfg struct {
+		Schools []SpellSchool `json:"schools"`
+		Events  []EventType   `json:"events"`
+		Power   PowerType     `json:"power"`
+	}
+	in := `{"schools": ["Shadowfrost", "shadow|frost", "0x10", 4], "events": ["spell_damage"], "power": "Runic Power"}`
+	if err := json.Unmarshal([]byte(in), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := []SpellSchool{Shadowfrost, Shadowfrost, Frost, Fire}
+	for i := range want {
+		if cfg.Schools[i] != want[i] {
+			t.Errorf("expected school %d to be %s, got %s", i, want[i], cfg.Schools[i])
+		}
+	}
+	if cfg.Events[0] != SpellDamage || cfg.Power != 6 {
+		t.Errorf("unexpected event type %q or power type %s", cfg.Events[0], cfg.Power)
+	}
+	var s SpellSchool
+	if err := s.UnmarshalText([]byte("Frostbolt")); err == nil {
+		t.Error("expected an unknown school name to fail")
+	}
+	var e EventType
+	if err := e.UnmarshalText([]byte("SPELL_NOPE")); err == nil {
+		t.Error("expected an unknown event type to fail")
+	}
+}
+
+func TestSpellSchoolComponents(t *testing.T) {
+	s := Physical | Holy | Fire
+	if got := s.String(); got != "Physical|Holy|Fire" {
+		t.Errorf("expected an unnamed combination to join its components, got %q", got)
+	}
+	if got := Shadowfrost.Components(); len(got) != 2 || got[0] != Frost || got[1] != Shadow {
+		t.Errorf("expected Shadowfrost to be Frost and Shadow, got %v", got)
+	}
+	if !Chromatic.Has(Frost|Fire) || Chromatic.Has(Physical) || Frost.Has(0) {
+		t.Error("unexpected result from Has")
+	}
+	if got := (Frost | 0x80).String(); got != "Frost|0x80" {
+		t.Errorf("expected unknown bits to be formatted as hex, got %q", got)
+	}
+	for _, in := range []SpellSchool{Frost, Shadowfrost, s, Fel, 0, Frost | 0x80} {
+		b, err := in.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out SpellSchool
+		if err := out.UnmarshalText(b); err != nil {
+			t.Fatalf("unmarshal %q: %v", b, err)
+		}
+		if out != in {
+			t.Errorf("expected %q to round trip to %d, got %d", b, in, out)
+		}
+	}
+}
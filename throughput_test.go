@@ -0,0 +1,104 @@
+//go:build throughput
+
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bradleybonitatibus/frostparse"
+	"github.com/bradleybonitatibus/frostparse/frostparsetest"
+)
+
+// Throughput target, in lines of the generated fixture parsed per second by
+// the sequential parser. It ran at about 400k lines/s when the floor was set,
+// which leaves room for slow machines while still catching a redesign that
+// halves throughput. Override the floor with $FROSTPARSE_MIN_LINES_PER_SEC
+// on unusually slow or fast hardware.
+//
+// CI runs it as a separate, non-blocking step with:
+// go test -tags throughput -run Throughput .
+const (
+	throughputLines    = 500_000
+	throughputSeed     = 3355
+	minLinesPerSec     = 200_000
+	throughputAttempts = 3
+)
+
+// writeThroughputFixture generates the fixture log into a temporary
+// directory and returns its path.
+func writeThroughputFixture(tb testing.TB) string {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "WoWCombatLog.txt")
+	f, err := os.Create(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	if err := frostparsetest.GenerateLog(f, throughputLines, throughputSeed); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+func TestParseThroughput(t *testing.T) {
+	if testing.Short() {
+		t.Skip("throughput is not measured in short mode")
+	}
+	floor := float64(minLinesPerSec)
+	if v := os.Getenv("FROSTPARSE_MIN_LINES_PER_SEC"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			t.Fatalf("invalid $FROSTPARSE_MIN_LINES_PER_SEC: %v", err)
+		}
+		floor = f
+	}
+	path := writeThroughputFixture(t)
+	// the best of a few runs, so a busy machine does not fail the test
+	var best float64
+	for i := 0; i < throughputAttempts; i++ {
+		start := time.Now()
+		data, err := frostparse.New(frostparse.WithLogFile(path)).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != throughputLines {
+			t.Fatalf("expected %d records, got %d", throughputLines, len(data))
+		}
+		best = max(best, float64(throughputLines)/time.Since(start).Seconds())
+	}
+	t.Logf("parsed %.0f lines/s, floor %.0f", best, floor)
+	if best < floor {
+		t.Errorf("parsed %.0f lines/s, below the floor of %.0f", best, floor)
+	}
+}
+
+func BenchmarkParseFixture(b *testing.B) {
+	path := writeThroughputFixture(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := frostparse.New(frostparse.WithLogFile(path)).Parse(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(throughputLines*b.N)/b.Elapsed().Seconds(), "lines/s")
+}
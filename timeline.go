@@ -0,0 +1,141 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+)
+
+// TimelineDirection describes how a player relates to a timeline event.
+type TimelineDirection string
+
+const (
+	// TimelineOutgoing is an event the player sourced.
+	TimelineOutgoing TimelineDirection = "out"
+	// TimelineIncoming is an event the player received.
+	TimelineIncoming TimelineDirection = "in"
+	// TimelineSelf is an event the player sourced on themselves.
+	TimelineSelf TimelineDirection = "self"
+)
+
+// TimelineEntry is a single event in a player's timeline, annotated with the
+// encounter it happened in and the buffs the player had at the time.
+type TimelineEntry struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Direction TimelineDirection `json:"direction"`
+	EventType EventType         `json:"event_type"`
+	Source    string            `json:"source"`
+	Target    string            `json:"target"`
+	Spell     string            `json:"spell,omitempty"`
+	Amount    uint64            `json:"amount,omitempty"`
+	Critical  bool              `json:"critical,omitempty"`
+	Encounter string            `json:"encounter"`
+	// EncounterTime is the time since the encounter was pulled, and is zero
+	// outside of boss encounters.
+	EncounterTime time.Duration `json:"encounter_time,omitempty"`
+	ActiveBuffs   []string      `json:"active_buffs,omitempty"`
+}
+
+// PlayerTimeline returns every event the named player sourced or received, in
+// log order.
+func PlayerTimeline(data []*CombatLogRecord, name string) []TimelineEntry {
+	out := []TimelineEntry{}
+	encounters := newEncounterTracker(defaultCombatGap, nil)
+	buffs := map[string]struct{}{}
+	current := TrashEncounter
+	var pulled time.Time
+
+	for i := range data {
+		row := *data[i]
+		encounter := encounters.observe(row)
+		if encounter != current {
+			current = encounter
+			pulled = row.Timestamp
+		}
+		sourced := row.SourceName == name
+		received := row.TargetName == name
+		if !sourced && !received {
+			continue
+		}
+		if received {
+			trackBuff(buffs, row)
+		}
+
+		e := TimelineEntry{
+			Timestamp: row.Timestamp,
+			EventType: row.EventType,
+			Source:    row.SourceName,
+			Target:    row.TargetName,
+			Amount:    recordAmount(row),
+			Encounter: encounter,
+		}
+		switch {
+		case sourced && received:
+			e.Direction = TimelineSelf
+		case sourced:
+			e.Direction = TimelineOutgoing
+		default:
+			e.Direction = TimelineIncoming
+		}
+		if row.SpellAndRangePrefix != nil {
+			e.Spell = row.SpellAndRangePrefix.SpellName
+		}
+		if row.DamageSuffix != nil {
+			e.Critical = row.DamageSuffix.Critical
+		} else if row.HealSuffix != nil {
+			e.Critical = row.HealSuffix.Critical
+		}
+		if encounter != TrashEncounter {
+			e.EncounterTime = row.Timestamp.Sub(pulled)
+		}
+		if len(buffs) > 0 {
+			e.ActiveBuffs = make([]string, 0, len(buffs))
+			for b := range buffs {
+				e.ActiveBuffs = append(e.ActiveBuffs, b)
+			}
+			sort.Strings(e.ActiveBuffs)
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// trackBuff updates the set of buffs active on the target of row.
+func trackBuff(buffs map[string]struct{}, row CombatLogRecord) {
+	if row.EventType == UnitDied {
+		clear(buffs)
+		return
+	}
+	if row.AuraSuffix == nil || row.AuraSuffix.AuraType != BuffAura || row.SpellAndRangePrefix == nil {
+		return
+	}
+	switch row.EventType {
+	case SpellAuraApplied, SpellAuraRefresh, SpellAuraAppliedDose:
+		buffs[row.SpellAndRangePrefix.SpellName] = struct{}{}
+	case SpellAuraRemoved:
+		delete(buffs, row.SpellAndRangePrefix.SpellName)
+	}
+}
+
+// ExportPlayerTimeline writes the PlayerTimeline of the named player to w as
+// a JSON array.
+func ExportPlayerTimeline(w io.Writer, data []*CombatLogRecord, name string) error {
+	return json.NewEncoder(w).Encode(PlayerTimeline(data, name))
+}
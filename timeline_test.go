@@ -0,0 +1,59 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPlayerTimeline(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:10.000  SPELL_AURA_APPLIED,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,2825,"Bloodlust",0x8,BUFF`,
+		`12/11 01:08:12.000  SPELL_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,47809,"Shadow Bolt",0x20,5000,0,32,0,0,0,1,nil,nil`,
+		`12/11 01:08:14.000  SPELL_AURA_REMOVED,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,2825,"Bloodlust",0x8,BUFF`,
+		`12/11 01:08:15.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,9000,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:16.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x0700000000821F6B,"Manorothh",0x514,9000,0,1,0,0,0,nil,nil,nil`,
+	)
+	tl := PlayerTimeline(data, "Winterinjuly")
+	if len(tl) != 4 {
+		t.Fatalf("expected 4 timeline entries, got %d", len(tl))
+	}
+	if tl[1].Direction != TimelineOutgoing || tl[1].Spell != "Shadow Bolt" || !tl[1].Critical {
+		t.Errorf("unexpected outgoing entry: %+v", tl[1])
+	}
+	if len(tl[1].ActiveBuffs) != 1 || tl[1].ActiveBuffs[0] != "Bloodlust" {
+		t.Errorf("expected Bloodlust to be active, got %v", tl[1].ActiveBuffs)
+	}
+	if tl[3].Direction != TimelineIncoming || tl[3].Encounter != "Lord Marrowgar" || tl[3].EncounterTime != time.Second*3 {
+		t.Errorf("unexpected incoming entry: %+v", tl[3])
+	}
+	if len(tl[3].ActiveBuffs) != 0 {
+		t.Errorf("expected no active buffs after removal, got %v", tl[3].ActiveBuffs)
+	}
+
+	var buf bytes.Buffer
+	if err := ExportPlayerTimeline(&buf, data, "Winterinjuly"); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 4 {
+		t.Errorf("expected 4 exported entries, got %d (%v)", len(decoded), err)
+	}
+}
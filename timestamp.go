@@ -0,0 +1,79 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidTimestamp is returned for lines whose timestamp is not a valid
+// "month/day hour:minute:second.millisecond" time.
+var ErrInvalidTimestamp = errors.New("invalid timestamp")
+
+// timestampFields describes each number of a timestamp: the separator before
+// it, its minimum and maximum number of digits and its range.
+var timestampFields = [6]struct {
+	sep                  byte
+	minDigits, maxDigits int
+	lo, hi               int
+}{
+	{0, 1, 2, 1, 12},    // month
+	{'/', 1, 2, 1, 31},  // day
+	{' ', 1, 2, 0, 23},  // hour
+	{':', 2, 2, 0, 59},  // minute
+	{':', 2, 2, 0, 59},  // second
+	{'.', 3, 3, 0, 999}, // millisecond
+}
+
+// parseTimestamp parses the timestamp that starts every line, such as
+// "12/11 00:13:06.105", in the given year and location. It accepts the same
+// input as the time.ParseInLocation layout "2006/1/_2 15:04:05.000" without
+// its allocations.
+func parseTimestamp(b []byte, year int, loc *time.Location) (time.Time, error) {
+	var v [len(timestampFields)]int
+	i := 0
+	for n, tf := range timestampFields {
+		if tf.sep != 0 {
+			if i >= len(b) || b[i] != tf.sep {
+				return time.Time{}, ErrInvalidTimestamp
+			}
+			i++
+		}
+		// days are space padded
+		if n == 1 && i < len(b) && b[i] == ' ' {
+			i++
+		}
+		start := i
+		for i < len(b) && i-start < tf.maxDigits && b[i] >= '0' && b[i] <= '9' {
+			v[n] = v[n]*10 + int(b[i]-'0')
+			i++
+		}
+		if i-start < tf.minDigits || v[n] < tf.lo || v[n] > tf.hi {
+			return time.Time{}, ErrInvalidTimestamp
+		}
+	}
+	if i != len(b) {
+		return time.Time{}, ErrInvalidTimestamp
+	}
+	t := time.Date(year, time.Month(v[0]), v[1], v[2], v[3], v[4], v[5]*int(time.Millisecond), loc)
+	// time.Date normalizes days past the end of the month instead of failing
+	if t.Day() != v[1] {
+		return time.Time{}, ErrInvalidTimestamp
+	}
+	return t, nil
+}
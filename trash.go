@@ -0,0 +1,171 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"fmt"
+	"sort"
+)
+
+// trashMobCount is how many of the most damaged NPCs are kept in the Mobs of
+// a trash segment.
+const trashMobCount = 3
+
+// isTrashHostile reports whether the record is the raid and an NPC trading
+// blows.
+func isTrashHostile(row CombatLogRecord) bool {
+	switch {
+	case isDamageEvent(row), row.EventType == SwingMissed, row.EventType == SpellMissed, row.EventType == RangeMissed:
+	default:
+		return false
+	}
+	raid := func(guid string) bool {
+		return isPlayerID(guid) || isPetID(guid)
+	}
+	return (raid(row.SourceID) && isNPCID(row.TargetID)) || (isNPCID(row.SourceID) && raid(row.TargetID))
+}
+
+// Segments returns every boss attempt, as returned by Split, together with
+// the trash pulls in between, in log order. A trash pull starts with the
+// raid and an NPC trading blows and ends with the last such event before a
+// combat gap.
+//
+// Trash pulls are named after the nearest boss, preferring the next one:
+// "Icecrown Citadel Trash before Lord Marrowgar", or "... Trash after ..."
+// when no boss follows. Pulls in a log without bosses are named after the
+// NPC the raid did the most damage to.
+func (s *EncounterSplitter) Segments(data []*CombatLogRecord) []Encounter {
+	bosses := s.Split(data)
+	var out []Encounter
+	var cur *Encounter
+	var damage map[string]uint64
+	// next is the first boss attempt not yet over and emitted the number
+	// of attempts already added to out
+	next, emitted := 0, 0
+
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		n := len(cur.Records)
+		for n > 0 && cur.Records[n-1].Timestamp.After(cur.EndTime) {
+			n--
+		}
+		cur.Records = cur.Records[:n:n]
+		cur.Mobs = topMobs(damage)
+		out = append(out, *cur)
+		cur = nil
+	}
+
+	for i := range data {
+		row := data[i]
+		for next < len(bosses) && row.Timestamp.After(bosses[next].EndTime) {
+			next++
+		}
+		if next < len(bosses) && !row.Timestamp.Before(bosses[next].StartTime) {
+			flush()
+			if next == emitted {
+				out = append(out, bosses[next])
+				emitted++
+			}
+			continue
+		}
+		hostile := isTrashHostile(*row)
+		if cur != nil && row.Timestamp.Sub(cur.EndTime) > s.CombatGap {
+			flush()
+		}
+		if cur == nil {
+			if !hostile {
+				continue
+			}
+			cur = &Encounter{Trash: true, StartTime: row.Timestamp}
+			damage = map[string]uint64{}
+		}
+		cur.Records = append(cur.Records, row)
+		if hostile {
+			cur.EndTime = row.Timestamp
+			if isNPCID(row.TargetID) {
+				damage[row.TargetName] += recordAmount(*row)
+			}
+		}
+	}
+	flush()
+	s.nameTrash(out)
+	return out
+}
+
+// nameTrash names every trash segment after the nearest boss attempt.
+func (s *EncounterSplitter) nameTrash(segments []Encounter) {
+	bosses := bossRegistryOrDefault(s.Bosses)
+	zone := func(e Encounter) string {
+		for _, b := range bosses.Bosses {
+			if b.Name == e.Name {
+				return b.Zone
+			}
+		}
+		return ""
+	}
+	for i := range segments {
+		if !segments[i].Trash {
+			continue
+		}
+		var before, after *Encounter
+		for j := i + 1; j < len(segments) && before == nil; j++ {
+			if !segments[j].Trash {
+				before = &segments[j]
+			}
+		}
+		for j := i - 1; j >= 0 && after == nil; j-- {
+			if !segments[j].Trash {
+				after = &segments[j]
+			}
+		}
+		switch {
+		case before != nil:
+			segments[i].Name = fmt.Sprintf("%s before %s", trashPrefix(zone(*before)), before.Name)
+		case after != nil:
+			segments[i].Name = fmt.Sprintf("%s after %s", trashPrefix(zone(*after)), after.Name)
+		case len(segments[i].Mobs) > 0:
+			segments[i].Name = fmt.Sprintf("%s: %s", TrashEncounter, segments[i].Mobs[0])
+		default:
+			segments[i].Name = TrashEncounter
+		}
+	}
+}
+
+func trashPrefix(zone string) string {
+	if zone == "" {
+		return TrashEncounter
+	}
+	return zone + " " + TrashEncounter
+}
+
+// topMobs returns the names of the NPCs that took the most damage, most
+// damaged first.
+func topMobs(damage map[string]uint64) []string {
+	names := make([]string, 0, len(damage))
+	for name := range damage {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if damage[names[i]] != damage[names[j]] {
+			return damage[names[i]] > damage[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names[:min(len(names), trashMobCount)]
+}
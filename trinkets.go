@@ -0,0 +1,286 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"sort"
+	"time"
+)
+
+// defaultWasteWindow is how close to the end of an attempt an on-use
+// cooldown can be used before it is considered wasted.
+const defaultWasteWindow = time.Second * 10
+
+// lustDuration is how long Bloodlust and Heroism last when their removal is
+// not logged.
+const lustDuration = time.Second * 40
+
+// OnUseCooldown is an on-use trinket, racial or item effect tracked by the
+// CooldownAlignmentAnalyzer, matched by the name of the spell it casts.
+type OnUseCooldown struct {
+	Name     string        `json:"name"`
+	Cooldown time.Duration `json:"cooldown"`
+}
+
+// DefaultOnUseCooldowns are the damage racials and engineering tinkers. On-use
+// trinkets vary by tier, so add the ones the raid uses with
+// WithOnUseCooldowns.
+var DefaultOnUseCooldowns = []OnUseCooldown{
+	{Name: "Blood Fury", Cooldown: time.Minute * 2},
+	{Name: "Berserking", Cooldown: time.Minute * 3},
+	{Name: "Hyperspeed Acceleration", Cooldown: time.Minute},
+}
+
+// lustSpells are the auras that open a lust window on the players they are
+// applied to.
+var lustSpells = []string{"Bloodlust", "Heroism"}
+
+// CooldownUse is a single use of an on-use cooldown.
+type CooldownUse struct {
+	Player    string    `json:"player"`
+	Spell     string    `json:"spell"`
+	Time      time.Time `json:"time"`
+	Encounter string    `json:"encounter,omitempty"`
+	Attempt   int       `json:"attempt,omitempty"`
+	// InLust is set when the player had Bloodlust or Heroism.
+	InLust bool `json:"in_lust"`
+	// InVulnerability is set when a vulnerability aura was up on an enemy.
+	InVulnerability bool `json:"in_vulnerability"`
+	// Wasted is set for uses outside of boss attempts, or in the last
+	// moments of one.
+	Wasted bool `json:"wasted"`
+	// Misaligned is set for uses outside of lust and vulnerability windows
+	// when holding the cooldown would have had it ready for a window later in
+	// the attempt.
+	Misaligned bool `json:"misaligned"`
+}
+
+// Aligned reports whether the use overlapped lust or a vulnerability window.
+func (u CooldownUse) Aligned() bool {
+	return u.InLust || u.InVulnerability
+}
+
+// PlayerCooldowns are the on-use cooldown uses of one player.
+type PlayerCooldowns struct {
+	Uses       []CooldownUse `json:"uses"`
+	Aligned    int           `json:"aligned"`
+	Wasted     int           `json:"wasted"`
+	Misaligned int           `json:"misaligned"`
+}
+
+// CooldownAlignmentReport maps player name to their cooldown uses.
+type CooldownAlignmentReport map[string]*PlayerCooldowns
+
+// CooldownAlignmentAnalyzerFunc is an option for
+// NewCooldownAlignmentAnalyzer.
+type CooldownAlignmentAnalyzerFunc func(*CooldownAlignmentAnalyzer)
+
+// CooldownAlignmentAnalyzer reports how well players lined up their on-use
+// cooldowns with lust and with boss vulnerability phases.
+type CooldownAlignmentAnalyzer struct {
+	// Cooldowns are the tracked on-use effects. Defaults to
+	// DefaultOnUseCooldowns.
+	Cooldowns []OnUseCooldown
+	// Vulnerabilities are the names of auras that mark a phase in which an
+	// enemy takes increased damage, for as long as they are on any NPC.
+	Vulnerabilities []string
+	// WasteWindow is how close to the end of an attempt a use is wasted.
+	WasteWindow time.Duration
+	// Splitter finds the boss attempts. Defaults to an EncounterSplitter with
+	// default settings.
+	Splitter *EncounterSplitter
+}
+
+// WithOnUseCooldowns sets the tracked on-use effects.
+func WithOnUseCooldowns(c ...OnUseCooldown) CooldownAlignmentAnalyzerFunc {
+	return func(a *CooldownAlignmentAnalyzer) {
+		a.Cooldowns = c
+	}
+}
+
+// WithVulnerabilityAuras sets the auras that mark boss vulnerability phases.
+func WithVulnerabilityAuras(names ...string) CooldownAlignmentAnalyzerFunc {
+	return func(a *CooldownAlignmentAnalyzer) {
+		a.Vulnerabilities = names
+	}
+}
+
+// WithWasteWindow sets how close to the end of an attempt a use is wasted.
+func WithWasteWindow(d time.Duration) CooldownAlignmentAnalyzerFunc {
+	return func(a *CooldownAlignmentAnalyzer) {
+		a.WasteWindow = d
+	}
+}
+
+// WithAlignmentSplitter sets the EncounterSplitter used to find boss
+// attempts.
+func WithAlignmentSplitter(s *EncounterSplitter) CooldownAlignmentAnalyzerFunc {
+	return func(a *CooldownAlignmentAnalyzer) {
+		a.Splitter = s
+	}
+}
+
+// NewCooldownAlignmentAnalyzer initializes, allocates and returns a pointer
+// to a CooldownAlignmentAnalyzer.
+func NewCooldownAlignmentAnalyzer(opts ...CooldownAlignmentAnalyzerFunc) *CooldownAlignmentAnalyzer {
+	a := &CooldownAlignmentAnalyzer{
+		Cooldowns:   DefaultOnUseCooldowns,
+		WasteWindow: defaultWasteWindow,
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	if a.Splitter == nil {
+		a.Splitter = NewEncounterSplitter()
+	}
+	return a
+}
+
+// alignWindow is a span of time in which a use is aligned.
+type alignWindow struct {
+	start, end time.Time
+}
+
+func (w alignWindow) contains(t time.Time) bool {
+	return !t.Before(w.start) && !t.After(w.end)
+}
+
+// Run finds every SPELL_CAST_SUCCESS of a tracked cooldown by a player and
+// classifies it against the lust windows of that player, the vulnerability
+// windows and the boss attempts.
+func (a *CooldownAlignmentAnalyzer) Run(data []*CombatLogRecord) CooldownAlignmentReport {
+	cooldowns := map[string]time.Duration{}
+	for _, c := range a.Cooldowns {
+		cooldowns[c.Name] = c.Cooldown
+	}
+
+	var uses []CooldownUse
+	lust := map[string][]alignWindow{}
+	openLust := map[string]time.Time{}
+	var vulns []alignWindow
+	openVulns := map[string]time.Time{}
+	closeLust := func(player string, at time.Time) {
+		start := openLust[player]
+		lust[player] = append(lust[player], alignWindow{start, minTime(at, start.Add(lustDuration))})
+		delete(openLust, player)
+	}
+	for _, row := range data {
+		if row.SpellAndRangePrefix == nil {
+			continue
+		}
+		spell := row.SpellAndRangePrefix.SpellName
+		switch {
+		case row.EventType == SpellCastSuccess && isPlayerID(row.SourceID):
+			if _, ok := cooldowns[spell]; ok {
+				uses = append(uses, CooldownUse{Player: row.SourceName, Spell: spell, Time: row.Timestamp})
+			}
+		case isPlayerID(row.TargetID) && sliceContains(lustSpells, spell):
+			switch row.EventType {
+			case SpellAuraApplied:
+				if _, ok := openLust[row.TargetName]; ok {
+					closeLust(row.TargetName, row.Timestamp)
+				}
+				openLust[row.TargetName] = row.Timestamp
+			case SpellAuraRemoved:
+				if _, ok := openLust[row.TargetName]; ok {
+					closeLust(row.TargetName, row.Timestamp)
+				}
+			}
+		case !isPlayerID(row.TargetID) && sliceContains(a.Vulnerabilities, spell):
+			key := row.TargetID + "\x00" + spell
+			switch row.EventType {
+			case SpellAuraApplied:
+				if _, ok := openVulns[key]; !ok {
+					openVulns[key] = row.Timestamp
+				}
+			case SpellAuraRemoved:
+				if start, ok := openVulns[key]; ok {
+					vulns = append(vulns, alignWindow{start, row.Timestamp})
+					delete(openVulns, key)
+				}
+			}
+		}
+	}
+	for player, start := range openLust {
+		lust[player] = append(lust[player], alignWindow{start, start.Add(lustDuration)})
+	}
+	if len(data) > 0 {
+		last := data[len(data)-1].Timestamp
+		for _, start := range openVulns {
+			vulns = append(vulns, alignWindow{start, last})
+		}
+	}
+
+	encounters := a.Splitter.Split(data)
+	out := CooldownAlignmentReport{}
+	for _, u := range uses {
+		windows := append(append([]alignWindow(nil), lust[u.Player]...), vulns...)
+		u.InLust = inAnyWindow(lust[u.Player], u.Time)
+		u.InVulnerability = inAnyWindow(vulns, u.Time)
+		enc, ok := encounterAt(encounters, u.Time)
+		if ok {
+			u.Encounter, u.Attempt = enc.Name, enc.Attempt
+		}
+		u.Wasted = !ok || enc.EndTime.Sub(u.Time) < a.WasteWindow
+		if ok && !u.Aligned() {
+			ready := u.Time.Add(cooldowns[u.Spell])
+			for _, w := range windows {
+				if w.start.After(u.Time) && w.start.Before(ready) && !w.start.After(enc.EndTime) {
+					u.Misaligned = true
+					break
+				}
+			}
+		}
+
+		p, seen := out[u.Player]
+		if !seen {
+			p = &PlayerCooldowns{}
+			out[u.Player] = p
+		}
+		p.Uses = append(p.Uses, u)
+		if u.Aligned() {
+			p.Aligned++
+		}
+		if u.Wasted {
+			p.Wasted++
+		}
+		if u.Misaligned {
+			p.Misaligned++
+		}
+	}
+	return out
+}
+
+func inAnyWindow(windows []alignWindow, t time.Time) bool {
+	for _, w := range windows {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// encounterAt returns the boss attempt in progress at t.
+func encounterAt(encounters []Encounter, t time.Time) (Encounter, bool) {
+	i := sort.Search(len(encounters), func(i int) bool {
+		return !encounters[i].EndTime.Before(t)
+	})
+	if i < len(encounters) && !t.Before(encounters[i].StartTime) {
+		return encounters[i], true
+	}
+	return Encounter{}, false
+}
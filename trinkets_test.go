@@ -0,0 +1,58 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "testing"
+
+func TestCooldownAlignmentAnalyzerRun(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:08:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:02.000  SPELL_CAST_SUCCESS,0x07000000009DF7A8,"Winterinjuly",0x514,0x0000000000000000,nil,0x80000000,26297,"Berserking",0x1`,
+		`12/11 01:08:10.000  SPELL_AURA_APPLIED,0x07000000007721EC,"Yogzar",0x511,0x07000000009DF7A8,"Winterinjuly",0x514,2825,"Bloodlust",0x8,BUFF`,
+		`12/11 01:08:10.000  SPELL_AURA_APPLIED,0x07000000007721EC,"Yogzar",0x511,0x07000000007721EC,"Yogzar",0x511,2825,"Bloodlust",0x8,BUFF`,
+		`12/11 01:08:15.000  SPELL_CAST_SUCCESS,0x07000000007721EC,"Yogzar",0x511,0x0000000000000000,nil,0x80000000,33697,"Blood Fury",0x1`,
+		`12/11 01:08:20.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:40.000  SWING_DAMAGE,0xF130008F0400003D,"Lord Marrowgar",0x10a48,0x07000000009DF7A8,"Winterinjuly",0x514,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:52.000  SPELL_CAST_SUCCESS,0x07000000007721EC,"Yogzar",0x511,0x0000000000000000,nil,0x80000000,54758,"Hyperspeed Acceleration",0x1`,
+		`12/11 01:08:56.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F0400003D,"Lord Marrowgar",0x10a48`,
+		`12/11 01:15:00.000  SPELL_CAST_SUCCESS,0x07000000009DF7A8,"Winterinjuly",0x514,0x0000000000000000,nil,0x80000000,54758,"Hyperspeed Acceleration",0x1`,
+	)
+	report := NewCooldownAlignmentAnalyzer().Run(data)
+
+	w := report["Winterinjuly"]
+	if w == nil || len(w.Uses) != 2 {
+		t.Fatalf("expected 2 uses for Winterinjuly, got %+v", w)
+	}
+	berserking := w.Uses[0]
+	if berserking.Encounter != "Lord Marrowgar" || berserking.InLust || !berserking.Misaligned || berserking.Wasted {
+		t.Errorf("expected Berserking before lust to be misaligned, got %+v", berserking)
+	}
+	if trash := w.Uses[1]; !trash.Wasted || trash.Encounter != "" {
+		t.Errorf("expected the use outside the attempt to be wasted, got %+v", trash)
+	}
+
+	y := report["Yogzar"]
+	if y == nil || len(y.Uses) != 2 || y.Aligned != 1 || y.Wasted != 1 {
+		t.Fatalf("unexpected uses for Yogzar: %+v", y)
+	}
+	if fury := y.Uses[0]; !fury.InLust || fury.Misaligned {
+		t.Errorf("expected Blood Fury in lust to be aligned, got %+v", fury)
+	}
+	if tinker := y.Uses[1]; !tinker.Wasted || tinker.InLust {
+		t.Errorf("expected the tinker 4s before the kill to be wasted, got %+v", tinker)
+	}
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+// Event is a combat log line converted to a concrete type, so consumers can
+// type-switch on the event instead of checking which of the embedded `Prefix`
+// and `Suffix` pointers are set. Event types without a dedicated struct are
+// returned as their `BaseCombatEvent`.
+type Event interface {
+	Base() BaseCombatEvent
+}
+
+// Base returns the common properties of the event.
+func (b BaseCombatEvent) Base() BaseCombatEvent {
+	return b
+}
+
+// SwingDamageEvent is a SWING_DAMAGE line.
+type SwingDamageEvent struct {
+	BaseCombatEvent
+	Damage DamageSuffix
+}
+
+// SwingMissedEvent is a SWING_MISSED line.
+type SwingMissedEvent struct {
+	BaseCombatEvent
+	Miss MissSuffix
+}
+
+// SpellDamageEvent is a SPELL_DAMAGE, SPELL_PERIODIC_DAMAGE, RANGE_DAMAGE,
+// DAMAGE_SHIELD or DAMAGE_SPLIT line.
+type SpellDamageEvent struct {
+	BaseCombatEvent
+	Spell  SpellAndRangePrefix
+	Damage DamageSuffix
+}
+
+// SpellMissedEvent is a SPELL_MISSED, SPELL_PERIODIC_MISSED, RANGE_MISSED or
+// DAMAGE_SHIELD_MISSED line.
+type SpellMissedEvent struct {
+	BaseCombatEvent
+	Spell SpellAndRangePrefix
+	Miss  MissSuffix
+}
+
+// EnvironmentalDamageEvent is an ENVIRONMENTAL_DAMAGE line.
+type EnvironmentalDamageEvent struct {
+	BaseCombatEvent
+	Environment EnvironmentalPrefix
+	Damage      DamageSuffix
+}
+
+// SpellHealEvent is a SPELL_HEAL or SPELL_PERIODIC_HEAL line.
+type SpellHealEvent struct {
+	BaseCombatEvent
+	Spell SpellAndRangePrefix
+	Heal  HealSuffix
+}
+
+// SpellEnergizeEvent is a SPELL_ENERGIZE or SPELL_PERIODIC_ENERGIZE line.
+type SpellEnergizeEvent struct {
+	BaseCombatEvent
+	Spell    SpellAndRangePrefix
+	Energize EnergizeSuffix
+}
+
+// SpellDrainEvent is a SPELL_DRAIN or SPELL_PERIODIC_LEECH line.
+type SpellDrainEvent struct {
+	BaseCombatEvent
+	Spell SpellAndRangePrefix
+	Drain LeechOrDrainSuffix
+}
+
+// AuraAppliedEvent is a SPELL_AURA_APPLIED line.
+type AuraAppliedEvent struct {
+	BaseCombatEvent
+	Spell SpellAndRangePrefix
+	Aura  AuraSuffix
+}
+
+// AuraRemovedEvent is a SPELL_AURA_REMOVED line.
+type AuraRemovedEvent struct {
+	BaseCombatEvent
+	Spell SpellAndRangePrefix
+	Aura  AuraSuffix
+}
+
+// AuraRefreshEvent is a SPELL_AURA_REFRESH line.
+type AuraRefreshEvent struct {
+	BaseCombatEvent
+	Spell SpellAndRangePrefix
+	Aura  AuraSuffix
+}
+
+// AuraDoseEvent is a SPELL_AURA_APPLIED_DOSE or SPELL_AURA_REMOVED_DOSE line.
+// The log does not record the aura type for removed doses, so `Aura` is empty
+// for those.
+type AuraDoseEvent struct {
+	BaseCombatEvent
+	Spell SpellAndRangePrefix
+	Aura  AuraSuffix
+}
+
+// SpellCastEvent is a SPELL_CAST_START or SPELL_CAST_SUCCESS line.
+type SpellCastEvent struct {
+	BaseCombatEvent
+	Spell SpellAndRangePrefix
+}
+
+// SpellCastFailedEvent is a SPELL_CAST_FAILED line.
+type SpellCastFailedEvent struct {
+	BaseCombatEvent
+	Spell SpellAndRangePrefix
+}
+
+// SpellInterruptEvent is a SPELL_INTERRUPT line.
+type SpellInterruptEvent struct {
+	BaseCombatEvent
+	Spell     SpellAndRangePrefix
+	Interrupt InterruptSuffix
+}
+
+// SpellDispelEvent is a SPELL_DISPEL, SPELL_DISPEL_FAILED or SPELL_STOLEN line.
+type SpellDispelEvent struct {
+	BaseCombatEvent
+	Spell  SpellAndRangePrefix
+	Dispel DispelOrStolenSuffix
+}
+
+// AuraBrokenEvent is a SPELL_AURA_BROKEN or SPELL_AURA_BROKEN_SPELL line, a
+// crowd control aura broken by damage. Breaker is the spell that broke it,
+// and is empty for SPELL_AURA_BROKEN, which is broken by a melee swing.
+type AuraBrokenEvent struct {
+	BaseCombatEvent
+	Spell   SpellAndRangePrefix
+	Aura    AuraSuffix
+	Breaker DispelOrStolenSuffix
+}
+
+// SpellExtraAttacksEvent is a SPELL_EXTRA_ATTACKS line.
+type SpellExtraAttacksEvent struct {
+	BaseCombatEvent
+	Spell        SpellAndRangePrefix
+	ExtraAttacks ExtraAttacksSuffix
+}
+
+// SpellEvent is a SPELL_CREATE, SPELL_SUMMON, SPELL_RESURRECT,
+// SPELL_INSTAKILL or SPELL_DURABILITY_DAMAGE(_ALL) line, which carry nothing
+// beyond the spell.
+type SpellEvent struct {
+	BaseCombatEvent
+	Spell SpellAndRangePrefix
+}
+
+// EnchantEvent is an ENCHANT_APPLIED or ENCHANT_REMOVED line.
+type EnchantEvent struct {
+	BaseCombatEvent
+	Enchant EnchantPrefix
+}
+
+// UnitDiedEvent is a UNIT_DIED line.
+type UnitDiedEvent struct {
+	BaseCombatEvent
+}
+
+// UnitDestroyedEvent is a UNIT_DESTROYED or UNIT_DISSIPATES line, logged when
+// a totem, guardian or other summoned unit goes away without dying.
+type UnitDestroyedEvent struct {
+	BaseCombatEvent
+}
+
+// PartyKillEvent is a PARTY_KILL line.
+type PartyKillEvent struct {
+	BaseCombatEvent
+}
+
+// Typed converts the record into its concrete `Event` type. Prefixes and
+// suffixes missing from the record are left as zero values.
+func (r *CombatLogRecord) Typed() Event {
+	b := r.BaseCombatEvent
+	spell := deref(r.SpellAndRangePrefix)
+	switch b.EventType {
+	case SwingDamage:
+		return SwingDamageEvent{b, deref(r.DamageSuffix)}
+	case SwingMissed:
+		return SwingMissedEvent{b, deref(r.MissSuffix)}
+	case SpellDamage, SpellPeriodicDamage, RangeDamage, DamageShield, DamageSplit:
+		return SpellDamageEvent{b, spell, deref(r.DamageSuffix)}
+	case SpellMissed, SpellPeriodicMissed, RangeMissed, DamageShieldMissed:
+		return SpellMissedEvent{b, spell, deref(r.MissSuffix)}
+	case EnvironmentalDamage:
+		return EnvironmentalDamageEvent{b, deref(r.EnvironmentalPrefix), deref(r.DamageSuffix)}
+	case SpellHeal, SpellPeriodicHeal:
+		return SpellHealEvent{b, spell, deref(r.HealSuffix)}
+	case SpellEnergize, SpellPeriodicEnergize:
+		return SpellEnergizeEvent{b, spell, deref(r.EnergizeSuffix)}
+	case SpellDrain, SpellPeriodicLeech:
+		return SpellDrainEvent{b, spell, deref(r.LeechOrDrainSuffix)}
+	case SpellAuraApplied:
+		return AuraAppliedEvent{b, spell, deref(r.AuraSuffix)}
+	case SpellAuraRemoved:
+		return AuraRemovedEvent{b, spell, deref(r.AuraSuffix)}
+	case SpellAuraRefresh:
+		return AuraRefreshEvent{b, spell, deref(r.AuraSuffix)}
+	case SpellAuraAppliedDose, SpellAuraRemovedDose:
+		return AuraDoseEvent{b, spell, deref(r.AuraSuffix)}
+	case SpellCastStart, SpellCastSuccess:
+		return SpellCastEvent{b, spell}
+	case SpellCastFailed:
+		return SpellCastFailedEvent{b, spell}
+	case SpellInterrupt:
+		return SpellInterruptEvent{b, spell, deref(r.InterruptSuffix)}
+	case SpellDispel, SpellDispelFailed, SpellStolen:
+		return SpellDispelEvent{b, spell, deref(r.DispelOrStolenSuffix)}
+	case SpellAuraBroken:
+		return AuraBrokenEvent{b, spell, deref(r.AuraSuffix), DispelOrStolenSuffix{}}
+	case SpellAuraBrokenSpell:
+		breaker := deref(r.DispelOrStolenSuffix)
+		return AuraBrokenEvent{b, spell, AuraSuffix{AuraType: breaker.AuraType}, breaker}
+	case SpellExtraAttacks:
+		return SpellExtraAttacksEvent{b, spell, deref(r.ExtraAttacksSuffix)}
+	case SpellCreate, SpellSummon, SpellResurrect, SpellInstakill, SpellDurabilityDamage, SpellDurabilityDamageAll:
+		return SpellEvent{b, spell}
+	case EnchantApplied, EnchantRemoved:
+		return EnchantEvent{b, deref(r.EnchantPrefix)}
+	case UnitDied:
+		return UnitDiedEvent{b}
+	case UnitDestroyed, UnitDissipates:
+		return UnitDestroyedEvent{b}
+	case PartyKill:
+		return PartyKillEvent{b}
+	}
+	return b
+}
+
+// Records is a slice of parsed combat log records.
+type Records []*CombatLogRecord
+
+// Typed converts every record into its concrete `Event` type, in order.
+func (rs Records) Typed() []Event {
+	out := make([]Event, len(rs))
+	for i, r := range rs {
+		out[i] = r.Typed()
+	}
+	return out
+}
+
+func deref[T any](p *T) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+	return *p
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import "testing"
+
+func TestRecordsTyped(t *testing.T) {
+	events := Records(parseTestLines(t,
+		`12/11 00:13:38.000  SWING_DAMAGE,0xF14000A1B2000001,"pettywap",0x1114,0xF130009093000102,"The Damned",0xa48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 00:13:39.000  SPELL_DAMAGE,0x070000000047DAB8,"Raddyboy",0x514,0xF130009093000102,"The Damned",0xa48,49050,"Aimed Shot",0x1,1000,0,1,0,0,0,1,nil,nil`,
+		`12/11 01:00:00.000  SPELL_AURA_APPLIED,0xF130009402000010,"Cult Adherent",0xa48,0x07000000009DF7A8,"Winterinjuly",0x514,71237,"Curse of Torpor",0x20,DEBUFF`,
+		`12/11 01:00:01.000  SPELL_AURA_REMOVED_DOSE,0xF130009402000010,"Cult Adherent",0xa48,0x07000000009DF7A8,"Winterinjuly",0x514,71237,"Curse of Torpor",0x20,DEBUFF,2`,
+		`12/11 01:00:02.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130009093000102,"The Damned",0xa48`,
+	)).Typed()
+	if len(events) != 5 {
+		t.Fatalf("expected 5 events, got %d", len(events))
+	}
+
+	for i, ev := range events {
+		switch e := ev.(type) {
+		case SwingDamageEvent:
+			if e.Damage.Amount != 100 || e.SourceName != "pettywap" {
+				t.Errorf("unexpected swing: %+v", e)
+			}
+		case SpellDamageEvent:
+			if e.Spell.SpellName != "Aimed Shot" || e.Damage.Amount != 1000 || !e.Damage.Critical {
+				t.Errorf("unexpected spell damage: %+v", e)
+			}
+		case AuraAppliedEvent:
+			if e.Aura.AuraType != DebuffAura || e.Spell.SpellID != 71237 {
+				t.Errorf("unexpected aura: %+v", e)
+			}
+		case AuraDoseEvent:
+			if e.Aura.AuraType != DebuffAura || e.Aura.Doses != 2 || e.Spell.SpellName != "Curse of Torpor" {
+				t.Errorf("unexpected dose: %+v", e)
+			}
+		case UnitDiedEvent:
+			if e.TargetName != "The Damned" {
+				t.Errorf("unexpected death: %+v", e)
+			}
+		default:
+			t.Errorf("event %d: unexpected type %T", i, ev)
+		}
+	}
+	if got := events[1].Base().EventType; got != SpellDamage {
+		t.Errorf("expected SPELL_DAMAGE base, got %s", got)
+	}
+}
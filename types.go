@@ -17,12 +17,17 @@ limitations under the License.
 package frostparse
 
 import (
+	"fmt"
+	"strings"
 	"time"
 )
 
 // AuraType represents the enumeration of Buff or Debuf auras.
 type AuraType string
 
+// MissType represents the reason an attack or spell missed.
+type MissType string
+
 // EnvironmentalType represents environmental damage types.
 type EnvironmentalType string
 
@@ -36,45 +41,107 @@ type PowerType int
 type SpellSchool int
 
 const (
-	DamageShield          EventType = "DAMAGE_SHIELD"
-	DamageShieldMissed    EventType = "DAMAGE_SHIELD_MISSED"
-	DamageSplit           EventType = "DAMAGE_SPLIT"
-	EnchantApplied        EventType = "ENCHANT_APPLIED"
-	EnchantRemoved        EventType = "ENCHANT_REMOVED"
-	EnvironmentalDamage   EventType = "ENVIRONMENTAL_DAMAGE"
-	PartyKill             EventType = "PARTY_KILL"
-	RangeDamage           EventType = "RANGE_DAMAGE"
-	RangeMissed           EventType = "RANGE_MISSED"
-	SpellAuraApplied      EventType = "SPELL_AURA_APPLIED"
-	SpellAuraAppliedDose  EventType = "SPELL_AURA_APPLIED_DOSE"
-	SpellAuraRefresh      EventType = "SPELL_AURA_REFRESH"
-	SpellAuraRemoved      EventType = "SPELL_AURA_REMOVED"
-	SpellAuraRemovedDose  EventType = "SPELL_AURA_REMOVED_DOSE"
-	SpellCastFailed       EventType = "SPELL_CAST_FAILED"
-	SpellCastStart        EventType = "SPELL_CAST_START"
-	SpellCastSuccess      EventType = "SPELL_CAST_SUCCESS"
-	SpellCreate           EventType = "SPELL_CREATE"
-	SpellDamage           EventType = "SPELL_DAMAGE"
-	SpellDispell          EventType = "SPELL_DISPEL"
-	SpellDrain            EventType = "SPELL_DRAIN"
-	SpellEnergize         EventType = "SPELL_ENERGIZE"
-	SpellExtraAttacks     EventType = "SPELL_EXTRA_ATTACKS"
-	SpellHeal             EventType = "SPELL_HEAL"
-	SpellInterrupt        EventType = "SPELL_INTERRUPT"
-	SpellInstakill        EventType = "SPELL_INSTAKILL"
-	SpellMissed           EventType = "SPELL_MISSED"
-	SpellPeriodicDamage   EventType = "SPELL_PERIODIC_DAMAGE"
-	SpellPeriodicEnergize EventType = "SPELL_PERIODIC_ENERGIZE"
-	SpellPeriodicHeal     EventType = "SPELL_PERIODIC_HEAL"
-	SpellPeriodicLeech    EventType = "SPELL_PERIODIC_LEECH"
-	SpellPeriodicMissed   EventType = "SPELL_PERIODIC_MISSED"
-	SpellResurrect        EventType = "SPELL_RESURRECT"
-	SpellSummon           EventType = "SPELL_SUMMON"
-	SwingDamage           EventType = "SWING_DAMAGE"
-	SwingMissed           EventType = "SWING_MISSED"
-	UnitDied              EventType = "UNIT_DIED"
+	DamageShield             EventType = "DAMAGE_SHIELD"
+	DamageShieldMissed       EventType = "DAMAGE_SHIELD_MISSED"
+	DamageSplit              EventType = "DAMAGE_SPLIT"
+	EnchantApplied           EventType = "ENCHANT_APPLIED"
+	EnchantRemoved           EventType = "ENCHANT_REMOVED"
+	EnvironmentalDamage      EventType = "ENVIRONMENTAL_DAMAGE"
+	PartyKill                EventType = "PARTY_KILL"
+	RangeDamage              EventType = "RANGE_DAMAGE"
+	RangeMissed              EventType = "RANGE_MISSED"
+	SpellAuraApplied         EventType = "SPELL_AURA_APPLIED"
+	SpellAuraAppliedDose     EventType = "SPELL_AURA_APPLIED_DOSE"
+	SpellAuraBroken          EventType = "SPELL_AURA_BROKEN"
+	SpellAuraBrokenSpell     EventType = "SPELL_AURA_BROKEN_SPELL"
+	SpellAuraRefresh         EventType = "SPELL_AURA_REFRESH"
+	SpellAuraRemoved         EventType = "SPELL_AURA_REMOVED"
+	SpellAuraRemovedDose     EventType = "SPELL_AURA_REMOVED_DOSE"
+	SpellCastFailed          EventType = "SPELL_CAST_FAILED"
+	SpellCastStart           EventType = "SPELL_CAST_START"
+	SpellCastSuccess         EventType = "SPELL_CAST_SUCCESS"
+	SpellCreate              EventType = "SPELL_CREATE"
+	SpellDamage              EventType = "SPELL_DAMAGE"
+	SpellDispel              EventType = "SPELL_DISPEL"
+	SpellDispelFailed        EventType = "SPELL_DISPEL_FAILED"
+	SpellDrain               EventType = "SPELL_DRAIN"
+	SpellDurabilityDamage    EventType = "SPELL_DURABILITY_DAMAGE"
+	SpellDurabilityDamageAll EventType = "SPELL_DURABILITY_DAMAGE_ALL"
+	SpellEnergize            EventType = "SPELL_ENERGIZE"
+	SpellExtraAttacks        EventType = "SPELL_EXTRA_ATTACKS"
+	SpellHeal                EventType = "SPELL_HEAL"
+	SpellInterrupt           EventType = "SPELL_INTERRUPT"
+	SpellInstakill           EventType = "SPELL_INSTAKILL"
+	SpellMissed              EventType = "SPELL_MISSED"
+	SpellPeriodicDamage      EventType = "SPELL_PERIODIC_DAMAGE"
+	SpellPeriodicEnergize    EventType = "SPELL_PERIODIC_ENERGIZE"
+	SpellPeriodicHeal        EventType = "SPELL_PERIODIC_HEAL"
+	SpellPeriodicLeech       EventType = "SPELL_PERIODIC_LEECH"
+	SpellPeriodicMissed      EventType = "SPELL_PERIODIC_MISSED"
+	SpellResurrect           EventType = "SPELL_RESURRECT"
+	SpellStolen              EventType = "SPELL_STOLEN"
+	SpellSummon              EventType = "SPELL_SUMMON"
+	SwingDamage              EventType = "SWING_DAMAGE"
+	SwingMissed              EventType = "SWING_MISSED"
+	UnitDestroyed            EventType = "UNIT_DESTROYED"
+	UnitDied                 EventType = "UNIT_DIED"
+	UnitDissipates           EventType = "UNIT_DISSIPATES"
+
+	// SpellDispell is the SPELL_DISPEL event type.
+	//
+	// Deprecated: SpellDispell is misspelled, use SpellDispel.
+	SpellDispell = SpellDispel
 )
 
+// EventTypes contains every event type the parser understands.
+var EventTypes []EventType = []EventType{
+	DamageShield,
+	DamageShieldMissed,
+	DamageSplit,
+	EnchantApplied,
+	EnchantRemoved,
+	EnvironmentalDamage,
+	PartyKill,
+	RangeDamage,
+	RangeMissed,
+	SpellAuraApplied,
+	SpellAuraAppliedDose,
+	SpellAuraBroken,
+	SpellAuraBrokenSpell,
+	SpellAuraRefresh,
+	SpellAuraRemoved,
+	SpellAuraRemovedDose,
+	SpellCastFailed,
+	SpellCastStart,
+	SpellCastSuccess,
+	SpellCreate,
+	SpellDamage,
+	SpellDispel,
+	SpellDispelFailed,
+	SpellDrain,
+	SpellDurabilityDamage,
+	SpellDurabilityDamageAll,
+	SpellEnergize,
+	SpellExtraAttacks,
+	SpellHeal,
+	SpellInterrupt,
+	SpellInstakill,
+	SpellMissed,
+	SpellPeriodicDamage,
+	SpellPeriodicEnergize,
+	SpellPeriodicHeal,
+	SpellPeriodicLeech,
+	SpellPeriodicMissed,
+	SpellResurrect,
+	SpellStolen,
+	SpellSummon,
+	SwingDamage,
+	SwingMissed,
+	UnitDestroyed,
+	UnitDied,
+	UnitDissipates,
+}
+
 // DamageEvents contains the events that dealt damage.
 var DamageEvents []EventType = []EventType{
 	DamageShield,
@@ -103,12 +170,19 @@ var OverlayEvents []EventType = []EventType{
 	SpellAuraRemoved,
 	SpellAuraRefresh,
 	SpellAuraRemovedDose,
-	SpellDispell,
+	SpellAuraBroken,
+	SpellAuraBrokenSpell,
+	SpellDispel,
+	SpellDispelFailed,
+	SpellStolen,
 	SpellInterrupt,
 	UnitDied,
 }
 
 // BossNames is the string enumeration containing the ICC Boss names.
+//
+// Deprecated: encounters are detected with a BossRegistry, see
+// IcecrownCitadel and DefaultBossRegistry. BossNames is no longer consulted.
 var BossNames []string = []string{
 	"Lord Marrowgar",
 	"Lady Deathwhisper",
@@ -126,8 +200,35 @@ var BossNames []string = []string{
 const (
 	// BuffAura is when a buff is applied to a target.
 	BuffAura AuraType = "BUFF"
-	// DebufAura is when a debuf is applied to a target.
-	DebufAura AuraType = "DEBUF"
+	// DebuffAura is when a debuff is applied to a target.
+	DebuffAura AuraType = "DEBUFF"
+	// DebufAura is when a debuff is applied to a target.
+	//
+	// Deprecated: DebufAura is misspelled, use DebuffAura.
+	DebufAura = DebuffAura
+)
+
+const (
+	// PlainMiss is an attack that missed outright (MISS).
+	PlainMiss MissType = "MISS"
+	// DodgeMiss is an attack the target dodged.
+	DodgeMiss MissType = "DODGE"
+	// ParryMiss is an attack the target parried.
+	ParryMiss MissType = "PARRY"
+	// BlockMiss is an attack the target blocked in full.
+	BlockMiss MissType = "BLOCK"
+	// AbsorbMiss is damage absorbed in full by a shield.
+	AbsorbMiss MissType = "ABSORB"
+	// ResistMiss is a spell the target resisted in full.
+	ResistMiss MissType = "RESIST"
+	// ImmuneMiss is an attack the target was immune to.
+	ImmuneMiss MissType = "IMMUNE"
+	// EvadeMiss is an attack on an evading NPC.
+	EvadeMiss MissType = "EVADE"
+	// DeflectMiss is a spell the target deflected.
+	DeflectMiss MissType = "DEFLECT"
+	// ReflectMiss is a spell the target reflected back at the caster.
+	ReflectMiss MissType = "REFLECT"
 )
 
 const (
@@ -176,8 +277,45 @@ const (
 	Slime    EnvironmentalType = "SLIME"
 )
 
-// String implementation of SpellSchool.
+// String implementation of SpellSchool. Combinations without a name of their
+// own are formatted as their components joined with "|", e.g.
+// "Physical|Holy|Fire", and bits outside the known schools as hex.
 func (s SpellSchool) String() string {
+	if name := s.name(); name != "" {
+		return name
+	}
+	if s == 0 {
+		return "unknown"
+	}
+	var parts []string
+	for _, c := range s.Components() {
+		parts = append(parts, c.name())
+	}
+	if rest := s &^ Fel; rest != 0 {
+		parts = append(parts, fmt.Sprintf("%#x", int(rest)))
+	}
+	return strings.Join(parts, "|")
+}
+
+// Has reports whether every school in o is part of s.
+func (s SpellSchool) Has(o SpellSchool) bool {
+	return o != 0 && s&o == o
+}
+
+// Components returns the single schools making up s, from Physical to
+// Arcane. Bits outside the known schools are ignored.
+func (s SpellSchool) Components() []SpellSchool {
+	var out []SpellSchool
+	for c := Physical; c <= Arcane; c <<= 1 {
+		if s&c != 0 {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// name returns the in-game name of s, or "" if the combination has none.
+func (s SpellSchool) name() string {
 	switch s {
 	case Physical:
 		return "Physical"
@@ -248,7 +386,7 @@ func (s SpellSchool) String() string {
 	case Fel:
 		return "Fel"
 	default:
-		return "unknown"
+		return ""
 	}
 }
 
@@ -306,11 +444,21 @@ type DamageSuffix struct {
 	Blocked     uint64
 	Absorbed    uint64
 	Critical    bool
+	// Glancing is set for player melee swings against higher level NPCs
+	// that glanced for reduced damage.
+	Glancing bool
+	// Crushing is set for NPC melee swings that crushed for increased
+	// damage.
+	Crushing bool
 }
 
 // AuraSuffix contains aura related metadata.
 type AuraSuffix struct {
 	AuraType AuraType
+	// Doses is the number of stacks of the aura after a
+	// SPELL_AURA_APPLIED_DOSE or SPELL_AURA_REMOVED_DOSE event, and zero for
+	// other events.
+	Doses uint64
 }
 
 // EnergizeSuffix contains metadata related to a unit getting their power energized
@@ -322,7 +470,13 @@ type EnergizeSuffix struct {
 
 // MissSuffix is used to identify why some spell / swing missed.
 type MissSuffix struct {
-	MissType string
+	MissType MissType
+	// IsOffHand is set for off-hand swings. The 3.3.5a client does not log
+	// it, so it is only read from logs of later clients that do.
+	IsOffHand bool
+	// Amount is the damage absorbed, resisted or blocked by an ABSORB,
+	// RESIST or BLOCK miss, and zero for the other miss types.
+	Amount uint64
 }
 
 // HealSuffix contains info about a _HEAL event, usual spell heal, or spell periodic heal.
@@ -340,7 +494,8 @@ type InterruptSuffix struct {
 	ExtraSpellSchool SpellSchool
 }
 
-// DispellOrStolenSuffix provides what spell was debuffed.
+// DispelOrStolenSuffix provides what spell was dispelled or stolen, or for
+// SPELL_AURA_BROKEN_SPELL the spell that broke the aura.
 type DispelOrStolenSuffix struct {
 	ExtraSpellID     uint64
 	ExtraSpellName   string
@@ -395,12 +550,26 @@ type Suffix struct {
 
 // BaseCombatEvent is the common properties across all combat log lines.
 type BaseCombatEvent struct {
-	Timestamp  time.Time
-	EventType  EventType
-	SourceName string
-	SourceID   string
-	TargetName string
-	TargetID   string
+	Timestamp time.Time
+	// AdjustedTimestamp is Timestamp corrected to never go backwards within
+	// a parse, with lines sharing a timestamp ordered a nanosecond apart, see
+	// monotonicClock.
+	AdjustedTimestamp time.Time
+	EventType         EventType
+	SourceName        string
+	SourceID          string
+	SourceFlags       UnitFlags
+	TargetName        string
+	TargetID          string
+	TargetFlags       UnitFlags
+}
+
+// RawEvent preserves the fields of an event type the parser does not
+// understand, so the data is not lost.
+type RawEvent struct {
+	// Fields are the fields after the source and target, as they appear in
+	// the log.
+	Fields []string
 }
 
 // CombatLogRecord composes the `BaseCombatEvent`, `Prefix`, and `Suffix` structs
@@ -409,4 +578,6 @@ type CombatLogRecord struct {
 	BaseCombatEvent
 	Prefix
 	Suffix
+	// Raw is only set for event types missing from EventTypes.
+	Raw *RawEvent
 }
@@ -0,0 +1,165 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"sort"
+	"time"
+)
+
+// AuraUptime is how long an aura was up on a unit during an encounter.
+type AuraUptime struct {
+	Uptime   time.Duration `json:"uptime"`
+	Duration time.Duration `json:"duration"`
+	// MaxDoses is the highest stack count the aura reached during the
+	// encounter, zero for auras that do not stack.
+	MaxDoses uint64 `json:"max_doses,omitempty"`
+}
+
+// Percent returns the uptime as a percentage of the encounter duration.
+func (a AuraUptime) Percent() float64 {
+	if a.Duration <= 0 {
+		return 0
+	}
+	return 100 * float64(a.Uptime) / float64(a.Duration)
+}
+
+// AuraUptimeReport maps encounter name to unit name to aura name. Attempts
+// at the same encounter are combined.
+type AuraUptimeReport map[string]map[string]map[string]*AuraUptime
+
+// AuraUptimeAnalyzer computes the uptime of every aura on every unit during
+// boss encounters.
+type AuraUptimeAnalyzer struct {
+	Splitter *EncounterSplitter
+}
+
+// AuraUptimeAnalyzerFunc is an option for NewAuraUptimeAnalyzer.
+type AuraUptimeAnalyzerFunc func(*AuraUptimeAnalyzer)
+
+// WithUptimeSplitter sets the EncounterSplitter used to find encounters.
+func WithUptimeSplitter(s *EncounterSplitter) AuraUptimeAnalyzerFunc {
+	return func(a *AuraUptimeAnalyzer) {
+		a.Splitter = s
+	}
+}
+
+// NewAuraUptimeAnalyzer initializes, allocates and returns a pointer to an
+// AuraUptimeAnalyzer.
+func NewAuraUptimeAnalyzer(opts ...AuraUptimeAnalyzerFunc) *AuraUptimeAnalyzer {
+	a := &AuraUptimeAnalyzer{
+		Splitter: NewEncounterSplitter(),
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+// auraInterval is a span of time an aura was up.
+type auraInterval struct {
+	start, end time.Time
+}
+
+// Run pairs aura events into per-source instances with TrackAuras, so auras
+// applied before the pull are counted, then clips them to each encounter.
+// Instances of the same aura from different sources are merged, so that two
+// players keeping up the same debuff do not count twice.
+func (a *AuraUptimeAnalyzer) Run(data []*CombatLogRecord) AuraUptimeReport {
+	out := AuraUptimeReport{}
+	instances := TrackAuras(data)
+	durations := map[string]time.Duration{}
+	for _, e := range a.Splitter.Split(data) {
+		units, ok := out[e.Name]
+		if !ok {
+			units = map[string]map[string]*AuraUptime{}
+			out[e.Name] = units
+		}
+		intervals := map[string]map[string][]auraInterval{}
+		doses := map[string]map[string]uint64{}
+		for _, inst := range instances {
+			start, end := maxTime(inst.Applied, e.StartTime), minTime(inst.Removed, e.EndTime)
+			if !start.Before(end) {
+				continue
+			}
+			if intervals[inst.TargetName] == nil {
+				intervals[inst.TargetName] = map[string][]auraInterval{}
+				doses[inst.TargetName] = map[string]uint64{}
+			}
+			intervals[inst.TargetName][inst.SpellName] = append(intervals[inst.TargetName][inst.SpellName], auraInterval{start, end})
+			doses[inst.TargetName][inst.SpellName] = max(doses[inst.TargetName][inst.SpellName], inst.maxDosesBetween(start, end))
+		}
+		for unit, auras := range intervals {
+			if units[unit] == nil {
+				units[unit] = map[string]*AuraUptime{}
+			}
+			for aura, spans := range auras {
+				u, ok := units[unit][aura]
+				if !ok {
+					u = &AuraUptime{}
+					units[unit][aura] = u
+				}
+				u.Uptime += mergedLength(spans)
+				u.MaxDoses = max(u.MaxDoses, doses[unit][aura])
+			}
+		}
+		durations[e.Name] += e.Duration()
+	}
+	// every aura is measured against all attempts at the encounter,
+	// including attempts where it was never applied
+	for name, units := range out {
+		for _, auras := range units {
+			for _, u := range auras {
+				u.Duration = durations[name]
+			}
+		}
+	}
+	return out
+}
+
+// mergedLength returns the total length covered by the intervals, counting
+// overlapping stretches once.
+func mergedLength(spans []auraInterval) time.Duration {
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start.Before(spans[j].start)
+	})
+	var total time.Duration
+	cur := spans[0]
+	for _, s := range spans[1:] {
+		if s.start.After(cur.end) {
+			total += cur.end.Sub(cur.start)
+			cur = s
+			continue
+		}
+		cur.end = maxTime(cur.end, s.end)
+	}
+	return total + cur.end.Sub(cur.start)
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
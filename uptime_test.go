@@ -0,0 +1,60 @@
+/*
+Copyright 2023 Bradley Bonitatibus.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frostparse
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAuraUptimeAnalyzerRun(t *testing.T) {
+	data := parseTestLines(t,
+		`12/11 01:07:50.000  SPELL_AURA_APPLIED,0x07000000009DF7A8,"Winterinjuly",0x514,0x07000000009DF7A8,"Winterinjuly",0x514,47893,"Fel Armor",0x20,BUFF`,
+		`12/11 01:08:00.000  SWING_DAMAGE,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,100,0,1,0,0,0,nil,nil,nil`,
+		`12/11 01:08:02.000  SPELL_AURA_APPLIED,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,770,"Faerie Fire",0x8,DEBUFF`,
+		`12/11 01:08:04.000  SPELL_AURA_APPLIED,0x07000000007721EC,"Yogzar",0x511,0xF130008F0400003D,"Lord Marrowgar",0x10a48,770,"Faerie Fire",0x8,DEBUFF`,
+		`12/11 01:08:04.000  SPELL_AURA_APPLIED,0x07000000007721EC,"Yogzar",0x511,0xF130008F0400003D,"Lord Marrowgar",0x10a48,7386,"Sunder Armor",0x1,DEBUFF`,
+		`12/11 01:08:05.000  SPELL_AURA_APPLIED_DOSE,0x07000000007721EC,"Yogzar",0x511,0xF130008F0400003D,"Lord Marrowgar",0x10a48,7386,"Sunder Armor",0x1,DEBUFF,5`,
+		`12/11 01:08:06.000  SPELL_AURA_REMOVED,0x07000000009DF7A8,"Winterinjuly",0x514,0xF130008F0400003D,"Lord Marrowgar",0x10a48,770,"Faerie Fire",0x8,DEBUFF`,
+		`12/11 01:08:08.000  SPELL_AURA_REMOVED,0x07000000007721EC,"Yogzar",0x511,0xF130008F0400003D,"Lord Marrowgar",0x10a48,770,"Faerie Fire",0x8,DEBUFF`,
+		`12/11 01:08:10.000  UNIT_DIED,0x0000000000000000,nil,0x80000000,0xF130008F0400003D,"Lord Marrowgar",0x10a48`,
+	)
+	report := NewAuraUptimeAnalyzer().Run(data)
+	ff := report["Lord Marrowgar"]["Lord Marrowgar"]["Faerie Fire"]
+	if ff == nil || ff.Uptime != time.Second*6 || ff.Percent() != 60 {
+		t.Errorf("expected overlapping Faerie Fires to be 60%% up, got %+v", ff)
+	}
+	if sunder := report["Lord Marrowgar"]["Lord Marrowgar"]["Sunder Armor"]; sunder == nil || sunder.MaxDoses != 5 || ff.MaxDoses != 0 {
+		t.Errorf("expected Sunder Armor to reach 5 stacks, got %+v", sunder)
+	}
+	fel := report["Lord Marrowgar"]["Winterinjuly"]["Fel Armor"]
+	if fel == nil || fel.Percent() != 100 {
+		t.Errorf("expected a buff applied before the pull to be 100%% up, got %+v", fel)
+	}
+}
+
+func TestAuraUptimeAnalyzerRunTestData(t *testing.T) {
+	data, err := newTestParser().Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := NewAuraUptimeAnalyzer().Run(data)
+	for aura, u := range report["Lord Marrowgar"]["Lord Marrowgar"] {
+		fmt.Printf("%s uptime on Lord Marrowgar: %.1f%%\n", aura, u.Percent())
+	}
+}
@@ -21,11 +21,8 @@ import (
 	"io"
 	"strconv"
 	"strings"
-	"time"
 )
 
-const combatLogTimestampFormat = "2006/1/_2 15:04:05.000"
-
 func rowsInFile(r io.Reader) (int, error) {
 	buf := make([]byte, 32*1024)
 	count := 0
@@ -45,62 +42,39 @@ func rowsInFile(r io.Reader) (int, error) {
 	}
 }
 
-func mustParseTimestamp(t string) time.Time {
-	ts, err := time.Parse(combatLogTimestampFormat, t)
-	if err != nil {
-		panic(err)
-	}
-	return ts
-}
+// nilField is how the log writes a missing value.
+var nilField = []byte("nil")
 
-func mustParseInt(s string) int64 {
-	t, err := strconv.ParseInt(s, 10, 64)
-	if err != nil {
-		panic(err)
+// unquoteField removes the surrounding quotes of a quoted field and unescapes
+// any backslash escaped quotes or backslashes inside of it, leaving unquoted
+// fields such as nil untouched.
+func unquoteField(b []byte) []byte {
+	if len(b) < 2 || b[0] != '"' || b[len(b)-1] != '"' {
+		return b
 	}
-	return t
-}
-
-func mustParseSpellSchool(s string) SpellSchool {
-	if strings.HasPrefix(s, "0x") {
-		return SpellSchool(mustParseHexInt(s))
+	b = b[1 : len(b)-1]
+	if bytes.IndexByte(b, '\\') < 0 {
+		return b
 	}
-	return SpellSchool(mustParseUint(s))
-}
-
-func mustParseHexInt(s string) uint64 {
-	t := strings.Replace(s, "0x", "", -1)
-	return mustParseUint(t)
-}
-
-func mustParseUint(s string) uint64 {
-	i, err := strconv.ParseInt(s, 10, 64)
-	if err != nil {
-		panic(err)
-	}
-	return uint64(i)
-}
-
-func removeQuoteString(s string) string {
-	return strings.ReplaceAll(s, `"`, "")
-}
-
-func mustParseIntOrNil(s string) uint64 {
-	if strings.Contains(s, "nil") {
-		return 0
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] == '\\' && i+1 < len(b) {
+			i++
+		}
+		out = append(out, b[i])
 	}
-	return mustParseUint(s)
+	return out
 }
 
-func parseNilBool(s string) bool {
-	if strings.Contains(s, "nil") {
+func parseNilBool(b []byte) bool {
+	if bytes.Contains(b, nilField) {
 		return false
 	}
-	b, err := strconv.ParseBool(s)
+	v, err := strconv.ParseBool(string(b))
 	if err != nil {
 		return false
 	}
-	return b
+	return v
 }
 
 func sliceContains[T comparable](seq []T, v T) bool {
@@ -124,10 +98,6 @@ func isOverlayEvent(c CombatLogRecord) bool {
 	return sliceContains(OverlayEvents, c.EventType)
 }
 
-func isBossName(s string) bool {
-	return sliceContains(BossNames, s)
-}
-
 func isBossID(v string) bool {
 	return strings.HasPrefix(v, "0xF15")
 }
@@ -139,3 +109,49 @@ func isNPCID(v string) bool {
 func isPlayerID(v string) bool {
 	return strings.HasPrefix(v, "0x07")
 }
+
+func isPetID(v string) bool {
+	return strings.HasPrefix(v, "0xF14")
+}
+
+// abilityName returns the spell name of a record, or "Melee" for swing events
+// which carry no spell prefix.
+func abilityName(c CombatLogRecord) string {
+	if c.SpellAndRangePrefix != nil {
+		return c.SpellAndRangePrefix.SpellName
+	}
+	return "Melee"
+}
+
+// recordAmount returns the damage, healing, or power amount of a record, or 0
+// for events that carry no amount.
+func recordAmount(c CombatLogRecord) uint64 {
+	switch {
+	case c.DamageSuffix != nil:
+		return c.DamageSuffix.Amount
+	case c.HealSuffix != nil:
+		return c.HealSuffix.Amount
+	case c.ExtraAttacksSuffix != nil:
+		return c.ExtraAttacksSuffix.Amount
+	case c.LeechOrDrainSuffix != nil:
+		return c.LeechOrDrainSuffix.Amount
+	case c.EnergizeSuffix != nil && c.EnergizeSuffix.Amount > 0:
+		return uint64(c.EnergizeSuffix.Amount)
+	}
+	return 0
+}
+
+// spellReferences returns the spell ID of the record's prefix and the extra
+// spell ID of an interrupt, dispel, or steal, with 0 meaning absent.
+func spellReferences(c CombatLogRecord) (spellID, extraSpellID uint64) {
+	if c.SpellAndRangePrefix != nil {
+		spellID = c.SpellAndRangePrefix.SpellID
+	}
+	switch {
+	case c.InterruptSuffix != nil:
+		extraSpellID = c.InterruptSuffix.ExtraSpellID
+	case c.DispelOrStolenSuffix != nil:
+		extraSpellID = c.DispelOrStolenSuffix.ExtraSpellID
+	}
+	return spellID, extraSpellID
+}